@@ -0,0 +1,435 @@
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"go-image-board/database"
+	"go-image-board/interfaces"
+	"go-image-board/logging"
+	"go-image-board/storage"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//formatVersion is incremented whenever the archive layout changes in a way older versions cannot restore
+const formatVersion = 1
+
+//manifestName is the last entry of every archive, it lists and checksums every other entry
+const manifestName = "manifest.json"
+
+//databaseDirectory holds one file per table, each a stream of JSON objects, one per row
+const databaseDirectory = "database/"
+
+//filesDirectory holds every stored image and thumbnail, using their storage names
+const filesDirectory = "images/"
+
+//manifest describes the contents of an archive
+type manifest struct {
+	FormatVersion int
+	CreatedTime   time.Time
+	Tables        []manifestTable
+	Files         []manifestFile
+}
+
+//manifestTable describes a table in an archive
+type manifestTable struct {
+	Name    string
+	Columns []string
+	Rows    uint64
+}
+
+//manifestFile is the size and sha256 checksum of an archive entry
+type manifestFile struct {
+	Name   string
+	Size   int64
+	SHA256 string
+}
+
+//tableExport is a table being exported to a temporary file, before it is added to the archive
+type tableExport struct {
+	file    *os.File
+	hash    hash.Hash
+	encoder *json.Encoder
+	rows    uint64
+}
+
+//CreateBackup writes every table and stored file to a new archive at FilePath.
+//The archive is written to a temporary name first, so FilePath only ever holds a complete backup
+func CreateBackup(FilePath string) error {
+	if _, err := os.Stat(FilePath); err == nil {
+		return errors.New(FilePath + " already exists")
+	}
+	partialPath := FilePath + ".partial"
+	archiveFile, err := os.OpenFile(partialPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	err = writeArchive(archiveFile)
+	if closeErr := archiveFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(partialPath, FilePath)
+	}
+	if err != nil {
+		os.Remove(partialPath)
+	}
+	return err
+}
+
+//writeArchive writes the database, then the stored files, then the manifest
+func writeArchive(Output *os.File) error {
+	archive := tar.NewWriter(Output)
+	backupManifest := manifest{FormatVersion: formatVersion, CreatedTime: time.Now().UTC()}
+
+	//Tables are exported to temporary files, as each tar entry needs its size up front
+	exports := make(map[string]*tableExport)
+	defer func() {
+		for _, export := range exports {
+			export.file.Close()
+			os.Remove(export.file.Name())
+		}
+	}()
+	for _, table := range interfaces.BackupTables {
+		file, err := ioutil.TempFile("", "gib-backup-")
+		if err != nil {
+			return err
+		}
+		export := &tableExport{file: file, hash: sha256.New()}
+		export.encoder = json.NewEncoder(io.MultiWriter(file, export.hash))
+		exports[table.Name] = export
+	}
+	err := database.DBInterface.ExportTables(interfaces.BackupTables, func(Table interfaces.BackupTable, Row interfaces.BackupRow) error {
+		export := exports[Table.Name]
+		export.rows++
+		return export.encoder.Encode(Row)
+	})
+	if err != nil {
+		return err
+	}
+	for _, table := range interfaces.BackupTables {
+		export := exports[table.Name]
+		size, err := export.file.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return err
+		}
+		if _, err := export.file.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		name := databaseDirectory + table.Name + ".jsonl"
+		if err := writeEntry(archive, name, size, export.file); err != nil {
+			return err
+		}
+		var columns []string
+		for _, column := range table.Columns {
+			columns = append(columns, column.Name)
+		}
+		backupManifest.Tables = append(backupManifest.Tables, manifestTable{Name: table.Name, Columns: columns, Rows: export.rows})
+		backupManifest.Files = append(backupManifest.Files, manifestFile{Name: name, Size: size, SHA256: hex.EncodeToString(export.hash.Sum(nil))})
+		logging.WriteLog(logging.LogLevelInfo, "backup/CreateBackup", "0", logging.ResultInfo, []string{"Exported " + table.Name, strconv.FormatUint(export.rows, 10) + " rows"})
+	}
+
+	//Files are listed after the export, so every image in the database is included
+	objects, err := storage.StorageInterface.List("", true)
+	if err != nil {
+		return err
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Name < objects[j].Name })
+	for _, object := range objects {
+		file, err := backupObject(archive, object.Name)
+		if os.IsNotExist(err) {
+			logging.WriteLog(logging.LogLevelWarning, "backup/CreateBackup", "0", logging.ResultInfo, []string{"File was removed while backing up, it will be skipped", object.Name})
+			continue
+		}
+		if err != nil {
+			return errors.New("Failed to back up " + object.Name + ", " + err.Error())
+		}
+		backupManifest.Files = append(backupManifest.Files, file)
+	}
+	logging.WriteLog(logging.LogLevelInfo, "backup/CreateBackup", "0", logging.ResultInfo, []string{"Backed up " + strconv.Itoa(len(objects)) + " files"})
+
+	manifestData, err := json.MarshalIndent(backupManifest, "", "\t")
+	if err != nil {
+		return err
+	}
+	if err := writeEntry(archive, manifestName, int64(len(manifestData)), bytes.NewReader(manifestData)); err != nil {
+		return err
+	}
+	if err := archive.Close(); err != nil {
+		return err
+	}
+	return Output.Sync()
+}
+
+//backupObject copies a stored object in to the archive, returning its manifest entry
+func backupObject(Archive *tar.Writer, Name string) (manifestFile, error) {
+	object, info, err := storage.StorageInterface.Get(Name)
+	if err != nil {
+		return manifestFile{}, err
+	}
+	defer object.Close()
+	checksum := sha256.New()
+	if err := writeEntry(Archive, filesDirectory+Name, info.Size, io.TeeReader(object, checksum)); err != nil {
+		return manifestFile{}, err
+	}
+	return manifestFile{Name: filesDirectory + Name, Size: info.Size, SHA256: hex.EncodeToString(checksum.Sum(nil))}, nil
+}
+
+//writeEntry adds a file of exactly Size bytes to the archive
+func writeEntry(Archive *tar.Writer, Name string, Size int64, Data io.Reader) error {
+	err := Archive.WriteHeader(&tar.Header{Name: Name, Mode: 0600, Size: Size, ModTime: time.Now(), Typeflag: tar.TypeReg, Format: tar.FormatPAX})
+	if err != nil {
+		return err
+	}
+	written, err := io.CopyN(Archive, Data, Size)
+	if err == io.EOF {
+		return errors.New(Name + " changed size while being backed up, expected " + strconv.FormatInt(Size, 10) + " bytes but read " + strconv.FormatInt(written, 10))
+	}
+	return err
+}
+
+//RestoreBackup verifies every checksum in the archive at FilePath, then restores it in to the freshly installed database and empty storage.
+//If restoring fails, the database is left empty and any files already restored are removed
+func RestoreBackup(FilePath string) error {
+	backupManifest, err := verifyArchive(FilePath)
+	if err != nil {
+		return err
+	}
+	logging.WriteLog(logging.LogLevelInfo, "backup/RestoreBackup", "0", logging.ResultInfo, []string{"Verified " + strconv.Itoa(len(backupManifest.Files)) + " checksums, created " + backupManifest.CreatedTime.Format(time.RFC3339)})
+	existing, err := storage.StorageInterface.List("", true)
+	if err != nil {
+		return err
+	}
+	if len(existing) > 0 {
+		return errors.New("Storage already contains files, backups can only be restored in to empty storage")
+	}
+
+	archiveFile, err := os.Open(FilePath)
+	if err != nil {
+		return err
+	}
+	defer archiveFile.Close()
+	archive := tar.NewReader(archiveFile)
+	var restoredFiles []string
+	//Files are restored from within the import, so a failure on either side undoes the database
+	err = database.DBInterface.ImportTables(interfaces.BackupTables, func(Insert func(Table interfaces.BackupTable, Row interfaces.BackupRow) error) error {
+		for {
+			header, err := archive.Next()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			switch {
+			case strings.HasPrefix(header.Name, databaseDirectory):
+				table, exists := interfaces.GetBackupTable(strings.TrimSuffix(strings.TrimPrefix(header.Name, databaseDirectory), ".jsonl"))
+				if exists == false {
+					return errors.New("Unknown table " + header.Name)
+				}
+				rows, err := restoreTable(archive, table, Insert)
+				if err != nil {
+					return errors.New("Failed to restore " + table.Name + ", " + err.Error())
+				}
+				logging.WriteLog(logging.LogLevelInfo, "backup/RestoreBackup", "0", logging.ResultInfo, []string{"Restored " + table.Name, strconv.FormatUint(rows, 10) + " rows"})
+			case strings.HasPrefix(header.Name, filesDirectory):
+				name := strings.TrimPrefix(header.Name, filesDirectory)
+				if err := storage.StorageInterface.Put(name, archive, header.Size); err != nil {
+					return errors.New("Failed to restore " + name + ", " + err.Error())
+				}
+				restoredFiles = append(restoredFiles, name)
+			}
+		}
+	})
+	if err != nil {
+		for _, name := range restoredFiles {
+			storage.StorageInterface.Delete(name)
+		}
+		return err
+	}
+	logging.WriteLog(logging.LogLevelInfo, "backup/RestoreBackup", "0", logging.ResultInfo, []string{"Restored " + strconv.Itoa(len(restoredFiles)) + " files"})
+	return nil
+}
+
+//restoreTable inserts each row of a table's entry, returning how many were inserted
+func restoreTable(Data io.Reader, Table interfaces.BackupTable, Insert func(Table interfaces.BackupTable, Row interfaces.BackupRow) error) (uint64, error) {
+	decoder := json.NewDecoder(Data)
+	decoder.UseNumber()
+	rows := uint64(0)
+	for {
+		var encoded map[string]interface{}
+		err := decoder.Decode(&encoded)
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return rows, err
+		}
+		row, err := decodeRow(Table, encoded)
+		if err != nil {
+			return rows, errors.New("row " + strconv.FormatUint(rows+1, 10) + ", " + err.Error())
+		}
+		if err := Insert(Table, row); err != nil {
+			return rows, err
+		}
+		rows++
+	}
+}
+
+//decodeRow converts a row decoded from JSON to the types given by each column's BackupColumnType
+func decodeRow(Table interfaces.BackupTable, Encoded map[string]interface{}) (interfaces.BackupRow, error) {
+	row := make(interfaces.BackupRow)
+	for _, column := range Table.Columns {
+		value, exists := Encoded[column.Name]
+		if exists == false {
+			return nil, errors.New("missing column " + column.Name)
+		}
+		var err error
+		switch column.Type {
+		case interfaces.BackupUint:
+			number, isNumber := value.(json.Number)
+			if isNumber == false {
+				err = errors.New("not a number")
+				break
+			}
+			row[column.Name], err = strconv.ParseUint(number.String(), 10, 64)
+		case interfaces.BackupInt:
+			number, isNumber := value.(json.Number)
+			if isNumber == false {
+				err = errors.New("not a number")
+				break
+			}
+			row[column.Name], err = strconv.ParseInt(number.String(), 10, 64)
+		case interfaces.BackupFloat:
+			number, isNumber := value.(json.Number)
+			if isNumber == false {
+				err = errors.New("not a number")
+				break
+			}
+			row[column.Name], err = strconv.ParseFloat(number.String(), 64)
+		case interfaces.BackupString, interfaces.BackupNullString:
+			text, isString := value.(string)
+			if isString == false && (value != nil || column.Type == interfaces.BackupString) {
+				err = errors.New("not a string")
+				break
+			}
+			row[column.Name] = value
+			if isString {
+				row[column.Name] = text
+			}
+		case interfaces.BackupBool:
+			boolean, isBool := value.(bool)
+			if isBool == false {
+				err = errors.New("not a boolean")
+				break
+			}
+			row[column.Name] = boolean
+		case interfaces.BackupTime, interfaces.BackupNullTime:
+			text, isString := value.(string)
+			if isString == false {
+				if value == nil && column.Type == interfaces.BackupNullTime {
+					row[column.Name] = nil
+					break
+				}
+				err = errors.New("not a time")
+				break
+			}
+			row[column.Name], err = time.Parse(time.RFC3339Nano, text)
+		}
+		if err != nil {
+			return nil, errors.New("column " + column.Name + ", " + err.Error())
+		}
+	}
+	return row, nil
+}
+
+//verifyArchive reads the whole archive, checking every entry against the manifest, before anything is restored
+func verifyArchive(FilePath string) (manifest, error) {
+	var backupManifest manifest
+	archiveFile, err := os.Open(FilePath)
+	if err != nil {
+		return backupManifest, err
+	}
+	defer archiveFile.Close()
+	archive := tar.NewReader(archiveFile)
+	entries := make(map[string]manifestFile)
+	foundManifest := false
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return backupManifest, err
+		}
+		if header.Typeflag != tar.TypeReg {
+			return backupManifest, errors.New("Unexpected entry " + header.Name)
+		}
+		if header.Name == manifestName {
+			if err := json.NewDecoder(archive).Decode(&backupManifest); err != nil {
+				return backupManifest, errors.New("Failed to read manifest, " + err.Error())
+			}
+			foundManifest = true
+			continue
+		}
+		checksum := sha256.New()
+		size, err := io.Copy(checksum, archive)
+		if err != nil {
+			return backupManifest, err
+		}
+		entries[header.Name] = manifestFile{Name: header.Name, Size: size, SHA256: hex.EncodeToString(checksum.Sum(nil))}
+	}
+	if foundManifest == false {
+		return backupManifest, errors.New("Archive has no manifest, it may be incomplete")
+	}
+	if backupManifest.FormatVersion != formatVersion {
+		return backupManifest, errors.New("Unsupported backup format version " + strconv.Itoa(backupManifest.FormatVersion))
+	}
+	tables := make(map[string]bool)
+	for _, table := range backupManifest.Tables {
+		tables[table.Name] = true
+		current, exists := interfaces.GetBackupTable(table.Name)
+		if exists == false {
+			return backupManifest, errors.New("Backup contains unknown table " + table.Name + ", it was created by a different version")
+		}
+		var columns []string
+		for _, column := range current.Columns {
+			columns = append(columns, column.Name)
+		}
+		if strings.Join(columns, ",") != strings.Join(table.Columns, ",") {
+			return backupManifest, errors.New("Backup columns for " + table.Name + " do not match this version, it was created by a different version")
+		}
+		if _, exists := entries[databaseDirectory+table.Name+".jsonl"]; exists == false {
+			return backupManifest, errors.New("Backup is missing table " + table.Name)
+		}
+	}
+	for name := range entries {
+		if strings.HasPrefix(name, databaseDirectory) && tables[strings.TrimSuffix(strings.TrimPrefix(name, databaseDirectory), ".jsonl")] == false {
+			return backupManifest, errors.New("Backup contains " + name + ", which is not a table in the manifest")
+		}
+	}
+	for _, file := range backupManifest.Files {
+		entry, exists := entries[file.Name]
+		if exists == false {
+			return backupManifest, errors.New("Backup is missing " + file.Name)
+		}
+		if entry != file {
+			return backupManifest, errors.New("Checksum mismatch for " + file.Name + ", the backup is corrupt")
+		}
+		delete(entries, file.Name)
+	}
+	for name := range entries {
+		return backupManifest, errors.New("Backup contains " + name + ", which is not in the manifest")
+	}
+	return backupManifest, nil
+}
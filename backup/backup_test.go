@@ -0,0 +1,312 @@
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"go-image-board/config"
+	"go-image-board/database"
+	"go-image-board/interfaces"
+	"go-image-board/logging"
+	"go-image-board/plugins"
+	"go-image-board/plugins/localstorageplugin"
+	"go-image-board/plugins/memoryplugin"
+	"go-image-board/plugins/sqliteplugin"
+	"go-image-board/storage"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+//testFiles are stored before each backup, covering both layouts and thumbnails
+var testFiles = map[string]string{
+	"one.png":                      "first image",
+	"ab/cd/abcdtwo.png":            "second image",
+	"thumbs/one.png.png":           "first thumbnail",
+	"thumbs/ab/cd/abcdtwo.png.png": "second thumbnail",
+}
+
+func TestMain(m *testing.M) {
+	logging.LogInterface = &plugins.STDLog{}
+	logging.LogInterface.Init(-1, "", "")
+	os.Exit(m.Run())
+}
+
+//useMemoryDatabase switches to an empty in-memory database
+func useMemoryDatabase(t *testing.T) {
+	t.Helper()
+	database.DBInterface = &memoryplugin.MemoryPlugin{PasswordHashCost: bcrypt.MinCost}
+	if err := database.DBInterface.InitDatabase(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+//useSQLiteDatabase switches to a freshly installed SQLite database
+func useSQLiteDatabase(t *testing.T) {
+	t.Helper()
+	config.Configuration.DBPath = filepath.Join(t.TempDir(), "gib.db")
+	sqlite := &sqliteplugin.SQLitePlugin{}
+	if err := sqlite.InitDatabase(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sqlite.DBHandle.Close() })
+	database.DBInterface = sqlite
+}
+
+//useEmptyStorage switches to an empty local storage directory
+func useEmptyStorage(t *testing.T) {
+	t.Helper()
+	storage.StorageInterface = &localstorageplugin.LocalStoragePlugin{Directory: t.TempDir()}
+	if err := storage.StorageInterface.InitStorage(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+//seedSite fills the current database and storage with one of everything a backup must keep
+func seedSite(t *testing.T) {
+	t.Helper()
+	db := database.DBInterface
+	mustSucceed := func(step string, err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("%s: %v", step, err)
+		}
+	}
+	mustSucceed("CreateUser", db.CreateUser("admin", []byte("adminpass"), "admin@example.com", 65535))
+	adminID, err := db.GetUserID("admin")
+	mustSucceed("GetUserID", err)
+	mustSucceed("SetSecurityQuestions", db.SetSecurityQuestions("admin", "One?", "Two?", "Three?", []byte("a"), []byte("b"), []byte("c"), []byte("adminpass")))
+	catID, err := db.NewTag("cat", "A cat", "", adminID)
+	mustSucceed("NewTag cat", err)
+	kittyID, err := db.NewTag("kitty", "", "", adminID)
+	mustSucceed("NewTag kitty", err)
+	mustSucceed("UpdateTag", db.UpdateTag(kittyID, "kitty", "", "", catID, true, adminID))
+	oneID, err := db.NewImage("one", "one.png", adminID, "http://example.com/one", false)
+	mustSucceed("NewImage one", err)
+	twoID, err := db.NewImage("two", "ab/cd/abcdtwo.png", adminID, "", false)
+	mustSucceed("NewImage two", err)
+	_, err = db.NewImage("three", "three.png", adminID, "", true)
+	mustSucceed("NewImage three", err)
+	mustSucceed("AddTag", db.AddTag([]uint64{catID}, oneID, adminID))
+	mustSucceed("SetImagedHash", db.SetImagedHash(oneID, 1<<63|5, 7))
+	mustSucceed("SetImageMetadata", db.SetImageMetadata(oneID, interfaces.ImageMetadata{Width: 64, Height: 36, FileSize: 1024, MIMEType: "video/mp4", Duration: 2.5}))
+	mustSucceed("UpdateUserVoteScore", db.UpdateUserVoteScore(adminID, oneID, 8))
+	mustSucceed("SetUserFavorite", db.SetUserFavorite(adminID, oneID, true))
+	collectionID, err := db.NewCollection("Pets", "Pictures of pets", adminID)
+	mustSucceed("NewCollection", err)
+	mustSucceed("AddCollectionMember", db.AddCollectionMember(collectionID, []uint64{oneID, twoID}, adminID))
+	mustSucceed("UpdateCollectionMember", db.UpdateCollectionMember(collectionID, oneID, 5))
+	_, err = db.NewSavedSearch(interfaces.SavedSearchInformation{UserID: adminID, Name: "Cats", Query: "cat order:score", Pinned: true, ShowNewCount: true})
+	mustSucceed("NewSavedSearch", err)
+	commentID, err := db.NewComment(interfaces.CommentInformation{ImageID: oneID, UserID: adminID, Body: "First"})
+	mustSucceed("NewComment", err)
+	_, err = db.NewComment(interfaces.CommentInformation{ImageID: oneID, ParentID: commentID, UserID: adminID, Body: "Reply"})
+	mustSucceed("NewComment reply", err)
+	mustSucceed("UpdateComment", db.UpdateComment(commentID, "First, edited", adminID))
+	mustSucceed("DeleteComment", db.DeleteComment(commentID, adminID))
+	noteID, err := db.NewNote(interfaces.NoteInformation{ImageID: oneID, CreatorID: adminID, NoteRegion: interfaces.NoteRegion{X: 1, Y: 2, Width: 3, Height: 4, ImageWidth: 10, ImageHeight: 10}, Body: "Ear"})
+	mustSucceed("NewNote", err)
+	mustSucceed("UpdateNote", db.UpdateNote(noteID, interfaces.NoteRegion{X: 5, Y: 2, Width: 3, Height: 4, ImageWidth: 10, ImageHeight: 10}, "Left ear", adminID))
+	reportID, err := db.NewReport(interfaces.ReportInformation{TargetType: "image", TargetID: twoID, ReporterID: adminID, Category: "duplicate", Reason: "Same as one"})
+	mustSucceed("NewReport", err)
+	mustSucceed("UpdateReport", db.UpdateReport(reportID, interfaces.ReportResolved, adminID, "Merged"))
+	_, err = db.NewReport(interfaces.ReportInformation{TargetType: "tag", TargetID: kittyID, ReporterID: adminID, Category: "other", Reason: "Typo"})
+	mustSucceed("NewReport open", err)
+	liftedBanID, err := db.NewBan(interfaces.BanInformation{IPRange: "198.51.100.7/32", Reason: "Mistake", BannerID: adminID})
+	mustSucceed("NewBan lifted", err)
+	mustSucceed("LiftBan", db.LiftBan(liftedBanID, adminID))
+	_, err = db.NewBan(interfaces.BanInformation{IPRange: "192.0.2.0/24", Reason: "Open proxy", BannerID: adminID})
+	mustSucceed("NewBan", err)
+	_, err = db.NewBan(interfaces.BanInformation{UserID: adminID, Reason: "Testing", BannerID: adminID, ExpiryTime: time.Now().Add(24 * time.Hour)})
+	mustSucceed("NewBan expiring", err)
+	mustSucceed("AddAuditLog", db.AddAuditLog(adminID, "TEST", "Seeded"))
+	for name, data := range testFiles {
+		mustSucceed("Put "+name, storage.StorageInterface.Put(name, strings.NewReader(data), int64(len(data))))
+	}
+}
+
+//archiveEntries returns the contents of each entry in an archive
+func archiveEntries(t *testing.T, FilePath string) map[string]string {
+	t.Helper()
+	file, err := os.Open(FilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	entries := make(map[string]string)
+	archive := tar.NewReader(file)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			return entries
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := ioutil.ReadAll(archive)
+		entries[header.Name] = string(data)
+	}
+}
+
+func TestBackupRestoresAcrossDatabases(t *testing.T) {
+	directory := t.TempDir()
+	useMemoryDatabase(t)
+	useEmptyStorage(t)
+	seedSite(t)
+	if err := CreateBackup(filepath.Join(directory, "memory.tar")); err != nil {
+		t.Fatal(err)
+	}
+
+	useSQLiteDatabase(t)
+	useEmptyStorage(t)
+	if err := RestoreBackup(filepath.Join(directory, "memory.tar")); err != nil {
+		t.Fatal(err)
+	}
+	db := database.DBInterface
+	if err := db.ValidateUser("admin", []byte("adminpass")); err != nil {
+		t.Errorf("restored password does not validate: %v", err)
+	}
+	if questionOne, _, _, err := db.GetSecurityQuestions("admin"); err != nil || questionOne != "One?" {
+		t.Errorf("security questions = %q, %v", questionOne, err)
+	}
+	image, err := db.GetImageByFileName("one.png")
+	if err != nil || image.Source != "http://example.com/one" {
+		t.Fatalf("GetImageByFileName = %+v, %v", image, err)
+	}
+	if image.Width != 64 || image.Height != 36 || image.FileSize != 1024 || image.MIMEType != "video/mp4" || image.Duration != 2.5 {
+		t.Errorf("metadata = %+v", image)
+	}
+	if hHash, vHash, err := db.GetImagedHash(image.ID); err != nil || hHash != 1<<63|5 || vHash != 7 {
+		t.Errorf("dHashes = %d, %d, %v", hHash, vHash, err)
+	}
+	if score, err := db.GetUserVoteScore(image.UploaderID, image.ID); err != nil || score != 8 {
+		t.Errorf("vote = %d, %v", score, err)
+	}
+	if favorite, err := db.GetUserFavorite(image.UploaderID, image.ID); err != nil || favorite == false || image.FavoriteCount != 1 {
+		t.Errorf("favorite = %v, %d, %v", favorite, image.FavoriteCount, err)
+	}
+	if alias, err := db.GetTagByName("kitty"); err != nil || alias.IsAlias == false {
+		t.Errorf("alias = %+v, %v", alias, err)
+	}
+	if searches, err := db.GetSavedSearches(image.UploaderID); err != nil || len(searches) != 1 || searches[0].Query != "cat order:score" || searches[0].Pinned == false || searches[0].ShowNewCount == false {
+		t.Errorf("saved searches = %+v, %v", searches, err)
+	}
+	comments, threads, err := db.GetComments(image.ID, 0, 0, 10)
+	if err != nil || threads != 1 || len(comments) != 2 || comments[0].Body != "First, edited" || comments[0].Deleted == false || comments[1].Depth != 1 {
+		t.Errorf("comments = %+v, %d, %v", comments, threads, err)
+	} else if revisions, err := db.GetCommentRevisions(comments[0].ID); err != nil || len(revisions) != 1 || revisions[0].Body != "First" {
+		t.Errorf("comment revisions = %+v, %v", revisions, err)
+	}
+	notes, err := db.GetNotes(image.ID, false)
+	if err != nil || len(notes) != 1 || notes[0].Body != "Left ear" || notes[0].X != 5 || notes[0].ImageHeight != 10 {
+		t.Errorf("notes = %+v, %v", notes, err)
+	} else if revisions, err := db.GetNoteRevisions(notes[0].ID); err != nil || len(revisions) != 2 || revisions[1].Body != "Ear" || revisions[1].X != 1 {
+		t.Errorf("note revisions = %+v, %v", revisions, err)
+	}
+	if pending, total, err := db.GetPendingImages(0, 10); err != nil || total != 1 || pending[0].Name != "three" {
+		t.Errorf("pending images = %+v, %d, %v", pending, total, err)
+	}
+	if reports, total, err := db.GetReports("", 0, 10); err != nil || total != 2 || reports[0].Status != interfaces.ReportResolved || reports[0].Outcome != "Merged" || reports[0].UpdateTime.IsZero() || reports[1].Status != interfaces.ReportOpen || reports[1].UpdateTime.IsZero() == false {
+		t.Errorf("reports = %+v, %d, %v", reports, total, err)
+	}
+	if bans, err := db.GetActiveBans(); err != nil || len(bans) != 2 || bans[0].UserName != "admin" || bans[0].ExpiryTime.Sub(time.Now()) < 23*time.Hour || bans[1].IPRange != "192.0.2.0/24" || bans[1].IsPermanent() == false {
+		t.Errorf("active bans = %+v, %v", bans, err)
+	}
+	if ban, err := db.GetBan(1); err != nil || ban.Reason != "Mistake" || ban.LiftTime.IsZero() || ban.LifterID == 0 {
+		t.Errorf("lifted ban = %+v, %v", ban, err)
+	}
+	collection, err := db.GetCollectionByName("Pets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	members, _, err := db.GetCollectionMembers(collection.ID, 0, 10)
+	if err != nil || len(members) != 2 || members[0].Name != "two" || members[1].Name != "one" {
+		t.Errorf("collection members = %+v, %v", members, err)
+	}
+	for name, data := range testFiles {
+		object, _, err := storage.StorageInterface.Get(name)
+		if err != nil {
+			t.Errorf("Get %s: %v", name, err)
+			continue
+		}
+		restored, _ := ioutil.ReadAll(object)
+		object.Close()
+		if string(restored) != data {
+			t.Errorf("%s = %q, want %q", name, restored, data)
+		}
+	}
+	//New rows must not collide with restored IDs
+	if _, err := db.NewTag("dog", "", "", image.UploaderID); err != nil {
+		t.Errorf("NewTag after restore: %v", err)
+	}
+
+	//Restoring again is refused, rather than mixing two sites
+	if err := RestoreBackup(filepath.Join(directory, "memory.tar")); err == nil {
+		t.Error("restoring over existing data succeeded")
+	}
+
+	//A backup of the restored database restores to the same rows
+	useSQLiteDatabase(t)
+	useEmptyStorage(t)
+	if err := RestoreBackup(filepath.Join(directory, "memory.tar")); err != nil {
+		t.Fatal(err)
+	}
+	if err := CreateBackup(filepath.Join(directory, "sqlite.tar")); err != nil {
+		t.Fatal(err)
+	}
+	useMemoryDatabase(t)
+	useEmptyStorage(t)
+	if err := RestoreBackup(filepath.Join(directory, "sqlite.tar")); err != nil {
+		t.Fatal(err)
+	}
+	if err := CreateBackup(filepath.Join(directory, "memory2.tar")); err != nil {
+		t.Fatal(err)
+	}
+	fromSQLite := archiveEntries(t, filepath.Join(directory, "sqlite.tar"))
+	fromMemory := archiveEntries(t, filepath.Join(directory, "memory2.tar"))
+	for _, table := range interfaces.BackupTables {
+		name := databaseDirectory + table.Name + ".jsonl"
+		if fromSQLite[name] != fromMemory[name] {
+			t.Errorf("%s differs after restoring\nsqlite: %s\nmemory: %s", name, fromSQLite[name], fromMemory[name])
+		}
+	}
+}
+
+func TestCorruptBackupIsNotRestored(t *testing.T) {
+	directory := t.TempDir()
+	useMemoryDatabase(t)
+	useEmptyStorage(t)
+	seedSite(t)
+	archivePath := filepath.Join(directory, "site.tar")
+	if err := CreateBackup(archivePath); err != nil {
+		t.Fatal(err)
+	}
+	if err := CreateBackup(archivePath); err == nil {
+		t.Error("CreateBackup replaced an existing archive")
+	}
+	data, err := ioutil.ReadFile(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	corrupted := bytes.Replace(data, []byte("second image"), []byte("second imagf"), 1)
+	if err := ioutil.WriteFile(archivePath, corrupted, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	useSQLiteDatabase(t)
+	useEmptyStorage(t)
+	if err := RestoreBackup(archivePath); err == nil || strings.Contains(err.Error(), "Checksum mismatch") == false {
+		t.Fatalf("RestoreBackup = %v, want a checksum mismatch", err)
+	}
+	if _, err := database.DBInterface.GetUserID("admin"); err == nil {
+		t.Error("a corrupt backup was partially restored")
+	}
+	if objects, _ := storage.StorageInterface.List("", true); len(objects) > 0 {
+		t.Errorf("a corrupt backup restored files %v", objects)
+	}
+}
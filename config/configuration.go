@@ -23,8 +23,30 @@ type ConfigurationSettings struct {
 	DBPort string
 	//DBHost hostname of the database server
 	DBHost string
-	//ImageDirectory path to where images are stored
+	//DBType which database plugin to use, either mariadb, postgres or sqlite
+	DBType string
+	//DBPath path to the database file, used by file based databases such as sqlite
+	DBPath string
+	//DBSSLMode sslmode used when connecting to postgres, such as disable, require or verify-full
+	DBSSLMode string
+	//ImageDirectory path to where images are stored, used when StorageType is local
 	ImageDirectory string
+	//StorageType where images and thumbnails are stored, either local or s3
+	StorageType string
+	//S3Endpoint host and port of an S3 compatible object store, such as MinIO, used when StorageType is s3
+	S3Endpoint string
+	//S3Region region of the bucket
+	S3Region string
+	//S3Bucket bucket images and thumbnails are stored in, it must already exist
+	S3Bucket string
+	//S3AccessKeyID access key used to auth to the object store
+	S3AccessKeyID string
+	//S3SecretAccessKey secret key used to auth to the object store
+	S3SecretAccessKey string
+	//S3UseSSL if set, connects to the object store using https
+	S3UseSSL bool
+	//UseShardedLayout if set, new images and thumbnails are stored in sub directories named after the start of their hash, such as ab/cd/abcd...jpg. Run with -shardfiles to move existing files
+	UseShardedLayout bool
 	//Address hostname/port that this server should listen on
 	Address string
 	//ReadTimeout timeout allowed for reads
@@ -59,8 +81,12 @@ type ConfigurationSettings struct {
 	FFMPEGPath string
 	//UseFFMPEG If set, when joined with FFMPEGPath, videos that are uploaded will have a thumbnail generated using FFMPEG
 	UseFFMPEG bool
+	//FFProbePath Path to the FFProbe application, used with UseFFMPEG to read the dimensions and duration of videos and audio. Defaults to ffprobe next to FFMPEGPath
+	FFProbePath string
 	//PageStride How many images to show on one page
 	PageStride uint64
+	//MaxWildcardTags How many tags a wildcard pattern in a search, such as artist_*, may match
+	MaxWildcardTags uint64
 	//APIThrottle How much time, in milliseconds, users using the API must wait between requests
 	APIThrottle int64
 	//UseTLS Enables TLS encryption on server
@@ -71,6 +97,10 @@ type ConfigurationSettings struct {
 	TLSKeyPath string
 	//ShowSimilarOnImages If enabled, shows similar count and link when viewing an image
 	ShowSimilarOnImages bool
+	//ModerateUploads if set, uploads from users without the approve posts permission wait in the moderation queue, hidden from everyone but the uploader and moderators, until a moderator approves them
+	ModerateUploads bool
+	//TrashRetention how long deleted images and collections are kept in the trash before they are purged
+	TrashRetention time.Duration
 	//TargetLogLevel increase or decrease log verbosity
 	TargetLogLevel int64
 	//LoggingWhiteList regex based white-list for logging
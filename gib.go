@@ -2,23 +2,30 @@ package main
 
 import (
 	"context"
-	"database/sql"
+	"errors"
 	"flag"
+	"go-image-board/backup"
 	"go-image-board/config"
 	"go-image-board/database"
+	"go-image-board/importer"
 	"go-image-board/interfaces"
 	"go-image-board/logging"
 	"go-image-board/plugins"
+	"go-image-board/plugins/localstorageplugin"
 	"go-image-board/plugins/mariadbplugin"
+	"go-image-board/plugins/migrate"
+	"go-image-board/plugins/postgresplugin"
+	"go-image-board/plugins/s3storageplugin"
+	"go-image-board/plugins/sqliteplugin"
 	"go-image-board/routers"
 	"go-image-board/routers/api"
 	"go-image-board/routers/templatecache"
-	"io/ioutil"
+	"go-image-board/storage"
 	"net/http"
 	"os"
-	"path"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -29,11 +36,19 @@ import (
 func main() {
 	//Commands
 	generateThumbsOnly := flag.Bool("thumbsonly", false, "Regenerates all thumbnails. You should run this if you change your thumbnail size or enable ffmpeg.")
+	generateMetadataOnly := flag.Bool("metadataonly", false, "Reads the dimensions, file size, type and duration of all images. You should run this after updating past the addition of metadata, or after enabling ffmpeg.")
 	generatedHashesOnly := flag.Bool("dhashonly", false, "Regenerates all dhashes. You should run this if you change hash method, or after updating past 1.0.3.8")
-	missingOnly := flag.Bool("missingonly", false, "When used with dhashonly or thumbsonly, prevents deleting pre-existing entries.")
+	missingOnly := flag.Bool("missingonly", false, "When used with dhashonly, thumbsonly or metadataonly, prevents deleting pre-existing entries.")
 	renameFilesOnly := flag.Bool("renameonly", false, "Renames all posts and corrects the names in the database. Use if changing naming convention of files.")
+	shardFilesOnly := flag.Bool("shardfiles", false, "Moves images and thumbnails in to the sharded layout and corrects their locations in the database. Requires UseShardedLayout. If interrupted, run again to resume.")
 	removeOrphanFiles := flag.Bool("removeorphanfiles", false, "Removes images and thumbnails that do not have an associated database entry.")
 	fixCollectionTags := flag.Bool("fixcollectiontags", false, "Validates and fixes tags applied to all collections")
+	applyImplications := flag.Bool("applyimplications", false, "Adds implied tags to every image missing them. Run this after adding a tag implication so images tagged before it also get the implied tag.")
+	backupFile := flag.String("backup", "", "Writes the database, images and thumbnails to a single archive at the given path, then exits. Stop the server first so nothing changes while backing up.")
+	restoreFile := flag.String("restore", "", "Restores an archive written by -backup in to an empty database and ImageDirectory or bucket, then exits.")
+	importDirectory := flag.String("import", "", "Imports every image below the given directory, with tags, ratings, sources, uploaders and pools from Danbooru or Gelbooru JSON, gallery-dl sidecars and Hydrus sidecars, then exits. Files already uploaded are skipped.")
+	importUser := flag.String("importuser", "", "When used with import, the user given images whose uploader has no account of the same name. Defaults to the SYSTEM user.")
+	migrateDryRun := flag.Bool("migrate-dry-run", false, "Prints the SQL of any pending database migrations without applying them, then exits.")
 	flag.Parse()
 
 	//Load succeeded
@@ -52,12 +67,18 @@ func main() {
 	//Init logging
 	logging.LogInterface.Init(config.Configuration.TargetLogLevel, config.Configuration.LoggingWhiteList, config.Configuration.LoggingBlackList)
 
+	//Init storage, used by the cli commands as well as the server
+	if err := initStorage(); err != nil {
+		logging.WriteLog(logging.LogLevelCritical, "main/main", "0", logging.ResultFailure, []string{"Failed to initialize storage", err.Error()})
+		return
+	}
+
 	if *generateThumbsOnly {
 		logging.WriteLog(logging.LogLevelInfo, "main/main", "0", logging.ResultInfo, []string{"Generate thumbnails flag detected. Server will not start and instead just generate thumbnails. This may take some time."})
 		//We need wait group so that we don't end the application before goroutines
 		var wg sync.WaitGroup
 		//list files
-		files, err := ioutil.ReadDir(config.Configuration.ImageDirectory)
+		files, err := storage.StorageInterface.List("", true)
 		if err != nil {
 			logging.WriteLog(logging.LogLevelError, "main/main", "0", logging.ResultFailure, []string{"failed to get files to generate new thumbnails", err.Error()})
 			return
@@ -65,20 +86,20 @@ func main() {
 		//for each image
 		generatedThumbnails := uint64(0)
 		for _, file := range files {
-			if file.IsDir() {
+			if storage.IsThumbnail(file.Name) {
 				continue
 			}
 			//Delete thumbnail
-			thumbNailPath := config.Configuration.ImageDirectory + string(filepath.Separator) + "thumbs" + string(filepath.Separator) + file.Name() + ".png"
-			if _, err := os.Stat(thumbNailPath); *missingOnly == false || (err != nil && os.IsNotExist(err)) {
-				os.Remove(thumbNailPath)
+			thumbnailName := storage.ThumbnailName(file.Name)
+			if _, err := storage.StorageInterface.Stat(thumbnailName); *missingOnly == false || (err != nil && os.IsNotExist(err)) {
+				storage.StorageInterface.Delete(thumbnailName)
 				//Goroutine generate a new one
 				generatedThumbnails++
 				wg.Add(1) //This magic thing will prevent program from closing before goroutines finish
 				go func(fileName string) {
 					defer wg.Done()
 					routers.GenerateThumbnail(fileName)
-				}(file.Name())
+				}(file.Name)
 			}
 			if generatedThumbnails%config.Configuration.PageStride == 0 {
 				wg.Wait() //Throttle how fast we generate thumbnails
@@ -99,12 +120,29 @@ func main() {
 	api.Throttle.Init()
 
 	//If we can, start the database
-	if config.Configuration.DBName == "" || config.Configuration.DBPassword == "" || config.Configuration.DBUser == "" || config.Configuration.DBHost == "" {
-		logging.WriteLog(logging.LogLevelCritical, "main/main", "0", logging.ResultFailure, []string{"Missing database information. (Instance, User, Password?)"})
+	if dbConfigErr := validateDatabaseConfig(); dbConfigErr != nil {
+		logging.WriteLog(logging.LogLevelCritical, "main/main", "0", logging.ResultFailure, []string{dbConfigErr.Error()})
+		if *migrateDryRun {
+			return //Nothing to check without a database
+		}
 	} else {
 		//Initialize DB Connection
-		database.DBInterface = &mariadbplugin.MariaDBPlugin{}
+		switch config.Configuration.DBType {
+		case "sqlite":
+			database.DBInterface = &sqliteplugin.SQLitePlugin{}
+		case "postgres":
+			database.DBInterface = &postgresplugin.PostgresPlugin{}
+		default:
+			database.DBInterface = &mariadbplugin.MariaDBPlugin{}
+		}
+		migrate.DryRun = *migrateDryRun
 		err = database.DBInterface.InitDatabase()
+		if *migrateDryRun {
+			if err != nil && err != migrate.ErrDryRun {
+				logging.WriteLog(logging.LogLevelError, "main/main", "0", logging.ResultFailure, []string{"Failed to check pending migrations", err.Error()})
+			}
+			return //We do not want to start server if used in cli
+		}
 		if err != nil {
 			logging.WriteLog(logging.LogLevelError, "main/main", "0", logging.ResultFailure, []string{"Failed to connect to database. Will keep trying. ", err.Error()})
 			//Wait group for ending server
@@ -143,12 +181,11 @@ func main() {
 		logging.WriteLog(logging.LogLevelInfo, "main/main", "0", logging.ResultInfo, []string{"Generate dHashes flag detected. Server will not start and instead just generate dHashes. This will take some time."})
 		//We need wait group so that we don't end the application before goroutines
 		var wg sync.WaitGroup
-		//for each image in the database
-		page := uint64(0)
+		//for each image in the database, including those in the trash or waiting for approval
+		lastID := uint64(0)
 		processedImages := uint64(0)
 		for true {
-			images, maxCount, err := database.DBInterface.SearchImages([]interfaces.TagInformation{}, page, config.Configuration.PageStride)
-			page += config.Configuration.PageStride
+			images, err := database.DBInterface.GetAllImages(lastID, config.Configuration.PageStride)
 			if err != nil {
 				logging.WriteLog(logging.LogLevelError, "main/main", "0", logging.ResultFailure, []string{"Error processing hashes.", err.Error()})
 				break
@@ -157,7 +194,8 @@ func main() {
 				logging.WriteLog(logging.LogLevelInfo, "main/main", "0", logging.ResultInfo, []string{"Finished queing images"})
 				break
 			}
-			logging.WriteLog(logging.LogLevelInfo, "main/main", "0", logging.ResultInfo, []string{"Queing", strconv.FormatUint(page, 10), "of", strconv.FormatUint(maxCount, 10)})
+			logging.WriteLog(logging.LogLevelInfo, "main/main", "0", logging.ResultInfo, []string{"Queing after", strconv.FormatUint(lastID, 10)})
+			lastID = images[len(images)-1].ID
 			for _, nextImage := range images {
 				var dhashExists error
 				if *missingOnly {
@@ -180,60 +218,46 @@ func main() {
 
 		return //We do not want to start server if used in cli
 	}
-	if *removeOrphanFiles {
-		//Scan image directory
-		files, err := ioutil.ReadDir(config.Configuration.ImageDirectory)
-		if err != nil {
-			logging.WriteLog(logging.LogLevelCritical, "main/main", "0", logging.ResultFailure, []string{"Failed to get images from directory", err.Error()})
-			return
-		}
-		for _, file := range files {
-			if file.IsDir() {
-				continue
-			}
-			//Search database for matching image entry
-			_, err := database.DBInterface.GetImageByFileName(file.Name())
-			if err != nil && err == sql.ErrNoRows {
-				logging.WriteLog(logging.LogLevelWarning, "main/main", "0", logging.ResultInfo, []string{"Failed to get image from database, it will be deleted", file.Name()})
-				//If database entry does not exist, delete the image
-				err = os.Remove(path.Join(config.Configuration.ImageDirectory, file.Name()))
-				if err != nil {
-					logging.WriteLog(logging.LogLevelError, "main/main", "0", logging.ResultFailure, []string{"Failed to delete image", file.Name(), err.Error()})
-				}
-			} else if err != nil {
-				logging.WriteLog(logging.LogLevelError, "main/main", "0", logging.ResultFailure, []string{"Failed to get image from database due to an unexpected db error, it will be skipped", file.Name(), err.Error()})
-			}
-		}
-		//Rinse&repeat with the thumbnails
-		files, err = ioutil.ReadDir(path.Join(config.Configuration.ImageDirectory, "thumbs"))
-		if err != nil {
-			logging.WriteLog(logging.LogLevelCritical, "main/main", "0", logging.ResultFailure, []string{"Failed to get images from directory", err.Error()})
-			return
-		}
-		for _, file := range files {
-			if file.IsDir() {
-				continue
+	if *generateMetadataOnly {
+		logging.WriteLog(logging.LogLevelInfo, "main/main", "0", logging.ResultInfo, []string{"Generate metadata flag detected. Server will not start and instead just read image metadata. This may take some time."})
+		//We need wait group so that we don't end the application before goroutines
+		var wg sync.WaitGroup
+		//for each image in the database, including those in the trash or waiting for approval
+		lastID := uint64(0)
+		processedImages := uint64(0)
+		for true {
+			images, err := database.DBInterface.GetAllImages(lastID, config.Configuration.PageStride)
+			if err != nil {
+				logging.WriteLog(logging.LogLevelError, "main/main", "0", logging.ResultFailure, []string{"Error processing metadata.", err.Error()})
+				break
 			}
-			//Search database for matching image entry
-			imageName := file.Name()
-			if len(imageName) > 4 { //Strip .png to get original name
-				imageName = imageName[:len(imageName)-4]
+			if len(images) <= 0 {
+				logging.WriteLog(logging.LogLevelInfo, "main/main", "0", logging.ResultInfo, []string{"Finished queing images"})
+				break
 			}
-			_, err := database.DBInterface.GetImageByFileName(imageName)
-			if err != nil && err == sql.ErrNoRows {
-				logging.WriteLog(logging.LogLevelWarning, "main/main", "0", logging.ResultInfo, []string{"Failed to get image from database, it will be deleted", file.Name()})
-				//If database entry does not exist, delete the image
-				err = os.Remove(path.Join(path.Join(config.Configuration.ImageDirectory, "thumbs"), file.Name()))
-				if err != nil {
-					logging.WriteLog(logging.LogLevelError, "main/main", "0", logging.ResultFailure, []string{"Failed to delete thumbnail", file.Name(), err.Error()})
+			logging.WriteLog(logging.LogLevelInfo, "main/main", "0", logging.ResultInfo, []string{"Queing after", strconv.FormatUint(lastID, 10)})
+			lastID = images[len(images)-1].ID
+			for _, nextImage := range images {
+				//Images whose metadata has been read always have a MIME type
+				if *missingOnly == false || nextImage.MIMEType == "" {
+					processedImages++
+					wg.Add(1) //This magic thing will prevent program from closing before goroutines finish
+					go func(fileName string, imageID uint64) {
+						defer wg.Done()
+						routers.GenerateMetadata(fileName, imageID)
+					}(nextImage.Location, nextImage.ID)
 				}
-			} else if err != nil {
-				logging.WriteLog(logging.LogLevelError, "main/main", "0", logging.ResultFailure, []string{"Failed to get image from database due to an unexpected db error, it will be skipped", file.Name(), err.Error()})
 			}
+			wg.Wait() //This will wait for all goroutines to finish
 		}
+		logging.WriteLog(logging.LogLevelInfo, "main/main", "0", logging.ResultSuccess, []string{"Finished reading metadata of " + strconv.FormatUint(processedImages, 10) + " images."})
 
 		return //We do not want to start server if used in cli
 	}
+	if *removeOrphanFiles {
+		removeAllOrphanFiles()
+		return //We do not want to start server if used in cli
+	}
 	if *fixCollectionTags {
 		//Loop through all collections
 		page := uint64(0)
@@ -265,6 +289,40 @@ func main() {
 
 		return //We do not want to start server if used in cli
 	}
+	if *applyImplications {
+		added, err := database.DBInterface.ApplyTagImplications(0)
+		if err != nil {
+			logging.WriteLog(logging.LogLevelCritical, "main/main", "0", logging.ResultFailure, []string{"Failed to apply tag implications", err.Error()})
+			return
+		}
+		logging.WriteLog(logging.LogLevelInfo, "main/main", "0", logging.ResultSuccess, []string{"Applied tag implications, tags added", strconv.FormatUint(added, 10)})
+		return //We do not want to start server if used in cli
+	}
+	if *backupFile != "" {
+		if err := backup.CreateBackup(*backupFile); err != nil {
+			logging.WriteLog(logging.LogLevelCritical, "main/main", "0", logging.ResultFailure, []string{"Failed to create backup", err.Error()})
+			return
+		}
+		logging.WriteLog(logging.LogLevelInfo, "main/main", "0", logging.ResultSuccess, []string{"Backup written to", *backupFile})
+		return //We do not want to start server if used in cli
+	}
+	if *restoreFile != "" {
+		if err := backup.RestoreBackup(*restoreFile); err != nil {
+			logging.WriteLog(logging.LogLevelCritical, "main/main", "0", logging.ResultFailure, []string{"Failed to restore backup", err.Error()})
+			return
+		}
+		logging.WriteLog(logging.LogLevelInfo, "main/main", "0", logging.ResultSuccess, []string{"Restored backup from", *restoreFile})
+		return //We do not want to start server if used in cli
+	}
+	if *importDirectory != "" {
+		summary, err := importer.ImportDirectory(*importDirectory, importer.Options{Uploader: *importUser}, os.Stdout)
+		if err != nil {
+			logging.WriteLog(logging.LogLevelCritical, "main/main", "0", logging.ResultFailure, []string{"Failed to import", *importDirectory, err.Error()})
+			return
+		}
+		logging.WriteLog(logging.LogLevelInfo, "main/main", "0", logging.ResultSuccess, []string{"Finished import.", "Imported", strconv.FormatUint(summary.Imported, 10), "duplicates", strconv.FormatUint(summary.Duplicates, 10), "skipped", strconv.FormatUint(summary.Skipped, 10), "failed", strconv.FormatUint(summary.Failed, 10)})
+		return //We do not want to start server if used in cli
+	}
 	//Verify TLS Settings
 	if config.Configuration.UseTLS {
 		if _, err := os.Stat(config.Configuration.TLSCertPath); err != nil {
@@ -285,17 +343,24 @@ func main() {
 			renameAllImages()
 			return //We only wanted to rename
 		}
+		if *shardFilesOnly {
+			shardAllImages()
+			return //We only wanted to move files
+		}
+		//Deleted images and collections are purged once they have been in the trash for longer than TrashRetention
+		go routers.TrashPurgeLoop()
 		//Web routers
 		requestRouter.HandleFunc("/resources/{file}", routers.ResourceRouter).Methods("GET")
 		requestRouter.HandleFunc("/", routers.AccountRequiredMiddleWare(routers.RootRouter)).Methods("GET")
 		requestRouter.HandleFunc("/images", routers.AccountRequiredMiddleWare(routers.ImageQueryRouter)).Methods("GET")
+		requestRouter.HandleFunc("/favorites", routers.AccountRequiredMiddleWare(routers.FavoritesRouter)).Methods("GET")
 		requestRouter.HandleFunc("/collectionorder", routers.AccountRequiredMiddleWare(routers.CollectionImageOrderGetRouter)).Methods("GET")
 		requestRouter.HandleFunc("/collectionorder", routers.AccountRequiredMiddleWare(routers.CollectionImageOrderPostRouter)).Methods("POST")
 		requestRouter.HandleFunc("/collection", routers.AccountRequiredMiddleWare(routers.CollectionGetRouter)).Methods("GET")
 		requestRouter.HandleFunc("/collection", routers.AccountRequiredMiddleWare(routers.CollectionPostRouter)).Methods("POST")
 		requestRouter.HandleFunc("/collections", routers.AccountRequiredMiddleWare(routers.CollectionsRouter)).Methods("GET")
-		requestRouter.HandleFunc("/images/{file}", routers.AccountRequiredMiddleWare(routers.ResourceImageRouter)).Methods("GET")
-		requestRouter.HandleFunc("/thumbs/{file}", routers.AccountRequiredMiddleWare(routers.ThumbnailRouter)).Methods("GET")
+		requestRouter.HandleFunc("/images/{file:.+}", routers.AccountRequiredMiddleWare(routers.ResourceImageRouter)).Methods("GET")
+		requestRouter.HandleFunc("/thumbs/{file:.+}", routers.AccountRequiredMiddleWare(routers.ThumbnailRouter)).Methods("GET")
 		requestRouter.HandleFunc("/image", routers.AccountRequiredMiddleWare(routers.ImageGetRouter)).Methods("GET")
 		requestRouter.HandleFunc("/image", routers.AccountRequiredMiddleWare(routers.ImagePostRouter)).Methods("POST")
 		requestRouter.HandleFunc("/uploadImage", routers.AccountRequiredMiddleWare(routers.UploadFormRouter)).Methods("GET")
@@ -309,18 +374,57 @@ func main() {
 		requestRouter.HandleFunc("/mod", routers.AccountRequiredMiddleWare(routers.ModRouter)).Methods("GET")
 		requestRouter.HandleFunc("/mod/user", routers.AccountRequiredMiddleWare(routers.ModUserGetRouter)).Methods("GET")
 		requestRouter.HandleFunc("/mod/user", routers.AccountRequiredMiddleWare(routers.ModUserPostRouter)).Methods("POST")
+		requestRouter.HandleFunc("/mod/queue", routers.AccountRequiredMiddleWare(routers.ModQueueGetRouter)).Methods("GET")
+		requestRouter.HandleFunc("/mod/queue", routers.AccountRequiredMiddleWare(routers.ModQueuePostRouter)).Methods("POST")
+		requestRouter.HandleFunc("/mod/reports", routers.AccountRequiredMiddleWare(routers.ModReportsGetRouter)).Methods("GET")
+		requestRouter.HandleFunc("/mod/reports", routers.AccountRequiredMiddleWare(routers.ModReportsPostRouter)).Methods("POST")
+		requestRouter.HandleFunc("/mod/bans", routers.AccountRequiredMiddleWare(routers.ModBansGetRouter)).Methods("GET")
+		requestRouter.HandleFunc("/mod/bans", routers.AccountRequiredMiddleWare(routers.ModBansPostRouter)).Methods("POST")
+		requestRouter.HandleFunc("/report", routers.AccountRequiredMiddleWare(routers.ReportPostRouter)).Methods("POST")
+		requestRouter.HandleFunc("/reports", routers.AccountRequiredMiddleWare(routers.ReportsGetRouter)).Methods("GET")
+		requestRouter.HandleFunc("/trash", routers.AccountRequiredMiddleWare(routers.TrashGetRouter)).Methods("GET")
+		requestRouter.HandleFunc("/trash", routers.AccountRequiredMiddleWare(routers.TrashPostRouter)).Methods("POST")
 
 		//API routers
 		requestRouter.HandleFunc("/api/Collection/{CollectionID}", api.CollectionGetAPIRouter).Methods("GET")
 		requestRouter.HandleFunc("/api/Collection/{CollectionID}", api.CollectionDeleteAPIRouter).Methods("DELETE")
+		requestRouter.HandleFunc("/api/Collection/{CollectionID}/Restore", api.CollectionRestoreAPIRouter).Methods("POST")
 		requestRouter.HandleFunc("/api/Collections", api.CollectionsGetAPIRouter).Methods("GET")
 		//
 		requestRouter.HandleFunc("/api/Tag/{TagID}", api.TagGetAPIRouter).Methods("GET")
 		requestRouter.HandleFunc("/api/Tag/{TagID}", api.TagDeleteAPIRouter).Methods("DELETE")
+		requestRouter.HandleFunc("/api/Tag/{TagID}/Implications", api.TagImplicationsGetAPIRouter).Methods("GET")
+		requestRouter.HandleFunc("/api/Tag/{TagID}/Implications", api.TagImplicationsPostAPIRouter).Methods("POST")
+		requestRouter.HandleFunc("/api/Tag/{TagID}/Implications/{ImpliedTagID}", api.TagImplicationDeleteAPIRouter).Methods("DELETE")
 		requestRouter.HandleFunc("/api/Tags", api.TagsGetAPIRouter).Methods("GET")
 		//
 		requestRouter.HandleFunc("/api/Image/{ImageID}", api.ImageGetAPIRouter).Methods("GET")
 		requestRouter.HandleFunc("/api/Image/{ImageID}", api.ImageDeleteAPIRouter).Methods("DELETE")
+		requestRouter.HandleFunc("/api/Image/{ImageID}/Restore", api.ImageRestoreAPIRouter).Methods("POST")
+		requestRouter.HandleFunc("/api/Image/{ImageID}/File", api.ImageFilePutAPIRouter).Methods("PUT")
+		requestRouter.HandleFunc("/api/Image/{ImageID}/Favorite", api.ImageFavoritePostAPIRouter).Methods("POST")
+		requestRouter.HandleFunc("/api/Image/{ImageID}/Favorite", api.ImageFavoriteDeleteAPIRouter).Methods("DELETE")
+		requestRouter.HandleFunc("/api/Image/{ImageID}/Revisions", api.ImageRevisionsGetAPIRouter).Methods("GET")
+		requestRouter.HandleFunc("/api/Image/{ImageID}/Revisions/{RevisionID}/Restore", api.ImageRevisionRestoreAPIRouter).Methods("POST")
+		requestRouter.HandleFunc("/api/Image/{ImageID}/TagHistory", api.ImageTagHistoryGetAPIRouter).Methods("GET")
+		requestRouter.HandleFunc("/api/Image/{ImageID}/TagHistory/{ChangeID}/Revert", api.ImageTagHistoryRevertAPIRouter).Methods("POST")
+		requestRouter.HandleFunc("/api/Image/{ImageID}/Comments", api.CommentsGetAPIRouter).Methods("GET")
+		requestRouter.HandleFunc("/api/Image/{ImageID}/Comments", api.CommentsPostAPIRouter).Methods("POST")
+		requestRouter.HandleFunc("/api/Collection/{CollectionID}/Comments", api.CommentsGetAPIRouter).Methods("GET")
+		requestRouter.HandleFunc("/api/Collection/{CollectionID}/Comments", api.CommentsPostAPIRouter).Methods("POST")
+		requestRouter.HandleFunc("/api/Comment/{CommentID}", api.CommentGetAPIRouter).Methods("GET")
+		requestRouter.HandleFunc("/api/Comment/{CommentID}", api.CommentPutAPIRouter).Methods("PUT")
+		requestRouter.HandleFunc("/api/Comment/{CommentID}", api.CommentDeleteAPIRouter).Methods("DELETE")
+		requestRouter.HandleFunc("/api/Comment/{CommentID}/Restore", api.CommentRestoreAPIRouter).Methods("POST")
+		requestRouter.HandleFunc("/api/Comment/{CommentID}/Revisions", api.CommentRevisionsGetAPIRouter).Methods("GET")
+		requestRouter.HandleFunc("/api/Image/{ImageID}/Notes", api.NotesGetAPIRouter).Methods("GET")
+		requestRouter.HandleFunc("/api/Image/{ImageID}/Notes", api.NotesPostAPIRouter).Methods("POST")
+		requestRouter.HandleFunc("/api/Image/{ImageID}/Notes/Rotate", api.NotesRotateAPIRouter).Methods("POST")
+		requestRouter.HandleFunc("/api/Note/{NoteID}", api.NoteGetAPIRouter).Methods("GET")
+		requestRouter.HandleFunc("/api/Note/{NoteID}", api.NotePutAPIRouter).Methods("PUT")
+		requestRouter.HandleFunc("/api/Note/{NoteID}", api.NoteDeleteAPIRouter).Methods("DELETE")
+		requestRouter.HandleFunc("/api/Note/{NoteID}/Revisions", api.NoteRevisionsGetAPIRouter).Methods("GET")
+		requestRouter.HandleFunc("/api/Note/{NoteID}/Revisions/{RevisionID}/Restore", api.NoteRevisionRestoreAPIRouter).Methods("POST")
 		requestRouter.HandleFunc("/api/Image", api.ImagePostAPIRouter).Methods("POST")
 		requestRouter.HandleFunc("/api/Images", api.ImagesGetAPIRouter).Methods("GET")
 		//
@@ -331,6 +435,16 @@ func main() {
 		requestRouter.HandleFunc("/api/Logon", api.LogonAPIRouter).Methods("POST")
 		requestRouter.HandleFunc("/api/Logout", api.LogoutAPIRouter).Methods("POST")
 		requestRouter.HandleFunc("/api/Users", api.UsersAPIRouter).Methods("GET")
+		//Saved searches
+		requestRouter.HandleFunc("/api/SavedSearches", api.SavedSearchesGetAPIRouter).Methods("GET")
+		requestRouter.HandleFunc("/api/SavedSearches", api.SavedSearchesPostAPIRouter).Methods("POST")
+		requestRouter.HandleFunc("/api/SavedSearches/{SavedSearchID}", api.SavedSearchGetAPIRouter).Methods("GET")
+		requestRouter.HandleFunc("/api/SavedSearches/{SavedSearchID}", api.SavedSearchPutAPIRouter).Methods("PUT")
+		requestRouter.HandleFunc("/api/SavedSearches/{SavedSearchID}", api.SavedSearchDeleteAPIRouter).Methods("DELETE")
+		//Reports
+		requestRouter.HandleFunc("/api/Reports", api.ReportsGetAPIRouter).Methods("GET")
+		requestRouter.HandleFunc("/api/Reports", api.ReportsPostAPIRouter).Methods("POST")
+		requestRouter.HandleFunc("/api/Report/{ReportID}", api.ReportGetAPIRouter).Methods("GET")
 		//Autocomplete helpers
 		requestRouter.HandleFunc("/api/TagName", api.TagNameAPIRouter).Methods("GET")
 		requestRouter.HandleFunc("/api/CollectionName", api.CollectionNameAPIRouter).Methods("GET")
@@ -399,9 +513,71 @@ func fixMissingConfigs() {
 	if config.Configuration.PageStride <= 0 {
 		config.Configuration.PageStride = 30
 	}
+	if config.Configuration.MaxWildcardTags <= 0 {
+		config.Configuration.MaxWildcardTags = 100
+	}
+	if config.Configuration.FFProbePath == "" && config.Configuration.FFMPEGPath != "" {
+		//FFProbe ships alongside FFMPEG
+		config.Configuration.FFProbePath = filepath.Join(filepath.Dir(config.Configuration.FFMPEGPath), strings.Replace(filepath.Base(config.Configuration.FFMPEGPath), "ffmpeg", "ffprobe", 1))
+	}
+	if config.Configuration.TrashRetention.Nanoseconds() <= 0 {
+		config.Configuration.TrashRetention = 30 * 24 * time.Hour
+	}
+	if config.Configuration.DBType == "" {
+		config.Configuration.DBType = "mariadb"
+	}
+	if config.Configuration.DBPath == "" {
+		config.Configuration.DBPath = "." + string(filepath.Separator) + "gib.db"
+	}
+	if config.Configuration.DBSSLMode == "" {
+		config.Configuration.DBSSLMode = "disable"
+	}
+	if config.Configuration.StorageType == "" {
+		config.Configuration.StorageType = "local"
+	}
+	if config.Configuration.S3Region == "" {
+		config.Configuration.S3Region = "us-east-1"
+	}
 	config.CreateSessionStore()
 }
 
+//validateDatabaseConfig returns an error describing what is missing from the database configuration, or nil if it is usable
+func validateDatabaseConfig() error {
+	switch config.Configuration.DBType {
+	case "mariadb", "postgres":
+		if config.Configuration.DBName == "" || config.Configuration.DBPassword == "" || config.Configuration.DBUser == "" || config.Configuration.DBHost == "" {
+			return errors.New("Missing database information. (Instance, User, Password?)")
+		}
+	case "sqlite":
+		if config.Configuration.DBPath == "" {
+			return errors.New("Missing database information. (DBPath?)")
+		}
+	default:
+		return errors.New("Unknown DBType " + config.Configuration.DBType + ", expected mariadb, postgres or sqlite")
+	}
+	return nil
+}
+
+//initStorage selects the storage plugin for images and thumbnails, and prepares it for use
+func initStorage() error {
+	switch config.Configuration.StorageType {
+	case "local":
+		storage.StorageInterface = &localstorageplugin.LocalStoragePlugin{Directory: config.Configuration.ImageDirectory}
+	case "s3":
+		storage.StorageInterface = &s3storageplugin.S3StoragePlugin{
+			Endpoint:        config.Configuration.S3Endpoint,
+			Region:          config.Configuration.S3Region,
+			Bucket:          config.Configuration.S3Bucket,
+			AccessKeyID:     config.Configuration.S3AccessKeyID,
+			SecretAccessKey: config.Configuration.S3SecretAccessKey,
+			UseSSL:          config.Configuration.S3UseSSL,
+		}
+	default:
+		return errors.New("Unknown StorageType " + config.Configuration.StorageType + ", expected local or s3")
+	}
+	return storage.StorageInterface.InitStorage()
+}
+
 func badConfigServerListenAndServe(serverEndedWG *sync.WaitGroup, server *http.Server) {
 	defer serverEndedWG.Done()
 	logging.WriteLog(logging.LogLevelInfo, "main/main", "0", logging.ResultInfo, []string{"Temp server now listening"})
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"go-image-board/database"
+	"go-image-board/logging"
+	"go-image-board/plugins"
+	"go-image-board/plugins/localstorageplugin"
+	"go-image-board/plugins/memoryplugin"
+	"go-image-board/storage"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestMain(m *testing.M) {
+	logging.LogInterface = &plugins.STDLog{}
+	logging.LogInterface.Init(-1, "", "")
+	os.Exit(m.Run())
+}
+
+//useEmptySite switches to an empty in-memory database and local storage directory, returning the ID of an admin to upload as
+func useEmptySite(t *testing.T) uint64 {
+	t.Helper()
+	database.DBInterface = &memoryplugin.MemoryPlugin{PasswordHashCost: bcrypt.MinCost}
+	if err := database.DBInterface.InitDatabase(); err != nil {
+		t.Fatal(err)
+	}
+	storage.StorageInterface = &localstorageplugin.LocalStoragePlugin{Directory: t.TempDir()}
+	if err := storage.StorageInterface.InitStorage(); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.DBInterface.CreateUser("admin", []byte("adminpass"), "admin@example.com", 65535); err != nil {
+		t.Fatal(err)
+	}
+	adminID, err := database.DBInterface.GetUserID("admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return adminID
+}
+
+//putFiles stores each file in the current storage
+func putFiles(t *testing.T, Files ...string) {
+	t.Helper()
+	for _, name := range Files {
+		if err := storage.StorageInterface.Put(name, strings.NewReader(name), int64(len(name))); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+//storedFiles returns whether each file exists in the current storage, and its contents if it does
+func storedFiles(t *testing.T, Files ...string) map[string]string {
+	t.Helper()
+	ToReturn := make(map[string]string)
+	for _, name := range Files {
+		object, _, err := storage.StorageInterface.Get(name)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		contents, err := ioutil.ReadAll(object)
+		object.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		ToReturn[name] = string(contents)
+	}
+	return ToReturn
+}
@@ -4,6 +4,7 @@ class AutoCompleteBox {
 	constructor(TextBox, SuggestionBox) {
 		var self=this;
 		this.completionResults = [];
+		this.completionCategories = [];
 		this.textBox = TextBox;
 		this.suggestionBox = SuggestionBox;
 		this.lastSetSuggestion =-1;
@@ -41,6 +42,9 @@ class AutoCompleteBox {
             valNode.type="hidden";
             valNode.value=I;
             liNode.innerHTML = this.completionResults[I];
+            if (this.completionCategories[I]) {
+                liNode.classList.add("tagCategory-"+this.completionCategories[I]);
+            }
             liNode.appendChild(valNode);
             liNode.addEventListener('click',function (e) {self.setSuggestion(this.getElementsByTagName("input")[0].value); self.ClearSuggestionList();},false);
             ulNode.appendChild(liNode);
@@ -70,6 +74,7 @@ class AutoCompleteBox {
                 var Result = JSON.parse(xhttp.responseText);
                 //Out with the old
                 self.completionResults = [];
+                self.completionCategories = [];
                 self.lastSetSuggestion = -1;
                 //In with new
                 if (Result.Tags) {
@@ -77,6 +82,7 @@ class AutoCompleteBox {
                     ulNode.classList.add("suggestionList");
                     for (var I = 0; I<Result.Tags.length; I++) {
                         self.completionResults.push(Result.Tags[I].Name)
+                        self.completionCategories.push(Result.Tags[I].Category)
                     }
                     self.FillSuggestionList();
                 } else {
@@ -152,6 +152,55 @@ function GenerateUserSearchMenu(searchQuery, pageStride, pageOffset, maxCount, t
     document.getElementById(targetID).innerHTML = pageMenu;
 }
 
+//SelectNoteRegion lets a user drag a rectangle over the image, then fills the given note form with it in pixels of the file
+function SelectNoteRegion(overlayID, formID) {
+    var overlay = document.getElementById(overlayID);
+    var form = document.getElementById(formID);
+    var image = overlay.getElementsByTagName("img")[0];
+    form.classList.remove("displayHidden");
+    if (Number(form.elements["ImageWidth"].value) <= 0 || Number(form.elements["ImageHeight"].value) <= 0) {
+        //Dimensions are not known for older uploads, so use the file as loaded
+        form.elements["ImageWidth"].value = image.naturalWidth;
+        form.elements["ImageHeight"].value = image.naturalHeight;
+    }
+    overlay.style.cursor = "crosshair";
+    overlay.onmousedown = function(e) {
+        e.preventDefault();
+        var bounds = overlay.getBoundingClientRect();
+        var startX = Math.min(Math.max(e.clientX - bounds.left, 0), bounds.width);
+        var startY = Math.min(Math.max(e.clientY - bounds.top, 0), bounds.height);
+        var selection = document.createElement("div");
+        selection.className = "noteSelection";
+        overlay.appendChild(selection);
+        var update = function(e) {
+            var endX = Math.min(Math.max(e.clientX - bounds.left, 0), bounds.width);
+            var endY = Math.min(Math.max(e.clientY - bounds.top, 0), bounds.height);
+            selection.style.left = Math.min(startX, endX) + "px";
+            selection.style.top = Math.min(startY, endY) + "px";
+            selection.style.width = Math.abs(endX - startX) + "px";
+            selection.style.height = Math.abs(endY - startY) + "px";
+            //The overlay may be shown smaller than the file, so scale back to its dimensions
+            var scaleX = Number(form.elements["ImageWidth"].value) / bounds.width;
+            var scaleY = Number(form.elements["ImageHeight"].value) / bounds.height;
+            form.elements["X"].value = Math.round(Math.min(startX, endX) * scaleX);
+            form.elements["Y"].value = Math.round(Math.min(startY, endY) * scaleY);
+            form.elements["Width"].value = Math.max(Math.round(Math.abs(endX - startX) * scaleX), 1);
+            form.elements["Height"].value = Math.max(Math.round(Math.abs(endY - startY) * scaleY), 1);
+        };
+        document.onmousemove = update;
+        document.onmouseup = function(e) {
+            update(e);
+            document.onmousemove = null;
+            document.onmouseup = null;
+            overlay.onmousedown = null;
+            overlay.style.cursor = "";
+            overlay.removeChild(selection);
+            form.elements["Body"].select();
+        };
+    };
+    return false;
+}
+
 //Helper functions for API
 function GetImageType(path) {
     text = "";
@@ -0,0 +1,467 @@
+package importer
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go-image-board/database"
+	"go-image-board/logging"
+	"go-image-board/routers"
+	"go-image-board/storage"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//Report statuses, the first column of each line written to an import's report
+const (
+	StatusImported   = "imported"
+	StatusDuplicate  = "duplicate"
+	StatusSkipped    = "skipped"
+	StatusFailed     = "failed"
+	StatusAlias      = "alias"
+	StatusCollection = "collection"
+)
+
+//Options changes how a directory is imported
+type Options struct {
+	//Uploader is the account given posts whose uploader has no account of the same name. If empty, the SYSTEM user is used
+	Uploader string
+}
+
+//Summary counts the files an import processed
+type Summary struct {
+	Imported   uint64
+	Duplicates uint64
+	Skipped    uint64
+	Failed     uint64
+}
+
+//importer holds the state of a single ImportDirectory call
+type importer struct {
+	directory  string
+	report     io.Writer
+	summary    Summary
+	uploaderID uint64
+	//userIDs caches the account of each uploader name, 0 and false if there is none
+	userIDs map[string]userLookup
+	tagIDs  map[string]uint64
+	//listPosts are posts from export lists, keyed by file name and by md5
+	listPosts map[string]record
+	pools     poolList
+	//imageIDs maps IDs from the source booru to imported or pre-existing images
+	imageIDs map[string]uint64
+}
+
+//userLookup is a cached uploader account lookup
+type userLookup struct {
+	ID     uint64
+	Exists bool
+}
+
+//ImportDirectory imports every media file below Directory, along with any metadata exported next to it:
+//Danbooru or Gelbooru style JSON lists of posts, pools and tag aliases, gallery-dl .json sidecars and Hydrus .txt sidecars.
+//Files already on the board, by their sha256 name, are not imported again. A line is written to Report for each file
+func ImportDirectory(Directory string, Options Options, Report io.Writer) (Summary, error) {
+	run := &importer{
+		directory: Directory,
+		report:    Report,
+		userIDs:   make(map[string]userLookup),
+		tagIDs:    make(map[string]uint64),
+		listPosts: make(map[string]record),
+		imageIDs:  make(map[string]uint64),
+	}
+	if Options.Uploader != "" {
+		uploaderID, err := database.DBInterface.GetUserID(Options.Uploader)
+		if err != nil {
+			return run.summary, errors.New("Uploader " + Options.Uploader + " does not exist")
+		}
+		run.uploaderID = uploaderID
+	}
+
+	//Sort every file in to media, sidecars, export lists and everything else
+	var mediaFiles []string
+	var listFiles []string
+	var otherFiles []string
+	files := make(map[string]bool)
+	err := filepath.Walk(Directory, func(FilePath string, Info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if Info.IsDir() == false {
+			files[FilePath] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return run.summary, err
+	}
+	for filePath := range files {
+		extension := strings.ToLower(filepath.Ext(filePath))
+		sidecarOf := strings.TrimSuffix(filePath, filepath.Ext(filePath))
+		switch {
+		case routers.IsUploadableFile(filePath):
+			mediaFiles = append(mediaFiles, filePath)
+		case (extension == ".json" || extension == ".txt") && files[sidecarOf] && routers.IsUploadableFile(sidecarOf):
+			//Read along with the media file
+		case extension == ".json":
+			listFiles = append(listFiles, filePath)
+		default:
+			otherFiles = append(otherFiles, filePath)
+		}
+	}
+	sort.Strings(mediaFiles)
+	sort.Strings(listFiles)
+	sort.Strings(otherFiles)
+
+	//Export lists are read first, so aliases exist before posts are tagged
+	var aliases []record
+	for _, listFile := range listFiles {
+		list, err := readExportList(listFile)
+		if err != nil {
+			run.write(StatusFailed, listFile, "could not be read, "+err.Error())
+			run.summary.Failed++
+			continue
+		}
+		for _, listPost := range list.Posts {
+			for _, name := range listPost.fileNames() {
+				run.listPosts[strings.ToLower(name)] = listPost
+			}
+			if md5 := listPost.text("md5"); md5 != "" {
+				run.listPosts[strings.ToLower(md5)] = listPost
+			}
+		}
+		for _, listPool := range list.Pools {
+			if listPool.text("name") != "" {
+				run.pools.add(listPool)
+			}
+		}
+		aliases = append(aliases, list.Aliases...)
+	}
+	for _, alias := range aliases {
+		run.importAlias(alias)
+	}
+
+	for _, mediaFile := range mediaFiles {
+		run.importFile(mediaFile, files)
+	}
+	for _, otherFile := range otherFiles {
+		run.write(StatusSkipped, otherFile, "not a supported file")
+		run.summary.Skipped++
+	}
+	for _, importPool := range run.pools.pools {
+		run.importPool(importPool)
+	}
+	return run.summary, nil
+}
+
+//write adds a line to the report, with FilePath relative to the imported directory
+func (Import *importer) write(Status string, FilePath string, Detail string) {
+	if relativePath, err := filepath.Rel(Import.directory, FilePath); err == nil && strings.HasPrefix(relativePath, "..") == false {
+		FilePath = relativePath
+	}
+	fmt.Fprintf(Import.report, "%s\t%s\t%s\n", Status, FilePath, Detail)
+}
+
+//importAlias creates a tag alias from a Danbooru style tag alias, creating either tag if needed
+func (Import *importer) importAlias(Alias record) {
+	status := Alias.text("status")
+	antecedent := tagName(Alias.text("antecedent_name"))
+	consequent := tagName(Alias.text("consequent_name"))
+	if (status != "" && status != "active") || antecedent == "" || consequent == "" || antecedent == consequent {
+		return
+	}
+	aliasDescription := antecedent + " -> " + consequent
+	consequentID, err := Import.tagID(consequent, Import.uploaderID)
+	if err != nil {
+		Import.write(StatusFailed, aliasDescription, err.Error())
+		return
+	}
+	antecedentTag, err := database.DBInterface.GetTagByName(antecedent)
+	if err != nil {
+		antecedentTag.Name = antecedent
+		antecedentTag.ID, err = database.DBInterface.NewTag(antecedent, "", "", Import.uploaderID)
+		if err != nil {
+			Import.write(StatusFailed, aliasDescription, "could not create tag "+antecedent+", "+err.Error())
+			return
+		}
+	} else if antecedentTag.IsAlias {
+		Import.write(StatusSkipped, aliasDescription, antecedent+" is already an alias")
+		return
+	}
+	if err := database.DBInterface.UpdateTag(antecedentTag.ID, antecedentTag.Name, antecedentTag.Description, antecedentTag.Category, consequentID, true, Import.uploaderID); err != nil {
+		Import.write(StatusFailed, aliasDescription, err.Error())
+		return
+	}
+	Import.tagIDs[antecedent] = consequentID
+	Import.write(StatusAlias, aliasDescription, "created")
+}
+
+//tagID returns the ID of the tag named Name, following aliases, creating the tag if it does not exist
+func (Import *importer) tagID(Name string, UploaderID uint64) (uint64, error) {
+	if ID, cached := Import.tagIDs[Name]; cached {
+		return ID, nil
+	}
+	tag, err := database.DBInterface.GetTagByName(Name)
+	if err == nil {
+		if tag.IsAlias {
+			tag.ID = tag.AliasedID
+		}
+	} else {
+		tag.ID, err = database.DBInterface.NewTag(Name, "", "", UploaderID)
+		if err != nil {
+			return 0, errors.New("could not create tag " + Name + ", " + err.Error())
+		}
+	}
+	Import.tagIDs[Name] = tag.ID
+	return tag.ID, nil
+}
+
+//userID returns the account for an uploader's name, falling back to the import's uploader
+func (Import *importer) userID(Name string) (uint64, bool) {
+	if Name == "" {
+		return Import.uploaderID, true
+	}
+	lookup, cached := Import.userIDs[Name]
+	if cached == false {
+		var err error
+		lookup.ID, err = database.DBInterface.GetUserID(Name)
+		lookup.Exists = err == nil
+		Import.userIDs[Name] = lookup
+	}
+	if lookup.Exists {
+		return lookup.ID, true
+	}
+	return Import.uploaderID, false
+}
+
+//importFile imports a single media file and its metadata. Files holds every file in the directory, for finding sidecars
+func (Import *importer) importFile(FilePath string, Files map[string]bool) {
+	file, err := os.Open(FilePath)
+	if err != nil {
+		Import.write(StatusFailed, FilePath, err.Error())
+		Import.summary.Failed++
+		return
+	}
+	defer file.Close()
+	md5Hasher := md5.New()
+	hashName, err := routers.GetNewImageName(FilePath, io.TeeReader(file, md5Hasher))
+	if err != nil {
+		Import.write(StatusFailed, FilePath, err.Error())
+		Import.summary.Failed++
+		return
+	}
+
+	//Gather metadata, sidecars take priority over export lists
+	var filePost post
+	if Files[FilePath+".json"] {
+		if err := readGalleryDLSidecar(FilePath+".json", &filePost, &Import.pools); err != nil {
+			Import.write(StatusFailed, FilePath, "could not read sidecar "+filepath.Base(FilePath)+".json, "+err.Error())
+			Import.summary.Failed++
+			return
+		}
+	}
+	if listPost, found := Import.listPosts[strings.ToLower(filepath.Base(FilePath))]; found {
+		filePost.merge(listPost)
+	} else if listPost, found := Import.listPosts[hex.EncodeToString(md5Hasher.Sum(nil))]; found {
+		filePost.merge(listPost)
+	}
+	if Files[FilePath+".txt"] {
+		if err := readHydrusSidecar(FilePath+".txt", &filePost); err != nil {
+			Import.write(StatusFailed, FilePath, "could not read sidecar "+filepath.Base(FilePath)+".txt, "+err.Error())
+			Import.summary.Failed++
+			return
+		}
+	}
+
+	//Skip files that are already uploaded, but still add them to their pools
+	if existingLocation, exists := routers.FindStoredImage(hashName); exists {
+		existingImage, err := database.DBInterface.GetImageByFileName(existingLocation)
+		if err != nil {
+			Import.write(StatusDuplicate, FilePath, "already uploaded as "+existingLocation)
+		} else {
+			Import.addToPools(filePost, existingImage.ID)
+			Import.write(StatusDuplicate, FilePath, "already uploaded as ID "+strconv.FormatUint(existingImage.ID, 10))
+		}
+		Import.summary.Duplicates++
+		return
+	}
+
+	uploaderID, uploaderExists := Import.userID(filePost.Uploader)
+	var tagIDs []uint64
+	for _, tag := range filePost.Tags {
+		name := tagName(tag)
+		if name == "" {
+			continue
+		}
+		ID, err := Import.tagID(name, uploaderID)
+		if err != nil {
+			Import.write(StatusFailed, FilePath, err.Error())
+			Import.summary.Failed++
+			return
+		}
+		if containsID(tagIDs, ID) == false {
+			tagIDs = append(tagIDs, ID)
+		}
+	}
+
+	//Save image
+	fileInfo, err := file.Stat()
+	if err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	imageLocation := routers.ImageLocation(hashName)
+	if err == nil {
+		err = storage.StorageInterface.Put(imageLocation, file, fileInfo.Size())
+	}
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "importer/importFile", "0", logging.ResultFailure, []string{"Failed to save file", FilePath, err.Error()})
+		Import.write(StatusFailed, FilePath, "could not be saved, "+err.Error())
+		Import.summary.Failed++
+		return
+	}
+	imageID, err := database.DBInterface.NewImage(hashName, imageLocation, uploaderID, filePost.Source, false)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "importer/importFile", "0", logging.ResultFailure, []string{"Failed to add file to database", FilePath, err.Error()})
+		routers.RemoveImageFiles(imageLocation)
+		Import.write(StatusFailed, FilePath, "could not be added to database, "+err.Error())
+		Import.summary.Failed++
+		return
+	}
+
+	//Everything after this point is reported against an image that has already been imported
+	var problems []string
+	var name, description, rating interface{}
+	if filePost.Name != "" {
+		name = filePost.Name
+	}
+	if filePost.Description != "" {
+		description = filePost.Description
+	}
+	if filePost.Rating != "" {
+		rating = filePost.Rating
+	}
+	if name != nil || description != nil || rating != nil {
+		if err := database.DBInterface.UpdateImage(imageID, name, description, nil, rating, nil, nil); err != nil {
+			problems = append(problems, "could not set name, description or rating, "+err.Error())
+		}
+	}
+	if len(tagIDs) > 0 {
+		if err := database.DBInterface.AddTag(tagIDs, imageID, uploaderID); err != nil {
+			problems = append(problems, "could not add tags, "+err.Error())
+		}
+	}
+	if uploaderExists == false {
+		problems = append(problems, "uploader "+filePost.Uploader+" has no account")
+	}
+	routers.GenerateThumbnail(imageLocation)
+	routers.GeneratedHash(imageLocation, imageID)
+	routers.GenerateMetadata(imageLocation, imageID)
+	database.DBInterface.AddAuditLog(uploaderID, "IMAGE-IMPORT", "Imported "+filepath.Base(FilePath)+" as image "+strconv.FormatUint(imageID, 10))
+	Import.addToPools(filePost, imageID)
+
+	detail := "ID " + strconv.FormatUint(imageID, 10) + ", " + strconv.Itoa(len(tagIDs)) + " tags"
+	if len(problems) > 0 {
+		detail += ", " + strings.Join(problems, ", ")
+	}
+	Import.write(StatusImported, FilePath, detail)
+	Import.summary.Imported++
+}
+
+//addToPools records which image a post became, for pools listing it by ID and pools named in its sidecar
+func (Import *importer) addToPools(Post post, ImageID uint64) {
+	if Post.SourceID != "" {
+		Import.imageIDs[Post.SourceID] = ImageID
+	}
+	for _, reference := range Post.Pools {
+		referencedPool := Import.pools.get(reference.Name)
+		if referencedPool == nil {
+			continue
+		}
+		position := reference.Position
+		if position < 0 {
+			position = indexOf(referencedPool.PostIDs, Post.SourceID)
+		}
+		if position < 0 {
+			//Not in the pool's post list, so keep the order posts were imported in, after the listed posts
+			position = len(referencedPool.PostIDs) + len(referencedPool.members)
+		}
+		referencedPool.members = append(referencedPool.members, poolMember{Position: position, ImageID: ImageID})
+	}
+}
+
+//importPool creates a collection for a pool, or adds to the existing one, with its images in pool order
+func (Import *importer) importPool(Pool *pool) {
+	members := Pool.members
+	for position, postID := range Pool.PostIDs {
+		if imageID, found := Import.imageIDs[postID]; found {
+			members = append(members, poolMember{Position: position, ImageID: imageID})
+		}
+	}
+	sort.SliceStable(members, func(i, j int) bool {
+		return members[i].Position < members[j].Position
+	})
+
+	collection, err := database.DBInterface.GetCollectionByName(Pool.Name)
+	if err != nil {
+		if len(members) == 0 {
+			Import.write(StatusSkipped, Pool.Name, "none of the collection's posts were found")
+			return
+		}
+		collection.ID, err = database.DBInterface.NewCollection(Pool.Name, Pool.Description, Import.uploaderID)
+		if err != nil {
+			Import.write(StatusFailed, Pool.Name, "could not create collection, "+err.Error())
+			return
+		}
+	}
+	//Leave out images already in the collection, such as when an import is run again
+	var imageIDs []uint64
+	for _, member := range members {
+		if containsID(imageIDs, member.ImageID) {
+			continue
+		}
+		memberOf, err := database.DBInterface.GetCollectionsWithImage(member.ImageID)
+		if err != nil {
+			Import.write(StatusFailed, Pool.Name, "could not check collections of image "+strconv.FormatUint(member.ImageID, 10)+", "+err.Error())
+			return
+		}
+		alreadyMember := false
+		for _, existing := range memberOf {
+			alreadyMember = alreadyMember || existing.ID == collection.ID
+		}
+		if alreadyMember == false {
+			imageIDs = append(imageIDs, member.ImageID)
+		}
+	}
+	if len(imageIDs) > 0 {
+		if err := database.DBInterface.AddCollectionMember(collection.ID, imageIDs, Import.uploaderID); err != nil {
+			Import.write(StatusFailed, Pool.Name, "could not add images to collection, "+err.Error())
+			return
+		}
+	}
+	Import.write(StatusCollection, Pool.Name, "ID "+strconv.FormatUint(collection.ID, 10)+", added "+strconv.Itoa(len(imageIDs))+" images")
+}
+
+//containsID returns true if IDs contains ID
+func containsID(IDs []uint64, ID uint64) bool {
+	for _, existing := range IDs {
+		if existing == ID {
+			return true
+		}
+	}
+	return false
+}
+
+//indexOf returns the index of Value in List, or -1
+func indexOf(List []string, Value string) int {
+	for index, existing := range List {
+		if existing == Value {
+			return index
+		}
+	}
+	return -1
+}
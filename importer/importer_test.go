@@ -0,0 +1,224 @@
+package importer
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"go-image-board/database"
+	"go-image-board/interfaces"
+	"go-image-board/logging"
+	"go-image-board/plugins"
+	"go-image-board/plugins/localstorageplugin"
+	"go-image-board/plugins/memoryplugin"
+	"go-image-board/routers"
+	"go-image-board/storage"
+	"image"
+	"image/color"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestMain(m *testing.M) {
+	logging.LogInterface = &plugins.STDLog{}
+	logging.LogInterface.Init(-1, "", "")
+	os.Exit(m.Run())
+}
+
+//testPNG returns a distinct PNG for each Width
+func testPNG(t *testing.T, Width int) []byte {
+	t.Helper()
+	picture := image.NewRGBA(image.Rect(0, 0, Width, 4))
+	for x := 0; x < Width; x++ {
+		picture.Set(x, 0, color.RGBA{R: uint8(x * 16), B: 128, A: 255})
+	}
+	var data bytes.Buffer
+	if err := png.Encode(&data, picture); err != nil {
+		t.Fatal(err)
+	}
+	return data.Bytes()
+}
+
+//writeFiles writes each file below Directory, creating directories as needed
+func writeFiles(t *testing.T, Directory string, Files map[string][]byte) {
+	t.Helper()
+	for name, data := range Files {
+		filePath := filepath.Join(Directory, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filePath, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+//importedImage returns the image imported from Data
+func importedImage(t *testing.T, Data []byte) interfaces.ImageInformation {
+	t.Helper()
+	hashName, err := routers.GetNewImageName("image.png", bytes.NewReader(Data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	imageInfo, err := database.DBInterface.GetImageByFileName(hashName)
+	if err != nil {
+		t.Fatalf("image %s was not imported: %v", hashName, err)
+	}
+	return imageInfo
+}
+
+//tagNames returns the sorted names of an image's tags
+func tagNames(t *testing.T, ImageID uint64) []string {
+	t.Helper()
+	tags, err := database.DBInterface.GetImageTags(ImageID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, tag := range tags {
+		names = append(names, tag.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+//collectionOrder returns the IDs of a collection's members in order
+func collectionOrder(t *testing.T, Name string) []uint64 {
+	t.Helper()
+	collection, err := database.DBInterface.GetCollectionByName(Name)
+	if err != nil {
+		t.Fatalf("collection %s was not created: %v", Name, err)
+	}
+	members, _, err := database.DBInterface.GetCollectionMembers(collection.ID, 0, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var IDs []uint64
+	for _, member := range members {
+		IDs = append(IDs, member.ID)
+	}
+	return IDs
+}
+
+func TestImportDirectory(t *testing.T) {
+	database.DBInterface = &memoryplugin.MemoryPlugin{PasswordHashCost: bcrypt.MinCost}
+	if err := database.DBInterface.InitDatabase(); err != nil {
+		t.Fatal(err)
+	}
+	storage.StorageInterface = &localstorageplugin.LocalStoragePlugin{Directory: t.TempDir()}
+	if err := storage.StorageInterface.InitStorage(); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"admin", "importbot"} {
+		if err := database.DBInterface.CreateUser(name, []byte("password1234"), name+"@example.com", 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	adminID, _ := database.DBInterface.GetUserID("admin")
+	importBotID, _ := database.DBInterface.GetUserID("importbot")
+
+	first, second, third, fourth, fifth := testPNG(t, 1), testPNG(t, 2), testPNG(t, 3), testPNG(t, 4), testPNG(t, 5)
+	firstMD5 := md5.Sum(first)
+	secondMD5 := md5.Sum(second)
+	directory := t.TempDir()
+	writeFiles(t, directory, map[string][]byte{
+		//Danbooru, one file named by its md5 and one only matched by its contents
+		"danbooru/" + hex.EncodeToString(firstMD5[:]) + ".png": first,
+		"danbooru/download.png": second,
+		"danbooru/posts.json": []byte(`[
+			{"id": 10, "md5": "` + hex.EncodeToString(firstMD5[:]) + `", "file_ext": "png", "tag_string": "cat blue_sky re:zero", "rating": "s", "source": "http://example.com/10", "uploader_name": "admin"},
+			{"id": 11, "md5": "` + hex.EncodeToString(secondMD5[:]) + `", "file_ext": "png", "tag_string": "cat", "rating": "e", "uploader_name": "nobody"}
+		]`),
+		"danbooru/pools.json":   []byte(`[{"id": 1, "name": "Cats", "description": "All cats", "post_ids": [11, 10]}]`),
+		"danbooru/aliases.json": []byte(`[{"antecedent_name": "kitty", "consequent_name": "cat", "status": "active"}, {"antecedent_name": "old", "consequent_name": "new", "status": "deleted"}]`),
+		//gallery-dl, downloaded from a pool out of order
+		"gallery-dl/c.png":      third,
+		"gallery-dl/c.png.json": []byte(`{"id": 5, "title": "Three", "description": "The third", "tags": ["Long Hair", "kitty"], "rating": "questionable", "owner": "admin", "pool": {"id": 3, "name": "Series"}, "num": 2}`),
+		"gallery-dl/d.png":      fourth,
+		"gallery-dl/d.png.json": []byte(`{"id": 6, "tags": "short_hair", "pool": {"id": 3, "name": "Series"}, "num": 1}`),
+		//Hydrus
+		"hydrus/e.png":     fifth,
+		"hydrus/e.png.txt": []byte("title:Five\ncreator:Some Artist\nrating:safe\nsource:http://example.com/5\n:odd:tag\n"),
+		"hydrus/copy.png":  first,
+		"readme.md":        []byte("Not an image"),
+	})
+
+	var report bytes.Buffer
+	summary, err := ImportDirectory(directory, Options{Uploader: "importbot"}, &report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary != (Summary{Imported: 5, Duplicates: 1, Skipped: 1}) {
+		t.Errorf("summary = %+v\n%s", summary, report.String())
+	}
+	for _, line := range []string{"duplicate\t" + filepath.Join("hydrus", "copy.png"), "skipped\treadme.md", "alias\tkitty -> cat", "uploader nobody has no account"} {
+		if strings.Contains(report.String(), line) == false {
+			t.Errorf("report is missing %q\n%s", line, report.String())
+		}
+	}
+
+	firstImage := importedImage(t, first)
+	if firstImage.Rating != "safe" || firstImage.Source != "http://example.com/10" || firstImage.UploaderID != adminID {
+		t.Errorf("first image = %+v", firstImage)
+	}
+	if tags := strings.Join(tagNames(t, firstImage.ID), " "); tags != "blue_sky cat re_zero" {
+		t.Errorf("first image tags = %s", tags)
+	}
+	secondImage := importedImage(t, second)
+	if secondImage.Rating != "explicit" || secondImage.UploaderID != importBotID {
+		t.Errorf("second image = %+v", secondImage)
+	}
+	thirdImage := importedImage(t, third)
+	if thirdImage.Name != "Three" || thirdImage.Description != "The third" || thirdImage.Rating != "questionable" {
+		t.Errorf("third image = %+v", thirdImage)
+	}
+	if tags := strings.Join(tagNames(t, thirdImage.ID), " "); tags != "cat long_hair" {
+		t.Errorf("third image tags, with kitty aliased to cat = %s", tags)
+	}
+	fifthImage := importedImage(t, fifth)
+	if fifthImage.Name != "Five" || fifthImage.Rating != "safe" || fifthImage.Source != "http://example.com/5" {
+		t.Errorf("fifth image = %+v", fifthImage)
+	}
+	if tags := strings.Join(tagNames(t, fifthImage.ID), " "); tags != "odd_tag some_artist" {
+		t.Errorf("fifth image tags = %s", tags)
+	}
+	if _, err := database.DBInterface.GetTagByName("new"); err == nil {
+		t.Error("an inactive alias was imported")
+	}
+	if _, err := storage.StorageInterface.Stat(storage.ThumbnailName(fifthImage.Location)); err != nil {
+		t.Errorf("thumbnail was not generated: %v", err)
+	}
+
+	cats := collectionOrder(t, "Cats")
+	if len(cats) != 2 || cats[0] != secondImage.ID || cats[1] != firstImage.ID {
+		t.Errorf("Cats = %v, want [%d %d]", cats, secondImage.ID, firstImage.ID)
+	}
+	fourthImage := importedImage(t, fourth)
+	series := collectionOrder(t, "Series")
+	if len(series) != 2 || series[0] != fourthImage.ID || series[1] != thirdImage.ID {
+		t.Errorf("Series = %v, want [%d %d]", series, fourthImage.ID, thirdImage.ID)
+	}
+
+	//Importing again finds every file already uploaded, and does not add them to their collections twice
+	report.Reset()
+	summary, err = ImportDirectory(directory, Options{Uploader: "importbot"}, &report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary != (Summary{Duplicates: 6, Skipped: 1}) {
+		t.Errorf("second summary = %+v\n%s", summary, report.String())
+	}
+	if cats := collectionOrder(t, "Cats"); len(cats) != 2 {
+		t.Errorf("Cats after importing again = %v", cats)
+	}
+
+	if _, err := ImportDirectory(directory, Options{Uploader: "missing"}, &report); err == nil {
+		t.Error("importing as a user that does not exist succeeded")
+	}
+}
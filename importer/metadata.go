@@ -0,0 +1,326 @@
+package importer
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+)
+
+//regexTagName matches the characters the database plugins replace when creating a tag
+var regexTagName = regexp.MustCompile("[^a-z0-9_-]+")
+
+//ratingNames expands the single letter ratings used by Danbooru and Gelbooru
+var ratingNames = map[string]string{
+	"g": "general",
+	"s": "safe",
+	"q": "questionable",
+	"e": "explicit",
+}
+
+//record is a JSON object from an export. Each format names its fields differently, so fields are looked up by any of their known names
+type record map[string]interface{}
+
+//text returns the first of Names that has a non empty string or number value
+func (Record record) text(Names ...string) string {
+	for _, name := range Names {
+		switch value := Record[name].(type) {
+		case string:
+			if strings.TrimSpace(value) != "" {
+				return strings.TrimSpace(value)
+			}
+		case json.Number:
+			return value.String()
+		}
+	}
+	return ""
+}
+
+//tags returns every tag in the record, from Danbooru's tag_string or a tags field holding a string, list, or lists keyed by category
+func (Record record) tags() []string {
+	var toReturn []string
+	toReturn = append(toReturn, strings.Fields(Record.text("tag_string"))...)
+	switch value := Record["tags"].(type) {
+	case string:
+		toReturn = append(toReturn, strings.Fields(value)...)
+	case []interface{}:
+		toReturn = append(toReturn, stringList(value)...)
+	case map[string]interface{}:
+		for _, category := range value {
+			if list, isList := category.([]interface{}); isList {
+				toReturn = append(toReturn, stringList(list)...)
+			}
+		}
+	}
+	return toReturn
+}
+
+//stringList returns the strings in List, ignoring anything else
+func stringList(List []interface{}) []string {
+	var toReturn []string
+	for _, value := range List {
+		if text, isString := value.(string); isString {
+			toReturn = append(toReturn, text)
+		}
+	}
+	return toReturn
+}
+
+//poolReference places a post in a pool. Position is -1 when the pool's own post list decides the order
+type poolReference struct {
+	Name     string
+	Position int
+}
+
+//post is everything known about one media file, merged from each export that describes it
+type post struct {
+	SourceID    string
+	Name        string
+	Description string
+	Rating      string
+	Source      string
+	Uploader    string
+	Tags        []string
+	Pools       []poolReference
+}
+
+//merge adds the fields of Record that are not already known
+func (Post *post) merge(Record record) {
+	setIfEmpty(&Post.SourceID, Record.text("id"))
+	setIfEmpty(&Post.Name, Record.text("title"))
+	setIfEmpty(&Post.Description, Record.text("description"))
+	setIfEmpty(&Post.Rating, ratingName(Record.text("rating")))
+	setIfEmpty(&Post.Source, Record.text("source"))
+	setIfEmpty(&Post.Uploader, Record.text("uploader_name", "uploader", "owner"))
+	Post.Tags = append(Post.Tags, Record.tags()...)
+}
+
+//setIfEmpty sets Field to Value, unless Field already has a value
+func setIfEmpty(Field *string, Value string) {
+	if *Field == "" {
+		*Field = Value
+	}
+}
+
+//ratingName returns the rating used by this board for a rating from an export
+func ratingName(Rating string) string {
+	Rating = strings.ToLower(strings.TrimSpace(Rating))
+	if name, isShort := ratingNames[Rating]; isShort {
+		return name
+	}
+	return Rating
+}
+
+//tagName cleans up a tag from an export the same way the database plugins clean up new tags.
+//Colons are replaced too, otherwise tags such as re:zero would be read as metatags
+func tagName(Name string) string {
+	return strings.Trim(regexTagName.ReplaceAllString(strings.ToLower(strings.TrimSpace(Name)), "_"), "_")
+}
+
+//readHydrusSidecar merges a Hydrus sidecar, one tag per line, in to Post.
+//The title, description, rating and source namespaces set those fields. Other namespaces are dropped from the tag, a leading colon escapes a tag that contains one
+func readHydrusSidecar(FilePath string, Post *post) error {
+	file, err := os.Open(FilePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			Post.Tags = append(Post.Tags, line[1:])
+			continue
+		}
+		namespace, value := "", line
+		if index := strings.Index(line, ":"); index > 0 {
+			namespace, value = strings.ToLower(line[:index]), strings.TrimSpace(line[index+1:])
+		}
+		switch namespace {
+		case "title":
+			setIfEmpty(&Post.Name, value)
+		case "description":
+			setIfEmpty(&Post.Description, value)
+		case "rating":
+			setIfEmpty(&Post.Rating, ratingName(value))
+		case "source", "url":
+			setIfEmpty(&Post.Source, value)
+		default:
+			Post.Tags = append(Post.Tags, value)
+		}
+	}
+	return scanner.Err()
+}
+
+//readGalleryDLSidecar merges a gallery-dl metadata sidecar in to Post, including the pool it was downloaded from, if any.
+//Pools found are added to Pools
+func readGalleryDLSidecar(FilePath string, Post *post, Pools *poolList) error {
+	value, err := readJSON(FilePath)
+	if err != nil {
+		return err
+	}
+	sidecar, isObject := value.(map[string]interface{})
+	if isObject == false {
+		return errors.New("expected a JSON object")
+	}
+	Post.merge(record(sidecar))
+	if poolObject, hasPool := sidecar["pool"].(map[string]interface{}); hasPool {
+		poolRecord := record(poolObject)
+		name := poolRecord.text("name")
+		if name == "" {
+			return nil
+		}
+		Pools.add(poolRecord)
+		position := -1
+		if _, hasPostIDs := poolRecord["post_ids"]; hasPostIDs == false {
+			//Without the pool's post list, gallery-dl numbers posts in pool order
+			if number, isNumber := sidecar["num"].(json.Number); isNumber {
+				if parsed, err := number.Int64(); err == nil {
+					position = int(parsed)
+				}
+			}
+		}
+		Post.Pools = append(Post.Pools, poolReference{Name: name, Position: position})
+	}
+	return nil
+}
+
+//exportList is the contents of a Danbooru or Gelbooru style JSON export, which may mix posts, pools and tag aliases
+type exportList struct {
+	Posts   []record
+	Pools   []record
+	Aliases []record
+}
+
+//readExportList reads a JSON array of posts, pools or tag aliases. Gelbooru's API wraps its array in an object, so a post or posts field is also accepted
+func readExportList(FilePath string) (exportList, error) {
+	var toReturn exportList
+	value, err := readJSON(FilePath)
+	if err != nil {
+		return toReturn, err
+	}
+	if object, isObject := value.(map[string]interface{}); isObject {
+		if posts, hasPosts := object["posts"]; hasPosts {
+			value = posts
+		} else if posts, hasPosts := object["post"]; hasPosts {
+			value = posts
+		} else {
+			value = []interface{}{object}
+		}
+	}
+	list, isList := value.([]interface{})
+	if isList == false {
+		return toReturn, errors.New("expected a JSON array")
+	}
+	for _, item := range list {
+		object, isObject := item.(map[string]interface{})
+		if isObject == false {
+			continue
+		}
+		if _, isPool := object["post_ids"]; isPool {
+			toReturn.Pools = append(toReturn.Pools, record(object))
+		} else if _, isAlias := object["antecedent_name"]; isAlias {
+			toReturn.Aliases = append(toReturn.Aliases, record(object))
+		} else {
+			toReturn.Posts = append(toReturn.Posts, record(object))
+		}
+	}
+	return toReturn, nil
+}
+
+//fileNames returns the names a post from an export list may have been saved with.
+//The md5 is not included, as files are also matched on the md5 of their contents
+func (Record record) fileNames() []string {
+	var toReturn []string
+	extension := Record.text("file_ext")
+	if extension != "" {
+		if md5 := Record.text("md5"); md5 != "" {
+			toReturn = append(toReturn, md5+"."+extension)
+		}
+		if id := Record.text("id"); id != "" {
+			toReturn = append(toReturn, id+"."+extension)
+		}
+	}
+	if fileURL := Record.text("file_url"); fileURL != "" {
+		toReturn = append(toReturn, path.Base(fileURL))
+	}
+	if image := Record.text("image"); image != "" {
+		toReturn = append(toReturn, image)
+	}
+	return toReturn
+}
+
+//readJSON decodes a JSON file, keeping numbers as json.Number so IDs are not rounded
+func readJSON(FilePath string) (interface{}, error) {
+	file, err := os.Open(FilePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	decoder := json.NewDecoder(file)
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+//pool is a pool or collection from an export
+type pool struct {
+	Name        string
+	Description string
+	//PostIDs are the IDs of the pool's posts in order, from the source booru
+	PostIDs []string
+	members []poolMember
+}
+
+//poolMember is an image that will be added to a pool
+type poolMember struct {
+	Position int
+	ImageID  uint64
+}
+
+//poolList keeps pools in the order they were first found
+type poolList struct {
+	pools []*pool
+}
+
+//add adds a pool, or fills in what was missing from one with the same name
+func (Pools *poolList) add(Record record) *pool {
+	name := Record.text("name")
+	existing := Pools.get(name)
+	if existing == nil {
+		existing = &pool{Name: name}
+		Pools.pools = append(Pools.pools, existing)
+	}
+	setIfEmpty(&existing.Description, Record.text("description"))
+	if len(existing.PostIDs) == 0 {
+		if postIDs, isList := Record["post_ids"].([]interface{}); isList {
+			for _, postID := range postIDs {
+				if number, isNumber := postID.(json.Number); isNumber {
+					existing.PostIDs = append(existing.PostIDs, number.String())
+				} else if text, isString := postID.(string); isString {
+					existing.PostIDs = append(existing.PostIDs, text)
+				}
+			}
+		}
+	}
+	return existing
+}
+
+//get returns the pool named Name, or nil
+func (Pools *poolList) get(Name string) *pool {
+	for _, existing := range Pools.pools {
+		if existing.Name == Name {
+			return existing
+		}
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package interfaces
+
+//BackupColumnType describes how a column's values are represented in a BackupRow
+type BackupColumnType int
+
+const (
+	//BackupUint values are uint64, this includes IDs and dHashes
+	BackupUint BackupColumnType = iota
+	//BackupInt values are int64
+	BackupInt
+	//BackupString values are string
+	BackupString
+	//BackupNullString values are either string or nil
+	BackupNullString
+	//BackupBool values are bool
+	BackupBool
+	//BackupTime values are time.Time
+	BackupTime
+	//BackupNullTime values are either time.Time or nil
+	BackupNullTime
+	//BackupFloat values are float64
+	BackupFloat
+)
+
+//BackupColumn is a single column of a table included in backups
+type BackupColumn struct {
+	Name string
+	Type BackupColumnType
+}
+
+//BackupTable is a table included in backups, the first column is always its ID
+type BackupTable struct {
+	Name    string
+	Columns []BackupColumn
+}
+
+//BackupRow maps column names to values, with the types given by each column's BackupColumnType
+type BackupRow map[string]interface{}
+
+//BackupTables lists every table in a backup, ordered so that rows are restored after the rows they reference.
+//CollectionTags comes before CollectionMembers and ImageTags, so the collection tags the databases maintain on insert are already present
+var BackupTables = []BackupTable{
+	{Name: "Users", Columns: []BackupColumn{
+		{"ID", BackupUint}, {"Name", BackupString}, {"EMail", BackupString}, {"PasswordHash", BackupString}, {"TokenID", BackupNullString}, {"IP", BackupNullString},
+		{"SecQuestionOne", BackupNullString}, {"SecQuestionTwo", BackupNullString}, {"SecQuestionThree", BackupNullString},
+		{"SecAnswerOne", BackupNullString}, {"SecAnswerTwo", BackupNullString}, {"SecAnswerThree", BackupNullString},
+		{"CreationTime", BackupTime}, {"Disabled", BackupBool}, {"Permissions", BackupUint}, {"SearchFilter", BackupString},
+	}},
+	{Name: "Tags", Columns: []BackupColumn{
+		{"ID", BackupUint}, {"Name", BackupString}, {"Description", BackupNullString}, {"UploaderID", BackupUint}, {"UploadTime", BackupTime}, {"AliasedID", BackupUint}, {"IsAlias", BackupBool}, {"Category", BackupString},
+	}},
+	{Name: "Images", Columns: []BackupColumn{
+		{"ID", BackupUint}, {"UploaderID", BackupUint}, {"Name", BackupString}, {"Rating", BackupNullString}, {"ScoreTotal", BackupInt}, {"ScoreAverage", BackupInt}, {"ScoreVoters", BackupInt},
+		{"Location", BackupString}, {"Source", BackupString}, {"UploadTime", BackupTime}, {"Description", BackupString},
+		{"DeletedTime", BackupNullTime}, {"DeleterID", BackupUint},
+		{"Width", BackupInt}, {"Height", BackupInt}, {"FileSize", BackupInt}, {"MIMEType", BackupString}, {"Duration", BackupFloat},
+		{"Pending", BackupBool},
+	}},
+	{Name: "ImageRevisions", Columns: []BackupColumn{
+		{"ID", BackupUint}, {"ImageID", BackupUint}, {"Location", BackupString}, {"ReplacerID", BackupUint}, {"ReplacedTime", BackupTime},
+	}},
+	{Name: "ImagedHashes", Columns: []BackupColumn{
+		{"ID", BackupUint}, {"ImageID", BackupUint}, {"vHash", BackupUint}, {"hHash", BackupUint},
+	}},
+	{Name: "ImageUserScores", Columns: []BackupColumn{
+		{"ID", BackupUint}, {"UserID", BackupUint}, {"ImageID", BackupUint}, {"Score", BackupInt}, {"CreationTime", BackupTime},
+	}},
+	{Name: "ImageUserFavorites", Columns: []BackupColumn{
+		{"ID", BackupUint}, {"UserID", BackupUint}, {"ImageID", BackupUint}, {"CreationTime", BackupTime},
+	}},
+	{Name: "AuditLogs", Columns: []BackupColumn{
+		{"ID", BackupUint}, {"UserID", BackupUint}, {"Type", BackupNullString}, {"Info", BackupString}, {"LogTime", BackupTime},
+	}},
+	{Name: "Collections", Columns: []BackupColumn{
+		{"ID", BackupUint}, {"Name", BackupString}, {"Description", BackupNullString}, {"UploaderID", BackupUint}, {"UploadTime", BackupTime}, {"DeletedTime", BackupNullTime}, {"DeleterID", BackupUint},
+	}},
+	{Name: "CollectionTags", Columns: []BackupColumn{
+		{"ID", BackupUint}, {"CollectionID", BackupUint}, {"TagID", BackupUint}, {"LinkerID", BackupUint}, {"LinkTime", BackupTime},
+	}},
+	{Name: "CollectionMembers", Columns: []BackupColumn{
+		{"ID", BackupUint}, {"ImageID", BackupUint}, {"CollectionID", BackupUint}, {"LinkerID", BackupUint}, {"LinkTime", BackupTime}, {"OrderWeight", BackupUint},
+	}},
+	{Name: "ImageTags", Columns: []BackupColumn{
+		{"ID", BackupUint}, {"ImageID", BackupUint}, {"TagID", BackupUint}, {"LinkerID", BackupUint}, {"LinkTime", BackupTime},
+	}},
+	{Name: "ImageTagHistory", Columns: []BackupColumn{
+		{"ID", BackupUint}, {"ImageID", BackupUint}, {"TagID", BackupUint}, {"UserID", BackupUint}, {"Added", BackupBool}, {"Operation", BackupString}, {"ChangeTime", BackupTime},
+	}},
+	{Name: "TagImplications", Columns: []BackupColumn{
+		{"ID", BackupUint}, {"TagID", BackupUint}, {"ImpliedTagID", BackupUint}, {"CreatorID", BackupUint}, {"CreationTime", BackupTime},
+	}},
+	{Name: "Comments", Columns: []BackupColumn{
+		{"ID", BackupUint}, {"ImageID", BackupUint}, {"CollectionID", BackupUint}, {"ParentID", BackupUint}, {"ThreadID", BackupUint}, {"UserID", BackupUint}, {"Body", BackupString},
+		{"CreationTime", BackupTime}, {"EditTime", BackupNullTime}, {"DeletedTime", BackupNullTime}, {"DeleterID", BackupUint},
+	}},
+	{Name: "CommentRevisions", Columns: []BackupColumn{
+		{"ID", BackupUint}, {"CommentID", BackupUint}, {"Body", BackupString}, {"EditorID", BackupUint}, {"EditTime", BackupTime},
+	}},
+	{Name: "Notes", Columns: []BackupColumn{
+		{"ID", BackupUint}, {"ImageID", BackupUint}, {"CreatorID", BackupUint}, {"X", BackupInt}, {"Y", BackupInt}, {"Width", BackupInt}, {"Height", BackupInt}, {"ImageWidth", BackupInt}, {"ImageHeight", BackupInt},
+		{"Body", BackupString}, {"CreationTime", BackupTime}, {"EditTime", BackupNullTime}, {"DeletedTime", BackupNullTime}, {"DeleterID", BackupUint},
+	}},
+	{Name: "NoteRevisions", Columns: []BackupColumn{
+		{"ID", BackupUint}, {"NoteID", BackupUint}, {"X", BackupInt}, {"Y", BackupInt}, {"Width", BackupInt}, {"Height", BackupInt}, {"ImageWidth", BackupInt}, {"ImageHeight", BackupInt},
+		{"Body", BackupString}, {"Deleted", BackupBool}, {"EditorID", BackupUint}, {"EditTime", BackupTime},
+	}},
+	{Name: "SavedSearches", Columns: []BackupColumn{
+		{"ID", BackupUint}, {"UserID", BackupUint}, {"Name", BackupString}, {"Query", BackupString}, {"CollectionContext", BackupBool}, {"Pinned", BackupBool}, {"ShowNewCount", BackupBool}, {"CreationTime", BackupTime}, {"LastViewed", BackupTime},
+	}},
+	{Name: "Reports", Columns: []BackupColumn{
+		{"ID", BackupUint}, {"TargetType", BackupString}, {"TargetID", BackupUint}, {"ReporterID", BackupUint}, {"Category", BackupString}, {"Reason", BackupString},
+		{"Status", BackupString}, {"ModeratorID", BackupUint}, {"Outcome", BackupString}, {"CreationTime", BackupTime}, {"UpdateTime", BackupNullTime},
+	}},
+	{Name: "Bans", Columns: []BackupColumn{
+		{"ID", BackupUint}, {"UserID", BackupUint}, {"IPRange", BackupString}, {"Reason", BackupString}, {"BannerID", BackupUint},
+		{"CreationTime", BackupTime}, {"ExpiryTime", BackupNullTime}, {"LifterID", BackupUint}, {"LiftTime", BackupNullTime},
+	}},
+}
+
+//GetBackupTable returns the BackupTable with the given name, and false if there is none
+func GetBackupTable(Name string) (BackupTable, bool) {
+	for _, table := range BackupTables {
+		if table.Name == Name {
+			return table, true
+		}
+	}
+	return BackupTable{}, false
+}
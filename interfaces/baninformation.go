@@ -0,0 +1,141 @@
+package interfaces
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"time"
+)
+
+//BanInformation is a ban on an account or on a range of IP addresses, until it expires or a moderator lifts it
+type BanInformation struct {
+	ID uint64
+	//UserID is the account banned, 0 when IPRange is banned instead
+	UserID   uint64
+	UserName string
+	//IPRange is the banned range of addresses in CIDR notation, blank for account bans
+	IPRange string
+	//Reason is shown to the banned user
+	Reason       string
+	BannerID     uint64
+	BannerName   string
+	CreationTime time.Time
+	//ExpiryTime is when the ban lifts itself, the zero time for bans that do not expire
+	ExpiryTime time.Time
+	//LifterID is the moderator that lifted the ban before it expired, and LiftTime when they did, the zero time unless it was lifted
+	LifterID uint64
+	LiftTime time.Time
+}
+
+//BanError is returned when an active ban stops a user, its message is meant to be shown to them
+type BanError struct {
+	Ban BanInformation
+}
+
+//Error returns the message of the ban
+func (Err BanError) Error() string {
+	return Err.Ban.Message()
+}
+
+//IsPermanent returns true if the ban does not expire
+func (Ban BanInformation) IsPermanent() bool {
+	return Ban.ExpiryTime.IsZero()
+}
+
+//IsActive returns true if the ban has neither been lifted nor expired at Now
+func (Ban BanInformation) IsActive(Now time.Time) bool {
+	return Ban.LiftTime.IsZero() && (Ban.IsPermanent() || Now.Before(Ban.ExpiryTime))
+}
+
+//Applies returns true if the ban is on the account UserID, or on a range containing IP
+func (Ban BanInformation) Applies(UserID uint64, IP string) bool {
+	if Ban.IPRange == "" {
+		return UserID != 0 && Ban.UserID == UserID
+	}
+	_, bannedRange, err := net.ParseCIDR(Ban.IPRange)
+	parsedIP := net.ParseIP(IP)
+	return err == nil && parsedIP != nil && bannedRange.Contains(parsedIP)
+}
+
+//Message returns the explanation shown to a banned user
+func (Ban BanInformation) Message() string {
+	message := "Your account is banned"
+	if Ban.IPRange != "" {
+		message = "Your address is banned"
+	}
+	if Ban.IsPermanent() {
+		message += " permanently"
+	} else {
+		message += " until " + Ban.ExpiryTime.UTC().Format("Jan 02, 2006 15:04 UTC")
+	}
+	return message + ". Reason: " + Ban.Reason
+}
+
+//Validate ensures a new ban is on either an account or a range of addresses, and that the range is in CIDR notation
+func (Ban BanInformation) Validate() error {
+	if (Ban.UserID == 0) == (Ban.IPRange == "") {
+		return errors.New("a ban must be on either an account or an address range")
+	}
+	if Ban.IPRange != "" {
+		if _, _, err := net.ParseCIDR(Ban.IPRange); err != nil {
+			return errors.New("the address range is not valid")
+		}
+	}
+	return nil
+}
+
+//ParseIPRange returns Range in CIDR notation, a single address becomes a range containing only that address
+func ParseIPRange(Range string) (string, error) {
+	Range = strings.TrimSpace(Range)
+	if strings.Contains(Range, "/") {
+		_, parsedRange, err := net.ParseCIDR(Range)
+		if err != nil {
+			return "", errors.New("the address range is not valid")
+		}
+		return parsedRange.String(), nil
+	}
+	parsedIP := net.ParseIP(Range)
+	if parsedIP == nil {
+		return "", errors.New("the address is not valid")
+	}
+	if parsedIP.To4() != nil {
+		return parsedIP.String() + "/32", nil
+	}
+	return parsedIP.String() + "/128", nil
+}
+
+//ContainingIPRanges returns every range in CIDR notation that contains IP, from the address alone to the whole address space, or nothing if IP is not an address
+//Bans store their range in the same notation, so these are the only ranges a ban on IP can have
+func ContainingIPRanges(IP string) []string {
+	parsedIP := net.ParseIP(IP)
+	if parsedIP == nil {
+		return nil
+	}
+	bits := 128
+	if parsedIP.To4() != nil {
+		parsedIP = parsedIP.To4()
+		bits = 32
+	}
+	var ToReturn []string
+	for ones := bits; ones >= 0; ones-- {
+		mask := net.CIDRMask(ones, bits)
+		ToReturn = append(ToReturn, (&net.IPNet{IP: parsedIP.Mask(mask), Mask: mask}).String())
+	}
+	return ToReturn
+}
+
+//FindBan returns the active ban in Bans that applies to the account UserID or to IP, preferring the one lasting longest
+func FindBan(Bans []BanInformation, UserID uint64, IP string, Now time.Time) (BanInformation, bool) {
+	var found BanInformation
+	isBanned := false
+	for _, ban := range Bans {
+		if ban.IsActive(Now) == false || ban.Applies(UserID, IP) == false {
+			continue
+		}
+		if isBanned == false || (found.IsPermanent() == false && (ban.IsPermanent() || ban.ExpiryTime.After(found.ExpiryTime))) {
+			found = ban
+			isBanned = true
+		}
+	}
+	return found, isBanned
+}
@@ -12,6 +12,11 @@ type CollectionInformation struct {
 	ID          uint64
 	UploaderID  uint64
 	UploadTime  time.Time
+	//InTrash is set when the collection has been deleted, but not yet purged. DeletedTime and DeleterID record when and by whom
+	InTrash     bool
+	DeletedTime time.Time
+	DeleterID   uint64
+	DeleterName string
 	//Members Number of members in this collection
 	Members uint64
 	//Special for images
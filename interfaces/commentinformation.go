@@ -0,0 +1,68 @@
+package interfaces
+
+import (
+	"sort"
+	"time"
+)
+
+//CommentInformation is a comment on an image, or on a collection when ImageID is 0
+type CommentInformation struct {
+	ID           uint64
+	ImageID      uint64
+	CollectionID uint64
+	//ParentID is the comment this one replies to, and ThreadID the comment that started its thread. Both are 0 for a comment starting a thread
+	ParentID     uint64
+	ThreadID     uint64
+	UserID       uint64
+	UserName     string
+	Body         string
+	CreationTime time.Time
+	//EditTime is the zero time unless the body was edited
+	EditTime time.Time
+	//Deleted comments keep their place in a thread, so replies to them still make sense, but only moderators see their body
+	Deleted     bool
+	DeletedTime time.Time
+	DeleterID   uint64
+	//Depth is how many replies deep the comment is, 0 for a comment starting a thread
+	Depth int
+}
+
+//CommentRevisionInformation is the body of a comment as it was before an edit
+type CommentRevisionInformation struct {
+	ID         uint64
+	CommentID  uint64
+	Body       string
+	EditorID   uint64
+	EditorName string
+	//EditTime is when this body was replaced
+	EditTime time.Time
+}
+
+//ThreadComments orders comments so that each is followed by its replies, oldest first, and sets their Depth
+//Comments replying to one that is not in the list are treated as starting a thread
+func ThreadComments(Comments []CommentInformation) []CommentInformation {
+	sort.Slice(Comments, func(i, j int) bool { return Comments[i].ID < Comments[j].ID })
+	present := make(map[uint64]bool)
+	for _, comment := range Comments {
+		present[comment.ID] = true
+	}
+	replies := make(map[uint64][]CommentInformation)
+	for _, comment := range Comments {
+		if present[comment.ParentID] {
+			replies[comment.ParentID] = append(replies[comment.ParentID], comment)
+		} else {
+			replies[0] = append(replies[0], comment)
+		}
+	}
+	var ToReturn []CommentInformation
+	var addThread func(ParentID uint64, Depth int)
+	addThread = func(ParentID uint64, Depth int) {
+		for _, comment := range replies[ParentID] {
+			comment.Depth = Depth
+			ToReturn = append(ToReturn, comment)
+			addThread(comment.ID, Depth+1)
+		}
+	}
+	addThread(0, 0)
+	return ToReturn
+}
@@ -1,5 +1,7 @@
 package interfaces
 
+import "time"
+
 //DBInterface is a generic interface to allow swappable databases
 type DBInterface interface {
 	////Account operations
@@ -45,6 +47,8 @@ type DBInterface interface {
 	SetImageSource(ID uint64, Source string) error
 	//SetImagedHash changes a given image's dHash
 	SetImagedHash(ID uint64, hHash uint64, vHash uint64) error
+	//SetImageMetadata changes a given image's dimensions, file size, MIME type and duration
+	SetImageMetadata(ID uint64, Metadata ImageMetadata) error
 	//GetImagedHash changes a given image's dHash
 	GetImagedHash(ID uint64) (uint64, uint64, error)
 	//GetUserFilter returns the raw string of the user's filter
@@ -55,12 +59,32 @@ type DBInterface interface {
 	GetUser(UserID uint64) (UserInformation, error)
 
 	//Image operations
-	//NewImage adds an image with the provided information and returns the id, or error
-	NewImage(ImageName string, ImageFileName string, OwnerID uint64, Source string) (uint64, error)
+	//NewImage adds an image with the provided information and returns the id, or error. Pending holds it in the moderation queue from the start
+	NewImage(ImageName string, ImageFileName string, OwnerID uint64, Source string, Pending bool) (uint64, error)
+	//GetAllImages returns the ID, Name, Location and MIMEType of up to Count images with an ID above AfterID, lowest first. Unlike SearchImages, images in the trash or waiting for approval are included
+	GetAllImages(AfterID uint64, Count uint64) ([]ImageInformation, error)
 	//UpdateImage updates properties of an image
 	UpdateImage(ImageID uint64, ImageName interface{}, ImageDescription interface{}, OwnerID interface{}, Rating interface{}, Source interface{}, Location interface{}) error
 	//DeleteImage removes an image from the db
 	DeleteImage(ImageID uint64) error
+	//TrashImage moves an image to the trash, hiding it from searches until it is restored or purged
+	TrashImage(ImageID uint64, DeleterID uint64) error
+	//RestoreImage returns an image from the trash
+	RestoreImage(ImageID uint64) error
+	//SetImagePending holds an image in the moderation queue, hiding it from searches, or publishes it
+	SetImagePending(ImageID uint64, Pending bool) error
+	//ReplaceImageFile points an image at a new file, keeping its previous Location as a revision
+	ReplaceImageFile(ImageID uint64, Location string, ReplacerID uint64) error
+	//GetImageRevisions returns the previous files of an image, newest first
+	GetImageRevisions(ImageID uint64) ([]ImageRevisionInformation, error)
+	//GetImageRevision returns a single previous file of an image
+	GetImageRevision(RevisionID uint64) (ImageRevisionInformation, error)
+	//UpdateImageRevisionLocation moves a previous file of an image to Location
+	UpdateImageRevisionLocation(RevisionID uint64, Location string) error
+	//GetImageRevisionByLocation returns the previous file of an image stored at Location
+	GetImageRevisionByLocation(Location string) (ImageRevisionInformation, error)
+	//RestoreImageRevision makes a previous file current again, the file it replaces becomes a revision in its place
+	RestoreImageRevision(RevisionID uint64, RestorerID uint64) error
 	//SearchImages performs a search for images (Returns a list of imageIDs, or error)
 	SearchImages(Tags []TagInformation, PageStart uint64, PageStride uint64) ([]ImageInformation, uint64, error)
 	//GetPrevNexImages performs a search for images (Returns a list of ImageInformations (Up to 2) and an error/nil)
@@ -83,20 +107,34 @@ type DBInterface interface {
 	//GetTagByName returns detailed information on one tag
 	GetTagByName(Name string) (TagInformation, error)
 	//Tag Operations
-	//NewTag adds a tag with the provided information
-	NewTag(Name string, Description string, UploaderID uint64) (uint64, error)
+	//NewTag adds a tag with the provided information, an empty Category is TagCategoryGeneral
+	NewTag(Name string, Description string, Category string, UploaderID uint64) (uint64, error)
 	//DeleteTag removes a tag
 	DeleteTag(TagID uint64) error
 	//AddTag adds an association of a tag to image into the association table
 	AddTag(TagID []uint64, ImageID uint64, LinkerID uint64) error
 	//RemoveTag remove a tag association
-	RemoveTag(TagID uint64, ImageID uint64) error
+	RemoveTag(TagID uint64, ImageID uint64, RemoverID uint64) error
 	//UpdateTag updates a pre-existing tag
-	UpdateTag(TagID uint64, Name string, Description string, AliasedID uint64, IsAlias bool, UploadID uint64) error
+	UpdateTag(TagID uint64, Name string, Description string, Category string, AliasedID uint64, IsAlias bool, UploadID uint64) error
 	//BulkAddTag Adds tags to images that already have another tag
 	BulkAddTag(TagID uint64, OldTagID uint64, LinkerID uint64) error
 	//ReplaceImageTags Replaces an old tag, with the new tag
 	ReplaceImageTags(OldTagID uint64, NewTagID uint64, LinkerID uint64) error
+	//GetImageTagHistory returns every tag change made to an image, newest first
+	GetImageTagHistory(ImageID uint64) ([]TagChangeInformation, error)
+	//GetUserTagHistory returns the tag changes made by a user between Since and Until, newest first
+	GetUserTagHistory(UserID uint64, Since time.Time, Until time.Time) ([]TagChangeInformation, error)
+	//UndoTagChanges reverses each change in order, skipping those already undone by later changes, returns how many were reversed
+	UndoTagChanges(Changes []TagChangeInformation, UserID uint64) (uint64, error)
+	//AddTagImplication adds a rule so that images given TagID are also given ImpliedTagID, errors if the rule would create a cycle
+	AddTagImplication(TagID uint64, ImpliedTagID uint64, CreatorID uint64) error
+	//RemoveTagImplication removes a rule added by AddTagImplication
+	RemoveTagImplication(TagID uint64, ImpliedTagID uint64) error
+	//GetTagImplications returns the rules where a tag implies, or is implied by, another tag
+	GetTagImplications(TagID uint64) ([]TagImplicationInformation, error)
+	//ApplyTagImplications adds implied tags to every image missing them, returns how many tags were added
+	ApplyTagImplications(LinkerID uint64) (uint64, error)
 	//SearchTags returns a list of tags like the provided name, but only the ID, Name, Description, and IsAlias
 	SearchTags(name string, PageStart uint64, PageStride uint64, WildcardForwardOnly bool, SortByUsage bool) ([]TagInformation, uint64, error)
 
@@ -106,12 +144,82 @@ type DBInterface interface {
 	UpdateScoreOnImage(ImageID uint64) error
 	//GetUserVoteScore Returns a user's vote on an image
 	GetUserVoteScore(UserID uint64, ImageID uint64) (int64, error)
+	//SetUserFavorite adds an image to, or removes it from, a user's favorites
+	SetUserFavorite(UserID uint64, ImageID uint64, Favorite bool) error
+	//GetUserFavorite Returns whether a user has favorited an image
+	GetUserFavorite(UserID uint64, ImageID uint64) (bool, error)
+
+	//Comments
+	//NewComment adds a comment to Comment.ImageID, or Comment.CollectionID, replying to Comment.ParentID if set. Returns its ID
+	NewComment(Comment CommentInformation) (uint64, error)
+	//GetComment returns a single comment, including deleted ones
+	GetComment(CommentID uint64) (CommentInformation, error)
+	//GetComments returns a page of the threads on an image, or on a collection when ImageID is 0, oldest first. Each thread is ordered by ThreadComments, and the count of all threads is returned
+	GetComments(ImageID uint64, CollectionID uint64, PageStart uint64, PageStride uint64) ([]CommentInformation, uint64, error)
+	//UpdateComment replaces the body of a comment, keeping the previous body as a revision
+	UpdateComment(CommentID uint64, Body string, EditorID uint64) error
+	//GetCommentRevisions returns the previous bodies of a comment, newest first
+	GetCommentRevisions(CommentID uint64) ([]CommentRevisionInformation, error)
+	//DeleteComment hides a comment, replies to it are kept
+	DeleteComment(CommentID uint64, DeleterID uint64) error
+	//RestoreComment shows a comment hidden by DeleteComment again
+	RestoreComment(CommentID uint64) error
+
+	//Notes
+	//NewNote adds a note to Note.ImageID, created by Note.CreatorID, and keeps it as the first revision. Returns its ID
+	NewNote(Note NoteInformation) (uint64, error)
+	//GetNote returns a single note, including deleted ones
+	GetNote(NoteID uint64) (NoteInformation, error)
+	//GetNotes returns the notes on an image, oldest first, deleted notes are only included if requested
+	GetNotes(ImageID uint64, IncludeDeleted bool) ([]NoteInformation, error)
+	//UpdateNote replaces the region and body of a note, shows it again if it was deleted, and keeps the result as a revision
+	UpdateNote(NoteID uint64, Region NoteRegion, Body string, EditorID uint64) error
+	//DeleteNote hides a note, keeping a revision that records the deletion
+	DeleteNote(NoteID uint64, DeleterID uint64) error
+	//GetNoteRevisions returns every version of a note, newest first
+	GetNoteRevisions(NoteID uint64) ([]NoteRevisionInformation, error)
+
+	//Reports
+	//NewReport files a report by Report.ReporterID against Report.TargetType and Report.TargetID, it starts open. Returns its ID
+	NewReport(Report ReportInformation) (uint64, error)
+	//GetReport returns a single report
+	GetReport(ReportID uint64) (ReportInformation, error)
+	//GetReports returns a page of the reports with the given status, or every report if Status is blank, oldest first, and the count of all of them
+	GetReports(Status string, PageStart uint64, PageStride uint64) ([]ReportInformation, uint64, error)
+	//GetUserReports returns a page of the reports filed by a user, newest first, and the count of all of them
+	GetUserReports(ReporterID uint64, PageStart uint64, PageStride uint64) ([]ReportInformation, uint64, error)
+	//UpdateReport sets the status of a report that is not closed, along with the moderator that changed it and their outcome
+	UpdateReport(ReportID uint64, Status string, ModeratorID uint64, Outcome string) error
+
+	//Bans
+	//NewBan bans Ban.UserID, or Ban.IPRange, by Ban.BannerID until Ban.ExpiryTime, or permanently if it is the zero time. Returns its ID
+	NewBan(Ban BanInformation) (uint64, error)
+	//GetBan returns a single ban
+	GetBan(BanID uint64) (BanInformation, error)
+	//GetActiveBans returns every ban that has neither been lifted nor expired, newest first
+	GetActiveBans() ([]BanInformation, error)
+	//GetActiveBansOn returns the bans on the account UserID, or on a range containing IP, that have neither been lifted nor expired, newest first. Use 0 for UserID to only check the address
+	GetActiveBansOn(UserID uint64, IP string) ([]BanInformation, error)
+	//LiftBan lifts a ban that has not already been lifted, before it expires
+	LiftBan(BanID uint64, LifterID uint64) error
 
 	//Maitenance
 	//InitDatabase connects to a database, and if needed, creates and or updates tables
 	InitDatabase() error
 	//AddAuditLog adds a new audit log to the db
 	AddAuditLog(UserID uint64, Type string, Info string) error
+	//ExportTables calls Row for every row of each table, in ID order, reading from a single snapshot so the tables are consistent with each other
+	ExportTables(Tables []BackupTable, Row func(Table BackupTable, Row BackupRow) error) error
+	//GetTrashedImages returns images in the trash, most recently deleted first, and the count of all images in the trash
+	GetTrashedImages(PageStart uint64, PageStride uint64) ([]ImageInformation, uint64, error)
+	//GetTrashedCollections returns collections in the trash, most recently deleted first, and the count of all collections in the trash
+	GetTrashedCollections(PageStart uint64, PageStride uint64) ([]CollectionInformation, uint64, error)
+	//GetPendingImages returns images waiting in the moderation queue that are not in the trash, oldest first, and the count of all of them
+	GetPendingImages(PageStart uint64, PageStride uint64) ([]ImageInformation, uint64, error)
+	//GetExpiredTrash returns every image and collection that has been in the trash for longer than RetentionPeriod
+	GetExpiredTrash(RetentionPeriod time.Duration) ([]ImageInformation, []CollectionInformation, error)
+	//ImportTables restores a backup in to a freshly installed database, keeping the IDs of every row. Rows is called with an Insert function for each row, and nothing is kept if either returns an error
+	ImportTables(Tables []BackupTable, Rows func(Insert func(Table BackupTable, Row BackupRow) error) error) error
 
 	//Collections
 	//NewCollection adds a collection with the provided information, returns collection ID and/or error
@@ -126,6 +234,10 @@ type DBInterface interface {
 	RemoveCollectionMember(CollectionID uint64, ImageID uint64) error
 	//DeleteCollection removes a collection
 	DeleteCollection(CollectionID uint64) error
+	//TrashCollection moves a collection to the trash, and if IncludeMembers is set, every member not already in the trash
+	TrashCollection(CollectionID uint64, DeleterID uint64, IncludeMembers bool) error
+	//RestoreCollection returns a collection from the trash, along with the members that were trashed with it
+	RestoreCollection(CollectionID uint64) error
 	//GetCollections returns a list of Collections
 	GetCollections(PageStart uint64, PageStride uint64) ([]CollectionInformation, uint64, error)
 	//GetTag return detailed information on one tag
@@ -142,4 +254,18 @@ type DBInterface interface {
 	GetCollectionTags(CollectionID uint64) ([]TagInformation, error)
 	//FixCollectionTags verifies and fixes collection tags, returns row count and error
 	FixCollectionTags(CollectionID uint64) (int64, error)
+
+	//Saved searches
+	//NewSavedSearch adds a saved search for Search.UserID, returns its ID
+	NewSavedSearch(Search SavedSearchInformation) (uint64, error)
+	//UpdateSavedSearch changes the name, query and options of a saved search, only if it belongs to Search.UserID. Returns sql.ErrNoRows otherwise
+	UpdateSavedSearch(Search SavedSearchInformation) error
+	//DeleteSavedSearch removes a saved search, only if it belongs to UserID. Returns sql.ErrNoRows otherwise
+	DeleteSavedSearch(ID uint64, UserID uint64) error
+	//GetSavedSearch returns a single saved search, whoever it belongs to
+	GetSavedSearch(ID uint64) (SavedSearchInformation, error)
+	//GetSavedSearches returns every saved search belonging to a user, ordered by name
+	GetSavedSearches(UserID uint64) ([]SavedSearchInformation, error)
+	//SetSavedSearchViewed records that a saved search was opened now, so new results are counted from here
+	SetSavedSearchViewed(ID uint64) error
 }
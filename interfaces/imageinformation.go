@@ -18,16 +18,78 @@ type ImageInformation struct {
 	ScoreTotal      int64
 	ScoreVoters     int64
 	UsersVotedScore int64
-	Source          string
-	SourceIsURL     bool
+	//FavoriteCount is how many users have favorited the image, UsersFavorite is whether the viewing user has
+	FavoriteCount int64
+	UsersFavorite bool
+	Source        string
+	SourceIsURL   bool
+	//InTrash is set when the image has been deleted, but not yet purged. DeletedTime and DeleterID record when and by whom
+	InTrash     bool
+	DeletedTime time.Time
+	DeleterID   uint64
+	DeleterName string
+	//Pending is set while an upload waits in the moderation queue, it is then only shown to the uploader and moderators
+	Pending bool
+	//Media metadata, captured when the file is uploaded. These are zero, and MIMEType empty, until captured
+	Width    int64
+	Height   int64
+	FileSize int64
+	MIMEType string
+	//Duration of video and audio, in seconds
+	Duration float64
 	//Special for collections
 	OrderInCollection uint64                  //Should be used in overview of a single collection
 	MemberCollections []CollectionInformation //Should be used in view of single image (For navigation of collections it's a member of)
 }
 
+//ImageRevisionInformation describes a previous file of an image, kept when the file is replaced
+type ImageRevisionInformation struct {
+	ID           uint64
+	ImageID      uint64
+	Location     string
+	ReplacerID   uint64
+	ReplacerName string
+	ReplacedTime time.Time
+}
+
+//ImageMetadata describes the media file of an image, as read from the file itself
+type ImageMetadata struct {
+	Width    int64
+	Height   int64
+	FileSize int64
+	MIMEType string
+	//Duration of video and audio, in seconds
+	Duration float64
+}
+
 //ImagedHash conveniently contains the vertical and horizontal dHashes of an image
 type ImagedHash struct {
 	ImagehHash          uint64
 	ImagevHash          uint64
 	SimilarityThreshold uint64
 }
+
+//Keys an image search can be sorted by, using the order metatag
+const (
+	//ImageOrderID sorts by upload order, this is the default
+	ImageOrderID = "id"
+	//ImageOrderScore sorts by average score
+	ImageOrderScore = "score"
+	//ImageOrderTotalScore sorts by the sum of all scores
+	ImageOrderTotalScore = "totalscore"
+	//ImageOrderScoreVoters sorts by how many users voted
+	ImageOrderScoreVoters = "scorevoters"
+	//ImageOrderUploaded sorts by upload time
+	ImageOrderUploaded = "uploaded"
+	//ImageOrderTagCount sorts by how many tags an image has
+	ImageOrderTagCount = "tagcount"
+	//ImageOrderRandom shuffles images, the same Seed always gives the same order
+	ImageOrderRandom = "random"
+)
+
+//ImageOrder is the MetaValue of the Order metatag. Images with the same value are sorted by ID in the same direction
+type ImageOrder struct {
+	Key       string
+	Ascending bool
+	Seed      int64
+}
@@ -0,0 +1,101 @@
+package interfaces
+
+import (
+	"errors"
+	"time"
+)
+
+//NoteRegion is a rectangle on an image, in pixels of the file it was placed on.
+//ImageWidth and ImageHeight are the dimensions of that file, so the region can be scaled to any later file or display size
+type NoteRegion struct {
+	X           int64
+	Y           int64
+	Width       int64
+	Height      int64
+	ImageWidth  int64
+	ImageHeight int64
+}
+
+//NoteInformation is a note attached to a region of an image
+type NoteInformation struct {
+	ID          uint64
+	ImageID     uint64
+	CreatorID   uint64
+	CreatorName string
+	NoteRegion
+	Body         string
+	CreationTime time.Time
+	//EditTime is the zero time unless the note was changed after it was created
+	EditTime    time.Time
+	Deleted     bool
+	DeletedTime time.Time
+	DeleterID   uint64
+}
+
+//NoteRevisionInformation is a version of a note, one is kept each time a note is created, changed or deleted
+type NoteRevisionInformation struct {
+	ID     uint64
+	NoteID uint64
+	NoteRegion
+	Body       string
+	Deleted    bool
+	EditorID   uint64
+	EditorName string
+	EditTime   time.Time
+}
+
+//Validate returns an error if the region is empty, or does not fit inside its image
+func (Region NoteRegion) Validate() error {
+	if Region.ImageWidth <= 0 || Region.ImageHeight <= 0 {
+		return errors.New("the dimensions of the image are required")
+	}
+	if Region.Width <= 0 || Region.Height <= 0 {
+		return errors.New("the width and height of a note must be positive")
+	}
+	if Region.X < 0 || Region.Y < 0 || Region.X+Region.Width > Region.ImageWidth || Region.Y+Region.Height > Region.ImageHeight {
+		return errors.New("the note must be inside the image")
+	}
+	return nil
+}
+
+//Rotate returns the region on the same image turned clockwise by Degrees, which must be 0, 90, 180 or 270
+func (Region NoteRegion) Rotate(Degrees int64) (NoteRegion, error) {
+	switch Degrees {
+	case 0:
+		return Region, nil
+	case 90:
+		return NoteRegion{X: Region.ImageHeight - Region.Y - Region.Height, Y: Region.X, Width: Region.Height, Height: Region.Width, ImageWidth: Region.ImageHeight, ImageHeight: Region.ImageWidth}, nil
+	case 180:
+		return NoteRegion{X: Region.ImageWidth - Region.X - Region.Width, Y: Region.ImageHeight - Region.Y - Region.Height, Width: Region.Width, Height: Region.Height, ImageWidth: Region.ImageWidth, ImageHeight: Region.ImageHeight}, nil
+	case 270:
+		return NoteRegion{X: Region.Y, Y: Region.ImageWidth - Region.X - Region.Width, Width: Region.Height, Height: Region.Width, ImageWidth: Region.ImageHeight, ImageHeight: Region.ImageWidth}, nil
+	}
+	return Region, errors.New("notes can only be rotated by 90, 180 or 270 degrees")
+}
+
+//LeftPercent returns X as a percentage of the image width, for placing the note over the image at any size
+func (Region NoteRegion) LeftPercent() float64 {
+	return percentOf(Region.X, Region.ImageWidth)
+}
+
+//TopPercent returns Y as a percentage of the image height
+func (Region NoteRegion) TopPercent() float64 {
+	return percentOf(Region.Y, Region.ImageHeight)
+}
+
+//WidthPercent returns Width as a percentage of the image width
+func (Region NoteRegion) WidthPercent() float64 {
+	return percentOf(Region.Width, Region.ImageWidth)
+}
+
+//HeightPercent returns Height as a percentage of the image height
+func (Region NoteRegion) HeightPercent() float64 {
+	return percentOf(Region.Height, Region.ImageHeight)
+}
+
+func percentOf(Value int64, Total int64) float64 {
+	if Total <= 0 {
+		return 0
+	}
+	return float64(Value) * 100 / float64(Total)
+}
@@ -30,7 +30,7 @@ const (
 	DisableUser UserPermission = 64
 	//EditUserPermissions Allows a user to edit permissions of another user
 	EditUserPermissions UserPermission = 128
-	//BulkTagOperations
+	//BulkTagOperations Allows a user to replace or add tags on every image with another tag, and to revert tag changes made by others
 	BulkTagOperations UserPermission = 256
 	//ScoreImage Allows a user to vote on an image's score
 	ScoreImage UserPermission = 512
@@ -47,6 +47,16 @@ const (
 	//APIWriteAccess grants a user access to the API for making changes. The user is still limited by their other permissions however.
 	//Read access is generally given to authenticated users.
 	APIWriteAccess UserPermission = 32768
+	//PostComments Allows a user to comment on images and collections, and to edit their own comments
+	PostComments UserPermission = 65536
+	//ModerateComments Allows a user to delete and restore the comments of others, and to see deleted comments
+	ModerateComments UserPermission = 131072
+	//EditNotes Allows a user to add, change, delete and rotate the notes on any image, every version of a note is kept
+	EditNotes UserPermission = 262144
+	//ApprovePosts Allows a user to approve or reject uploads waiting in the moderation queue. Their own uploads are never held for approval
+	ApprovePosts UserPermission = 524288
+	//HandleReports Allows a user to see every report, and to claim, resolve and dismiss them. Acting on what was reported still needs the permission for that action
+	HandleReports UserPermission = 1048576
 	//Add more permissions here as needed in future. Keep using powers of 2 for this to work.
 	//Max number will be 18446744073709551615, after 64 possible permission assignments.
 )
@@ -0,0 +1,87 @@
+package interfaces
+
+import (
+	"errors"
+	"strconv"
+	"time"
+)
+
+//ReportTargetTypes are the kinds of objects that may be reported
+var ReportTargetTypes = []string{"image", "collection", "tag"}
+
+//ReportCategories are the reasons a report may be filed under
+var ReportCategories = []string{"duplicate", "miscategorised", "illegal", "other"}
+
+//Report statuses, a report starts open, may be claimed by a moderator, and is closed by being resolved or dismissed
+const (
+	ReportOpen      = "open"
+	ReportClaimed   = "claimed"
+	ReportResolved  = "resolved"
+	ReportDismissed = "dismissed"
+)
+
+//ReportInformation is a user's report that an image, collection or tag needs a moderator, and what was done about it
+type ReportInformation struct {
+	ID uint64
+	//TargetType is one of ReportTargetTypes, and TargetID the ID of the object of that type
+	TargetType   string
+	TargetID     uint64
+	ReporterID   uint64
+	ReporterName string
+	//Category is one of ReportCategories, and Reason the reporter's own words
+	Category string
+	Reason   string
+	Status   string
+	//ModeratorID is the moderator that last claimed or closed the report, 0 while it is open
+	ModeratorID   uint64
+	ModeratorName string
+	//Outcome is what the moderator did about the report, shown to the reporter
+	Outcome      string
+	CreationTime time.Time
+	//UpdateTime is the zero time until a moderator changes the status
+	UpdateTime time.Time
+}
+
+//IsClosed returns true once a report has been resolved or dismissed
+func (Report ReportInformation) IsClosed() bool {
+	return Report.Status == ReportResolved || Report.Status == ReportDismissed
+}
+
+//TargetLink returns the path of the page showing the reported object
+func (Report ReportInformation) TargetLink() string {
+	return "/" + Report.TargetType + "?ID=" + strconv.FormatUint(Report.TargetID, 10)
+}
+
+//Validate ensures the target type and category of a new report are known, and the target is set
+func (Report ReportInformation) Validate() error {
+	if IsReportTargetType(Report.TargetType) == false {
+		return errors.New("only images, collections and tags may be reported")
+	}
+	if Report.TargetID == 0 {
+		return errors.New("nothing to report was given")
+	}
+	if IsReportCategory(Report.Category) == false {
+		return errors.New("unknown report category")
+	}
+	return nil
+}
+
+//IsReportTargetType returns true if TargetType is one of ReportTargetTypes
+func IsReportTargetType(TargetType string) bool {
+	for _, existing := range ReportTargetTypes {
+		if existing == TargetType {
+			return true
+		}
+	}
+	return false
+}
+
+//IsReportCategory returns true if Category is one of ReportCategories
+func IsReportCategory(Category string) bool {
+	for _, existing := range ReportCategories {
+		if existing == Category {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,39 @@
+package interfaces
+
+import (
+	"io"
+	"time"
+)
+
+//StorageInterface is a generic interface to allow swappable storage for images and thumbnails
+//
+//Object names use / as a separator regardless of platform, such as thumbs/<name>.png
+//Functions that find no object return an error for which os.IsNotExist is true
+type StorageInterface interface {
+	//InitStorage prepares the storage for use, validating it can be reached
+	InitStorage() error
+	//Put stores Size bytes read from Data as Name, replacing any existing object
+	Put(Name string, Data io.Reader, Size int64) error
+	//Get opens Name for reading, the returned object must be closed
+	Get(Name string) (StorageObject, StorageObjectInfo, error)
+	//Stat returns information on Name without opening it
+	Stat(Name string) (StorageObjectInfo, error)
+	//Delete removes Name
+	Delete(Name string) error
+	//List returns the objects directly inside Directory, or all objects beneath it if Recursive is set. Use "" for the top level
+	List(Directory string, Recursive bool) ([]StorageObjectInfo, error)
+}
+
+//StorageObject is an open object, seeking allows ranges to be served without reading the whole object
+type StorageObject interface {
+	io.ReadSeeker
+	io.Closer
+}
+
+//StorageObjectInfo describes an object in storage
+type StorageObjectInfo struct {
+	//Name the full name of the object, as passed to Get
+	Name    string
+	Size    int64
+	ModTime time.Time
+}
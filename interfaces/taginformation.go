@@ -1,6 +1,7 @@
 package interfaces
 
 import (
+	"sort"
 	"time"
 )
 
@@ -15,6 +16,7 @@ type TagInformation struct {
 	AliasedID   uint64
 	UseCount    uint64
 	IsAlias     bool
+	Category    string
 	//If the tag is a valid tag
 	Exists bool
 	//If user is trying to exclude this tag/value
@@ -29,6 +31,117 @@ type TagInformation struct {
 	IsComplexMeta bool
 	//Is this tag being added due to a user's global filter
 	FromUserFilter bool
+	//Is a group of tags from a query, such as (a OR b), searched as a complex metatag
+	IsGroup bool
+	//If only one of GroupTags must match, rather than all of them
+	MatchAny bool
+	//Members of the group, which may be groups themselves
+	GroupTags []TagInformation
+}
+
+//QueryError is returned when a search query can not be used as written, such as a wildcard matching too many tags. The message is meant for the user
+type QueryError struct {
+	Message string
+}
+
+func (Err QueryError) Error() string {
+	return Err.Message
+}
+
+//Categories a tag can belong to
+const (
+	//TagCategoryGeneral describes what is in the image, this is the default category
+	TagCategoryGeneral = "general"
+	//TagCategoryArtist who created the image
+	TagCategoryArtist = "artist"
+	//TagCategoryCharacter a character shown in the image
+	TagCategoryCharacter = "character"
+	//TagCategorySeries the series or franchise the image belongs to
+	TagCategorySeries = "series"
+)
+
+//TagCategories lists every category, in the order they are displayed
+var TagCategories = []string{TagCategoryArtist, TagCategoryCharacter, TagCategorySeries, TagCategoryGeneral}
+
+//IsTagCategory returns true if Category is one of TagCategories
+func IsTagCategory(Category string) bool {
+	for _, existing := range TagCategories {
+		if existing == Category {
+			return true
+		}
+	}
+	return false
+}
+
+//SortTagsByCategory sorts tags in to the order of TagCategories, then by name
+func SortTagsByCategory(Tags []TagInformation) {
+	categoryOrder := func(Category string) int {
+		for index, existing := range TagCategories {
+			if existing == Category {
+				return index
+			}
+		}
+		return len(TagCategories)
+	}
+	sort.SliceStable(Tags, func(i, j int) bool {
+		if Tags[i].Category != Tags[j].Category {
+			return categoryOrder(Tags[i].Category) < categoryOrder(Tags[j].Category)
+		}
+		return Tags[i].Name < Tags[j].Name
+	})
+}
+
+//TagCategoryCount is the MetaValue of the CategoryTags metatag, which compares how many of an image's tags are in Category against Count
+type TagCategoryCount struct {
+	Category string
+	Count    int64
+}
+
+//TimeRange is the MetaValue of date metatags such as uploaded:2025-03, covering from Start up to, but not including, End
+type TimeRange struct {
+	Start time.Time
+	End   time.Time
+}
+
+//Operations recorded in TagChangeInformation
+const (
+	//TagChangeAdd tags added to a single image
+	TagChangeAdd = "add"
+	//TagChangeRemove a tag removed from a single image
+	TagChangeRemove = "remove"
+	//TagChangeBulkAdd a tag added to every image with another tag
+	TagChangeBulkAdd = "bulkadd"
+	//TagChangeReplace a tag replaced by another on every image, including when it becomes an alias
+	TagChangeReplace = "replace"
+	//TagChangeRevert earlier changes undone by a moderator
+	TagChangeRevert = "revert"
+	//TagChangeImply a tag added because another tag on the image implies it
+	TagChangeImply = "imply"
+)
+
+//TagChangeInformation describes a single tag added to or removed from an image
+type TagChangeInformation struct {
+	ID       uint64
+	ImageID  uint64
+	TagID    uint64
+	TagName  string
+	UserID   uint64
+	UserName string
+	//Added is true when the tag was added, false when it was removed
+	Added      bool
+	Operation  string
+	ChangeTime time.Time
+}
+
+//TagImplicationInformation describes a rule that adds ImpliedTagID to every image tagged with TagID
+type TagImplicationInformation struct {
+	ID             uint64
+	TagID          uint64
+	TagName        string
+	ImpliedTagID   uint64
+	ImpliedTagName string
+	CreatorID      uint64
+	CreationTime   time.Time
 }
 
 //RemoveDuplicateTags removes duplicate tags from a given TagInformation slice.
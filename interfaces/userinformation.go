@@ -30,3 +30,23 @@ func (ui UserInformation) GetCompositeID() string {
 	}
 	return toReturn[:len(toReturn)-1]
 }
+
+//SavedSearchInformation is a query a user saved by name, so it can be run again from /images?SavedSearch=ID
+type SavedSearchInformation struct {
+	ID     uint64
+	UserID uint64
+	Name   string
+	//Query is the search terms as they would be typed in to the search box, including any metatags
+	Query string
+	//CollectionContext is true if Query searches collections rather than images
+	CollectionContext bool
+	//Pinned saved searches are listed in the header menu
+	Pinned bool
+	//ShowNewCount shows how many results were uploaded since the saved search was last opened
+	ShowNewCount bool
+	CreationTime time.Time
+	//LastViewed is when the saved search was last opened
+	LastViewed time.Time
+	//NewCount is how many results were uploaded since LastViewed, only counted when ShowNewCount is set
+	NewCount uint64
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"database/sql"
+	"go-image-board/database"
+	"go-image-board/logging"
+	"go-image-board/storage"
+	"strings"
+)
+
+//removeAllOrphanFiles deletes images and thumbnails that neither an image nor an image revision refers to
+func removeAllOrphanFiles() {
+	//Scan image directory
+	files, err := storage.StorageInterface.List("", true)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelCritical, "orphanUtility/removeAllOrphanFiles", "0", logging.ResultFailure, []string{"Failed to get images from directory", err.Error()})
+		return
+	}
+	for _, file := range files {
+		if storage.IsThumbnail(file.Name) {
+			continue
+		}
+		removeIfOrphan(file.Name, file.Name)
+	}
+	//Rinse&repeat with the thumbnails
+	files, err = storage.StorageInterface.List(storage.ThumbnailDirectory, true)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelCritical, "orphanUtility/removeAllOrphanFiles", "0", logging.ResultFailure, []string{"Failed to get images from directory", err.Error()})
+		return
+	}
+	for _, file := range files {
+		imageName := strings.TrimPrefix(file.Name, storage.ThumbnailDirectory+"/")
+		if len(imageName) > 4 { //Strip .png to get original name
+			imageName = imageName[:len(imageName)-4]
+		}
+		removeIfOrphan(file.Name, imageName)
+	}
+}
+
+//removeIfOrphan deletes the file Name when no image or image revision is stored at Location, which is Name itself for images and the image it belongs to for thumbnails
+func removeIfOrphan(Name string, Location string) {
+	//Search database for matching image entry, then for an earlier file of one
+	_, err := database.DBInterface.GetImageByFileName(Location)
+	if err == sql.ErrNoRows {
+		_, err = database.DBInterface.GetImageRevisionByLocation(Location)
+	}
+	if err != nil && err == sql.ErrNoRows {
+		logging.WriteLog(logging.LogLevelWarning, "orphanUtility/removeIfOrphan", "0", logging.ResultInfo, []string{"Failed to get image from database, it will be deleted", Name})
+		//If database entry does not exist, delete the file
+		err = storage.StorageInterface.Delete(Name)
+		if err != nil {
+			logging.WriteLog(logging.LogLevelError, "orphanUtility/removeIfOrphan", "0", logging.ResultFailure, []string{"Failed to delete file", Name, err.Error()})
+		}
+	} else if err != nil {
+		logging.WriteLog(logging.LogLevelError, "orphanUtility/removeIfOrphan", "0", logging.ResultFailure, []string{"Failed to get image from database due to an unexpected db error, it will be skipped", Name, err.Error()})
+	}
+}
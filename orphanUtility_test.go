@@ -0,0 +1,30 @@
+package main
+
+import (
+	"go-image-board/database"
+	"testing"
+)
+
+func TestRemoveAllOrphanFiles(t *testing.T) {
+	adminID := useEmptySite(t)
+	imageID, err := database.DBInterface.NewImage("one", "one.png", adminID, "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	//one.png is kept as a revision once the image is replaced
+	if err := database.DBInterface.ReplaceImageFile(imageID, "ab/cd/abcdtwo.png", adminID); err != nil {
+		t.Fatal(err)
+	}
+	kept := []string{"one.png", "thumbs/one.png.png", "ab/cd/abcdtwo.png", "thumbs/ab/cd/abcdtwo.png.png"}
+	orphans := []string{"three.png", "thumbs/three.png.png", "thumbs/ef/gh/efghfour.png.png"}
+	putFiles(t, append(kept, orphans...)...)
+
+	removeAllOrphanFiles()
+
+	if remaining := storedFiles(t, kept...); len(remaining) != len(kept) {
+		t.Errorf("referenced files were removed, %v remain", remaining)
+	}
+	if remaining := storedFiles(t, orphans...); len(remaining) != 0 {
+		t.Errorf("orphans were not removed, %v remain", remaining)
+	}
+}
@@ -0,0 +1,147 @@
+package localstorageplugin
+
+import (
+	"errors"
+	"go-image-board/interfaces"
+	"go-image-board/storage"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+//tempFilePrefix marks partially written files, these are hidden from List
+const tempFilePrefix = ".gib-upload-"
+
+//LocalStoragePlugin stores images and thumbnails as files beneath Directory
+type LocalStoragePlugin struct {
+	Directory string
+}
+
+//InitStorage creates the image and thumbnail directories if they do not exist
+func (Storage *LocalStoragePlugin) InitStorage() error {
+	return os.MkdirAll(filepath.Join(Storage.Directory, storage.ThumbnailDirectory), 0770)
+}
+
+//filePath converts an object name to a path beneath Directory, refusing names that would escape it
+func (Storage *LocalStoragePlugin) filePath(Name string) (string, error) {
+	cleaned := path.Clean("/" + Name)
+	if cleaned == "/" || cleaned != "/"+Name || strings.Contains(Name, "\\") {
+		return "", errors.New("Invalid object name " + Name)
+	}
+	return filepath.Join(Storage.Directory, filepath.FromSlash(cleaned[1:])), nil
+}
+
+//Put writes Data to a temporary file, then moves it in to place so a partial upload is never seen as Name
+func (Storage *LocalStoragePlugin) Put(Name string, Data io.Reader, Size int64) error {
+	filePath, err := Storage.filePath(Name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(filePath), 0770); err != nil {
+		return err
+	}
+	tempFile, err := ioutil.TempFile(filepath.Dir(filePath), tempFilePrefix)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(tempFile, Data)
+	if closeErr := tempFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tempFile.Name(), 0660)
+	}
+	if err == nil {
+		err = os.Rename(tempFile.Name(), filePath)
+	}
+	if err != nil {
+		os.Remove(tempFile.Name())
+	}
+	return err
+}
+
+//Get opens the file for Name
+func (Storage *LocalStoragePlugin) Get(Name string) (interfaces.StorageObject, interfaces.StorageObjectInfo, error) {
+	filePath, err := Storage.filePath(Name)
+	if err != nil {
+		return nil, interfaces.StorageObjectInfo{}, err
+	}
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, interfaces.StorageObjectInfo{}, err
+	}
+	fileInfo, err := file.Stat()
+	if err == nil && fileInfo.IsDir() {
+		err = &os.PathError{Op: "open", Path: Name, Err: os.ErrNotExist}
+	}
+	if err != nil {
+		file.Close()
+		return nil, interfaces.StorageObjectInfo{}, err
+	}
+	return file, interfaces.StorageObjectInfo{Name: Name, Size: fileInfo.Size(), ModTime: fileInfo.ModTime()}, nil
+}
+
+//Stat returns information on the file for Name
+func (Storage *LocalStoragePlugin) Stat(Name string) (interfaces.StorageObjectInfo, error) {
+	filePath, err := Storage.filePath(Name)
+	if err != nil {
+		return interfaces.StorageObjectInfo{}, err
+	}
+	fileInfo, err := os.Stat(filePath)
+	if err != nil {
+		return interfaces.StorageObjectInfo{}, err
+	}
+	if fileInfo.IsDir() {
+		return interfaces.StorageObjectInfo{}, &os.PathError{Op: "stat", Path: Name, Err: os.ErrNotExist}
+	}
+	return interfaces.StorageObjectInfo{Name: Name, Size: fileInfo.Size(), ModTime: fileInfo.ModTime()}, nil
+}
+
+//Delete removes the file for Name
+func (Storage *LocalStoragePlugin) Delete(Name string) error {
+	filePath, err := Storage.filePath(Name)
+	if err != nil {
+		return err
+	}
+	return os.Remove(filePath)
+}
+
+//List returns the files inside Directory, skipping sub directories unless Recursive is set
+func (Storage *LocalStoragePlugin) List(Directory string, Recursive bool) ([]interfaces.StorageObjectInfo, error) {
+	directoryPath := Storage.Directory
+	if Directory != "" {
+		var err error
+		if directoryPath, err = Storage.filePath(Directory); err != nil {
+			return nil, err
+		}
+	}
+	var objects []interfaces.StorageObjectInfo
+	if Recursive {
+		err := filepath.Walk(directoryPath, func(filePath string, file os.FileInfo, err error) error {
+			if err != nil || file.IsDir() || strings.HasPrefix(file.Name(), tempFilePrefix) {
+				return err
+			}
+			relativePath, err := filepath.Rel(directoryPath, filePath)
+			if err != nil {
+				return err
+			}
+			objects = append(objects, interfaces.StorageObjectInfo{Name: path.Join(Directory, filepath.ToSlash(relativePath)), Size: file.Size(), ModTime: file.ModTime()})
+			return nil
+		})
+		return objects, err
+	}
+	files, err := ioutil.ReadDir(directoryPath)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		if file.IsDir() || strings.HasPrefix(file.Name(), tempFilePrefix) {
+			continue
+		}
+		objects = append(objects, interfaces.StorageObjectInfo{Name: path.Join(Directory, file.Name()), Size: file.Size(), ModTime: file.ModTime()})
+	}
+	return objects, nil
+}
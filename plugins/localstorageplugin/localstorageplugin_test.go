@@ -0,0 +1,118 @@
+package localstorageplugin
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestStorage(t *testing.T) *LocalStoragePlugin {
+	t.Helper()
+	storage := &LocalStoragePlugin{Directory: filepath.Join(t.TempDir(), "images")}
+	if err := storage.InitStorage(); err != nil {
+		t.Fatal(err)
+	}
+	return storage
+}
+
+func TestPutGetListDelete(t *testing.T) {
+	storage := newTestStorage(t)
+	for _, name := range []string{"abc.png", "def.mp4", "thumbs/abc.png.png"} {
+		if err := storage.Put(name, strings.NewReader(name), int64(len(name))); err != nil {
+			t.Fatalf("Put %s: %v", name, err)
+		}
+	}
+	object, info, err := storage.Get("thumbs/abc.png.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := ioutil.ReadAll(object)
+	object.Close()
+	if string(data) != "thumbs/abc.png.png" || info.Size != int64(len(data)) {
+		t.Errorf("Get = %q (%d bytes)", data, info.Size)
+	}
+
+	//The top level excludes the thumbs directory, and thumbnails keep their directory in their name
+	for directory, want := range map[string]string{"": "abc.png,def.mp4", "thumbs": "thumbs/abc.png.png"} {
+		objects, err := storage.List(directory, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, object := range objects {
+			names = append(names, object.Name)
+		}
+		if strings.Join(names, ",") != want {
+			t.Errorf("List(%q) = %v, want %s", directory, names, want)
+		}
+	}
+
+	if err := storage.Delete("abc.png"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := storage.Stat("abc.png"); os.IsNotExist(err) == false {
+		t.Errorf("Stat after delete = %v, want not exist", err)
+	}
+	if _, err := storage.Stat("thumbs"); os.IsNotExist(err) == false {
+		t.Errorf("Stat of a directory = %v, want not exist", err)
+	}
+}
+
+func TestPutReplacesAndHidesPartialFiles(t *testing.T) {
+	storage := newTestStorage(t)
+	if err := storage.Put("abc.png", bytes.NewReader([]byte("a much longer original")), 22); err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.Put("abc.png", bytes.NewReader([]byte("short")), 5); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(filepath.Join(storage.Directory, "abc.png"))
+	if err != nil || string(data) != "short" {
+		t.Errorf("file = %q, %v, want it replaced", data, err)
+	}
+	//A file left behind by an interrupted Put is not listed
+	if err := ioutil.WriteFile(filepath.Join(storage.Directory, tempFilePrefix+"123"), []byte("partial"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	objects, err := storage.List("", false)
+	if err != nil || len(objects) != 1 {
+		t.Errorf("List = %v, %v, want only abc.png", objects, err)
+	}
+}
+
+func TestListRecursive(t *testing.T) {
+	storage := newTestStorage(t)
+	for _, name := range []string{"ab/cd/abcd.png", "abef.png", "thumbs/ab/cd/abcd.png.png"} {
+		if err := storage.Put(name, strings.NewReader(name), int64(len(name))); err != nil {
+			t.Fatalf("Put %s: %v", name, err)
+		}
+	}
+	for directory, want := range map[string]string{"": "ab/cd/abcd.png,abef.png,thumbs/ab/cd/abcd.png.png", "thumbs": "thumbs/ab/cd/abcd.png.png"} {
+		objects, err := storage.List(directory, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, object := range objects {
+			names = append(names, object.Name)
+		}
+		if strings.Join(names, ",") != want {
+			t.Errorf("List(%q, true) = %v, want %s", directory, names, want)
+		}
+	}
+}
+
+func TestRejectsNamesOutsideDirectory(t *testing.T) {
+	storage := newTestStorage(t)
+	for _, name := range []string{"", "../abc.png", "thumbs/../../abc.png", "/abc.png", "a//b.png"} {
+		if err := storage.Put(name, strings.NewReader("x"), 1); err == nil {
+			t.Errorf("Put(%q) succeeded, want an error", name)
+		}
+		if _, _, err := storage.Get(name); err == nil {
+			t.Errorf("Get(%q) succeeded, want an error", name)
+		}
+	}
+}
@@ -4,18 +4,21 @@ import (
 	"bytes"
 	"database/sql"
 	"errors"
+	"go-image-board/interfaces"
 	"go-image-board/logging"
+	"time"
 
 	uuid "github.com/satori/go.uuid"
 )
 
 //ValidateToken Validate a cookie token (true if valid cookie, false otherwise, error for reason or nil)
 func (DBConnection *MariaDBPlugin) ValidateToken(userName string, tokenID string, ip string) error {
+	var userID uint64
 	var validTokenID sql.NullString
 	var validTokenIP sql.NullString
 	var userDisabled bool
-	row := DBConnection.DBHandle.QueryRow("SELECT TokenID, IP, Disabled FROM Users WHERE Name = ?", userName)
-	err := row.Scan(&validTokenID, &validTokenIP, &userDisabled)
+	row := DBConnection.DBHandle.QueryRow("SELECT ID, TokenID, IP, Disabled FROM Users WHERE Name = ?", userName)
+	err := row.Scan(&userID, &validTokenID, &validTokenIP, &userDisabled)
 	if userDisabled {
 		return errors.New("Account disabled")
 	}
@@ -44,6 +47,15 @@ func (DBConnection *MariaDBPlugin) ValidateToken(userName string, tokenID string
 		return errors.New("Token invalid")
 	}
 
+	//A valid token does not get past a ban placed since it was generated
+	bans, err := DBConnection.GetActiveBansOn(userID, ip)
+	if err != nil {
+		return err
+	}
+	if ban, isBanned := interfaces.FindBan(bans, userID, ip, time.Now()); isBanned {
+		return interfaces.BanError{Ban: ban}
+	}
+
 	return nil
 }
 
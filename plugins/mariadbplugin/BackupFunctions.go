@@ -0,0 +1,25 @@
+package mariadbplugin
+
+import (
+	"go-image-board/interfaces"
+	"go-image-board/logging"
+	"go-image-board/plugins/sqlbackup"
+)
+
+//ExportTables calls Row for every row of each table, in ID order, reading from a single snapshot so the tables are consistent with each other
+func (DBConnection *MariaDBPlugin) ExportTables(Tables []interfaces.BackupTable, Row func(Table interfaces.BackupTable, Row interfaces.BackupRow) error) error {
+	err := sqlbackup.ExportTables(DBConnection.DBHandle, sqlbackup.MariaDB, Tables, Row)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/BackupFunctions/ExportTables", "0", logging.ResultFailure, []string{"Failed to export tables", err.Error()})
+	}
+	return err
+}
+
+//ImportTables restores a backup in to a freshly installed database, keeping the IDs of every row. Nothing is kept if an error occurs
+func (DBConnection *MariaDBPlugin) ImportTables(Tables []interfaces.BackupTable, Rows func(Insert func(Table interfaces.BackupTable, Row interfaces.BackupRow) error) error) error {
+	err := sqlbackup.ImportTables(DBConnection.DBHandle, sqlbackup.MariaDB, Tables, Rows)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/BackupFunctions/ImportTables", "0", logging.ResultFailure, []string{"Failed to import tables", err.Error()})
+	}
+	return err
+}
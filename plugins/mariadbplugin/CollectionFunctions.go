@@ -38,6 +38,10 @@ func (DBConnection *MariaDBPlugin) DeleteCollection(CollectionID uint64) error {
 		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/DeleteCollection", "0", logging.ResultFailure, []string{"Colleciton to delete is still in use and members could not be removed", strconv.FormatUint(CollectionID, 10)})
 		return errors.New("could not remove members from collection before deleting collection")
 	}
+	if err = DBConnection.deleteComments(0, CollectionID); err != nil {
+		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/DeleteCollection", "0", logging.ResultFailure, []string{"Failed to delete collection comments", err.Error(), strconv.FormatUint(CollectionID, 10)})
+		return err
+	}
 
 	//Delete
 	_, err = DBConnection.DBHandle.Exec("DELETE FROM Collections WHERE ID=?;", CollectionID)
@@ -85,10 +89,11 @@ func (DBConnection *MariaDBPlugin) GetCollections(PageStart uint64, PageStride u
 		INNER JOIN Images on Images.ID = CM.ImageID
 		WHERE OrderWeight = (SELECT MIN(OrderWeight) From CollectionMembers WHERE CollectionMembers.CollectionID = CM.CollectionID)
 	) Preview ON Preview.CollectionID = CL.ID
+	WHERE CL.DeletedTime IS NULL
 	ORDER BY Name
 	LIMIT ? OFFSET ?;`
 
-	sqlCountQuery := `SELECT COUNT(*) AS Count FROM Collections`
+	sqlCountQuery := `SELECT COUNT(*) AS Count FROM Collections WHERE DeletedTime IS NULL`
 	//Get Count query
 	var MaxResults uint64
 	//Run the count query (Count query does not use start/stride)
@@ -130,7 +135,7 @@ func (DBConnection *MariaDBPlugin) GetCollections(PageStart uint64, PageStride u
 
 //GetCollection returns detailed information on one collection
 func (DBConnection *MariaDBPlugin) GetCollection(ID uint64) (interfaces.CollectionInformation, error) {
-	sqlQuery := "SELECT Name, Description, UploaderID, UploadTime FROM Collections WHERE ID=?"
+	sqlQuery := "SELECT Collections.Name, Collections.Description, Collections.UploaderID, Collections.UploadTime, Collections.DeletedTime, Collections.DeleterID, IFNULL(Users.Name, '') FROM Collections LEFT OUTER JOIN Users ON Collections.DeleterID = Users.ID WHERE Collections.ID=?"
 	//Pass the sql query to DB
 	//Placeholders for data returned by each row
 	var Description sql.NullString
@@ -138,7 +143,10 @@ func (DBConnection *MariaDBPlugin) GetCollection(ID uint64) (interfaces.Collecti
 	var UploaderID uint64
 	var NUploadTime mysql.NullTime
 	var UploadTime time.Time
-	if err := DBConnection.DBHandle.QueryRow(sqlQuery, ID).Scan(&Name, &Description, &UploaderID, &NUploadTime); err != nil {
+	var DeletedTime mysql.NullTime
+	var DeleterID uint64
+	var DeleterName string
+	if err := DBConnection.DBHandle.QueryRow(sqlQuery, ID).Scan(&Name, &Description, &UploaderID, &NUploadTime, &DeletedTime, &DeleterID, &DeleterName); err != nil {
 		return interfaces.CollectionInformation{}, err
 	}
 
@@ -157,12 +165,12 @@ func (DBConnection *MariaDBPlugin) GetCollection(ID uint64) (interfaces.Collecti
 		UploadTime = NUploadTime.Time
 	}
 
-	return interfaces.CollectionInformation{Name: Name, ID: ID, Description: SDescription, UploaderID: UploaderID, UploadTime: UploadTime, Members: MemberCount}, nil
+	return interfaces.CollectionInformation{Name: Name, ID: ID, Description: SDescription, UploaderID: UploaderID, UploadTime: UploadTime, Members: MemberCount, InTrash: DeletedTime.Valid, DeletedTime: DeletedTime.Time, DeleterID: DeleterID, DeleterName: DeleterName}, nil
 }
 
 //GetCollectionByName returns detailed information on one collection
 func (DBConnection *MariaDBPlugin) GetCollectionByName(Name string) (interfaces.CollectionInformation, error) {
-	sqlQuery := "SELECT ID, Name, Description, UploaderID, UploadTime FROM Collections WHERE Name=?"
+	sqlQuery := "SELECT Collections.ID, Collections.Name, Collections.Description, Collections.UploaderID, Collections.UploadTime, Collections.DeletedTime, Collections.DeleterID, IFNULL(Users.Name, '') FROM Collections LEFT OUTER JOIN Users ON Collections.DeleterID = Users.ID WHERE Collections.Name=?"
 	//Pass the sql query to DB
 	//Placeholders for data returned by each row
 	var Description sql.NullString
@@ -170,7 +178,10 @@ func (DBConnection *MariaDBPlugin) GetCollectionByName(Name string) (interfaces.
 	var UploaderID uint64
 	var NUploadTime mysql.NullTime
 	var UploadTime time.Time
-	if err := DBConnection.DBHandle.QueryRow(sqlQuery, Name).Scan(&CollectionID, &Name, &Description, &UploaderID, &NUploadTime); err != nil {
+	var DeletedTime mysql.NullTime
+	var DeleterID uint64
+	var DeleterName string
+	if err := DBConnection.DBHandle.QueryRow(sqlQuery, Name).Scan(&CollectionID, &Name, &Description, &UploaderID, &NUploadTime, &DeletedTime, &DeleterID, &DeleterName); err != nil {
 		return interfaces.CollectionInformation{}, err
 	}
 
@@ -189,7 +200,7 @@ func (DBConnection *MariaDBPlugin) GetCollectionByName(Name string) (interfaces.
 		UploadTime = NUploadTime.Time
 	}
 
-	return interfaces.CollectionInformation{Name: Name, ID: CollectionID, Description: SDescription, UploaderID: UploaderID, UploadTime: UploadTime, Members: MemberCount}, nil
+	return interfaces.CollectionInformation{Name: Name, ID: CollectionID, Description: SDescription, UploaderID: UploaderID, UploadTime: UploadTime, Members: MemberCount, InTrash: DeletedTime.Valid, DeletedTime: DeletedTime.Time, DeleterID: DeleterID, DeleterName: DeleterName}, nil
 }
 
 //--Collection Members
@@ -317,10 +328,10 @@ func (DBConnection *MariaDBPlugin) GetCollectionMembers(CollectionID uint64, Pag
 	queryArray = append(queryArray, CollectionID)
 
 	//Queries
-	sqlQuery := `SELECT ImageID, Name, Location, OrderWeight
+	sqlQuery := `SELECT ImageID, Name, Location, MIMEType, OrderWeight
 	FROM Images
 	INNER JOIN CollectionMembers ON Images.ID=CollectionMembers.ImageID
-	WHERE CollectionMembers.CollectionID=?
+	WHERE CollectionMembers.CollectionID=? AND Images.DeletedTime IS NULL AND Images.Pending = FALSE
 	ORDER BY CollectionMembers.OrderWeight`
 
 	//If we limited the search
@@ -334,7 +345,7 @@ func (DBConnection *MariaDBPlugin) GetCollectionMembers(CollectionID uint64, Pag
 	sqlCountQuery := `SELECT COUNT(ImageID)
 	FROM Images
 	INNER JOIN CollectionMembers ON Images.ID=CollectionMembers.ImageID
-	WHERE CollectionMembers.CollectionID=?;`
+	WHERE CollectionMembers.CollectionID=? AND Images.DeletedTime IS NULL AND Images.Pending = FALSE;`
 
 	//Init Output
 	var ToReturn []interfaces.ImageInformation
@@ -357,16 +368,17 @@ func (DBConnection *MariaDBPlugin) GetCollectionMembers(CollectionID uint64, Pag
 	var ImageID uint64
 	var Name string
 	var Location string
+	var MIMEType string
 	var Order uint64
 	//For each row
 	for rows.Next() {
 		//Parse out the data
-		err := rows.Scan(&ImageID, &Name, &Location, &Order)
+		err := rows.Scan(&ImageID, &Name, &Location, &MIMEType, &Order)
 		if err != nil {
 			return nil, 0, err
 		}
 		//Add this result to ToReturn
-		ToReturn = append(ToReturn, interfaces.ImageInformation{Name: Name, ID: ImageID, Location: Location, OrderInCollection: Order})
+		ToReturn = append(ToReturn, interfaces.ImageInformation{Name: Name, ID: ImageID, Location: Location, MIMEType: MIMEType, OrderInCollection: Order})
 	}
 	return ToReturn, MaxResults, nil
 }
@@ -374,7 +386,7 @@ func (DBConnection *MariaDBPlugin) GetCollectionMembers(CollectionID uint64, Pag
 //GetCollectionsWithImage returns a slice of collections with a specific image
 func (DBConnection *MariaDBPlugin) GetCollectionsWithImage(ImageID uint64) ([]interfaces.CollectionInformation, error) {
 	var ToReturn []interfaces.CollectionInformation
-	sqlQuery := `SELECT Collections.Name, Collections.Description, CollectionMembers.OrderWeight, Collections.ID, Counts.Members, IFNULL(BeforeMember.ImageID,0) as BeforeMember, IFNULL(AfterMember.ImageID,0) as AfterMember
+	sqlQuery := `SELECT Collections.Name, Collections.Description, CollectionMembers.OrderWeight, Collections.ID, Counts.Members, IFNULL(BeforeMember.ImageID,0) as BeforeMember, IFNULL(AfterMember.ImageID,0) as AfterMember, Collections.DeletedTime IS NOT NULL as InTrash
 	FROM CollectionMembers
 	INNER JOIN Collections ON Collections.ID=CollectionMembers.CollectionID
 	-- This part gets the number of members in a collection
@@ -415,15 +427,16 @@ func (DBConnection *MariaDBPlugin) GetCollectionsWithImage(ImageID uint64) ([]in
 	var Members uint64
 	var BeforeID uint64
 	var AfterID uint64
+	var InTrash bool
 	//For each row
 	for rows.Next() {
 		//Parse out the data
-		err := rows.Scan(&Name, &Description, &Order, &CollectionID, &Members, &BeforeID, &AfterID)
+		err := rows.Scan(&Name, &Description, &Order, &CollectionID, &Members, &BeforeID, &AfterID, &InTrash)
 		if err != nil {
 			return nil, err
 		}
 		//Add this result to ToReturn
-		ToReturn = append(ToReturn, interfaces.CollectionInformation{Name: Name, Description: Description, ID: CollectionID, OrderInCollection: Order, Members: Members, PreviousMemberID: BeforeID, NextMemberID: AfterID})
+		ToReturn = append(ToReturn, interfaces.CollectionInformation{Name: Name, Description: Description, ID: CollectionID, OrderInCollection: Order, Members: Members, PreviousMemberID: BeforeID, NextMemberID: AfterID, InTrash: InTrash})
 	}
 
 	return ToReturn, nil
@@ -432,7 +445,7 @@ func (DBConnection *MariaDBPlugin) GetCollectionsWithImage(ImageID uint64) ([]in
 //GetCollectionTags returns a list of TagInformation for all tags that apply to the given collection
 func (DBConnection *MariaDBPlugin) GetCollectionTags(CollectionID uint64) ([]interfaces.TagInformation, error) {
 	var ToReturn []interfaces.TagInformation
-	sqlQuery := "SELECT Tags.ID, Tags.Name, Tags.Description FROM CollectionTags INNER JOIN Tags ON Tags.ID = CollectionTags.TagID WHERE CollectionID=?"
+	sqlQuery := "SELECT Tags.ID, Tags.Name, Tags.Description, Tags.Category FROM CollectionTags INNER JOIN Tags ON Tags.ID = CollectionTags.TagID WHERE CollectionID=?"
 	//Pass the sql query to DB
 	rows, err := DBConnection.DBHandle.Query(sqlQuery, CollectionID)
 	if err != nil {
@@ -443,10 +456,11 @@ func (DBConnection *MariaDBPlugin) GetCollectionTags(CollectionID uint64) ([]int
 	var Description sql.NullString
 	var ID uint64
 	var Name string
+	var Category string
 	//For each row
 	for rows.Next() {
 		//Parse out the data
-		err := rows.Scan(&ID, &Name, &Description)
+		err := rows.Scan(&ID, &Name, &Description, &Category)
 		if err != nil {
 			return nil, err
 		}
@@ -456,7 +470,7 @@ func (DBConnection *MariaDBPlugin) GetCollectionTags(CollectionID uint64) ([]int
 			SDescription = Description.String
 		}
 		//Add this result to ToReturn
-		ToReturn = append(ToReturn, interfaces.TagInformation{Name: Name, ID: ID, Description: SDescription, Exists: true, Exclude: false})
+		ToReturn = append(ToReturn, interfaces.TagInformation{Name: Name, ID: ID, Description: SDescription, Exists: true, Exclude: false, Category: Category})
 	}
 	return ToReturn, nil
 }
@@ -0,0 +1,33 @@
+package mariadbplugin
+
+import (
+	"go-image-board/logging"
+	"strconv"
+)
+
+//Favorite operations
+
+//SetUserFavorite adds an image to, or removes it from, a user's favorites
+func (DBConnection *MariaDBPlugin) SetUserFavorite(UserID uint64, ImageID uint64, Favorite bool) error {
+	sqlQuery := "DELETE FROM ImageUserFavorites WHERE UserID=? AND ImageID=?;"
+	if Favorite {
+		//Favoriting an image twice leaves it favorited once
+		sqlQuery = "INSERT INTO ImageUserFavorites (UserID, ImageID) VALUES (?, ?) ON DUPLICATE KEY UPDATE UserID = UserID;"
+	}
+	if _, err := DBConnection.DBHandle.Exec(sqlQuery, UserID, ImageID); err != nil {
+		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/SetUserFavorite", strconv.FormatUint(UserID, 10), logging.ResultFailure, []string{"Failed to update favorite", strconv.FormatUint(ImageID, 10), err.Error()})
+		return err
+	}
+	logging.WriteLog(logging.LogLevelInfo, "MariaDBPlugin/SetUserFavorite", strconv.FormatUint(UserID, 10), logging.ResultSuccess, []string{"Favorite updated", strconv.FormatUint(ImageID, 10), strconv.FormatBool(Favorite)})
+	return nil
+}
+
+//GetUserFavorite Returns whether a user has favorited an image
+func (DBConnection *MariaDBPlugin) GetUserFavorite(UserID uint64, ImageID uint64) (bool, error) {
+	var count uint64
+	if err := DBConnection.DBHandle.QueryRow("SELECT COUNT(*) FROM ImageUserFavorites WHERE UserID=? AND ImageID=?;", UserID, ImageID).Scan(&count); err != nil {
+		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/GetUserFavorite", strconv.FormatUint(UserID, 10), logging.ResultFailure, []string{"Failed to get favorite", strconv.FormatUint(ImageID, 10), err.Error()})
+		return false, err
+	}
+	return count > 0, nil
+}
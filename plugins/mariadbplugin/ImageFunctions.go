@@ -13,8 +13,8 @@ import (
 //Image operations
 
 //NewImage adds an image with the provided information
-func (DBConnection *MariaDBPlugin) NewImage(ImageName string, ImageFileName string, OwnerID uint64, Source string) (uint64, error) {
-	resultInfo, err := DBConnection.DBHandle.Exec("INSERT INTO Images (Name, Location, UploaderID, Source) VALUES (?, ?, ?, ?);", ImageName, ImageFileName, OwnerID, Source)
+func (DBConnection *MariaDBPlugin) NewImage(ImageName string, ImageFileName string, OwnerID uint64, Source string, Pending bool) (uint64, error) {
+	resultInfo, err := DBConnection.DBHandle.Exec("INSERT INTO Images (Name, Location, UploaderID, Source, Pending) VALUES (?, ?, ?, ?, ?);", ImageName, ImageFileName, OwnerID, Source, Pending)
 	if err != nil {
 		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/NewImage", strconv.FormatUint(OwnerID, 10), logging.ResultFailure, []string{"Failed to add image", err.Error()})
 		return 0, err
@@ -24,6 +24,26 @@ func (DBConnection *MariaDBPlugin) NewImage(ImageName string, ImageFileName stri
 	return uint64(id), err
 }
 
+//GetAllImages returns the ID, Name, Location and MIMEType of up to Count images with an ID above AfterID, lowest first. Unlike SearchImages, images in the trash or waiting for approval are included
+func (DBConnection *MariaDBPlugin) GetAllImages(AfterID uint64, Count uint64) ([]interfaces.ImageInformation, error) {
+	rows, err := DBConnection.DBHandle.Query("SELECT ID, Name, Location, MIMEType FROM Images WHERE ID > ? ORDER BY ID LIMIT ?;", AfterID, Count)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/GetAllImages", "0", logging.ResultFailure, []string{"Failed to get images", err.Error()})
+		return nil, err
+	}
+	defer rows.Close()
+	var ToReturn []interfaces.ImageInformation
+	for rows.Next() {
+		var image interfaces.ImageInformation
+		if err := rows.Scan(&image.ID, &image.Name, &image.Location, &image.MIMEType); err != nil {
+			logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/GetAllImages", "0", logging.ResultFailure, []string{"Failed to get images", err.Error()})
+			return nil, err
+		}
+		ToReturn = append(ToReturn, image)
+	}
+	return ToReturn, rows.Err()
+}
+
 //DeleteImage removes an image from the db
 func (DBConnection *MariaDBPlugin) DeleteImage(ImageID uint64) error {
 	//First, remove image from any associated collections
@@ -45,6 +65,34 @@ func (DBConnection *MariaDBPlugin) DeleteImage(ImageID uint64) error {
 		return err
 	}
 	logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/DeleteImage", "0", logging.ResultSuccess, []string{"Image tags deleted", strconv.FormatUint(ImageID, 10)})
+	//And its tag history
+	_, err = DBConnection.DBHandle.Exec("DELETE FROM ImageTagHistory WHERE ImageID=?;", ImageID)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/DeleteImage", "0", logging.ResultFailure, []string{"Failed to delete image tag history", err.Error(), strconv.FormatUint(ImageID, 10)})
+		return err
+	}
+	//And any favorites of it
+	_, err = DBConnection.DBHandle.Exec("DELETE FROM ImageUserFavorites WHERE ImageID=?;", ImageID)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/DeleteImage", "0", logging.ResultFailure, []string{"Failed to delete image favorites", err.Error(), strconv.FormatUint(ImageID, 10)})
+		return err
+	}
+	//And its comments
+	if err = DBConnection.deleteComments(ImageID, 0); err != nil {
+		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/DeleteImage", "0", logging.ResultFailure, []string{"Failed to delete image comments", err.Error(), strconv.FormatUint(ImageID, 10)})
+		return err
+	}
+	//And its notes
+	if err = DBConnection.deleteNotes(ImageID); err != nil {
+		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/DeleteImage", "0", logging.ResultFailure, []string{"Failed to delete image notes", err.Error(), strconv.FormatUint(ImageID, 10)})
+		return err
+	}
+	//Then the records of its previous files, those files are removed by the caller along with the current one
+	_, err = DBConnection.DBHandle.Exec("DELETE FROM ImageRevisions WHERE ImageID=?;", ImageID)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/DeleteImage", "0", logging.ResultFailure, []string{"Failed to delete image revisions", err.Error(), strconv.FormatUint(ImageID, 10)})
+		return err
+	}
 	//Second delete Image from table
 	_, err = DBConnection.DBHandle.Exec("DELETE FROM Images WHERE ID=?;", ImageID)
 	if err != nil {
@@ -125,7 +173,8 @@ func (DBConnection *MariaDBPlugin) UpdateImage(ImageID uint64, ImageName interfa
 func (DBConnection *MariaDBPlugin) GetImage(ID uint64) (interfaces.ImageInformation, error) {
 	ToReturn := interfaces.ImageInformation{ID: ID}
 	var UploadTime mysql.NullTime
-	err := DBConnection.DBHandle.QueryRow("Select Images.Name, IFNULL(Images.Description,'') AS Description, Images.Location, Images.UploaderID, Images.UploadTime, Images.Rating, Users.Name, Images.ScoreAverage, Images.ScoreTotal, Images.ScoreVoters, Images.Source FROM Images LEFT OUTER JOIN Users ON Images.UploaderID = Users.ID WHERE Images.ID=?", ID).Scan(&ToReturn.Name, &ToReturn.Description, &ToReturn.Location, &ToReturn.UploaderID, &UploadTime, &ToReturn.Rating, &ToReturn.UploaderName, &ToReturn.ScoreAverage, &ToReturn.ScoreTotal, &ToReturn.ScoreVoters, &ToReturn.Source)
+	var DeletedTime mysql.NullTime
+	err := DBConnection.DBHandle.QueryRow("Select Images.Name, IFNULL(Images.Description,'') AS Description, Images.Location, Images.UploaderID, Images.UploadTime, Images.Rating, Users.Name, Images.ScoreAverage, Images.ScoreTotal, Images.ScoreVoters, Images.Source, Images.DeletedTime, Images.DeleterID, IFNULL(Deleters.Name, ''), Images.Width, Images.Height, Images.FileSize, Images.MIMEType, Images.Duration, (SELECT COUNT(*) FROM ImageUserFavorites WHERE ImageUserFavorites.ImageID = Images.ID), Images.Pending FROM Images LEFT OUTER JOIN Users ON Images.UploaderID = Users.ID LEFT OUTER JOIN Users AS Deleters ON Images.DeleterID = Deleters.ID WHERE Images.ID=?", ID).Scan(&ToReturn.Name, &ToReturn.Description, &ToReturn.Location, &ToReturn.UploaderID, &UploadTime, &ToReturn.Rating, &ToReturn.UploaderName, &ToReturn.ScoreAverage, &ToReturn.ScoreTotal, &ToReturn.ScoreVoters, &ToReturn.Source, &DeletedTime, &ToReturn.DeleterID, &ToReturn.DeleterName, &ToReturn.Width, &ToReturn.Height, &ToReturn.FileSize, &ToReturn.MIMEType, &ToReturn.Duration, &ToReturn.FavoriteCount, &ToReturn.Pending)
 	if err != nil {
 		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/ImageFunctions/GetImage", "0", logging.ResultFailure, []string{"Failed to get image info from database", err.Error()})
 		return ToReturn, err
@@ -133,6 +182,8 @@ func (DBConnection *MariaDBPlugin) GetImage(ID uint64) (interfaces.ImageInformat
 	if UploadTime.Valid {
 		ToReturn.UploadTime = UploadTime.Time
 	}
+	ToReturn.InTrash = DeletedTime.Valid
+	ToReturn.DeletedTime = DeletedTime.Time
 	return ToReturn, nil
 }
 
@@ -140,7 +191,8 @@ func (DBConnection *MariaDBPlugin) GetImage(ID uint64) (interfaces.ImageInformat
 func (DBConnection *MariaDBPlugin) GetImageByFileName(imageName string) (interfaces.ImageInformation, error) {
 	ToReturn := interfaces.ImageInformation{Location: imageName}
 	var UploadTime mysql.NullTime
-	err := DBConnection.DBHandle.QueryRow("Select Images.Name, IFNULL(Images.Description,'') AS Description, Images.ID, Images.UploaderID, Images.UploadTime, Images.Rating, Users.Name, Images.ScoreAverage, Images.ScoreTotal, Images.ScoreVoters, Images.Source FROM Images LEFT OUTER JOIN Users ON Images.UploaderID = Users.ID WHERE Images.Location=?", imageName).Scan(&ToReturn.Name, &ToReturn.Description, &ToReturn.ID, &ToReturn.UploaderID, &UploadTime, &ToReturn.Rating, &ToReturn.UploaderName, &ToReturn.ScoreAverage, &ToReturn.ScoreTotal, &ToReturn.ScoreVoters, &ToReturn.Source)
+	var DeletedTime mysql.NullTime
+	err := DBConnection.DBHandle.QueryRow("Select Images.Name, IFNULL(Images.Description,'') AS Description, Images.ID, Images.UploaderID, Images.UploadTime, Images.Rating, Users.Name, Images.ScoreAverage, Images.ScoreTotal, Images.ScoreVoters, Images.Source, Images.DeletedTime, Images.DeleterID, IFNULL(Deleters.Name, ''), Images.Width, Images.Height, Images.FileSize, Images.MIMEType, Images.Duration, (SELECT COUNT(*) FROM ImageUserFavorites WHERE ImageUserFavorites.ImageID = Images.ID), Images.Pending FROM Images LEFT OUTER JOIN Users ON Images.UploaderID = Users.ID LEFT OUTER JOIN Users AS Deleters ON Images.DeleterID = Deleters.ID WHERE Images.Location=?", imageName).Scan(&ToReturn.Name, &ToReturn.Description, &ToReturn.ID, &ToReturn.UploaderID, &UploadTime, &ToReturn.Rating, &ToReturn.UploaderName, &ToReturn.ScoreAverage, &ToReturn.ScoreTotal, &ToReturn.ScoreVoters, &ToReturn.Source, &DeletedTime, &ToReturn.DeleterID, &ToReturn.DeleterName, &ToReturn.Width, &ToReturn.Height, &ToReturn.FileSize, &ToReturn.MIMEType, &ToReturn.Duration, &ToReturn.FavoriteCount, &ToReturn.Pending)
 	if err != nil {
 		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/ImageFunctions/GetImageByFileName", "0", logging.ResultFailure, []string{"Failed to get image info from database", err.Error()})
 		return ToReturn, err
@@ -148,6 +200,8 @@ func (DBConnection *MariaDBPlugin) GetImageByFileName(imageName string) (interfa
 	if UploadTime.Valid {
 		ToReturn.UploadTime = UploadTime.Time
 	}
+	ToReturn.InTrash = DeletedTime.Valid
+	ToReturn.DeletedTime = DeletedTime.Time
 	return ToReturn, nil
 }
 
@@ -171,6 +225,16 @@ func (DBConnection *MariaDBPlugin) SetImageSource(ID uint64, Source string) erro
 	return nil
 }
 
+//SetImageMetadata changes a given image's dimensions, file size, MIME type and duration in the database
+func (DBConnection *MariaDBPlugin) SetImageMetadata(ID uint64, Metadata interfaces.ImageMetadata) error {
+	_, err := DBConnection.DBHandle.Exec("UPDATE Images SET Width = ?, Height = ?, FileSize = ?, MIMEType = ?, Duration = ? WHERE ID = ?;", Metadata.Width, Metadata.Height, Metadata.FileSize, Metadata.MIMEType, Metadata.Duration, ID)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/ImageFunctions/SetImageMetadata", "0", logging.ResultFailure, []string{"Failed to set image metadata", err.Error()})
+		return err
+	}
+	return nil
+}
+
 //SetImagedHash changes a given image's dHash in the database
 func (DBConnection *MariaDBPlugin) SetImagedHash(ID uint64, hHash uint64, vHash uint64) error {
 	_, err := DBConnection.DBHandle.Exec("INSERT INTO ImagedHashes (ImageID, hHash, vHash) VALUES (?,?,?) ON DUPLICATE KEY UPDATE hHash = VALUES(hHash), vHash = VALUES(vHash);", ID, hHash, vHash)
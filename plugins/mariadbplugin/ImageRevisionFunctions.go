@@ -0,0 +1,123 @@
+package mariadbplugin
+
+import (
+	"database/sql"
+	"errors"
+	"go-image-board/interfaces"
+	"go-image-board/logging"
+	"strconv"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+//ReplaceImageFile points an image at a new file, keeping its previous Location as a revision
+func (DBConnection *MariaDBPlugin) ReplaceImageFile(ImageID uint64, Location string, ReplacerID uint64) error {
+	imageInfo, err := DBConnection.GetImage(ImageID)
+	if err != nil {
+		return err
+	}
+	//Only update if the file was not replaced since we read it, otherwise its revision would be lost
+	resultInfo, err := DBConnection.DBHandle.Exec("UPDATE Images SET Location = ? WHERE ID = ? AND Location = ?;", Location, ImageID, imageInfo.Location)
+	if err == nil {
+		if affected, _ := resultInfo.RowsAffected(); affected == 0 {
+			err = errors.New("image file was replaced by another request")
+		}
+	}
+	if err == nil {
+		_, err = DBConnection.DBHandle.Exec("INSERT INTO ImageRevisions (ImageID, Location, ReplacerID) VALUES (?, ?, ?);", ImageID, imageInfo.Location, ReplacerID)
+		if err != nil {
+			//Without a revision nothing refers to the previous file, so put it back
+			DBConnection.DBHandle.Exec("UPDATE Images SET Location = ? WHERE ID = ?;", imageInfo.Location, ImageID)
+		}
+	}
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/ReplaceImageFile", strconv.FormatUint(ReplacerID, 10), logging.ResultFailure, []string{"Failed to replace image file", strconv.FormatUint(ImageID, 10), Location, err.Error()})
+		return err
+	}
+	logging.WriteLog(logging.LogLevelInfo, "MariaDBPlugin/ReplaceImageFile", strconv.FormatUint(ReplacerID, 10), logging.ResultSuccess, []string{"Image file replaced", strconv.FormatUint(ImageID, 10), imageInfo.Location, Location})
+	return nil
+}
+
+//GetImageRevisions returns the previous files of an image, newest first
+func (DBConnection *MariaDBPlugin) GetImageRevisions(ImageID uint64) ([]interfaces.ImageRevisionInformation, error) {
+	ToReturn, err := DBConnection.queryImageRevisions("WHERE ImageRevisions.ImageID = ? ORDER BY ImageRevisions.ReplacedTime DESC, ImageRevisions.ID DESC;", ImageID)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/GetImageRevisions", "0", logging.ResultFailure, []string{"Failed to get image revisions", strconv.FormatUint(ImageID, 10), err.Error()})
+		return nil, err
+	}
+	return ToReturn, nil
+}
+
+//GetImageRevision returns a single previous file of an image
+func (DBConnection *MariaDBPlugin) GetImageRevision(RevisionID uint64) (interfaces.ImageRevisionInformation, error) {
+	revisions, err := DBConnection.queryImageRevisions("WHERE ImageRevisions.ID = ?;", RevisionID)
+	if err == nil && len(revisions) == 0 {
+		err = sql.ErrNoRows
+	}
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/GetImageRevision", "0", logging.ResultFailure, []string{"Failed to get image revision", strconv.FormatUint(RevisionID, 10), err.Error()})
+		return interfaces.ImageRevisionInformation{}, err
+	}
+	return revisions[0], nil
+}
+
+//UpdateImageRevisionLocation moves a previous file of an image to Location
+func (DBConnection *MariaDBPlugin) UpdateImageRevisionLocation(RevisionID uint64, Location string) error {
+	_, err := DBConnection.DBHandle.Exec("UPDATE ImageRevisions SET Location = ? WHERE ID = ?;", Location, RevisionID)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/UpdateImageRevisionLocation", "0", logging.ResultFailure, []string{"Failed to move image revision", strconv.FormatUint(RevisionID, 10), Location, err.Error()})
+		return err
+	}
+	return nil
+}
+
+//GetImageRevisionByLocation returns the previous file of an image stored at Location
+func (DBConnection *MariaDBPlugin) GetImageRevisionByLocation(Location string) (interfaces.ImageRevisionInformation, error) {
+	revisions, err := DBConnection.queryImageRevisions("WHERE ImageRevisions.Location = ? LIMIT 1;", Location)
+	if err == nil && len(revisions) == 0 {
+		err = sql.ErrNoRows
+	}
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/GetImageRevisionByLocation", "0", logging.ResultFailure, []string{"Failed to get image revision", Location, err.Error()})
+		return interfaces.ImageRevisionInformation{}, err
+	}
+	return revisions[0], nil
+}
+
+//RestoreImageRevision makes a previous file current again, the file it replaces becomes a revision in its place
+func (DBConnection *MariaDBPlugin) RestoreImageRevision(RevisionID uint64, RestorerID uint64) error {
+	revision, err := DBConnection.GetImageRevision(RevisionID)
+	if err != nil {
+		return err
+	}
+	if err := DBConnection.ReplaceImageFile(revision.ImageID, revision.Location, RestorerID); err != nil {
+		return err
+	}
+	if _, err := DBConnection.DBHandle.Exec("DELETE FROM ImageRevisions WHERE ID = ?;", RevisionID); err != nil {
+		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/RestoreImageRevision", strconv.FormatUint(RestorerID, 10), logging.ResultFailure, []string{"Failed to remove restored revision", strconv.FormatUint(RevisionID, 10), err.Error()})
+		return err
+	}
+	return nil
+}
+
+//queryImageRevisions returns image revisions, Suffix is added after the joins to filter and order them
+func (DBConnection *MariaDBPlugin) queryImageRevisions(Suffix string, Arguments ...interface{}) ([]interfaces.ImageRevisionInformation, error) {
+	rows, err := DBConnection.DBHandle.Query(`SELECT ImageRevisions.ID, ImageRevisions.ImageID, ImageRevisions.Location, ImageRevisions.ReplacerID, IFNULL(Users.Name, ''), ImageRevisions.ReplacedTime
+	FROM ImageRevisions
+	LEFT OUTER JOIN Users ON ImageRevisions.ReplacerID = Users.ID `+Suffix, Arguments...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ToReturn []interfaces.ImageRevisionInformation
+	for rows.Next() {
+		var revision interfaces.ImageRevisionInformation
+		var ReplacedTime mysql.NullTime
+		if err := rows.Scan(&revision.ID, &revision.ImageID, &revision.Location, &revision.ReplacerID, &revision.ReplacerName, &ReplacedTime); err != nil {
+			return nil, err
+		}
+		revision.ReplacedTime = ReplacedTime.Time
+		ToReturn = append(ToReturn, revision)
+	}
+	return ToReturn, rows.Err()
+}
@@ -14,7 +14,7 @@ func (DBConnection *MariaDBPlugin) GetImageTags(ImageID uint64) ([]interfaces.Ta
 
 	//SELECT Tags.ID AS ID, Tags.Name AS Name, Tags.Description AS Description FROM ImageTags INNER JOIN Tags ON Tags.ID = ImageTags.TagID WHERE ImageID=?
 
-	sqlQuery := "SELECT Tags.ID, Tags.Name, Tags.Description FROM ImageTags INNER JOIN Tags ON Tags.ID = ImageTags.TagID WHERE ImageID=?"
+	sqlQuery := "SELECT Tags.ID, Tags.Name, Tags.Description, Tags.Category FROM ImageTags INNER JOIN Tags ON Tags.ID = ImageTags.TagID WHERE ImageID=?"
 	//Pass the sql query to DB
 	rows, err := DBConnection.DBHandle.Query(sqlQuery, ImageID)
 	if err != nil {
@@ -25,10 +25,11 @@ func (DBConnection *MariaDBPlugin) GetImageTags(ImageID uint64) ([]interfaces.Ta
 	var Description sql.NullString
 	var ID uint64
 	var Name string
+	var Category string
 	//For each row
 	for rows.Next() {
 		//Parse out the data
-		err := rows.Scan(&ID, &Name, &Description)
+		err := rows.Scan(&ID, &Name, &Description, &Category)
 		if err != nil {
 			return nil, err
 		}
@@ -38,18 +39,24 @@ func (DBConnection *MariaDBPlugin) GetImageTags(ImageID uint64) ([]interfaces.Ta
 			SDescription = Description.String
 		}
 		//Add this result to ToReturn
-		ToReturn = append(ToReturn, interfaces.TagInformation{Name: Name, ID: ID, Description: SDescription, Exists: true, Exclude: false})
+		ToReturn = append(ToReturn, interfaces.TagInformation{Name: Name, ID: ID, Description: SDescription, Exists: true, Exclude: false, Category: Category})
 	}
 	return ToReturn, nil
 }
 
 //RemoveTag remove a tag association
-func (DBConnection *MariaDBPlugin) RemoveTag(TagID uint64, ImageID uint64) error {
-	if _, err := DBConnection.DBHandle.Exec("DELETE FROM ImageTags WHERE TagID=? AND ImageID=?;", TagID, ImageID); err != nil {
-		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/RemoveTag", "0", logging.ResultFailure, []string{"Tag to remove was not on image", strconv.FormatUint(TagID, 10), strconv.FormatUint(ImageID, 10), err.Error()})
+func (DBConnection *MariaDBPlugin) RemoveTag(TagID uint64, ImageID uint64, RemoverID uint64) error {
+	resultInfo, err := DBConnection.DBHandle.Exec("DELETE FROM ImageTags WHERE TagID=? AND ImageID=?;", TagID, ImageID)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/RemoveTag", strconv.FormatUint(RemoverID, 10), logging.ResultFailure, []string{"Tag to remove was not on image", strconv.FormatUint(TagID, 10), strconv.FormatUint(ImageID, 10), err.Error()})
 		return err
 	}
-	logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/RemoveTag", "0", logging.ResultSuccess, []string{"Tag removed", strconv.FormatUint(TagID, 10), strconv.FormatUint(ImageID, 10)})
+	if affected, _ := resultInfo.RowsAffected(); affected > 0 {
+		if err := DBConnection.recordTagChange(ImageID, TagID, RemoverID, false, interfaces.TagChangeRemove); err != nil {
+			return err
+		}
+	}
+	logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/RemoveTag", strconv.FormatUint(RemoverID, 10), logging.ResultSuccess, []string{"Tag removed", strconv.FormatUint(TagID, 10), strconv.FormatUint(ImageID, 10)})
 	return nil
 }
 
@@ -63,10 +70,10 @@ func tagsContainID(ID uint64, Tags []interfaces.TagInformation) bool {
 	return false
 }
 
-//tagsContainName is a helper function to check if a TagInformation slice contains a specified Name
-func tagsContainName(Name string, Tags []interfaces.TagInformation) bool {
-	for _, Tag := range Tags {
-		if Tag.Name == Name {
+//uint64SliceContains is a helper function that returns whether a slice contains a specifc ID
+func uint64SliceContains(slice []uint64, item uint64) bool {
+	for _, sliceItem := range slice {
+		if sliceItem == item {
 			return true
 		}
 	}
@@ -75,13 +82,22 @@ func tagsContainName(Name string, Tags []interfaces.TagInformation) bool {
 
 //ReplaceImageTags replaces all instances of ImageTags that have the specified tag with the new tag
 func (DBConnection *MariaDBPlugin) ReplaceImageTags(OldTagID uint64, NewTagID uint64, LinkerID uint64) error {
+	//Record the changes first, as afterwards there is no telling which images had the old tag
+	_, err := DBConnection.DBHandle.Exec("INSERT INTO ImageTagHistory (ImageID, TagID, UserID, Added, Operation) SELECT ImageID, ?, ?, TRUE, ? FROM ImageTags WHERE TagID=? AND ImageID NOT IN (SELECT ImageID FROM ImageTags WHERE TagID=?);", NewTagID, LinkerID, interfaces.TagChangeReplace, OldTagID, NewTagID)
+	if err == nil {
+		_, err = DBConnection.DBHandle.Exec("INSERT INTO ImageTagHistory (ImageID, TagID, UserID, Added, Operation) SELECT ImageID, TagID, ?, FALSE, ? FROM ImageTags WHERE TagID=?;", LinkerID, interfaces.TagChangeReplace, OldTagID)
+	}
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/ReplaceImageTags", strconv.FormatUint(LinkerID, 10), logging.ResultFailure, []string{"Failed to record tag history", err.Error()})
+		return err
+	}
 	query := `UPDATE ImageTags
 	SET TagID = ? , LinkerID=?
 	WHERE TagID=? AND ImageID NOT IN
 	(
 		SELECT ImageID from ImageTags WHERE TagID=?
 	);`
-	_, err := DBConnection.DBHandle.Exec(query, NewTagID, LinkerID, OldTagID, NewTagID)
+	_, err = DBConnection.DBHandle.Exec(query, NewTagID, LinkerID, OldTagID, NewTagID)
 	if err != nil {
 		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/ReplaceImageTags", strconv.FormatUint(LinkerID, 10), logging.ResultFailure, []string{"Failed to update imagetags", err.Error()})
 		return err
@@ -114,6 +130,10 @@ func (DBConnection *MariaDBPlugin) BulkAddTag(TagID uint64, OldTagID uint64, Lin
 		OldTagID = oldTagInfo.AliasedID
 	}
 
+	if _, err := DBConnection.DBHandle.Exec("INSERT INTO ImageTagHistory (ImageID, TagID, UserID, Added, Operation) SELECT ImageID, ?, ?, TRUE, ? FROM ImageTags WHERE TagID=? AND ImageID NOT IN (SELECT ImageID FROM ImageTags WHERE TagID=?);", TagID, LinkerID, interfaces.TagChangeBulkAdd, OldTagID, TagID); err != nil {
+		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/BulkAddTag", strconv.FormatUint(LinkerID, 10), logging.ResultFailure, []string{"Failed to record tag history", strconv.FormatUint(OldTagID, 10), strconv.FormatUint(TagID, 10), err.Error()})
+		return err
+	}
 	if _, err := DBConnection.DBHandle.Exec("INSERT INTO ImageTags (TagID, ImageID, LinkerID) SELECT ?, ImageID, ? FROM ImageTags WHERE TagID=? AND ImageID NOT IN (SELECT ImageID FROM ImageTags WHERE TagID=?);", TagID, LinkerID, OldTagID, TagID); err != nil {
 		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/BulkAddTag", strconv.FormatUint(LinkerID, 10), logging.ResultFailure, []string{"Tag not added to image", strconv.FormatUint(OldTagID, 10), strconv.FormatUint(TagID, 10), err.Error()})
 		return err
@@ -121,36 +141,3 @@ func (DBConnection *MariaDBPlugin) BulkAddTag(TagID uint64, OldTagID uint64, Lin
 	logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/BulkAddTag", strconv.FormatUint(LinkerID, 10), logging.ResultSuccess, []string{"Tags added", strconv.FormatUint(OldTagID, 10), strconv.FormatUint(TagID, 10)})
 	return nil
 }
-
-//sliceContains is a helper function that returns whether a slice contains a specifc string
-func sliceContains(slice []string, item string) bool {
-	for _, sliceItem := range slice {
-		if sliceItem == item {
-			return true
-		}
-	}
-	return false
-}
-
-//inverts a tags comparator
-func getInvertedComparator(comparator string) string {
-	if comparator == "=" {
-		return "!="
-	}
-	if comparator == ">" {
-		return "<="
-	}
-	if comparator == "<" {
-		return ">="
-	}
-	if comparator == ">=" {
-		return "<"
-	}
-	if comparator == "<=" {
-		return ">"
-	}
-	if comparator == "LIKE" {
-		return "NOT LIKE"
-	}
-	return ""
-}
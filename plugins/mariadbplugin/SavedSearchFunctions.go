@@ -0,0 +1,108 @@
+package mariadbplugin
+
+import (
+	"database/sql"
+	"go-image-board/interfaces"
+	"go-image-board/logging"
+	"strconv"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+//NewSavedSearch adds a saved search for Search.UserID, returns its ID
+func (DBConnection *MariaDBPlugin) NewSavedSearch(Search interfaces.SavedSearchInformation) (uint64, error) {
+	resultInfo, err := DBConnection.DBHandle.Exec("INSERT INTO SavedSearches (UserID, Name, Query, CollectionContext, Pinned, ShowNewCount) VALUES (?, ?, ?, ?, ?, ?);", Search.UserID, Search.Name, Search.Query, Search.CollectionContext, Search.Pinned, Search.ShowNewCount)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/NewSavedSearch", strconv.FormatUint(Search.UserID, 10), logging.ResultFailure, []string{"Failed to add saved search", Search.Name, err.Error()})
+		return 0, err
+	}
+	logging.WriteLog(logging.LogLevelInfo, "MariaDBPlugin/NewSavedSearch", strconv.FormatUint(Search.UserID, 10), logging.ResultSuccess, []string{"Saved search added", Search.Name})
+	id, _ := resultInfo.LastInsertId()
+	return uint64(id), nil
+}
+
+//UpdateSavedSearch changes the name, query and options of a saved search, only if it belongs to Search.UserID. Returns sql.ErrNoRows otherwise
+func (DBConnection *MariaDBPlugin) UpdateSavedSearch(Search interfaces.SavedSearchInformation) error {
+	resultInfo, err := DBConnection.DBHandle.Exec("UPDATE SavedSearches SET Name = ?, Query = ?, CollectionContext = ?, Pinned = ?, ShowNewCount = ? WHERE ID = ? AND UserID = ?;", Search.Name, Search.Query, Search.CollectionContext, Search.Pinned, Search.ShowNewCount, Search.ID, Search.UserID)
+	if err == nil {
+		if affected, _ := resultInfo.RowsAffected(); affected == 0 {
+			err = sql.ErrNoRows
+		}
+	}
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/UpdateSavedSearch", strconv.FormatUint(Search.UserID, 10), logging.ResultFailure, []string{"Failed to update saved search", strconv.FormatUint(Search.ID, 10), err.Error()})
+		return err
+	}
+	logging.WriteLog(logging.LogLevelInfo, "MariaDBPlugin/UpdateSavedSearch", strconv.FormatUint(Search.UserID, 10), logging.ResultSuccess, []string{"Saved search updated", strconv.FormatUint(Search.ID, 10)})
+	return nil
+}
+
+//DeleteSavedSearch removes a saved search, only if it belongs to UserID. Returns sql.ErrNoRows otherwise
+func (DBConnection *MariaDBPlugin) DeleteSavedSearch(ID uint64, UserID uint64) error {
+	resultInfo, err := DBConnection.DBHandle.Exec("DELETE FROM SavedSearches WHERE ID = ? AND UserID = ?;", ID, UserID)
+	if err == nil {
+		if affected, _ := resultInfo.RowsAffected(); affected == 0 {
+			err = sql.ErrNoRows
+		}
+	}
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/DeleteSavedSearch", strconv.FormatUint(UserID, 10), logging.ResultFailure, []string{"Failed to remove saved search", strconv.FormatUint(ID, 10), err.Error()})
+		return err
+	}
+	logging.WriteLog(logging.LogLevelInfo, "MariaDBPlugin/DeleteSavedSearch", strconv.FormatUint(UserID, 10), logging.ResultSuccess, []string{"Saved search removed", strconv.FormatUint(ID, 10)})
+	return nil
+}
+
+//GetSavedSearch returns a single saved search, whoever it belongs to
+func (DBConnection *MariaDBPlugin) GetSavedSearch(ID uint64) (interfaces.SavedSearchInformation, error) {
+	searches, err := DBConnection.querySavedSearches("WHERE ID = ?;", ID)
+	if err == nil && len(searches) == 0 {
+		err = sql.ErrNoRows
+	}
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/GetSavedSearch", "0", logging.ResultFailure, []string{"Failed to get saved search", strconv.FormatUint(ID, 10), err.Error()})
+		return interfaces.SavedSearchInformation{}, err
+	}
+	return searches[0], nil
+}
+
+//GetSavedSearches returns every saved search belonging to a user, ordered by name
+func (DBConnection *MariaDBPlugin) GetSavedSearches(UserID uint64) ([]interfaces.SavedSearchInformation, error) {
+	ToReturn, err := DBConnection.querySavedSearches("WHERE UserID = ? ORDER BY Name, ID;", UserID)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/GetSavedSearches", strconv.FormatUint(UserID, 10), logging.ResultFailure, []string{"Failed to get saved searches", err.Error()})
+		return nil, err
+	}
+	return ToReturn, nil
+}
+
+//SetSavedSearchViewed records that a saved search was opened now, so new results are counted from here
+func (DBConnection *MariaDBPlugin) SetSavedSearchViewed(ID uint64) error {
+	if _, err := DBConnection.DBHandle.Exec("UPDATE SavedSearches SET LastViewed = CURRENT_TIMESTAMP WHERE ID = ?;", ID); err != nil {
+		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/SetSavedSearchViewed", "0", logging.ResultFailure, []string{"Failed to update saved search", strconv.FormatUint(ID, 10), err.Error()})
+		return err
+	}
+	return nil
+}
+
+//querySavedSearches returns saved searches, Suffix is added after the table to filter and order them
+func (DBConnection *MariaDBPlugin) querySavedSearches(Suffix string, Arguments ...interface{}) ([]interfaces.SavedSearchInformation, error) {
+	rows, err := DBConnection.DBHandle.Query("SELECT ID, UserID, Name, Query, CollectionContext, Pinned, ShowNewCount, CreationTime, LastViewed FROM SavedSearches "+Suffix, Arguments...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ToReturn []interfaces.SavedSearchInformation
+	for rows.Next() {
+		var search interfaces.SavedSearchInformation
+		var CreationTime mysql.NullTime
+		var LastViewed mysql.NullTime
+		if err := rows.Scan(&search.ID, &search.UserID, &search.Name, &search.Query, &search.CollectionContext, &search.Pinned, &search.ShowNewCount, &CreationTime, &LastViewed); err != nil {
+			return nil, err
+		}
+		search.CreationTime = CreationTime.Time
+		search.LastViewed = LastViewed.Time
+		ToReturn = append(ToReturn, search)
+	}
+	return ToReturn, rows.Err()
+}
@@ -5,7 +5,7 @@ import (
 	"errors"
 	"go-image-board/interfaces"
 	"go-image-board/logging"
-	"regexp"
+	"go-image-board/plugins/sqlsearch"
 	"strconv"
 	"strings"
 	"time"
@@ -13,38 +13,24 @@ import (
 	"github.com/go-sql-driver/mysql"
 )
 
-//Tag Operations
-var regexTagName = regexp.MustCompile("[^a-zA-Z0-9_-]") //Used to cleanup tag names
-var regexWhiteSpace = regexp.MustCompile("\\s{2,}")     //Matches 2 or more consecutive whitespace
-var regexTagValue = regexp.MustCompile("[^a-zA-Z0-9_\\-\\.]")
-
-func prepareTagName(Name string) string {
-	//Lowercase Name -> Trimmed front and end of whitespace -> any inner whitespace reduced and underscored
-	Name = regexWhiteSpace.ReplaceAllString(strings.TrimSpace(strings.ToLower(Name)), "_") //Replace all whitespace with _
-	//Case of metatag
-	if strings.Count(Name, ":") == 1 {
-		//Assume a metatag
-		NameValue := strings.Split(Name, ":")
-		value, comparator := getTagComparator(NameValue[1]) //Strip comparator, so it does not get replaced by a _
-		Name = regexTagName.ReplaceAllString(NameValue[0], "_") + ":" + comparator + regexTagValue.ReplaceAllString(value, "_")
-	} else {
-		//Then any special characters replaced with _
-		Name = regexTagName.ReplaceAllString(Name, "_")
-	}
-	return Name
-}
-
 //NewTag adds a tag with the provided information
-func (DBConnection *MariaDBPlugin) NewTag(Name string, Description string, UploaderID uint64) (uint64, error) {
+func (DBConnection *MariaDBPlugin) NewTag(Name string, Description string, Category string, UploaderID uint64) (uint64, error) {
 	//Cleanup name
-	Name = prepareTagName(Name)
+	Name = sqlsearch.PrepareTagName(Name)
+	if Category == "" {
+		Category = interfaces.TagCategoryGeneral
+	}
 
 	if len(Name) < 3 || len(Name) > 255 || len(Description) > 255 {
 		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/NewTag", strconv.FormatUint(UploaderID, 10), logging.ResultFailure, []string{"Failed to add tag dues to size of name/description", Name, Description})
 		return 0, errors.New("name or description outside of right sizes")
 	}
+	if interfaces.IsTagCategory(Category) == false {
+		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/NewTag", strconv.FormatUint(UploaderID, 10), logging.ResultFailure, []string{"Failed to add tag due to unknown category", Name, Category})
+		return 0, errors.New("unknown tag category")
+	}
 
-	resultInfo, err := DBConnection.DBHandle.Exec("INSERT INTO Tags (Name, Description, UploaderID) VALUES (?, ?, ?);", Name, Description, UploaderID)
+	resultInfo, err := DBConnection.DBHandle.Exec("INSERT INTO Tags (Name, Description, UploaderID, Category) VALUES (?, ?, ?, ?);", Name, Description, UploaderID, Category)
 	if err != nil {
 		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/NewTag", strconv.FormatUint(UploaderID, 10), logging.ResultFailure, []string{"Failed to add tag", err.Error()})
 		return 0, err
@@ -69,6 +55,17 @@ func (DBConnection *MariaDBPlugin) DeleteTag(TagID uint64) error {
 		return errors.New("tag to delete is still in use")
 	}
 
+	//Only history refers to the tag now, which can no longer be reverted to
+	if _, err := DBConnection.DBHandle.Exec("DELETE FROM ImageTagHistory WHERE TagID=?;", TagID); err != nil {
+		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/DeleteTag", "0", logging.ResultFailure, []string{"Failed to delete tag history", err.Error(), strconv.FormatUint(TagID, 10)})
+		return err
+	}
+	//Implications go along with the tag
+	if _, err := DBConnection.DBHandle.Exec("DELETE FROM TagImplications WHERE TagID=? OR ImpliedTagID=?;", TagID, TagID); err != nil {
+		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/DeleteTag", "0", logging.ResultFailure, []string{"Failed to delete tag implications", err.Error(), strconv.FormatUint(TagID, 10)})
+		return err
+	}
+
 	//Delete
 	_, err := DBConnection.DBHandle.Exec("DELETE FROM Tags WHERE ID=?;", TagID)
 	if err != nil {
@@ -86,36 +83,58 @@ func (DBConnection *MariaDBPlugin) AddTag(TagIDs []uint64, ImageID uint64, Linke
 	}
 	//Validate tags, if some are alias, add alias instead, if a tag does not exist, error out
 	var validatedTagIDs []uint64
-	values := ""
-	queryArray := []interface{}{}
 	for i := 0; i < len(TagIDs); i++ {
 		TagID := TagIDs[i]
 		tagInfo, err := DBConnection.GetTag(TagID, false)
 		if err != nil {
 			return errors.New("Failed to validate tag " + strconv.FormatUint(TagID, 10))
 		}
-		values += " ("
 		//If this is an alias, then add aliasedid instead
 		if tagInfo.IsAlias {
 			validatedTagIDs = append(validatedTagIDs, tagInfo.AliasedID)
-			values += " ?,"
-			queryArray = append(queryArray, tagInfo.AliasedID)
 		} else {
 			validatedTagIDs = append(validatedTagIDs, TagID)
-			values += " ?,"
-			queryArray = append(queryArray, TagID)
 		}
+	}
+	//Add every tag the validated tags imply as well, without duplicates
+	allTagIDs, err := DBConnection.getImpliedTags(validatedTagIDs)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/AddTag", strconv.FormatUint(LinkerID, 10), logging.ResultFailure, []string{"Failed to get implied tags", strconv.FormatUint(ImageID, 10), err.Error()})
+		return err
+	}
+	values := ""
+	queryArray := []interface{}{}
+	for _, TagID := range allTagIDs {
+		values += " ( ?, ?, ?),"
+		queryArray = append(queryArray, TagID)
 		queryArray = append(queryArray, ImageID)
 		queryArray = append(queryArray, LinkerID)
-		values += " ?, ?),"
 	}
 	values = values[:len(values)-1] + " ON DUPLICATE KEY UPDATE LinkerID=?;" //Strip last comma, add end
 	queryArray = append(queryArray, LinkerID)                                //For duplicate key update
+	//Only tags the image does not already have are recorded as changes
+	existingTags, err := DBConnection.GetImageTags(ImageID)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/AddTag", strconv.FormatUint(LinkerID, 10), logging.ResultFailure, []string{"Failed to get current tags of image", strconv.FormatUint(ImageID, 10), err.Error()})
+		return err
+	}
 	sqlQuery := "INSERT INTO ImageTags (TagID, ImageID, LinkerID) VALUES" + values
 	if _, err := DBConnection.DBHandle.Exec(sqlQuery, queryArray...); err != nil {
 		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/AddTag", strconv.FormatUint(LinkerID, 10), logging.ResultFailure, []string{"Tags not added to image", strconv.FormatUint(ImageID, 10), sqlQuery, err.Error()})
 		return err
 	}
+	for _, TagID := range allTagIDs {
+		if tagsContainID(TagID, existingTags) {
+			continue
+		}
+		operation := interfaces.TagChangeAdd
+		if uint64SliceContains(validatedTagIDs, TagID) == false {
+			operation = interfaces.TagChangeImply
+		}
+		if err := DBConnection.recordTagChange(ImageID, TagID, LinkerID, true, operation); err != nil {
+			return err
+		}
+	}
 	logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/AddTag", strconv.FormatUint(LinkerID, 10), logging.ResultSuccess, []string{"Tags added", strconv.FormatUint(ImageID, 10)})
 	return nil
 }
@@ -124,7 +143,7 @@ func (DBConnection *MariaDBPlugin) AddTag(TagIDs []uint64, ImageID uint64, Linke
 func (DBConnection *MariaDBPlugin) GetAllTags() ([]interfaces.TagInformation, error) {
 	var ToReturn []interfaces.TagInformation
 
-	sqlQuery := "SELECT ID, Name, Description, IsAlias FROM Tags ORDER BY Name"
+	sqlQuery := "SELECT ID, Name, Description, IsAlias, Category FROM Tags ORDER BY Name"
 	//Pass the sql query to DB
 	rows, err := DBConnection.DBHandle.Query(sqlQuery)
 	if err != nil {
@@ -136,10 +155,11 @@ func (DBConnection *MariaDBPlugin) GetAllTags() ([]interfaces.TagInformation, er
 	var ID uint64
 	var Name string
 	var IsAlias bool
+	var Category string
 	//For each row
 	for rows.Next() {
 		//Parse out the data
-		err := rows.Scan(&ID, &Name, &Description, &IsAlias)
+		err := rows.Scan(&ID, &Name, &Description, &IsAlias, &Category)
 		if err != nil {
 			return nil, err
 		}
@@ -149,14 +169,14 @@ func (DBConnection *MariaDBPlugin) GetAllTags() ([]interfaces.TagInformation, er
 			SDescription = Description.String
 		}
 		//Add this result to ToReturn
-		ToReturn = append(ToReturn, interfaces.TagInformation{Name: Name, ID: ID, Description: SDescription, Exists: true, Exclude: false, IsAlias: IsAlias})
+		ToReturn = append(ToReturn, interfaces.TagInformation{Name: Name, ID: ID, Description: SDescription, Exists: true, Exclude: false, IsAlias: IsAlias, Category: Category})
 	}
 	return ToReturn, nil
 }
 
 //GetTag returns detailed information on one tag
 func (DBConnection *MariaDBPlugin) GetTag(ID uint64, IncludeCount bool) (interfaces.TagInformation, error) {
-	sqlQuery := "SELECT Name, Description, UploaderID, UploadTime, AliasedID, IsAlias FROM Tags WHERE ID=?"
+	sqlQuery := "SELECT Name, Description, UploaderID, UploadTime, AliasedID, IsAlias, Category FROM Tags WHERE ID=?"
 	//Pass the sql query to DB
 	//Placeholders for data returned by each row
 	var Description sql.NullString
@@ -166,8 +186,9 @@ func (DBConnection *MariaDBPlugin) GetTag(ID uint64, IncludeCount bool) (interfa
 	var UploadTime time.Time
 	var AliasedID uint64
 	var IsAlias bool
+	var Category string
 	var TagCount uint64
-	err := DBConnection.DBHandle.QueryRow(sqlQuery, ID).Scan(&Name, &Description, &UploaderID, &NUploadTime, &AliasedID, &IsAlias)
+	err := DBConnection.DBHandle.QueryRow(sqlQuery, ID).Scan(&Name, &Description, &UploaderID, &NUploadTime, &AliasedID, &IsAlias, &Category)
 	if err != nil {
 		return interfaces.TagInformation{ID: ID, Exists: false}, err
 	}
@@ -189,12 +210,12 @@ func (DBConnection *MariaDBPlugin) GetTag(ID uint64, IncludeCount bool) (interfa
 		}
 	}
 
-	return interfaces.TagInformation{Name: Name, ID: ID, Description: SDescription, Exists: true, Exclude: false, UploaderID: UploaderID, UploadTime: UploadTime, AliasedID: AliasedID, IsAlias: IsAlias, UseCount: TagCount}, nil
+	return interfaces.TagInformation{Name: Name, ID: ID, Description: SDescription, Exists: true, Exclude: false, UploaderID: UploaderID, UploadTime: UploadTime, AliasedID: AliasedID, IsAlias: IsAlias, Category: Category, UseCount: TagCount}, nil
 }
 
 //GetTagByName returns detailed information on one tag as queried by name
 func (DBConnection *MariaDBPlugin) GetTagByName(Name string) (interfaces.TagInformation, error) {
-	sqlQuery := "SELECT ID, Description, UploaderID, UploadTime, AliasedID, IsAlias FROM Tags WHERE Name=?"
+	sqlQuery := "SELECT ID, Description, UploaderID, UploadTime, AliasedID, IsAlias, Category FROM Tags WHERE Name=?"
 	//Pass the sql query to DB
 	//Placeholders for data returned by each row
 	var Description sql.NullString
@@ -204,7 +225,8 @@ func (DBConnection *MariaDBPlugin) GetTagByName(Name string) (interfaces.TagInfo
 	var UploadTime time.Time
 	var AliasedID uint64
 	var IsAlias bool
-	err := DBConnection.DBHandle.QueryRow(sqlQuery, Name).Scan(&TagID, &Description, &UploaderID, &NUploadTime, &AliasedID, &IsAlias)
+	var Category string
+	err := DBConnection.DBHandle.QueryRow(sqlQuery, Name).Scan(&TagID, &Description, &UploaderID, &NUploadTime, &AliasedID, &IsAlias, &Category)
 	if err != nil {
 		return interfaces.TagInformation{Name: Name, Exists: false}, err
 	}
@@ -218,17 +240,24 @@ func (DBConnection *MariaDBPlugin) GetTagByName(Name string) (interfaces.TagInfo
 		UploadTime = NUploadTime.Time
 	}
 
-	return interfaces.TagInformation{Name: Name, ID: TagID, Description: SDescription, Exists: true, Exclude: false, UploaderID: UploaderID, UploadTime: UploadTime, AliasedID: AliasedID, IsAlias: IsAlias}, nil
+	return interfaces.TagInformation{Name: Name, ID: TagID, Description: SDescription, Exists: true, Exclude: false, UploaderID: UploaderID, UploadTime: UploadTime, AliasedID: AliasedID, IsAlias: IsAlias, Category: Category}, nil
 }
 
 //UpdateTag updates a pre-existing tag
-func (DBConnection *MariaDBPlugin) UpdateTag(TagID uint64, Name string, Description string, AliasedID uint64, IsAlias bool, RequestorID uint64) error {
+func (DBConnection *MariaDBPlugin) UpdateTag(TagID uint64, Name string, Description string, Category string, AliasedID uint64, IsAlias bool, RequestorID uint64) error {
 	//Cleanup name
-	Name = prepareTagName(Name)
+	Name = sqlsearch.PrepareTagName(Name)
 	if len(Name) < 3 || len(Name) > 255 || len(Description) > 255 {
 		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/UpdateTag", strconv.FormatUint(RequestorID, 10), logging.ResultFailure, []string{"Failed to update tag dues to size", Name, Description})
 		return errors.New("name or description outside of right sizes")
 	}
+	if Category == "" {
+		Category = interfaces.TagCategoryGeneral
+	}
+	if interfaces.IsTagCategory(Category) == false {
+		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/UpdateTag", strconv.FormatUint(RequestorID, 10), logging.ResultFailure, []string{"Failed to update tag due to unknown category", Name, Category})
+		return errors.New("unknown tag category")
+	}
 
 	if IsAlias {
 		//Prevent adding alias
@@ -238,7 +267,7 @@ func (DBConnection *MariaDBPlugin) UpdateTag(TagID uint64, Name string, Descript
 		}
 	}
 
-	_, err := DBConnection.DBHandle.Exec("UPDATE Tags SET Name = ?, Description=?, AliasedID=?, IsAlias=? WHERE ID=?;", Name, Description, AliasedID, IsAlias, TagID)
+	_, err := DBConnection.DBHandle.Exec("UPDATE Tags SET Name = ?, Description=?, Category=?, AliasedID=?, IsAlias=? WHERE ID=?;", Name, Description, Category, AliasedID, IsAlias, TagID)
 	if err != nil {
 		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/UpdateTag", strconv.FormatUint(RequestorID, 10), logging.ResultFailure, []string{"Failed to update tag", err.Error()})
 		return err
@@ -256,7 +285,7 @@ func (DBConnection *MariaDBPlugin) UpdateTag(TagID uint64, Name string, Descript
 func (DBConnection *MariaDBPlugin) SearchTags(name string, PageStart uint64, PageStride uint64, WildcardForwardOnly bool, SortByUsage bool) ([]interfaces.TagInformation, uint64, error) {
 	var ToReturn []interfaces.TagInformation
 	queryArray := []interface{}{}
-	sqlQuery := "SELECT ID, Name, Description, IsAlias FROM Tags"
+	sqlQuery := "SELECT ID, Name, Description, IsAlias, Category FROM Tags"
 	sqlCountQuery := "SELECT Count(*) FROM Tags"
 
 	if SortByUsage {
@@ -311,10 +340,11 @@ func (DBConnection *MariaDBPlugin) SearchTags(name string, PageStart uint64, Pag
 	var ID uint64
 	var Name string
 	var IsAlias bool
+	var Category string
 	//For each row
 	for rows.Next() {
 		//Parse out the data
-		err := rows.Scan(&ID, &Name, &Description, &IsAlias)
+		err := rows.Scan(&ID, &Name, &Description, &IsAlias, &Category)
 		if err != nil {
 			return nil, 0, err
 		}
@@ -324,7 +354,7 @@ func (DBConnection *MariaDBPlugin) SearchTags(name string, PageStart uint64, Pag
 			SDescription = Description.String
 		}
 		//Add this result to ToReturn
-		ToReturn = append(ToReturn, interfaces.TagInformation{Name: Name, ID: ID, Description: SDescription, Exists: true, Exclude: false, IsAlias: IsAlias})
+		ToReturn = append(ToReturn, interfaces.TagInformation{Name: Name, ID: ID, Description: SDescription, Exists: true, Exclude: false, IsAlias: IsAlias, Category: Category})
 	}
 	return ToReturn, MaxResults, nil
 }
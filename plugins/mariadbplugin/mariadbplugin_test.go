@@ -0,0 +1,84 @@
+package mariadbplugin
+
+import (
+	"go-image-board/config"
+	"go-image-board/logging"
+	"go-image-board/plugins"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+func TestMain(m *testing.M) {
+	logging.LogInterface = &plugins.STDLog{}
+	logging.LogInterface.Init(-1, "", "")
+	os.Exit(m.Run())
+}
+
+func TestDataSourceNameUsesUTC(t *testing.T) {
+	config.Configuration.DBUser, config.Configuration.DBPassword = "gib", "p@ss:word"
+	config.Configuration.DBHost, config.Configuration.DBPort, config.Configuration.DBName = "localhost", "3306", "gib"
+	dsn, err := mysql.ParseDSN(dataSourceName())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dsn.Params["time_zone"] != "'+00:00'" || dsn.Loc != time.UTC {
+		t.Errorf("connections use time zone %q, and times are sent in %v", dsn.Params["time_zone"], dsn.Loc)
+	}
+	if dsn.User != "gib" || dsn.Passwd != "p@ss:word" || dsn.Addr != "localhost:3306" || dsn.DBName != "gib" {
+		t.Errorf("DSN is %+v", dsn)
+	}
+}
+
+//TestUploadedRangeBoundaries checks a date range includes its first second and excludes its end, whatever the server's time zone
+//To run it, set GIB_TEST_MARIADB_HOST, GIB_TEST_MARIADB_PORT, GIB_TEST_MARIADB_USER, GIB_TEST_MARIADB_PASSWORD and GIB_TEST_MARIADB_NAME. The database should be empty.
+func TestUploadedRangeBoundaries(t *testing.T) {
+	if os.Getenv("GIB_TEST_MARIADB_HOST") == "" {
+		t.Skip("GIB_TEST_MARIADB_HOST is not set")
+	}
+	config.Configuration.DBHost, config.Configuration.DBPort = os.Getenv("GIB_TEST_MARIADB_HOST"), os.Getenv("GIB_TEST_MARIADB_PORT")
+	config.Configuration.DBUser, config.Configuration.DBPassword = os.Getenv("GIB_TEST_MARIADB_USER"), os.Getenv("GIB_TEST_MARIADB_PASSWORD")
+	config.Configuration.DBName = os.Getenv("GIB_TEST_MARIADB_NAME")
+	db := &MariaDBPlugin{}
+	if err := db.InitDatabase(); err != nil {
+		t.Fatalf("InitDatabase: %v", err)
+	}
+	defer db.DBHandle.Close()
+	db.CreateUser("uploader", []byte("uploaderpass"), "uploader@example.com", 0)
+	uploaderID, err := db.GetUserID("uploader")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//FROM_UNIXTIME stores the instant, independent of how the session zone writes it
+	start := time.Date(2001, 9, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+	uploaded := map[string]time.Time{"before": start.Add(-time.Second), "first": start, "last": end.Add(-time.Second), "after": end}
+	for name, uploadTime := range uploaded {
+		imageID, err := db.NewImage(name, name+".png", uploaderID, "", false)
+		if err == nil {
+			_, err = db.DBHandle.Exec("UPDATE Images SET UploadTime = FROM_UNIXTIME(?) WHERE ID = ?;", uploadTime.Unix(), imageID)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tags, err := db.GetQueryTags("uploaded:2001-09", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	images, _, err := db.SearchImages(tags, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := map[string]bool{}
+	for _, image := range images {
+		found[image.Name] = true
+	}
+	if len(images) != 2 || found["first"] == false || found["last"] == false {
+		t.Errorf("uploaded:2001-09 found %+v", images)
+	}
+}
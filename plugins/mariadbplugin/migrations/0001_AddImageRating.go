@@ -0,0 +1,14 @@
+package migrations
+
+import "go-image-board/plugins/migrate"
+
+func init() {
+	Registry.Register(migrate.Migration{
+		Version:     1,
+		Description: "Add image ratings",
+		Statements: []string{
+			"ALTER TABLE Images ADD COLUMN (Rating VARCHAR(255) DEFAULT 'unrated');",
+			"UPDATE Images SET Rating = 'unrated';",
+		},
+	})
+}
@@ -0,0 +1,14 @@
+package migrations
+
+import "go-image-board/plugins/migrate"
+
+func init() {
+	Registry.Register(migrate.Migration{
+		Version:     2,
+		Description: "Add image scores",
+		Statements: []string{
+			"ALTER TABLE Images ADD COLUMN (ScoreTotal BIGINT NOT NULL DEFAULT 0, ScoreAverage BIGINT NOT NULL DEFAULT 0, ScoreVoters BIGINT NOT NULL DEFAULT 0);",
+			"CREATE TABLE ImageUserScores (ID BIGINT UNSIGNED NOT NULL AUTO_INCREMENT UNIQUE, UserID BIGINT UNSIGNED NOT NULL, ImageID BIGINT UNSIGNED NOT NULL, Score BIGINT NOT NULL, CreationTime TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL, UNIQUE INDEX ImageUserPair (UserID,ImageID));",
+		},
+	})
+}
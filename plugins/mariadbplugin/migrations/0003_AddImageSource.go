@@ -0,0 +1,13 @@
+package migrations
+
+import "go-image-board/plugins/migrate"
+
+func init() {
+	Registry.Register(migrate.Migration{
+		Version:     3,
+		Description: "Add image source",
+		Statements: []string{
+			"ALTER TABLE Images ADD COLUMN (Source VARCHAR(2000) NOT NULL DEFAULT '');",
+		},
+	})
+}
@@ -0,0 +1,13 @@
+package migrations
+
+import "go-image-board/plugins/migrate"
+
+func init() {
+	Registry.Register(migrate.Migration{
+		Version:     4,
+		Description: "Add user search filter",
+		Statements: []string{
+			"ALTER TABLE Users ADD COLUMN (SearchFilter VARCHAR(255) NOT NULL DEFAULT '');",
+		},
+	})
+}
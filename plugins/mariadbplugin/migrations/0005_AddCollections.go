@@ -0,0 +1,14 @@
+package migrations
+
+import "go-image-board/plugins/migrate"
+
+func init() {
+	Registry.Register(migrate.Migration{
+		Version:     5,
+		Description: "Add collections",
+		Statements: []string{
+			"CREATE TABLE Collections (ID BIGINT UNSIGNED NOT NULL AUTO_INCREMENT UNIQUE, Name VARCHAR(255) NOT NULL UNIQUE, Description VARCHAR(255), UploaderID BIGINT UNSIGNED NOT NULL, UploadTime TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL);",
+			"CREATE TABLE CollectionMembers (ID BIGINT UNSIGNED NOT NULL AUTO_INCREMENT UNIQUE, ImageID BIGINT UNSIGNED NOT NULL, CollectionID BIGINT UNSIGNED NOT NULL, LinkerID BIGINT UNSIGNED NOT NULL, LinkTime TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL, UNIQUE INDEX ImageCollectionPair (CollectionID,ImageID), OrderWeight BIGINT UNSIGNED NOT NULL);",
+		},
+	})
+}
@@ -0,0 +1,14 @@
+package migrations
+
+import "go-image-board/plugins/migrate"
+
+func init() {
+	Registry.Register(migrate.Migration{
+		Version:     6,
+		Description: "Extend audit logs",
+		Statements: []string{
+			"ALTER TABLE AuditLogs ADD COLUMN (LogTime TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL);",
+			"ALTER TABLE AuditLogs CHANGE COLUMN Info Info VARCHAR(10240) NOT NULL DEFAULT '';",
+		},
+	})
+}
@@ -0,0 +1,89 @@
+package migrations
+
+import "go-image-board/plugins/migrate"
+
+func init() {
+	Registry.Register(migrate.Migration{
+		Version:     7,
+		Description: "Add collection tags",
+		Statements: []string{
+			"CREATE TABLE CollectionTags (ID BIGINT UNSIGNED NOT NULL AUTO_INCREMENT UNIQUE, CollectionID BIGINT UNSIGNED NOT NULL, TagID BIGINT UNSIGNED NOT NULL, LinkerID BIGINT UNSIGNED NOT NULL, LinkTime TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL, UNIQUE INDEX CollectionTagPair (TagID,CollectionID));",
+			`CREATE PROCEDURE LinkCollTags(IN collID BIGINT UNSIGNED)
+	BEGIN
+	-- Insert missing tags
+	INSERT INTO CollectionTags (TagID, CollectionID, LinkerID)
+	SELECT DISTINCT(ImageTags.TagID), collID, ImageTags.LinkerID
+	FROM ImageTags
+	INNER JOIN CollectionMembers on CollectionMembers.ImageID = ImageTags.ImageID
+	WHERE CollectionMembers.CollectionID = collID
+	AND TagID NOT IN (SELECT TagID from CollectionTags WHERE CollectionID = collID);
+	-- Remove extra tags
+	DELETE FROM CollectionTags
+	WHERE TagID NOT IN ( SELECT TagID 
+						 FROM ImageTags 
+						 INNER JOIN CollectionMembers on CollectionMembers.ImageID = ImageTags.ImageID
+						 WHERE CollectionMembers.CollectionID = collID
+					   )
+	AND CollectionID=collID;
+	END`,
+			`CREATE EVENT auditCleanup 
+	ON SCHEDULE EVERY 1 DAY 
+	DO 
+	DELETE FROM AuditLogs WHERE LogTime < DATE_SUB(current_timestamp(), INTERVAL 30 DAY);`,
+			`CREATE TRIGGER onCollectionDelete BEFORE DELETE ON Collections
+	FOR EACH ROW BEGIN
+		DELETE FROM CollectionMembers WHERE CollectionID=OLD.ID;
+		DELETE FROM CollectionTags WHERE CollectionID=OLD.ID;
+	END`,
+			`CREATE TRIGGER onCollectionMemberAdd AFTER INSERT ON CollectionMembers
+	FOR EACH ROW BEGIN
+		CALL LinkCollTags(NEW.CollectionID);
+	END`,
+			`CREATE TRIGGER onCollectionMemberDelete AFTER DELETE ON CollectionMembers
+	FOR EACH ROW BEGIN
+		CALL LinkCollTags(OLD.CollectionID);
+	END`,
+			`CREATE TRIGGER onImageTagDelete AFTER DELETE ON ImageTags
+	FOR EACH ROW BEGIN
+		DECLARE collID BIGINT UNSIGNED;
+		DECLARE cursorDone BOOL DEFAULT FALSE;
+		DECLARE collCursor CURSOR FOR SELECT CollectionID FROM CollectionMembers WHERE ImageID = OLD.ImageID;
+		DECLARE CONTINUE HANDLER FOR NOT FOUND SET cursorDone = TRUE;
+		OPEN collCursor;
+		collLoop: LOOP
+			FETCH collCursor INTO collID;
+			IF cursorDone THEN
+				LEAVE collLoop;
+			END IF;
+			CALL LinkCollTags(collID);
+		END LOOP;
+	END`,
+			`CREATE TRIGGER onImageDelete BEFORE DELETE ON Images
+	FOR EACH ROW BEGIN
+		DELETE FROM ImageTags WHERE ImageID=OLD.ID;
+		DELETE FROM ImageUserScores WHERE ImageID=OLD.ID;
+		DELETE FROM CollectionMembers WHERE ImageID=OLD.ID;
+	END`,
+			`CREATE TRIGGER onImageTagInsert AFTER INSERT ON ImageTags
+	FOR EACH ROW BEGIN
+		DECLARE collID BIGINT UNSIGNED;
+		DECLARE cursorDone BOOL DEFAULT FALSE;
+		DECLARE collCursor CURSOR FOR SELECT CollectionID FROM CollectionMembers WHERE ImageID = NEW.ImageID;
+		DECLARE CONTINUE HANDLER FOR NOT FOUND SET cursorDone = TRUE;
+		OPEN collCursor;
+		collLoop: LOOP
+			FETCH collCursor INTO collID;
+			IF cursorDone THEN
+				LEAVE collLoop;
+			END IF;
+			CALL LinkCollTags(collID);
+		END LOOP;
+	END`,
+			`CREATE TRIGGER onTagDelete BEFORE DELETE ON Tags
+	FOR EACH ROW BEGIN
+		DELETE FROM ImageTags WHERE TagID=OLD.ID;
+		DELETE FROM CollectionTags WHERE TagID=OLD.ID;
+	END`,
+		},
+	})
+}
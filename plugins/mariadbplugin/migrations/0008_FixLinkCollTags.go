@@ -0,0 +1,31 @@
+package migrations
+
+import "go-image-board/plugins/migrate"
+
+func init() {
+	Registry.Register(migrate.Migration{
+		Version:     8,
+		Description: "Fix collection tag linking",
+		Statements: []string{
+			"DROP PROCEDURE IF EXISTS LinkCollTags;",
+			`CREATE PROCEDURE LinkCollTags(IN collID BIGINT UNSIGNED)
+	BEGIN
+	-- Insert missing tags
+	INSERT INTO CollectionTags (TagID, CollectionID, LinkerID)
+	SELECT DISTINCT ImageTags.TagID, collID, ImageTags.LinkerID
+	FROM ImageTags
+	INNER JOIN CollectionMembers on CollectionMembers.ImageID = ImageTags.ImageID
+	LEFT JOIN CollectionTags on CollectionTags.CollectionID = CollectionMembers.CollectionID AND CollectionTags.TagID = ImageTags.TagID
+	WHERE CollectionMembers.CollectionID = collID AND CollectionTags.CollectionID IS NULL;
+	-- Remove extra tags
+	DELETE FROM CollectionTags
+	WHERE TagID NOT IN ( SELECT TagID 
+							FROM ImageTags 
+							INNER JOIN CollectionMembers on CollectionMembers.ImageID = ImageTags.ImageID
+							WHERE CollectionMembers.CollectionID = collID
+						)
+	AND CollectionID=collID;
+	END`,
+		},
+	})
+}
@@ -0,0 +1,75 @@
+package migrations
+
+import "go-image-board/plugins/migrate"
+
+func init() {
+	Registry.Register(migrate.Migration{
+		Version:     9,
+		Description: "Add indexes and split collection tag procedures",
+		Statements: []string{
+			"ALTER TABLE ImageTags ADD INDEX(ImageID);",
+			"ALTER TABLE ImageTags ADD INDEX(LinkerID);",
+			"ALTER TABLE Images ADD INDEX(UploaderID);",
+			"ALTER TABLE Images ADD INDEX(Rating);",
+			"ALTER TABLE Images ADD INDEX(UploadTime);",
+			"ALTER TABLE Images ADD INDEX(ScoreAverage);",
+			`CREATE PROCEDURE AddMissingCollectionImageTags(IN imgID BIGINT UNSIGNED)
+	BEGIN
+		-- Insert missing tags
+		INSERT INTO CollectionTags(TagID, CollectionID, LinkerID)
+	SELECT DISTINCT
+		ImageTags.TagID,
+		CollectionMembers.CollectionID,
+		ImageTags.LinkerID
+	FROM
+		ImageTags
+	INNER JOIN CollectionMembers ON CollectionMembers.ImageID = ImageTags.ImageID
+	LEFT JOIN CollectionTags ON CollectionTags.CollectionID = CollectionMembers.CollectionID AND CollectionTags.TagID = ImageTags.TagID
+	WHERE
+		CollectionTags.CollectionID IS NULL AND ImageTags.ImageID = imgID;
+	END`,
+			`CREATE PROCEDURE RemSurplusCollectionImageTags(IN collID BIGINT UNSIGNED)
+	BEGIN
+		-- Remove extra tags
+		DELETE FROM CollectionTags
+		WHERE TagID NOT IN ( SELECT TagID 
+								FROM ImageTags 
+								INNER JOIN CollectionMembers on CollectionMembers.ImageID = ImageTags.ImageID
+								WHERE CollectionMembers.CollectionID = collID
+							)
+		AND CollectionID=collID;
+	END`,
+			"DROP TRIGGER IF EXISTS onImageTagInsert;",
+			"DROP TRIGGER IF EXISTS onCollectionMemberAdd;",
+			"DROP TRIGGER IF EXISTS onImageTagDelete;",
+			"DROP TRIGGER IF EXISTS onCollectionMemberDelete;",
+			`CREATE TRIGGER onImageTagDelete AFTER DELETE ON ImageTags
+	FOR EACH ROW BEGIN
+		DECLARE collID BIGINT UNSIGNED;
+		DECLARE cursorDone BOOL DEFAULT FALSE;
+		DECLARE collCursor CURSOR FOR SELECT CollectionID FROM CollectionMembers WHERE ImageID = OLD.ImageID;
+		DECLARE CONTINUE HANDLER FOR NOT FOUND SET cursorDone = TRUE;
+		OPEN collCursor;
+		collLoop: LOOP
+			FETCH collCursor INTO collID;
+			IF cursorDone THEN
+				LEAVE collLoop;
+			END IF;
+			CALL RemSurplusCollectionImageTags(collID);
+		END LOOP;
+	END`,
+			`CREATE TRIGGER onCollectionMemberDelete AFTER DELETE ON CollectionMembers
+	FOR EACH ROW BEGIN
+		CALL RemSurplusCollectionImageTags(OLD.CollectionID);
+	END`,
+			`CREATE TRIGGER onImageTagInsert AFTER INSERT ON ImageTags
+	FOR EACH ROW BEGIN
+		CALL AddMissingCollectionImageTags(NEW.ImageID);
+	END`,
+			`CREATE TRIGGER onCollectionMemberAdd AFTER INSERT ON CollectionMembers
+	FOR EACH ROW BEGIN
+		CALL AddMissingCollectionImageTags(NEW.ImageID);
+	END`,
+		},
+	})
+}
@@ -0,0 +1,13 @@
+package migrations
+
+import "go-image-board/plugins/migrate"
+
+func init() {
+	Registry.Register(migrate.Migration{
+		Version:     10,
+		Description: "Add image descriptions",
+		Statements: []string{
+			"ALTER TABLE Images ADD COLUMN (Description VARCHAR(1024) DEFAULT '');",
+		},
+	})
+}
@@ -0,0 +1,13 @@
+package migrations
+
+import "go-image-board/plugins/migrate"
+
+func init() {
+	Registry.Register(migrate.Migration{
+		Version:     11,
+		Description: "Allow longer image descriptions",
+		Statements: []string{
+			"ALTER TABLE Images MODIFY Description TEXT NOT NULL DEFAULT '';",
+		},
+	})
+}
@@ -0,0 +1,24 @@
+package migrations
+
+import "go-image-board/plugins/migrate"
+
+func init() {
+	Registry.Register(migrate.Migration{
+		Version:     13,
+		Description: "Add foreign keys",
+		Statements: []string{
+			"ALTER TABLE ImagedHashes ADD CONSTRAINT fk_ImagedHashesImageID FOREIGN KEY (ImageID) REFERENCES Images(ID);",
+			"ALTER TABLE CollectionMembers ADD CONSTRAINT fk_CollectionMembersImageID FOREIGN KEY (ImageID) REFERENCES Images(ID), ADD CONSTRAINT fk_CollectionMembersCollectionID FOREIGN KEY (CollectionID) REFERENCES Collections(ID);",
+			"ALTER TABLE CollectionTags ADD CONSTRAINT fk_CollectionTagsCollectionID FOREIGN KEY (CollectionID) REFERENCES Collections(ID), ADD CONSTRAINT fk_CollectionTagsTagID FOREIGN KEY (TagID) REFERENCES Tags(ID);",
+			"ALTER TABLE ImageTags ADD CONSTRAINT fk_ImageTagsImageID FOREIGN KEY (ImageID) REFERENCES Images(ID), ADD CONSTRAINT fk_ImageTagsTagID FOREIGN KEY (TagID) REFERENCES Tags(ID);",
+			"DROP TRIGGER onImageDelete;",
+			`CREATE TRIGGER onImageDelete BEFORE DELETE ON Images
+	FOR EACH ROW BEGIN
+		DELETE FROM ImageTags WHERE ImageID=OLD.ID;
+		DELETE FROM ImageUserScores WHERE ImageID=OLD.ID;
+		DELETE FROM CollectionMembers WHERE ImageID=OLD.ID;
+		DELETE FROM ImagedHashes WHERE ImageID=OLD.ID;
+	END`,
+		},
+	})
+}
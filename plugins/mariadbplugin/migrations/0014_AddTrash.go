@@ -0,0 +1,14 @@
+package migrations
+
+import "go-image-board/plugins/migrate"
+
+func init() {
+	Registry.Register(migrate.Migration{
+		Version:     14,
+		Description: "Add trash to images and collections",
+		Statements: []string{
+			"ALTER TABLE Images ADD COLUMN DeletedTime TIMESTAMP NULL DEFAULT NULL, ADD COLUMN DeleterID BIGINT UNSIGNED NOT NULL DEFAULT 0, ADD INDEX(DeletedTime);",
+			"ALTER TABLE Collections ADD COLUMN DeletedTime TIMESTAMP NULL DEFAULT NULL, ADD COLUMN DeleterID BIGINT UNSIGNED NOT NULL DEFAULT 0, ADD INDEX(DeletedTime);",
+		},
+	})
+}
@@ -0,0 +1,13 @@
+package migrations
+
+import "go-image-board/plugins/migrate"
+
+func init() {
+	Registry.Register(migrate.Migration{
+		Version:     15,
+		Description: "Add image revisions",
+		Statements: []string{
+			"CREATE TABLE ImageRevisions (ID BIGINT UNSIGNED NOT NULL AUTO_INCREMENT UNIQUE, ImageID BIGINT UNSIGNED NOT NULL, Location VARCHAR(255) NOT NULL, ReplacerID BIGINT UNSIGNED NOT NULL, ReplacedTime TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL, INDEX(ImageID), CONSTRAINT fk_ImageRevisionsImageID FOREIGN KEY (ImageID) REFERENCES Images(ID));",
+		},
+	})
+}
@@ -0,0 +1,13 @@
+package migrations
+
+import "go-image-board/plugins/migrate"
+
+func init() {
+	Registry.Register(migrate.Migration{
+		Version:     16,
+		Description: "Add image tag history",
+		Statements: []string{
+			"CREATE TABLE ImageTagHistory (ID BIGINT UNSIGNED NOT NULL AUTO_INCREMENT UNIQUE, ImageID BIGINT UNSIGNED NOT NULL, TagID BIGINT UNSIGNED NOT NULL, UserID BIGINT UNSIGNED NOT NULL, Added BOOL NOT NULL, Operation VARCHAR(16) NOT NULL, ChangeTime TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL, INDEX(ImageID), INDEX UserTime (UserID, ChangeTime), CONSTRAINT fk_ImageTagHistoryImageID FOREIGN KEY (ImageID) REFERENCES Images(ID), CONSTRAINT fk_ImageTagHistoryTagID FOREIGN KEY (TagID) REFERENCES Tags(ID));",
+		},
+	})
+}
@@ -0,0 +1,13 @@
+package migrations
+
+import "go-image-board/plugins/migrate"
+
+func init() {
+	Registry.Register(migrate.Migration{
+		Version:     17,
+		Description: "Add tag implications",
+		Statements: []string{
+			"CREATE TABLE TagImplications (ID BIGINT UNSIGNED NOT NULL AUTO_INCREMENT UNIQUE, TagID BIGINT UNSIGNED NOT NULL, ImpliedTagID BIGINT UNSIGNED NOT NULL, CreatorID BIGINT UNSIGNED NOT NULL, CreationTime TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL, UNIQUE INDEX TagImplied (TagID, ImpliedTagID), INDEX(ImpliedTagID), CONSTRAINT fk_TagImplicationsTagID FOREIGN KEY (TagID) REFERENCES Tags(ID), CONSTRAINT fk_TagImplicationsImpliedTagID FOREIGN KEY (ImpliedTagID) REFERENCES Tags(ID));",
+		},
+	})
+}
@@ -0,0 +1,13 @@
+package migrations
+
+import "go-image-board/plugins/migrate"
+
+func init() {
+	Registry.Register(migrate.Migration{
+		Version:     18,
+		Description: "Add tag categories",
+		Statements: []string{
+			"ALTER TABLE Tags ADD COLUMN Category VARCHAR(32) NOT NULL DEFAULT 'general', ADD INDEX(Category);",
+		},
+	})
+}
@@ -0,0 +1,13 @@
+package migrations
+
+import "go-image-board/plugins/migrate"
+
+func init() {
+	Registry.Register(migrate.Migration{
+		Version:     19,
+		Description: "Add image metadata",
+		Statements: []string{
+			"ALTER TABLE Images ADD COLUMN Width BIGINT NOT NULL DEFAULT 0, ADD COLUMN Height BIGINT NOT NULL DEFAULT 0, ADD COLUMN FileSize BIGINT NOT NULL DEFAULT 0, ADD COLUMN MIMEType VARCHAR(255) NOT NULL DEFAULT '', ADD COLUMN Duration DOUBLE NOT NULL DEFAULT 0, ADD INDEX(MIMEType);",
+		},
+	})
+}
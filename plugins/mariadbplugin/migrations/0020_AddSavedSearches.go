@@ -0,0 +1,13 @@
+package migrations
+
+import "go-image-board/plugins/migrate"
+
+func init() {
+	Registry.Register(migrate.Migration{
+		Version:     20,
+		Description: "Add saved searches",
+		Statements: []string{
+			"CREATE TABLE SavedSearches (ID BIGINT UNSIGNED NOT NULL AUTO_INCREMENT UNIQUE, UserID BIGINT UNSIGNED NOT NULL, Name VARCHAR(255) NOT NULL, Query TEXT NOT NULL, CollectionContext BOOL NOT NULL DEFAULT FALSE, Pinned BOOL NOT NULL DEFAULT FALSE, ShowNewCount BOOL NOT NULL DEFAULT FALSE, CreationTime TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL, LastViewed TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL, INDEX(UserID));",
+		},
+	})
+}
@@ -0,0 +1,13 @@
+package migrations
+
+import "go-image-board/plugins/migrate"
+
+func init() {
+	Registry.Register(migrate.Migration{
+		Version:     21,
+		Description: "Add image favorites",
+		Statements: []string{
+			"CREATE TABLE ImageUserFavorites (ID BIGINT UNSIGNED NOT NULL AUTO_INCREMENT UNIQUE, UserID BIGINT UNSIGNED NOT NULL, ImageID BIGINT UNSIGNED NOT NULL, CreationTime TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL, UNIQUE INDEX ImageUserPair (UserID,ImageID), INDEX(ImageID), CONSTRAINT fk_ImageUserFavoritesImageID FOREIGN KEY (ImageID) REFERENCES Images(ID));",
+		},
+	})
+}
@@ -0,0 +1,15 @@
+package migrations
+
+import "go-image-board/plugins/migrate"
+
+func init() {
+	Registry.Register(migrate.Migration{
+		Version:     22,
+		Description: "Add comments",
+		Statements: []string{
+			//ImageID and CollectionID are 0 when the comment is on the other, so neither can reference its table
+			"CREATE TABLE Comments (ID BIGINT UNSIGNED NOT NULL AUTO_INCREMENT UNIQUE, ImageID BIGINT UNSIGNED NOT NULL DEFAULT 0, CollectionID BIGINT UNSIGNED NOT NULL DEFAULT 0, ParentID BIGINT UNSIGNED NOT NULL DEFAULT 0, ThreadID BIGINT UNSIGNED NOT NULL DEFAULT 0, UserID BIGINT UNSIGNED NOT NULL, Body TEXT NOT NULL, CreationTime TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL, EditTime TIMESTAMP NULL DEFAULT NULL, DeletedTime TIMESTAMP NULL DEFAULT NULL, DeleterID BIGINT UNSIGNED NOT NULL DEFAULT 0, INDEX(ImageID), INDEX(CollectionID), INDEX(ThreadID));",
+			"CREATE TABLE CommentRevisions (ID BIGINT UNSIGNED NOT NULL AUTO_INCREMENT UNIQUE, CommentID BIGINT UNSIGNED NOT NULL, Body TEXT NOT NULL, EditorID BIGINT UNSIGNED NOT NULL, EditTime TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL, INDEX(CommentID), CONSTRAINT fk_CommentRevisionsCommentID FOREIGN KEY (CommentID) REFERENCES Comments(ID));",
+		},
+	})
+}
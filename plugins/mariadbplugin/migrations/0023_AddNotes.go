@@ -0,0 +1,14 @@
+package migrations
+
+import "go-image-board/plugins/migrate"
+
+func init() {
+	Registry.Register(migrate.Migration{
+		Version:     23,
+		Description: "Add notes",
+		Statements: []string{
+			"CREATE TABLE Notes (ID BIGINT UNSIGNED NOT NULL AUTO_INCREMENT UNIQUE, ImageID BIGINT UNSIGNED NOT NULL, CreatorID BIGINT UNSIGNED NOT NULL, X BIGINT NOT NULL, Y BIGINT NOT NULL, Width BIGINT NOT NULL, Height BIGINT NOT NULL, ImageWidth BIGINT NOT NULL, ImageHeight BIGINT NOT NULL, Body TEXT NOT NULL, CreationTime TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL, EditTime TIMESTAMP NULL DEFAULT NULL, DeletedTime TIMESTAMP NULL DEFAULT NULL, DeleterID BIGINT UNSIGNED NOT NULL DEFAULT 0, INDEX(ImageID), CONSTRAINT fk_NotesImageID FOREIGN KEY (ImageID) REFERENCES Images(ID));",
+			"CREATE TABLE NoteRevisions (ID BIGINT UNSIGNED NOT NULL AUTO_INCREMENT UNIQUE, NoteID BIGINT UNSIGNED NOT NULL, X BIGINT NOT NULL, Y BIGINT NOT NULL, Width BIGINT NOT NULL, Height BIGINT NOT NULL, ImageWidth BIGINT NOT NULL, ImageHeight BIGINT NOT NULL, Body TEXT NOT NULL, Deleted BOOL NOT NULL DEFAULT FALSE, EditorID BIGINT UNSIGNED NOT NULL, EditTime TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL, INDEX(NoteID), CONSTRAINT fk_NoteRevisionsNoteID FOREIGN KEY (NoteID) REFERENCES Notes(ID));",
+		},
+	})
+}
@@ -0,0 +1,13 @@
+package migrations
+
+import "go-image-board/plugins/migrate"
+
+func init() {
+	Registry.Register(migrate.Migration{
+		Version:     24,
+		Description: "Add the moderation queue to images",
+		Statements: []string{
+			"ALTER TABLE Images ADD COLUMN Pending BOOL NOT NULL DEFAULT FALSE, ADD INDEX(Pending);",
+		},
+	})
+}
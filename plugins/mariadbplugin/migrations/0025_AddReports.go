@@ -0,0 +1,13 @@
+package migrations
+
+import "go-image-board/plugins/migrate"
+
+func init() {
+	Registry.Register(migrate.Migration{
+		Version:     25,
+		Description: "Add reports",
+		Statements: []string{
+			"CREATE TABLE Reports (ID BIGINT UNSIGNED NOT NULL AUTO_INCREMENT UNIQUE, TargetType VARCHAR(16) NOT NULL, TargetID BIGINT UNSIGNED NOT NULL, ReporterID BIGINT UNSIGNED NOT NULL, Category VARCHAR(32) NOT NULL, Reason TEXT NOT NULL, Status VARCHAR(16) NOT NULL DEFAULT 'open', ModeratorID BIGINT UNSIGNED NOT NULL DEFAULT 0, Outcome TEXT NOT NULL, CreationTime TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL, UpdateTime TIMESTAMP NULL DEFAULT NULL, INDEX(Status), INDEX(ReporterID));",
+		},
+	})
+}
@@ -0,0 +1,13 @@
+package migrations
+
+import "go-image-board/plugins/migrate"
+
+func init() {
+	Registry.Register(migrate.Migration{
+		Version:     26,
+		Description: "Add bans",
+		Statements: []string{
+			"CREATE TABLE Bans (ID BIGINT UNSIGNED NOT NULL AUTO_INCREMENT UNIQUE, UserID BIGINT UNSIGNED NOT NULL DEFAULT 0, IPRange VARCHAR(64) NOT NULL DEFAULT '', Reason TEXT NOT NULL, BannerID BIGINT UNSIGNED NOT NULL, CreationTime TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL, ExpiryTime TIMESTAMP NULL DEFAULT NULL, LifterID BIGINT UNSIGNED NOT NULL DEFAULT 0, LiftTime TIMESTAMP NULL DEFAULT NULL, INDEX(LiftTime));",
+		},
+	})
+}
@@ -0,0 +1,13 @@
+package migrations
+
+import "go-image-board/plugins/migrate"
+
+func init() {
+	Registry.Register(migrate.Migration{
+		Version:     27,
+		Description: "Index active bans by expiry",
+		Statements: []string{
+			"ALTER TABLE Bans DROP INDEX LiftTime, ADD INDEX Active (LiftTime, ExpiryTime);",
+		},
+	})
+}
@@ -0,0 +1,13 @@
+package migrations
+
+import "go-image-board/plugins/migrate"
+
+func init() {
+	Registry.Register(migrate.Migration{
+		Version:     28,
+		Description: "Index bans by account and address range",
+		Statements: []string{
+			"ALTER TABLE Bans ADD INDEX (UserID), ADD INDEX (IPRange);",
+		},
+	})
+}
@@ -0,0 +1,8 @@
+//Package migrations holds the numbered MariaDB schema migrations, one per file.
+//To change the schema, add a new file with the next version and update performFreshDBInstall to match. Never edit a migration once released, its checksum is recorded when applied.
+package migrations
+
+import "go-image-board/plugins/migrate"
+
+//Registry contains every MariaDB migration, populated by the init function in each migration file
+var Registry = &migrate.Registry{}
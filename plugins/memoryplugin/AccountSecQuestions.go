@@ -0,0 +1,114 @@
+package memoryplugin
+
+import (
+	"errors"
+	"go-image-board/logging"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+//SetSecurityQuestions changes a user's security questions (nil if success)
+func (DBConnection *MemoryPlugin) SetSecurityQuestions(userName string, questionOne string, questionTwo string, questionThree string, answerOne []byte, answerTwo []byte, answerThree []byte, challengeAnswer []byte) error {
+	answerOneHash, errA := DBConnection.getPasswordHash(answerOne)
+	answerTwoHash, errB := DBConnection.getPasswordHash(answerTwo)
+	answerThreeHash, errC := DBConnection.getPasswordHash(answerThree)
+
+	if errA != nil || errB != nil || errC != nil {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/SetSecurityQuestions", userName, logging.ResultFailure, []string{"Failed to hash security question answers", userName})
+		return errors.New("Failed to set answers")
+	}
+
+	DBConnection.dbMutex.Lock()
+	defer DBConnection.dbMutex.Unlock()
+	user := DBConnection.getUserByName(userName)
+	if user == nil {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/SetSecurityQuestions", userName, logging.ResultFailure, []string{"Security questions failed to update. Challenge could not be loaded, user not found.", userName})
+		return errors.New("sql error occured attempt to load old question")
+	}
+	//If question one is set
+	if user.SecQuestionsSet && user.SecQuestionOne != "" {
+		//Challenge needed/Require that the user entered in the answer to q1
+		if bcrypt.CompareHashAndPassword([]byte(user.SecAnswerOne), challengeAnswer) != nil {
+			//Challenge failed/If we fail, log it, and quit without setting questions
+			logging.WriteLog(logging.LogLevelError, "MemoryPlugin/SetSecurityQuestions", userName, logging.ResultFailure, []string{"Security questions failed to update. Challenge answer incorrect.", userName})
+			return errors.New("provided answer did not pass challenge")
+		}
+	}
+
+	//Disabled accounts are silently left alone, the same as the other plugins
+	if user.Disabled == false {
+		user.SecQuestionsSet = true
+		user.SecQuestionOne = questionOne
+		user.SecQuestionTwo = questionTwo
+		user.SecQuestionThree = questionThree
+		user.SecAnswerOne = string(answerOneHash)
+		user.SecAnswerTwo = string(answerTwoHash)
+		user.SecAnswerThree = string(answerThreeHash)
+	}
+	logging.WriteLog(logging.LogLevelError, "MemoryPlugin/SetSecurityQuestions", userName, logging.ResultSuccess, []string{"Security questions updated!", userName})
+	return nil
+}
+
+//ValidateSecurityQuestions Validates answers against a user's security questions (nil on success)
+func (DBConnection *MemoryPlugin) ValidateSecurityQuestions(userName string, answerOne []byte, answerTwo []byte, answerThree []byte) error {
+	DBConnection.dbMutex.RLock()
+	defer DBConnection.dbMutex.RUnlock()
+	return DBConnection.validateSecurityQuestions(userName, answerOne, answerTwo, answerThree)
+}
+
+//validateSecurityQuestions is ValidateSecurityQuestions for callers already holding the lock
+func (DBConnection *MemoryPlugin) validateSecurityQuestions(userName string, answerOne []byte, answerTwo []byte, answerThree []byte) error {
+	//Ensure answers have values
+	if answerOne == nil || answerTwo == nil || answerThree == nil {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/ValidateSecurityQuestions", userName, logging.ResultFailure, []string{"No answers?", userName})
+		return errors.New("Security Question validation failed, provide answers")
+	}
+
+	//Ensure Questions Exist
+	secQuestionOne, secQuestionTwo, secQuestionThree, err := DBConnection.getSecurityQuestions(userName)
+	if err != nil || secQuestionOne == "" || secQuestionTwo == "" || secQuestionThree == "" {
+		if err != nil {
+			logging.WriteLog(logging.LogLevelError, "MemoryPlugin/ValidateSecurityQuestions", userName, logging.ResultFailure, []string{"User does not exist?", err.Error(), userName})
+			return err
+		}
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/ValidateSecurityQuestions", userName, logging.ResultFailure, []string{"Questions do not exist for user", userName})
+		return errors.New("Questions do not exist for user")
+	}
+
+	user := DBConnection.getUserByName(userName)
+	if bcrypt.CompareHashAndPassword([]byte(user.SecAnswerOne), answerOne) != nil {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/ValidateSecurityQuestions", userName, logging.ResultFailure, []string{"Answer 1 incorrect", userName})
+		return errors.New("Security Question validation failed")
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(user.SecAnswerTwo), answerTwo) != nil {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/ValidateSecurityQuestions", userName, logging.ResultFailure, []string{"Answer 2 incorrect", userName})
+		return errors.New("Security Question validation failed")
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(user.SecAnswerThree), answerThree) != nil {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/ValidateSecurityQuestions", userName, logging.ResultFailure, []string{"Answer 3 incorrect", userName})
+		return errors.New("Security Question validation failed")
+	}
+
+	return nil
+}
+
+//GetSecurityQuestions returns the three questions, first, second, third, and an error if an issue occured
+func (DBConnection *MemoryPlugin) GetSecurityQuestions(userName string) (string, string, string, error) {
+	DBConnection.dbMutex.RLock()
+	defer DBConnection.dbMutex.RUnlock()
+	return DBConnection.getSecurityQuestions(userName)
+}
+
+//getSecurityQuestions is GetSecurityQuestions for callers already holding the lock
+func (DBConnection *MemoryPlugin) getSecurityQuestions(userName string) (string, string, string, error) {
+	user := DBConnection.getUserByName(userName)
+	if user == nil {
+		return "", "", "", errors.New("user not found")
+	}
+	if user.SecQuestionsSet {
+		return user.SecQuestionOne, user.SecQuestionTwo, user.SecQuestionThree, nil
+	}
+	return "", "", "", errors.New("one or more questions nil")
+}
@@ -0,0 +1,79 @@
+package memoryplugin
+
+import (
+	"bytes"
+	"errors"
+	"go-image-board/interfaces"
+	"go-image-board/logging"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+//ValidateToken Validate a cookie token (true if valid cookie, false otherwise, error for reason or nil)
+func (DBConnection *MemoryPlugin) ValidateToken(userName string, tokenID string, ip string) error {
+	DBConnection.dbMutex.RLock()
+	defer DBConnection.dbMutex.RUnlock()
+	user := DBConnection.getUserByName(userName)
+	if user != nil && user.Disabled {
+		return errors.New("Account disabled")
+	}
+
+	UUIDBytes := uuid.FromStringOrNil(tokenID)
+	if uuid.Equal(UUIDBytes, uuid.UUID{}) == true {
+		//Token provided is blank
+		return errors.New("Token provided is blank")
+	}
+
+	if user == nil || user.TokenID == "" {
+		//User's token in DB is blank
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/ValidateToken", userName, logging.ResultFailure, []string{"Token Invalid", userName, tokenID, ip})
+		return errors.New("Token invalid")
+	}
+
+	if user.IP != ip {
+		//Token is registered for a different IP
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/ValidateToken", userName, logging.ResultFailure, []string{"Token for a different IP", userName, tokenID, ip})
+		return errors.New("Token invalid")
+	}
+
+	if bytes.Equal(UUIDBytes.Bytes(), uuid.FromStringOrNil(user.TokenID).Bytes()) == false {
+		//Tokens do not match
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/ValidateToken", userName, logging.ResultFailure, []string{"Tokens don't match", userName, tokenID, ip})
+		return errors.New("Token invalid")
+	}
+
+	//A valid token does not get past a ban placed since it was generated
+	if ban, isBanned := interfaces.FindBan(DBConnection.getActiveBansOn(user.ID, ip), user.ID, ip, time.Now()); isBanned {
+		return interfaces.BanError{Ban: ban}
+	}
+
+	return nil
+}
+
+//GenerateToken Generate a cookie token (string token, or error)
+func (DBConnection *MemoryPlugin) GenerateToken(userName string, ip string) (string, error) {
+	DBConnection.dbMutex.Lock()
+	defer DBConnection.dbMutex.Unlock()
+	user := DBConnection.getUserByName(userName)
+	if user == nil {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/GenerateToken", userName, logging.ResultFailure, []string{"Failed to save token", userName, ip, "user not found"})
+		return "", errors.New("failed to generate a token, check if user exists")
+	}
+	newToken := uuid.NewV4()
+	user.TokenID = newToken.String()
+	user.IP = ip
+	return newToken.String(), nil
+}
+
+//RevokeToken Revokes a token (nil on success)
+func (DBConnection *MemoryPlugin) RevokeToken(userName string) error {
+	DBConnection.dbMutex.Lock()
+	defer DBConnection.dbMutex.Unlock()
+	if user := DBConnection.getUserByName(userName); user != nil {
+		user.TokenID = ""
+		user.IP = ""
+	}
+	logging.WriteLog(logging.LogLevelError, "MemoryPlugin/RevokeToken", userName, logging.ResultSuccess, []string{"Token revoked!", userName})
+	return nil
+}
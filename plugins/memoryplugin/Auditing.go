@@ -0,0 +1,30 @@
+package memoryplugin
+
+import (
+	"go-image-board/logging"
+	"strconv"
+	"time"
+)
+
+//AddAuditLog adds an audit event into the audit table
+func (DBConnection *MemoryPlugin) AddAuditLog(UserID uint64, Type string, Info string) error {
+	if len(Type) > 40 || len(Info) > 10240 {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/AddAuditLog", strconv.FormatUint(UserID, 10), logging.ResultFailure, []string{"either the type, or the info is too long for the audit log table", Type, Info})
+		if len(Info) > 10240 {
+			Info = Info[:10240]
+		}
+		if len(Type) > 40 {
+			Type = Type[:40]
+		}
+	}
+
+	DBConnection.dbMutex.Lock()
+	defer DBConnection.dbMutex.Unlock()
+	//Same retention as the auditCleanup event in the other plugins
+	cutOff := time.Now().AddDate(0, 0, -30)
+	for len(DBConnection.auditLogs) > 0 && DBConnection.auditLogs[0].LogTime.Before(cutOff) {
+		DBConnection.auditLogs = DBConnection.auditLogs[1:]
+	}
+	DBConnection.auditLogs = append(DBConnection.auditLogs, memoryAuditLog{ID: DBConnection.nextID("AuditLogs"), UserID: UserID, Type: Type, Info: Info, LogTime: time.Now()})
+	return nil
+}
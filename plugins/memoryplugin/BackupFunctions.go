@@ -0,0 +1,328 @@
+package memoryplugin
+
+import (
+	"errors"
+	"go-image-board/interfaces"
+	"go-image-board/logging"
+	"sort"
+	"time"
+)
+
+//ExportTables calls Row for every row of each table, in ID order. The read lock is held throughout, so the tables are consistent with each other
+func (DBConnection *MemoryPlugin) ExportTables(Tables []interfaces.BackupTable, Row func(Table interfaces.BackupTable, Row interfaces.BackupRow) error) error {
+	DBConnection.dbMutex.RLock()
+	defer DBConnection.dbMutex.RUnlock()
+	for _, table := range Tables {
+		rows, err := DBConnection.tableRows(table.Name)
+		if err != nil {
+			logging.WriteLog(logging.LogLevelError, "MemoryPlugin/BackupFunctions/ExportTables", "0", logging.ResultFailure, []string{"Failed to export tables", err.Error()})
+			return err
+		}
+		sort.Slice(rows, func(i, j int) bool { return rows[i]["ID"].(uint64) < rows[j]["ID"].(uint64) })
+		for _, row := range rows {
+			if err := Row(table, row); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+//tableRows converts a table to BackupRows, in no particular order
+func (DBConnection *MemoryPlugin) tableRows(Table string) ([]interfaces.BackupRow, error) {
+	var rows []interfaces.BackupRow
+	switch Table {
+	case "Users":
+		for _, user := range DBConnection.users {
+			rows = append(rows, interfaces.BackupRow{"ID": user.ID, "Name": user.Name, "EMail": user.EMail, "PasswordHash": user.PasswordHash, "TokenID": user.TokenID, "IP": user.IP,
+				"SecQuestionOne": setString(user.SecQuestionsSet, user.SecQuestionOne), "SecQuestionTwo": setString(user.SecQuestionsSet, user.SecQuestionTwo), "SecQuestionThree": setString(user.SecQuestionsSet, user.SecQuestionThree),
+				"SecAnswerOne": setString(user.SecQuestionsSet, user.SecAnswerOne), "SecAnswerTwo": setString(user.SecQuestionsSet, user.SecAnswerTwo), "SecAnswerThree": setString(user.SecQuestionsSet, user.SecAnswerThree),
+				"CreationTime": user.CreationTime, "Disabled": user.Disabled, "Permissions": user.Permissions, "SearchFilter": user.SearchFilter})
+		}
+	case "Tags":
+		for _, tag := range DBConnection.tags {
+			rows = append(rows, interfaces.BackupRow{"ID": tag.ID, "Name": tag.Name, "Description": tag.Description, "UploaderID": tag.UploaderID, "UploadTime": tag.UploadTime, "AliasedID": tag.AliasedID, "IsAlias": tag.IsAlias, "Category": tag.Category})
+		}
+	case "Images":
+		for _, image := range DBConnection.images {
+			rows = append(rows, interfaces.BackupRow{"ID": image.ID, "UploaderID": image.UploaderID, "Name": image.Name, "Rating": image.Rating, "ScoreTotal": image.ScoreTotal, "ScoreAverage": image.ScoreAverage, "ScoreVoters": image.ScoreVoters,
+				"Location": image.Location, "Source": image.Source, "UploadTime": image.UploadTime, "Description": image.Description, "DeletedTime": setTime(image.DeletedTime), "DeleterID": image.DeleterID,
+				"Width": image.Metadata.Width, "Height": image.Metadata.Height, "FileSize": image.Metadata.FileSize, "MIMEType": image.Metadata.MIMEType, "Duration": image.Metadata.Duration, "Pending": image.Pending})
+		}
+	case "ImagedHashes":
+		for imageID, hashes := range DBConnection.imagedHashes {
+			rows = append(rows, interfaces.BackupRow{"ID": hashes.ID, "ImageID": imageID, "vHash": hashes.vHash, "hHash": hashes.hHash})
+		}
+	case "ImageRevisions":
+		for _, revision := range DBConnection.imageRevisions {
+			rows = append(rows, interfaces.BackupRow{"ID": revision.ID, "ImageID": revision.ImageID, "Location": revision.Location, "ReplacerID": revision.ReplacerID, "ReplacedTime": revision.ReplacedTime})
+		}
+	case "ImageUserScores":
+		for _, score := range DBConnection.imageUserScores {
+			rows = append(rows, interfaces.BackupRow{"ID": score.ID, "UserID": score.UserID, "ImageID": score.ImageID, "Score": score.Score, "CreationTime": score.CreationTime})
+		}
+	case "ImageUserFavorites":
+		for _, favorite := range DBConnection.imageFavorites {
+			rows = append(rows, interfaces.BackupRow{"ID": favorite.ID, "UserID": favorite.UserID, "ImageID": favorite.ImageID, "CreationTime": favorite.CreationTime})
+		}
+	case "AuditLogs":
+		for _, log := range DBConnection.auditLogs {
+			rows = append(rows, interfaces.BackupRow{"ID": log.ID, "UserID": log.UserID, "Type": log.Type, "Info": log.Info, "LogTime": log.LogTime})
+		}
+	case "Collections":
+		for _, collection := range DBConnection.collections {
+			rows = append(rows, interfaces.BackupRow{"ID": collection.ID, "Name": collection.Name, "Description": collection.Description, "UploaderID": collection.UploaderID, "UploadTime": collection.UploadTime, "DeletedTime": setTime(collection.DeletedTime), "DeleterID": collection.DeleterID})
+		}
+	case "CollectionTags":
+		for _, collectionTag := range DBConnection.collectionTags {
+			rows = append(rows, interfaces.BackupRow{"ID": collectionTag.ID, "CollectionID": collectionTag.CollectionID, "TagID": collectionTag.TagID, "LinkerID": collectionTag.LinkerID, "LinkTime": collectionTag.LinkTime})
+		}
+	case "CollectionMembers":
+		for _, member := range DBConnection.collectionMembers {
+			rows = append(rows, interfaces.BackupRow{"ID": member.ID, "ImageID": member.ImageID, "CollectionID": member.CollectionID, "LinkerID": member.LinkerID, "LinkTime": member.LinkTime, "OrderWeight": member.OrderWeight})
+		}
+	case "ImageTags":
+		for _, imageTag := range DBConnection.imageTags {
+			rows = append(rows, interfaces.BackupRow{"ID": imageTag.ID, "ImageID": imageTag.ImageID, "TagID": imageTag.TagID, "LinkerID": imageTag.LinkerID, "LinkTime": imageTag.LinkTime})
+		}
+	case "ImageTagHistory":
+		for _, change := range DBConnection.tagHistory {
+			rows = append(rows, interfaces.BackupRow{"ID": change.ID, "ImageID": change.ImageID, "TagID": change.TagID, "UserID": change.UserID, "Added": change.Added, "Operation": change.Operation, "ChangeTime": change.ChangeTime})
+		}
+	case "TagImplications":
+		for _, implication := range DBConnection.tagImplications {
+			rows = append(rows, interfaces.BackupRow{"ID": implication.ID, "TagID": implication.TagID, "ImpliedTagID": implication.ImpliedTagID, "CreatorID": implication.CreatorID, "CreationTime": implication.CreationTime})
+		}
+	case "Comments":
+		for _, comment := range DBConnection.comments {
+			rows = append(rows, interfaces.BackupRow{"ID": comment.ID, "ImageID": comment.ImageID, "CollectionID": comment.CollectionID, "ParentID": comment.ParentID, "ThreadID": comment.ThreadID, "UserID": comment.UserID, "Body": comment.Body,
+				"CreationTime": comment.CreationTime, "EditTime": setTime(comment.EditTime), "DeletedTime": setTime(comment.DeletedTime), "DeleterID": comment.DeleterID})
+		}
+	case "CommentRevisions":
+		for _, revision := range DBConnection.commentRevisions {
+			rows = append(rows, interfaces.BackupRow{"ID": revision.ID, "CommentID": revision.CommentID, "Body": revision.Body, "EditorID": revision.EditorID, "EditTime": revision.EditTime})
+		}
+	case "Notes":
+		for _, note := range DBConnection.notes {
+			rows = append(rows, interfaces.BackupRow{"ID": note.ID, "ImageID": note.ImageID, "CreatorID": note.CreatorID, "X": note.Region.X, "Y": note.Region.Y, "Width": note.Region.Width, "Height": note.Region.Height, "ImageWidth": note.Region.ImageWidth, "ImageHeight": note.Region.ImageHeight,
+				"Body": note.Body, "CreationTime": note.CreationTime, "EditTime": setTime(note.EditTime), "DeletedTime": setTime(note.DeletedTime), "DeleterID": note.DeleterID})
+		}
+	case "NoteRevisions":
+		for _, revision := range DBConnection.noteRevisions {
+			rows = append(rows, interfaces.BackupRow{"ID": revision.ID, "NoteID": revision.NoteID, "X": revision.Region.X, "Y": revision.Region.Y, "Width": revision.Region.Width, "Height": revision.Region.Height, "ImageWidth": revision.Region.ImageWidth, "ImageHeight": revision.Region.ImageHeight,
+				"Body": revision.Body, "Deleted": revision.Deleted, "EditorID": revision.EditorID, "EditTime": revision.EditTime})
+		}
+	case "SavedSearches":
+		for _, search := range DBConnection.savedSearches {
+			rows = append(rows, interfaces.BackupRow{"ID": search.ID, "UserID": search.UserID, "Name": search.Name, "Query": search.Query, "CollectionContext": search.CollectionContext, "Pinned": search.Pinned, "ShowNewCount": search.ShowNewCount, "CreationTime": search.CreationTime, "LastViewed": search.LastViewed})
+		}
+	case "Reports":
+		for _, report := range DBConnection.reports {
+			rows = append(rows, interfaces.BackupRow{"ID": report.ID, "TargetType": report.TargetType, "TargetID": report.TargetID, "ReporterID": report.ReporterID, "Category": report.Category, "Reason": report.Reason, "Status": report.Status,
+				"ModeratorID": report.ModeratorID, "Outcome": report.Outcome, "CreationTime": report.CreationTime, "UpdateTime": setTime(report.UpdateTime)})
+		}
+	case "Bans":
+		for _, ban := range DBConnection.bans {
+			rows = append(rows, interfaces.BackupRow{"ID": ban.ID, "UserID": ban.UserID, "IPRange": ban.IPRange, "Reason": ban.Reason, "BannerID": ban.BannerID,
+				"CreationTime": ban.CreationTime, "ExpiryTime": setTime(ban.ExpiryTime), "LifterID": ban.LifterID, "LiftTime": setTime(ban.LiftTime)})
+		}
+	default:
+		return nil, errors.New("Unknown table " + Table)
+	}
+	return rows, nil
+}
+
+//ImportTables restores a backup in to a freshly initialized database, keeping the IDs of every row.
+//Rows are collected in a copy of the database, which only replaces it once every row was imported
+func (DBConnection *MemoryPlugin) ImportTables(Tables []interfaces.BackupTable, Rows func(Insert func(Table interfaces.BackupTable, Row interfaces.BackupRow) error) error) error {
+	DBConnection.dbMutex.Lock()
+	defer DBConnection.dbMutex.Unlock()
+	if len(DBConnection.users) > 1 || len(DBConnection.images) > 0 || len(DBConnection.tags) > 0 || len(DBConnection.collections) > 0 || len(DBConnection.auditLogs) > 0 {
+		err := errors.New("Database already contains data, backups can only be restored in to a fresh database")
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/BackupFunctions/ImportTables", "0", logging.ResultFailure, []string{"Failed to import tables", err.Error()})
+		return err
+	}
+	restored := &MemoryPlugin{
+		autoIncrement:     make(map[string]uint64),
+		users:             make(map[uint64]*memoryUser),
+		images:            make(map[uint64]*memoryImage),
+		tags:              make(map[uint64]*memoryTag),
+		imageTags:         make(map[imageTagKey]*memoryImageTag),
+		tagHistory:        make(map[uint64]*memoryTagChange),
+		tagImplications:   make(map[uint64]*memoryTagImplication),
+		savedSearches:     make(map[uint64]*memorySavedSearch),
+		reports:           make(map[uint64]*memoryReport),
+		bans:              make(map[uint64]*memoryBan),
+		imagedHashes:      make(map[uint64]memoryImagedHash),
+		imageRevisions:    make(map[uint64]*memoryImageRevision),
+		imageUserScores:   make(map[imageUserScoreKey]*memoryImageUserScore),
+		imageFavorites:    make(map[imageUserScoreKey]*memoryImageUserFavorite),
+		comments:          make(map[uint64]*memoryComment),
+		commentRevisions:  make(map[uint64]*memoryCommentRevision),
+		notes:             make(map[uint64]*memoryNote),
+		noteRevisions:     make(map[uint64]*memoryNoteRevision),
+		collections:       make(map[uint64]*memoryCollection),
+		collectionMembers: make(map[collectionMemberKey]*memoryCollectionMember),
+		collectionTags:    make(map[collectionTagKey]*memoryCollectionTag),
+	}
+	err := Rows(func(Table interfaces.BackupTable, Row interfaces.BackupRow) error {
+		return restored.insertRow(Table.Name, Row)
+	})
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/BackupFunctions/ImportTables", "0", logging.ResultFailure, []string{"Failed to import tables", err.Error()})
+		return err
+	}
+	sort.Slice(restored.auditLogs, func(i, j int) bool { return restored.auditLogs[i].ID < restored.auditLogs[j].ID })
+	DBConnection.autoIncrement = restored.autoIncrement
+	DBConnection.users = restored.users
+	DBConnection.images = restored.images
+	DBConnection.tags = restored.tags
+	DBConnection.imageTags = restored.imageTags
+	DBConnection.tagHistory = restored.tagHistory
+	DBConnection.tagImplications = restored.tagImplications
+	DBConnection.savedSearches = restored.savedSearches
+	DBConnection.reports = restored.reports
+	DBConnection.bans = restored.bans
+	DBConnection.imagedHashes = restored.imagedHashes
+	DBConnection.imageRevisions = restored.imageRevisions
+	DBConnection.imageUserScores = restored.imageUserScores
+	DBConnection.imageFavorites = restored.imageFavorites
+	DBConnection.comments = restored.comments
+	DBConnection.commentRevisions = restored.commentRevisions
+	DBConnection.notes = restored.notes
+	DBConnection.noteRevisions = restored.noteRevisions
+	DBConnection.auditLogs = restored.auditLogs
+	DBConnection.collections = restored.collections
+	DBConnection.collectionMembers = restored.collectionMembers
+	DBConnection.collectionTags = restored.collectionTags
+	return nil
+}
+
+//insertRow adds a single BackupRow, moving the table's auto increment past its ID
+func (DBConnection *MemoryPlugin) insertRow(Table string, Row interfaces.BackupRow) error {
+	ID := rowUint(Row, "ID")
+	switch Table {
+	case "Users":
+		DBConnection.users[ID] = &memoryUser{ID: ID, Name: rowString(Row, "Name"), EMail: rowString(Row, "EMail"), PasswordHash: rowString(Row, "PasswordHash"), TokenID: rowString(Row, "TokenID"), IP: rowString(Row, "IP"),
+			SecQuestionsSet: Row["SecQuestionOne"] != nil,
+			SecQuestionOne:  rowString(Row, "SecQuestionOne"), SecQuestionTwo: rowString(Row, "SecQuestionTwo"), SecQuestionThree: rowString(Row, "SecQuestionThree"),
+			SecAnswerOne: rowString(Row, "SecAnswerOne"), SecAnswerTwo: rowString(Row, "SecAnswerTwo"), SecAnswerThree: rowString(Row, "SecAnswerThree"),
+			CreationTime: rowTime(Row, "CreationTime"), Disabled: rowBool(Row, "Disabled"), Permissions: rowUint(Row, "Permissions"), SearchFilter: rowString(Row, "SearchFilter")}
+	case "Tags":
+		DBConnection.tags[ID] = &memoryTag{ID: ID, Name: rowString(Row, "Name"), Description: rowString(Row, "Description"), UploaderID: rowUint(Row, "UploaderID"), UploadTime: rowTime(Row, "UploadTime"), AliasedID: rowUint(Row, "AliasedID"), IsAlias: rowBool(Row, "IsAlias"), Category: rowString(Row, "Category")}
+	case "Images":
+		DBConnection.images[ID] = &memoryImage{ID: ID, UploaderID: rowUint(Row, "UploaderID"), Name: rowString(Row, "Name"), Rating: rowString(Row, "Rating"), ScoreTotal: rowInt(Row, "ScoreTotal"), ScoreAverage: rowInt(Row, "ScoreAverage"), ScoreVoters: rowInt(Row, "ScoreVoters"),
+			Location: rowString(Row, "Location"), Source: rowString(Row, "Source"), UploadTime: rowTime(Row, "UploadTime"), Description: rowString(Row, "Description"),
+			DeletedTime: rowTime(Row, "DeletedTime"), DeleterID: rowUint(Row, "DeleterID"), Pending: rowBool(Row, "Pending"),
+			Metadata: interfaces.ImageMetadata{Width: rowInt(Row, "Width"), Height: rowInt(Row, "Height"), FileSize: rowInt(Row, "FileSize"), MIMEType: rowString(Row, "MIMEType"), Duration: rowFloat(Row, "Duration")}}
+	case "ImagedHashes":
+		DBConnection.imagedHashes[rowUint(Row, "ImageID")] = memoryImagedHash{ID: ID, hHash: rowUint(Row, "hHash"), vHash: rowUint(Row, "vHash")}
+	case "ImageRevisions":
+		DBConnection.imageRevisions[ID] = &memoryImageRevision{ID: ID, ImageID: rowUint(Row, "ImageID"), Location: rowString(Row, "Location"), ReplacerID: rowUint(Row, "ReplacerID"), ReplacedTime: rowTime(Row, "ReplacedTime")}
+	case "ImageUserScores":
+		key := imageUserScoreKey{UserID: rowUint(Row, "UserID"), ImageID: rowUint(Row, "ImageID")}
+		DBConnection.imageUserScores[key] = &memoryImageUserScore{ID: ID, UserID: key.UserID, ImageID: key.ImageID, Score: rowInt(Row, "Score"), CreationTime: rowTime(Row, "CreationTime")}
+	case "ImageUserFavorites":
+		key := imageUserScoreKey{UserID: rowUint(Row, "UserID"), ImageID: rowUint(Row, "ImageID")}
+		DBConnection.imageFavorites[key] = &memoryImageUserFavorite{ID: ID, UserID: key.UserID, ImageID: key.ImageID, CreationTime: rowTime(Row, "CreationTime")}
+	case "AuditLogs":
+		DBConnection.auditLogs = append(DBConnection.auditLogs, memoryAuditLog{ID: ID, UserID: rowUint(Row, "UserID"), Type: rowString(Row, "Type"), Info: rowString(Row, "Info"), LogTime: rowTime(Row, "LogTime")})
+	case "Collections":
+		DBConnection.collections[ID] = &memoryCollection{ID: ID, Name: rowString(Row, "Name"), Description: rowString(Row, "Description"), UploaderID: rowUint(Row, "UploaderID"), UploadTime: rowTime(Row, "UploadTime"),
+			DeletedTime: rowTime(Row, "DeletedTime"), DeleterID: rowUint(Row, "DeleterID")}
+	case "CollectionTags":
+		key := collectionTagKey{CollectionID: rowUint(Row, "CollectionID"), TagID: rowUint(Row, "TagID")}
+		DBConnection.collectionTags[key] = &memoryCollectionTag{ID: ID, CollectionID: key.CollectionID, TagID: key.TagID, LinkerID: rowUint(Row, "LinkerID"), LinkTime: rowTime(Row, "LinkTime")}
+	case "CollectionMembers":
+		key := collectionMemberKey{CollectionID: rowUint(Row, "CollectionID"), ImageID: rowUint(Row, "ImageID")}
+		DBConnection.collectionMembers[key] = &memoryCollectionMember{ID: ID, ImageID: key.ImageID, CollectionID: key.CollectionID, LinkerID: rowUint(Row, "LinkerID"), LinkTime: rowTime(Row, "LinkTime"), OrderWeight: rowUint(Row, "OrderWeight")}
+	case "ImageTags":
+		key := imageTagKey{ImageID: rowUint(Row, "ImageID"), TagID: rowUint(Row, "TagID")}
+		DBConnection.imageTags[key] = &memoryImageTag{ID: ID, ImageID: key.ImageID, TagID: key.TagID, LinkerID: rowUint(Row, "LinkerID"), LinkTime: rowTime(Row, "LinkTime")}
+	case "ImageTagHistory":
+		DBConnection.tagHistory[ID] = &memoryTagChange{ID: ID, ImageID: rowUint(Row, "ImageID"), TagID: rowUint(Row, "TagID"), UserID: rowUint(Row, "UserID"), Added: rowBool(Row, "Added"), Operation: rowString(Row, "Operation"), ChangeTime: rowTime(Row, "ChangeTime")}
+	case "TagImplications":
+		DBConnection.tagImplications[ID] = &memoryTagImplication{ID: ID, TagID: rowUint(Row, "TagID"), ImpliedTagID: rowUint(Row, "ImpliedTagID"), CreatorID: rowUint(Row, "CreatorID"), CreationTime: rowTime(Row, "CreationTime")}
+	case "Comments":
+		DBConnection.comments[ID] = &memoryComment{ID: ID, ImageID: rowUint(Row, "ImageID"), CollectionID: rowUint(Row, "CollectionID"), ParentID: rowUint(Row, "ParentID"), ThreadID: rowUint(Row, "ThreadID"), UserID: rowUint(Row, "UserID"), Body: rowString(Row, "Body"),
+			CreationTime: rowTime(Row, "CreationTime"), EditTime: rowTime(Row, "EditTime"), DeletedTime: rowTime(Row, "DeletedTime"), DeleterID: rowUint(Row, "DeleterID")}
+	case "CommentRevisions":
+		DBConnection.commentRevisions[ID] = &memoryCommentRevision{ID: ID, CommentID: rowUint(Row, "CommentID"), Body: rowString(Row, "Body"), EditorID: rowUint(Row, "EditorID"), EditTime: rowTime(Row, "EditTime")}
+	case "Notes":
+		DBConnection.notes[ID] = &memoryNote{ID: ID, ImageID: rowUint(Row, "ImageID"), CreatorID: rowUint(Row, "CreatorID"), Region: rowNoteRegion(Row), Body: rowString(Row, "Body"),
+			CreationTime: rowTime(Row, "CreationTime"), EditTime: rowTime(Row, "EditTime"), DeletedTime: rowTime(Row, "DeletedTime"), DeleterID: rowUint(Row, "DeleterID")}
+	case "NoteRevisions":
+		DBConnection.noteRevisions[ID] = &memoryNoteRevision{ID: ID, NoteID: rowUint(Row, "NoteID"), Region: rowNoteRegion(Row), Body: rowString(Row, "Body"), Deleted: rowBool(Row, "Deleted"), EditorID: rowUint(Row, "EditorID"), EditTime: rowTime(Row, "EditTime")}
+	case "SavedSearches":
+		DBConnection.savedSearches[ID] = &memorySavedSearch{ID: ID, UserID: rowUint(Row, "UserID"), Name: rowString(Row, "Name"), Query: rowString(Row, "Query"), CollectionContext: rowBool(Row, "CollectionContext"), Pinned: rowBool(Row, "Pinned"), ShowNewCount: rowBool(Row, "ShowNewCount"), CreationTime: rowTime(Row, "CreationTime"), LastViewed: rowTime(Row, "LastViewed")}
+	case "Reports":
+		DBConnection.reports[ID] = &memoryReport{ID: ID, TargetType: rowString(Row, "TargetType"), TargetID: rowUint(Row, "TargetID"), ReporterID: rowUint(Row, "ReporterID"), Category: rowString(Row, "Category"), Reason: rowString(Row, "Reason"), Status: rowString(Row, "Status"),
+			ModeratorID: rowUint(Row, "ModeratorID"), Outcome: rowString(Row, "Outcome"), CreationTime: rowTime(Row, "CreationTime"), UpdateTime: rowTime(Row, "UpdateTime")}
+	case "Bans":
+		DBConnection.bans[ID] = &memoryBan{ID: ID, UserID: rowUint(Row, "UserID"), IPRange: rowString(Row, "IPRange"), Reason: rowString(Row, "Reason"), BannerID: rowUint(Row, "BannerID"),
+			CreationTime: rowTime(Row, "CreationTime"), ExpiryTime: rowTime(Row, "ExpiryTime"), LifterID: rowUint(Row, "LifterID"), LiftTime: rowTime(Row, "LiftTime")}
+	default:
+		return errors.New("Unknown table " + Table)
+	}
+	if ID > DBConnection.autoIncrement[Table] {
+		DBConnection.autoIncrement[Table] = ID
+	}
+	return nil
+}
+
+//setString returns Value, or nil for NULL if it was never set
+func setString(Set bool, Value string) interface{} {
+	if Set == false {
+		return nil
+	}
+	return Value
+}
+
+//setTime returns Value, or nil for NULL if it is the zero time
+func setTime(Value time.Time) interface{} {
+	if Value.IsZero() {
+		return nil
+	}
+	return Value
+}
+
+//rowUint returns a BackupUint column, or 0 if it is missing
+func rowUint(Row interfaces.BackupRow, Column string) uint64 {
+	value, _ := Row[Column].(uint64)
+	return value
+}
+
+//rowInt returns a BackupInt column, or 0 if it is missing
+func rowInt(Row interfaces.BackupRow, Column string) int64 {
+	value, _ := Row[Column].(int64)
+	return value
+}
+
+//rowFloat returns a BackupFloat column, or 0 if it is missing
+func rowFloat(Row interfaces.BackupRow, Column string) float64 {
+	value, _ := Row[Column].(float64)
+	return value
+}
+
+//rowString returns a BackupString or BackupNullString column, NULL becomes an empty string as in the rest of this plugin
+func rowString(Row interfaces.BackupRow, Column string) string {
+	value, _ := Row[Column].(string)
+	return value
+}
+
+//rowBool returns a BackupBool column, or false if it is missing
+func rowBool(Row interfaces.BackupRow, Column string) bool {
+	value, _ := Row[Column].(bool)
+	return value
+}
+
+//rowTime returns a BackupTime or BackupNullTime column, or the zero time if it is missing or NULL
+func rowTime(Row interfaces.BackupRow, Column string) time.Time {
+	value, _ := Row[Column].(time.Time)
+	return value
+}
+
+//rowNoteRegion returns the region columns of a note or note revision
+func rowNoteRegion(Row interfaces.BackupRow) interfaces.NoteRegion {
+	return interfaces.NoteRegion{X: rowInt(Row, "X"), Y: rowInt(Row, "Y"), Width: rowInt(Row, "Width"), Height: rowInt(Row, "Height"), ImageWidth: rowInt(Row, "ImageWidth"), ImageHeight: rowInt(Row, "ImageHeight")}
+}
@@ -0,0 +1,99 @@
+package memoryplugin
+
+import (
+	"database/sql"
+	"errors"
+	"go-image-board/interfaces"
+	"go-image-board/logging"
+	"sort"
+	"strconv"
+	"time"
+)
+
+//NewBan bans Ban.UserID, or Ban.IPRange, by Ban.BannerID until Ban.ExpiryTime, or permanently if it is the zero time. Returns its ID
+func (DBConnection *MemoryPlugin) NewBan(Ban interfaces.BanInformation) (uint64, error) {
+	DBConnection.dbMutex.Lock()
+	defer DBConnection.dbMutex.Unlock()
+	ID := DBConnection.nextID("Bans")
+	DBConnection.bans[ID] = &memoryBan{ID: ID, UserID: Ban.UserID, IPRange: Ban.IPRange, Reason: Ban.Reason, BannerID: Ban.BannerID, CreationTime: time.Now(), ExpiryTime: Ban.ExpiryTime}
+	logging.WriteLog(logging.LogLevelInfo, "MemoryPlugin/NewBan", strconv.FormatUint(Ban.BannerID, 10), logging.ResultSuccess, []string{"Ban added", strconv.FormatUint(ID, 10)})
+	return ID, nil
+}
+
+//GetBan returns a single ban
+func (DBConnection *MemoryPlugin) GetBan(BanID uint64) (interfaces.BanInformation, error) {
+	DBConnection.dbMutex.RLock()
+	defer DBConnection.dbMutex.RUnlock()
+	ban, exists := DBConnection.bans[BanID]
+	if exists == false {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/GetBan", "0", logging.ResultFailure, []string{"Failed to get ban", strconv.FormatUint(BanID, 10)})
+		return interfaces.BanInformation{}, sql.ErrNoRows
+	}
+	return DBConnection.getBanInformation(ban), nil
+}
+
+//GetActiveBans returns every ban that has neither been lifted nor expired, newest first
+func (DBConnection *MemoryPlugin) GetActiveBans() ([]interfaces.BanInformation, error) {
+	DBConnection.dbMutex.RLock()
+	defer DBConnection.dbMutex.RUnlock()
+	return DBConnection.getActiveBans(), nil
+}
+
+//GetActiveBansOn returns the bans on the account UserID, or on a range containing IP, that have neither been lifted nor expired, newest first. Use 0 for UserID to only check the address
+func (DBConnection *MemoryPlugin) GetActiveBansOn(UserID uint64, IP string) ([]interfaces.BanInformation, error) {
+	DBConnection.dbMutex.RLock()
+	defer DBConnection.dbMutex.RUnlock()
+	return DBConnection.getActiveBansOn(UserID, IP), nil
+}
+
+//getActiveBansOn is GetActiveBansOn for callers already holding the lock
+func (DBConnection *MemoryPlugin) getActiveBansOn(UserID uint64, IP string) []interfaces.BanInformation {
+	var ToReturn []interfaces.BanInformation
+	for _, ban := range DBConnection.getActiveBans() {
+		if ban.Applies(UserID, IP) {
+			ToReturn = append(ToReturn, ban)
+		}
+	}
+	return ToReturn
+}
+
+//getActiveBans is GetActiveBans for callers already holding the lock
+func (DBConnection *MemoryPlugin) getActiveBans() []interfaces.BanInformation {
+	var ToReturn []interfaces.BanInformation
+	now := time.Now()
+	for _, ban := range DBConnection.bans {
+		if banInformation := DBConnection.getBanInformation(ban); banInformation.IsActive(now) {
+			ToReturn = append(ToReturn, banInformation)
+		}
+	}
+	sort.Slice(ToReturn, func(i, j int) bool { return ToReturn[i].ID > ToReturn[j].ID })
+	return ToReturn
+}
+
+//LiftBan lifts a ban that has not already been lifted, before it expires
+func (DBConnection *MemoryPlugin) LiftBan(BanID uint64, LifterID uint64) error {
+	DBConnection.dbMutex.Lock()
+	defer DBConnection.dbMutex.Unlock()
+	ban, exists := DBConnection.bans[BanID]
+	if exists == false || ban.LiftTime.IsZero() == false {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/LiftBan", strconv.FormatUint(LifterID, 10), logging.ResultFailure, []string{"Failed to lift ban", strconv.FormatUint(BanID, 10)})
+		return errors.New("ban does not exist or is already lifted")
+	}
+	ban.LifterID = LifterID
+	ban.LiftTime = time.Now()
+	logging.WriteLog(logging.LogLevelInfo, "MemoryPlugin/LiftBan", strconv.FormatUint(LifterID, 10), logging.ResultSuccess, []string{"Ban lifted", strconv.FormatUint(BanID, 10)})
+	return nil
+}
+
+//getBanInformation converts a stored ban, including the names of the banned account and the moderator that banned them
+func (DBConnection *MemoryPlugin) getBanInformation(ban *memoryBan) interfaces.BanInformation {
+	ToReturn := interfaces.BanInformation{ID: ban.ID, UserID: ban.UserID, IPRange: ban.IPRange, Reason: ban.Reason, BannerID: ban.BannerID, CreationTime: ban.CreationTime,
+		ExpiryTime: ban.ExpiryTime, LifterID: ban.LifterID, LiftTime: ban.LiftTime}
+	if user, exists := DBConnection.users[ban.UserID]; exists && ban.UserID != 0 {
+		ToReturn.UserName = user.Name
+	}
+	if user, exists := DBConnection.users[ban.BannerID]; exists {
+		ToReturn.BannerName = user.Name
+	}
+	return ToReturn
+}
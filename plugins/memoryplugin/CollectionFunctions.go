@@ -0,0 +1,441 @@
+package memoryplugin
+
+import (
+	"database/sql"
+	"errors"
+	"go-image-board/interfaces"
+	"go-image-board/logging"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//--Collections
+
+//NewCollection adds a collection with the provided information
+func (DBConnection *MemoryPlugin) NewCollection(Name string, Description string, UploaderID uint64) (uint64, error) {
+	if len(Name) < 3 || len(Name) > 255 || len(Description) > 255 {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/NewCollection", strconv.FormatUint(UploaderID, 10), logging.ResultFailure, []string{"Failed to add collection due to name/description size", Name, Description})
+		return 0, errors.New("name or description outside size range")
+	}
+
+	DBConnection.dbMutex.Lock()
+	defer DBConnection.dbMutex.Unlock()
+	if DBConnection.getCollectionByName(Name) != nil {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/NewCollection", strconv.FormatUint(UploaderID, 10), logging.ResultFailure, []string{"Failed to add collection", "duplicate name " + Name})
+		return 0, errors.New("a collection with that name already exists")
+	}
+	ID := DBConnection.nextID("Collections")
+	DBConnection.collections[ID] = &memoryCollection{ID: ID, Name: Name, Description: Description, UploaderID: UploaderID, UploadTime: time.Now()}
+	logging.WriteLog(logging.LogLevelError, "MemoryPlugin/NewCollection", strconv.FormatUint(UploaderID, 10), logging.ResultSuccess, []string{"Collection added"})
+	return ID, nil
+}
+
+//DeleteCollection removes a collection
+func (DBConnection *MemoryPlugin) DeleteCollection(CollectionID uint64) error {
+	DBConnection.dbMutex.Lock()
+	defer DBConnection.dbMutex.Unlock()
+	DBConnection.deleteCollection(CollectionID)
+	return nil
+}
+
+//deleteCollection removes a collection, along with its members and tags as the onCollectionDelete trigger would, and its comments
+func (DBConnection *MemoryPlugin) deleteCollection(CollectionID uint64) {
+	for key := range DBConnection.collectionMembers {
+		if key.CollectionID == CollectionID {
+			delete(DBConnection.collectionMembers, key)
+		}
+	}
+	for key := range DBConnection.collectionTags {
+		if key.CollectionID == CollectionID {
+			delete(DBConnection.collectionTags, key)
+		}
+	}
+	DBConnection.deleteComments(0, CollectionID)
+	delete(DBConnection.collections, CollectionID)
+	logging.WriteLog(logging.LogLevelError, "MemoryPlugin/DeleteCollection", "0", logging.ResultSuccess, []string{"Collection deleted", strconv.FormatUint(CollectionID, 10)})
+}
+
+//UpdateCollection updates a pre-existing collection
+func (DBConnection *MemoryPlugin) UpdateCollection(CollectionID uint64, Name string, Description string) error {
+	//Cleanup name
+	if len(Name) < 3 || len(Name) > 255 || len(Description) > 255 {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/UpdateCollection", "0", logging.ResultFailure, []string{"Failed to update collection due to size of name/description", Name, Description})
+		return errors.New("name or description outside of right sizes")
+	}
+
+	DBConnection.dbMutex.Lock()
+	defer DBConnection.dbMutex.Unlock()
+	if other := DBConnection.getCollectionByName(Name); other != nil && other.ID != CollectionID {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/UpdateCollection", "0", logging.ResultFailure, []string{"Failed to update collection", "duplicate name " + Name})
+		return errors.New("a collection with that name already exists")
+	}
+	if collection, exists := DBConnection.collections[CollectionID]; exists {
+		collection.Name = Name
+		collection.Description = Description
+	}
+	logging.WriteLog(logging.LogLevelError, "MemoryPlugin/UpdateCollection", "0", logging.ResultSuccess, []string{"Collection updated"})
+	return nil
+}
+
+//GetCollections returns a list of all collections, but only the ID, Name, Description
+func (DBConnection *MemoryPlugin) GetCollections(PageStart uint64, PageStride uint64) ([]interfaces.CollectionInformation, uint64, error) {
+	DBConnection.dbMutex.RLock()
+	defer DBConnection.dbMutex.RUnlock()
+	var ToReturn []interfaces.CollectionInformation
+
+	var collections []*memoryCollection
+	for _, collection := range DBConnection.collections {
+		if collection.DeletedTime.IsZero() {
+			collections = append(collections, collection)
+		}
+	}
+	sort.Slice(collections, func(i, j int) bool { return collections[i].Name < collections[j].Name })
+
+	start, end := pageBounds(len(collections), PageStart, PageStride)
+	for _, collection := range collections[start:end] {
+		members := DBConnection.getCollectionMemberRows(collection.ID)
+		ToReturn = append(ToReturn, interfaces.CollectionInformation{Name: collection.Name, ID: collection.ID, Description: collection.Description, Location: DBConnection.getCollectionPreview(members), Members: uint64(len(members))})
+	}
+	return ToReturn, uint64(len(collections)), nil
+}
+
+//GetCollection returns detailed information on one collection
+func (DBConnection *MemoryPlugin) GetCollection(ID uint64) (interfaces.CollectionInformation, error) {
+	DBConnection.dbMutex.RLock()
+	defer DBConnection.dbMutex.RUnlock()
+	collection, exists := DBConnection.collections[ID]
+	if exists == false {
+		return interfaces.CollectionInformation{}, sql.ErrNoRows
+	}
+	return DBConnection.getCollectionInformation(collection), nil
+}
+
+//GetCollectionByName returns detailed information on one collection
+func (DBConnection *MemoryPlugin) GetCollectionByName(Name string) (interfaces.CollectionInformation, error) {
+	DBConnection.dbMutex.RLock()
+	defer DBConnection.dbMutex.RUnlock()
+	collection := DBConnection.getCollectionByName(Name)
+	if collection == nil {
+		return interfaces.CollectionInformation{}, sql.ErrNoRows
+	}
+	return DBConnection.getCollectionInformation(collection), nil
+}
+
+//getCollectionInformation fills out the detailed CollectionInformation for a collection
+func (DBConnection *MemoryPlugin) getCollectionInformation(collection *memoryCollection) interfaces.CollectionInformation {
+	MemberCount := uint64(len(DBConnection.getCollectionMemberRows(collection.ID)))
+	ToReturn := interfaces.CollectionInformation{Name: collection.Name, ID: collection.ID, Description: collection.Description, UploaderID: collection.UploaderID, UploadTime: collection.UploadTime, Members: MemberCount,
+		InTrash: collection.DeletedTime.IsZero() == false, DeletedTime: collection.DeletedTime, DeleterID: collection.DeleterID}
+	if deleter, exists := DBConnection.users[collection.DeleterID]; exists && ToReturn.InTrash {
+		ToReturn.DeleterName = deleter.Name
+	}
+	return ToReturn
+}
+
+//getCollectionByName returns the collection with the given name, or nil
+func (DBConnection *MemoryPlugin) getCollectionByName(Name string) *memoryCollection {
+	for _, collection := range DBConnection.collections {
+		if strings.EqualFold(collection.Name, Name) {
+			return collection
+		}
+	}
+	return nil
+}
+
+//getCollectionMemberRows returns the CollectionMembers rows of a collection ordered by OrderWeight
+func (DBConnection *MemoryPlugin) getCollectionMemberRows(CollectionID uint64) []*memoryCollectionMember {
+	var ToReturn []*memoryCollectionMember
+	for key, member := range DBConnection.collectionMembers {
+		if key.CollectionID == CollectionID {
+			ToReturn = append(ToReturn, member)
+		}
+	}
+	sort.Slice(ToReturn, func(i, j int) bool {
+		if ToReturn[i].OrderWeight == ToReturn[j].OrderWeight {
+			return ToReturn[i].ID < ToReturn[j].ID
+		}
+		return ToReturn[i].OrderWeight < ToReturn[j].OrderWeight
+	})
+	return ToReturn
+}
+
+//getCollectionPreview returns the location of the first image in a collection, for use as a cover
+func (DBConnection *MemoryPlugin) getCollectionPreview(members []*memoryCollectionMember) string {
+	if len(members) == 0 {
+		return ""
+	}
+	if image, exists := DBConnection.images[members[0].ImageID]; exists {
+		return image.Location
+	}
+	return ""
+}
+
+//--Collection Members
+
+//AddCollectionMember adds an image to a collection
+func (DBConnection *MemoryPlugin) AddCollectionMember(CollectionID uint64, ImageIDs []uint64, LinkerID uint64) error {
+	if len(ImageIDs) == 0 {
+		return errors.New("ImageIDs required")
+	}
+	DBConnection.dbMutex.Lock()
+	defer DBConnection.dbMutex.Unlock()
+
+	idString := ""
+	for i := 0; i < len(ImageIDs); i++ {
+		idString += strconv.FormatUint(ImageIDs[i], 10) + ", "
+	}
+
+	//Validate everything first, so the insert either fully happens or not at all
+	var err error
+	if _, exists := DBConnection.collections[CollectionID]; exists == false {
+		err = errors.New("collection does not exist")
+	}
+	for i := 0; i < len(ImageIDs) && err == nil; i++ {
+		if _, exists := DBConnection.images[ImageIDs[i]]; exists == false {
+			err = errors.New("image " + strconv.FormatUint(ImageIDs[i], 10) + " does not exist")
+		} else if _, exists := DBConnection.collectionMembers[collectionMemberKey{CollectionID: CollectionID, ImageID: ImageIDs[i]}]; exists || uint64SliceContains(ImageIDs[:i], ImageIDs[i]) {
+			err = errors.New("image " + strconv.FormatUint(ImageIDs[i], 10) + " is already in the collection")
+		}
+	}
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/AddCollectionMember", strconv.FormatUint(LinkerID, 10), logging.ResultFailure, []string{"Image not added to collection", strconv.FormatUint(CollectionID, 10), idString, err.Error()})
+		return err
+	}
+
+	//Get last order
+	//If we are not an empty collection, increment the number
+	//Otherwise first image will have 0 as it's weight
+	members := DBConnection.getCollectionMemberRows(CollectionID)
+	lastOrder := uint64(0)
+	for _, member := range members {
+		if member.OrderWeight > lastOrder {
+			lastOrder = member.OrderWeight
+		}
+	}
+	if len(members) != 0 {
+		lastOrder++
+	}
+	for _, ImageID := range ImageIDs {
+		DBConnection.collectionMembers[collectionMemberKey{CollectionID: CollectionID, ImageID: ImageID}] = &memoryCollectionMember{ID: DBConnection.nextID("CollectionMembers"), ImageID: ImageID, CollectionID: CollectionID, LinkerID: LinkerID, LinkTime: time.Now(), OrderWeight: lastOrder}
+		lastOrder++
+		//onCollectionMemberAdd
+		DBConnection.addMissingCollectionImageTags(ImageID)
+	}
+	logging.WriteLog(logging.LogLevelError, "MemoryPlugin/AddCollectionMember", strconv.FormatUint(LinkerID, 10), logging.ResultSuccess, []string{"Image added to collection", strconv.FormatUint(CollectionID, 10), idString})
+	return nil
+}
+
+//RemoveCollectionMember removes an image from collection
+func (DBConnection *MemoryPlugin) RemoveCollectionMember(CollectionID uint64, ImageID uint64) error {
+	DBConnection.dbMutex.Lock()
+	defer DBConnection.dbMutex.Unlock()
+	return DBConnection.removeCollectionMember(CollectionID, ImageID)
+}
+
+//removeCollectionMember is RemoveCollectionMember for callers already holding the lock
+func (DBConnection *MemoryPlugin) removeCollectionMember(CollectionID uint64, ImageID uint64) error {
+	//Get Order
+	key := collectionMemberKey{CollectionID: CollectionID, ImageID: ImageID}
+	member, exists := DBConnection.collectionMembers[key]
+	if exists == false {
+		return sql.ErrNoRows
+	}
+	Order := member.OrderWeight
+
+	//If last member of collection, just delete it instead
+	if len(DBConnection.getCollectionMemberRows(CollectionID)) <= 1 {
+		DBConnection.deleteCollection(CollectionID)
+		return nil
+	}
+
+	//Delete Image, then onCollectionMemberDelete
+	delete(DBConnection.collectionMembers, key)
+	DBConnection.removeSurplusCollectionTags(CollectionID)
+	logging.WriteLog(logging.LogLevelError, "MemoryPlugin/RemoveCollectionMember", "0", logging.ResultSuccess, []string{"Image removed from collection", strconv.FormatUint(CollectionID, 10), strconv.FormatUint(ImageID, 10)})
+
+	//Decrement Order
+	for _, other := range DBConnection.getCollectionMemberRows(CollectionID) {
+		if other.OrderWeight > Order {
+			other.OrderWeight--
+		}
+	}
+	return nil
+}
+
+//UpdateCollectionMember updates an image's properties in a collection
+func (DBConnection *MemoryPlugin) UpdateCollectionMember(CollectionID uint64, ImageID uint64, Order uint64) error {
+	DBConnection.dbMutex.Lock()
+	defer DBConnection.dbMutex.Unlock()
+	//Get Current Order
+	member, exists := DBConnection.collectionMembers[collectionMemberKey{CollectionID: CollectionID, ImageID: ImageID}]
+	if exists == false {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/UpdateCollectionMember", "0", logging.ResultFailure, []string{"Could not get previous order to update collectionmember", strconv.FormatUint(CollectionID, 10), strconv.FormatUint(ImageID, 10), sql.ErrNoRows.Error()})
+		return sql.ErrNoRows
+	}
+	BeforeOrder := member.OrderWeight
+	members := DBConnection.getCollectionMemberRows(CollectionID)
+
+	//Ensure that we do not try and set this image to say, the 20th position when we have 3 images. Don't error, just silently set order to last image.
+	MemberCount := uint64(len(members))
+	if MemberCount <= Order {
+		Order = MemberCount - 1 //-1 because we are ordering from 0. If we have 20 images, the last spot is actually 19
+	}
+
+	//Set order for image
+	member.OrderWeight = Order
+
+	//Decrement Order
+	for _, other := range members {
+		if other.ImageID != ImageID && other.OrderWeight >= BeforeOrder {
+			other.OrderWeight--
+		}
+	}
+
+	//Increment Order
+	for _, other := range members {
+		if other.ImageID != ImageID && other.OrderWeight >= Order {
+			other.OrderWeight++
+		}
+	}
+	return nil
+}
+
+//GetCollectionMembers gets a list of images in a collection (Returns a list of imageIDs, or error)
+func (DBConnection *MemoryPlugin) GetCollectionMembers(CollectionID uint64, PageStart uint64, PageStride uint64) ([]interfaces.ImageInformation, uint64, error) {
+	DBConnection.dbMutex.RLock()
+	defer DBConnection.dbMutex.RUnlock()
+	var ToReturn []interfaces.ImageInformation
+	//Members in the trash or waiting for approval are left out
+	var members []*memoryCollectionMember
+	for _, member := range DBConnection.getCollectionMemberRows(CollectionID) {
+		if DBConnection.images[member.ImageID].DeletedTime.IsZero() && DBConnection.images[member.ImageID].Pending == false {
+			members = append(members, member)
+		}
+	}
+
+	//If we did not limit the search, return everything
+	start, end := 0, len(members)
+	if PageStride > 0 {
+		start, end = pageBounds(len(members), PageStart, PageStride)
+	}
+	for _, member := range members[start:end] {
+		image := DBConnection.images[member.ImageID]
+		ToReturn = append(ToReturn, interfaces.ImageInformation{Name: image.Name, ID: image.ID, Location: image.Location, MIMEType: image.Metadata.MIMEType, OrderInCollection: member.OrderWeight})
+	}
+	return ToReturn, uint64(len(members)), nil
+}
+
+//GetCollectionsWithImage returns a slice of collections with a specific image
+func (DBConnection *MemoryPlugin) GetCollectionsWithImage(ImageID uint64) ([]interfaces.CollectionInformation, error) {
+	DBConnection.dbMutex.RLock()
+	defer DBConnection.dbMutex.RUnlock()
+	var ToReturn []interfaces.CollectionInformation
+	for _, CollectionID := range DBConnection.getCollectionIDsWithImage(ImageID) {
+		collection := DBConnection.collections[CollectionID]
+		members := DBConnection.getCollectionMemberRows(CollectionID)
+		var Order, BeforeID, AfterID uint64
+		for index, member := range members {
+			if member.ImageID != ImageID {
+				continue
+			}
+			Order = member.OrderWeight
+			if index > 0 {
+				BeforeID = members[index-1].ImageID
+			}
+			if index < len(members)-1 {
+				AfterID = members[index+1].ImageID
+			}
+		}
+		ToReturn = append(ToReturn, interfaces.CollectionInformation{Name: collection.Name, Description: collection.Description, ID: CollectionID, OrderInCollection: Order, Members: uint64(len(members)), PreviousMemberID: BeforeID, NextMemberID: AfterID, InTrash: collection.DeletedTime.IsZero() == false})
+	}
+	return ToReturn, nil
+}
+
+//getCollectionIDsWithImage returns the IDs of every collection an image is a member of, lowest first
+func (DBConnection *MemoryPlugin) getCollectionIDsWithImage(ImageID uint64) []uint64 {
+	var ToReturn []uint64
+	for key := range DBConnection.collectionMembers {
+		if key.ImageID == ImageID {
+			ToReturn = append(ToReturn, key.CollectionID)
+		}
+	}
+	sort.Slice(ToReturn, func(i, j int) bool { return ToReturn[i] < ToReturn[j] })
+	return ToReturn
+}
+
+//GetCollectionTags returns a list of TagInformation for all tags that apply to the given collection
+func (DBConnection *MemoryPlugin) GetCollectionTags(CollectionID uint64) ([]interfaces.TagInformation, error) {
+	DBConnection.dbMutex.RLock()
+	defer DBConnection.dbMutex.RUnlock()
+	var collectionTags []*memoryCollectionTag
+	for key, collectionTag := range DBConnection.collectionTags {
+		if key.CollectionID == CollectionID {
+			collectionTags = append(collectionTags, collectionTag)
+		}
+	}
+	sort.Slice(collectionTags, func(i, j int) bool { return collectionTags[i].ID < collectionTags[j].ID })
+
+	var ToReturn []interfaces.TagInformation
+	for _, collectionTag := range collectionTags {
+		tag := DBConnection.tags[collectionTag.TagID]
+		ToReturn = append(ToReturn, interfaces.TagInformation{Name: tag.Name, ID: tag.ID, Description: tag.Description, Exists: true, Exclude: false, Category: tag.Category})
+	}
+	return ToReturn, nil
+}
+
+//FixCollectionTags  verifies and fixes collection tags, returns row count and error
+func (DBConnection *MemoryPlugin) FixCollectionTags(CollectionID uint64) (int64, error) {
+	DBConnection.dbMutex.Lock()
+	defer DBConnection.dbMutex.Unlock()
+	var RowsAffected int64
+	//Insert missing tags
+	for _, member := range DBConnection.getCollectionMemberRows(CollectionID) {
+		for _, imageTag := range DBConnection.getImageTagRows(member.ImageID) {
+			RowsAffected += DBConnection.addCollectionTag(CollectionID, imageTag)
+		}
+	}
+	//Remove extra tags
+	RowsAffected += DBConnection.removeSurplusCollectionTags(CollectionID)
+	return RowsAffected, nil
+}
+
+//addMissingCollectionImageTags adds an image's tags to every collection it is in, returning how many were added
+func (DBConnection *MemoryPlugin) addMissingCollectionImageTags(ImageID uint64) int64 {
+	var RowsAffected int64
+	for _, CollectionID := range DBConnection.getCollectionIDsWithImage(ImageID) {
+		for _, imageTag := range DBConnection.getImageTagRows(ImageID) {
+			RowsAffected += DBConnection.addCollectionTag(CollectionID, imageTag)
+		}
+	}
+	return RowsAffected
+}
+
+//addCollectionTag adds the tag from an ImageTags row to a collection if it does not already have it, returning how many were added
+func (DBConnection *MemoryPlugin) addCollectionTag(CollectionID uint64, imageTag *memoryImageTag) int64 {
+	key := collectionTagKey{CollectionID: CollectionID, TagID: imageTag.TagID}
+	if _, exists := DBConnection.collectionTags[key]; exists {
+		return 0
+	}
+	DBConnection.collectionTags[key] = &memoryCollectionTag{ID: DBConnection.nextID("CollectionTags"), CollectionID: CollectionID, TagID: imageTag.TagID, LinkerID: imageTag.LinkerID, LinkTime: time.Now()}
+	return 1
+}
+
+//removeSurplusCollectionTags removes collection tags no member image has anymore, returning how many were removed
+func (DBConnection *MemoryPlugin) removeSurplusCollectionTags(CollectionID uint64) int64 {
+	var RowsAffected int64
+	memberTags := make(map[uint64]bool)
+	for _, member := range DBConnection.getCollectionMemberRows(CollectionID) {
+		for _, imageTag := range DBConnection.getImageTagRows(member.ImageID) {
+			memberTags[imageTag.TagID] = true
+		}
+	}
+	for key := range DBConnection.collectionTags {
+		if key.CollectionID == CollectionID && memberTags[key.TagID] == false {
+			delete(DBConnection.collectionTags, key)
+			RowsAffected++
+		}
+	}
+	return RowsAffected
+}
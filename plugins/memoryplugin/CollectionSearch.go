@@ -0,0 +1,181 @@
+package memoryplugin
+
+import (
+	"errors"
+	"go-image-board/interfaces"
+	"sort"
+	"time"
+)
+
+//SearchCollections performs a search for collections (Returns a list of CollectionInformation a result count and an error/nil)
+//If you edit this function, consider SearchImages for a similar change
+func (DBConnection *MemoryPlugin) SearchCollections(Tags []interfaces.TagInformation, PageStart uint64, PageStride uint64) ([]interfaces.CollectionInformation, uint64, error) {
+	//Cleanup input for use in code below
+	//Specifically we separate the include, the exclude and metatags into their own lists
+	var IncludeTags []uint64
+	var ExcludeTags []uint64
+	var MetaTags []interfaces.TagInformation
+	for _, tag := range Tags {
+		if tag.Exists && tag.IsAlias == false && tag.IsMeta == false {
+			if tag.Exclude {
+				ExcludeTags = append(ExcludeTags, tag.ID)
+			} else {
+				IncludeTags = append(IncludeTags, tag.ID)
+			}
+		} else if tag.Exists && tag.IsMeta {
+			MetaTags = append(MetaTags, tag)
+		}
+	}
+
+	//Initialize output
+	var ToReturn []interfaces.CollectionInformation
+
+	//Resolve comparators up front, so a bad tag fails the whole search as it would in SQL
+	comparators := make([]string, len(MetaTags))
+	for index, tag := range MetaTags {
+		if tag.IsGroup {
+			continue //Groups resolve the comparators of their members as they are checked
+		}
+		comparator := tag.Comparator
+		if tag.Exclude {
+			comparator = getInvertedComparator(comparator)
+		}
+		if comparator == "" {
+			return ToReturn, 0, errors.New("Failed to invert query to negate on " + tag.Name)
+		}
+		comparators[index] = comparator
+	}
+
+	DBConnection.dbMutex.RLock()
+	defer DBConnection.dbMutex.RUnlock()
+	var collectionIDs []uint64
+	for ID, collection := range DBConnection.collections {
+		if collection.DeletedTime.IsZero() {
+			collectionIDs = append(collectionIDs, ID)
+		}
+	}
+	//Newest first
+	sort.Slice(collectionIDs, func(i, j int) bool { return collectionIDs[i] > collectionIDs[j] })
+
+	var matchingIDs []uint64
+	for _, ID := range collectionIDs {
+		collection := DBConnection.collections[ID]
+		//A collection must have every include tag, counted the same way as the MatchingTags count in SQL
+		if len(IncludeTags) > 0 {
+			var MatchingTags int
+			for _, TagID := range uint64SetOf(IncludeTags) {
+				if _, exists := DBConnection.collectionTags[collectionTagKey{CollectionID: ID, TagID: TagID}]; exists {
+					MatchingTags++
+				}
+			}
+			if MatchingTags != len(IncludeTags) {
+				continue
+			}
+		}
+		excluded := false
+		for _, TagID := range ExcludeTags {
+			if _, exists := DBConnection.collectionTags[collectionTagKey{CollectionID: ID, TagID: TagID}]; exists {
+				excluded = true
+				break
+			}
+		}
+		if excluded {
+			continue
+		}
+		matches := true
+		for index, tag := range MetaTags {
+			var match bool
+			var err error
+			if tag.IsGroup {
+				match, err = DBConnection.collectionMatchesTagGroup(ID, collection, tag)
+			} else {
+				match, err = DBConnection.collectionMatchesMetaTag(collection, tag, comparators[index])
+			}
+			if err != nil {
+				return nil, 0, err
+			}
+			if match == false {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			matchingIDs = append(matchingIDs, ID)
+		}
+	}
+
+	start, end := pageBounds(len(matchingIDs), PageStart, PageStride)
+	for _, ID := range matchingIDs[start:end] {
+		collection := DBConnection.collections[ID]
+		members := DBConnection.getCollectionMemberRows(ID)
+		ToReturn = append(ToReturn, interfaces.CollectionInformation{Name: collection.Name, ID: ID, Location: DBConnection.getCollectionPreview(members), Members: uint64(len(members))})
+	}
+	return ToReturn, uint64(len(matchingIDs)), nil
+}
+
+//collectionMatchesMetaTag checks a single metatag, with its already inverted comparator, against a collection
+func (DBConnection *MemoryPlugin) collectionMatchesMetaTag(collection *memoryCollection, tag interfaces.TagInformation, comparator string) (bool, error) {
+	switch tag.Name {
+	case "Uploaded": //Special Exception for dates
+		tagTimeValue, isTagValued := tag.MetaValue.(interfaces.TimeRange)
+		if isTagValued == false {
+			return false, errors.New("Failed get value of " + tag.Name)
+		}
+		return timeRangeMatches(collection.UploadTime, comparator, tagTimeValue)
+	case "Tagged":
+		var Times []time.Time
+		for key, row := range DBConnection.collectionTags {
+			if key.CollectionID == collection.ID {
+				Times = append(Times, row.LinkTime)
+			}
+		}
+		return anyTimeMatches(Times, tag)
+	case "Voted":
+		//Votes are cast on the collection's member images
+		var Times []time.Time
+		for key, score := range DBConnection.imageUserScores {
+			if _, isMember := DBConnection.collectionMembers[collectionMemberKey{CollectionID: collection.ID, ImageID: key.ImageID}]; isMember {
+				Times = append(Times, score.CreationTime)
+			}
+		}
+		return anyTimeMatches(Times, tag)
+	case "CommentCount":
+		return compareValues(DBConnection.countComments(0, collection.ID), comparator, tag.MetaValue)
+	case "Name":
+		return compareValues(collection.Name, comparator, tag.MetaValue)
+	case "UploaderID":
+		return compareValues(collection.UploaderID, comparator, tag.MetaValue)
+	}
+	return false, errors.New("Unknown column " + tag.Name)
+}
+
+//collectionMatchesTagGroup checks a group of tags, and any groups within it, against a collection
+func (DBConnection *MemoryPlugin) collectionMatchesTagGroup(ID uint64, collection *memoryCollection, Group interfaces.TagInformation) (bool, error) {
+	for _, tag := range Group.GroupTags {
+		var match bool
+		var err error
+		if tag.IsGroup {
+			match, err = DBConnection.collectionMatchesTagGroup(ID, collection, tag)
+		} else if tag.IsMeta {
+			comparator := tag.Comparator
+			if tag.Exclude {
+				comparator = getInvertedComparator(comparator)
+			}
+			if comparator == "" {
+				return false, errors.New("Failed to invert query to negate on " + tag.Name)
+			}
+			match, err = DBConnection.collectionMatchesMetaTag(collection, tag, comparator)
+		} else {
+			_, exists := DBConnection.collectionTags[collectionTagKey{CollectionID: ID, TagID: tag.ID}]
+			match = exists != tag.Exclude
+		}
+		if err != nil {
+			return false, err
+		}
+		//An OR group is decided by the first member that matches, any other group by the first that does not
+		if match == Group.MatchAny {
+			return match != Group.Exclude, nil
+		}
+	}
+	return Group.MatchAny == Group.Exclude, nil
+}
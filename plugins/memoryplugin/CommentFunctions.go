@@ -0,0 +1,180 @@
+package memoryplugin
+
+import (
+	"database/sql"
+	"errors"
+	"go-image-board/interfaces"
+	"go-image-board/logging"
+	"sort"
+	"strconv"
+	"time"
+)
+
+//NewComment adds a comment to Comment.ImageID, or Comment.CollectionID, replying to Comment.ParentID if set. Returns its ID
+func (DBConnection *MemoryPlugin) NewComment(Comment interfaces.CommentInformation) (uint64, error) {
+	DBConnection.dbMutex.Lock()
+	defer DBConnection.dbMutex.Unlock()
+	var ThreadID uint64
+	if Comment.ParentID != 0 {
+		parent, exists := DBConnection.comments[Comment.ParentID]
+		if exists == false {
+			logging.WriteLog(logging.LogLevelError, "MemoryPlugin/NewComment", strconv.FormatUint(Comment.UserID, 10), logging.ResultFailure, []string{"Failed to get comment", strconv.FormatUint(Comment.ParentID, 10)})
+			return 0, sql.ErrNoRows
+		}
+		if parent.ImageID != Comment.ImageID || parent.CollectionID != Comment.CollectionID {
+			return 0, errors.New("the comment being replied to is on something else")
+		}
+		ThreadID = parent.ThreadID
+		if ThreadID == 0 {
+			ThreadID = parent.ID
+		}
+	}
+	ID := DBConnection.nextID("Comments")
+	DBConnection.comments[ID] = &memoryComment{ID: ID, ImageID: Comment.ImageID, CollectionID: Comment.CollectionID, ParentID: Comment.ParentID, ThreadID: ThreadID, UserID: Comment.UserID, Body: Comment.Body, CreationTime: time.Now()}
+	logging.WriteLog(logging.LogLevelInfo, "MemoryPlugin/NewComment", strconv.FormatUint(Comment.UserID, 10), logging.ResultSuccess, []string{"Comment added", strconv.FormatUint(ID, 10)})
+	return ID, nil
+}
+
+//GetComment returns a single comment, including deleted ones
+func (DBConnection *MemoryPlugin) GetComment(CommentID uint64) (interfaces.CommentInformation, error) {
+	DBConnection.dbMutex.RLock()
+	defer DBConnection.dbMutex.RUnlock()
+	comment, exists := DBConnection.comments[CommentID]
+	if exists == false {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/GetComment", "0", logging.ResultFailure, []string{"Failed to get comment", strconv.FormatUint(CommentID, 10)})
+		return interfaces.CommentInformation{}, sql.ErrNoRows
+	}
+	return DBConnection.getCommentInformation(comment), nil
+}
+
+//GetComments returns a page of the threads on an image, or on a collection when ImageID is 0, oldest first. Each thread is ordered by ThreadComments, and the count of all threads is returned
+func (DBConnection *MemoryPlugin) GetComments(ImageID uint64, CollectionID uint64, PageStart uint64, PageStride uint64) ([]interfaces.CommentInformation, uint64, error) {
+	DBConnection.dbMutex.RLock()
+	defer DBConnection.dbMutex.RUnlock()
+	var threadIDs []uint64
+	for _, comment := range DBConnection.comments {
+		if comment.ImageID == ImageID && comment.CollectionID == CollectionID && comment.ParentID == 0 {
+			threadIDs = append(threadIDs, comment.ID)
+		}
+	}
+	sort.Slice(threadIDs, func(i, j int) bool { return threadIDs[i] < threadIDs[j] })
+	MaxResults := uint64(len(threadIDs))
+	if PageStart >= MaxResults {
+		return nil, MaxResults, nil
+	}
+	PageEnd := PageStart + PageStride
+	if PageEnd > MaxResults {
+		PageEnd = MaxResults
+	}
+	threads := make(map[uint64]bool)
+	for _, ID := range threadIDs[PageStart:PageEnd] {
+		threads[ID] = true
+	}
+	var comments []interfaces.CommentInformation
+	for _, comment := range DBConnection.comments {
+		if threads[comment.ID] || threads[comment.ThreadID] {
+			comments = append(comments, DBConnection.getCommentInformation(comment))
+		}
+	}
+	return interfaces.ThreadComments(comments), MaxResults, nil
+}
+
+//UpdateComment replaces the body of a comment, keeping the previous body as a revision
+func (DBConnection *MemoryPlugin) UpdateComment(CommentID uint64, Body string, EditorID uint64) error {
+	DBConnection.dbMutex.Lock()
+	defer DBConnection.dbMutex.Unlock()
+	comment, exists := DBConnection.comments[CommentID]
+	if exists == false {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/UpdateComment", strconv.FormatUint(EditorID, 10), logging.ResultFailure, []string{"Failed to get comment", strconv.FormatUint(CommentID, 10)})
+		return sql.ErrNoRows
+	}
+	now := time.Now()
+	ID := DBConnection.nextID("CommentRevisions")
+	DBConnection.commentRevisions[ID] = &memoryCommentRevision{ID: ID, CommentID: CommentID, Body: comment.Body, EditorID: EditorID, EditTime: now}
+	comment.Body = Body
+	comment.EditTime = now
+	logging.WriteLog(logging.LogLevelInfo, "MemoryPlugin/UpdateComment", strconv.FormatUint(EditorID, 10), logging.ResultSuccess, []string{"Comment updated", strconv.FormatUint(CommentID, 10)})
+	return nil
+}
+
+//GetCommentRevisions returns the previous bodies of a comment, newest first
+func (DBConnection *MemoryPlugin) GetCommentRevisions(CommentID uint64) ([]interfaces.CommentRevisionInformation, error) {
+	DBConnection.dbMutex.RLock()
+	defer DBConnection.dbMutex.RUnlock()
+	var ToReturn []interfaces.CommentRevisionInformation
+	for _, revision := range DBConnection.commentRevisions {
+		if revision.CommentID == CommentID {
+			revisionInfo := interfaces.CommentRevisionInformation{ID: revision.ID, CommentID: revision.CommentID, Body: revision.Body, EditorID: revision.EditorID, EditTime: revision.EditTime}
+			if editor, exists := DBConnection.users[revision.EditorID]; exists {
+				revisionInfo.EditorName = editor.Name
+			}
+			ToReturn = append(ToReturn, revisionInfo)
+		}
+	}
+	sort.Slice(ToReturn, func(i, j int) bool { return ToReturn[i].ID > ToReturn[j].ID })
+	return ToReturn, nil
+}
+
+//DeleteComment hides a comment, replies to it are kept
+func (DBConnection *MemoryPlugin) DeleteComment(CommentID uint64, DeleterID uint64) error {
+	DBConnection.dbMutex.Lock()
+	defer DBConnection.dbMutex.Unlock()
+	comment, exists := DBConnection.comments[CommentID]
+	if exists == false || comment.DeletedTime.IsZero() == false {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/DeleteComment", strconv.FormatUint(DeleterID, 10), logging.ResultFailure, []string{"Failed to delete comment", strconv.FormatUint(CommentID, 10)})
+		return errors.New("comment does not exist or is already deleted")
+	}
+	comment.DeletedTime = time.Now()
+	comment.DeleterID = DeleterID
+	logging.WriteLog(logging.LogLevelInfo, "MemoryPlugin/DeleteComment", strconv.FormatUint(DeleterID, 10), logging.ResultSuccess, []string{"Comment deleted", strconv.FormatUint(CommentID, 10)})
+	return nil
+}
+
+//RestoreComment shows a comment hidden by DeleteComment again
+func (DBConnection *MemoryPlugin) RestoreComment(CommentID uint64) error {
+	DBConnection.dbMutex.Lock()
+	defer DBConnection.dbMutex.Unlock()
+	comment, exists := DBConnection.comments[CommentID]
+	if exists == false || comment.DeletedTime.IsZero() {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/RestoreComment", "0", logging.ResultFailure, []string{"Failed to restore comment", strconv.FormatUint(CommentID, 10)})
+		return errors.New("comment does not exist or is not deleted")
+	}
+	comment.DeletedTime = time.Time{}
+	comment.DeleterID = 0
+	logging.WriteLog(logging.LogLevelInfo, "MemoryPlugin/RestoreComment", "0", logging.ResultSuccess, []string{"Comment restored", strconv.FormatUint(CommentID, 10)})
+	return nil
+}
+
+//deleteComments removes every comment on an image, or on a collection when ImageID is 0, along with their revisions
+func (DBConnection *MemoryPlugin) deleteComments(ImageID uint64, CollectionID uint64) {
+	for ID, comment := range DBConnection.comments {
+		if comment.ImageID == ImageID && comment.CollectionID == CollectionID {
+			for revisionID, revision := range DBConnection.commentRevisions {
+				if revision.CommentID == ID {
+					delete(DBConnection.commentRevisions, revisionID)
+				}
+			}
+			delete(DBConnection.comments, ID)
+		}
+	}
+}
+
+//countComments returns how many comments on an image, or on a collection when ImageID is 0, are not deleted
+func (DBConnection *MemoryPlugin) countComments(ImageID uint64, CollectionID uint64) int64 {
+	var count int64
+	for _, comment := range DBConnection.comments {
+		if comment.ImageID == ImageID && comment.CollectionID == CollectionID && comment.DeletedTime.IsZero() {
+			count++
+		}
+	}
+	return count
+}
+
+//getCommentInformation converts a stored comment, including the author's name
+func (DBConnection *MemoryPlugin) getCommentInformation(comment *memoryComment) interfaces.CommentInformation {
+	ToReturn := interfaces.CommentInformation{ID: comment.ID, ImageID: comment.ImageID, CollectionID: comment.CollectionID, ParentID: comment.ParentID, ThreadID: comment.ThreadID, UserID: comment.UserID, Body: comment.Body, CreationTime: comment.CreationTime, EditTime: comment.EditTime, Deleted: comment.DeletedTime.IsZero() == false, DeletedTime: comment.DeletedTime, DeleterID: comment.DeleterID}
+	if user, exists := DBConnection.users[comment.UserID]; exists {
+		ToReturn.UserName = user.Name
+	}
+	return ToReturn
+}
@@ -0,0 +1,34 @@
+package memoryplugin
+
+import (
+	"go-image-board/logging"
+	"strconv"
+	"time"
+)
+
+//Favorite operations
+
+//SetUserFavorite adds an image to, or removes it from, a user's favorites
+func (DBConnection *MemoryPlugin) SetUserFavorite(UserID uint64, ImageID uint64, Favorite bool) error {
+	DBConnection.dbMutex.Lock()
+	defer DBConnection.dbMutex.Unlock()
+	key := imageUserScoreKey{UserID: UserID, ImageID: ImageID}
+	if Favorite == false {
+		delete(DBConnection.imageFavorites, key)
+		logging.WriteLog(logging.LogLevelInfo, "MemoryPlugin/SetUserFavorite", strconv.FormatUint(UserID, 10), logging.ResultSuccess, []string{"Favorite removed", strconv.FormatUint(ImageID, 10)})
+		return nil
+	}
+	if _, exists := DBConnection.imageFavorites[key]; exists == false {
+		DBConnection.imageFavorites[key] = &memoryImageUserFavorite{ID: DBConnection.nextID("ImageUserFavorites"), UserID: UserID, ImageID: ImageID, CreationTime: time.Now()}
+	}
+	logging.WriteLog(logging.LogLevelInfo, "MemoryPlugin/SetUserFavorite", strconv.FormatUint(UserID, 10), logging.ResultSuccess, []string{"Favorite added", strconv.FormatUint(ImageID, 10)})
+	return nil
+}
+
+//GetUserFavorite Returns whether a user has favorited an image
+func (DBConnection *MemoryPlugin) GetUserFavorite(UserID uint64, ImageID uint64) (bool, error) {
+	DBConnection.dbMutex.RLock()
+	defer DBConnection.dbMutex.RUnlock()
+	_, exists := DBConnection.imageFavorites[imageUserScoreKey{UserID: UserID, ImageID: ImageID}]
+	return exists, nil
+}
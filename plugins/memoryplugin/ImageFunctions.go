@@ -0,0 +1,259 @@
+package memoryplugin
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"go-image-board/interfaces"
+	"go-image-board/logging"
+	"strconv"
+	"time"
+)
+
+//Image operations
+
+//NewImage adds an image with the provided information
+func (DBConnection *MemoryPlugin) NewImage(ImageName string, ImageFileName string, OwnerID uint64, Source string, Pending bool) (uint64, error) {
+	DBConnection.dbMutex.Lock()
+	defer DBConnection.dbMutex.Unlock()
+	if DBConnection.getImageByLocation(ImageFileName) != nil {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/NewImage", strconv.FormatUint(OwnerID, 10), logging.ResultFailure, []string{"Failed to add image", "duplicate location " + ImageFileName})
+		return 0, errors.New("an image with that location already exists")
+	}
+	ID := DBConnection.nextID("Images")
+	DBConnection.images[ID] = &memoryImage{ID: ID, Name: ImageName, Location: ImageFileName, UploaderID: OwnerID, Source: Source, Rating: "unrated", UploadTime: time.Now(), Pending: Pending}
+	logging.WriteLog(logging.LogLevelError, "MemoryPlugin/NewImage", strconv.FormatUint(OwnerID, 10), logging.ResultSuccess, []string{"Image added"})
+	return ID, nil
+}
+
+//GetAllImages returns the ID, Name, Location and MIMEType of up to Count images with an ID above AfterID, lowest first. Unlike SearchImages, images in the trash or waiting for approval are included
+func (DBConnection *MemoryPlugin) GetAllImages(AfterID uint64, Count uint64) ([]interfaces.ImageInformation, error) {
+	DBConnection.dbMutex.RLock()
+	defer DBConnection.dbMutex.RUnlock()
+	var ToReturn []interfaces.ImageInformation
+	for _, ID := range DBConnection.sortedImageIDs() {
+		if ID > AfterID && uint64(len(ToReturn)) < Count {
+			ToReturn = append(ToReturn, DBConnection.images[ID].searchResult())
+		}
+	}
+	return ToReturn, nil
+}
+
+//DeleteImage removes an image from the db
+func (DBConnection *MemoryPlugin) DeleteImage(ImageID uint64) error {
+	DBConnection.dbMutex.Lock()
+	defer DBConnection.dbMutex.Unlock()
+	//First, remove image from any associated collections
+	for _, collectionID := range DBConnection.getCollectionIDsWithImage(ImageID) {
+		if err := DBConnection.removeCollectionMember(collectionID, ImageID); err != nil {
+			logging.WriteLog(logging.LogLevelWarning, "MemoryPlugin/DeleteImage", "0", logging.ResultFailure, []string{"Failed to remove image from collection", err.Error(), strconv.FormatUint(ImageID, 10)})
+		}
+	}
+
+	//Then the ImageTags, scores, and hashes, as the onImageDelete trigger would, and its favorites, comments, notes and the records of its previous files and tag changes
+	for key := range DBConnection.imageTags {
+		if key.ImageID == ImageID {
+			DBConnection.deleteImageTag(key)
+		}
+	}
+	for key := range DBConnection.imageUserScores {
+		if key.ImageID == ImageID {
+			delete(DBConnection.imageUserScores, key)
+		}
+	}
+	for key := range DBConnection.imageFavorites {
+		if key.ImageID == ImageID {
+			delete(DBConnection.imageFavorites, key)
+		}
+	}
+	delete(DBConnection.imagedHashes, ImageID)
+	for ID, revision := range DBConnection.imageRevisions {
+		if revision.ImageID == ImageID {
+			delete(DBConnection.imageRevisions, ID)
+		}
+	}
+	for ID, change := range DBConnection.tagHistory {
+		if change.ImageID == ImageID {
+			delete(DBConnection.tagHistory, ID)
+		}
+	}
+	DBConnection.deleteComments(ImageID, 0)
+	DBConnection.deleteNotes(ImageID)
+	delete(DBConnection.images, ImageID)
+	logging.WriteLog(logging.LogLevelError, "MemoryPlugin/DeleteImage", "0", logging.ResultSuccess, []string{"Image deleted", strconv.FormatUint(ImageID, 10)})
+	return nil
+}
+
+//UpdateImage updates properties of an image
+func (DBConnection *MemoryPlugin) UpdateImage(ImageID uint64, ImageName interface{}, ImageDescription interface{}, OwnerID interface{}, Rating interface{}, Source interface{}, Location interface{}) error {
+	if _, correctValue := OwnerID.(uint64); OwnerID != nil && correctValue == false {
+		return errors.New("OwnerID, when provided, must be of uint64 type")
+	}
+
+	DBConnection.dbMutex.Lock()
+	defer DBConnection.dbMutex.Unlock()
+	//See if image exists
+	image, exists := DBConnection.images[ImageID]
+	if exists == false {
+		return sql.ErrNoRows
+	}
+
+	if Location != nil {
+		newLocation := fmt.Sprintf("%v", Location)
+		if other := DBConnection.getImageByLocation(newLocation); other != nil && other.ID != ImageID {
+			return errors.New("an image with that location already exists")
+		}
+		image.Location = newLocation
+	}
+	if ImageName != nil {
+		image.Name = fmt.Sprintf("%v", ImageName)
+	}
+	if ImageDescription != nil {
+		image.Description = fmt.Sprintf("%v", ImageDescription)
+	}
+	if unwrappedOwnerID, correctValue := OwnerID.(uint64); OwnerID != nil && correctValue {
+		image.UploaderID = unwrappedOwnerID
+	}
+	if Rating != nil {
+		image.Rating = fmt.Sprintf("%v", Rating)
+	}
+	if Source != nil {
+		image.Source = fmt.Sprintf("%v", Source)
+	}
+	return nil
+}
+
+//GetImage returns information on a single image (Returns an ImageInformation, or error)
+func (DBConnection *MemoryPlugin) GetImage(ID uint64) (interfaces.ImageInformation, error) {
+	DBConnection.dbMutex.RLock()
+	defer DBConnection.dbMutex.RUnlock()
+	image, exists := DBConnection.images[ID]
+	if exists == false {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/ImageFunctions/GetImage", "0", logging.ResultFailure, []string{"Failed to get image info from database", sql.ErrNoRows.Error()})
+		return interfaces.ImageInformation{ID: ID}, sql.ErrNoRows
+	}
+	return DBConnection.getImageInformation(image), nil
+}
+
+//GetImageByFileName returns an ImageInformation object given a ImageName
+func (DBConnection *MemoryPlugin) GetImageByFileName(imageName string) (interfaces.ImageInformation, error) {
+	DBConnection.dbMutex.RLock()
+	defer DBConnection.dbMutex.RUnlock()
+	image := DBConnection.getImageByLocation(imageName)
+	if image == nil {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/ImageFunctions/GetImageByFileName", "0", logging.ResultFailure, []string{"Failed to get image info from database", sql.ErrNoRows.Error()})
+		return interfaces.ImageInformation{Location: imageName}, sql.ErrNoRows
+	}
+	return DBConnection.getImageInformation(image), nil
+}
+
+//getImageInformation fills out the full ImageInformation for an image, including the uploader's name
+func (DBConnection *MemoryPlugin) getImageInformation(image *memoryImage) interfaces.ImageInformation {
+	ToReturn := interfaces.ImageInformation{
+		ID:           image.ID,
+		Name:         image.Name,
+		Description:  image.Description,
+		Location:     image.Location,
+		UploaderID:   image.UploaderID,
+		UploadTime:   image.UploadTime,
+		Rating:       image.Rating,
+		ScoreAverage: image.ScoreAverage,
+		ScoreTotal:   image.ScoreTotal,
+		ScoreVoters:  image.ScoreVoters,
+		Source:       image.Source,
+		InTrash:      image.DeletedTime.IsZero() == false,
+		DeletedTime:  image.DeletedTime,
+		DeleterID:    image.DeleterID,
+		Pending:      image.Pending,
+		Width:        image.Metadata.Width,
+		Height:       image.Metadata.Height,
+		FileSize:     image.Metadata.FileSize,
+		MIMEType:     image.Metadata.MIMEType,
+		Duration:     image.Metadata.Duration}
+	for key := range DBConnection.imageFavorites {
+		if key.ImageID == image.ID {
+			ToReturn.FavoriteCount++
+		}
+	}
+	if uploader, exists := DBConnection.users[image.UploaderID]; exists {
+		ToReturn.UploaderName = uploader.Name
+	}
+	if deleter, exists := DBConnection.users[image.DeleterID]; exists && ToReturn.InTrash {
+		ToReturn.DeleterName = deleter.Name
+	}
+	return ToReturn
+}
+
+//getImageByLocation returns the image stored at the given location, or nil
+func (DBConnection *MemoryPlugin) getImageByLocation(Location string) *memoryImage {
+	for _, image := range DBConnection.images {
+		if image.Location == Location {
+			return image
+		}
+	}
+	return nil
+}
+
+//SetImageRating changes a given image's rating in the database
+func (DBConnection *MemoryPlugin) SetImageRating(ID uint64, Rating string) error {
+	DBConnection.dbMutex.Lock()
+	defer DBConnection.dbMutex.Unlock()
+	if image, exists := DBConnection.images[ID]; exists {
+		image.Rating = Rating
+	}
+	return nil
+}
+
+//SetImageSource changes a given image's source in the database
+func (DBConnection *MemoryPlugin) SetImageSource(ID uint64, Source string) error {
+	DBConnection.dbMutex.Lock()
+	defer DBConnection.dbMutex.Unlock()
+	if image, exists := DBConnection.images[ID]; exists {
+		image.Source = Source
+	}
+	return nil
+}
+
+//SetImageMetadata changes a given image's dimensions, file size, MIME type and duration in the database
+func (DBConnection *MemoryPlugin) SetImageMetadata(ID uint64, Metadata interfaces.ImageMetadata) error {
+	DBConnection.dbMutex.Lock()
+	defer DBConnection.dbMutex.Unlock()
+	if image, exists := DBConnection.images[ID]; exists {
+		image.Metadata = Metadata
+	}
+	return nil
+}
+
+//SetImagedHash changes a given image's dHash in the database
+func (DBConnection *MemoryPlugin) SetImagedHash(ID uint64, hHash uint64, vHash uint64) error {
+	DBConnection.dbMutex.Lock()
+	defer DBConnection.dbMutex.Unlock()
+	if _, exists := DBConnection.images[ID]; exists == false {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/ImageFunctions/SetImagedHash", "0", logging.ResultFailure, []string{"Failed to set image dHashes", "image does not exist"})
+		return errors.New("image does not exist")
+	}
+	hashes, exists := DBConnection.imagedHashes[ID]
+	if exists == false {
+		hashes.ID = DBConnection.nextID("ImagedHashes")
+	}
+	hashes.hHash = hHash
+	hashes.vHash = vHash
+	DBConnection.imagedHashes[ID] = hashes
+	return nil
+}
+
+//GetImagedHash changes a given image's dHash in the database
+func (DBConnection *MemoryPlugin) GetImagedHash(ID uint64) (uint64, uint64, error) {
+	DBConnection.dbMutex.RLock()
+	defer DBConnection.dbMutex.RUnlock()
+	return DBConnection.getImagedHash(ID)
+}
+
+//getImagedHash is GetImagedHash for callers already holding the lock
+func (DBConnection *MemoryPlugin) getImagedHash(ID uint64) (uint64, uint64, error) {
+	hashes, exists := DBConnection.imagedHashes[ID]
+	if exists == false {
+		return 0, 0, sql.ErrNoRows
+	}
+	return hashes.hHash, hashes.vHash, nil
+}
@@ -0,0 +1,122 @@
+package memoryplugin
+
+import (
+	"database/sql"
+	"errors"
+	"go-image-board/interfaces"
+	"go-image-board/logging"
+	"sort"
+	"strconv"
+	"time"
+)
+
+//ReplaceImageFile points an image at a new file, keeping its previous Location as a revision
+func (DBConnection *MemoryPlugin) ReplaceImageFile(ImageID uint64, Location string, ReplacerID uint64) error {
+	DBConnection.dbMutex.Lock()
+	defer DBConnection.dbMutex.Unlock()
+	if err := DBConnection.replaceImageFile(ImageID, Location, ReplacerID); err != nil {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/ReplaceImageFile", strconv.FormatUint(ReplacerID, 10), logging.ResultFailure, []string{"Failed to replace image file", strconv.FormatUint(ImageID, 10), Location, err.Error()})
+		return err
+	}
+	logging.WriteLog(logging.LogLevelInfo, "MemoryPlugin/ReplaceImageFile", strconv.FormatUint(ReplacerID, 10), logging.ResultSuccess, []string{"Image file replaced", strconv.FormatUint(ImageID, 10), Location})
+	return nil
+}
+
+//replaceImageFile moves an image's current Location in to a new revision, then sets the new one
+func (DBConnection *MemoryPlugin) replaceImageFile(ImageID uint64, Location string, ReplacerID uint64) error {
+	image, exists := DBConnection.images[ImageID]
+	if exists == false {
+		return sql.ErrNoRows
+	}
+	if DBConnection.getImageByLocation(Location) != nil {
+		return errors.New("an image with that location already exists")
+	}
+	ID := DBConnection.nextID("ImageRevisions")
+	DBConnection.imageRevisions[ID] = &memoryImageRevision{ID: ID, ImageID: ImageID, Location: image.Location, ReplacerID: ReplacerID, ReplacedTime: time.Now()}
+	image.Location = Location
+	return nil
+}
+
+//GetImageRevisions returns the previous files of an image, newest first
+func (DBConnection *MemoryPlugin) GetImageRevisions(ImageID uint64) ([]interfaces.ImageRevisionInformation, error) {
+	DBConnection.dbMutex.RLock()
+	defer DBConnection.dbMutex.RUnlock()
+	var ToReturn []interfaces.ImageRevisionInformation
+	for _, revision := range DBConnection.imageRevisions {
+		if revision.ImageID == ImageID {
+			ToReturn = append(ToReturn, DBConnection.getImageRevisionInformation(revision))
+		}
+	}
+	sort.Slice(ToReturn, func(i, j int) bool {
+		if ToReturn[i].ReplacedTime.Equal(ToReturn[j].ReplacedTime) == false {
+			return ToReturn[i].ReplacedTime.After(ToReturn[j].ReplacedTime)
+		}
+		return ToReturn[i].ID > ToReturn[j].ID
+	})
+	return ToReturn, nil
+}
+
+//GetImageRevision returns a single previous file of an image
+func (DBConnection *MemoryPlugin) GetImageRevision(RevisionID uint64) (interfaces.ImageRevisionInformation, error) {
+	DBConnection.dbMutex.RLock()
+	defer DBConnection.dbMutex.RUnlock()
+	revision, exists := DBConnection.imageRevisions[RevisionID]
+	if exists == false {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/GetImageRevision", "0", logging.ResultFailure, []string{"Failed to get image revision", strconv.FormatUint(RevisionID, 10)})
+		return interfaces.ImageRevisionInformation{}, sql.ErrNoRows
+	}
+	return DBConnection.getImageRevisionInformation(revision), nil
+}
+
+//UpdateImageRevisionLocation moves a previous file of an image to Location
+func (DBConnection *MemoryPlugin) UpdateImageRevisionLocation(RevisionID uint64, Location string) error {
+	DBConnection.dbMutex.Lock()
+	defer DBConnection.dbMutex.Unlock()
+	revision, exists := DBConnection.imageRevisions[RevisionID]
+	if exists == false {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/UpdateImageRevisionLocation", "0", logging.ResultFailure, []string{"Failed to move image revision", strconv.FormatUint(RevisionID, 10), Location})
+		return sql.ErrNoRows
+	}
+	revision.Location = Location
+	return nil
+}
+
+//GetImageRevisionByLocation returns the previous file of an image stored at Location
+func (DBConnection *MemoryPlugin) GetImageRevisionByLocation(Location string) (interfaces.ImageRevisionInformation, error) {
+	DBConnection.dbMutex.RLock()
+	defer DBConnection.dbMutex.RUnlock()
+	for _, revision := range DBConnection.imageRevisions {
+		if revision.Location == Location {
+			return DBConnection.getImageRevisionInformation(revision), nil
+		}
+	}
+	logging.WriteLog(logging.LogLevelError, "MemoryPlugin/GetImageRevisionByLocation", "0", logging.ResultFailure, []string{"Failed to get image revision", Location})
+	return interfaces.ImageRevisionInformation{}, sql.ErrNoRows
+}
+
+//RestoreImageRevision makes a previous file current again, the file it replaces becomes a revision in its place
+func (DBConnection *MemoryPlugin) RestoreImageRevision(RevisionID uint64, RestorerID uint64) error {
+	DBConnection.dbMutex.Lock()
+	defer DBConnection.dbMutex.Unlock()
+	revision, exists := DBConnection.imageRevisions[RevisionID]
+	if exists == false {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/RestoreImageRevision", strconv.FormatUint(RestorerID, 10), logging.ResultFailure, []string{"Failed to get image revision", strconv.FormatUint(RevisionID, 10)})
+		return sql.ErrNoRows
+	}
+	if err := DBConnection.replaceImageFile(revision.ImageID, revision.Location, RestorerID); err != nil {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/RestoreImageRevision", strconv.FormatUint(RestorerID, 10), logging.ResultFailure, []string{"Failed to restore image revision", strconv.FormatUint(RevisionID, 10), err.Error()})
+		return err
+	}
+	delete(DBConnection.imageRevisions, RevisionID)
+	logging.WriteLog(logging.LogLevelInfo, "MemoryPlugin/RestoreImageRevision", strconv.FormatUint(RestorerID, 10), logging.ResultSuccess, []string{"Image revision restored", strconv.FormatUint(RevisionID, 10)})
+	return nil
+}
+
+//getImageRevisionInformation converts a stored revision, including the replacer's name
+func (DBConnection *MemoryPlugin) getImageRevisionInformation(revision *memoryImageRevision) interfaces.ImageRevisionInformation {
+	ToReturn := interfaces.ImageRevisionInformation{ID: revision.ID, ImageID: revision.ImageID, Location: revision.Location, ReplacerID: revision.ReplacerID, ReplacedTime: revision.ReplacedTime}
+	if replacer, exists := DBConnection.users[revision.ReplacerID]; exists {
+		ToReturn.ReplacerName = replacer.Name
+	}
+	return ToReturn
+}
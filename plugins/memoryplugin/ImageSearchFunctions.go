@@ -0,0 +1,493 @@
+package memoryplugin
+
+import (
+	"errors"
+	"go-image-board/interfaces"
+	"go-image-board/plugins/tagquery"
+	"math/bits"
+	"math/rand"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//SearchImages performs a search for images (Returns a list of ImageInformations a result count and an error/nil)
+//If you edit this function, consider SearchCollections and GetPrevNexImages for a similar change
+func (DBConnection *MemoryPlugin) SearchImages(Tags []interfaces.TagInformation, PageStart uint64, PageStride uint64) ([]interfaces.ImageInformation, uint64, error) {
+	DBConnection.dbMutex.RLock()
+	defer DBConnection.dbMutex.RUnlock()
+	var ToReturn []interfaces.ImageInformation
+	imageIDs, _, err := DBConnection.searchImageIDs(Tags)
+	if err != nil {
+		return ToReturn, 0, err
+	}
+	start, end := pageBounds(len(imageIDs), PageStart, PageStride)
+	for _, ID := range imageIDs[start:end] {
+		ToReturn = append(ToReturn, DBConnection.images[ID].searchResult())
+	}
+	return ToReturn, uint64(len(imageIDs)), nil
+}
+
+//GetPrevNexImages performs a search for images (Returns a list of ImageInformations (Up to 2) and an error/nil)
+func (DBConnection *MemoryPlugin) GetPrevNexImages(Tags []interfaces.TagInformation, TargetID uint64) ([]interfaces.ImageInformation, error) {
+	if TargetID == 0 {
+		return nil, errors.New("invalid targetid")
+	}
+
+	DBConnection.dbMutex.RLock()
+	defer DBConnection.dbMutex.RUnlock()
+	var ToReturn []interfaces.ImageInformation
+	imageIDs, Order, err := DBConnection.searchImageIDs(Tags)
+	if err != nil {
+		return ToReturn, err
+	}
+
+	//Next is the closest image before the target in the search order, previous the closest after it
+	less := imageOrderLess(Order, DBConnection.imageOrderValues(Order, append([]uint64{TargetID}, imageIDs...)))
+	for index := len(imageIDs) - 1; index >= 0; index-- {
+		if less(imageIDs[index], TargetID) {
+			ToReturn = append(ToReturn, DBConnection.images[imageIDs[index]].searchResult())
+			break
+		}
+	}
+	for _, ID := range imageIDs {
+		if less(TargetID, ID) {
+			ToReturn = append(ToReturn, DBConnection.images[ID].searchResult())
+			break
+		}
+	}
+	return ToReturn, nil
+}
+
+//GetRandomImage returns a random image (Returns a ImageInformation and an error/nil)
+func (DBConnection *MemoryPlugin) GetRandomImage(Tags []interfaces.TagInformation) (interfaces.ImageInformation, uint64, error) {
+	DBConnection.dbMutex.RLock()
+	defer DBConnection.dbMutex.RUnlock()
+	imageIDs, _, err := DBConnection.searchImageIDs(Tags)
+	if err != nil {
+		return interfaces.ImageInformation{}, 0, err
+	}
+	resultCount := uint64(len(imageIDs))
+	if resultCount <= 0 {
+		return interfaces.ImageInformation{}, 0, errors.New("no images found with provided tags")
+	}
+	rando := rand.Float64()
+	randoID := uint64(rando * float64(resultCount))
+	return DBConnection.images[imageIDs[randoID]].searchResult(), resultCount, nil
+}
+
+//searchImageIDs returns the IDs of all images matching the provided tags, sorted by the order metatag or newest first, and the order used
+func (DBConnection *MemoryPlugin) searchImageIDs(Tags []interfaces.TagInformation) ([]uint64, interfaces.ImageOrder, error) {
+	//Cleanup input for use in code below
+	//Specifically we separate the include, the exclude and metatags into their own lists
+	var IncludeTags []uint64
+	var ExcludeTags []uint64
+	var MetaTags []interfaces.TagInformation
+	var Order interfaces.ImageOrder
+	for _, tag := range Tags {
+		if tag.Exists && tag.IsAlias == false && tag.IsMeta == false {
+			if tag.Exclude {
+				ExcludeTags = append(ExcludeTags, tag.ID)
+			} else {
+				IncludeTags = append(IncludeTags, tag.ID)
+			}
+		} else if orderValue, isOrder := tag.MetaValue.(interfaces.ImageOrder); tag.Exists && isOrder {
+			//Only the first order is used, so a user's query wins over their filter
+			if Order.Key == "" {
+				Order = orderValue
+			}
+		} else if tag.Exists && tag.IsMeta {
+			MetaTags = append(MetaTags, tag)
+		}
+	}
+
+	//Resolve comparators up front, so a bad tag fails the whole search as it would in SQL
+	comparators := make([]string, len(MetaTags))
+	for index, tag := range MetaTags {
+		if tag.IsGroup {
+			continue //Groups resolve the comparators of their members as they are checked
+		}
+		comparator := tag.Comparator
+		if tag.Exclude {
+			comparator = getInvertedComparator(comparator)
+		}
+		if comparator == "" {
+			return nil, Order, errors.New("Failed to invert query to negate on " + tag.Name)
+		}
+		comparators[index] = comparator
+	}
+
+	var ToReturn []uint64
+	for _, ID := range DBConnection.sortedImageIDs() {
+		//Images in the trash or waiting for approval are never found
+		if DBConnection.images[ID].DeletedTime.IsZero() == false || DBConnection.images[ID].Pending {
+			continue
+		}
+		//An image must have every include tag, counted the same way as the MatchingTags count in SQL
+		if len(IncludeTags) > 0 {
+			var MatchingTags int
+			for _, TagID := range uint64SetOf(IncludeTags) {
+				if _, exists := DBConnection.imageTags[imageTagKey{ImageID: ID, TagID: TagID}]; exists {
+					MatchingTags++
+				}
+			}
+			if MatchingTags != len(IncludeTags) {
+				continue
+			}
+		}
+		excluded := false
+		for _, TagID := range ExcludeTags {
+			if _, exists := DBConnection.imageTags[imageTagKey{ImageID: ID, TagID: TagID}]; exists {
+				excluded = true
+				break
+			}
+		}
+		if excluded {
+			continue
+		}
+		matches := true
+		for index, tag := range MetaTags {
+			var match bool
+			var err error
+			if tag.IsGroup {
+				match, err = DBConnection.imageMatchesTagGroup(DBConnection.images[ID], tag)
+			} else {
+				match, err = DBConnection.imageMatchesMetaTag(DBConnection.images[ID], tag, comparators[index])
+			}
+			if err != nil {
+				return nil, Order, err
+			}
+			if match == false {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			ToReturn = append(ToReturn, ID)
+		}
+	}
+	less := imageOrderLess(Order, DBConnection.imageOrderValues(Order, ToReturn))
+	sort.Slice(ToReturn, func(i, j int) bool { return less(ToReturn[i], ToReturn[j]) })
+	return ToReturn, Order, nil
+}
+
+//imageOrderValues returns the value each image is sorted by in Order, the same way the SQL plugins compute it
+func (DBConnection *MemoryPlugin) imageOrderValues(Order interfaces.ImageOrder, IDs []uint64) map[uint64]int64 {
+	tagCounts := make(map[uint64]int64)
+	if Order.Key == interfaces.ImageOrderTagCount {
+		for key := range DBConnection.imageTags {
+			tagCounts[key.ImageID]++
+		}
+	}
+	ToReturn := make(map[uint64]int64)
+	for _, ID := range IDs {
+		image, exists := DBConnection.images[ID]
+		if exists == false {
+			image = &memoryImage{ID: ID}
+		}
+		switch Order.Key {
+		case interfaces.ImageOrderScore:
+			ToReturn[ID] = image.ScoreAverage
+		case interfaces.ImageOrderTotalScore:
+			ToReturn[ID] = image.ScoreTotal
+		case interfaces.ImageOrderScoreVoters:
+			ToReturn[ID] = image.ScoreVoters
+		case interfaces.ImageOrderUploaded:
+			ToReturn[ID] = image.UploadTime.UnixNano()
+		case interfaces.ImageOrderTagCount:
+			ToReturn[ID] = tagCounts[ID]
+		case interfaces.ImageOrderRandom:
+			ToReturn[ID] = int64(tagquery.RandomOrderValue(ID, Order.Seed))
+		default:
+			ToReturn[ID] = int64(ID)
+		}
+	}
+	return ToReturn
+}
+
+//imageOrderLess returns a function reporting if image A comes before image B in Order, ties are broken by ID in the same direction
+func imageOrderLess(Order interfaces.ImageOrder, Values map[uint64]int64) func(A uint64, B uint64) bool {
+	return func(A uint64, B uint64) bool {
+		if Values[A] != Values[B] {
+			return (Values[A] < Values[B]) == Order.Ascending
+		}
+		return A != B && (A < B) == Order.Ascending
+	}
+}
+
+//imageMatchesMetaTag checks a single metatag, with its already inverted comparator, against an image
+func (DBConnection *MemoryPlugin) imageMatchesMetaTag(image *memoryImage, tag interfaces.TagInformation, comparator string) (bool, error) {
+	//Handle Complex Tags Here
+	switch tag.Name {
+	case "InCollection": //Special Exception for InCollection
+		tagBoolValue, isTagValued := tag.MetaValue.(bool)
+		if isTagValued == false {
+			return false, errors.New("Failed get value of " + tag.Name)
+		}
+		inCollection := len(DBConnection.getCollectionIDsWithImage(image.ID)) > 0
+		if (comparator == "=" && tagBoolValue == true) || (comparator == "!=" && tagBoolValue == false) {
+			return inCollection, nil
+		}
+		return inCollection == false, nil
+	case "HasNotes": //Special Exception for HasNotes
+		tagBoolValue, isTagValued := tag.MetaValue.(bool)
+		if isTagValued == false {
+			return false, errors.New("Failed get value of " + tag.Name)
+		}
+		hasNotes := DBConnection.hasNotes(image.ID)
+		if (comparator == "=" && tagBoolValue == true) || (comparator == "!=" && tagBoolValue == false) {
+			return hasNotes, nil
+		}
+		return hasNotes == false, nil
+	case "TagCount": //Special Exception for TagCount
+		tagStringValue, isTagValued := tag.MetaValue.(string)
+		if isTagValued == false {
+			return false, errors.New("Failed get value of " + tag.Name)
+		}
+		tagCountValue, err := strconv.ParseInt(tagStringValue, 10, 64)
+		if err != nil {
+			return false, err
+		}
+		//Images without any tags never show up in the grouped count
+		tagCount := int64(len(DBConnection.getImageTagRows(image.ID)))
+		if tagCount == 0 {
+			return false, nil
+		}
+		return compareValues(tagCount, comparator, tagCountValue)
+	case "CategoryTags": //Special Exception for CategoryTags
+		tagCategoryValue, isTagValued := tag.MetaValue.(interfaces.TagCategoryCount)
+		if isTagValued == false {
+			return false, errors.New("Failed get value of " + tag.Name)
+		}
+		var categoryCount int64
+		for _, row := range DBConnection.getImageTagRows(image.ID) {
+			if categoryTag, exists := DBConnection.tags[row.TagID]; exists && categoryTag.Category == tagCategoryValue.Category {
+				categoryCount++
+			}
+		}
+		return compareValues(categoryCount, comparator, tagCategoryValue.Count)
+	case "Similar": //Special Exception for Similar
+		tagImagedHashValue, isTagValued := tag.MetaValue.(interfaces.ImagedHash)
+		if isTagValued == false {
+			return false, errors.New("Failed get value of " + tag.Name)
+		}
+		hashes, exists := DBConnection.imagedHashes[image.ID]
+		if exists == false {
+			return false, nil
+		}
+		distance := uint64(bits.OnesCount64(hashes.hHash^tagImagedHashValue.ImagehHash) + bits.OnesCount64(hashes.vHash^tagImagedHashValue.ImagevHash))
+		return compareValues(distance, comparator, tagImagedHashValue.SimilarityThreshold)
+	case "Order":
+		return false, errors.New("order can not be used inside a group")
+	case "Uploaded": //Special Exception for dates
+		tagTimeValue, isTagValued := tag.MetaValue.(interfaces.TimeRange)
+		if isTagValued == false {
+			return false, errors.New("Failed get value of " + tag.Name)
+		}
+		return timeRangeMatches(image.UploadTime, comparator, tagTimeValue)
+	case "Tagged":
+		var Times []time.Time
+		for _, row := range DBConnection.getImageTagRows(image.ID) {
+			Times = append(Times, row.LinkTime)
+		}
+		return anyTimeMatches(Times, tag)
+	case "Voted":
+		var Times []time.Time
+		for key, score := range DBConnection.imageUserScores {
+			if key.ImageID == image.ID {
+				Times = append(Times, score.CreationTime)
+			}
+		}
+		return anyTimeMatches(Times, tag)
+	case "Favorite": //Special Exception for Favorite
+		tagUserValue, isTagValued := tag.MetaValue.(uint64)
+		if isTagValued == false {
+			return false, errors.New("Failed get value of " + tag.Name)
+		}
+		_, isFavorite := DBConnection.imageFavorites[imageUserScoreKey{UserID: tagUserValue, ImageID: image.ID}]
+		if comparator == "=" {
+			return isFavorite, nil
+		}
+		return isFavorite == false, nil
+	case "FavoriteCount": //Special Exception for FavoriteCount
+		var favoriteCount int64
+		for key := range DBConnection.imageFavorites {
+			if key.ImageID == image.ID {
+				favoriteCount++
+			}
+		}
+		return compareValues(favoriteCount, comparator, tag.MetaValue)
+	case "CommentCount": //Special Exception for CommentCount
+		return compareValues(DBConnection.countComments(image.ID, 0), comparator, tag.MetaValue)
+	case "Ratio": //Special Exception for Ratio
+		tagRatioValue, isTagValued := tag.MetaValue.(float64)
+		if isTagValued == false {
+			return false, errors.New("Failed get value of " + tag.Name)
+		}
+		return ratioMatches(image.Metadata, comparator, tagRatioValue)
+	case "UploaderID":
+		return compareValues(image.UploaderID, comparator, tag.MetaValue)
+	case "Name":
+		return compareValues(image.Name, comparator, tag.MetaValue)
+	case "Rating":
+		return compareValues(image.Rating, comparator, tag.MetaValue)
+	case "ScoreAverage":
+		return compareValues(image.ScoreAverage, comparator, tag.MetaValue)
+	case "ScoreTotal":
+		return compareValues(image.ScoreTotal, comparator, tag.MetaValue)
+	case "ScoreVoters":
+		return compareValues(image.ScoreVoters, comparator, tag.MetaValue)
+	case "Location":
+		return compareValues(image.Location, comparator, tag.MetaValue)
+	case "Width":
+		return compareValues(image.Metadata.Width, comparator, tag.MetaValue)
+	case "Height":
+		return compareValues(image.Metadata.Height, comparator, tag.MetaValue)
+	case "FileSize":
+		return compareValues(image.Metadata.FileSize, comparator, tag.MetaValue)
+	case "MIMEType":
+		return compareValues(image.Metadata.MIMEType, comparator, tag.MetaValue)
+	case "Duration":
+		return compareValues(image.Metadata.Duration, comparator, tag.MetaValue)
+	}
+	return false, errors.New("Unknown column " + tag.Name)
+}
+
+//imageMatchesTagGroup checks a group of tags, and any groups within it, against an image
+func (DBConnection *MemoryPlugin) imageMatchesTagGroup(image *memoryImage, Group interfaces.TagInformation) (bool, error) {
+	for _, tag := range Group.GroupTags {
+		var match bool
+		var err error
+		if tag.IsGroup {
+			match, err = DBConnection.imageMatchesTagGroup(image, tag)
+		} else if tag.IsMeta {
+			comparator := tag.Comparator
+			if tag.Exclude {
+				comparator = getInvertedComparator(comparator)
+			}
+			if comparator == "" {
+				return false, errors.New("Failed to invert query to negate on " + tag.Name)
+			}
+			match, err = DBConnection.imageMatchesMetaTag(image, tag, comparator)
+		} else {
+			_, exists := DBConnection.imageTags[imageTagKey{ImageID: image.ID, TagID: tag.ID}]
+			match = exists != tag.Exclude
+		}
+		if err != nil {
+			return false, err
+		}
+		//An OR group is decided by the first member that matches, any other group by the first that does not
+		if match == Group.MatchAny {
+			return match != Group.Exclude, nil
+		}
+	}
+	return Group.MatchAny == Group.Exclude, nil
+}
+
+//uint64SetOf returns the provided IDs with duplicates removed
+func uint64SetOf(IDs []uint64) []uint64 {
+	var ToReturn []uint64
+	for _, ID := range IDs {
+		if uint64SliceContains(ToReturn, ID) == false {
+			ToReturn = append(ToReturn, ID)
+		}
+	}
+	return ToReturn
+}
+
+//compareValues evaluates "column comparator value" the way the database would
+func compareValues(column interface{}, comparator string, value interface{}) (bool, error) {
+	if comparator == "LIKE" || comparator == "NOT LIKE" {
+		return likeMatch(toComparableString(column), toComparableString(value)) == (comparator == "LIKE"), nil
+	}
+
+	var result int
+	columnNumber, columnIsNumber := toComparableNumber(column)
+	valueNumber, valueIsNumber := toComparableNumber(value)
+	if columnIsNumber && valueIsNumber {
+		if columnNumber < valueNumber {
+			result = -1
+		} else if columnNumber > valueNumber {
+			result = 1
+		}
+	} else {
+		//String comparisons are case insensitive, as with the default collation
+		result = strings.Compare(strings.ToLower(toComparableString(column)), strings.ToLower(toComparableString(value)))
+	}
+
+	switch comparator {
+	case "=":
+		return result == 0, nil
+	case "!=":
+		return result != 0, nil
+	case ">":
+		return result > 0, nil
+	case "<":
+		return result < 0, nil
+	case ">=":
+		return result >= 0, nil
+	case "<=":
+		return result <= 0, nil
+	}
+	return false, errors.New("Unknown comparator " + comparator)
+}
+
+//toComparableNumber converts numeric values, or strings holding a number, into a float64
+func toComparableNumber(value interface{}) (float64, bool) {
+	switch typedValue := value.(type) {
+	case int:
+		return float64(typedValue), true
+	case int64:
+		return float64(typedValue), true
+	case uint64:
+		return float64(typedValue), true
+	case float64:
+		return typedValue, true
+	case string:
+		parsedValue, err := strconv.ParseFloat(typedValue, 64)
+		return parsedValue, err == nil
+	}
+	return 0, false
+}
+
+//toComparableString converts a value into a string for comparison
+func toComparableString(value interface{}) string {
+	switch typedValue := value.(type) {
+	case string:
+		return typedValue
+	case int64:
+		return strconv.FormatInt(typedValue, 10)
+	case uint64:
+		return strconv.FormatUint(typedValue, 10)
+	}
+	return ""
+}
+
+//likeMatch returns whether value matches a SQL LIKE pattern, supporting %, _ and \ escapes, case insensitively
+func likeMatch(value string, pattern string) bool {
+	expression := "(?is)^"
+	escaped := false
+	for _, character := range pattern {
+		switch {
+		case escaped:
+			expression += regexp.QuoteMeta(string(character))
+			escaped = false
+		case character == '\\':
+			escaped = true
+		case character == '%':
+			expression += ".*"
+		case character == '_':
+			expression += "."
+		default:
+			expression += regexp.QuoteMeta(string(character))
+		}
+	}
+	expression += "$"
+	matcher, err := regexp.Compile(expression)
+	if err != nil {
+		return false
+	}
+	return matcher.MatchString(value)
+}
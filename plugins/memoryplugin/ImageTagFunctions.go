@@ -0,0 +1,272 @@
+package memoryplugin
+
+import (
+	"errors"
+	"go-image-board/interfaces"
+	"go-image-board/logging"
+	"go-image-board/plugins/tagquery"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+)
+
+//GetImageTags returns a list of TagInformation for all tags that apply to the given image
+func (DBConnection *MemoryPlugin) GetImageTags(ImageID uint64) ([]interfaces.TagInformation, error) {
+	DBConnection.dbMutex.RLock()
+	defer DBConnection.dbMutex.RUnlock()
+	var ToReturn []interfaces.TagInformation
+	for _, imageTag := range DBConnection.getImageTagRows(ImageID) {
+		tag := DBConnection.tags[imageTag.TagID]
+		ToReturn = append(ToReturn, interfaces.TagInformation{Name: tag.Name, ID: tag.ID, Description: tag.Description, Exists: true, Exclude: false, Category: tag.Category})
+	}
+	return ToReturn, nil
+}
+
+//getImageTagRows returns the ImageTags rows for an image, in the order they were added
+func (DBConnection *MemoryPlugin) getImageTagRows(ImageID uint64) []*memoryImageTag {
+	var ToReturn []*memoryImageTag
+	for key, imageTag := range DBConnection.imageTags {
+		if key.ImageID == ImageID {
+			ToReturn = append(ToReturn, imageTag)
+		}
+	}
+	sort.Slice(ToReturn, func(i, j int) bool { return ToReturn[i].ID < ToReturn[j].ID })
+	return ToReturn
+}
+
+//RemoveTag remove a tag association
+func (DBConnection *MemoryPlugin) RemoveTag(TagID uint64, ImageID uint64, RemoverID uint64) error {
+	DBConnection.dbMutex.Lock()
+	defer DBConnection.dbMutex.Unlock()
+	if DBConnection.deleteImageTag(imageTagKey{ImageID: ImageID, TagID: TagID}) {
+		DBConnection.recordTagChange(ImageID, TagID, RemoverID, false, interfaces.TagChangeRemove)
+	}
+	logging.WriteLog(logging.LogLevelError, "MemoryPlugin/RemoveTag", strconv.FormatUint(RemoverID, 10), logging.ResultSuccess, []string{"Tag removed", strconv.FormatUint(TagID, 10), strconv.FormatUint(ImageID, 10)})
+	return nil
+}
+
+//insertImageTag adds or updates an ImageTags row and then performs the work of the onImageTagInsert trigger, returns false if the row already existed
+func (DBConnection *MemoryPlugin) insertImageTag(TagID uint64, ImageID uint64, LinkerID uint64) bool {
+	key := imageTagKey{ImageID: ImageID, TagID: TagID}
+	if existing, exists := DBConnection.imageTags[key]; exists {
+		existing.LinkerID = LinkerID
+		return false
+	}
+	DBConnection.imageTags[key] = &memoryImageTag{ID: DBConnection.nextID("ImageTags"), ImageID: ImageID, TagID: TagID, LinkerID: LinkerID, LinkTime: time.Now()}
+	DBConnection.addMissingCollectionImageTags(ImageID)
+	return true
+}
+
+//deleteImageTag removes an ImageTags row and then performs the work of the onImageTagDelete trigger, returns false if there was no such row
+func (DBConnection *MemoryPlugin) deleteImageTag(key imageTagKey) bool {
+	if _, exists := DBConnection.imageTags[key]; exists == false {
+		return false
+	}
+	delete(DBConnection.imageTags, key)
+	for _, collectionID := range DBConnection.getCollectionIDsWithImage(key.ImageID) {
+		DBConnection.removeSurplusCollectionTags(collectionID)
+	}
+	return true
+}
+
+//tagsContainID is a helper function to check if a TagInformation slice contains a specified ID
+func tagsContainID(ID uint64, Tags []interfaces.TagInformation) bool {
+	for _, Tag := range Tags {
+		if Tag.ID == ID {
+			return true
+		}
+	}
+	return false
+}
+
+//tagsContainName is a helper function to check if a TagInformation slice contains a specified Name
+func tagsContainName(Name string, Tags []interfaces.TagInformation) bool {
+	for _, Tag := range Tags {
+		if Tag.Name == Name {
+			return true
+		}
+	}
+	return false
+}
+
+//ReplaceImageTags replaces all instances of ImageTags that have the specified tag with the new tag
+func (DBConnection *MemoryPlugin) ReplaceImageTags(OldTagID uint64, NewTagID uint64, LinkerID uint64) error {
+	DBConnection.dbMutex.Lock()
+	defer DBConnection.dbMutex.Unlock()
+	DBConnection.replaceImageTags(OldTagID, NewTagID, LinkerID)
+	return nil
+}
+
+//replaceImageTags is ReplaceImageTags for callers already holding the lock
+func (DBConnection *MemoryPlugin) replaceImageTags(OldTagID uint64, NewTagID uint64, LinkerID uint64) {
+	var imageIDs []uint64
+	for key := range DBConnection.imageTags {
+		if key.TagID == OldTagID {
+			imageIDs = append(imageIDs, key.ImageID)
+		}
+	}
+	sort.Slice(imageIDs, func(i, j int) bool { return imageIDs[i] < imageIDs[j] })
+	//Move the old tag over to the new one, unless the image already has the new tag
+	//Like the UPDATE in the other plugins, this does not fire any collection tag trigger
+	for _, imageID := range imageIDs {
+		key := imageTagKey{ImageID: imageID, TagID: OldTagID}
+		newKey := imageTagKey{ImageID: imageID, TagID: NewTagID}
+		if _, exists := DBConnection.imageTags[newKey]; exists {
+			continue
+		}
+		imageTag := DBConnection.imageTags[key]
+		delete(DBConnection.imageTags, key)
+		imageTag.TagID = NewTagID
+		imageTag.LinkerID = LinkerID
+		DBConnection.imageTags[newKey] = imageTag
+		DBConnection.recordTagChange(imageID, NewTagID, LinkerID, true, interfaces.TagChangeReplace)
+	}
+	//Remove any instances of old tag that would have lead to a duplicate
+	for _, imageID := range imageIDs {
+		DBConnection.deleteImageTag(imageTagKey{ImageID: imageID, TagID: OldTagID})
+		DBConnection.recordTagChange(imageID, OldTagID, LinkerID, false, interfaces.TagChangeReplace)
+	}
+}
+
+//BulkAddTag adds an association of a tag to image into the association table that already have another tag
+func (DBConnection *MemoryPlugin) BulkAddTag(TagID uint64, OldTagID uint64, LinkerID uint64) error {
+	DBConnection.dbMutex.Lock()
+	defer DBConnection.dbMutex.Unlock()
+	//Prevent adding alias
+	tagInfo, err := DBConnection.getTag(TagID, false)
+	oldTagInfo, err2 := DBConnection.getTag(OldTagID, false)
+	if err != nil || err2 != nil {
+		return errors.New("Failed to validate tags")
+	}
+
+	//If this is an alias, then add aliasedid instead
+	if tagInfo.IsAlias {
+		TagID = tagInfo.AliasedID
+	}
+
+	//Similiarly convert oldTag if it is an alias
+	if oldTagInfo.IsAlias {
+		OldTagID = oldTagInfo.AliasedID
+	}
+
+	var imageIDs []uint64
+	for key := range DBConnection.imageTags {
+		if key.TagID == OldTagID {
+			if _, exists := DBConnection.imageTags[imageTagKey{ImageID: key.ImageID, TagID: TagID}]; exists == false {
+				imageIDs = append(imageIDs, key.ImageID)
+			}
+		}
+	}
+	sort.Slice(imageIDs, func(i, j int) bool { return imageIDs[i] < imageIDs[j] })
+	for _, imageID := range imageIDs {
+		DBConnection.insertImageTag(TagID, imageID, LinkerID)
+		DBConnection.recordTagChange(imageID, TagID, LinkerID, true, interfaces.TagChangeBulkAdd)
+	}
+	logging.WriteLog(logging.LogLevelError, "MemoryPlugin/BulkAddTag", strconv.FormatUint(LinkerID, 10), logging.ResultSuccess, []string{"Tags added", strconv.FormatUint(OldTagID, 10), strconv.FormatUint(TagID, 10)})
+	return nil
+}
+
+//sliceContains is a helper function that returns whether a slice contains a specifc string
+func sliceContains(slice []string, item string) bool {
+	for _, sliceItem := range slice {
+		if sliceItem == item {
+			return true
+		}
+	}
+	return false
+}
+
+//uint64SliceContains is a helper function that returns whether a slice contains a specifc ID
+func uint64SliceContains(slice []uint64, item uint64) bool {
+	for _, sliceItem := range slice {
+		if sliceItem == item {
+			return true
+		}
+	}
+	return false
+}
+
+//inverts a tags comparator
+func getInvertedComparator(comparator string) string {
+	if comparator == "=" {
+		return "!="
+	}
+	if comparator == ">" {
+		return "<="
+	}
+	if comparator == "<" {
+		return ">="
+	}
+	if comparator == ">=" {
+		return "<"
+	}
+	if comparator == "<=" {
+		return ">"
+	}
+	if comparator == "LIKE" {
+		return "NOT LIKE"
+	}
+	return ""
+}
+
+//timeRangeMatches compares a time against a date metatag's range, the same way getTimeRangeCondition does in SQL
+func timeRangeMatches(Value time.Time, comparator string, Range interfaces.TimeRange) (bool, error) {
+	switch comparator {
+	case "=":
+		return Value.Before(Range.Start) == false && Value.Before(Range.End), nil
+	case "!=":
+		return Value.Before(Range.Start) || Value.Before(Range.End) == false, nil
+	case ">":
+		return Value.Before(Range.End) == false, nil
+	case ">=":
+		return Value.Before(Range.Start) == false, nil
+	case "<":
+		return Value.Before(Range.Start), nil
+	case "<=":
+		return Value.Before(Range.End), nil
+	}
+	return false, errors.New("Unsupported comparator for dates " + comparator)
+}
+
+//anyTimeMatches returns true if any of Times is in a date metatag's range. As with the SQL plugins, excluding finds items without a matching time
+func anyTimeMatches(Times []time.Time, tag interfaces.TagInformation) (bool, error) {
+	tagTimeValue, isTagValued := tag.MetaValue.(interfaces.TimeRange)
+	if isTagValued == false {
+		return false, errors.New("Failed get value of " + tag.Name)
+	}
+	for _, Time := range Times {
+		match, err := timeRangeMatches(Time, tag.Comparator, tagTimeValue)
+		if err != nil {
+			return false, err
+		}
+		if match {
+			return tag.Exclude == false, nil
+		}
+	}
+	return tag.Exclude, nil
+}
+
+//ratioMatches returns true if the width over the height of an image compares to Ratio, ratios within tagquery.RatioTolerance of it are equal
+//As with the SQL plugins, images without dimensions have no ratio, so never match
+func ratioMatches(Metadata interfaces.ImageMetadata, comparator string, Ratio float64) (bool, error) {
+	if Metadata.Height <= 0 {
+		return false, nil
+	}
+	ratio := float64(Metadata.Width) / float64(Metadata.Height)
+	switch comparator {
+	case "=":
+		return math.Abs(ratio-Ratio) < tagquery.RatioTolerance, nil
+	case "!=":
+		return math.Abs(ratio-Ratio) >= tagquery.RatioTolerance, nil
+	case ">":
+		return ratio >= Ratio+tagquery.RatioTolerance, nil
+	case ">=":
+		return ratio > Ratio-tagquery.RatioTolerance, nil
+	case "<":
+		return ratio <= Ratio-tagquery.RatioTolerance, nil
+	case "<=":
+		return ratio < Ratio+tagquery.RatioTolerance, nil
+	}
+	return false, errors.New("Unsupported comparator for ratios " + comparator)
+}
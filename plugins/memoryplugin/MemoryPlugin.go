@@ -0,0 +1,384 @@
+package memoryplugin
+
+import (
+	"go-image-board/interfaces"
+	"go-image-board/logging"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+//MemoryPlugin is a DBInterface that keeps everything in process memory. Nothing is persisted between runs.
+//It follows the same query semantics as the MariaDB plugin, which makes it useful for tests and demos where no database server is available.
+type MemoryPlugin struct {
+	//PasswordHashCost bcrypt cost used for passwords and security answers, 0 uses the same cost as the other plugins
+	PasswordHashCost int
+
+	//dbMutex guards every table below. Exported functions take the lock, lower case helpers expect the caller to hold it.
+	dbMutex           sync.RWMutex
+	autoIncrement     map[string]uint64
+	users             map[uint64]*memoryUser
+	images            map[uint64]*memoryImage
+	tags              map[uint64]*memoryTag
+	imageTags         map[imageTagKey]*memoryImageTag
+	tagHistory        map[uint64]*memoryTagChange
+	tagImplications   map[uint64]*memoryTagImplication
+	savedSearches     map[uint64]*memorySavedSearch
+	reports           map[uint64]*memoryReport
+	bans              map[uint64]*memoryBan
+	imagedHashes      map[uint64]memoryImagedHash
+	imageRevisions    map[uint64]*memoryImageRevision
+	imageUserScores   map[imageUserScoreKey]*memoryImageUserScore
+	imageFavorites    map[imageUserScoreKey]*memoryImageUserFavorite
+	comments          map[uint64]*memoryComment
+	commentRevisions  map[uint64]*memoryCommentRevision
+	notes             map[uint64]*memoryNote
+	noteRevisions     map[uint64]*memoryNoteRevision
+	auditLogs         []memoryAuditLog
+	collections       map[uint64]*memoryCollection
+	collectionMembers map[collectionMemberKey]*memoryCollectionMember
+	collectionTags    map[collectionTagKey]*memoryCollectionTag
+}
+
+//memoryUser mirrors a row of the Users table
+type memoryUser struct {
+	ID               uint64
+	Name             string
+	EMail            string
+	PasswordHash     string
+	TokenID          string
+	IP               string
+	SecQuestionsSet  bool
+	SecQuestionOne   string
+	SecQuestionTwo   string
+	SecQuestionThree string
+	SecAnswerOne     string
+	SecAnswerTwo     string
+	SecAnswerThree   string
+	CreationTime     time.Time
+	Disabled         bool
+	Permissions      uint64
+	SearchFilter     string
+}
+
+//memoryImage mirrors a row of the Images table
+type memoryImage struct {
+	ID           uint64
+	UploaderID   uint64
+	Name         string
+	Rating       string
+	ScoreTotal   int64
+	ScoreAverage int64
+	ScoreVoters  int64
+	Location     string
+	Source       string
+	UploadTime   time.Time
+	Description  string
+	//DeletedTime is the zero time unless the image is in the trash
+	DeletedTime time.Time
+	DeleterID   uint64
+	Metadata    interfaces.ImageMetadata
+	//Pending is set while the image waits in the moderation queue
+	Pending bool
+}
+
+//memoryTag mirrors a row of the Tags table
+type memoryTag struct {
+	ID          uint64
+	Name        string
+	Description string
+	UploaderID  uint64
+	UploadTime  time.Time
+	AliasedID   uint64
+	IsAlias     bool
+	Category    string
+}
+
+type imageTagKey struct {
+	ImageID uint64
+	TagID   uint64
+}
+
+//memoryImageTag mirrors a row of the ImageTags table
+type memoryImageTag struct {
+	ID       uint64
+	ImageID  uint64
+	TagID    uint64
+	LinkerID uint64
+	LinkTime time.Time
+}
+
+//memoryTagChange mirrors a row of the ImageTagHistory table
+type memoryTagChange struct {
+	ID         uint64
+	ImageID    uint64
+	TagID      uint64
+	UserID     uint64
+	Added      bool
+	Operation  string
+	ChangeTime time.Time
+}
+
+//memoryTagImplication mirrors a row of the TagImplications table
+type memoryTagImplication struct {
+	ID           uint64
+	TagID        uint64
+	ImpliedTagID uint64
+	CreatorID    uint64
+	CreationTime time.Time
+}
+
+//memorySavedSearch mirrors a row of the SavedSearches table
+type memorySavedSearch struct {
+	ID                uint64
+	UserID            uint64
+	Name              string
+	Query             string
+	CollectionContext bool
+	Pinned            bool
+	ShowNewCount      bool
+	CreationTime      time.Time
+	LastViewed        time.Time
+}
+
+//memoryImagedHash mirrors a row of the ImagedHashes table
+type memoryImagedHash struct {
+	ID    uint64
+	hHash uint64
+	vHash uint64
+}
+
+//memoryImageRevision mirrors a row of the ImageRevisions table
+type memoryImageRevision struct {
+	ID           uint64
+	ImageID      uint64
+	Location     string
+	ReplacerID   uint64
+	ReplacedTime time.Time
+}
+
+type imageUserScoreKey struct {
+	UserID  uint64
+	ImageID uint64
+}
+
+//memoryImageUserScore mirrors a row of the ImageUserScores table
+type memoryImageUserScore struct {
+	ID           uint64
+	UserID       uint64
+	ImageID      uint64
+	Score        int64
+	CreationTime time.Time
+}
+
+//memoryImageUserFavorite mirrors a row of the ImageUserFavorites table
+type memoryImageUserFavorite struct {
+	ID           uint64
+	UserID       uint64
+	ImageID      uint64
+	CreationTime time.Time
+}
+
+//memoryComment mirrors a row of the Comments table
+type memoryComment struct {
+	ID           uint64
+	ImageID      uint64
+	CollectionID uint64
+	ParentID     uint64
+	ThreadID     uint64
+	UserID       uint64
+	Body         string
+	CreationTime time.Time
+	//EditTime is the zero time unless the body was edited
+	EditTime time.Time
+	//DeletedTime is the zero time unless the comment was deleted
+	DeletedTime time.Time
+	DeleterID   uint64
+}
+
+//memoryCommentRevision mirrors a row of the CommentRevisions table
+type memoryCommentRevision struct {
+	ID        uint64
+	CommentID uint64
+	Body      string
+	EditorID  uint64
+	EditTime  time.Time
+}
+
+//memoryReport mirrors a row of the Reports table
+type memoryReport struct {
+	ID           uint64
+	TargetType   string
+	TargetID     uint64
+	ReporterID   uint64
+	Category     string
+	Reason       string
+	Status       string
+	ModeratorID  uint64
+	Outcome      string
+	CreationTime time.Time
+	//UpdateTime is the zero time until a moderator changes the status
+	UpdateTime time.Time
+}
+
+//memoryBan mirrors a row of the Bans table
+type memoryBan struct {
+	ID           uint64
+	UserID       uint64
+	IPRange      string
+	Reason       string
+	BannerID     uint64
+	CreationTime time.Time
+	//ExpiryTime is the zero time for bans that do not expire
+	ExpiryTime time.Time
+	LifterID   uint64
+	//LiftTime is the zero time unless the ban was lifted
+	LiftTime time.Time
+}
+
+//memoryNote mirrors a row of the Notes table
+type memoryNote struct {
+	ID           uint64
+	ImageID      uint64
+	CreatorID    uint64
+	Region       interfaces.NoteRegion
+	Body         string
+	CreationTime time.Time
+	//EditTime is the zero time unless the note was changed
+	EditTime time.Time
+	//DeletedTime is the zero time unless the note was deleted
+	DeletedTime time.Time
+	DeleterID   uint64
+}
+
+//memoryNoteRevision mirrors a row of the NoteRevisions table
+type memoryNoteRevision struct {
+	ID       uint64
+	NoteID   uint64
+	Region   interfaces.NoteRegion
+	Body     string
+	Deleted  bool
+	EditorID uint64
+	EditTime time.Time
+}
+
+//memoryAuditLog mirrors a row of the AuditLogs table
+type memoryAuditLog struct {
+	ID      uint64
+	UserID  uint64
+	Type    string
+	Info    string
+	LogTime time.Time
+}
+
+//memoryCollection mirrors a row of the Collections table
+type memoryCollection struct {
+	ID          uint64
+	Name        string
+	Description string
+	UploaderID  uint64
+	UploadTime  time.Time
+	//DeletedTime is the zero time unless the collection is in the trash
+	DeletedTime time.Time
+	DeleterID   uint64
+}
+
+type collectionMemberKey struct {
+	CollectionID uint64
+	ImageID      uint64
+}
+
+//memoryCollectionMember mirrors a row of the CollectionMembers table
+type memoryCollectionMember struct {
+	ID           uint64
+	ImageID      uint64
+	CollectionID uint64
+	LinkerID     uint64
+	LinkTime     time.Time
+	OrderWeight  uint64
+}
+
+type collectionTagKey struct {
+	CollectionID uint64
+	TagID        uint64
+}
+
+//memoryCollectionTag mirrors a row of the CollectionTags table
+type memoryCollectionTag struct {
+	ID           uint64
+	CollectionID uint64
+	TagID        uint64
+	LinkerID     uint64
+	LinkTime     time.Time
+}
+
+//InitDatabase creates a fresh, empty, database. Calling this again discards all existing data.
+func (DBConnection *MemoryPlugin) InitDatabase() error {
+	rand.Seed(time.Now().UnixNano())
+	DBConnection.dbMutex.Lock()
+	defer DBConnection.dbMutex.Unlock()
+
+	DBConnection.autoIncrement = make(map[string]uint64)
+	DBConnection.users = make(map[uint64]*memoryUser)
+	DBConnection.images = make(map[uint64]*memoryImage)
+	DBConnection.tags = make(map[uint64]*memoryTag)
+	DBConnection.imageTags = make(map[imageTagKey]*memoryImageTag)
+	DBConnection.tagHistory = make(map[uint64]*memoryTagChange)
+	DBConnection.tagImplications = make(map[uint64]*memoryTagImplication)
+	DBConnection.savedSearches = make(map[uint64]*memorySavedSearch)
+	DBConnection.reports = make(map[uint64]*memoryReport)
+	DBConnection.bans = make(map[uint64]*memoryBan)
+	DBConnection.imagedHashes = make(map[uint64]memoryImagedHash)
+	DBConnection.imageRevisions = make(map[uint64]*memoryImageRevision)
+	DBConnection.imageUserScores = make(map[imageUserScoreKey]*memoryImageUserScore)
+	DBConnection.imageFavorites = make(map[imageUserScoreKey]*memoryImageUserFavorite)
+	DBConnection.comments = make(map[uint64]*memoryComment)
+	DBConnection.commentRevisions = make(map[uint64]*memoryCommentRevision)
+	DBConnection.notes = make(map[uint64]*memoryNote)
+	DBConnection.noteRevisions = make(map[uint64]*memoryNoteRevision)
+	DBConnection.auditLogs = nil
+	DBConnection.collections = make(map[uint64]*memoryCollection)
+	DBConnection.collectionMembers = make(map[collectionMemberKey]*memoryCollectionMember)
+	DBConnection.collectionTags = make(map[collectionTagKey]*memoryCollectionTag)
+
+	//Reserve system for auditing
+	DBConnection.users[0] = &memoryUser{ID: 0, Name: "SYSTEM", Disabled: true, CreationTime: time.Now()}
+
+	logging.WriteLog(logging.LogLevelInfo, "MemoryPlugin/InitDatabase", "0", logging.ResultInfo, []string{"In-memory database initialized, data will not be persisted"})
+	return nil
+}
+
+//nextID returns the next auto increment value for a table
+func (DBConnection *MemoryPlugin) nextID(table string) uint64 {
+	DBConnection.autoIncrement[table]++
+	return DBConnection.autoIncrement[table]
+}
+
+//sortedImageIDs returns the ID of every image, lowest first
+func (DBConnection *MemoryPlugin) sortedImageIDs() []uint64 {
+	var IDs []uint64
+	for ID := range DBConnection.images {
+		IDs = append(IDs, ID)
+	}
+	sort.Slice(IDs, func(i, j int) bool { return IDs[i] < IDs[j] })
+	return IDs
+}
+
+//pageBounds converts a LIMIT/OFFSET pair into slice bounds for a result set of the given size
+func pageBounds(resultCount int, PageStart uint64, PageStride uint64) (int, int) {
+	start := resultCount
+	if PageStart < uint64(resultCount) {
+		start = int(PageStart)
+	}
+	end := resultCount
+	if PageStride < uint64(resultCount-start) {
+		end = start + int(PageStride)
+	}
+	return start, end
+}
+
+//searchResult converts a stored image to the format used by search results
+func (image *memoryImage) searchResult() interfaces.ImageInformation {
+	return interfaces.ImageInformation{Name: image.Name, ID: image.ID, Location: image.Location, MIMEType: image.Metadata.MIMEType}
+}
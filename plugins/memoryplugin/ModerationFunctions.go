@@ -0,0 +1,43 @@
+package memoryplugin
+
+import (
+	"errors"
+	"go-image-board/interfaces"
+	"go-image-board/logging"
+	"strconv"
+)
+
+//SetImagePending holds an image in the moderation queue, hiding it from searches, or publishes it
+func (DBConnection *MemoryPlugin) SetImagePending(ImageID uint64, Pending bool) error {
+	DBConnection.dbMutex.Lock()
+	defer DBConnection.dbMutex.Unlock()
+	image, exists := DBConnection.images[ImageID]
+	if exists == false || image.Pending == Pending {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/SetImagePending", "0", logging.ResultFailure, []string{"Failed to update image pending state", strconv.FormatUint(ImageID, 10)})
+		if Pending {
+			return errors.New("image does not exist or is already waiting for approval")
+		}
+		return errors.New("image does not exist or is not waiting for approval")
+	}
+	image.Pending = Pending
+	logging.WriteLog(logging.LogLevelInfo, "MemoryPlugin/SetImagePending", "0", logging.ResultSuccess, []string{"Image pending state updated", strconv.FormatUint(ImageID, 10), strconv.FormatBool(Pending)})
+	return nil
+}
+
+//GetPendingImages returns images waiting in the moderation queue that are not in the trash, oldest first, and the count of all of them
+func (DBConnection *MemoryPlugin) GetPendingImages(PageStart uint64, PageStride uint64) ([]interfaces.ImageInformation, uint64, error) {
+	DBConnection.dbMutex.RLock()
+	defer DBConnection.dbMutex.RUnlock()
+	var ToReturn []interfaces.ImageInformation
+	var pending []*memoryImage
+	for _, ID := range DBConnection.sortedImageIDs() {
+		if image := DBConnection.images[ID]; image.Pending && image.DeletedTime.IsZero() {
+			pending = append(pending, image)
+		}
+	}
+	start, end := pageBounds(len(pending), PageStart, PageStride)
+	for _, image := range pending[start:end] {
+		ToReturn = append(ToReturn, DBConnection.getImageInformation(image))
+	}
+	return ToReturn, uint64(len(pending)), nil
+}
@@ -0,0 +1,151 @@
+package memoryplugin
+
+import (
+	"database/sql"
+	"errors"
+	"go-image-board/interfaces"
+	"go-image-board/logging"
+	"sort"
+	"strconv"
+	"time"
+)
+
+//NewNote adds a note to Note.ImageID, created by Note.CreatorID, and keeps it as the first revision. Returns its ID
+func (DBConnection *MemoryPlugin) NewNote(Note interfaces.NoteInformation) (uint64, error) {
+	DBConnection.dbMutex.Lock()
+	defer DBConnection.dbMutex.Unlock()
+	if _, exists := DBConnection.images[Note.ImageID]; exists == false {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/NewNote", strconv.FormatUint(Note.CreatorID, 10), logging.ResultFailure, []string{"Failed to add note", "image does not exist", strconv.FormatUint(Note.ImageID, 10)})
+		return 0, errors.New("image does not exist")
+	}
+	now := time.Now()
+	ID := DBConnection.nextID("Notes")
+	DBConnection.notes[ID] = &memoryNote{ID: ID, ImageID: Note.ImageID, CreatorID: Note.CreatorID, Region: Note.NoteRegion, Body: Note.Body, CreationTime: now}
+	DBConnection.addNoteRevision(ID, Note.NoteRegion, Note.Body, false, Note.CreatorID, now)
+	logging.WriteLog(logging.LogLevelInfo, "MemoryPlugin/NewNote", strconv.FormatUint(Note.CreatorID, 10), logging.ResultSuccess, []string{"Note added", strconv.FormatUint(ID, 10)})
+	return ID, nil
+}
+
+//GetNote returns a single note, including deleted ones
+func (DBConnection *MemoryPlugin) GetNote(NoteID uint64) (interfaces.NoteInformation, error) {
+	DBConnection.dbMutex.RLock()
+	defer DBConnection.dbMutex.RUnlock()
+	note, exists := DBConnection.notes[NoteID]
+	if exists == false {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/GetNote", "0", logging.ResultFailure, []string{"Failed to get note", strconv.FormatUint(NoteID, 10)})
+		return interfaces.NoteInformation{}, sql.ErrNoRows
+	}
+	return DBConnection.getNoteInformation(note), nil
+}
+
+//GetNotes returns the notes on an image, oldest first, deleted notes are only included if requested
+func (DBConnection *MemoryPlugin) GetNotes(ImageID uint64, IncludeDeleted bool) ([]interfaces.NoteInformation, error) {
+	DBConnection.dbMutex.RLock()
+	defer DBConnection.dbMutex.RUnlock()
+	var ToReturn []interfaces.NoteInformation
+	for _, note := range DBConnection.notes {
+		if note.ImageID == ImageID && (IncludeDeleted || note.DeletedTime.IsZero()) {
+			ToReturn = append(ToReturn, DBConnection.getNoteInformation(note))
+		}
+	}
+	sort.Slice(ToReturn, func(i, j int) bool { return ToReturn[i].ID < ToReturn[j].ID })
+	return ToReturn, nil
+}
+
+//UpdateNote replaces the region and body of a note, shows it again if it was deleted, and keeps the result as a revision
+func (DBConnection *MemoryPlugin) UpdateNote(NoteID uint64, Region interfaces.NoteRegion, Body string, EditorID uint64) error {
+	DBConnection.dbMutex.Lock()
+	defer DBConnection.dbMutex.Unlock()
+	note, exists := DBConnection.notes[NoteID]
+	if exists == false {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/UpdateNote", strconv.FormatUint(EditorID, 10), logging.ResultFailure, []string{"Failed to get note", strconv.FormatUint(NoteID, 10)})
+		return sql.ErrNoRows
+	}
+	now := time.Now()
+	note.Region = Region
+	note.Body = Body
+	note.EditTime = now
+	note.DeletedTime = time.Time{}
+	note.DeleterID = 0
+	DBConnection.addNoteRevision(NoteID, Region, Body, false, EditorID, now)
+	logging.WriteLog(logging.LogLevelInfo, "MemoryPlugin/UpdateNote", strconv.FormatUint(EditorID, 10), logging.ResultSuccess, []string{"Note updated", strconv.FormatUint(NoteID, 10)})
+	return nil
+}
+
+//DeleteNote hides a note, keeping a revision that records the deletion
+func (DBConnection *MemoryPlugin) DeleteNote(NoteID uint64, DeleterID uint64) error {
+	DBConnection.dbMutex.Lock()
+	defer DBConnection.dbMutex.Unlock()
+	note, exists := DBConnection.notes[NoteID]
+	if exists == false {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/DeleteNote", strconv.FormatUint(DeleterID, 10), logging.ResultFailure, []string{"Failed to get note", strconv.FormatUint(NoteID, 10)})
+		return sql.ErrNoRows
+	}
+	if note.DeletedTime.IsZero() == false {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/DeleteNote", strconv.FormatUint(DeleterID, 10), logging.ResultFailure, []string{"Failed to delete note", strconv.FormatUint(NoteID, 10)})
+		return errors.New("note is already deleted")
+	}
+	now := time.Now()
+	note.DeletedTime = now
+	note.DeleterID = DeleterID
+	DBConnection.addNoteRevision(NoteID, note.Region, note.Body, true, DeleterID, now)
+	logging.WriteLog(logging.LogLevelInfo, "MemoryPlugin/DeleteNote", strconv.FormatUint(DeleterID, 10), logging.ResultSuccess, []string{"Note deleted", strconv.FormatUint(NoteID, 10)})
+	return nil
+}
+
+//GetNoteRevisions returns every version of a note, newest first
+func (DBConnection *MemoryPlugin) GetNoteRevisions(NoteID uint64) ([]interfaces.NoteRevisionInformation, error) {
+	DBConnection.dbMutex.RLock()
+	defer DBConnection.dbMutex.RUnlock()
+	var ToReturn []interfaces.NoteRevisionInformation
+	for _, revision := range DBConnection.noteRevisions {
+		if revision.NoteID == NoteID {
+			revisionInfo := interfaces.NoteRevisionInformation{ID: revision.ID, NoteID: revision.NoteID, NoteRegion: revision.Region, Body: revision.Body, Deleted: revision.Deleted, EditorID: revision.EditorID, EditTime: revision.EditTime}
+			if editor, exists := DBConnection.users[revision.EditorID]; exists {
+				revisionInfo.EditorName = editor.Name
+			}
+			ToReturn = append(ToReturn, revisionInfo)
+		}
+	}
+	sort.Slice(ToReturn, func(i, j int) bool { return ToReturn[i].ID > ToReturn[j].ID })
+	return ToReturn, nil
+}
+
+//addNoteRevision keeps a version of a note
+func (DBConnection *MemoryPlugin) addNoteRevision(NoteID uint64, Region interfaces.NoteRegion, Body string, Deleted bool, EditorID uint64, EditTime time.Time) {
+	ID := DBConnection.nextID("NoteRevisions")
+	DBConnection.noteRevisions[ID] = &memoryNoteRevision{ID: ID, NoteID: NoteID, Region: Region, Body: Body, Deleted: Deleted, EditorID: EditorID, EditTime: EditTime}
+}
+
+//deleteNotes removes every note on an image, along with their revisions
+func (DBConnection *MemoryPlugin) deleteNotes(ImageID uint64) {
+	for ID, note := range DBConnection.notes {
+		if note.ImageID == ImageID {
+			for revisionID, revision := range DBConnection.noteRevisions {
+				if revision.NoteID == ID {
+					delete(DBConnection.noteRevisions, revisionID)
+				}
+			}
+			delete(DBConnection.notes, ID)
+		}
+	}
+}
+
+//hasNotes returns whether an image has any notes that are not deleted
+func (DBConnection *MemoryPlugin) hasNotes(ImageID uint64) bool {
+	for _, note := range DBConnection.notes {
+		if note.ImageID == ImageID && note.DeletedTime.IsZero() {
+			return true
+		}
+	}
+	return false
+}
+
+//getNoteInformation converts a stored note, including the creator's name
+func (DBConnection *MemoryPlugin) getNoteInformation(note *memoryNote) interfaces.NoteInformation {
+	ToReturn := interfaces.NoteInformation{ID: note.ID, ImageID: note.ImageID, CreatorID: note.CreatorID, NoteRegion: note.Region, Body: note.Body, CreationTime: note.CreationTime, EditTime: note.EditTime, Deleted: note.DeletedTime.IsZero() == false, DeletedTime: note.DeletedTime, DeleterID: note.DeleterID}
+	if user, exists := DBConnection.users[note.CreatorID]; exists {
+		ToReturn.CreatorName = user.Name
+	}
+	return ToReturn
+}
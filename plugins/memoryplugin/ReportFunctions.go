@@ -0,0 +1,93 @@
+package memoryplugin
+
+import (
+	"database/sql"
+	"errors"
+	"go-image-board/interfaces"
+	"go-image-board/logging"
+	"sort"
+	"strconv"
+	"time"
+)
+
+//NewReport files a report by Report.ReporterID against Report.TargetType and Report.TargetID, it starts open. Returns its ID
+func (DBConnection *MemoryPlugin) NewReport(Report interfaces.ReportInformation) (uint64, error) {
+	DBConnection.dbMutex.Lock()
+	defer DBConnection.dbMutex.Unlock()
+	ID := DBConnection.nextID("Reports")
+	DBConnection.reports[ID] = &memoryReport{ID: ID, TargetType: Report.TargetType, TargetID: Report.TargetID, ReporterID: Report.ReporterID, Category: Report.Category, Reason: Report.Reason, Status: interfaces.ReportOpen, CreationTime: time.Now()}
+	logging.WriteLog(logging.LogLevelInfo, "MemoryPlugin/NewReport", strconv.FormatUint(Report.ReporterID, 10), logging.ResultSuccess, []string{"Report added", strconv.FormatUint(ID, 10)})
+	return ID, nil
+}
+
+//GetReport returns a single report
+func (DBConnection *MemoryPlugin) GetReport(ReportID uint64) (interfaces.ReportInformation, error) {
+	DBConnection.dbMutex.RLock()
+	defer DBConnection.dbMutex.RUnlock()
+	report, exists := DBConnection.reports[ReportID]
+	if exists == false {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/GetReport", "0", logging.ResultFailure, []string{"Failed to get report", strconv.FormatUint(ReportID, 10)})
+		return interfaces.ReportInformation{}, sql.ErrNoRows
+	}
+	return DBConnection.getReportInformation(report), nil
+}
+
+//GetReports returns a page of the reports with the given status, or every report if Status is blank, oldest first, and the count of all of them
+func (DBConnection *MemoryPlugin) GetReports(Status string, PageStart uint64, PageStride uint64) ([]interfaces.ReportInformation, uint64, error) {
+	DBConnection.dbMutex.RLock()
+	defer DBConnection.dbMutex.RUnlock()
+	var ToReturn []interfaces.ReportInformation
+	for _, report := range DBConnection.reports {
+		if Status == "" || report.Status == Status {
+			ToReturn = append(ToReturn, DBConnection.getReportInformation(report))
+		}
+	}
+	sort.Slice(ToReturn, func(i, j int) bool { return ToReturn[i].ID < ToReturn[j].ID })
+	start, end := pageBounds(len(ToReturn), PageStart, PageStride)
+	return ToReturn[start:end], uint64(len(ToReturn)), nil
+}
+
+//GetUserReports returns a page of the reports filed by a user, newest first, and the count of all of them
+func (DBConnection *MemoryPlugin) GetUserReports(ReporterID uint64, PageStart uint64, PageStride uint64) ([]interfaces.ReportInformation, uint64, error) {
+	DBConnection.dbMutex.RLock()
+	defer DBConnection.dbMutex.RUnlock()
+	var ToReturn []interfaces.ReportInformation
+	for _, report := range DBConnection.reports {
+		if report.ReporterID == ReporterID {
+			ToReturn = append(ToReturn, DBConnection.getReportInformation(report))
+		}
+	}
+	sort.Slice(ToReturn, func(i, j int) bool { return ToReturn[i].ID > ToReturn[j].ID })
+	start, end := pageBounds(len(ToReturn), PageStart, PageStride)
+	return ToReturn[start:end], uint64(len(ToReturn)), nil
+}
+
+//UpdateReport sets the status of a report that is not closed, along with the moderator that changed it and their outcome
+func (DBConnection *MemoryPlugin) UpdateReport(ReportID uint64, Status string, ModeratorID uint64, Outcome string) error {
+	DBConnection.dbMutex.Lock()
+	defer DBConnection.dbMutex.Unlock()
+	report, exists := DBConnection.reports[ReportID]
+	if exists == false || report.Status == interfaces.ReportResolved || report.Status == interfaces.ReportDismissed {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/UpdateReport", strconv.FormatUint(ModeratorID, 10), logging.ResultFailure, []string{"Failed to update report", strconv.FormatUint(ReportID, 10)})
+		return errors.New("report does not exist or is already closed")
+	}
+	report.Status = Status
+	report.ModeratorID = ModeratorID
+	report.Outcome = Outcome
+	report.UpdateTime = time.Now()
+	logging.WriteLog(logging.LogLevelInfo, "MemoryPlugin/UpdateReport", strconv.FormatUint(ModeratorID, 10), logging.ResultSuccess, []string{"Report updated", strconv.FormatUint(ReportID, 10), Status})
+	return nil
+}
+
+//getReportInformation converts a stored report, including the names of the reporter and moderator
+func (DBConnection *MemoryPlugin) getReportInformation(report *memoryReport) interfaces.ReportInformation {
+	ToReturn := interfaces.ReportInformation{ID: report.ID, TargetType: report.TargetType, TargetID: report.TargetID, ReporterID: report.ReporterID, Category: report.Category, Reason: report.Reason, Status: report.Status,
+		ModeratorID: report.ModeratorID, Outcome: report.Outcome, CreationTime: report.CreationTime, UpdateTime: report.UpdateTime}
+	if user, exists := DBConnection.users[report.ReporterID]; exists {
+		ToReturn.ReporterName = user.Name
+	}
+	if user, exists := DBConnection.users[report.ModeratorID]; exists && report.ModeratorID != 0 {
+		ToReturn.ModeratorName = user.Name
+	}
+	return ToReturn
+}
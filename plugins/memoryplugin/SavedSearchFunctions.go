@@ -0,0 +1,98 @@
+package memoryplugin
+
+import (
+	"database/sql"
+	"go-image-board/interfaces"
+	"go-image-board/logging"
+	"sort"
+	"strconv"
+	"time"
+)
+
+//NewSavedSearch adds a saved search for Search.UserID, returns its ID
+func (DBConnection *MemoryPlugin) NewSavedSearch(Search interfaces.SavedSearchInformation) (uint64, error) {
+	DBConnection.dbMutex.Lock()
+	defer DBConnection.dbMutex.Unlock()
+	ID := DBConnection.nextID("SavedSearches")
+	now := time.Now()
+	DBConnection.savedSearches[ID] = &memorySavedSearch{ID: ID, UserID: Search.UserID, Name: Search.Name, Query: Search.Query, CollectionContext: Search.CollectionContext, Pinned: Search.Pinned, ShowNewCount: Search.ShowNewCount, CreationTime: now, LastViewed: now}
+	logging.WriteLog(logging.LogLevelInfo, "MemoryPlugin/NewSavedSearch", strconv.FormatUint(Search.UserID, 10), logging.ResultSuccess, []string{"Saved search added", Search.Name})
+	return ID, nil
+}
+
+//UpdateSavedSearch changes the name, query and options of a saved search, only if it belongs to Search.UserID. Returns sql.ErrNoRows otherwise
+func (DBConnection *MemoryPlugin) UpdateSavedSearch(Search interfaces.SavedSearchInformation) error {
+	DBConnection.dbMutex.Lock()
+	defer DBConnection.dbMutex.Unlock()
+	search, exists := DBConnection.savedSearches[Search.ID]
+	if exists == false || search.UserID != Search.UserID {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/UpdateSavedSearch", strconv.FormatUint(Search.UserID, 10), logging.ResultFailure, []string{"Failed to update saved search", strconv.FormatUint(Search.ID, 10)})
+		return sql.ErrNoRows
+	}
+	search.Name = Search.Name
+	search.Query = Search.Query
+	search.CollectionContext = Search.CollectionContext
+	search.Pinned = Search.Pinned
+	search.ShowNewCount = Search.ShowNewCount
+	logging.WriteLog(logging.LogLevelInfo, "MemoryPlugin/UpdateSavedSearch", strconv.FormatUint(Search.UserID, 10), logging.ResultSuccess, []string{"Saved search updated", strconv.FormatUint(Search.ID, 10)})
+	return nil
+}
+
+//DeleteSavedSearch removes a saved search, only if it belongs to UserID. Returns sql.ErrNoRows otherwise
+func (DBConnection *MemoryPlugin) DeleteSavedSearch(ID uint64, UserID uint64) error {
+	DBConnection.dbMutex.Lock()
+	defer DBConnection.dbMutex.Unlock()
+	search, exists := DBConnection.savedSearches[ID]
+	if exists == false || search.UserID != UserID {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/DeleteSavedSearch", strconv.FormatUint(UserID, 10), logging.ResultFailure, []string{"Failed to remove saved search", strconv.FormatUint(ID, 10)})
+		return sql.ErrNoRows
+	}
+	delete(DBConnection.savedSearches, ID)
+	logging.WriteLog(logging.LogLevelInfo, "MemoryPlugin/DeleteSavedSearch", strconv.FormatUint(UserID, 10), logging.ResultSuccess, []string{"Saved search removed", strconv.FormatUint(ID, 10)})
+	return nil
+}
+
+//GetSavedSearch returns a single saved search, whoever it belongs to
+func (DBConnection *MemoryPlugin) GetSavedSearch(ID uint64) (interfaces.SavedSearchInformation, error) {
+	DBConnection.dbMutex.RLock()
+	defer DBConnection.dbMutex.RUnlock()
+	search, exists := DBConnection.savedSearches[ID]
+	if exists == false {
+		return interfaces.SavedSearchInformation{}, sql.ErrNoRows
+	}
+	return search.information(), nil
+}
+
+//GetSavedSearches returns every saved search belonging to a user, ordered by name
+func (DBConnection *MemoryPlugin) GetSavedSearches(UserID uint64) ([]interfaces.SavedSearchInformation, error) {
+	DBConnection.dbMutex.RLock()
+	defer DBConnection.dbMutex.RUnlock()
+	var ToReturn []interfaces.SavedSearchInformation
+	for _, search := range DBConnection.savedSearches {
+		if search.UserID == UserID {
+			ToReturn = append(ToReturn, search.information())
+		}
+	}
+	sort.Slice(ToReturn, func(i, j int) bool {
+		if ToReturn[i].Name != ToReturn[j].Name {
+			return ToReturn[i].Name < ToReturn[j].Name
+		}
+		return ToReturn[i].ID < ToReturn[j].ID
+	})
+	return ToReturn, nil
+}
+
+//SetSavedSearchViewed records that a saved search was opened now, so new results are counted from here
+func (DBConnection *MemoryPlugin) SetSavedSearchViewed(ID uint64) error {
+	DBConnection.dbMutex.Lock()
+	defer DBConnection.dbMutex.Unlock()
+	if search, exists := DBConnection.savedSearches[ID]; exists {
+		search.LastViewed = time.Now()
+	}
+	return nil
+}
+
+//information converts a stored saved search to the format returned by the plugin
+func (search *memorySavedSearch) information() interfaces.SavedSearchInformation {
+	return interfaces.SavedSearchInformation{ID: search.ID, UserID: search.UserID, Name: search.Name, Query: search.Query, CollectionContext: search.CollectionContext, Pinned: search.Pinned, ShowNewCount: search.ShowNewCount, CreationTime: search.CreationTime, LastViewed: search.LastViewed}
+}
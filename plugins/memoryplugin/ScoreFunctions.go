@@ -0,0 +1,66 @@
+package memoryplugin
+
+import (
+	"go-image-board/logging"
+	"math"
+	"strconv"
+	"time"
+)
+
+//Score operations
+
+//UpdateUserVoteScore Either creates or changes a user's vote on an image
+func (DBConnection *MemoryPlugin) UpdateUserVoteScore(UserID uint64, ImageID uint64, Score int64) error {
+	DBConnection.dbMutex.Lock()
+	defer DBConnection.dbMutex.Unlock()
+	key := imageUserScoreKey{UserID: UserID, ImageID: ImageID}
+	if existing, exists := DBConnection.imageUserScores[key]; exists {
+		existing.Score = Score
+	} else {
+		DBConnection.imageUserScores[key] = &memoryImageUserScore{ID: DBConnection.nextID("ImageUserScores"), UserID: UserID, ImageID: ImageID, Score: Score, CreationTime: time.Now()}
+	}
+	logging.WriteLog(logging.LogLevelError, "MemoryPlugin/UpdateUserVoteScore", strconv.FormatUint(UserID, 10), logging.ResultSuccess, []string{"Score added/updated"})
+	//The other plugins do this in the background, there is no reason to here
+	DBConnection.updateScoreOnImage(ImageID)
+	return nil
+}
+
+//UpdateScoreOnImage update ScoreTotal, ScoreAverage, and ScoreVoters on an image
+func (DBConnection *MemoryPlugin) UpdateScoreOnImage(ImageID uint64) error {
+	DBConnection.dbMutex.Lock()
+	defer DBConnection.dbMutex.Unlock()
+	DBConnection.updateScoreOnImage(ImageID)
+	return nil
+}
+
+//updateScoreOnImage is UpdateScoreOnImage for callers already holding the lock
+func (DBConnection *MemoryPlugin) updateScoreOnImage(ImageID uint64) {
+	image, exists := DBConnection.images[ImageID]
+	if exists == false {
+		return
+	}
+	var count, sum int64
+	for key, score := range DBConnection.imageUserScores {
+		if key.ImageID == ImageID {
+			count++
+			sum += score.Score
+		}
+	}
+	image.ScoreVoters = count
+	image.ScoreTotal = sum
+	image.ScoreAverage = 0
+	if count > 0 {
+		image.ScoreAverage = int64(math.Round(float64(sum) / float64(count)))
+	}
+}
+
+//GetUserVoteScore Returns a user's vote on an image
+func (DBConnection *MemoryPlugin) GetUserVoteScore(UserID uint64, ImageID uint64) (int64, error) {
+	DBConnection.dbMutex.RLock()
+	defer DBConnection.dbMutex.RUnlock()
+	score, exists := DBConnection.imageUserScores[imageUserScoreKey{UserID: UserID, ImageID: ImageID}]
+	if exists == false {
+		return 0, nil
+	}
+	return score.Score, nil
+}
@@ -0,0 +1,303 @@
+package memoryplugin
+
+import (
+	"database/sql"
+	"errors"
+	"go-image-board/interfaces"
+	"go-image-board/logging"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//Tag Operations
+var regexTagName = regexp.MustCompile("[^a-zA-Z0-9_-]") //Used to cleanup tag names
+var regexWhiteSpace = regexp.MustCompile("\\s{2,}")     //Matches 2 or more consecutive whitespace
+//regexTagValue is used to cleanup metatag values, / and + are kept for ratios such as 16/9 and types such as image/svg+xml
+var regexTagValue = regexp.MustCompile("[^a-zA-Z0-9_\\-\\.\\*/+]")
+
+func prepareTagName(Name string) string {
+	//Lowercase Name -> Trimmed front and end of whitespace -> any inner whitespace reduced and underscored
+	Name = regexWhiteSpace.ReplaceAllString(strings.TrimSpace(strings.ToLower(Name)), "_") //Replace all whitespace with _
+	//Case of metatag
+	if strings.Count(Name, ":") == 1 {
+		//Assume a metatag
+		NameValue := strings.Split(Name, ":")
+		value, comparator := getTagComparator(NameValue[1]) //Strip comparator, so it does not get replaced by a _
+		Name = regexTagName.ReplaceAllString(NameValue[0], "_") + ":" + comparator + regexTagValue.ReplaceAllString(value, "_")
+	} else {
+		//Then any special characters replaced with _
+		Name = regexTagName.ReplaceAllString(Name, "_")
+	}
+	return Name
+}
+
+//NewTag adds a tag with the provided information
+func (DBConnection *MemoryPlugin) NewTag(Name string, Description string, Category string, UploaderID uint64) (uint64, error) {
+	//Cleanup name
+	Name = prepareTagName(Name)
+	if Category == "" {
+		Category = interfaces.TagCategoryGeneral
+	}
+
+	if len(Name) < 3 || len(Name) > 255 || len(Description) > 255 {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/NewTag", strconv.FormatUint(UploaderID, 10), logging.ResultFailure, []string{"Failed to add tag dues to size of name/description", Name, Description})
+		return 0, errors.New("name or description outside of right sizes")
+	}
+	if interfaces.IsTagCategory(Category) == false {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/NewTag", strconv.FormatUint(UploaderID, 10), logging.ResultFailure, []string{"Failed to add tag due to unknown category", Name, Category})
+		return 0, errors.New("unknown tag category")
+	}
+
+	DBConnection.dbMutex.Lock()
+	defer DBConnection.dbMutex.Unlock()
+	if DBConnection.getTagByName(Name) != nil {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/NewTag", strconv.FormatUint(UploaderID, 10), logging.ResultFailure, []string{"Failed to add tag", "duplicate name " + Name})
+		return 0, errors.New("a tag with that name already exists")
+	}
+	ID := DBConnection.nextID("Tags")
+	DBConnection.tags[ID] = &memoryTag{ID: ID, Name: Name, Description: Description, UploaderID: UploaderID, UploadTime: time.Now(), Category: Category}
+	logging.WriteLog(logging.LogLevelError, "MemoryPlugin/NewTag", strconv.FormatUint(UploaderID, 10), logging.ResultSuccess, []string{"Tag added", strconv.FormatUint(ID, 10)})
+	return ID, nil
+}
+
+//DeleteTag removes a tag
+func (DBConnection *MemoryPlugin) DeleteTag(TagID uint64) error {
+	DBConnection.dbMutex.Lock()
+	defer DBConnection.dbMutex.Unlock()
+	//Ensure not in use
+	useCount := DBConnection.getTagUseCount(TagID)
+	if useCount > 0 {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/DeleteTag", "0", logging.ResultFailure, []string{"Tag to delete is still in use", strconv.FormatUint(TagID, 10), "in use", strconv.FormatUint(useCount, 10)})
+		return errors.New("tag to delete is still in use")
+	}
+
+	//Delete, along with what the onTagDelete trigger would remove
+	for key := range DBConnection.collectionTags {
+		if key.TagID == TagID {
+			delete(DBConnection.collectionTags, key)
+		}
+	}
+	//Only history refers to the tag now, which can no longer be reverted to
+	for ID, change := range DBConnection.tagHistory {
+		if change.TagID == TagID {
+			delete(DBConnection.tagHistory, ID)
+		}
+	}
+	//Implications go along with the tag
+	for ID, implication := range DBConnection.tagImplications {
+		if implication.TagID == TagID || implication.ImpliedTagID == TagID {
+			delete(DBConnection.tagImplications, ID)
+		}
+	}
+	delete(DBConnection.tags, TagID)
+	logging.WriteLog(logging.LogLevelError, "MemoryPlugin/DeleteTag", "0", logging.ResultSuccess, []string{"Tag deleted", strconv.FormatUint(TagID, 10)})
+	return nil
+}
+
+//AddTag adds an association of a tag to image into the association table
+func (DBConnection *MemoryPlugin) AddTag(TagIDs []uint64, ImageID uint64, LinkerID uint64) error {
+	if len(TagIDs) == 0 {
+		return errors.New("No tags provided")
+	}
+	DBConnection.dbMutex.Lock()
+	defer DBConnection.dbMutex.Unlock()
+	//Validate tags, if some are alias, add alias instead, if a tag does not exist, error out
+	var validatedTagIDs []uint64
+	for i := 0; i < len(TagIDs); i++ {
+		TagID := TagIDs[i]
+		tagInfo, err := DBConnection.getTag(TagID, false)
+		if err != nil {
+			return errors.New("Failed to validate tag " + strconv.FormatUint(TagID, 10))
+		}
+		//If this is an alias, then add aliasedid instead
+		if tagInfo.IsAlias {
+			validatedTagIDs = append(validatedTagIDs, tagInfo.AliasedID)
+		} else {
+			validatedTagIDs = append(validatedTagIDs, TagID)
+		}
+	}
+	if _, exists := DBConnection.images[ImageID]; exists == false {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/AddTag", strconv.FormatUint(LinkerID, 10), logging.ResultFailure, []string{"Tags not added to image", strconv.FormatUint(ImageID, 10), "image does not exist"})
+		return errors.New("image does not exist")
+	}
+	//Add every tag the validated tags imply as well
+	for _, TagID := range DBConnection.getImpliedTags(validatedTagIDs) {
+		if DBConnection.insertImageTag(TagID, ImageID, LinkerID) {
+			operation := interfaces.TagChangeAdd
+			if uint64SliceContains(validatedTagIDs, TagID) == false {
+				operation = interfaces.TagChangeImply
+			}
+			DBConnection.recordTagChange(ImageID, TagID, LinkerID, true, operation)
+		}
+	}
+	logging.WriteLog(logging.LogLevelError, "MemoryPlugin/AddTag", strconv.FormatUint(LinkerID, 10), logging.ResultSuccess, []string{"Tags added", strconv.FormatUint(ImageID, 10)})
+	return nil
+}
+
+//GetAllTags returns a list of all tags, but only the ID, Name, Description, and IsAlias
+func (DBConnection *MemoryPlugin) GetAllTags() ([]interfaces.TagInformation, error) {
+	DBConnection.dbMutex.RLock()
+	defer DBConnection.dbMutex.RUnlock()
+	var ToReturn []interfaces.TagInformation
+	for _, tag := range DBConnection.getTagsByName() {
+		ToReturn = append(ToReturn, interfaces.TagInformation{Name: tag.Name, ID: tag.ID, Description: tag.Description, Exists: true, Exclude: false, IsAlias: tag.IsAlias, Category: tag.Category})
+	}
+	return ToReturn, nil
+}
+
+//GetTag returns detailed information on one tag
+func (DBConnection *MemoryPlugin) GetTag(ID uint64, IncludeCount bool) (interfaces.TagInformation, error) {
+	DBConnection.dbMutex.RLock()
+	defer DBConnection.dbMutex.RUnlock()
+	return DBConnection.getTag(ID, IncludeCount)
+}
+
+//getTag is GetTag for callers already holding the lock
+func (DBConnection *MemoryPlugin) getTag(ID uint64, IncludeCount bool) (interfaces.TagInformation, error) {
+	tag, exists := DBConnection.tags[ID]
+	if exists == false {
+		return interfaces.TagInformation{ID: ID, Exists: false}, sql.ErrNoRows
+	}
+	var TagCount uint64
+	if IncludeCount {
+		TagCount = DBConnection.getTagUseCount(ID)
+	}
+	return interfaces.TagInformation{Name: tag.Name, ID: ID, Description: tag.Description, Exists: true, Exclude: false, UploaderID: tag.UploaderID, UploadTime: tag.UploadTime, AliasedID: tag.AliasedID, IsAlias: tag.IsAlias, Category: tag.Category, UseCount: TagCount}, nil
+}
+
+//GetTagByName returns detailed information on one tag as queried by name
+func (DBConnection *MemoryPlugin) GetTagByName(Name string) (interfaces.TagInformation, error) {
+	DBConnection.dbMutex.RLock()
+	defer DBConnection.dbMutex.RUnlock()
+	tag := DBConnection.getTagByName(Name)
+	if tag == nil {
+		return interfaces.TagInformation{Name: Name, Exists: false}, sql.ErrNoRows
+	}
+	return interfaces.TagInformation{Name: Name, ID: tag.ID, Description: tag.Description, Exists: true, Exclude: false, UploaderID: tag.UploaderID, UploadTime: tag.UploadTime, AliasedID: tag.AliasedID, IsAlias: tag.IsAlias, Category: tag.Category}, nil
+}
+
+//getTagByName returns the tag with the given name, or nil
+func (DBConnection *MemoryPlugin) getTagByName(Name string) *memoryTag {
+	for _, tag := range DBConnection.tags {
+		if strings.EqualFold(tag.Name, Name) {
+			return tag
+		}
+	}
+	return nil
+}
+
+//getTagsByName returns every tag sorted by name
+func (DBConnection *MemoryPlugin) getTagsByName() []*memoryTag {
+	var ToReturn []*memoryTag
+	for _, tag := range DBConnection.tags {
+		ToReturn = append(ToReturn, tag)
+	}
+	sort.Slice(ToReturn, func(i, j int) bool { return ToReturn[i].Name < ToReturn[j].Name })
+	return ToReturn
+}
+
+//getTagUseCount returns how many images have the given tag
+func (DBConnection *MemoryPlugin) getTagUseCount(TagID uint64) uint64 {
+	var useCount uint64
+	for key := range DBConnection.imageTags {
+		if key.TagID == TagID {
+			useCount++
+		}
+	}
+	return useCount
+}
+
+//UpdateTag updates a pre-existing tag
+func (DBConnection *MemoryPlugin) UpdateTag(TagID uint64, Name string, Description string, Category string, AliasedID uint64, IsAlias bool, RequestorID uint64) error {
+	//Cleanup name
+	Name = prepareTagName(Name)
+	if len(Name) < 3 || len(Name) > 255 || len(Description) > 255 {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/UpdateTag", strconv.FormatUint(RequestorID, 10), logging.ResultFailure, []string{"Failed to update tag dues to size", Name, Description})
+		return errors.New("name or description outside of right sizes")
+	}
+	if Category == "" {
+		Category = interfaces.TagCategoryGeneral
+	}
+	if interfaces.IsTagCategory(Category) == false {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/UpdateTag", strconv.FormatUint(RequestorID, 10), logging.ResultFailure, []string{"Failed to update tag due to unknown category", Name, Category})
+		return errors.New("unknown tag category")
+	}
+
+	DBConnection.dbMutex.Lock()
+	defer DBConnection.dbMutex.Unlock()
+	if IsAlias {
+		//Prevent adding alias
+		tagInfo, err := DBConnection.getTag(AliasedID, false)
+		if err != nil || tagInfo.IsAlias {
+			return errors.New("Tag to alias could not be found, or is an alias itself")
+		}
+	}
+
+	if other := DBConnection.getTagByName(Name); other != nil && other.ID != TagID {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/UpdateTag", strconv.FormatUint(RequestorID, 10), logging.ResultFailure, []string{"Failed to update tag", "duplicate name " + Name})
+		return errors.New("a tag with that name already exists")
+	}
+	if tag, exists := DBConnection.tags[TagID]; exists {
+		tag.Name = Name
+		tag.Description = Description
+		tag.Category = Category
+		tag.AliasedID = AliasedID
+		tag.IsAlias = IsAlias
+	}
+	logging.WriteLog(logging.LogLevelError, "MemoryPlugin/UpdateTag", strconv.FormatUint(RequestorID, 10), logging.ResultSuccess, []string{"Tag updated"})
+
+	//The other plugins do this in the background, there is no reason to here
+	if IsAlias {
+		DBConnection.replaceImageTags(TagID, AliasedID, RequestorID)
+	}
+
+	return nil
+}
+
+//SearchTags returns a list of tags like the provided name, but only the ID, Name, Description, and IsAlias
+func (DBConnection *MemoryPlugin) SearchTags(name string, PageStart uint64, PageStride uint64, WildcardForwardOnly bool, SortByUsage bool) ([]interfaces.TagInformation, uint64, error) {
+	DBConnection.dbMutex.RLock()
+	defer DBConnection.dbMutex.RUnlock()
+	var ToReturn []interfaces.TagInformation
+
+	//Cleanup Query and alter if we were provided a name
+	name = strings.TrimSpace(name)
+	name = strings.Replace(name, "%", "", -1)
+	if WildcardForwardOnly {
+		name = name + "%"
+	} else {
+		name = "%" + name + "%"
+	}
+
+	var matchingTags []*memoryTag
+	var MaxResults uint64
+	usage := make(map[uint64]uint64)
+	for key := range DBConnection.imageTags {
+		usage[key.TagID]++
+	}
+	for _, tag := range DBConnection.getTagsByName() {
+		if likeMatch(tag.Name, name) == false {
+			continue
+		}
+		//Count is not restricted to used tags, but the results are when sorting by usage
+		MaxResults++
+		if SortByUsage && usage[tag.ID] == 0 {
+			continue
+		}
+		matchingTags = append(matchingTags, tag)
+	}
+
+	//Add the sorting
+	if SortByUsage {
+		sort.SliceStable(matchingTags, func(i, j int) bool { return usage[matchingTags[i].ID] > usage[matchingTags[j].ID] })
+	}
+
+	start, end := pageBounds(len(matchingTags), PageStart, PageStride)
+	for _, tag := range matchingTags[start:end] {
+		ToReturn = append(ToReturn, interfaces.TagInformation{Name: tag.Name, ID: tag.ID, Description: tag.Description, Exists: true, Exclude: false, IsAlias: tag.IsAlias, Category: tag.Category})
+	}
+	return ToReturn, MaxResults, nil
+}
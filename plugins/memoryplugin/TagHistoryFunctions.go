@@ -0,0 +1,84 @@
+package memoryplugin
+
+import (
+	"go-image-board/interfaces"
+	"go-image-board/logging"
+	"sort"
+	"strconv"
+	"time"
+)
+
+//GetImageTagHistory returns every tag change made to an image, newest first
+func (DBConnection *MemoryPlugin) GetImageTagHistory(ImageID uint64) ([]interfaces.TagChangeInformation, error) {
+	DBConnection.dbMutex.RLock()
+	defer DBConnection.dbMutex.RUnlock()
+	return DBConnection.getTagHistory(func(change *memoryTagChange) bool { return change.ImageID == ImageID }), nil
+}
+
+//GetUserTagHistory returns the tag changes made by a user between Since and Until, newest first
+func (DBConnection *MemoryPlugin) GetUserTagHistory(UserID uint64, Since time.Time, Until time.Time) ([]interfaces.TagChangeInformation, error) {
+	DBConnection.dbMutex.RLock()
+	defer DBConnection.dbMutex.RUnlock()
+	return DBConnection.getTagHistory(func(change *memoryTagChange) bool {
+		return change.UserID == UserID && change.ChangeTime.Before(Since) == false && change.ChangeTime.After(Until) == false
+	}), nil
+}
+
+//UndoTagChanges reverses each change in order, skipping those already undone by later changes, returns how many were reversed
+func (DBConnection *MemoryPlugin) UndoTagChanges(Changes []interfaces.TagChangeInformation, UserID uint64) (uint64, error) {
+	DBConnection.dbMutex.Lock()
+	defer DBConnection.dbMutex.Unlock()
+	var undone uint64
+	for _, change := range Changes {
+		if change.Added {
+			if DBConnection.deleteImageTag(imageTagKey{ImageID: change.ImageID, TagID: change.TagID}) == false {
+				continue
+			}
+		} else {
+			//A removed tag may have become an alias since, in which case the tag it aliases is added instead
+			tagInfo, err := DBConnection.getTag(change.TagID, false)
+			if err != nil {
+				continue
+			}
+			if tagInfo.IsAlias {
+				change.TagID = tagInfo.AliasedID
+			}
+			if _, exists := DBConnection.images[change.ImageID]; exists == false {
+				continue
+			}
+			if DBConnection.insertImageTag(change.TagID, change.ImageID, UserID) == false {
+				continue
+			}
+		}
+		DBConnection.recordTagChange(change.ImageID, change.TagID, UserID, change.Added == false, interfaces.TagChangeRevert)
+		undone++
+	}
+	logging.WriteLog(logging.LogLevelInfo, "MemoryPlugin/UndoTagChanges", strconv.FormatUint(UserID, 10), logging.ResultSuccess, []string{"Tag changes undone", strconv.FormatUint(undone, 10)})
+	return undone, nil
+}
+
+//recordTagChange adds a single entry to an image's tag history
+func (DBConnection *MemoryPlugin) recordTagChange(ImageID uint64, TagID uint64, UserID uint64, Added bool, Operation string) {
+	ID := DBConnection.nextID("ImageTagHistory")
+	DBConnection.tagHistory[ID] = &memoryTagChange{ID: ID, ImageID: ImageID, TagID: TagID, UserID: UserID, Added: Added, Operation: Operation, ChangeTime: time.Now()}
+}
+
+//getTagHistory returns the tag changes Include accepts, newest first
+func (DBConnection *MemoryPlugin) getTagHistory(Include func(change *memoryTagChange) bool) []interfaces.TagChangeInformation {
+	var ToReturn []interfaces.TagChangeInformation
+	for _, change := range DBConnection.tagHistory {
+		if Include(change) == false {
+			continue
+		}
+		changeInfo := interfaces.TagChangeInformation{ID: change.ID, ImageID: change.ImageID, TagID: change.TagID, UserID: change.UserID, Added: change.Added, Operation: change.Operation, ChangeTime: change.ChangeTime}
+		if tag, exists := DBConnection.tags[change.TagID]; exists {
+			changeInfo.TagName = tag.Name
+		}
+		if user, exists := DBConnection.users[change.UserID]; exists {
+			changeInfo.UserName = user.Name
+		}
+		ToReturn = append(ToReturn, changeInfo)
+	}
+	sort.Slice(ToReturn, func(i, j int) bool { return ToReturn[i].ID > ToReturn[j].ID })
+	return ToReturn
+}
@@ -0,0 +1,171 @@
+package memoryplugin
+
+import (
+	"database/sql"
+	"errors"
+	"go-image-board/interfaces"
+	"go-image-board/logging"
+	"sort"
+	"strconv"
+	"time"
+)
+
+//AddTagImplication adds a rule so that images given TagID are also given ImpliedTagID, errors if the rule would create a cycle
+func (DBConnection *MemoryPlugin) AddTagImplication(TagID uint64, ImpliedTagID uint64, CreatorID uint64) error {
+	DBConnection.dbMutex.Lock()
+	defer DBConnection.dbMutex.Unlock()
+	TagID, ImpliedTagID, err := DBConnection.validateTagImplication(TagID, ImpliedTagID)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/AddTagImplication", strconv.FormatUint(CreatorID, 10), logging.ResultFailure, []string{"Failed to add tag implication", strconv.FormatUint(TagID, 10), strconv.FormatUint(ImpliedTagID, 10), err.Error()})
+		return err
+	}
+	ID := DBConnection.nextID("TagImplications")
+	DBConnection.tagImplications[ID] = &memoryTagImplication{ID: ID, TagID: TagID, ImpliedTagID: ImpliedTagID, CreatorID: CreatorID, CreationTime: time.Now()}
+	logging.WriteLog(logging.LogLevelInfo, "MemoryPlugin/AddTagImplication", strconv.FormatUint(CreatorID, 10), logging.ResultSuccess, []string{"Tag implication added", strconv.FormatUint(TagID, 10), strconv.FormatUint(ImpliedTagID, 10)})
+	return nil
+}
+
+//validateTagImplication swaps aliases for the tags they alias, then ensures the implication is not a duplicate and would not create a cycle
+func (DBConnection *MemoryPlugin) validateTagImplication(TagID uint64, ImpliedTagID uint64) (uint64, uint64, error) {
+	tagInfo, err := DBConnection.getTag(TagID, false)
+	impliedTagInfo, err2 := DBConnection.getTag(ImpliedTagID, false)
+	if err != nil || err2 != nil {
+		return TagID, ImpliedTagID, errors.New("Failed to validate tags")
+	}
+	if tagInfo.IsAlias {
+		TagID = tagInfo.AliasedID
+	}
+	if impliedTagInfo.IsAlias {
+		ImpliedTagID = impliedTagInfo.AliasedID
+	}
+	if TagID == ImpliedTagID {
+		return TagID, ImpliedTagID, errors.New("a tag cannot imply itself")
+	}
+	if DBConnection.getTagImplication(TagID, ImpliedTagID) != nil {
+		return TagID, ImpliedTagID, errors.New("tag implication already exists")
+	}
+	//If the implied tag already leads back to the tag, this rule would close a loop
+	if uint64SliceContains(DBConnection.getImpliedTags([]uint64{ImpliedTagID}), TagID) {
+		return TagID, ImpliedTagID, errors.New("tag implication would create a cycle")
+	}
+	return TagID, ImpliedTagID, nil
+}
+
+//getTagImplication returns the rule that TagID implies ImpliedTagID, or nil
+func (DBConnection *MemoryPlugin) getTagImplication(TagID uint64, ImpliedTagID uint64) *memoryTagImplication {
+	for _, implication := range DBConnection.tagImplications {
+		if implication.TagID == TagID && implication.ImpliedTagID == ImpliedTagID {
+			return implication
+		}
+	}
+	return nil
+}
+
+//RemoveTagImplication removes a rule added by AddTagImplication
+func (DBConnection *MemoryPlugin) RemoveTagImplication(TagID uint64, ImpliedTagID uint64) error {
+	DBConnection.dbMutex.Lock()
+	defer DBConnection.dbMutex.Unlock()
+	implication := DBConnection.getTagImplication(TagID, ImpliedTagID)
+	if implication == nil {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/RemoveTagImplication", "0", logging.ResultFailure, []string{"Failed to remove tag implication", strconv.FormatUint(TagID, 10), strconv.FormatUint(ImpliedTagID, 10)})
+		return sql.ErrNoRows
+	}
+	delete(DBConnection.tagImplications, implication.ID)
+	logging.WriteLog(logging.LogLevelInfo, "MemoryPlugin/RemoveTagImplication", "0", logging.ResultSuccess, []string{"Tag implication removed", strconv.FormatUint(TagID, 10), strconv.FormatUint(ImpliedTagID, 10)})
+	return nil
+}
+
+//GetTagImplications returns the rules where a tag implies, or is implied by, another tag
+func (DBConnection *MemoryPlugin) GetTagImplications(TagID uint64) ([]interfaces.TagImplicationInformation, error) {
+	DBConnection.dbMutex.RLock()
+	defer DBConnection.dbMutex.RUnlock()
+	var ToReturn []interfaces.TagImplicationInformation
+	for _, implication := range DBConnection.tagImplications {
+		if implication.TagID != TagID && implication.ImpliedTagID != TagID {
+			continue
+		}
+		tag, exists := DBConnection.tags[implication.TagID]
+		impliedTag, impliedExists := DBConnection.tags[implication.ImpliedTagID]
+		if exists == false || impliedExists == false {
+			continue
+		}
+		ToReturn = append(ToReturn, interfaces.TagImplicationInformation{ID: implication.ID, TagID: implication.TagID, TagName: tag.Name, ImpliedTagID: implication.ImpliedTagID, ImpliedTagName: impliedTag.Name, CreatorID: implication.CreatorID, CreationTime: implication.CreationTime})
+	}
+	sort.Slice(ToReturn, func(i, j int) bool {
+		if ToReturn[i].TagName != ToReturn[j].TagName {
+			return ToReturn[i].TagName < ToReturn[j].TagName
+		}
+		return ToReturn[i].ImpliedTagName < ToReturn[j].ImpliedTagName
+	})
+	return ToReturn, nil
+}
+
+//ApplyTagImplications adds implied tags to every image missing them, returns how many tags were added
+func (DBConnection *MemoryPlugin) ApplyTagImplications(LinkerID uint64) (uint64, error) {
+	DBConnection.dbMutex.Lock()
+	defer DBConnection.dbMutex.Unlock()
+	//Implied tags can imply further tags, so repeat until a pass adds nothing. Cycles are refused when adding implications, so this ends.
+	var added uint64
+	for {
+		var toAdd []imageTagKey
+		for _, implication := range DBConnection.tagImplications {
+			impliedTagID := DBConnection.resolveAlias(implication.ImpliedTagID)
+			for key := range DBConnection.imageTags {
+				if key.TagID != implication.TagID {
+					continue
+				}
+				if _, exists := DBConnection.imageTags[imageTagKey{ImageID: key.ImageID, TagID: impliedTagID}]; exists == false {
+					toAdd = append(toAdd, imageTagKey{ImageID: key.ImageID, TagID: impliedTagID})
+				}
+			}
+		}
+		var passAdded uint64
+		for _, key := range toAdd {
+			if DBConnection.insertImageTag(key.TagID, key.ImageID, LinkerID) {
+				DBConnection.recordTagChange(key.ImageID, key.TagID, LinkerID, true, interfaces.TagChangeImply)
+				passAdded++
+			}
+		}
+		if passAdded == 0 {
+			break
+		}
+		added += passAdded
+	}
+	logging.WriteLog(logging.LogLevelInfo, "MemoryPlugin/ApplyTagImplications", strconv.FormatUint(LinkerID, 10), logging.ResultSuccess, []string{"Implied tags added", strconv.FormatUint(added, 10)})
+	return added, nil
+}
+
+//getImpliedTags returns TagIDs followed by every tag they imply, directly or through other implications. Implied aliases are swapped for the tags they alias.
+func (DBConnection *MemoryPlugin) getImpliedTags(TagIDs []uint64) []uint64 {
+	var ToReturn []uint64
+	for _, TagID := range TagIDs {
+		if uint64SliceContains(ToReturn, TagID) == false {
+			ToReturn = append(ToReturn, TagID)
+		}
+	}
+	//Tags already seen are not expanded again, which also keeps a cycle from looping forever
+	for index := 0; index < len(ToReturn); index++ {
+		var found []uint64
+		for _, implication := range DBConnection.tagImplications {
+			if implication.TagID != ToReturn[index] {
+				continue
+			}
+			impliedTagID := DBConnection.resolveAlias(implication.ImpliedTagID)
+			if uint64SliceContains(ToReturn, impliedTagID) == false && uint64SliceContains(found, impliedTagID) == false {
+				found = append(found, impliedTagID)
+			}
+		}
+		//Map order is random, keep the result stable
+		sort.Slice(found, func(i, j int) bool { return found[i] < found[j] })
+		ToReturn = append(ToReturn, found...)
+	}
+	return ToReturn
+}
+
+//resolveAlias returns the tag TagID aliases, or TagID if it is not an alias
+func (DBConnection *MemoryPlugin) resolveAlias(TagID uint64) uint64 {
+	if tag, exists := DBConnection.tags[TagID]; exists && tag.IsAlias {
+		return tag.AliasedID
+	}
+	return TagID
+}
@@ -0,0 +1,717 @@
+package memoryplugin
+
+import (
+	"database/sql"
+	"errors"
+	"go-image-board/config"
+	"go-image-board/interfaces"
+	"go-image-board/logging"
+	"go-image-board/plugins/tagquery"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//GetUserFilterTags returns a slice of tags based on a user's custom filter
+func (DBConnection *MemoryPlugin) GetUserFilterTags(UserID uint64, CollectionContext bool) ([]interfaces.TagInformation, error) {
+	DBConnection.dbMutex.RLock()
+	user, exists := DBConnection.users[UserID]
+	var userFilter string
+	if exists {
+		userFilter = user.SearchFilter
+	}
+	DBConnection.dbMutex.RUnlock()
+	if exists == false {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/GetUserQueryTags", strconv.FormatUint(UserID, 10), logging.ResultFailure, []string{"Failed to get user filter", sql.ErrNoRows.Error()})
+		return nil, sql.ErrNoRows
+	}
+	tags, err := DBConnection.GetQueryTags(userFilter, CollectionContext)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/GetUserQueryTags", strconv.FormatUint(UserID, 10), logging.ResultFailure, []string{"Failed to get tags from user filter", err.Error()})
+		return nil, err
+	}
+	//Loop through the tags and ensure we have them set as FromUserFilter
+	for i := 0; i < len(tags); i++ {
+		tags[i].FromUserFilter = true
+	}
+	return tags, nil
+}
+
+//GetQueryTags returns a slice of tags based on a query string, CollectionContext should be true if these tags are being parsed for a collection
+func (DBConnection *MemoryPlugin) GetQueryTags(UserQuery string, CollectionContext bool) ([]interfaces.TagInformation, error) {
+	//What we want to return
+	var ToReturn []interfaces.TagInformation
+	//If the user query is blank, just short circuit outta here
+	if len(UserQuery) == 0 {
+		return ToReturn, nil
+	}
+	DBConnection.dbMutex.RLock()
+	defer DBConnection.dbMutex.RUnlock()
+	//Parse the query into terms, from "-Jaws (Movie OR Film) Best" to "-Jaws", "(Movie OR Film)", "Best"
+	//Groups are looked up on their own, the remaining tags are looked up together below
+	var RawQueryTags []string
+	for _, Term := range tagquery.Parse(UserQuery) {
+		if tagquery.IsPattern(Term.Name) {
+			patternInfo, err := DBConnection.getPatternInfo(Term.Name, Term.Exclude)
+			if err != nil {
+				return ToReturn, err
+			}
+			ToReturn = append(ToReturn, patternInfo)
+			continue
+		}
+		if Term.IsGroup {
+			groupInfo, err := tagquery.GroupInfo(Term, func(Name string, Exclude bool) ([]interfaces.TagInformation, error) {
+				if tagquery.IsPattern(Name) {
+					patternInfo, err := DBConnection.getPatternInfo(Name, Exclude)
+					return []interfaces.TagInformation{patternInfo}, err
+				}
+				return DBConnection.getTagsInfo([]string{prepareTagName(Name)}, Exclude, CollectionContext)
+			})
+			if err != nil {
+				return ToReturn, err
+			}
+			ToReturn = append(ToReturn, groupInfo)
+			continue
+		}
+		Tag := prepareTagName(Term.Name) //Cleanup
+		if len(Tag) == 0 || sliceContains(RawQueryTags, Tag) || sliceContains(RawQueryTags, "-"+Tag) {
+			continue //Ensure no duplicates
+		}
+		if Term.Exclude {
+			Tag = "-" + Tag
+		}
+		RawQueryTags = append(RawQueryTags, Tag)
+	}
+
+	//These are passed to the getTagsInfo function to query SQL
+	var IncludeQueryTags []string
+	var ExcludeQueryTags []string
+	//This stores our pre-toReturn result
+	queryMap := make(map[string]interfaces.TagInformation)
+	//Loop through each user query tag, and add it to the map, as well as the Exclude/Include subcategories
+	for _, v := range RawQueryTags {
+		if v[:1] == "-" {
+			ExcludeQueryTags = append(ExcludeQueryTags, strings.ToLower(v[1:]))
+			//queryMap[strings.ToLower(v[1:])] = interfaces.TagInformation{Name: strings.ToLower(v[1:]), Exclude: true, Exists: false}
+		} else if v[:1] == "+" {
+			IncludeQueryTags = append(IncludeQueryTags, strings.ToLower(v[1:]))
+			//queryMap[strings.ToLower(v[1:])] = interfaces.TagInformation{Name: strings.ToLower(v[1:]), Exclude: false, Exists: false}
+		} else {
+			IncludeQueryTags = append(IncludeQueryTags, strings.ToLower(v))
+			//queryMap[strings.ToLower(v)] = interfaces.TagInformation{Name: strings.ToLower(v), Exclude: false, Exists: false}
+		}
+	}
+
+	//If we have exclude tags
+	if len(ExcludeQueryTags) > 0 {
+		//Get more info on them and update querymap with new info
+		returnedTags, err := DBConnection.getTagsInfo(ExcludeQueryTags, true, CollectionContext)
+		if err != nil {
+			return ToReturn, err
+		}
+		for _, tag := range returnedTags {
+			queryMap[tag.Name] = tag
+		}
+	}
+	//If we have include tags
+	if len(IncludeQueryTags) > 0 {
+		//Get more info on them and add them to the map
+		returnedTags, err := DBConnection.getTagsInfo(IncludeQueryTags, false, CollectionContext)
+		if err != nil {
+			return ToReturn, err
+		}
+		for _, tag := range returnedTags {
+			queryMap[tag.Name] = tag
+		}
+	}
+
+	//Now query map contains all the data we need. Now we just need to convert it to a slice
+	for _, TagInfo := range queryMap {
+		ToReturn = append(ToReturn, TagInfo)
+	}
+	return ToReturn, nil
+}
+
+//getTagComparator returns the tagvalue and the comparator, or the original TagValue and an empty string if one does not exist
+func getTagComparator(TagValue string) (string, string) {
+	tagRunes := []rune(TagValue)
+	toReturn := ""
+	if len(tagRunes) == 0 { //Edge case if someone searched "tagname:"
+		return "", ""
+	}
+	if tagRunes[0] == '>' || tagRunes[0] == '<' {
+		toReturn += string(tagRunes[0])
+		tagRunes = tagRunes[1:]
+	}
+	if tagRunes[0] == '=' {
+		toReturn += string(tagRunes[0])
+		tagRunes = tagRunes[1:]
+	}
+	return string(tagRunes), toReturn
+}
+
+//getPatternInfo looks up the tags matching a wildcard pattern such as artist_*, returned as a group matching any of them
+//Errors if more than MaxWildcardTags tags match
+func (DBConnection *MemoryPlugin) getPatternInfo(Pattern string, Exclude bool) (interfaces.TagInformation, error) {
+	Pattern = tagquery.CleanPattern(Pattern)
+	likePattern := tagquery.LikePattern(Pattern)
+	var Matches []interfaces.TagInformation
+	for _, tag := range DBConnection.getTagsByName() {
+		if likeMatch(tag.Name, likePattern) == false {
+			continue
+		}
+		if uint64(len(Matches)) >= config.Configuration.MaxWildcardTags {
+			return interfaces.TagInformation{}, tagquery.PatternError(Pattern, config.Configuration.MaxWildcardTags)
+		}
+		Matches = append(Matches, interfaces.TagInformation{ID: tag.ID, Name: tag.Name, AliasedID: tag.AliasedID, IsAlias: tag.IsAlias, Category: tag.Category})
+	}
+	return tagquery.PatternInfo(Pattern, Exclude, Matches), nil
+}
+
+//getTagsInfo is a helper function to get more details on a set of tags by name, note that the names should be cleaned up before passing to this function.
+//This function will also parse Alias mapping and return those, as well as parse meta tags
+func (DBConnection *MemoryPlugin) getTagsInfo(Tags []string, Exclude bool, CollectionContext bool) ([]interfaces.TagInformation, error) {
+	//What we will return
+	var ToReturn []interfaces.TagInformation
+	if len(Tags) == 0 {
+		return ToReturn, nil
+	}
+
+	//First we handle meta tags
+	var NonMetaTags []string              //Tags will be set to this and used later on in code
+	TagCategories := make(map[string]string) //Category given as a prefix to a tag name, such as artist:name
+	for _, value := range Tags {
+		if strings.Contains(value, ":") {
+			MetaValue, Comparator := getTagComparator(strings.Split(value, ":")[1])
+			//A category followed by a name is a tag in that category, only category:* is a metatag
+			if interfaces.IsTagCategory(strings.Split(value, ":")[0]) && Comparator == "" && MetaValue != "" && MetaValue != "*" {
+				TagName := regexTagName.ReplaceAllString(MetaValue, "_")
+				TagCategories[TagName] = strings.Split(value, ":")[0]
+				NonMetaTags = append(NonMetaTags, TagName)
+				continue
+			}
+			if Comparator == "" {
+				Comparator = "="
+			}
+			ToAdd := interfaces.TagInformation{
+				Name:       strings.Split(value, ":")[0],
+				MetaValue:  MetaValue,
+				Comparator: Comparator,
+				Exclude:    Exclude,
+				IsMeta:     true}
+			ToReturn = append(ToReturn, ToAdd)
+		} else {
+			NonMetaTags = append(NonMetaTags, value)
+		}
+	}
+	//Parse meta tags further
+	//Need to ensure column names are correct, and values too
+	if len(ToReturn) > 0 {
+		ToReturn, _ = DBConnection.parseMetaTags(ToReturn, CollectionContext)
+	}
+
+	Tags = NonMetaTags
+	if len(Tags) <= 0 {
+		return ToReturn, nil
+	}
+
+	//Gather the tags that exist
+	for _, name := range Tags {
+		tag := DBConnection.getTagByName(name)
+		if tag == nil || tagsContainID(tag.ID, ToReturn) {
+			continue
+		}
+		ToReturn = append(ToReturn, interfaces.TagInformation{Name: tag.Name, ID: tag.ID, Description: tag.Description, Exists: true, Exclude: Exclude, UploaderID: tag.UploaderID, UploadTime: tag.UploadTime, AliasedID: tag.AliasedID, IsAlias: tag.IsAlias, Category: tag.Category})
+	}
+
+	//Add back in non-existant tags
+	for _, tag := range Tags {
+		if tagsContainName(tag, ToReturn) == false {
+			ToReturn = append(ToReturn, interfaces.TagInformation{
+				Name:     tag,
+				Exists:   false,
+				Exclude:  Exclude,
+				Category: TagCategories[tag]})
+		}
+	}
+
+	//Parse alaises
+	var AliasedIDs []uint64
+	for index := 0; index < len(ToReturn); index++ {
+		if ToReturn[index].IsAlias && tagsContainID(ToReturn[index].AliasedID, ToReturn) == false && uint64SliceContains(AliasedIDs, ToReturn[index].AliasedID) == false {
+			AliasedIDs = append(AliasedIDs, ToReturn[index].AliasedID)
+		}
+	}
+
+	//Add the aliased tags to ToReturn
+	for _, ID := range AliasedIDs {
+		tag, exists := DBConnection.tags[ID]
+		if exists == false {
+			continue
+		}
+		ToReturn = append(ToReturn, interfaces.TagInformation{Name: tag.Name, ID: tag.ID, Description: tag.Description, Exists: true, Exclude: Exclude, UploaderID: tag.UploaderID, UploadTime: tag.UploadTime, AliasedID: tag.AliasedID, IsAlias: tag.IsAlias, Category: tag.Category})
+	}
+
+	//Pass output
+	return ToReturn, nil
+}
+
+//parseMetaTags fills in additional information for MetaTags and vets out non-MetaTags
+func (DBConnection *MemoryPlugin) parseMetaTags(MetaTags []interfaces.TagInformation, CollectionContext bool) ([]interfaces.TagInformation, []error) {
+	var ToReturn []interfaces.TagInformation
+	var ErrorList []error
+	for _, tag := range MetaTags {
+		ToAdd := tag
+		switch {
+		//TODO: Add additional metatags here
+		case ToAdd.Name == "uploader":
+			ToAdd.Name = "UploaderID"
+			ToAdd.Description = "The uploaded of the image"
+			//Get uploader ID and set that to value
+			name, isString := ToAdd.MetaValue.(string)
+			if isString {
+				value, err := DBConnection.getUserID(name)
+				if err != nil {
+					ErrorList = append(ErrorList, err)
+				} else {
+					ToAdd.MetaValue = value
+					ToAdd.Exists = true
+				}
+				ToAdd.Comparator = "=" //Clobber any other comparator requested. This one will only support equals
+			} else {
+				ErrorList = append(ErrorList, errors.New("Could not convert metatag value to string as expected"))
+			}
+		case ToAdd.Name == "rating" && CollectionContext == false:
+			ToAdd.Name = "Rating"
+			ToAdd.Description = "The rating of the image"
+			ToAdd.Exists = true
+			ToAdd.Comparator = "=" //Clobber any other comparator requested. This one will only support equals
+			//Since rating is a string, no futher processing needed!
+		case ToAdd.Name == "score" && CollectionContext == false:
+			ToAdd.Name = "ScoreAverage"
+			ToAdd.Description = "The average voted score of the image"
+			sscore, isString := ToAdd.MetaValue.(string)
+			if isString {
+				score, err := strconv.ParseInt(sscore, 10, 64)
+				if err == nil {
+					ToAdd.MetaValue = score
+				}
+			}
+			//Must be an int64
+			_, isInt := ToAdd.MetaValue.(int64)
+			if isInt {
+				ToAdd.Exists = true
+			} else {
+				ErrorList = append(ErrorList, errors.New("could not parse requested score, ensure it is a number"))
+			}
+			//All comparators valid
+		case ToAdd.Name == "averagescore" && CollectionContext == false:
+			ToAdd.Name = "ScoreAverage"
+			ToAdd.Description = "The average voted score of the image"
+			sscore, isString := ToAdd.MetaValue.(string)
+			if isString {
+				score, err := strconv.ParseInt(sscore, 10, 64)
+				if err == nil {
+					ToAdd.MetaValue = score
+				}
+			}
+			//Must be an int64
+			_, isInt := ToAdd.MetaValue.(int64)
+			if isInt {
+				ToAdd.Exists = true
+			} else {
+				ErrorList = append(ErrorList, errors.New("could not parse requested score, ensure it is a number"))
+			}
+			//All comparators valid
+		case ToAdd.Name == "totalscore" && CollectionContext == false:
+			ToAdd.Name = "ScoreTotal"
+			ToAdd.Description = "The total sum of all voted scores for the image"
+			sscore, isString := ToAdd.MetaValue.(string)
+			if isString {
+				score, err := strconv.ParseInt(sscore, 10, 64)
+				if err == nil {
+					ToAdd.MetaValue = score
+				}
+			}
+			//Must be an int64
+			_, isInt := ToAdd.MetaValue.(int64)
+			if isInt {
+				ToAdd.Exists = true
+			} else {
+				ErrorList = append(ErrorList, errors.New("could not parse requested score, ensure it is a number"))
+			}
+			//All comparators valid
+		case ToAdd.Name == "scorevoters" && CollectionContext == false:
+			ToAdd.Name = "ScoreVoters"
+			ToAdd.Description = "The count of all users that voted on the image"
+			sscore, isString := ToAdd.MetaValue.(string)
+			if isString {
+				score, err := strconv.ParseInt(sscore, 10, 64)
+				if err == nil {
+					ToAdd.MetaValue = score
+				}
+			}
+			//Must be an int64
+			_, isInt := ToAdd.MetaValue.(int64)
+			if isInt {
+				ToAdd.Exists = true
+			} else {
+				ErrorList = append(ErrorList, errors.New("could not parse requested score, ensure it is a number"))
+			}
+			//All comparators valid
+		case ToAdd.Name == "fav" && CollectionContext == false:
+			ToAdd.Name = "Favorite"
+			ToAdd.Description = "Whether the user favorited the image"
+			ToAdd.IsComplexMeta = true
+			name, isString := ToAdd.MetaValue.(string)
+			if isString {
+				value, err := DBConnection.getUserID(name)
+				if err != nil {
+					//An unknown user has no favorites, so fav:nobody finds nothing rather than being ignored
+					ErrorList = append(ErrorList, err)
+					value = 0
+				}
+				ToAdd.MetaValue = value
+				ToAdd.Exists = true
+			} else {
+				ErrorList = append(ErrorList, errors.New("could not parse fav tag"))
+			}
+			ToAdd.Comparator = "=" //Clobber any other comparator requested. This one will only support equals
+		case ToAdd.Name == "favcount" && CollectionContext == false:
+			ToAdd.Name = "FavoriteCount"
+			ToAdd.Description = "The count of all users that favorited the image"
+			ToAdd.IsComplexMeta = true
+			stringValue, isString := ToAdd.MetaValue.(string)
+			if isString {
+				count, err := strconv.ParseInt(stringValue, 10, 64)
+				if err == nil {
+					ToAdd.Exists = true
+					ToAdd.MetaValue = count
+				} else {
+					ErrorList = append(ErrorList, errors.New("could not parse requested favorite count, ensure it is a number"))
+				}
+			} else {
+				ErrorList = append(ErrorList, errors.New("could not parse favcount tag"))
+			}
+			//All comparators valid
+		case ToAdd.Name == "commentcount":
+			ToAdd.Name = "CommentCount"
+			ToAdd.Description = "The count of comments that are not deleted"
+			ToAdd.IsComplexMeta = true
+			stringValue, isString := ToAdd.MetaValue.(string)
+			if isString {
+				count, err := strconv.ParseInt(stringValue, 10, 64)
+				if err == nil {
+					ToAdd.Exists = true
+					ToAdd.MetaValue = count
+				} else {
+					ErrorList = append(ErrorList, errors.New("could not parse requested comment count, ensure it is a number"))
+				}
+			} else {
+				ErrorList = append(ErrorList, errors.New("could not parse commentcount tag"))
+			}
+			//All comparators valid
+		case ToAdd.Name == "hasnotes" && CollectionContext == false:
+			ToAdd.Name = "HasNotes"
+			ToAdd.Description = "Whether the image has notes or not"
+			ToAdd.IsComplexMeta = true
+			hasNotesOption, isString := ToAdd.MetaValue.(string)
+			if isString {
+				if hasNotesOption == "Y" || hasNotesOption == "y" || hasNotesOption == "true" {
+					ToAdd.MetaValue = true
+					ToAdd.Exists = true
+				} else if hasNotesOption == "N" || hasNotesOption == "n" || hasNotesOption == "false" {
+					ToAdd.MetaValue = false
+					ToAdd.Exists = true
+				} else {
+					ErrorList = append(ErrorList, errors.New("could not parse hasnotes tag"))
+				}
+			} else {
+				ErrorList = append(ErrorList, errors.New("could not parse hasnotes tag"))
+			}
+			ToAdd.Comparator = "=" //Clobber any other comparator requested. This one will only support equals
+		case ToAdd.Name == "incollection" && CollectionContext == false:
+			ToAdd.Name = "InCollection"
+			ToAdd.Description = "Whether the image is in a collection or not"
+			ToAdd.IsComplexMeta = true
+			inCollOption, isString := ToAdd.MetaValue.(string)
+			if isString {
+				if inCollOption == "Y" || inCollOption == "y" || inCollOption == "true" {
+					ToAdd.MetaValue = true
+					ToAdd.Exists = true
+				} else if inCollOption == "N" || inCollOption == "n" || inCollOption == "false" {
+					ToAdd.MetaValue = false
+					ToAdd.Exists = true
+				} else {
+					ErrorList = append(ErrorList, errors.New("could not parse incollection tag"))
+				}
+			} else {
+				ErrorList = append(ErrorList, errors.New("could not parse incollection tag"))
+			}
+			ToAdd.Comparator = "=" //Clobber any other comparator requested. This one will only support equals
+		case ToAdd.Name == "tagcount" && CollectionContext == false:
+			ToAdd.Name = "TagCount"
+			ToAdd.Description = "Number of tags an image has"
+			ToAdd.IsComplexMeta = true
+			stringValue, isString := ToAdd.MetaValue.(string)
+			if isString {
+				countValue, err := strconv.ParseInt(stringValue, 10, 64)
+				if err == nil {
+					ToAdd.Exists = true
+					ToAdd.MetaValue = strconv.FormatInt(countValue, 10)
+				}
+			} else {
+				ErrorList = append(ErrorList, errors.New("could not parse tagcount tag"))
+			}
+		case ToAdd.Name == "similar" && CollectionContext == false:
+			ToAdd.Name = "Similar"
+			ToAdd.Description = "Show images similar to the id specified"
+			ToAdd.IsComplexMeta = true
+			stringValue, isString := ToAdd.MetaValue.(string)
+			ToAdd.Comparator = "<=" //Only return results less than or equal to threshold
+			if isString {
+				//First handle similarity if needed
+				SimilarityThreshold := uint64(26) //At 128 bits, 26 is 20%...ish
+				stringComponents := strings.Split(stringValue, "-")
+				if len(stringComponents) == 2 {
+					newSimilarity, err := strconv.ParseUint(stringComponents[0], 10, 64)
+					if err != nil {
+						ErrorList = append(ErrorList, errors.New("error parsing similarity threshold for similarity tag"))
+						break
+					}
+					stringValue = stringComponents[1]
+					SimilarityThreshold = newSimilarity
+				} else if len(stringComponents) != 1 {
+					ErrorList = append(ErrorList, errors.New("could not parse similar tag"))
+					break
+				}
+				//Then id value
+				idValue, err := strconv.ParseUint(stringValue, 10, 64)
+				if err == nil {
+					hHash, vHash, err := DBConnection.getImagedHash(idValue)
+					if err == nil {
+						ToAdd.Exists = true
+						ToAdd.MetaValue = interfaces.ImagedHash{ImagehHash: hHash, ImagevHash: vHash, SimilarityThreshold: SimilarityThreshold}
+					} else {
+						ErrorList = append(ErrorList, errors.New("internal error occured querying database for similar"))
+					}
+				} else {
+					ErrorList = append(ErrorList, errors.New("could not find requested image for similar tag"))
+				}
+			} else {
+				ErrorList = append(ErrorList, errors.New("could not parse similar tag"))
+			}
+		case ToAdd.Name == "order" && CollectionContext == false:
+			ToAdd.Name = "Order"
+			ToAdd.Description = "The order images are sorted in"
+			ToAdd.IsComplexMeta = true
+			stringValue, isString := ToAdd.MetaValue.(string)
+			if isString {
+				order, err := tagquery.ParseImageOrder(stringValue)
+				if err == nil {
+					ToAdd.Exists = true
+					ToAdd.MetaValue = order
+				} else {
+					ErrorList = append(ErrorList, err)
+				}
+			} else {
+				ErrorList = append(ErrorList, errors.New("could not parse order tag"))
+			}
+			ToAdd.Comparator = "=" //Clobber any other comparator requested. Orders are not compared
+		case (ToAdd.Name == "width" || ToAdd.Name == "height") && CollectionContext == false:
+			if ToAdd.Name == "width" {
+				ToAdd.Name = "Width"
+				ToAdd.Description = "The width of the image, in pixels"
+			} else {
+				ToAdd.Name = "Height"
+				ToAdd.Description = "The height of the image, in pixels"
+			}
+			stringValue, isString := ToAdd.MetaValue.(string)
+			if isString {
+				size, err := strconv.ParseInt(stringValue, 10, 64)
+				if err == nil {
+					ToAdd.Exists = true
+					ToAdd.MetaValue = size
+				} else {
+					ErrorList = append(ErrorList, errors.New("could not parse requested size, ensure it is a number"))
+				}
+			} else {
+				ErrorList = append(ErrorList, errors.New("could not parse size tag"))
+			}
+			//All comparators valid
+		case ToAdd.Name == "ratio" && CollectionContext == false:
+			ToAdd.Name = "Ratio"
+			ToAdd.Description = "The width of the image divided by its height"
+			ToAdd.IsComplexMeta = true
+			stringValue, isString := ToAdd.MetaValue.(string)
+			if isString {
+				ratio, err := tagquery.ParseRatio(stringValue)
+				if err == nil {
+					ToAdd.Exists = true
+					ToAdd.MetaValue = ratio
+				} else {
+					ErrorList = append(ErrorList, err)
+				}
+			} else {
+				ErrorList = append(ErrorList, errors.New("could not parse ratio tag"))
+			}
+			//All comparators valid
+		case ToAdd.Name == "filesize" && CollectionContext == false:
+			ToAdd.Name = "FileSize"
+			ToAdd.Description = "The size of the file, in bytes"
+			stringValue, isString := ToAdd.MetaValue.(string)
+			if isString {
+				size, err := tagquery.ParseFileSize(stringValue)
+				if err == nil {
+					ToAdd.Exists = true
+					ToAdd.MetaValue = size
+				} else {
+					ErrorList = append(ErrorList, err)
+				}
+			} else {
+				ErrorList = append(ErrorList, errors.New("could not parse filesize tag"))
+			}
+			//All comparators valid
+		case ToAdd.Name == "duration" && CollectionContext == false:
+			ToAdd.Name = "Duration"
+			ToAdd.Description = "The length of a video or audio file, in seconds"
+			stringValue, isString := ToAdd.MetaValue.(string)
+			if isString {
+				seconds, err := tagquery.ParseSeconds(stringValue)
+				if err == nil {
+					ToAdd.Exists = true
+					ToAdd.MetaValue = seconds
+				} else {
+					ErrorList = append(ErrorList, err)
+				}
+			} else {
+				ErrorList = append(ErrorList, errors.New("could not parse duration tag"))
+			}
+			//All comparators valid
+		case ToAdd.Name == "type" && CollectionContext == false:
+			ToAdd.Name = "MIMEType"
+			ToAdd.Description = "The MIME type of the file"
+			stringValue, isString := ToAdd.MetaValue.(string)
+			if isString {
+				pattern, err := tagquery.MIMETypePattern(stringValue)
+				if err == nil {
+					ToAdd.Exists = true
+					ToAdd.MetaValue = pattern
+				} else {
+					ErrorList = append(ErrorList, err)
+				}
+			} else {
+				ErrorList = append(ErrorList, errors.New("could not parse type tag"))
+			}
+			ToAdd.Comparator = "LIKE" //Clobber any other comparator requested. This one will only support LIKE
+		case ToAdd.Name == "uploaded" || ToAdd.Name == "tagged" || ToAdd.Name == "voted":
+			switch ToAdd.Name {
+			case "uploaded":
+				ToAdd.Name = "Uploaded"
+				ToAdd.Description = "When the item was uploaded"
+			case "tagged":
+				ToAdd.Name = "Tagged"
+				ToAdd.Description = "When a tag was added to the item"
+			default:
+				ToAdd.Name = "Voted"
+				ToAdd.Description = "When a vote was cast on the item"
+			}
+			ToAdd.IsComplexMeta = true
+			stringValue, isString := ToAdd.MetaValue.(string)
+			if isString {
+				timeRange, comparator, err := tagquery.ParseTimeRange(stringValue, ToAdd.Comparator, time.Now())
+				if err == nil {
+					ToAdd.Exists = true
+					ToAdd.MetaValue = timeRange
+					ToAdd.Comparator = comparator
+				} else {
+					ErrorList = append(ErrorList, err)
+				}
+			} else {
+				ErrorList = append(ErrorList, errors.New("could not parse date tag"))
+			}
+			//All comparators valid
+		case ToAdd.Name == "name":
+			ToAdd.Name = "Name"
+			ToAdd.Description = "Name of the item"
+			ToAdd.IsComplexMeta = false
+			inCollOption, isString := ToAdd.MetaValue.(string)
+			if isString {
+
+				//This chunk is ugly, but allows us to escape spaces //TODO: This is stupid and needs fixing, and a dedicated function to do so
+				inCollOption = strings.Replace(inCollOption, "--", "#", -1) //Placeholder for dash
+				inCollOption = strings.Replace(inCollOption, "-_", "$", -1) //Placeholder for underscore
+				inCollOption = strings.Replace(inCollOption, "__", " ", -1)
+				inCollOption = strings.Replace(inCollOption, "#", "-", -1)
+				inCollOption = strings.Replace(inCollOption, "_", "$", -1)
+				inCollOption = strings.Replace(inCollOption, "$", "\\_", -1)
+				if len(inCollOption) > 3 {
+					ToAdd.MetaValue = "%" + inCollOption + "%"
+					ToAdd.Exists = true
+				} else {
+					ErrorList = append(ErrorList, errors.New("could not parse name tag, please lengthen your query"))
+				}
+			} else {
+				ErrorList = append(ErrorList, errors.New("could not parse name tag"))
+			}
+			ToAdd.Comparator = "LIKE" //Clobber any other comparator requested. This one will only support LIKE
+		case ToAdd.Name == "location" && CollectionContext == false:
+			ToAdd.Name = "Location"
+			ToAdd.Description = "The item's file location/name"
+			ToAdd.IsComplexMeta = false
+			inCollOption, isString := ToAdd.MetaValue.(string)
+			if isString {
+				//This chunk is ugly, but allows us to escape spaces
+				inCollOption = strings.Replace(inCollOption, "--", "#", -1) //Placeholder for dash
+				inCollOption = strings.Replace(inCollOption, "-_", "$", -1) //Placeholder for underscore
+				inCollOption = strings.Replace(inCollOption, "__", " ", -1)
+				inCollOption = strings.Replace(inCollOption, "#", "-", -1)
+				inCollOption = strings.Replace(inCollOption, "_", "$", -1)
+				inCollOption = strings.Replace(inCollOption, "$", "\\_", -1)
+				if len(inCollOption) > 3 {
+					ToAdd.MetaValue = "%" + inCollOption + "%"
+					ToAdd.Exists = true
+				} else {
+					ErrorList = append(ErrorList, errors.New("could not parse filename tag, please lengthen your query"))
+				}
+			} else {
+				ErrorList = append(ErrorList, errors.New("could not parse filename tag"))
+			}
+			ToAdd.Comparator = "LIKE" //Clobber any other comparator requested. This one will only support LIKE
+		case interfaces.IsTagCategory(ToAdd.Name) && CollectionContext == false:
+			Category := ToAdd.Name
+			ToAdd.Name = "CategoryTags"
+			ToAdd.Description = "Whether the image has a tag in the category"
+			ToAdd.IsComplexMeta = true
+			if ToAdd.MetaValue == "*" {
+				ToAdd.MetaValue = interfaces.TagCategoryCount{Category: Category, Count: 0}
+				ToAdd.Exists = true
+			} else {
+				ErrorList = append(ErrorList, errors.New("could not parse category tag, use category:* or categorytags:count"))
+			}
+			ToAdd.Comparator = ">" //Clobber any other comparator requested. Matches more than zero tags in the category
+		case strings.HasSuffix(ToAdd.Name, "tags") && interfaces.IsTagCategory(strings.TrimSuffix(ToAdd.Name, "tags")) && CollectionContext == false:
+			Category := strings.TrimSuffix(ToAdd.Name, "tags")
+			ToAdd.Name = "CategoryTags"
+			ToAdd.Description = "Number of tags in the category an image has"
+			ToAdd.IsComplexMeta = true
+			stringValue, isString := ToAdd.MetaValue.(string)
+			if isString {
+				countValue, err := strconv.ParseInt(stringValue, 10, 64)
+				if err == nil {
+					ToAdd.Exists = true
+					ToAdd.MetaValue = interfaces.TagCategoryCount{Category: Category, Count: countValue}
+				} else {
+					ErrorList = append(ErrorList, errors.New("could not parse category tag count, ensure it is a number"))
+				}
+			} else {
+				ErrorList = append(ErrorList, errors.New("could not parse category tag count"))
+			}
+			//All comparators valid
+		default:
+			ErrorList = append(ErrorList, errors.New("MetaTag does not exist"))
+		}
+		ToReturn = append(ToReturn, ToAdd)
+	}
+	return ToReturn, ErrorList
+}
@@ -0,0 +1,163 @@
+package memoryplugin
+
+import (
+	"errors"
+	"go-image-board/interfaces"
+	"go-image-board/logging"
+	"sort"
+	"strconv"
+	"time"
+)
+
+//TrashImage moves an image to the trash, hiding it from searches until it is restored or purged
+func (DBConnection *MemoryPlugin) TrashImage(ImageID uint64, DeleterID uint64) error {
+	DBConnection.dbMutex.Lock()
+	defer DBConnection.dbMutex.Unlock()
+	image, exists := DBConnection.images[ImageID]
+	if exists == false || image.DeletedTime.IsZero() == false {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/TrashImage", strconv.FormatUint(DeleterID, 10), logging.ResultFailure, []string{"Failed to trash image", strconv.FormatUint(ImageID, 10)})
+		return errors.New("image does not exist or is already in the trash")
+	}
+	image.DeletedTime = time.Now()
+	image.DeleterID = DeleterID
+	logging.WriteLog(logging.LogLevelInfo, "MemoryPlugin/TrashImage", strconv.FormatUint(DeleterID, 10), logging.ResultSuccess, []string{"Image moved to trash", strconv.FormatUint(ImageID, 10)})
+	return nil
+}
+
+//RestoreImage returns an image from the trash
+func (DBConnection *MemoryPlugin) RestoreImage(ImageID uint64) error {
+	DBConnection.dbMutex.Lock()
+	defer DBConnection.dbMutex.Unlock()
+	image, exists := DBConnection.images[ImageID]
+	if exists == false || image.DeletedTime.IsZero() {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/RestoreImage", "0", logging.ResultFailure, []string{"Failed to restore image", strconv.FormatUint(ImageID, 10)})
+		return errors.New("image does not exist or is not in the trash")
+	}
+	image.DeletedTime = time.Time{}
+	image.DeleterID = 0
+	logging.WriteLog(logging.LogLevelInfo, "MemoryPlugin/RestoreImage", "0", logging.ResultSuccess, []string{"Image restored from trash", strconv.FormatUint(ImageID, 10)})
+	return nil
+}
+
+//TrashCollection moves a collection to the trash, and if IncludeMembers is set, every member not already in the trash.
+//Members share the collection's DeletedTime, which is how RestoreCollection finds them again
+func (DBConnection *MemoryPlugin) TrashCollection(CollectionID uint64, DeleterID uint64, IncludeMembers bool) error {
+	DBConnection.dbMutex.Lock()
+	defer DBConnection.dbMutex.Unlock()
+	collection, exists := DBConnection.collections[CollectionID]
+	if exists == false || collection.DeletedTime.IsZero() == false {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/TrashCollection", strconv.FormatUint(DeleterID, 10), logging.ResultFailure, []string{"Failed to trash collection", strconv.FormatUint(CollectionID, 10)})
+		return errors.New("collection does not exist or is already in the trash")
+	}
+	collection.DeletedTime = time.Now()
+	collection.DeleterID = DeleterID
+	if IncludeMembers {
+		for _, member := range DBConnection.getCollectionMemberRows(CollectionID) {
+			if image := DBConnection.images[member.ImageID]; image.DeletedTime.IsZero() {
+				image.DeletedTime = collection.DeletedTime
+				image.DeleterID = DeleterID
+			}
+		}
+	}
+	logging.WriteLog(logging.LogLevelInfo, "MemoryPlugin/TrashCollection", strconv.FormatUint(DeleterID, 10), logging.ResultSuccess, []string{"Collection moved to trash", strconv.FormatUint(CollectionID, 10)})
+	return nil
+}
+
+//RestoreCollection returns a collection from the trash, along with the members that were trashed with it
+func (DBConnection *MemoryPlugin) RestoreCollection(CollectionID uint64) error {
+	DBConnection.dbMutex.Lock()
+	defer DBConnection.dbMutex.Unlock()
+	collection, exists := DBConnection.collections[CollectionID]
+	if exists == false || collection.DeletedTime.IsZero() {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/RestoreCollection", "0", logging.ResultFailure, []string{"Failed to restore collection", strconv.FormatUint(CollectionID, 10)})
+		return errors.New("collection does not exist or is not in the trash")
+	}
+	for _, member := range DBConnection.getCollectionMemberRows(CollectionID) {
+		if image := DBConnection.images[member.ImageID]; image.DeletedTime.Equal(collection.DeletedTime) && image.DeleterID == collection.DeleterID {
+			image.DeletedTime = time.Time{}
+			image.DeleterID = 0
+		}
+	}
+	collection.DeletedTime = time.Time{}
+	collection.DeleterID = 0
+	logging.WriteLog(logging.LogLevelInfo, "MemoryPlugin/RestoreCollection", "0", logging.ResultSuccess, []string{"Collection restored from trash", strconv.FormatUint(CollectionID, 10)})
+	return nil
+}
+
+//GetTrashedImages returns images in the trash, most recently deleted first, and the count of all images in the trash
+func (DBConnection *MemoryPlugin) GetTrashedImages(PageStart uint64, PageStride uint64) ([]interfaces.ImageInformation, uint64, error) {
+	DBConnection.dbMutex.RLock()
+	defer DBConnection.dbMutex.RUnlock()
+	var ToReturn []interfaces.ImageInformation
+	trashed := DBConnection.trashedImages(time.Time{})
+	start, end := pageBounds(len(trashed), PageStart, PageStride)
+	for _, image := range trashed[start:end] {
+		ToReturn = append(ToReturn, DBConnection.getImageInformation(image))
+	}
+	return ToReturn, uint64(len(trashed)), nil
+}
+
+//GetTrashedCollections returns collections in the trash, most recently deleted first, and the count of all collections in the trash
+func (DBConnection *MemoryPlugin) GetTrashedCollections(PageStart uint64, PageStride uint64) ([]interfaces.CollectionInformation, uint64, error) {
+	DBConnection.dbMutex.RLock()
+	defer DBConnection.dbMutex.RUnlock()
+	var ToReturn []interfaces.CollectionInformation
+	trashed := DBConnection.trashedCollections(time.Time{})
+	start, end := pageBounds(len(trashed), PageStart, PageStride)
+	for _, collection := range trashed[start:end] {
+		collectionInfo := DBConnection.getCollectionInformation(collection)
+		collectionInfo.Location = DBConnection.getCollectionPreview(DBConnection.getCollectionMemberRows(collection.ID))
+		ToReturn = append(ToReturn, collectionInfo)
+	}
+	return ToReturn, uint64(len(trashed)), nil
+}
+
+//GetExpiredTrash returns every image and collection that has been in the trash for longer than RetentionPeriod
+func (DBConnection *MemoryPlugin) GetExpiredTrash(RetentionPeriod time.Duration) ([]interfaces.ImageInformation, []interfaces.CollectionInformation, error) {
+	DBConnection.dbMutex.RLock()
+	defer DBConnection.dbMutex.RUnlock()
+	var Images []interfaces.ImageInformation
+	var Collections []interfaces.CollectionInformation
+	cutoff := time.Now().Add(-RetentionPeriod)
+	for _, image := range DBConnection.trashedImages(cutoff) {
+		Images = append(Images, DBConnection.getImageInformation(image))
+	}
+	for _, collection := range DBConnection.trashedCollections(cutoff) {
+		Collections = append(Collections, DBConnection.getCollectionInformation(collection))
+	}
+	return Images, Collections, nil
+}
+
+//trashedImages returns the images in the trash, most recently deleted first. If Before is set, only those deleted before it are returned
+func (DBConnection *MemoryPlugin) trashedImages(Before time.Time) []*memoryImage {
+	var ToReturn []*memoryImage
+	for _, image := range DBConnection.images {
+		if image.DeletedTime.IsZero() == false && (Before.IsZero() || image.DeletedTime.Before(Before)) {
+			ToReturn = append(ToReturn, image)
+		}
+	}
+	sort.Slice(ToReturn, func(i, j int) bool {
+		if ToReturn[i].DeletedTime.Equal(ToReturn[j].DeletedTime) {
+			return ToReturn[i].ID > ToReturn[j].ID
+		}
+		return ToReturn[i].DeletedTime.After(ToReturn[j].DeletedTime)
+	})
+	return ToReturn
+}
+
+//trashedCollections returns the collections in the trash, most recently deleted first. If Before is set, only those deleted before it are returned
+func (DBConnection *MemoryPlugin) trashedCollections(Before time.Time) []*memoryCollection {
+	var ToReturn []*memoryCollection
+	for _, collection := range DBConnection.collections {
+		if collection.DeletedTime.IsZero() == false && (Before.IsZero() || collection.DeletedTime.Before(Before)) {
+			ToReturn = append(ToReturn, collection)
+		}
+	}
+	sort.Slice(ToReturn, func(i, j int) bool {
+		if ToReturn[i].DeletedTime.Equal(ToReturn[j].DeletedTime) {
+			return ToReturn[i].ID > ToReturn[j].ID
+		}
+		return ToReturn[i].DeletedTime.After(ToReturn[j].DeletedTime)
+	})
+	return ToReturn
+}
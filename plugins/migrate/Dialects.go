@@ -0,0 +1,85 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+)
+
+//Dialect describes the database specific parts of running migrations
+type Dialect struct {
+	//HistoryTable creates the SchemaMigrations table if it does not already exist
+	HistoryTable string
+	//TryLock attempts to take the migration lock on conn, returning false if another instance holds it
+	TryLock func(ctx context.Context, conn *sql.Conn) (bool, error)
+	//Unlock releases the migration lock taken by TryLock
+	Unlock func(ctx context.Context, conn *sql.Conn) error
+	//StaleLockHint tells an operator how to clear a lock left behind by a crashed instance
+	StaleLockHint string
+	//TransactionalDDL is true when schema changes can be rolled back, allowing each migration to run in a single transaction
+	TransactionalDDL bool
+	//Placeholder returns the nth (1 based) bind parameter, nil if the database uses ?
+	Placeholder func(n int) string
+}
+
+//lockName is the name of the MariaDB user lock held while migrating
+const lockName = "gib_schema_migrations"
+
+//advisoryLockKey is the Postgres advisory lock key held while migrating, the value has no meaning beyond being unique to gib
+const advisoryLockKey int64 = 0x676962
+
+//MariaDB dialect, DDL implicitly commits so progress is tracked per statement
+var MariaDB = Dialect{
+	HistoryTable: "CREATE TABLE IF NOT EXISTS SchemaMigrations (Version BIGINT UNSIGNED NOT NULL PRIMARY KEY, Description VARCHAR(255) NOT NULL, Checksum CHAR(64) NOT NULL, Status VARCHAR(20) NOT NULL, AppliedSteps INT NOT NULL DEFAULT 0, Error TEXT, AppliedTime TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL);",
+	TryLock: func(ctx context.Context, conn *sql.Conn) (bool, error) {
+		var locked sql.NullInt64
+		err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, 0)", lockName).Scan(&locked)
+		return locked.Valid && locked.Int64 == 1, err
+	},
+	Unlock: func(ctx context.Context, conn *sql.Conn) error {
+		var released sql.NullInt64
+		return conn.QueryRowContext(ctx, "SELECT RELEASE_LOCK(?)", lockName).Scan(&released)
+	},
+	StaleLockHint: "MariaDB releases the lock when the holding connection closes",
+}
+
+//Postgres dialect, DDL is transactional so each migration is all or nothing
+var Postgres = Dialect{
+	HistoryTable: "CREATE TABLE IF NOT EXISTS SchemaMigrations (Version BIGINT NOT NULL PRIMARY KEY, Description VARCHAR(255) NOT NULL, Checksum CHAR(64) NOT NULL, Status VARCHAR(20) NOT NULL, AppliedSteps INT NOT NULL DEFAULT 0, Error TEXT, AppliedTime TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP NOT NULL);",
+	TryLock: func(ctx context.Context, conn *sql.Conn) (bool, error) {
+		var locked bool
+		err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", advisoryLockKey).Scan(&locked)
+		return locked, err
+	},
+	Unlock: func(ctx context.Context, conn *sql.Conn) error {
+		var released bool
+		return conn.QueryRowContext(ctx, "SELECT pg_advisory_unlock($1)", advisoryLockKey).Scan(&released)
+	},
+	StaleLockHint:    "Postgres releases the lock when the holding connection closes",
+	TransactionalDDL: true,
+	Placeholder: func(n int) string {
+		return "$" + strconv.Itoa(n)
+	},
+}
+
+//SQLite dialect, DDL is transactional, but there are no session locks so a single row lock table is used instead
+var SQLite = Dialect{
+	HistoryTable: "CREATE TABLE IF NOT EXISTS SchemaMigrations (Version INTEGER NOT NULL PRIMARY KEY, Description VARCHAR(255) NOT NULL, Checksum CHAR(64) NOT NULL, Status VARCHAR(20) NOT NULL, AppliedSteps INTEGER NOT NULL DEFAULT 0, Error TEXT, AppliedTime DATETIME DEFAULT CURRENT_TIMESTAMP NOT NULL);",
+	TryLock: func(ctx context.Context, conn *sql.Conn) (bool, error) {
+		if _, err := conn.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS SchemaMigrationLock (ID INTEGER NOT NULL PRIMARY KEY, LockTime DATETIME DEFAULT CURRENT_TIMESTAMP NOT NULL);"); err != nil {
+			return false, err
+		}
+		result, err := conn.ExecContext(ctx, "INSERT OR IGNORE INTO SchemaMigrationLock (ID) VALUES (1);")
+		if err != nil {
+			return false, err
+		}
+		inserted, err := result.RowsAffected()
+		return inserted == 1, err
+	},
+	Unlock: func(ctx context.Context, conn *sql.Conn) error {
+		_, err := conn.ExecContext(ctx, "DELETE FROM SchemaMigrationLock WHERE ID = 1;")
+		return err
+	},
+	StaleLockHint:    "If no other instance is running, a previous run may have crashed, remove the row from SchemaMigrationLock",
+	TransactionalDDL: true,
+}
@@ -0,0 +1,438 @@
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go-image-board/logging"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//DryRun when set, Run prints pending migrations instead of applying them
+var DryRun bool
+
+//ErrDryRun is returned by Run after printing pending migrations when DryRun is set
+var ErrDryRun = errors.New("migration dry run requested, no changes were applied")
+
+//LockTimeout is how long Run waits for another instance to finish migrating before giving up
+var LockTimeout = 5 * time.Minute
+
+const (
+	statusApplied = "applied"
+	statusRunning = "running"
+	statusFailed  = "failed"
+)
+
+//Migration is a single numbered schema change
+type Migration struct {
+	//Version orders migrations, it must be unique within a registry and greater than zero
+	Version int64
+	//Description is a short summary of the change, recorded in the history table
+	Description string
+	//Statements are executed in order, one Exec each
+	Statements []string
+}
+
+//Checksum returns a sha256 of the statements, used to detect a migration that was edited after being applied
+func (migration Migration) Checksum() string {
+	hash := sha256.New()
+	for _, statement := range migration.Statements {
+		hash.Write([]byte(statement))
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+//Registry holds the migrations for a single database plugin
+type Registry struct {
+	migrations []Migration
+}
+
+//Register adds a migration to the registry, it panics on an invalid or duplicate version as that is a programming error
+func (registry *Registry) Register(migration Migration) {
+	if migration.Version <= 0 {
+		panic("migration version must be greater than zero: " + strconv.FormatInt(migration.Version, 10))
+	}
+	if len(migration.Statements) == 0 {
+		panic("migration " + strconv.FormatInt(migration.Version, 10) + " has no statements")
+	}
+	for _, existing := range registry.migrations {
+		if existing.Version == migration.Version {
+			panic("duplicate migration version: " + strconv.FormatInt(migration.Version, 10))
+		}
+	}
+	registry.migrations = append(registry.migrations, migration)
+	sort.Slice(registry.migrations, func(i, j int) bool { return registry.migrations[i].Version < registry.migrations[j].Version })
+}
+
+//Migrations returns the registered migrations ordered by version
+func (registry *Registry) Migrations() []Migration {
+	return registry.migrations
+}
+
+//Latest returns the highest registered version, or 0 if nothing is registered
+func (registry *Registry) Latest() int64 {
+	if len(registry.migrations) == 0 {
+		return 0
+	}
+	return registry.migrations[len(registry.migrations)-1].Version
+}
+
+//historyEntry is a row of the SchemaMigrations table
+type historyEntry struct {
+	Version      int64
+	Checksum     string
+	Status       string
+	AppliedSteps int
+}
+
+//pendingMigration is a migration to run, and the statement to start from when resuming a failed migration
+type pendingMigration struct {
+	Migration Migration
+	StartStep int
+}
+
+//execer is satisfied by both sql.Conn and sql.Tx
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+//Runner applies the migrations in a registry to a database
+type Runner struct {
+	DB       *sql.DB
+	Dialect  Dialect
+	Registry *Registry
+	//FreshInstall, if set, creates the latest schema directly on an empty database, after which every migration is recorded as applied.
+	//It is given the connection holding the migration lock, and is run again on the next start if it fails, so must be safe to repeat.
+	//If nil, an empty database is built by running every migration from the first.
+	FreshInstall func(ctx context.Context, conn *sql.Conn) error
+	//Output receives the dry run output, os.Stdout if nil
+	Output io.Writer
+}
+
+//Run brings the database up to the latest registered migration
+func (runner *Runner) Run() error {
+	ctx := context.Background()
+	//A single connection is used throughout, as MariaDB and Postgres locks belong to the session that took them
+	conn, err := runner.DB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if DryRun {
+		return runner.dryRun(ctx, conn)
+	}
+
+	if err := runner.lock(ctx, conn); err != nil {
+		logging.WriteLog(logging.LogLevelError, "Migrate/Run", "0", logging.ResultFailure, []string{"Failed to take migration lock", err.Error()})
+		return err
+	}
+	defer func() {
+		if err := runner.Dialect.Unlock(ctx, conn); err != nil {
+			logging.WriteLog(logging.LogLevelError, "Migrate/Run", "0", logging.ResultFailure, []string{"Failed to release migration lock", err.Error()})
+		}
+	}()
+
+	if _, err := conn.ExecContext(ctx, runner.Dialect.HistoryTable); err != nil {
+		logging.WriteLog(logging.LogLevelError, "Migrate/Run", "0", logging.ResultFailure, []string{"Failed to create migration history table", err.Error()})
+		return err
+	}
+	history, err := runner.loadHistory(ctx, conn)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "Migrate/Run", "0", logging.ResultFailure, []string{"Failed to read migration history", err.Error()})
+		return err
+	}
+	if len(history) == 0 {
+		if err := runner.baseline(ctx, conn); err != nil {
+			return err
+		}
+		if history, err = runner.loadHistory(ctx, conn); err != nil {
+			logging.WriteLog(logging.LogLevelError, "Migrate/Run", "0", logging.ResultFailure, []string{"Failed to read migration history", err.Error()})
+			return err
+		}
+	}
+
+	pending, err := runner.pending(history)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "Migrate/Run", "0", logging.ResultFailure, []string{err.Error()})
+		return err
+	}
+	for _, next := range pending {
+		if err := runner.apply(ctx, conn, next); err != nil {
+			return err
+		}
+	}
+	logging.WriteLog(logging.LogLevelInfo, "Migrate/Run", "0", logging.ResultInfo, []string{"Database schema is at version " + strconv.FormatInt(runner.Registry.Latest(), 10)})
+	return nil
+}
+
+//lock waits until the migration lock is acquired or LockTimeout passes
+func (runner *Runner) lock(ctx context.Context, conn *sql.Conn) error {
+	deadline := time.Now().Add(LockTimeout)
+	for {
+		locked, err := runner.Dialect.TryLock(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if locked {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return errors.New("timed out waiting for another instance to finish migrating. " + runner.Dialect.StaleLockHint)
+		}
+		logging.WriteLog(logging.LogLevelInfo, "Migrate/lock", "0", logging.ResultInfo, []string{"Another instance is migrating the database, waiting"})
+		time.Sleep(time.Second)
+	}
+}
+
+//loadHistory reads the history table, ordered by version
+func (runner *Runner) loadHistory(ctx context.Context, conn *sql.Conn) ([]historyEntry, error) {
+	var ToReturn []historyEntry
+	rows, err := conn.QueryContext(ctx, "SELECT Version, Checksum, Status, AppliedSteps FROM SchemaMigrations ORDER BY Version")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var entry historyEntry
+		if err := rows.Scan(&entry.Version, &entry.Checksum, &entry.Status, &entry.AppliedSteps); err != nil {
+			return nil, err
+		}
+		ToReturn = append(ToReturn, entry)
+	}
+	return ToReturn, rows.Err()
+}
+
+//legacyVersion reads the single row DBVersion table used before the migration history existed
+func (runner *Runner) legacyVersion(ctx context.Context, conn *sql.Conn) (int64, error) {
+	var version int64
+	err := conn.QueryRowContext(ctx, "SELECT version FROM DBVersion").Scan(&version)
+	return version, err
+}
+
+//baseline populates an empty history table, either from the legacy DBVersion table or by performing a fresh install
+func (runner *Runner) baseline(ctx context.Context, conn *sql.Conn) error {
+	version, err := runner.legacyVersion(ctx, conn)
+	if err == nil {
+		logging.WriteLog(logging.LogLevelInfo, "Migrate/baseline", "0", logging.ResultInfo, []string{"Recording migrations up to legacy DBVersion " + strconv.FormatInt(version, 10)})
+		if err := runner.recordApplied(ctx, conn, version); err != nil {
+			return err
+		}
+		if _, err := conn.ExecContext(ctx, "DROP TABLE DBVersion"); err != nil {
+			logging.WriteLog(logging.LogLevelError, "Migrate/baseline", "0", logging.ResultFailure, []string{"Failed to remove legacy DBVersion table", err.Error()})
+			return err
+		}
+		return nil
+	}
+	if runner.FreshInstall == nil {
+		//Nothing to record, every migration will run from the first
+		return nil
+	}
+	logging.WriteLog(logging.LogLevelInfo, "Migrate/baseline", "0", logging.ResultInfo, []string{"No schema found, performing fresh install", err.Error()})
+	if err := runner.FreshInstall(ctx, conn); err != nil {
+		return err
+	}
+	return runner.recordApplied(ctx, conn, runner.Registry.Latest())
+}
+
+//recordApplied marks every migration up to and including version as applied without running it
+func (runner *Runner) recordApplied(ctx context.Context, conn *sql.Conn, version int64) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	for _, migration := range runner.Registry.Migrations() {
+		if migration.Version > version {
+			break
+		}
+		if err := runner.writeHistory(ctx, tx, migration, statusApplied, len(migration.Statements), ""); err != nil {
+			logging.WriteLog(logging.LogLevelError, "Migrate/recordApplied", "0", logging.ResultFailure, []string{"Failed to record migration " + strconv.FormatInt(migration.Version, 10), err.Error()})
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+//pending validates the history against the registry and returns the migrations still to run
+func (runner *Runner) pending(history []historyEntry) ([]pendingMigration, error) {
+	var ToReturn []pendingMigration
+	recorded := make(map[int64]historyEntry)
+	for _, entry := range history {
+		recorded[entry.Version] = entry
+	}
+	known := make(map[int64]bool)
+	for _, migration := range runner.Registry.Migrations() {
+		known[migration.Version] = true
+		entry, exists := recorded[migration.Version]
+		if exists == false {
+			ToReturn = append(ToReturn, pendingMigration{Migration: migration})
+			continue
+		}
+		checksumMatches := entry.Checksum == migration.Checksum()
+		switch entry.Status {
+		case statusApplied:
+			if checksumMatches == false {
+				return nil, errors.New("migration " + strconv.FormatInt(migration.Version, 10) + " was changed after it was applied, checksum does not match history")
+			}
+		case statusRunning, statusFailed:
+			if checksumMatches {
+				//Resume after the last statement known to have succeeded
+				ToReturn = append(ToReturn, pendingMigration{Migration: migration, StartStep: entry.AppliedSteps})
+			} else if entry.AppliedSteps == 0 {
+				//Nothing from the old version ran, so the corrected migration can run in full
+				ToReturn = append(ToReturn, pendingMigration{Migration: migration})
+			} else {
+				return nil, errors.New("migration " + strconv.FormatInt(migration.Version, 10) + " partially ran " + strconv.Itoa(entry.AppliedSteps) + " statements and has since changed. Repair the schema by hand, then remove its row from SchemaMigrations")
+			}
+		default:
+			return nil, errors.New("migration " + strconv.FormatInt(migration.Version, 10) + " has unknown status " + entry.Status)
+		}
+	}
+	for _, entry := range history {
+		if known[entry.Version] == false {
+			return nil, errors.New("database has migration " + strconv.FormatInt(entry.Version, 10) + " which this version of gib does not know about, refusing to continue")
+		}
+	}
+	return ToReturn, nil
+}
+
+//apply runs a single migration, recording the outcome in the history table
+func (runner *Runner) apply(ctx context.Context, conn *sql.Conn, next pendingMigration) error {
+	migration := next.Migration
+	versionString := strconv.FormatInt(migration.Version, 10)
+	logging.WriteLog(logging.LogLevelInfo, "Migrate/apply", "0", logging.ResultInfo, []string{"Applying migration " + versionString, migration.Description})
+	if runner.Dialect.TransactionalDDL {
+		tx, err := conn.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		for step := next.StartStep; step < len(migration.Statements); step++ {
+			if _, err := tx.ExecContext(ctx, migration.Statements[step]); err != nil {
+				tx.Rollback()
+				return runner.failed(ctx, conn, migration, 0, step, err)
+			}
+		}
+		if err := runner.writeHistory(ctx, tx, migration, statusApplied, len(migration.Statements), ""); err != nil {
+			tx.Rollback()
+			return runner.failed(ctx, conn, migration, 0, len(migration.Statements), err)
+		}
+		if err := tx.Commit(); err != nil {
+			return runner.failed(ctx, conn, migration, 0, len(migration.Statements), err)
+		}
+	} else {
+		//Without transactional DDL, progress is recorded after every statement so a crash can resume where it stopped
+		if err := runner.writeHistory(ctx, conn, migration, statusRunning, next.StartStep, ""); err != nil {
+			logging.WriteLog(logging.LogLevelError, "Migrate/apply", "0", logging.ResultFailure, []string{"Failed to record migration " + versionString, err.Error()})
+			return err
+		}
+		for step := next.StartStep; step < len(migration.Statements); step++ {
+			if _, err := conn.ExecContext(ctx, migration.Statements[step]); err != nil {
+				return runner.failed(ctx, conn, migration, step, step, err)
+			}
+			if _, err := conn.ExecContext(ctx, runner.bind("UPDATE SchemaMigrations SET AppliedSteps = ? WHERE Version = ?"), step+1, migration.Version); err != nil {
+				return runner.failed(ctx, conn, migration, step+1, step, err)
+			}
+		}
+		if _, err := conn.ExecContext(ctx, runner.bind("UPDATE SchemaMigrations SET Status = ?, AppliedTime = CURRENT_TIMESTAMP WHERE Version = ?"), statusApplied, migration.Version); err != nil {
+			return runner.failed(ctx, conn, migration, len(migration.Statements), len(migration.Statements), err)
+		}
+	}
+	logging.WriteLog(logging.LogLevelInfo, "Migrate/apply", "0", logging.ResultSuccess, []string{"Applied migration " + versionString})
+	return nil
+}
+
+//failed records a failed migration and returns an error describing the statement that failed
+func (runner *Runner) failed(ctx context.Context, conn *sql.Conn, migration Migration, appliedSteps int, step int, cause error) error {
+	versionString := strconv.FormatInt(migration.Version, 10)
+	if err := runner.writeHistory(ctx, conn, migration, statusFailed, appliedSteps, cause.Error()); err != nil {
+		logging.WriteLog(logging.LogLevelError, "Migrate/failed", "0", logging.ResultFailure, []string{"Failed to record failure of migration " + versionString, err.Error()})
+	}
+	logging.WriteLog(logging.LogLevelError, "Migrate/failed", "0", logging.ResultFailure, []string{"Migration " + versionString + " failed at statement " + strconv.Itoa(step+1) + " of " + strconv.Itoa(len(migration.Statements)), cause.Error()})
+	return errors.New("migration " + versionString + " failed at statement " + strconv.Itoa(step+1) + ": " + cause.Error())
+}
+
+//writeHistory replaces the history row for a migration
+func (runner *Runner) writeHistory(ctx context.Context, target execer, migration Migration, status string, appliedSteps int, errorText string) error {
+	if _, err := target.ExecContext(ctx, runner.bind("DELETE FROM SchemaMigrations WHERE Version = ?"), migration.Version); err != nil {
+		return err
+	}
+	var errorValue sql.NullString
+	if errorText != "" {
+		errorValue = sql.NullString{String: errorText, Valid: true}
+	}
+	_, err := target.ExecContext(ctx, runner.bind("INSERT INTO SchemaMigrations (Version, Description, Checksum, Status, AppliedSteps, Error) VALUES (?, ?, ?, ?, ?, ?)"), migration.Version, migration.Description, migration.Checksum(), status, appliedSteps, errorValue)
+	return err
+}
+
+//dryRun prints the pending migrations without changing the database
+func (runner *Runner) dryRun(ctx context.Context, conn *sql.Conn) error {
+	output := runner.Output
+	if output == nil {
+		output = os.Stdout
+	}
+	//The history table may not exist yet, in which case treat it as empty
+	history, err := runner.loadHistory(ctx, conn)
+	if err != nil || len(history) == 0 {
+		history = nil
+		if version, err := runner.legacyVersion(ctx, conn); err == nil {
+			fmt.Fprintln(output, "-- Migrations up to legacy DBVersion "+strconv.FormatInt(version, 10)+" would be recorded as applied, and DBVersion dropped")
+			for _, migration := range runner.Registry.Migrations() {
+				if migration.Version <= version {
+					history = append(history, historyEntry{Version: migration.Version, Checksum: migration.Checksum(), Status: statusApplied, AppliedSteps: len(migration.Statements)})
+				}
+			}
+		} else if runner.FreshInstall != nil {
+			fmt.Fprintln(output, "-- No schema found, a fresh install of schema version "+strconv.FormatInt(runner.Registry.Latest(), 10)+" would be performed")
+			return ErrDryRun
+		}
+	}
+	pending, err := runner.pending(history)
+	if err != nil {
+		return err
+	}
+	if len(pending) == 0 {
+		fmt.Fprintln(output, "-- No pending migrations")
+	}
+	for _, next := range pending {
+		fmt.Fprintln(output, "-- Migration "+strconv.FormatInt(next.Migration.Version, 10)+": "+next.Migration.Description+" (checksum "+next.Migration.Checksum()+")")
+		if next.StartStep > 0 {
+			fmt.Fprintln(output, "-- Resuming after statement "+strconv.Itoa(next.StartStep))
+		}
+		for _, statement := range next.Migration.Statements[next.StartStep:] {
+			statement = strings.TrimSpace(statement)
+			if strings.HasSuffix(statement, ";") == false {
+				statement += ";"
+			}
+			fmt.Fprintln(output, statement)
+		}
+	}
+	return ErrDryRun
+}
+
+//bind converts the ? placeholders used by the runner for dialects that number their parameters
+func (runner *Runner) bind(query string) string {
+	if runner.Dialect.Placeholder == nil {
+		return query
+	}
+	var builder strings.Builder
+	placeholder := 0
+	for _, character := range query {
+		if character == '?' {
+			placeholder++
+			builder.WriteString(runner.Dialect.Placeholder(placeholder))
+			continue
+		}
+		builder.WriteRune(character)
+	}
+	return builder.String()
+}
@@ -0,0 +1,295 @@
+package migrate
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"go-image-board/logging"
+	"go-image-board/plugins"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+func TestMain(m *testing.M) {
+	logging.LogInterface = &plugins.STDLog{}
+	logging.LogInterface.Init(-1, "", "")
+	os.Exit(m.Run())
+}
+
+//openTestDatabase returns a new, empty SQLite database
+func openTestDatabase(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", "file:"+filepath.Join(t.TempDir(), "migrate.db")+"?_pragma=busy_timeout(10000)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+//newTestRegistry returns a registry holding the given migrations
+func newTestRegistry(migrations ...Migration) *Registry {
+	registry := &Registry{}
+	for _, migration := range migrations {
+		registry.Register(migration)
+	}
+	return registry
+}
+
+var createWidgets = Migration{Version: 1, Description: "Add widgets", Statements: []string{"CREATE TABLE Widgets (ID INTEGER PRIMARY KEY);"}}
+var createGadgets = Migration{Version: 2, Description: "Add gadgets", Statements: []string{"CREATE TABLE Gadgets (ID INTEGER PRIMARY KEY);", "INSERT INTO Gadgets (ID) VALUES (1);"}}
+
+//historyStatus returns the status and applied steps recorded for a migration
+func historyStatus(t *testing.T, db *sql.DB, version int64) (string, int) {
+	t.Helper()
+	var status string
+	var appliedSteps int
+	if err := db.QueryRow("SELECT Status, AppliedSteps FROM SchemaMigrations WHERE Version = ?", version).Scan(&status, &appliedSteps); err != nil {
+		t.Fatalf("reading history for %d: %v", version, err)
+	}
+	return status, appliedSteps
+}
+
+func tableExists(db *sql.DB, name string) bool {
+	var count int
+	db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", name).Scan(&count)
+	return count == 1
+}
+
+func TestRunAppliesAllMigrationsToEmptyDatabase(t *testing.T) {
+	db := openTestDatabase(t)
+	runner := Runner{DB: db, Dialect: SQLite, Registry: newTestRegistry(createGadgets, createWidgets)}
+	if err := runner.Run(); err != nil {
+		t.Fatal(err)
+	}
+	for _, version := range []int64{1, 2} {
+		if status, _ := historyStatus(t, db, version); status != statusApplied {
+			t.Errorf("migration %d status = %q, want %q", version, status, statusApplied)
+		}
+	}
+	if tableExists(db, "Widgets") == false || tableExists(db, "Gadgets") == false {
+		t.Error("expected both migrations to create their tables")
+	}
+	//A second run has nothing to do
+	if err := runner.Run(); err != nil {
+		t.Fatalf("second run: %v", err)
+	}
+}
+
+func TestRunRecordsLegacyVersion(t *testing.T) {
+	db := openTestDatabase(t)
+	for _, statement := range []string{"CREATE TABLE DBVersion (version INTEGER NOT NULL);", "INSERT INTO DBVersion (version) VALUES (1);", "CREATE TABLE Widgets (ID INTEGER PRIMARY KEY);"} {
+		if _, err := db.Exec(statement); err != nil {
+			t.Fatal(err)
+		}
+	}
+	//Migration 1 would fail if it ran again, as Widgets already exists
+	runner := Runner{DB: db, Dialect: SQLite, Registry: newTestRegistry(createWidgets, createGadgets)}
+	if err := runner.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if tableExists(db, "DBVersion") {
+		t.Error("expected DBVersion to be dropped")
+	}
+	if tableExists(db, "Gadgets") == false {
+		t.Error("expected migration 2 to run")
+	}
+}
+
+func TestRunFreshInstallSkipsMigrations(t *testing.T) {
+	db := openTestDatabase(t)
+	installed := false
+	runner := Runner{DB: db, Dialect: SQLite, Registry: newTestRegistry(createWidgets, createGadgets), FreshInstall: func(ctx context.Context, conn *sql.Conn) error {
+		installed = true
+		return nil
+	}}
+	if err := runner.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if installed == false {
+		t.Fatal("expected fresh install to run")
+	}
+	if tableExists(db, "Widgets") {
+		t.Error("migrations should be recorded, not run, after a fresh install")
+	}
+	if status, _ := historyStatus(t, db, 2); status != statusApplied {
+		t.Errorf("migration 2 status = %q, want %q", status, statusApplied)
+	}
+}
+
+func TestRunRetriesFailedFreshInstall(t *testing.T) {
+	db := openTestDatabase(t)
+	attempts := 0
+	runner := Runner{DB: db, Dialect: SQLite, Registry: newTestRegistry(createWidgets), FreshInstall: func(ctx context.Context, conn *sql.Conn) error {
+		attempts++
+		//The install runs on the connection holding the lock
+		var locks int
+		if err := conn.QueryRowContext(ctx, "SELECT COUNT(*) FROM SchemaMigrationLock;").Scan(&locks); err != nil || locks != 1 {
+			t.Errorf("fresh install ran without the lock, %d locks, %v", locks, err)
+		}
+		if _, err := conn.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS Installed (ID INTEGER PRIMARY KEY);"); err != nil {
+			return err
+		}
+		if attempts == 1 {
+			return errors.New("interrupted")
+		}
+		return nil
+	}}
+	if err := runner.Run(); err == nil {
+		t.Fatal("expected the interrupted fresh install to fail")
+	}
+	var recorded int
+	if db.QueryRow("SELECT COUNT(*) FROM SchemaMigrations;").Scan(&recorded); recorded != 0 {
+		t.Fatalf("%d migrations were recorded after a failed fresh install", recorded)
+	}
+	if err := runner.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if attempts != 2 || tableExists(db, "Installed") == false {
+		t.Errorf("expected the fresh install to run again, %d attempts", attempts)
+	}
+	if status, _ := historyStatus(t, db, 1); status != statusApplied {
+		t.Errorf("migration 1 status = %q, want %q", status, statusApplied)
+	}
+}
+
+func TestRunRejectsEditedMigration(t *testing.T) {
+	db := openTestDatabase(t)
+	runner := Runner{DB: db, Dialect: SQLite, Registry: newTestRegistry(createWidgets)}
+	if err := runner.Run(); err != nil {
+		t.Fatal(err)
+	}
+	edited := createWidgets
+	edited.Statements = []string{"CREATE TABLE Widgets (ID INTEGER PRIMARY KEY, Name TEXT);"}
+	runner.Registry = newTestRegistry(edited)
+	if err := runner.Run(); err == nil || strings.Contains(err.Error(), "checksum") == false {
+		t.Fatalf("expected checksum error, got %v", err)
+	}
+}
+
+func TestRunRejectsUnknownMigration(t *testing.T) {
+	db := openTestDatabase(t)
+	runner := Runner{DB: db, Dialect: SQLite, Registry: newTestRegistry(createWidgets, createGadgets)}
+	if err := runner.Run(); err != nil {
+		t.Fatal(err)
+	}
+	runner.Registry = newTestRegistry(createWidgets)
+	if err := runner.Run(); err == nil {
+		t.Fatal("expected an error when the database is ahead of the registry")
+	}
+}
+
+func TestRunRollsBackFailedTransactionalMigration(t *testing.T) {
+	db := openTestDatabase(t)
+	broken := Migration{Version: 1, Description: "Broken", Statements: []string{"CREATE TABLE Widgets (ID INTEGER PRIMARY KEY);", "NOT VALID SQL;"}}
+	runner := Runner{DB: db, Dialect: SQLite, Registry: newTestRegistry(broken)}
+	if err := runner.Run(); err == nil {
+		t.Fatal("expected the broken migration to fail")
+	}
+	if status, appliedSteps := historyStatus(t, db, 1); status != statusFailed || appliedSteps != 0 {
+		t.Errorf("history = %q/%d, want %q/0", status, appliedSteps, statusFailed)
+	}
+	if tableExists(db, "Widgets") {
+		t.Error("expected the first statement to be rolled back")
+	}
+	//A corrected migration runs in full
+	fixed := broken
+	fixed.Statements = []string{"CREATE TABLE Widgets (ID INTEGER PRIMARY KEY);", "INSERT INTO Widgets (ID) VALUES (1);"}
+	runner.Registry = newTestRegistry(fixed)
+	if err := runner.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if status, _ := historyStatus(t, db, 1); status != statusApplied {
+		t.Errorf("migration status = %q, want %q", status, statusApplied)
+	}
+}
+
+func TestRunResumesNonTransactionalMigration(t *testing.T) {
+	db := openTestDatabase(t)
+	dialect := SQLite
+	dialect.TransactionalDDL = false
+	//The second statement fails until Gadgets exists
+	partial := Migration{Version: 1, Description: "Partial", Statements: []string{"CREATE TABLE Widgets (ID INTEGER PRIMARY KEY);", "INSERT INTO Gadgets (ID) VALUES (1);"}}
+	runner := Runner{DB: db, Dialect: dialect, Registry: newTestRegistry(partial)}
+	if err := runner.Run(); err == nil {
+		t.Fatal("expected the migration to fail")
+	}
+	if status, appliedSteps := historyStatus(t, db, 1); status != statusFailed || appliedSteps != 1 {
+		t.Errorf("history = %q/%d, want %q/1", status, appliedSteps, statusFailed)
+	}
+	//Changing a partially applied migration needs a manual repair
+	edited := partial
+	edited.Statements = []string{"CREATE TABLE Widgets (ID INTEGER PRIMARY KEY);", "SELECT 1;"}
+	runner.Registry = newTestRegistry(edited)
+	if err := runner.Run(); err == nil {
+		t.Fatal("expected an error for an edited, partially applied migration")
+	}
+	//Fixing the cause resumes at the failed statement, creating Widgets again would fail
+	if _, err := db.Exec("CREATE TABLE Gadgets (ID INTEGER PRIMARY KEY);"); err != nil {
+		t.Fatal(err)
+	}
+	runner.Registry = newTestRegistry(partial)
+	if err := runner.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if status, appliedSteps := historyStatus(t, db, 1); status != statusApplied || appliedSteps != 2 {
+		t.Errorf("history = %q/%d, want %q/2", status, appliedSteps, statusApplied)
+	}
+}
+
+func TestRunDryRunPrintsPendingSQL(t *testing.T) {
+	db := openTestDatabase(t)
+	var output bytes.Buffer
+	runner := Runner{DB: db, Dialect: SQLite, Registry: newTestRegistry(createWidgets, createGadgets), Output: &output}
+	DryRun = true
+	defer func() { DryRun = false }()
+	if err := runner.Run(); err != ErrDryRun {
+		t.Fatalf("expected ErrDryRun, got %v", err)
+	}
+	for _, expected := range []string{"-- Migration 1: Add widgets", "CREATE TABLE Widgets", "INSERT INTO Gadgets (ID) VALUES (1);"} {
+		if strings.Contains(output.String(), expected) == false {
+			t.Errorf("dry run output missing %q:\n%s", expected, output.String())
+		}
+	}
+	if tableExists(db, "Widgets") || tableExists(db, "SchemaMigrations") {
+		t.Error("dry run must not change the database")
+	}
+}
+
+func TestRunTimesOutWhenLocked(t *testing.T) {
+	db := openTestDatabase(t)
+	for _, statement := range []string{"CREATE TABLE SchemaMigrationLock (ID INTEGER NOT NULL PRIMARY KEY, LockTime DATETIME DEFAULT CURRENT_TIMESTAMP NOT NULL);", "INSERT INTO SchemaMigrationLock (ID) VALUES (1);"} {
+		if _, err := db.Exec(statement); err != nil {
+			t.Fatal(err)
+		}
+	}
+	previousTimeout := LockTimeout
+	LockTimeout = 0
+	defer func() { LockTimeout = previousTimeout }()
+	runner := Runner{DB: db, Dialect: SQLite, Registry: newTestRegistry(createWidgets)}
+	start := time.Now()
+	if err := runner.Run(); err == nil {
+		t.Fatal("expected a lock timeout")
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Error("lock timeout took too long")
+	}
+	if tableExists(db, "Widgets") {
+		t.Error("no migration should run without the lock")
+	}
+}
+
+func TestRegisterRejectsDuplicateVersion(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic for a duplicate version")
+		}
+	}()
+	newTestRegistry(createWidgets, createWidgets)
+}
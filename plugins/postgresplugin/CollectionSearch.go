@@ -0,0 +1,8 @@
+package postgresplugin
+
+import "go-image-board/interfaces"
+
+//SearchCollections performs a search for collections (Returns a list of CollectionInformation a result count and an error/nil)
+func (DBConnection *PostgresPlugin) SearchCollections(Tags []interfaces.TagInformation, PageStart uint64, PageStride uint64) ([]interfaces.CollectionInformation, uint64, error) {
+	return DBConnection.searcher().SearchCollections(Tags, PageStart, PageStride)
+}
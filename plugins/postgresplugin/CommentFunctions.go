@@ -0,0 +1,204 @@
+package postgresplugin
+
+import (
+	"database/sql"
+	"errors"
+	"go-image-board/interfaces"
+	"go-image-board/logging"
+	"strconv"
+	"strings"
+)
+
+//NewComment adds a comment to Comment.ImageID, or Comment.CollectionID, replying to Comment.ParentID if set. Returns its ID
+func (DBConnection *PostgresPlugin) NewComment(Comment interfaces.CommentInformation) (uint64, error) {
+	var ThreadID uint64
+	if Comment.ParentID != 0 {
+		parent, err := DBConnection.GetComment(Comment.ParentID)
+		if err != nil {
+			return 0, err
+		}
+		if parent.ImageID != Comment.ImageID || parent.CollectionID != Comment.CollectionID {
+			return 0, errors.New("the comment being replied to is on something else")
+		}
+		ThreadID = parent.ThreadID
+		if ThreadID == 0 {
+			ThreadID = parent.ID
+		}
+	}
+	var id uint64
+	err := DBConnection.DBHandle.QueryRow("INSERT INTO Comments (ImageID, CollectionID, ParentID, ThreadID, UserID, Body) VALUES (?, ?, ?, ?, ?, ?) RETURNING ID;", Comment.ImageID, Comment.CollectionID, Comment.ParentID, ThreadID, Comment.UserID, Comment.Body).Scan(&id)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "PostgresPlugin/NewComment", strconv.FormatUint(Comment.UserID, 10), logging.ResultFailure, []string{"Failed to add comment", err.Error()})
+		return 0, err
+	}
+	logging.WriteLog(logging.LogLevelInfo, "PostgresPlugin/NewComment", strconv.FormatUint(Comment.UserID, 10), logging.ResultSuccess, []string{"Comment added", strconv.FormatUint(id, 10)})
+	return id, nil
+}
+
+//GetComment returns a single comment, including deleted ones
+func (DBConnection *PostgresPlugin) GetComment(CommentID uint64) (interfaces.CommentInformation, error) {
+	comments, err := DBConnection.queryComments("WHERE Comments.ID = ?;", CommentID)
+	if err == nil && len(comments) == 0 {
+		err = sql.ErrNoRows
+	}
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "PostgresPlugin/GetComment", "0", logging.ResultFailure, []string{"Failed to get comment", strconv.FormatUint(CommentID, 10), err.Error()})
+		return interfaces.CommentInformation{}, err
+	}
+	return comments[0], nil
+}
+
+//GetComments returns a page of the threads on an image, or on a collection when ImageID is 0, oldest first. Each thread is ordered by ThreadComments, and the count of all threads is returned
+func (DBConnection *PostgresPlugin) GetComments(ImageID uint64, CollectionID uint64, PageStart uint64, PageStride uint64) ([]interfaces.CommentInformation, uint64, error) {
+	var MaxResults uint64
+	if err := DBConnection.DBHandle.QueryRow("SELECT COUNT(*) FROM Comments WHERE ImageID = ? AND CollectionID = ? AND ParentID = 0;", ImageID, CollectionID).Scan(&MaxResults); err != nil {
+		logging.WriteLog(logging.LogLevelError, "PostgresPlugin/GetComments", "0", logging.ResultFailure, []string{"Failed to count comments", err.Error()})
+		return nil, 0, err
+	}
+	rows, err := DBConnection.DBHandle.Query("SELECT ID FROM Comments WHERE ImageID = ? AND CollectionID = ? AND ParentID = 0 ORDER BY ID LIMIT ? OFFSET ?;", ImageID, CollectionID, PageStride, PageStart)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "PostgresPlugin/GetComments", "0", logging.ResultFailure, []string{"Failed to get comments", err.Error()})
+		return nil, 0, err
+	}
+	var threadIDs []interface{}
+	for rows.Next() {
+		var ID uint64
+		if err := rows.Scan(&ID); err != nil {
+			rows.Close()
+			return nil, 0, err
+		}
+		threadIDs = append(threadIDs, ID)
+	}
+	rows.Close()
+	if len(threadIDs) == 0 {
+		return nil, MaxResults, nil
+	}
+	//Each thread is the comment that started it, and every comment with it as their ThreadID
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(threadIDs)), ", ")
+	comments, err := DBConnection.queryComments("WHERE Comments.ID IN ("+placeholders+") OR Comments.ThreadID IN ("+placeholders+");", append(threadIDs, threadIDs...)...)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "PostgresPlugin/GetComments", "0", logging.ResultFailure, []string{"Failed to get comments", err.Error()})
+		return nil, 0, err
+	}
+	return interfaces.ThreadComments(comments), MaxResults, nil
+}
+
+//UpdateComment replaces the body of a comment, keeping the previous body as a revision
+func (DBConnection *PostgresPlugin) UpdateComment(CommentID uint64, Body string, EditorID uint64) error {
+	comment, err := DBConnection.GetComment(CommentID)
+	if err != nil {
+		return err
+	}
+	//Only update if the body was not edited since we read it, otherwise its revision would be lost
+	resultInfo, err := DBConnection.DBHandle.Exec("UPDATE Comments SET Body = ?, EditTime = CURRENT_TIMESTAMP WHERE ID = ? AND Body = ?;", Body, CommentID, comment.Body)
+	if err == nil {
+		if affected, _ := resultInfo.RowsAffected(); affected == 0 {
+			err = errors.New("comment was edited by another request")
+		}
+	}
+	if err == nil {
+		_, err = DBConnection.DBHandle.Exec("INSERT INTO CommentRevisions (CommentID, Body, EditorID) VALUES (?, ?, ?);", CommentID, comment.Body, EditorID)
+		if err != nil {
+			//Without a revision the previous body would be lost, so put it back
+			DBConnection.DBHandle.Exec("UPDATE Comments SET Body = ? WHERE ID = ?;", comment.Body, CommentID)
+		}
+	}
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "PostgresPlugin/UpdateComment", strconv.FormatUint(EditorID, 10), logging.ResultFailure, []string{"Failed to update comment", strconv.FormatUint(CommentID, 10), err.Error()})
+		return err
+	}
+	logging.WriteLog(logging.LogLevelInfo, "PostgresPlugin/UpdateComment", strconv.FormatUint(EditorID, 10), logging.ResultSuccess, []string{"Comment updated", strconv.FormatUint(CommentID, 10)})
+	return nil
+}
+
+//GetCommentRevisions returns the previous bodies of a comment, newest first
+func (DBConnection *PostgresPlugin) GetCommentRevisions(CommentID uint64) ([]interfaces.CommentRevisionInformation, error) {
+	rows, err := DBConnection.DBHandle.Query(`SELECT CommentRevisions.ID, CommentRevisions.CommentID, CommentRevisions.Body, CommentRevisions.EditorID, COALESCE(Users.Name, ''), CommentRevisions.EditTime
+	FROM CommentRevisions
+	LEFT OUTER JOIN Users ON CommentRevisions.EditorID = Users.ID
+	WHERE CommentRevisions.CommentID = ? ORDER BY CommentRevisions.ID DESC;`, CommentID)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "PostgresPlugin/GetCommentRevisions", "0", logging.ResultFailure, []string{"Failed to get comment revisions", strconv.FormatUint(CommentID, 10), err.Error()})
+		return nil, err
+	}
+	defer rows.Close()
+	var ToReturn []interfaces.CommentRevisionInformation
+	for rows.Next() {
+		var revision interfaces.CommentRevisionInformation
+		var EditTime sql.NullTime
+		if err := rows.Scan(&revision.ID, &revision.CommentID, &revision.Body, &revision.EditorID, &revision.EditorName, &EditTime); err != nil {
+			return nil, err
+		}
+		revision.EditTime = EditTime.Time
+		ToReturn = append(ToReturn, revision)
+	}
+	return ToReturn, rows.Err()
+}
+
+//DeleteComment hides a comment, replies to it are kept
+func (DBConnection *PostgresPlugin) DeleteComment(CommentID uint64, DeleterID uint64) error {
+	resultInfo, err := DBConnection.DBHandle.Exec("UPDATE Comments SET DeletedTime = CURRENT_TIMESTAMP, DeleterID = ? WHERE ID = ? AND DeletedTime IS NULL;", DeleterID, CommentID)
+	if err == nil {
+		if affected, _ := resultInfo.RowsAffected(); affected == 0 {
+			err = errors.New("comment does not exist or is already deleted")
+		}
+	}
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "PostgresPlugin/DeleteComment", strconv.FormatUint(DeleterID, 10), logging.ResultFailure, []string{"Failed to delete comment", strconv.FormatUint(CommentID, 10), err.Error()})
+		return err
+	}
+	logging.WriteLog(logging.LogLevelInfo, "PostgresPlugin/DeleteComment", strconv.FormatUint(DeleterID, 10), logging.ResultSuccess, []string{"Comment deleted", strconv.FormatUint(CommentID, 10)})
+	return nil
+}
+
+//RestoreComment shows a comment hidden by DeleteComment again
+func (DBConnection *PostgresPlugin) RestoreComment(CommentID uint64) error {
+	resultInfo, err := DBConnection.DBHandle.Exec("UPDATE Comments SET DeletedTime = NULL, DeleterID = 0 WHERE ID = ? AND DeletedTime IS NOT NULL;", CommentID)
+	if err == nil {
+		if affected, _ := resultInfo.RowsAffected(); affected == 0 {
+			err = errors.New("comment does not exist or is not deleted")
+		}
+	}
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "PostgresPlugin/RestoreComment", "0", logging.ResultFailure, []string{"Failed to restore comment", strconv.FormatUint(CommentID, 10), err.Error()})
+		return err
+	}
+	logging.WriteLog(logging.LogLevelInfo, "PostgresPlugin/RestoreComment", "0", logging.ResultSuccess, []string{"Comment restored", strconv.FormatUint(CommentID, 10)})
+	return nil
+}
+
+//deleteComments removes every comment on an image, or on a collection when ImageID is 0, along with their revisions
+func (DBConnection *PostgresPlugin) deleteComments(ImageID uint64, CollectionID uint64) error {
+	_, err := DBConnection.DBHandle.Exec("DELETE FROM CommentRevisions WHERE CommentID IN (SELECT ID FROM Comments WHERE ImageID = ? AND CollectionID = ?);", ImageID, CollectionID)
+	if err == nil {
+		_, err = DBConnection.DBHandle.Exec("DELETE FROM Comments WHERE ImageID = ? AND CollectionID = ?;", ImageID, CollectionID)
+	}
+	return err
+}
+
+//queryComments returns comments, Suffix is added after the joins to filter and order them
+func (DBConnection *PostgresPlugin) queryComments(Suffix string, Arguments ...interface{}) ([]interfaces.CommentInformation, error) {
+	rows, err := DBConnection.DBHandle.Query(`SELECT Comments.ID, Comments.ImageID, Comments.CollectionID, Comments.ParentID, Comments.ThreadID, Comments.UserID, COALESCE(Users.Name, ''), Comments.Body, Comments.CreationTime, Comments.EditTime, Comments.DeletedTime, Comments.DeleterID
+	FROM Comments
+	LEFT OUTER JOIN Users ON Comments.UserID = Users.ID `+Suffix, Arguments...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ToReturn []interfaces.CommentInformation
+	for rows.Next() {
+		var comment interfaces.CommentInformation
+		var CreationTime sql.NullTime
+		var EditTime sql.NullTime
+		var DeletedTime sql.NullTime
+		if err := rows.Scan(&comment.ID, &comment.ImageID, &comment.CollectionID, &comment.ParentID, &comment.ThreadID, &comment.UserID, &comment.UserName, &comment.Body, &CreationTime, &EditTime, &DeletedTime, &comment.DeleterID); err != nil {
+			return nil, err
+		}
+		comment.CreationTime = CreationTime.Time
+		comment.EditTime = EditTime.Time
+		comment.Deleted = DeletedTime.Valid
+		comment.DeletedTime = DeletedTime.Time
+		ToReturn = append(ToReturn, comment)
+	}
+	return ToReturn, rows.Err()
+}
@@ -0,0 +1,299 @@
+package postgresplugin
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"go-image-board/interfaces"
+	"go-image-board/logging"
+	"strconv"
+)
+
+//Image operations
+
+//NewImage adds an image with the provided information
+func (DBConnection *PostgresPlugin) NewImage(ImageName string, ImageFileName string, OwnerID uint64, Source string, Pending bool) (uint64, error) {
+	var id uint64
+	err := DBConnection.DBHandle.QueryRow("INSERT INTO Images (Name, Location, UploaderID, Source, Pending) VALUES (?, ?, ?, ?, ?) RETURNING ID;", ImageName, ImageFileName, OwnerID, Source, Pending).Scan(&id)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "PostgresPlugin/NewImage", strconv.FormatUint(OwnerID, 10), logging.ResultFailure, []string{"Failed to add image", err.Error()})
+		return 0, err
+	}
+	logging.WriteLog(logging.LogLevelError, "PostgresPlugin/NewImage", strconv.FormatUint(OwnerID, 10), logging.ResultSuccess, []string{"Image added"})
+	return id, err
+}
+
+//GetAllImages returns the ID, Name, Location and MIMEType of up to Count images with an ID above AfterID, lowest first. Unlike SearchImages, images in the trash or waiting for approval are included
+func (DBConnection *PostgresPlugin) GetAllImages(AfterID uint64, Count uint64) ([]interfaces.ImageInformation, error) {
+	rows, err := DBConnection.DBHandle.Query("SELECT ID, Name, Location, MIMEType FROM Images WHERE ID > ? ORDER BY ID LIMIT ?;", AfterID, Count)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "PostgresPlugin/GetAllImages", "0", logging.ResultFailure, []string{"Failed to get images", err.Error()})
+		return nil, err
+	}
+	defer rows.Close()
+	var ToReturn []interfaces.ImageInformation
+	for rows.Next() {
+		var image interfaces.ImageInformation
+		if err := rows.Scan(&image.ID, &image.Name, &image.Location, &image.MIMEType); err != nil {
+			logging.WriteLog(logging.LogLevelError, "PostgresPlugin/GetAllImages", "0", logging.ResultFailure, []string{"Failed to get images", err.Error()})
+			return nil, err
+		}
+		ToReturn = append(ToReturn, image)
+	}
+	return ToReturn, rows.Err()
+}
+
+//DeleteImage removes an image from the db
+func (DBConnection *PostgresPlugin) DeleteImage(ImageID uint64) error {
+	//First, remove image from any associated collections
+	collectionInfo, err := DBConnection.GetCollectionsWithImage(ImageID)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "PostgresPlugin/DeleteImage", "0", logging.ResultFailure, []string{"Failed to get collection data to delete image", err.Error(), strconv.FormatUint(ImageID, 10)})
+		return err
+	}
+
+	for I := 0; I < len(collectionInfo); I++ {
+		if err = DBConnection.RemoveCollectionMember(collectionInfo[I].ID, ImageID); err != nil {
+			logging.WriteLog(logging.LogLevelWarning, "PostgresPlugin/DeleteImage", "0", logging.ResultFailure, []string{"Failed to remove image from collection", err.Error(), strconv.FormatUint(ImageID, 10)})
+		}
+	}
+
+	//First delete ImageTags
+	_, err = DBConnection.DBHandle.Exec("DELETE FROM ImageTags WHERE ImageID=?;", ImageID)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "PostgresPlugin/DeleteImage", "0", logging.ResultFailure, []string{"Failed to delete image", err.Error(), strconv.FormatUint(ImageID, 10)})
+		return err
+	}
+	logging.WriteLog(logging.LogLevelError, "PostgresPlugin/DeleteImage", "0", logging.ResultSuccess, []string{"Image tags deleted", strconv.FormatUint(ImageID, 10)})
+	//And its tag history
+	_, err = DBConnection.DBHandle.Exec("DELETE FROM ImageTagHistory WHERE ImageID=?;", ImageID)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "PostgresPlugin/DeleteImage", "0", logging.ResultFailure, []string{"Failed to delete image tag history", err.Error(), strconv.FormatUint(ImageID, 10)})
+		return err
+	}
+	//And any favorites of it
+	_, err = DBConnection.DBHandle.Exec("DELETE FROM ImageUserFavorites WHERE ImageID=?;", ImageID)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "PostgresPlugin/DeleteImage", "0", logging.ResultFailure, []string{"Failed to delete image favorites", err.Error(), strconv.FormatUint(ImageID, 10)})
+		return err
+	}
+	//And its comments
+	if err = DBConnection.deleteComments(ImageID, 0); err != nil {
+		logging.WriteLog(logging.LogLevelError, "PostgresPlugin/DeleteImage", "0", logging.ResultFailure, []string{"Failed to delete image comments", err.Error(), strconv.FormatUint(ImageID, 10)})
+		return err
+	}
+	//And its notes
+	if err = DBConnection.deleteNotes(ImageID); err != nil {
+		logging.WriteLog(logging.LogLevelError, "PostgresPlugin/DeleteImage", "0", logging.ResultFailure, []string{"Failed to delete image notes", err.Error(), strconv.FormatUint(ImageID, 10)})
+		return err
+	}
+	//Then the records of its previous files, those files are removed by the caller along with the current one
+	_, err = DBConnection.DBHandle.Exec("DELETE FROM ImageRevisions WHERE ImageID=?;", ImageID)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "PostgresPlugin/DeleteImage", "0", logging.ResultFailure, []string{"Failed to delete image revisions", err.Error(), strconv.FormatUint(ImageID, 10)})
+		return err
+	}
+	//Second delete Image from table
+	_, err = DBConnection.DBHandle.Exec("DELETE FROM Images WHERE ID=?;", ImageID)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "PostgresPlugin/DeleteImage", "0", logging.ResultFailure, []string{"Failed to delete image", err.Error(), strconv.FormatUint(ImageID, 10)})
+	} else {
+		logging.WriteLog(logging.LogLevelError, "PostgresPlugin/DeleteImage", "0", logging.ResultSuccess, []string{"Image deleted", strconv.FormatUint(ImageID, 10)})
+	}
+	return err
+}
+
+//UpdateImage updates properties of an image
+func (DBConnection *PostgresPlugin) UpdateImage(ImageID uint64, ImageName interface{}, ImageDescription interface{}, OwnerID interface{}, Rating interface{}, Source interface{}, Location interface{}) error {
+	if _, correctValue := OwnerID.(uint64); OwnerID != nil && correctValue == false {
+		return errors.New("OwnerID, when provided, must be of uint64 type")
+	}
+
+	//See if image exists
+	_, err := DBConnection.GetImage(ImageID)
+	if err != nil {
+		return err
+	}
+
+	queryArray := []interface{}{}
+	sqlQuery := ""
+
+	if ImageName != nil {
+		queryArray = append(queryArray, fmt.Sprintf("%v", ImageName))
+		if sqlQuery != "" {
+			sqlQuery += ", "
+		}
+		sqlQuery += "Name = ? "
+	}
+	if ImageDescription != nil {
+		queryArray = append(queryArray, fmt.Sprintf("%v", ImageDescription))
+		if sqlQuery != "" {
+			sqlQuery += ", "
+		}
+		sqlQuery += "Description = ? "
+	}
+	if unwrappedOwnerID, correctValue := OwnerID.(uint64); OwnerID != nil && correctValue {
+		queryArray = append(queryArray, unwrappedOwnerID)
+		if sqlQuery != "" {
+			sqlQuery += ", "
+		}
+		sqlQuery += "UploaderID = ? "
+	}
+	if Rating != nil {
+		queryArray = append(queryArray, fmt.Sprintf("%v", Rating))
+		if sqlQuery != "" {
+			sqlQuery += ", "
+		}
+		sqlQuery += "Rating = ? "
+	}
+	if Source != nil {
+		queryArray = append(queryArray, fmt.Sprintf("%v", Source))
+		if sqlQuery != "" {
+			sqlQuery += ", "
+		}
+		sqlQuery += "Source = ? "
+	}
+	if Location != nil {
+		queryArray = append(queryArray, fmt.Sprintf("%v", Location))
+		if sqlQuery != "" {
+			sqlQuery += ", "
+		}
+		sqlQuery += "Location = ? "
+	}
+	queryArray = append(queryArray, ImageID)
+	if sqlQuery == "" {
+		return nil //No change requested
+	}
+	sqlQuery = "UPDATE Images SET " + sqlQuery + "WHERE ID = ?"
+	_, err = DBConnection.DBHandle.Exec(sqlQuery, queryArray...)
+	return err
+}
+
+//GetImage returns information on a single image (Returns an ImageInformation, or error)
+func (DBConnection *PostgresPlugin) GetImage(ID uint64) (interfaces.ImageInformation, error) {
+	ToReturn := interfaces.ImageInformation{ID: ID}
+	var UploadTime sql.NullTime
+	var DeletedTime sql.NullTime
+	err := DBConnection.DBHandle.QueryRow("Select Images.Name, COALESCE(Images.Description,'') AS Description, Images.Location, Images.UploaderID, Images.UploadTime, Images.Rating, Users.Name, Images.ScoreAverage, Images.ScoreTotal, Images.ScoreVoters, Images.Source, Images.DeletedTime, Images.DeleterID, COALESCE(Deleters.Name, ''), Images.Width, Images.Height, Images.FileSize, Images.MIMEType, Images.Duration, (SELECT COUNT(*) FROM ImageUserFavorites WHERE ImageUserFavorites.ImageID = Images.ID), Images.Pending FROM Images LEFT OUTER JOIN Users ON Images.UploaderID = Users.ID LEFT OUTER JOIN Users AS Deleters ON Images.DeleterID = Deleters.ID WHERE Images.ID=?", ID).Scan(&ToReturn.Name, &ToReturn.Description, &ToReturn.Location, &ToReturn.UploaderID, &UploadTime, &ToReturn.Rating, &ToReturn.UploaderName, &ToReturn.ScoreAverage, &ToReturn.ScoreTotal, &ToReturn.ScoreVoters, &ToReturn.Source, &DeletedTime, &ToReturn.DeleterID, &ToReturn.DeleterName, &ToReturn.Width, &ToReturn.Height, &ToReturn.FileSize, &ToReturn.MIMEType, &ToReturn.Duration, &ToReturn.FavoriteCount, &ToReturn.Pending)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "PostgresPlugin/ImageFunctions/GetImage", "0", logging.ResultFailure, []string{"Failed to get image info from database", err.Error()})
+		return ToReturn, err
+	}
+	if UploadTime.Valid {
+		ToReturn.UploadTime = UploadTime.Time
+	}
+	ToReturn.InTrash = DeletedTime.Valid
+	ToReturn.DeletedTime = DeletedTime.Time
+	return ToReturn, nil
+}
+
+//GetImageByFileName returns an ImageInformation object given a ImageName
+func (DBConnection *PostgresPlugin) GetImageByFileName(imageName string) (interfaces.ImageInformation, error) {
+	ToReturn := interfaces.ImageInformation{Location: imageName}
+	var UploadTime sql.NullTime
+	var DeletedTime sql.NullTime
+	err := DBConnection.DBHandle.QueryRow("Select Images.Name, COALESCE(Images.Description,'') AS Description, Images.ID, Images.UploaderID, Images.UploadTime, Images.Rating, Users.Name, Images.ScoreAverage, Images.ScoreTotal, Images.ScoreVoters, Images.Source, Images.DeletedTime, Images.DeleterID, COALESCE(Deleters.Name, ''), Images.Width, Images.Height, Images.FileSize, Images.MIMEType, Images.Duration, (SELECT COUNT(*) FROM ImageUserFavorites WHERE ImageUserFavorites.ImageID = Images.ID), Images.Pending FROM Images LEFT OUTER JOIN Users ON Images.UploaderID = Users.ID LEFT OUTER JOIN Users AS Deleters ON Images.DeleterID = Deleters.ID WHERE Images.Location=?", imageName).Scan(&ToReturn.Name, &ToReturn.Description, &ToReturn.ID, &ToReturn.UploaderID, &UploadTime, &ToReturn.Rating, &ToReturn.UploaderName, &ToReturn.ScoreAverage, &ToReturn.ScoreTotal, &ToReturn.ScoreVoters, &ToReturn.Source, &DeletedTime, &ToReturn.DeleterID, &ToReturn.DeleterName, &ToReturn.Width, &ToReturn.Height, &ToReturn.FileSize, &ToReturn.MIMEType, &ToReturn.Duration, &ToReturn.FavoriteCount, &ToReturn.Pending)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "PostgresPlugin/ImageFunctions/GetImageByFileName", "0", logging.ResultFailure, []string{"Failed to get image info from database", err.Error()})
+		return ToReturn, err
+	}
+	if UploadTime.Valid {
+		ToReturn.UploadTime = UploadTime.Time
+	}
+	ToReturn.InTrash = DeletedTime.Valid
+	ToReturn.DeletedTime = DeletedTime.Time
+	return ToReturn, nil
+}
+
+//SetImageRating changes a given image's rating in the database
+func (DBConnection *PostgresPlugin) SetImageRating(ID uint64, Rating string) error {
+	_, err := DBConnection.DBHandle.Exec("UPDATE Images SET Rating = ? WHERE ID = ?;", Rating, ID)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "PostgresPlugin/ImageFunctions/SetImageRating", "0", logging.ResultFailure, []string{"Failed to set image rating", err.Error()})
+		return err
+	}
+	return nil
+}
+
+//SetImageSource changes a given image's source in the database
+func (DBConnection *PostgresPlugin) SetImageSource(ID uint64, Source string) error {
+	_, err := DBConnection.DBHandle.Exec("UPDATE Images SET Source = ? WHERE ID = ?;", Source, ID)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "PostgresPlugin/ImageFunctions/SetImageSource", "0", logging.ResultFailure, []string{"Failed to set image source", err.Error()})
+		return err
+	}
+	return nil
+}
+
+//SetImageMetadata changes a given image's dimensions, file size, MIME type and duration in the database
+func (DBConnection *PostgresPlugin) SetImageMetadata(ID uint64, Metadata interfaces.ImageMetadata) error {
+	_, err := DBConnection.DBHandle.Exec("UPDATE Images SET Width = ?, Height = ?, FileSize = ?, MIMEType = ?, Duration = ? WHERE ID = ?;", Metadata.Width, Metadata.Height, Metadata.FileSize, Metadata.MIMEType, Metadata.Duration, ID)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "PostgresPlugin/ImageFunctions/SetImageMetadata", "0", logging.ResultFailure, []string{"Failed to set image metadata", err.Error()})
+		return err
+	}
+	return nil
+}
+
+//SetImagedHash changes a given image's dHash in the database
+func (DBConnection *PostgresPlugin) SetImagedHash(ID uint64, hHash uint64, vHash uint64) error {
+	//Postgres integers are signed, so store the bits of the hash as an int64
+	_, err := DBConnection.DBHandle.Exec("INSERT INTO ImagedHashes (ImageID, hHash, vHash) VALUES (?,?,?) ON CONFLICT (ImageID) DO UPDATE SET hHash = excluded.hHash, vHash = excluded.vHash;", ID, int64(hHash), int64(vHash))
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "PostgresPlugin/ImageFunctions/SetImagedHash", "0", logging.ResultFailure, []string{"Failed to set image dHashes", err.Error()})
+		return err
+	}
+	return nil
+}
+
+//GetImagedHash changes a given image's dHash in the database
+func (DBConnection *PostgresPlugin) GetImagedHash(ID uint64) (uint64, uint64, error) {
+	var hHash, vHash int64
+	err := DBConnection.DBHandle.QueryRow("SELECT hHash, vHash from ImagedHashes WHERE ImageID = ?", ID).Scan(&hHash, &vHash)
+	if err != nil {
+		return uint64(hHash), uint64(vHash), err
+	}
+	return uint64(hHash), uint64(vHash), nil
+}
+
+/*
+//Our select query, if inclusive
+SELECT ImageID, Name, Location FROM (
+	SELECT ImageID, Name, Location, Count(*) as MatchingTags
+	FROM ImageTags
+	INNER JOIN Images ON ImageTags.ImageID=Images.ID
+	[WHERE ][TagID IN (1, 2, 3)]
+		[AND ][ImageID NOT IN (
+								SELECT DISTINCT ImageID FROM ImageTags WHERE TagID IN (4)
+							)]
+	GROUP BY ImageID
+) InnerStatement
+WHERE MatchingTags = 3
+ORDER BY ImageID DESC LIMIT 30 OFFSET 0;
+
+//Our Count Query
+SELECT COUNT(ImageID) FROM (
+	SELECT ImageID, Name, Location, Count(*) as MatchingTags
+	FROM ImageTags
+	INNER JOIN Images ON ImageTags.ImageID=Images.ID
+	WHERE TagID IN (1, 2, 3)
+		AND ImageID NOT IN (
+								SELECT DISTINCT ImageID FROM ImageTags WHERE TagID IN (4)
+							)
+	GROUP BY ImageID
+) InnerStatement
+WHERE MatchingTags = 3
+*/
+
+/*
+//Our select query, if blank or exlusive
+SELECT ImageID, Name, Location FROM Images [WHERE ][ImageID NOT IN (
+		SELECT DISTINCT ImageID FROM ImageTags WHERE TagID IN (4, 5, 6)
+	)]
+ORDER BY ImageID DESC LIMIT 30 OFFSET 0;
+
+//Our Count Query
+SELECT COUNT(*) FROM Images [WHERE ][ImageID NOT IN (
+		SELECT DISTINCT ImageID FROM ImageTags WHERE TagID IN (4, 5, 6)
+	)]
+*/
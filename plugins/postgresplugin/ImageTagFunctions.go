@@ -0,0 +1,143 @@
+package postgresplugin
+
+import (
+	"database/sql"
+	"errors"
+	"go-image-board/interfaces"
+	"go-image-board/logging"
+	"strconv"
+)
+
+//GetImageTags returns a list of TagInformation for all tags that apply to the given image
+func (DBConnection *PostgresPlugin) GetImageTags(ImageID uint64) ([]interfaces.TagInformation, error) {
+	var ToReturn []interfaces.TagInformation
+
+	//SELECT Tags.ID AS ID, Tags.Name AS Name, Tags.Description AS Description FROM ImageTags INNER JOIN Tags ON Tags.ID = ImageTags.TagID WHERE ImageID=?
+
+	sqlQuery := "SELECT Tags.ID, Tags.Name, Tags.Description, Tags.Category FROM ImageTags INNER JOIN Tags ON Tags.ID = ImageTags.TagID WHERE ImageID=?"
+	//Pass the sql query to DB
+	rows, err := DBConnection.DBHandle.Query(sqlQuery, ImageID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	//Placeholders for data returned by each row
+	var Description sql.NullString
+	var ID uint64
+	var Name string
+	var Category string
+	//For each row
+	for rows.Next() {
+		//Parse out the data
+		err := rows.Scan(&ID, &Name, &Description, &Category)
+		if err != nil {
+			return nil, err
+		}
+		//If description is a valid non-null value, use it, else, use ""
+		var SDescription string
+		if Description.Valid {
+			SDescription = Description.String
+		}
+		//Add this result to ToReturn
+		ToReturn = append(ToReturn, interfaces.TagInformation{Name: Name, ID: ID, Description: SDescription, Exists: true, Exclude: false, Category: Category})
+	}
+	return ToReturn, nil
+}
+
+//RemoveTag remove a tag association
+func (DBConnection *PostgresPlugin) RemoveTag(TagID uint64, ImageID uint64, RemoverID uint64) error {
+	resultInfo, err := DBConnection.DBHandle.Exec("DELETE FROM ImageTags WHERE TagID=? AND ImageID=?;", TagID, ImageID)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "PostgresPlugin/RemoveTag", strconv.FormatUint(RemoverID, 10), logging.ResultFailure, []string{"Tag to remove was not on image", strconv.FormatUint(TagID, 10), strconv.FormatUint(ImageID, 10), err.Error()})
+		return err
+	}
+	if affected, _ := resultInfo.RowsAffected(); affected > 0 {
+		if err := DBConnection.recordTagChange(ImageID, TagID, RemoverID, false, interfaces.TagChangeRemove); err != nil {
+			return err
+		}
+	}
+	logging.WriteLog(logging.LogLevelError, "PostgresPlugin/RemoveTag", strconv.FormatUint(RemoverID, 10), logging.ResultSuccess, []string{"Tag removed", strconv.FormatUint(TagID, 10), strconv.FormatUint(ImageID, 10)})
+	return nil
+}
+
+//tagsContainID is a helper function to check if a TagInformation slice contains a specified ID
+func tagsContainID(ID uint64, Tags []interfaces.TagInformation) bool {
+	for _, Tag := range Tags {
+		if Tag.ID == ID {
+			return true
+		}
+	}
+	return false
+}
+
+//ReplaceImageTags replaces all instances of ImageTags that have the specified tag with the new tag
+func (DBConnection *PostgresPlugin) ReplaceImageTags(OldTagID uint64, NewTagID uint64, LinkerID uint64) error {
+	//Record the changes first, as afterwards there is no telling which images had the old tag
+	_, err := DBConnection.DBHandle.Exec("INSERT INTO ImageTagHistory (ImageID, TagID, UserID, Added, Operation) SELECT ImageID, CAST(? AS BIGINT), CAST(? AS BIGINT), TRUE, CAST(? AS VARCHAR) FROM ImageTags WHERE TagID=? AND ImageID NOT IN (SELECT ImageID FROM ImageTags WHERE TagID=?);", NewTagID, LinkerID, interfaces.TagChangeReplace, OldTagID, NewTagID)
+	if err == nil {
+		_, err = DBConnection.DBHandle.Exec("INSERT INTO ImageTagHistory (ImageID, TagID, UserID, Added, Operation) SELECT ImageID, TagID, CAST(? AS BIGINT), FALSE, CAST(? AS VARCHAR) FROM ImageTags WHERE TagID=?;", LinkerID, interfaces.TagChangeReplace, OldTagID)
+	}
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "PostgresPlugin/ReplaceImageTags", strconv.FormatUint(LinkerID, 10), logging.ResultFailure, []string{"Failed to record tag history", err.Error()})
+		return err
+	}
+	query := `UPDATE ImageTags
+	SET TagID = ? , LinkerID=?
+	WHERE TagID=? AND ImageID NOT IN
+	(
+		SELECT ImageID from ImageTags WHERE TagID=?
+	);`
+	_, err = DBConnection.DBHandle.Exec(query, NewTagID, LinkerID, OldTagID, NewTagID)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "PostgresPlugin/ReplaceImageTags", strconv.FormatUint(LinkerID, 10), logging.ResultFailure, []string{"Failed to update imagetags", err.Error()})
+		return err
+	}
+	//Remove any instances of old tag, first query replaces the old tag on all images, but does not allow duplicates. This query will remove the old tag that would have been replaced if it would not have lead to a duplicate.
+	_, err = DBConnection.DBHandle.Exec("DELETE FROM ImageTags WHERE TagID=?;", OldTagID)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "PostgresPlugin/ReplaceImageTags", strconv.FormatUint(LinkerID, 10), logging.ResultFailure, []string{"Failed to remove old instances of tag", err.Error()})
+		return err
+	}
+	return nil
+}
+
+//BulkAddTag adds an association of a tag to image into the association table that already have another tag
+func (DBConnection *PostgresPlugin) BulkAddTag(TagID uint64, OldTagID uint64, LinkerID uint64) error {
+	//Prevent adding alias
+	tagInfo, err := DBConnection.GetTag(TagID, false)
+	oldTagInfo, err2 := DBConnection.GetTag(OldTagID, false)
+	if err != nil || err2 != nil {
+		return errors.New("Failed to validate tags")
+	}
+
+	//If this is an alias, then add aliasedid instead
+	if tagInfo.IsAlias {
+		TagID = tagInfo.AliasedID
+	}
+
+	//Similiarly convert oldTag if it is an alias
+	if oldTagInfo.IsAlias {
+		OldTagID = oldTagInfo.AliasedID
+	}
+
+	if _, err := DBConnection.DBHandle.Exec("INSERT INTO ImageTagHistory (ImageID, TagID, UserID, Added, Operation) SELECT ImageID, CAST(? AS BIGINT), CAST(? AS BIGINT), TRUE, CAST(? AS VARCHAR) FROM ImageTags WHERE TagID=? AND ImageID NOT IN (SELECT ImageID FROM ImageTags WHERE TagID=?);", TagID, LinkerID, interfaces.TagChangeBulkAdd, OldTagID, TagID); err != nil {
+		logging.WriteLog(logging.LogLevelError, "PostgresPlugin/BulkAddTag", strconv.FormatUint(LinkerID, 10), logging.ResultFailure, []string{"Failed to record tag history", strconv.FormatUint(OldTagID, 10), strconv.FormatUint(TagID, 10), err.Error()})
+		return err
+	}
+	if _, err := DBConnection.DBHandle.Exec("INSERT INTO ImageTags (TagID, ImageID, LinkerID) SELECT CAST(? AS BIGINT), ImageID, CAST(? AS BIGINT) FROM ImageTags WHERE TagID=? AND ImageID NOT IN (SELECT ImageID FROM ImageTags WHERE TagID=?);", TagID, LinkerID, OldTagID, TagID); err != nil {
+		logging.WriteLog(logging.LogLevelError, "PostgresPlugin/BulkAddTag", strconv.FormatUint(LinkerID, 10), logging.ResultFailure, []string{"Tag not added to image", strconv.FormatUint(OldTagID, 10), strconv.FormatUint(TagID, 10), err.Error()})
+		return err
+	}
+	logging.WriteLog(logging.LogLevelError, "PostgresPlugin/BulkAddTag", strconv.FormatUint(LinkerID, 10), logging.ResultSuccess, []string{"Tags added", strconv.FormatUint(OldTagID, 10), strconv.FormatUint(TagID, 10)})
+	return nil
+}
+
+//uint64SliceContains is a helper function that returns whether a slice contains a specifc ID
+func uint64SliceContains(slice []uint64, item uint64) bool {
+	for _, sliceItem := range slice {
+		if sliceItem == item {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,168 @@
+package postgresplugin
+
+import (
+	"database/sql"
+	"errors"
+	"go-image-board/interfaces"
+	"go-image-board/logging"
+	"strconv"
+)
+
+//NewNote adds a note to Note.ImageID, created by Note.CreatorID, and keeps it as the first revision. Returns its ID
+func (DBConnection *PostgresPlugin) NewNote(Note interfaces.NoteInformation) (uint64, error) {
+	var id uint64
+	err := DBConnection.DBHandle.QueryRow("INSERT INTO Notes (ImageID, CreatorID, X, Y, Width, Height, ImageWidth, ImageHeight, Body) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING ID;", Note.ImageID, Note.CreatorID, Note.X, Note.Y, Note.Width, Note.Height, Note.ImageWidth, Note.ImageHeight, Note.Body).Scan(&id)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "PostgresPlugin/NewNote", strconv.FormatUint(Note.CreatorID, 10), logging.ResultFailure, []string{"Failed to add note", err.Error()})
+		return 0, err
+	}
+	if err := DBConnection.addNoteRevision(id, Note.NoteRegion, Note.Body, false, Note.CreatorID); err != nil {
+		//A note without its first revision would have no history, so remove it
+		DBConnection.DBHandle.Exec("DELETE FROM Notes WHERE ID = ?;", id)
+		logging.WriteLog(logging.LogLevelError, "PostgresPlugin/NewNote", strconv.FormatUint(Note.CreatorID, 10), logging.ResultFailure, []string{"Failed to add note revision", err.Error()})
+		return 0, err
+	}
+	logging.WriteLog(logging.LogLevelInfo, "PostgresPlugin/NewNote", strconv.FormatUint(Note.CreatorID, 10), logging.ResultSuccess, []string{"Note added", strconv.FormatUint(id, 10)})
+	return id, nil
+}
+
+//GetNote returns a single note, including deleted ones
+func (DBConnection *PostgresPlugin) GetNote(NoteID uint64) (interfaces.NoteInformation, error) {
+	notes, err := DBConnection.queryNotes("WHERE Notes.ID = ?;", NoteID)
+	if err == nil && len(notes) == 0 {
+		err = sql.ErrNoRows
+	}
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "PostgresPlugin/GetNote", "0", logging.ResultFailure, []string{"Failed to get note", strconv.FormatUint(NoteID, 10), err.Error()})
+		return interfaces.NoteInformation{}, err
+	}
+	return notes[0], nil
+}
+
+//GetNotes returns the notes on an image, oldest first, deleted notes are only included if requested
+func (DBConnection *PostgresPlugin) GetNotes(ImageID uint64, IncludeDeleted bool) ([]interfaces.NoteInformation, error) {
+	suffix := "WHERE Notes.ImageID = ? "
+	if IncludeDeleted == false {
+		suffix += "AND Notes.DeletedTime IS NULL "
+	}
+	notes, err := DBConnection.queryNotes(suffix+"ORDER BY Notes.ID;", ImageID)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "PostgresPlugin/GetNotes", "0", logging.ResultFailure, []string{"Failed to get notes", strconv.FormatUint(ImageID, 10), err.Error()})
+		return nil, err
+	}
+	return notes, nil
+}
+
+//UpdateNote replaces the region and body of a note, shows it again if it was deleted, and keeps the result as a revision
+func (DBConnection *PostgresPlugin) UpdateNote(NoteID uint64, Region interfaces.NoteRegion, Body string, EditorID uint64) error {
+	note, err := DBConnection.GetNote(NoteID)
+	if err != nil {
+		return err
+	}
+	_, err = DBConnection.DBHandle.Exec("UPDATE Notes SET X = ?, Y = ?, Width = ?, Height = ?, ImageWidth = ?, ImageHeight = ?, Body = ?, EditTime = CURRENT_TIMESTAMP, DeletedTime = NULL, DeleterID = 0 WHERE ID = ?;", Region.X, Region.Y, Region.Width, Region.Height, Region.ImageWidth, Region.ImageHeight, Body, NoteID)
+	if err == nil {
+		err = DBConnection.addNoteRevision(NoteID, Region, Body, false, EditorID)
+		if err != nil {
+			//Without a revision the history would not match the note, so put it back
+			DBConnection.DBHandle.Exec("UPDATE Notes SET X = ?, Y = ?, Width = ?, Height = ?, ImageWidth = ?, ImageHeight = ?, Body = ? WHERE ID = ?;", note.X, note.Y, note.Width, note.Height, note.ImageWidth, note.ImageHeight, note.Body, NoteID)
+		}
+	}
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "PostgresPlugin/UpdateNote", strconv.FormatUint(EditorID, 10), logging.ResultFailure, []string{"Failed to update note", strconv.FormatUint(NoteID, 10), err.Error()})
+		return err
+	}
+	logging.WriteLog(logging.LogLevelInfo, "PostgresPlugin/UpdateNote", strconv.FormatUint(EditorID, 10), logging.ResultSuccess, []string{"Note updated", strconv.FormatUint(NoteID, 10)})
+	return nil
+}
+
+//DeleteNote hides a note, keeping a revision that records the deletion
+func (DBConnection *PostgresPlugin) DeleteNote(NoteID uint64, DeleterID uint64) error {
+	note, err := DBConnection.GetNote(NoteID)
+	if err != nil {
+		return err
+	}
+	resultInfo, err := DBConnection.DBHandle.Exec("UPDATE Notes SET DeletedTime = CURRENT_TIMESTAMP, DeleterID = ? WHERE ID = ? AND DeletedTime IS NULL;", DeleterID, NoteID)
+	if err == nil {
+		if affected, _ := resultInfo.RowsAffected(); affected == 0 {
+			err = errors.New("note is already deleted")
+		}
+	}
+	if err == nil {
+		err = DBConnection.addNoteRevision(NoteID, note.NoteRegion, note.Body, true, DeleterID)
+		if err != nil {
+			DBConnection.DBHandle.Exec("UPDATE Notes SET DeletedTime = NULL, DeleterID = 0 WHERE ID = ?;", NoteID)
+		}
+	}
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "PostgresPlugin/DeleteNote", strconv.FormatUint(DeleterID, 10), logging.ResultFailure, []string{"Failed to delete note", strconv.FormatUint(NoteID, 10), err.Error()})
+		return err
+	}
+	logging.WriteLog(logging.LogLevelInfo, "PostgresPlugin/DeleteNote", strconv.FormatUint(DeleterID, 10), logging.ResultSuccess, []string{"Note deleted", strconv.FormatUint(NoteID, 10)})
+	return nil
+}
+
+//GetNoteRevisions returns every version of a note, newest first
+func (DBConnection *PostgresPlugin) GetNoteRevisions(NoteID uint64) ([]interfaces.NoteRevisionInformation, error) {
+	rows, err := DBConnection.DBHandle.Query(`SELECT NoteRevisions.ID, NoteRevisions.NoteID, NoteRevisions.X, NoteRevisions.Y, NoteRevisions.Width, NoteRevisions.Height, NoteRevisions.ImageWidth, NoteRevisions.ImageHeight, NoteRevisions.Body, NoteRevisions.Deleted, NoteRevisions.EditorID, COALESCE(Users.Name, ''), NoteRevisions.EditTime
+	FROM NoteRevisions
+	LEFT OUTER JOIN Users ON NoteRevisions.EditorID = Users.ID
+	WHERE NoteRevisions.NoteID = ? ORDER BY NoteRevisions.ID DESC;`, NoteID)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "PostgresPlugin/GetNoteRevisions", "0", logging.ResultFailure, []string{"Failed to get note revisions", strconv.FormatUint(NoteID, 10), err.Error()})
+		return nil, err
+	}
+	defer rows.Close()
+	var ToReturn []interfaces.NoteRevisionInformation
+	for rows.Next() {
+		var revision interfaces.NoteRevisionInformation
+		var EditTime sql.NullTime
+		if err := rows.Scan(&revision.ID, &revision.NoteID, &revision.X, &revision.Y, &revision.Width, &revision.Height, &revision.ImageWidth, &revision.ImageHeight, &revision.Body, &revision.Deleted, &revision.EditorID, &revision.EditorName, &EditTime); err != nil {
+			return nil, err
+		}
+		revision.EditTime = EditTime.Time
+		ToReturn = append(ToReturn, revision)
+	}
+	return ToReturn, rows.Err()
+}
+
+//addNoteRevision keeps a version of a note
+func (DBConnection *PostgresPlugin) addNoteRevision(NoteID uint64, Region interfaces.NoteRegion, Body string, Deleted bool, EditorID uint64) error {
+	_, err := DBConnection.DBHandle.Exec("INSERT INTO NoteRevisions (NoteID, X, Y, Width, Height, ImageWidth, ImageHeight, Body, Deleted, EditorID) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?);", NoteID, Region.X, Region.Y, Region.Width, Region.Height, Region.ImageWidth, Region.ImageHeight, Body, Deleted, EditorID)
+	return err
+}
+
+//deleteNotes removes every note on an image, along with their revisions
+func (DBConnection *PostgresPlugin) deleteNotes(ImageID uint64) error {
+	_, err := DBConnection.DBHandle.Exec("DELETE FROM NoteRevisions WHERE NoteID IN (SELECT ID FROM Notes WHERE ImageID = ?);", ImageID)
+	if err == nil {
+		_, err = DBConnection.DBHandle.Exec("DELETE FROM Notes WHERE ImageID = ?;", ImageID)
+	}
+	return err
+}
+
+//queryNotes returns notes, Suffix is added after the joins to filter and order them
+func (DBConnection *PostgresPlugin) queryNotes(Suffix string, Arguments ...interface{}) ([]interfaces.NoteInformation, error) {
+	rows, err := DBConnection.DBHandle.Query(`SELECT Notes.ID, Notes.ImageID, Notes.CreatorID, COALESCE(Users.Name, ''), Notes.X, Notes.Y, Notes.Width, Notes.Height, Notes.ImageWidth, Notes.ImageHeight, Notes.Body, Notes.CreationTime, Notes.EditTime, Notes.DeletedTime, Notes.DeleterID
+	FROM Notes
+	LEFT OUTER JOIN Users ON Notes.CreatorID = Users.ID `+Suffix, Arguments...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ToReturn []interfaces.NoteInformation
+	for rows.Next() {
+		var note interfaces.NoteInformation
+		var CreationTime sql.NullTime
+		var EditTime sql.NullTime
+		var DeletedTime sql.NullTime
+		if err := rows.Scan(&note.ID, &note.ImageID, &note.CreatorID, &note.CreatorName, &note.X, &note.Y, &note.Width, &note.Height, &note.ImageWidth, &note.ImageHeight, &note.Body, &CreationTime, &EditTime, &DeletedTime, &note.DeleterID); err != nil {
+			return nil, err
+		}
+		note.CreationTime = CreationTime.Time
+		note.EditTime = EditTime.Time
+		note.Deleted = DeletedTime.Valid
+		note.DeletedTime = DeletedTime.Time
+		ToReturn = append(ToReturn, note)
+	}
+	return ToReturn, rows.Err()
+}
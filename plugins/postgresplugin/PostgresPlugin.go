@@ -0,0 +1,127 @@
+package postgresplugin
+
+import (
+	"database/sql"
+	"go-image-board/config"
+	"go-image-board/logging"
+	"go-image-board/plugins/postgresplugin/migrations"
+	"go-image-board/plugins/migrate"
+	"go-image-board/plugins/sqlsearch"
+	"math/rand"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	//Postgres driver for database/sql
+	_ "github.com/lib/pq"
+)
+
+//auditLogRetention how long audit logs are kept before the cleanup task removes them
+var auditLogRetention = "30 days"
+
+//PostgresPlugin acts as plugin between gib and a PostgreSQL database
+type PostgresPlugin struct {
+	DBHandle *RebindDB
+	//PasswordHashCost bcrypt cost used for passwords and security answers, 0 uses the same cost as the other plugins
+	PasswordHashCost int
+	//cleanupOnce ensures we only start one maintenance routine, even if InitDatabase is retried
+	cleanupOnce sync.Once
+}
+
+//searcher runs image, collection and tag searches against this database
+func (DBConnection *PostgresPlugin) searcher() *sqlsearch.Searcher {
+	return &sqlsearch.Searcher{DB: DBConnection.DBHandle, Dialect: sqlsearch.Postgres, Lookup: DBConnection}
+}
+
+//RebindDB wraps a sql.DB, converting the ? placeholders used throughout gib into Postgres' numbered $1, $2... placeholders
+type RebindDB struct {
+	*sql.DB
+}
+
+//Exec executes a query without returning any rows
+func (handle *RebindDB) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return handle.DB.Exec(rebind(query, len(args)), args...)
+}
+
+//Query executes a query that returns rows
+func (handle *RebindDB) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return handle.DB.Query(rebind(query, len(args)), args...)
+}
+
+//QueryRow executes a query that is expected to return at most one row
+func (handle *RebindDB) QueryRow(query string, args ...interface{}) *sql.Row {
+	return handle.DB.QueryRow(rebind(query, len(args)), args...)
+}
+
+//rebind replaces each ? outside of a quoted string or identifier with $1, $2 and so on
+func rebind(query string, argCount int) string {
+	if argCount == 0 {
+		return query
+	}
+	var builder strings.Builder
+	builder.Grow(len(query) + argCount*2)
+	var quote rune
+	placeholder := 0
+	for _, character := range query {
+		switch {
+		case quote != 0:
+			//Doubled quotes, as in 'it''s', simply close and re-open the literal
+			if character == quote {
+				quote = 0
+			}
+		case character == '\'' || character == '"':
+			quote = character
+		case character == '?':
+			placeholder++
+			builder.WriteString("$" + strconv.Itoa(placeholder))
+			continue
+		}
+		builder.WriteRune(character)
+	}
+	return builder.String()
+}
+
+//InitDatabase connects to a database, and if needed, creates and or updates tables
+func (DBConnection *PostgresPlugin) InitDatabase() error {
+	rand.Seed(time.Now().UnixNano())
+	//https://pkg.go.dev/github.com/lib/pq#hdr-Connection_String_Parameters
+	port := config.Configuration.DBPort
+	if port == "" {
+		port = "5432"
+	}
+	connectionURL := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(config.Configuration.DBUser, config.Configuration.DBPassword),
+		Host:     net.JoinHostPort(config.Configuration.DBHost, port),
+		Path:     "/" + config.Configuration.DBName,
+		RawQuery: url.Values{"sslmode": {config.Configuration.DBSSLMode}}.Encode(),
+	}
+	handle, err := sql.Open("postgres", connectionURL.String())
+	if err == nil {
+		DBConnection.DBHandle = &RebindDB{DB: handle}
+		err = DBConnection.DBHandle.Ping() //Ping actually validates we can query database
+		if err == nil {
+			runner := migrate.Runner{DB: DBConnection.DBHandle.DB, Dialect: migrate.Postgres, Registry: migrations.Registry}
+			if err := runner.Run(); err != nil {
+				return err
+			}
+			//Postgres has no event scheduler, so we run our own maintenance
+			DBConnection.cleanupOnce.Do(func() { go DBConnection.maintenanceLoop() })
+		}
+	}
+
+	return err
+}
+
+//maintenanceLoop performs the work MariaDB would otherwise do in scheduled events
+func (DBConnection *PostgresPlugin) maintenanceLoop() {
+	for {
+		if _, err := DBConnection.DBHandle.Exec("DELETE FROM AuditLogs WHERE LogTime < NOW() - CAST(? AS INTERVAL);", auditLogRetention); err != nil {
+			logging.WriteLog(logging.LogLevelError, "PostgresPlugin/maintenanceLoop", "0", logging.ResultFailure, []string{"Failed to cleanup audit logs", err.Error()})
+		}
+		time.Sleep(24 * time.Hour)
+	}
+}
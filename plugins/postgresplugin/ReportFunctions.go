@@ -0,0 +1,111 @@
+package postgresplugin
+
+import (
+	"database/sql"
+	"errors"
+	"go-image-board/interfaces"
+	"go-image-board/logging"
+	"strconv"
+)
+
+//NewReport files a report by Report.ReporterID against Report.TargetType and Report.TargetID, it starts open. Returns its ID
+func (DBConnection *PostgresPlugin) NewReport(Report interfaces.ReportInformation) (uint64, error) {
+	var id uint64
+	err := DBConnection.DBHandle.QueryRow("INSERT INTO Reports (TargetType, TargetID, ReporterID, Category, Reason, Status, Outcome) VALUES (?, ?, ?, ?, ?, ?, '') RETURNING ID;", Report.TargetType, Report.TargetID, Report.ReporterID, Report.Category, Report.Reason, interfaces.ReportOpen).Scan(&id)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "PostgresPlugin/NewReport", strconv.FormatUint(Report.ReporterID, 10), logging.ResultFailure, []string{"Failed to add report", err.Error()})
+		return 0, err
+	}
+	logging.WriteLog(logging.LogLevelInfo, "PostgresPlugin/NewReport", strconv.FormatUint(Report.ReporterID, 10), logging.ResultSuccess, []string{"Report added", strconv.FormatUint(id, 10)})
+	return id, nil
+}
+
+//GetReport returns a single report
+func (DBConnection *PostgresPlugin) GetReport(ReportID uint64) (interfaces.ReportInformation, error) {
+	reports, err := DBConnection.queryReports("WHERE Reports.ID = ?;", ReportID)
+	if err == nil && len(reports) == 0 {
+		err = sql.ErrNoRows
+	}
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "PostgresPlugin/GetReport", "0", logging.ResultFailure, []string{"Failed to get report", strconv.FormatUint(ReportID, 10), err.Error()})
+		return interfaces.ReportInformation{}, err
+	}
+	return reports[0], nil
+}
+
+//GetReports returns a page of the reports with the given status, or every report if Status is blank, oldest first, and the count of all of them
+func (DBConnection *PostgresPlugin) GetReports(Status string, PageStart uint64, PageStride uint64) ([]interfaces.ReportInformation, uint64, error) {
+	filter := ""
+	var Arguments []interface{}
+	if Status != "" {
+		filter = "WHERE Reports.Status = ? "
+		Arguments = append(Arguments, Status)
+	}
+	var MaxResults uint64
+	if err := DBConnection.DBHandle.QueryRow("SELECT COUNT(*) FROM Reports "+filter+";", Arguments...).Scan(&MaxResults); err != nil {
+		logging.WriteLog(logging.LogLevelError, "PostgresPlugin/GetReports", "0", logging.ResultFailure, []string{"Error running count query", err.Error()})
+		return nil, 0, err
+	}
+	reports, err := DBConnection.queryReports(filter+"ORDER BY Reports.ID LIMIT ? OFFSET ?;", append(Arguments, PageStride, PageStart)...)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "PostgresPlugin/GetReports", "0", logging.ResultFailure, []string{"Failed to get reports", err.Error()})
+		return nil, 0, err
+	}
+	return reports, MaxResults, nil
+}
+
+//GetUserReports returns a page of the reports filed by a user, newest first, and the count of all of them
+func (DBConnection *PostgresPlugin) GetUserReports(ReporterID uint64, PageStart uint64, PageStride uint64) ([]interfaces.ReportInformation, uint64, error) {
+	var MaxResults uint64
+	if err := DBConnection.DBHandle.QueryRow("SELECT COUNT(*) FROM Reports WHERE ReporterID = ?;", ReporterID).Scan(&MaxResults); err != nil {
+		logging.WriteLog(logging.LogLevelError, "PostgresPlugin/GetUserReports", strconv.FormatUint(ReporterID, 10), logging.ResultFailure, []string{"Error running count query", err.Error()})
+		return nil, 0, err
+	}
+	reports, err := DBConnection.queryReports("WHERE Reports.ReporterID = ? ORDER BY Reports.ID DESC LIMIT ? OFFSET ?;", ReporterID, PageStride, PageStart)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "PostgresPlugin/GetUserReports", strconv.FormatUint(ReporterID, 10), logging.ResultFailure, []string{"Failed to get reports", err.Error()})
+		return nil, 0, err
+	}
+	return reports, MaxResults, nil
+}
+
+//UpdateReport sets the status of a report that is not closed, along with the moderator that changed it and their outcome
+func (DBConnection *PostgresPlugin) UpdateReport(ReportID uint64, Status string, ModeratorID uint64, Outcome string) error {
+	resultInfo, err := DBConnection.DBHandle.Exec("UPDATE Reports SET Status = ?, ModeratorID = ?, Outcome = ?, UpdateTime = CURRENT_TIMESTAMP WHERE ID = ? AND Status IN (?, ?);", Status, ModeratorID, Outcome, ReportID, interfaces.ReportOpen, interfaces.ReportClaimed)
+	if err == nil {
+		if affected, _ := resultInfo.RowsAffected(); affected == 0 {
+			err = errors.New("report does not exist or is already closed")
+		}
+	}
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "PostgresPlugin/UpdateReport", strconv.FormatUint(ModeratorID, 10), logging.ResultFailure, []string{"Failed to update report", strconv.FormatUint(ReportID, 10), err.Error()})
+		return err
+	}
+	logging.WriteLog(logging.LogLevelInfo, "PostgresPlugin/UpdateReport", strconv.FormatUint(ModeratorID, 10), logging.ResultSuccess, []string{"Report updated", strconv.FormatUint(ReportID, 10), Status})
+	return nil
+}
+
+//queryReports returns reports with the names of their reporter and moderator, Suffix is added after the joins to filter and order them
+func (DBConnection *PostgresPlugin) queryReports(Suffix string, Arguments ...interface{}) ([]interfaces.ReportInformation, error) {
+	rows, err := DBConnection.DBHandle.Query(`SELECT Reports.ID, Reports.TargetType, Reports.TargetID, Reports.ReporterID, COALESCE(Reporters.Name, ''), Reports.Category, Reports.Reason, Reports.Status, Reports.ModeratorID, COALESCE(Moderators.Name, ''), Reports.Outcome, Reports.CreationTime, Reports.UpdateTime
+	FROM Reports
+	LEFT OUTER JOIN Users AS Reporters ON Reports.ReporterID = Reporters.ID
+	LEFT OUTER JOIN Users AS Moderators ON Reports.ModeratorID = Moderators.ID AND Reports.ModeratorID <> 0 `+Suffix, Arguments...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ToReturn []interfaces.ReportInformation
+	for rows.Next() {
+		var report interfaces.ReportInformation
+		var CreationTime sql.NullTime
+		var UpdateTime sql.NullTime
+		if err := rows.Scan(&report.ID, &report.TargetType, &report.TargetID, &report.ReporterID, &report.ReporterName, &report.Category, &report.Reason, &report.Status, &report.ModeratorID, &report.ModeratorName, &report.Outcome, &CreationTime, &UpdateTime); err != nil {
+			return nil, err
+		}
+		report.CreationTime = CreationTime.Time
+		report.UpdateTime = UpdateTime.Time
+		ToReturn = append(ToReturn, report)
+	}
+	return ToReturn, rows.Err()
+}
@@ -0,0 +1,96 @@
+package postgresplugin
+
+import (
+	"database/sql"
+	"go-image-board/interfaces"
+	"go-image-board/logging"
+	"strconv"
+	"time"
+)
+
+//GetImageTagHistory returns every tag change made to an image, newest first
+func (DBConnection *PostgresPlugin) GetImageTagHistory(ImageID uint64) ([]interfaces.TagChangeInformation, error) {
+	ToReturn, err := DBConnection.queryTagHistory("WHERE ImageTagHistory.ImageID = ? ORDER BY ImageTagHistory.ID DESC;", ImageID)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "PostgresPlugin/GetImageTagHistory", "0", logging.ResultFailure, []string{"Failed to get tag history", strconv.FormatUint(ImageID, 10), err.Error()})
+		return nil, err
+	}
+	return ToReturn, nil
+}
+
+//GetUserTagHistory returns the tag changes made by a user between Since and Until, newest first
+func (DBConnection *PostgresPlugin) GetUserTagHistory(UserID uint64, Since time.Time, Until time.Time) ([]interfaces.TagChangeInformation, error) {
+	ToReturn, err := DBConnection.queryTagHistory("WHERE ImageTagHistory.UserID = ? AND ImageTagHistory.ChangeTime >= ? AND ImageTagHistory.ChangeTime <= ? ORDER BY ImageTagHistory.ID DESC;", UserID, Since, Until)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "PostgresPlugin/GetUserTagHistory", strconv.FormatUint(UserID, 10), logging.ResultFailure, []string{"Failed to get tag history", err.Error()})
+		return nil, err
+	}
+	return ToReturn, nil
+}
+
+//UndoTagChanges reverses each change in order, skipping those already undone by later changes, returns how many were reversed
+func (DBConnection *PostgresPlugin) UndoTagChanges(Changes []interfaces.TagChangeInformation, UserID uint64) (uint64, error) {
+	var undone uint64
+	for _, change := range Changes {
+		var resultInfo sql.Result
+		var err error
+		if change.Added {
+			resultInfo, err = DBConnection.DBHandle.Exec("DELETE FROM ImageTags WHERE TagID = ? AND ImageID = ?;", change.TagID, change.ImageID)
+		} else {
+			//A removed tag may have become an alias since, in which case the tag it aliases is added instead
+			tagInfo, tagErr := DBConnection.GetTag(change.TagID, false)
+			if tagErr != nil {
+				continue
+			}
+			if tagInfo.IsAlias {
+				change.TagID = tagInfo.AliasedID
+			}
+			resultInfo, err = DBConnection.DBHandle.Exec("INSERT INTO ImageTags (TagID, ImageID, LinkerID) SELECT CAST(? AS BIGINT), ID, CAST(? AS BIGINT) FROM Images WHERE ID = ? AND ID NOT IN (SELECT ImageID FROM ImageTags WHERE TagID = ?);", change.TagID, UserID, change.ImageID, change.TagID)
+		}
+		if err != nil {
+			logging.WriteLog(logging.LogLevelError, "PostgresPlugin/UndoTagChanges", strconv.FormatUint(UserID, 10), logging.ResultFailure, []string{"Failed to undo tag change", strconv.FormatUint(change.ID, 10), err.Error()})
+			return undone, err
+		}
+		if affected, _ := resultInfo.RowsAffected(); affected == 0 {
+			continue
+		}
+		if err := DBConnection.recordTagChange(change.ImageID, change.TagID, UserID, change.Added == false, interfaces.TagChangeRevert); err != nil {
+			return undone, err
+		}
+		undone++
+	}
+	logging.WriteLog(logging.LogLevelInfo, "PostgresPlugin/UndoTagChanges", strconv.FormatUint(UserID, 10), logging.ResultSuccess, []string{"Tag changes undone", strconv.FormatUint(undone, 10)})
+	return undone, nil
+}
+
+//recordTagChange adds a single entry to an image's tag history
+func (DBConnection *PostgresPlugin) recordTagChange(ImageID uint64, TagID uint64, UserID uint64, Added bool, Operation string) error {
+	if _, err := DBConnection.DBHandle.Exec("INSERT INTO ImageTagHistory (ImageID, TagID, UserID, Added, Operation) VALUES (?, ?, ?, ?, ?);", ImageID, TagID, UserID, Added, Operation); err != nil {
+		logging.WriteLog(logging.LogLevelError, "PostgresPlugin/recordTagChange", strconv.FormatUint(UserID, 10), logging.ResultFailure, []string{"Failed to record tag change", strconv.FormatUint(ImageID, 10), strconv.FormatUint(TagID, 10), err.Error()})
+		return err
+	}
+	return nil
+}
+
+//queryTagHistory returns tag changes, Suffix is added after the joins to filter and order them
+func (DBConnection *PostgresPlugin) queryTagHistory(Suffix string, Arguments ...interface{}) ([]interfaces.TagChangeInformation, error) {
+	rows, err := DBConnection.DBHandle.Query(`SELECT ImageTagHistory.ID, ImageTagHistory.ImageID, ImageTagHistory.TagID, COALESCE(Tags.Name, ''), ImageTagHistory.UserID, COALESCE(Users.Name, ''), ImageTagHistory.Added, ImageTagHistory.Operation, ImageTagHistory.ChangeTime
+	FROM ImageTagHistory
+	LEFT OUTER JOIN Tags ON ImageTagHistory.TagID = Tags.ID
+	LEFT OUTER JOIN Users ON ImageTagHistory.UserID = Users.ID `+Suffix, Arguments...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ToReturn []interfaces.TagChangeInformation
+	for rows.Next() {
+		var change interfaces.TagChangeInformation
+		var ChangeTime sql.NullTime
+		if err := rows.Scan(&change.ID, &change.ImageID, &change.TagID, &change.TagName, &change.UserID, &change.UserName, &change.Added, &change.Operation, &ChangeTime); err != nil {
+			return nil, err
+		}
+		change.ChangeTime = ChangeTime.Time
+		ToReturn = append(ToReturn, change)
+	}
+	return ToReturn, rows.Err()
+}
@@ -0,0 +1,177 @@
+package postgresplugin
+
+import (
+	"database/sql"
+	"errors"
+	"go-image-board/interfaces"
+	"go-image-board/logging"
+	"strconv"
+	"strings"
+)
+
+//AddTagImplication adds a rule so that images given TagID are also given ImpliedTagID, errors if the rule would create a cycle
+func (DBConnection *PostgresPlugin) AddTagImplication(TagID uint64, ImpliedTagID uint64, CreatorID uint64) error {
+	TagID, ImpliedTagID, err := DBConnection.validateTagImplication(TagID, ImpliedTagID)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "PostgresPlugin/AddTagImplication", strconv.FormatUint(CreatorID, 10), logging.ResultFailure, []string{"Failed to add tag implication", strconv.FormatUint(TagID, 10), strconv.FormatUint(ImpliedTagID, 10), err.Error()})
+		return err
+	}
+	if _, err := DBConnection.DBHandle.Exec("INSERT INTO TagImplications (TagID, ImpliedTagID, CreatorID) VALUES (?, ?, ?);", TagID, ImpliedTagID, CreatorID); err != nil {
+		logging.WriteLog(logging.LogLevelError, "PostgresPlugin/AddTagImplication", strconv.FormatUint(CreatorID, 10), logging.ResultFailure, []string{"Failed to add tag implication", strconv.FormatUint(TagID, 10), strconv.FormatUint(ImpliedTagID, 10), err.Error()})
+		return err
+	}
+	logging.WriteLog(logging.LogLevelInfo, "PostgresPlugin/AddTagImplication", strconv.FormatUint(CreatorID, 10), logging.ResultSuccess, []string{"Tag implication added", strconv.FormatUint(TagID, 10), strconv.FormatUint(ImpliedTagID, 10)})
+	return nil
+}
+
+//validateTagImplication swaps aliases for the tags they alias, then ensures the implication is not a duplicate and would not create a cycle
+func (DBConnection *PostgresPlugin) validateTagImplication(TagID uint64, ImpliedTagID uint64) (uint64, uint64, error) {
+	tagInfo, err := DBConnection.GetTag(TagID, false)
+	impliedTagInfo, err2 := DBConnection.GetTag(ImpliedTagID, false)
+	if err != nil || err2 != nil {
+		return TagID, ImpliedTagID, errors.New("Failed to validate tags")
+	}
+	if tagInfo.IsAlias {
+		TagID = tagInfo.AliasedID
+	}
+	if impliedTagInfo.IsAlias {
+		ImpliedTagID = impliedTagInfo.AliasedID
+	}
+	if TagID == ImpliedTagID {
+		return TagID, ImpliedTagID, errors.New("a tag cannot imply itself")
+	}
+	var count uint64
+	if err := DBConnection.DBHandle.QueryRow("SELECT COUNT(*) FROM TagImplications WHERE TagID = ? AND ImpliedTagID = ?;", TagID, ImpliedTagID).Scan(&count); err != nil {
+		return TagID, ImpliedTagID, err
+	}
+	if count > 0 {
+		return TagID, ImpliedTagID, errors.New("tag implication already exists")
+	}
+	//If the implied tag already leads back to the tag, this rule would close a loop
+	impliedTagIDs, err := DBConnection.getImpliedTags([]uint64{ImpliedTagID})
+	if err != nil {
+		return TagID, ImpliedTagID, err
+	}
+	if uint64SliceContains(impliedTagIDs, TagID) {
+		return TagID, ImpliedTagID, errors.New("tag implication would create a cycle")
+	}
+	return TagID, ImpliedTagID, nil
+}
+
+//RemoveTagImplication removes a rule added by AddTagImplication
+func (DBConnection *PostgresPlugin) RemoveTagImplication(TagID uint64, ImpliedTagID uint64) error {
+	resultInfo, err := DBConnection.DBHandle.Exec("DELETE FROM TagImplications WHERE TagID = ? AND ImpliedTagID = ?;", TagID, ImpliedTagID)
+	if err == nil {
+		if affected, _ := resultInfo.RowsAffected(); affected == 0 {
+			err = sql.ErrNoRows
+		}
+	}
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "PostgresPlugin/RemoveTagImplication", "0", logging.ResultFailure, []string{"Failed to remove tag implication", strconv.FormatUint(TagID, 10), strconv.FormatUint(ImpliedTagID, 10), err.Error()})
+		return err
+	}
+	logging.WriteLog(logging.LogLevelInfo, "PostgresPlugin/RemoveTagImplication", "0", logging.ResultSuccess, []string{"Tag implication removed", strconv.FormatUint(TagID, 10), strconv.FormatUint(ImpliedTagID, 10)})
+	return nil
+}
+
+//GetTagImplications returns the rules where a tag implies, or is implied by, another tag
+func (DBConnection *PostgresPlugin) GetTagImplications(TagID uint64) ([]interfaces.TagImplicationInformation, error) {
+	rows, err := DBConnection.DBHandle.Query(`SELECT TagImplications.ID, TagImplications.TagID, Tags.Name, TagImplications.ImpliedTagID, ImpliedTags.Name, TagImplications.CreatorID, TagImplications.CreationTime
+	FROM TagImplications
+	INNER JOIN Tags ON TagImplications.TagID = Tags.ID
+	INNER JOIN Tags AS ImpliedTags ON TagImplications.ImpliedTagID = ImpliedTags.ID
+	WHERE TagImplications.TagID = ? OR TagImplications.ImpliedTagID = ?
+	ORDER BY Tags.Name, ImpliedTags.Name;`, TagID, TagID)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "PostgresPlugin/GetTagImplications", "0", logging.ResultFailure, []string{"Failed to get tag implications", strconv.FormatUint(TagID, 10), err.Error()})
+		return nil, err
+	}
+	defer rows.Close()
+	var ToReturn []interfaces.TagImplicationInformation
+	for rows.Next() {
+		var implication interfaces.TagImplicationInformation
+		var CreationTime sql.NullTime
+		if err := rows.Scan(&implication.ID, &implication.TagID, &implication.TagName, &implication.ImpliedTagID, &implication.ImpliedTagName, &implication.CreatorID, &CreationTime); err != nil {
+			logging.WriteLog(logging.LogLevelError, "PostgresPlugin/GetTagImplications", "0", logging.ResultFailure, []string{"Failed to get tag implications", strconv.FormatUint(TagID, 10), err.Error()})
+			return nil, err
+		}
+		implication.CreationTime = CreationTime.Time
+		ToReturn = append(ToReturn, implication)
+	}
+	return ToReturn, rows.Err()
+}
+
+//ApplyTagImplications adds implied tags to every image missing them, returns how many tags were added
+func (DBConnection *PostgresPlugin) ApplyTagImplications(LinkerID uint64) (uint64, error) {
+	//Implied tags can imply further tags, so repeat until a pass adds nothing. Cycles are refused when adding implications, so this ends.
+	//An implied tag that has since become an alias is swapped for the tag it aliases
+	impliedTagColumn := "CASE WHEN ImpliedTags.IsAlias THEN ImpliedTags.AliasedID ELSE ImpliedTags.ID END"
+	impliedTagsMissing := `FROM ImageTags
+		INNER JOIN TagImplications ON ImageTags.TagID = TagImplications.TagID
+		INNER JOIN Tags AS ImpliedTags ON TagImplications.ImpliedTagID = ImpliedTags.ID
+		WHERE NOT EXISTS (SELECT 1 FROM ImageTags AS Existing WHERE Existing.ImageID = ImageTags.ImageID AND Existing.TagID = ` + impliedTagColumn + `);`
+	var added uint64
+	for {
+		_, err := DBConnection.DBHandle.Exec(`INSERT INTO ImageTagHistory (ImageID, TagID, UserID, Added, Operation)
+		SELECT DISTINCT ImageTags.ImageID, `+impliedTagColumn+`, CAST(? AS BIGINT), TRUE, CAST(? AS VARCHAR) `+impliedTagsMissing, LinkerID, interfaces.TagChangeImply)
+		if err != nil {
+			logging.WriteLog(logging.LogLevelError, "PostgresPlugin/ApplyTagImplications", strconv.FormatUint(LinkerID, 10), logging.ResultFailure, []string{"Failed to record tag history", err.Error()})
+			return added, err
+		}
+		resultInfo, err := DBConnection.DBHandle.Exec(`INSERT INTO ImageTags (TagID, ImageID, LinkerID)
+		SELECT DISTINCT `+impliedTagColumn+`, ImageTags.ImageID, CAST(? AS BIGINT) `+impliedTagsMissing, LinkerID)
+		if err != nil {
+			logging.WriteLog(logging.LogLevelError, "PostgresPlugin/ApplyTagImplications", strconv.FormatUint(LinkerID, 10), logging.ResultFailure, []string{"Failed to add implied tags", err.Error()})
+			return added, err
+		}
+		affected, _ := resultInfo.RowsAffected()
+		if affected <= 0 {
+			break
+		}
+		added += uint64(affected)
+	}
+	logging.WriteLog(logging.LogLevelInfo, "PostgresPlugin/ApplyTagImplications", strconv.FormatUint(LinkerID, 10), logging.ResultSuccess, []string{"Implied tags added", strconv.FormatUint(added, 10)})
+	return added, nil
+}
+
+//getImpliedTags returns TagIDs followed by every tag they imply, directly or through other implications. Implied aliases are swapped for the tags they alias.
+func (DBConnection *PostgresPlugin) getImpliedTags(TagIDs []uint64) ([]uint64, error) {
+	var ToReturn []uint64
+	for _, TagID := range TagIDs {
+		if uint64SliceContains(ToReturn, TagID) == false {
+			ToReturn = append(ToReturn, TagID)
+		}
+	}
+	toExpand := ToReturn
+	for len(toExpand) > 0 {
+		queryArray := []interface{}{}
+		for _, TagID := range toExpand {
+			queryArray = append(queryArray, TagID)
+		}
+		rows, err := DBConnection.DBHandle.Query(`SELECT CASE WHEN Tags.IsAlias THEN Tags.AliasedID ELSE Tags.ID END
+		FROM TagImplications INNER JOIN Tags ON TagImplications.ImpliedTagID = Tags.ID
+		WHERE TagImplications.TagID IN (?`+strings.Repeat(", ?", len(toExpand)-1)+`);`, queryArray...)
+		if err != nil {
+			return nil, err
+		}
+		//Tags already seen are not expanded again, which also keeps a cycle from looping forever
+		var found []uint64
+		for rows.Next() {
+			var TagID uint64
+			if err := rows.Scan(&TagID); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			if uint64SliceContains(ToReturn, TagID) == false {
+				ToReturn = append(ToReturn, TagID)
+				found = append(found, TagID)
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		toExpand = found
+	}
+	return ToReturn, nil
+}
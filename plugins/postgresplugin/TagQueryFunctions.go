@@ -0,0 +1,13 @@
+package postgresplugin
+
+import "go-image-board/interfaces"
+
+//GetUserFilterTags returns a slice of tags based on a user's custom filter
+func (DBConnection *PostgresPlugin) GetUserFilterTags(UserID uint64, CollectionContext bool) ([]interfaces.TagInformation, error) {
+	return DBConnection.searcher().GetUserFilterTags(UserID, CollectionContext)
+}
+
+//GetQueryTags returns a slice of tags based on a query string, CollectionContext should be true if these tags are being parsed for a collection
+func (DBConnection *PostgresPlugin) GetQueryTags(UserQuery string, CollectionContext bool) ([]interfaces.TagInformation, error) {
+	return DBConnection.searcher().GetQueryTags(UserQuery, CollectionContext)
+}
@@ -0,0 +1,154 @@
+package migrations
+
+import "go-image-board/plugins/migrate"
+
+func init() {
+	Registry.Register(migrate.Migration{
+		Version:     1,
+		Description: "Initial schema",
+		Statements: []string{
+			//citext gives us the case insensitive names MariaDB's default collation provides, it is a trusted extension since Postgres 13
+			"CREATE EXTENSION IF NOT EXISTS citext;",
+			//Images and tags
+			"CREATE TABLE Tags (ID BIGSERIAL PRIMARY KEY, Name CITEXT NOT NULL UNIQUE, Description VARCHAR(255), UploaderID BIGINT NOT NULL, UploadTime TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP NOT NULL, AliasedID BIGINT NOT NULL DEFAULT 0, IsAlias BOOL NOT NULL DEFAULT FALSE);",
+			"CREATE TABLE Images (ID BIGSERIAL PRIMARY KEY, UploaderID BIGINT NOT NULL, Name VARCHAR(255) NOT NULL, Rating VARCHAR(255) DEFAULT 'unrated', ScoreTotal BIGINT NOT NULL DEFAULT 0, ScoreAverage BIGINT NOT NULL DEFAULT 0, ScoreVoters BIGINT NOT NULL DEFAULT 0, Location VARCHAR(255) UNIQUE NOT NULL, Source VARCHAR(2000) NOT NULL DEFAULT '', UploadTime TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP NOT NULL, Description TEXT NOT NULL DEFAULT '');",
+			"CREATE INDEX ImagesUploaderID ON Images(UploaderID);",
+			"CREATE INDEX ImagesRating ON Images(Rating);",
+			"CREATE INDEX ImagesUploadTime ON Images(UploadTime);",
+			"CREATE INDEX ImagesScoreAverage ON Images(ScoreAverage);",
+			"CREATE TABLE ImageTags (ID BIGSERIAL PRIMARY KEY, ImageID BIGINT NOT NULL REFERENCES Images(ID), TagID BIGINT NOT NULL REFERENCES Tags(ID), LinkerID BIGINT NOT NULL, LinkTime TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP NOT NULL, UNIQUE (TagID, ImageID));",
+			"CREATE INDEX ImageTagsImageID ON ImageTags(ImageID);",
+			"CREATE INDEX ImageTagsLinkerID ON ImageTags(LinkerID);",
+			//Hashes are stored as signed 64bit integers, as Postgres does not support unsigned, the bits are identical
+			"CREATE TABLE ImagedHashes (ID BIGSERIAL PRIMARY KEY, ImageID BIGINT NOT NULL UNIQUE REFERENCES Images(ID), vHash BIGINT NOT NULL, hHash BIGINT NOT NULL);",
+			"CREATE INDEX ImagedHashesvHash ON ImagedHashes(vHash);",
+			"CREATE INDEX ImagedHasheshHash ON ImagedHashes(hHash);",
+			"CREATE TABLE ImageUserScores (ID BIGSERIAL PRIMARY KEY, UserID BIGINT NOT NULL, ImageID BIGINT NOT NULL, Score BIGINT NOT NULL, CreationTime TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP NOT NULL, UNIQUE (UserID, ImageID));",
+			//Users
+			"CREATE TABLE Users (ID BIGSERIAL PRIMARY KEY, Name CITEXT NOT NULL UNIQUE, EMail CITEXT NOT NULL UNIQUE, PasswordHash VARCHAR(255) NOT NULL, TokenID VARCHAR(255), IP VARCHAR(50), SecQuestionOne VARCHAR(50), SecQuestionTwo VARCHAR(50), SecQuestionThree VARCHAR(50), SecAnswerOne VARCHAR(255), SecAnswerTwo VARCHAR(255), SecAnswerThree VARCHAR(255), CreationTime TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP NOT NULL, Disabled BOOL NOT NULL DEFAULT FALSE, Permissions BIGINT NOT NULL DEFAULT 0, SearchFilter VARCHAR(255) NOT NULL DEFAULT '');",
+			//Auditing
+			"CREATE TABLE AuditLogs (ID BIGSERIAL PRIMARY KEY, UserID BIGINT NOT NULL, Type VARCHAR(40), Info VARCHAR(10240) NOT NULL DEFAULT '', LogTime TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP NOT NULL);",
+			"CREATE INDEX AuditLogsLogTime ON AuditLogs(LogTime);",
+			//Collections
+			"CREATE TABLE Collections (ID BIGSERIAL PRIMARY KEY, Name CITEXT NOT NULL UNIQUE, Description VARCHAR(255), UploaderID BIGINT NOT NULL, UploadTime TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP NOT NULL);",
+			"CREATE TABLE CollectionMembers (ID BIGSERIAL PRIMARY KEY, ImageID BIGINT NOT NULL REFERENCES Images(ID), CollectionID BIGINT NOT NULL REFERENCES Collections(ID), LinkerID BIGINT NOT NULL, LinkTime TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP NOT NULL, OrderWeight BIGINT NOT NULL, UNIQUE (CollectionID, ImageID));",
+			"CREATE TABLE CollectionTags (ID BIGSERIAL PRIMARY KEY, CollectionID BIGINT NOT NULL REFERENCES Collections(ID), TagID BIGINT NOT NULL REFERENCES Tags(ID), LinkerID BIGINT NOT NULL, LinkTime TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP NOT NULL, UNIQUE (TagID, CollectionID));",
+			//Functions
+			//Postgres has no BIT_COUNT for integers, so provide the hamming distance used by the similar metatag
+			`CREATE FUNCTION HAMMING_DISTANCE(valueA BIGINT, valueB BIGINT) RETURNS INTEGER AS $$
+		SELECT LENGTH(REPLACE(CAST(CAST(valueA # valueB AS BIT(64)) AS TEXT), '0', ''));
+	$$ LANGUAGE SQL IMMUTABLE STRICT;`,
+			`CREATE FUNCTION LinkCollTags(collID BIGINT) RETURNS BIGINT AS $$
+	DECLARE
+		addedCount BIGINT;
+		removedCount BIGINT;
+	BEGIN
+		-- Insert missing tags
+		INSERT INTO CollectionTags (TagID, CollectionID, LinkerID)
+		SELECT DISTINCT ImageTags.TagID, collID, ImageTags.LinkerID
+		FROM ImageTags
+		INNER JOIN CollectionMembers on CollectionMembers.ImageID = ImageTags.ImageID
+		LEFT JOIN CollectionTags on CollectionTags.CollectionID = CollectionMembers.CollectionID AND CollectionTags.TagID = ImageTags.TagID
+		WHERE CollectionMembers.CollectionID = collID AND CollectionTags.CollectionID IS NULL
+		ON CONFLICT DO NOTHING;
+		GET DIAGNOSTICS addedCount = ROW_COUNT;
+		-- Remove extra tags
+		DELETE FROM CollectionTags
+		WHERE TagID NOT IN ( SELECT TagID
+								FROM ImageTags
+								INNER JOIN CollectionMembers on CollectionMembers.ImageID = ImageTags.ImageID
+								WHERE CollectionMembers.CollectionID = collID
+							)
+		AND CollectionID=collID;
+		GET DIAGNOSTICS removedCount = ROW_COUNT;
+		RETURN addedCount + removedCount;
+	END;
+	$$ LANGUAGE plpgsql;`,
+			`CREATE FUNCTION AddMissingCollectionImageTags(imgID BIGINT) RETURNS VOID AS $$
+	BEGIN
+		-- Insert missing tags
+		INSERT INTO CollectionTags(TagID, CollectionID, LinkerID)
+		SELECT DISTINCT ImageTags.TagID, CollectionMembers.CollectionID, ImageTags.LinkerID
+		FROM ImageTags
+		INNER JOIN CollectionMembers ON CollectionMembers.ImageID = ImageTags.ImageID
+		LEFT JOIN CollectionTags ON CollectionTags.CollectionID = CollectionMembers.CollectionID AND CollectionTags.TagID = ImageTags.TagID
+		WHERE CollectionTags.CollectionID IS NULL AND ImageTags.ImageID = imgID
+		ON CONFLICT DO NOTHING;
+	END;
+	$$ LANGUAGE plpgsql;`,
+			`CREATE FUNCTION RemSurplusCollectionImageTags(collID BIGINT) RETURNS VOID AS $$
+	BEGIN
+		-- Remove extra tags
+		DELETE FROM CollectionTags
+		WHERE TagID NOT IN ( SELECT TagID
+								FROM ImageTags
+								INNER JOIN CollectionMembers on CollectionMembers.ImageID = ImageTags.ImageID
+								WHERE CollectionMembers.CollectionID = collID
+							)
+		AND CollectionID=collID;
+	END;
+	$$ LANGUAGE plpgsql;`,
+			//Triggers, Postgres triggers call a trigger function rather than having a body of their own
+			`CREATE FUNCTION onCollectionDelete() RETURNS TRIGGER AS $$
+	BEGIN
+		DELETE FROM CollectionMembers WHERE CollectionID=OLD.ID;
+		DELETE FROM CollectionTags WHERE CollectionID=OLD.ID;
+		RETURN OLD;
+	END;
+	$$ LANGUAGE plpgsql;`,
+			"CREATE TRIGGER onCollectionDelete BEFORE DELETE ON Collections FOR EACH ROW EXECUTE FUNCTION onCollectionDelete();",
+			`CREATE FUNCTION onCollectionMemberAdd() RETURNS TRIGGER AS $$
+	BEGIN
+		PERFORM AddMissingCollectionImageTags(NEW.ImageID);
+		RETURN NULL;
+	END;
+	$$ LANGUAGE plpgsql;`,
+			"CREATE TRIGGER onCollectionMemberAdd AFTER INSERT ON CollectionMembers FOR EACH ROW EXECUTE FUNCTION onCollectionMemberAdd();",
+			`CREATE FUNCTION onCollectionMemberDelete() RETURNS TRIGGER AS $$
+	BEGIN
+		PERFORM RemSurplusCollectionImageTags(OLD.CollectionID);
+		RETURN NULL;
+	END;
+	$$ LANGUAGE plpgsql;`,
+			"CREATE TRIGGER onCollectionMemberDelete AFTER DELETE ON CollectionMembers FOR EACH ROW EXECUTE FUNCTION onCollectionMemberDelete();",
+			`CREATE FUNCTION onImageTagDelete() RETURNS TRIGGER AS $$
+	DECLARE
+		collID BIGINT;
+	BEGIN
+		FOR collID IN SELECT CollectionID FROM CollectionMembers WHERE ImageID = OLD.ImageID LOOP
+			PERFORM RemSurplusCollectionImageTags(collID);
+		END LOOP;
+		RETURN NULL;
+	END;
+	$$ LANGUAGE plpgsql;`,
+			"CREATE TRIGGER onImageTagDelete AFTER DELETE ON ImageTags FOR EACH ROW EXECUTE FUNCTION onImageTagDelete();",
+			`CREATE FUNCTION onImageDelete() RETURNS TRIGGER AS $$
+	BEGIN
+		DELETE FROM ImageTags WHERE ImageID=OLD.ID;
+		DELETE FROM ImageUserScores WHERE ImageID=OLD.ID;
+		DELETE FROM CollectionMembers WHERE ImageID=OLD.ID;
+		DELETE FROM ImagedHashes WHERE ImageID=OLD.ID;
+		RETURN OLD;
+	END;
+	$$ LANGUAGE plpgsql;`,
+			"CREATE TRIGGER onImageDelete BEFORE DELETE ON Images FOR EACH ROW EXECUTE FUNCTION onImageDelete();",
+			`CREATE FUNCTION onImageTagInsert() RETURNS TRIGGER AS $$
+	BEGIN
+		PERFORM AddMissingCollectionImageTags(NEW.ImageID);
+		RETURN NULL;
+	END;
+	$$ LANGUAGE plpgsql;`,
+			"CREATE TRIGGER onImageTagInsert AFTER INSERT ON ImageTags FOR EACH ROW EXECUTE FUNCTION onImageTagInsert();",
+			`CREATE FUNCTION onTagDelete() RETURNS TRIGGER AS $$
+	BEGIN
+		DELETE FROM ImageTags WHERE TagID=OLD.ID;
+		DELETE FROM CollectionTags WHERE TagID=OLD.ID;
+		RETURN OLD;
+	END;
+	$$ LANGUAGE plpgsql;`,
+			"CREATE TRIGGER onTagDelete BEFORE DELETE ON Tags FOR EACH ROW EXECUTE FUNCTION onTagDelete();",
+			//Reserve system for auditing
+			"INSERT INTO Users (ID, Name, EMail, PasswordHash, Disabled) VALUES (0, 'SYSTEM', '', '', TRUE);",
+		},
+	})
+}
@@ -0,0 +1,16 @@
+package migrations
+
+import "go-image-board/plugins/migrate"
+
+func init() {
+	Registry.Register(migrate.Migration{
+		Version:     2,
+		Description: "Add trash to images and collections",
+		Statements: []string{
+			"ALTER TABLE Images ADD COLUMN DeletedTime TIMESTAMPTZ NULL DEFAULT NULL, ADD COLUMN DeleterID BIGINT NOT NULL DEFAULT 0;",
+			"CREATE INDEX ImagesDeletedTime ON Images(DeletedTime);",
+			"ALTER TABLE Collections ADD COLUMN DeletedTime TIMESTAMPTZ NULL DEFAULT NULL, ADD COLUMN DeleterID BIGINT NOT NULL DEFAULT 0;",
+			"CREATE INDEX CollectionsDeletedTime ON Collections(DeletedTime);",
+		},
+	})
+}
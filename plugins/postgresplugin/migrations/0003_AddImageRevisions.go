@@ -0,0 +1,14 @@
+package migrations
+
+import "go-image-board/plugins/migrate"
+
+func init() {
+	Registry.Register(migrate.Migration{
+		Version:     3,
+		Description: "Add image revisions",
+		Statements: []string{
+			"CREATE TABLE ImageRevisions (ID BIGSERIAL PRIMARY KEY, ImageID BIGINT NOT NULL REFERENCES Images(ID), Location VARCHAR(255) NOT NULL, ReplacerID BIGINT NOT NULL, ReplacedTime TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP NOT NULL);",
+			"CREATE INDEX ImageRevisionsImageID ON ImageRevisions(ImageID);",
+		},
+	})
+}
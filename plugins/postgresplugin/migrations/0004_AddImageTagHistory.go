@@ -0,0 +1,15 @@
+package migrations
+
+import "go-image-board/plugins/migrate"
+
+func init() {
+	Registry.Register(migrate.Migration{
+		Version:     4,
+		Description: "Add image tag history",
+		Statements: []string{
+			"CREATE TABLE ImageTagHistory (ID BIGSERIAL PRIMARY KEY, ImageID BIGINT NOT NULL REFERENCES Images(ID), TagID BIGINT NOT NULL REFERENCES Tags(ID), UserID BIGINT NOT NULL, Added BOOLEAN NOT NULL, Operation VARCHAR(16) NOT NULL, ChangeTime TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP NOT NULL);",
+			"CREATE INDEX ImageTagHistoryImageID ON ImageTagHistory(ImageID);",
+			"CREATE INDEX ImageTagHistoryUserTime ON ImageTagHistory(UserID, ChangeTime);",
+		},
+	})
+}
@@ -0,0 +1,14 @@
+package migrations
+
+import "go-image-board/plugins/migrate"
+
+func init() {
+	Registry.Register(migrate.Migration{
+		Version:     5,
+		Description: "Add tag implications",
+		Statements: []string{
+			"CREATE TABLE TagImplications (ID BIGSERIAL PRIMARY KEY, TagID BIGINT NOT NULL REFERENCES Tags(ID), ImpliedTagID BIGINT NOT NULL REFERENCES Tags(ID), CreatorID BIGINT NOT NULL, CreationTime TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP NOT NULL, UNIQUE (TagID, ImpliedTagID));",
+			"CREATE INDEX TagImplicationsImpliedTagID ON TagImplications(ImpliedTagID);",
+		},
+	})
+}
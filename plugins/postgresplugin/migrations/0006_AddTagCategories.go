@@ -0,0 +1,14 @@
+package migrations
+
+import "go-image-board/plugins/migrate"
+
+func init() {
+	Registry.Register(migrate.Migration{
+		Version:     6,
+		Description: "Add tag categories",
+		Statements: []string{
+			"ALTER TABLE Tags ADD COLUMN Category VARCHAR(32) NOT NULL DEFAULT 'general';",
+			"CREATE INDEX TagsCategory ON Tags(Category);",
+		},
+	})
+}
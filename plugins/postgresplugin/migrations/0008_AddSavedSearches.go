@@ -0,0 +1,14 @@
+package migrations
+
+import "go-image-board/plugins/migrate"
+
+func init() {
+	Registry.Register(migrate.Migration{
+		Version:     8,
+		Description: "Add saved searches",
+		Statements: []string{
+			"CREATE TABLE SavedSearches (ID BIGSERIAL PRIMARY KEY, UserID BIGINT NOT NULL, Name VARCHAR(255) NOT NULL, Query TEXT NOT NULL, CollectionContext BOOL NOT NULL DEFAULT FALSE, Pinned BOOL NOT NULL DEFAULT FALSE, ShowNewCount BOOL NOT NULL DEFAULT FALSE, CreationTime TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP NOT NULL, LastViewed TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP NOT NULL);",
+			"CREATE INDEX SavedSearchesUserID ON SavedSearches(UserID);",
+		},
+	})
+}
@@ -0,0 +1,14 @@
+package migrations
+
+import "go-image-board/plugins/migrate"
+
+func init() {
+	Registry.Register(migrate.Migration{
+		Version:     9,
+		Description: "Add image favorites",
+		Statements: []string{
+			"CREATE TABLE ImageUserFavorites (ID BIGSERIAL PRIMARY KEY, UserID BIGINT NOT NULL, ImageID BIGINT NOT NULL REFERENCES Images(ID), CreationTime TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP NOT NULL, UNIQUE (UserID, ImageID));",
+			"CREATE INDEX ImageUserFavoritesImageID ON ImageUserFavorites(ImageID);",
+		},
+	})
+}
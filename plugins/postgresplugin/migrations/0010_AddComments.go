@@ -0,0 +1,19 @@
+package migrations
+
+import "go-image-board/plugins/migrate"
+
+func init() {
+	Registry.Register(migrate.Migration{
+		Version:     10,
+		Description: "Add comments",
+		Statements: []string{
+			//ImageID and CollectionID are 0 when the comment is on the other, so neither can reference its table
+			"CREATE TABLE Comments (ID BIGSERIAL PRIMARY KEY, ImageID BIGINT NOT NULL DEFAULT 0, CollectionID BIGINT NOT NULL DEFAULT 0, ParentID BIGINT NOT NULL DEFAULT 0, ThreadID BIGINT NOT NULL DEFAULT 0, UserID BIGINT NOT NULL, Body TEXT NOT NULL, CreationTime TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP NOT NULL, EditTime TIMESTAMPTZ NULL DEFAULT NULL, DeletedTime TIMESTAMPTZ NULL DEFAULT NULL, DeleterID BIGINT NOT NULL DEFAULT 0);",
+			"CREATE INDEX CommentsImageID ON Comments(ImageID);",
+			"CREATE INDEX CommentsCollectionID ON Comments(CollectionID);",
+			"CREATE INDEX CommentsThreadID ON Comments(ThreadID);",
+			"CREATE TABLE CommentRevisions (ID BIGSERIAL PRIMARY KEY, CommentID BIGINT NOT NULL REFERENCES Comments(ID), Body TEXT NOT NULL, EditorID BIGINT NOT NULL, EditTime TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP NOT NULL);",
+			"CREATE INDEX CommentRevisionsCommentID ON CommentRevisions(CommentID);",
+		},
+	})
+}
@@ -0,0 +1,16 @@
+package migrations
+
+import "go-image-board/plugins/migrate"
+
+func init() {
+	Registry.Register(migrate.Migration{
+		Version:     11,
+		Description: "Add notes",
+		Statements: []string{
+			"CREATE TABLE Notes (ID BIGSERIAL PRIMARY KEY, ImageID BIGINT NOT NULL REFERENCES Images(ID), CreatorID BIGINT NOT NULL, X BIGINT NOT NULL, Y BIGINT NOT NULL, Width BIGINT NOT NULL, Height BIGINT NOT NULL, ImageWidth BIGINT NOT NULL, ImageHeight BIGINT NOT NULL, Body TEXT NOT NULL, CreationTime TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP NOT NULL, EditTime TIMESTAMPTZ NULL DEFAULT NULL, DeletedTime TIMESTAMPTZ NULL DEFAULT NULL, DeleterID BIGINT NOT NULL DEFAULT 0);",
+			"CREATE INDEX NotesImageID ON Notes(ImageID);",
+			"CREATE TABLE NoteRevisions (ID BIGSERIAL PRIMARY KEY, NoteID BIGINT NOT NULL REFERENCES Notes(ID), X BIGINT NOT NULL, Y BIGINT NOT NULL, Width BIGINT NOT NULL, Height BIGINT NOT NULL, ImageWidth BIGINT NOT NULL, ImageHeight BIGINT NOT NULL, Body TEXT NOT NULL, Deleted BOOL NOT NULL DEFAULT FALSE, EditorID BIGINT NOT NULL, EditTime TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP NOT NULL);",
+			"CREATE INDEX NoteRevisionsNoteID ON NoteRevisions(NoteID);",
+		},
+	})
+}
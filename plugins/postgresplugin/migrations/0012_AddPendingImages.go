@@ -0,0 +1,14 @@
+package migrations
+
+import "go-image-board/plugins/migrate"
+
+func init() {
+	Registry.Register(migrate.Migration{
+		Version:     12,
+		Description: "Add the moderation queue to images",
+		Statements: []string{
+			"ALTER TABLE Images ADD COLUMN Pending BOOL NOT NULL DEFAULT FALSE;",
+			"CREATE INDEX ImagesPending ON Images(Pending);",
+		},
+	})
+}
@@ -0,0 +1,14 @@
+package migrations
+
+import "go-image-board/plugins/migrate"
+
+func init() {
+	Registry.Register(migrate.Migration{
+		Version:     14,
+		Description: "Add bans",
+		Statements: []string{
+			"CREATE TABLE Bans (ID BIGSERIAL PRIMARY KEY, UserID BIGINT NOT NULL DEFAULT 0, IPRange VARCHAR(64) NOT NULL DEFAULT '', Reason TEXT NOT NULL, BannerID BIGINT NOT NULL, CreationTime TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP NOT NULL, ExpiryTime TIMESTAMPTZ NULL DEFAULT NULL, LifterID BIGINT NOT NULL DEFAULT 0, LiftTime TIMESTAMPTZ NULL DEFAULT NULL);",
+			"CREATE INDEX BansLiftTime ON Bans(LiftTime);",
+		},
+	})
+}
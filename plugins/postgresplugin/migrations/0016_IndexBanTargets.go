@@ -0,0 +1,14 @@
+package migrations
+
+import "go-image-board/plugins/migrate"
+
+func init() {
+	Registry.Register(migrate.Migration{
+		Version:     16,
+		Description: "Index bans by account and address range",
+		Statements: []string{
+			"CREATE INDEX BansUserID ON Bans(UserID);",
+			"CREATE INDEX BansIPRange ON Bans(IPRange);",
+		},
+	})
+}
@@ -0,0 +1,114 @@
+package s3storageplugin
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+//https://docs.aws.amazon.com/AmazonS3/latest/API/sig-v4-header-based-auth.html
+
+//signingAlgorithm the only algorithm supported by this signer
+const signingAlgorithm = "AWS4-HMAC-SHA256"
+
+//amzDateFormat the format of the x-amz-date header
+const amzDateFormat = "20060102T150405Z"
+
+//unsignedPayload is used in place of a body hash, so uploads can be streamed without reading them twice
+const unsignedPayload = "UNSIGNED-PAYLOAD"
+
+//emptyPayloadHash is the sha256 of an empty body
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+//signRequest adds an AWS Signature Version 4 Authorization header to request, signing host and every header already set
+func signRequest(request *http.Request, AccessKeyID string, SecretAccessKey string, Region string, PayloadHash string, SignTime time.Time) {
+	request.Header.Set("X-Amz-Date", SignTime.UTC().Format(amzDateFormat))
+	request.Header.Set("X-Amz-Content-Sha256", PayloadHash)
+	signedHeaders := []string{"host"}
+	for name := range request.Header {
+		signedHeaders = append(signedHeaders, strings.ToLower(name))
+	}
+	sort.Strings(signedHeaders)
+
+	scope := SignTime.UTC().Format("20060102") + "/" + Region + "/s3/aws4_request"
+	request.Header.Set("Authorization", signingAlgorithm+" Credential="+AccessKeyID+"/"+scope+", SignedHeaders="+strings.Join(signedHeaders, ";")+", Signature="+requestSignature(request, signedHeaders, SecretAccessKey, Region, SignTime))
+}
+
+//requestSignature computes the signature of request, covering the headers named in SignedHeaders, which must be lower case and sorted
+func requestSignature(request *http.Request, SignedHeaders []string, SecretAccessKey string, Region string, SignTime time.Time) string {
+	amzDate := SignTime.UTC().Format(amzDateFormat)
+	shortDate := SignTime.UTC().Format("20060102")
+
+	var canonicalHeaders strings.Builder
+	for _, name := range SignedHeaders {
+		var value string
+		if name == "host" {
+			value = request.Host
+			if value == "" {
+				value = request.URL.Host
+			}
+		} else {
+			var values []string
+			for _, headerValue := range request.Header.Values(name) {
+				values = append(values, strings.Join(strings.Fields(headerValue), " "))
+			}
+			value = strings.Join(values, ",")
+		}
+		canonicalHeaders.WriteString(name + ":" + value + "\n")
+	}
+
+	canonicalRequest := strings.Join([]string{
+		request.Method,
+		request.URL.EscapedPath(),
+		canonicalQuery(request.URL.Query()),
+		canonicalHeaders.String(),
+		strings.Join(SignedHeaders, ";"),
+		request.Header.Get("X-Amz-Content-Sha256"),
+	}, "\n")
+	canonicalHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := signingAlgorithm + "\n" + amzDate + "\n" + shortDate + "/" + Region + "/s3/aws4_request\n" + hex.EncodeToString(canonicalHash[:])
+
+	signingKey := hmacSHA256([]byte("AWS4"+SecretAccessKey), shortDate)
+	signingKey = hmacSHA256(signingKey, Region)
+	signingKey = hmacSHA256(signingKey, "s3")
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+}
+
+func hmacSHA256(Key []byte, Data string) []byte {
+	hash := hmac.New(sha256.New, Key)
+	hash.Write([]byte(Data))
+	return hash.Sum(nil)
+}
+
+//canonicalQuery returns the query sorted by key then value, with both encoded as S3 expects
+func canonicalQuery(Query url.Values) string {
+	var pairs []string
+	for key, values := range Query {
+		for _, value := range values {
+			pairs = append(pairs, uriEncode(key, true)+"="+uriEncode(value, true))
+		}
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "&")
+}
+
+//uriEncode percent encodes everything but unreserved characters, and optionally /
+func uriEncode(Value string, EncodeSlash bool) string {
+	var builder strings.Builder
+	for _, character := range []byte(Value) {
+		switch {
+		case 'A' <= character && character <= 'Z', 'a' <= character && character <= 'z', '0' <= character && character <= '9',
+			character == '-', character == '_', character == '.', character == '~', character == '/' && EncodeSlash == false:
+			builder.WriteByte(character)
+		default:
+			builder.WriteString("%" + strings.ToUpper(hex.EncodeToString([]byte{character})))
+		}
+	}
+	return builder.String()
+}
@@ -0,0 +1,331 @@
+package s3storageplugin
+
+import (
+	"encoding/xml"
+	"errors"
+	"go-image-board/interfaces"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//S3StoragePlugin stores images and thumbnails in a bucket of an S3 compatible object store, such as MinIO
+type S3StoragePlugin struct {
+	//Endpoint host, and optionally port, of the object store, such as s3.us-east-1.amazonaws.com or localhost:9000
+	Endpoint string
+	//Region the bucket is in, MinIO uses us-east-1 unless configured otherwise
+	Region string
+	//Bucket objects are stored in, it must already exist
+	Bucket string
+	//AccessKeyID and SecretAccessKey are the credentials used to sign requests
+	AccessKeyID     string
+	SecretAccessKey string
+	//UseSSL connects over https when set
+	UseSSL bool
+	//Client is used for all requests, http.DefaultClient is used if nil
+	Client *http.Client
+}
+
+//s3Error is the body of a failed S3 request
+type s3Error struct {
+	Code    string
+	Message string
+}
+
+//listBucketResult is the body of a ListObjectsV2 response
+type listBucketResult struct {
+	Contents []struct {
+		Key          string
+		Size         int64
+		LastModified time.Time
+	}
+	IsTruncated           bool
+	NextContinuationToken string
+}
+
+//InitStorage validates the configuration and that the bucket can be reached
+func (Storage *S3StoragePlugin) InitStorage() error {
+	if Storage.Endpoint == "" || Storage.Bucket == "" || Storage.AccessKeyID == "" || Storage.SecretAccessKey == "" {
+		return errors.New("Missing storage information. (S3Endpoint, S3Bucket, S3AccessKeyID, S3SecretAccessKey?)")
+	}
+	response, err := Storage.send(http.MethodHead, "", nil, nil, 0, nil)
+	if err != nil {
+		return err
+	}
+	response.Body.Close()
+	if response.StatusCode == http.StatusNotFound {
+		return errors.New("Bucket " + Storage.Bucket + " does not exist")
+	}
+	if response.StatusCode != http.StatusOK {
+		return errors.New("Failed to access bucket " + Storage.Bucket + ", " + response.Status)
+	}
+	return nil
+}
+
+//Put uploads Data as Name, streaming it rather than buffering it in memory
+func (Storage *S3StoragePlugin) Put(Name string, Data io.Reader, Size int64) error {
+	if err := validateName(Name); err != nil {
+		return err
+	}
+	header := http.Header{}
+	if contentType := mime.TypeByExtension(path.Ext(Name)); contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	response, err := Storage.send(http.MethodPut, Name, nil, Data, Size, header)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return responseError(response, "put", Name)
+	}
+	return nil
+}
+
+//Get returns an object that reads Name in ranges, so only the parts that are read are downloaded
+func (Storage *S3StoragePlugin) Get(Name string) (interfaces.StorageObject, interfaces.StorageObjectInfo, error) {
+	info, eTag, err := Storage.stat(Name)
+	if err != nil {
+		return nil, interfaces.StorageObjectInfo{}, err
+	}
+	return &s3Object{storage: Storage, info: info, eTag: eTag}, info, nil
+}
+
+//Stat returns information on Name
+func (Storage *S3StoragePlugin) Stat(Name string) (interfaces.StorageObjectInfo, error) {
+	info, _, err := Storage.stat(Name)
+	return info, err
+}
+
+//stat returns information on Name along with its ETag
+func (Storage *S3StoragePlugin) stat(Name string) (interfaces.StorageObjectInfo, string, error) {
+	if err := validateName(Name); err != nil {
+		return interfaces.StorageObjectInfo{}, "", err
+	}
+	response, err := Storage.send(http.MethodHead, Name, nil, nil, 0, nil)
+	if err != nil {
+		return interfaces.StorageObjectInfo{}, "", err
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return interfaces.StorageObjectInfo{}, "", responseError(response, "stat", Name)
+	}
+	info := interfaces.StorageObjectInfo{Name: Name, Size: response.ContentLength}
+	info.ModTime, _ = http.ParseTime(response.Header.Get("Last-Modified"))
+	return info, response.Header.Get("ETag"), nil
+}
+
+//Delete removes Name, S3 does not report whether the object existed
+func (Storage *S3StoragePlugin) Delete(Name string) error {
+	if err := validateName(Name); err != nil {
+		return err
+	}
+	response, err := Storage.send(http.MethodDelete, Name, nil, nil, 0, nil)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusNoContent && response.StatusCode != http.StatusOK {
+		return responseError(response, "delete", Name)
+	}
+	return nil
+}
+
+//List returns the objects inside Directory, following continuation tokens until all have been listed
+func (Storage *S3StoragePlugin) List(Directory string, Recursive bool) ([]interfaces.StorageObjectInfo, error) {
+	prefix := ""
+	if Directory != "" {
+		if err := validateName(Directory); err != nil {
+			return nil, err
+		}
+		prefix = Directory + "/"
+	}
+	var objects []interfaces.StorageObjectInfo
+	continuationToken := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if Recursive == false {
+			query.Set("delimiter", "/")
+		}
+		if continuationToken != "" {
+			query.Set("continuation-token", continuationToken)
+		}
+		response, err := Storage.send(http.MethodGet, "", query, nil, 0, nil)
+		if err != nil {
+			return nil, err
+		}
+		if response.StatusCode != http.StatusOK {
+			err = responseError(response, "list", Directory)
+			response.Body.Close()
+			return nil, err
+		}
+		var result listBucketResult
+		err = xml.NewDecoder(response.Body).Decode(&result)
+		response.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		for _, object := range result.Contents {
+			if strings.HasSuffix(object.Key, "/") {
+				continue //Folder placeholders created by some tools
+			}
+			objects = append(objects, interfaces.StorageObjectInfo{Name: object.Key, Size: object.Size, ModTime: object.LastModified})
+		}
+		if result.IsTruncated == false || result.NextContinuationToken == "" {
+			return objects, nil
+		}
+		continuationToken = result.NextContinuationToken
+	}
+}
+
+//send signs and performs a request against Name in the bucket, or the bucket itself if Name is empty
+func (Storage *S3StoragePlugin) send(Method string, Name string, Query url.Values, Body io.Reader, Size int64, Header http.Header) (*http.Response, error) {
+	scheme := "http"
+	if Storage.UseSSL {
+		scheme = "https"
+	}
+	objectPath := "/" + Storage.Bucket
+	if Name != "" {
+		objectPath += "/" + Name
+	}
+	requestURL := &url.URL{
+		Scheme:   scheme,
+		Host:     Storage.Endpoint,
+		Path:     objectPath,
+		RawPath:  uriEncode(objectPath, false),
+		RawQuery: canonicalQuery(Query),
+	}
+	if Body == nil || Size == 0 {
+		Body = http.NoBody
+	}
+	request, err := http.NewRequest(Method, requestURL.String(), Body)
+	if err != nil {
+		return nil, err
+	}
+	request.ContentLength = Size
+	for name, values := range Header {
+		request.Header[name] = values
+	}
+	payloadHash := emptyPayloadHash
+	if Body != http.NoBody {
+		payloadHash = unsignedPayload
+	}
+	signRequest(request, Storage.AccessKeyID, Storage.SecretAccessKey, Storage.region(), payloadHash, time.Now())
+
+	client := Storage.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return client.Do(request)
+}
+
+//region returns the configured region, or the default region used by MinIO and AWS
+func (Storage *S3StoragePlugin) region() string {
+	if Storage.Region == "" {
+		return "us-east-1"
+	}
+	return Storage.Region
+}
+
+//validateName refuses names that would be interpreted differently by the object store than on disk
+func validateName(Name string) error {
+	if Name == "" || path.Clean("/"+Name) != "/"+Name {
+		return errors.New("Invalid object name " + Name)
+	}
+	return nil
+}
+
+//responseError converts a failed response in to an error, a missing object becomes an error for which os.IsNotExist is true
+func responseError(response *http.Response, Operation string, Name string) error {
+	if response.StatusCode == http.StatusNotFound {
+		return &os.PathError{Op: Operation, Path: Name, Err: os.ErrNotExist}
+	}
+	var details s3Error
+	body, _ := ioutil.ReadAll(io.LimitReader(response.Body, 64<<10))
+	if xml.Unmarshal(body, &details) != nil || details.Code == "" {
+		return errors.New("S3 " + Operation + " of " + Name + " failed, " + response.Status)
+	}
+	return errors.New("S3 " + Operation + " of " + Name + " failed, " + response.Status + " " + details.Code + ": " + details.Message)
+}
+
+//s3Object reads an object lazily, each read after a seek starts a new ranged download
+type s3Object struct {
+	storage *S3StoragePlugin
+	info    interfaces.StorageObjectInfo
+	eTag    string
+	offset  int64
+	body    io.ReadCloser
+}
+
+//Read downloads from the current offset, opening a ranged request if one is not already open
+func (Object *s3Object) Read(p []byte) (int, error) {
+	if Object.offset >= Object.info.Size {
+		return 0, io.EOF
+	}
+	if Object.body == nil {
+		header := http.Header{}
+		if Object.offset > 0 {
+			header.Set("Range", "bytes="+strconv.FormatInt(Object.offset, 10)+"-")
+		}
+		if Object.eTag != "" {
+			//Fail rather than mix parts of different objects if Name is replaced between ranges
+			header.Set("If-Match", Object.eTag)
+		}
+		response, err := Object.storage.send(http.MethodGet, Object.info.Name, nil, nil, 0, header)
+		if err != nil {
+			return 0, err
+		}
+		if (Object.offset > 0 && response.StatusCode != http.StatusPartialContent) || (Object.offset == 0 && response.StatusCode != http.StatusOK) {
+			err = responseError(response, "get", Object.info.Name)
+			response.Body.Close()
+			return 0, err
+		}
+		Object.body = response.Body
+	}
+	count, err := Object.body.Read(p)
+	Object.offset += int64(count)
+	if err == io.EOF && Object.offset < Object.info.Size {
+		err = io.ErrUnexpectedEOF
+	}
+	return count, err
+}
+
+//Seek moves the offset, the open download is dropped if the offset changes
+func (Object *s3Object) Seek(Offset int64, Whence int) (int64, error) {
+	newOffset := Offset
+	switch Whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		newOffset += Object.offset
+	case io.SeekEnd:
+		newOffset += Object.info.Size
+	default:
+		return Object.offset, errors.New("Invalid whence")
+	}
+	if newOffset < 0 {
+		return Object.offset, errors.New("Negative position")
+	}
+	if newOffset != Object.offset && Object.body != nil {
+		Object.body.Close()
+		Object.body = nil
+	}
+	Object.offset = newOffset
+	return newOffset, nil
+}
+
+//Close ends any open download
+func (Object *s3Object) Close() error {
+	if Object.body == nil {
+		return nil
+	}
+	err := Object.body.Close()
+	Object.body = nil
+	return err
+}
@@ -0,0 +1,275 @@
+package sqlbackup
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"go-image-board/interfaces"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//Dialect describes the database specific parts of exporting and importing tables
+type Dialect struct {
+	//SignedIntegers is true when unsigned values, such as dHashes, are stored in signed 64bit columns with the same bits
+	SignedIntegers bool
+	//Placeholder returns the nth (1 based) bind parameter, nil if the database uses ?
+	Placeholder func(n int) string
+	//ExportIsolation is the isolation level that gives a single consistent snapshot across every exported table
+	ExportIsolation sql.IsolationLevel
+	//ExportReadOnly marks the export transaction read only, where the driver supports it
+	ExportReadOnly bool
+	//TimeValue converts a time before it is inserted, nil inserts it as a UTC time.Time
+	TimeValue func(Value time.Time) interface{}
+	//BeginImport statements run at the start of the import transaction, such as allowing the SYSTEM user's ID of 0 to be inserted
+	BeginImport []string
+	//FinishImport returns statements run once a table has been imported, such as moving its ID sequence past the imported rows
+	FinishImport func(Table string) []string
+}
+
+//MariaDB dialect, IDs of 0 would otherwise be replaced by the next auto increment value
+var MariaDB = Dialect{
+	ExportIsolation: sql.LevelRepeatableRead,
+	ExportReadOnly:  true,
+	BeginImport:     []string{"SET SESSION sql_mode = CONCAT(@@SESSION.sql_mode, ',NO_AUTO_VALUE_ON_ZERO');"},
+}
+
+//Postgres dialect, serial columns do not see explicit IDs so their sequences are moved after each table
+var Postgres = Dialect{
+	SignedIntegers: true,
+	Placeholder: func(n int) string {
+		return "$" + strconv.Itoa(n)
+	},
+	ExportIsolation: sql.LevelRepeatableRead,
+	ExportReadOnly:  true,
+	FinishImport: func(Table string) []string {
+		return []string{"SELECT setval(pg_get_serial_sequence('" + Table + "', 'id'), COALESCE(MAX(ID), 0) + 1, false) FROM " + Table + ";"}
+	},
+}
+
+//SQLite dialect, a deferred transaction in WAL mode already reads from a single snapshot.
+//Times are stored in the same format as CURRENT_TIMESTAMP so they still compare correctly with datetime()
+var SQLite = Dialect{
+	SignedIntegers: true,
+	TimeValue: func(Value time.Time) interface{} {
+		return Value.UTC().Format("2006-01-02 15:04:05")
+	},
+}
+
+//timeLayouts are the formats drivers that do not parse times may return them in
+var timeLayouts = []string{"2006-01-02 15:04:05.999999999-07:00", "2006-01-02 15:04:05.999999999", time.RFC3339Nano}
+
+//ExportTables calls Row for every row of each table, in ID order, from within a single transaction
+func ExportTables(DB *sql.DB, Dialect Dialect, Tables []interfaces.BackupTable, Row func(Table interfaces.BackupTable, Row interfaces.BackupRow) error) error {
+	tx, err := DB.BeginTx(context.Background(), &sql.TxOptions{Isolation: Dialect.ExportIsolation, ReadOnly: Dialect.ExportReadOnly})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, table := range Tables {
+		if err := exportTable(tx, table, Row); err != nil {
+			return errors.New("Failed to export " + table.Name + ", " + err.Error())
+		}
+	}
+	return tx.Commit()
+}
+
+//exportTable reads every row of Table
+func exportTable(tx *sql.Tx, Table interfaces.BackupTable, Row func(Table interfaces.BackupTable, Row interfaces.BackupRow) error) error {
+	var columnNames []string
+	for _, column := range Table.Columns {
+		columnNames = append(columnNames, column.Name)
+	}
+	rows, err := tx.Query("SELECT " + strings.Join(columnNames, ", ") + " FROM " + Table.Name + " ORDER BY ID")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	values := make([]interface{}, len(Table.Columns))
+	pointers := make([]interface{}, len(Table.Columns))
+	for index := range values {
+		pointers[index] = &values[index]
+	}
+	for rows.Next() {
+		if err := rows.Scan(pointers...); err != nil {
+			return err
+		}
+		row := make(interfaces.BackupRow)
+		for index, column := range Table.Columns {
+			value, err := convertValue(column.Type, values[index])
+			if err != nil {
+				return errors.New("column " + column.Name + ", " + err.Error())
+			}
+			row[column.Name] = value
+		}
+		if err := Row(Table, row); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+//convertValue converts a value as returned by a driver to the type used in a BackupRow
+func convertValue(Type interfaces.BackupColumnType, Value interface{}) (interface{}, error) {
+	if raw, isBytes := Value.([]byte); isBytes {
+		Value = string(raw)
+	}
+	switch Type {
+	case interfaces.BackupUint:
+		switch value := Value.(type) {
+		case int64:
+			return uint64(value), nil
+		case uint64:
+			return value, nil
+		case string:
+			return strconv.ParseUint(value, 10, 64)
+		}
+	case interfaces.BackupInt:
+		switch value := Value.(type) {
+		case int64:
+			return value, nil
+		case string:
+			return strconv.ParseInt(value, 10, 64)
+		}
+	case interfaces.BackupString, interfaces.BackupNullString:
+		switch value := Value.(type) {
+		case nil:
+			if Type == interfaces.BackupNullString {
+				return nil, nil
+			}
+		case string:
+			return value, nil
+		}
+	case interfaces.BackupBool:
+		switch value := Value.(type) {
+		case bool:
+			return value, nil
+		case int64:
+			return value != 0, nil
+		case string:
+			return value == "1" || strings.EqualFold(value, "true"), nil
+		}
+	case interfaces.BackupFloat:
+		switch value := Value.(type) {
+		case float64:
+			return value, nil
+		case int64:
+			return float64(value), nil
+		case string:
+			return strconv.ParseFloat(value, 64)
+		}
+	case interfaces.BackupTime, interfaces.BackupNullTime:
+		switch value := Value.(type) {
+		case nil:
+			if Type == interfaces.BackupNullTime {
+				return nil, nil
+			}
+		case time.Time:
+			return value.UTC(), nil
+		case string:
+			for _, layout := range timeLayouts {
+				if parsed, err := time.ParseInLocation(layout, value, time.UTC); err == nil {
+					return parsed.UTC(), nil
+				}
+			}
+		}
+	}
+	return nil, errors.New("unexpected value " + strconv.Quote(fmt.Sprint(Value)))
+}
+
+//ImportTables inserts the rows passed to Insert in to a freshly installed database, keeping their IDs.
+//Everything runs in one transaction, so nothing is kept if an error occurs. The reserved SYSTEM user is replaced by the one in the backup
+func ImportTables(DB *sql.DB, Dialect Dialect, Tables []interfaces.BackupTable, Rows func(Insert func(Table interfaces.BackupTable, Row interfaces.BackupRow) error) error) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, statement := range Dialect.BeginImport {
+		if _, err := tx.Exec(statement); err != nil {
+			return err
+		}
+	}
+	for _, table := range Tables {
+		query := "SELECT COUNT(*) FROM " + table.Name
+		if table.Name == "Users" {
+			query += " WHERE ID <> 0"
+		}
+		var count uint64
+		if err := tx.QueryRow(query).Scan(&count); err != nil {
+			return err
+		}
+		if count > 0 {
+			return errors.New("Table " + table.Name + " already contains data, backups can only be restored in to a fresh database")
+		}
+		if table.Name == "Users" {
+			if _, err := tx.Exec("DELETE FROM Users WHERE ID = 0"); err != nil {
+				return err
+			}
+		}
+	}
+
+	insertQueries := make(map[string]string)
+	err = Rows(func(Table interfaces.BackupTable, Row interfaces.BackupRow) error {
+		query, exists := insertQueries[Table.Name]
+		if exists == false {
+			query = insertQuery(Dialect, Table)
+			insertQueries[Table.Name] = query
+		}
+		var args []interface{}
+		for _, column := range Table.Columns {
+			args = append(args, insertValue(Dialect, Row[column.Name]))
+		}
+		if _, err := tx.Exec(query, args...); err != nil {
+			return errors.New("Failed to insert in to " + Table.Name + ", " + err.Error())
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if Dialect.FinishImport != nil {
+		for _, table := range Tables {
+			for _, statement := range Dialect.FinishImport(table.Name) {
+				if _, err := tx.Exec(statement); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+//insertQuery returns an INSERT for every column of Table
+func insertQuery(Dialect Dialect, Table interfaces.BackupTable) string {
+	var columnNames []string
+	var placeholders []string
+	for index, column := range Table.Columns {
+		columnNames = append(columnNames, column.Name)
+		if Dialect.Placeholder != nil {
+			placeholders = append(placeholders, Dialect.Placeholder(index+1))
+		} else {
+			placeholders = append(placeholders, "?")
+		}
+	}
+	return "INSERT INTO " + Table.Name + " (" + strings.Join(columnNames, ", ") + ") VALUES (" + strings.Join(placeholders, ", ") + ");"
+}
+
+//insertValue converts a value from a BackupRow to the type the database expects
+func insertValue(Dialect Dialect, Value interface{}) interface{} {
+	switch value := Value.(type) {
+	case uint64:
+		if Dialect.SignedIntegers {
+			return int64(value)
+		}
+	case time.Time:
+		if Dialect.TimeValue != nil {
+			return Dialect.TimeValue(value)
+		}
+		return value.UTC()
+	}
+	return Value
+}
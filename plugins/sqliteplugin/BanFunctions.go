@@ -0,0 +1,114 @@
+package sqliteplugin
+
+import (
+	"database/sql"
+	"errors"
+	"go-image-board/interfaces"
+	"go-image-board/logging"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//NewBan bans Ban.UserID, or Ban.IPRange, by Ban.BannerID until Ban.ExpiryTime, or permanently if it is the zero time. Returns its ID
+func (DBConnection *SQLitePlugin) NewBan(Ban interfaces.BanInformation) (uint64, error) {
+	//Whole seconds in UTC are stored so that the text compares in order with the time GetActiveBans passes
+	ExpiryTime := sql.NullTime{Time: Ban.ExpiryTime.UTC().Truncate(time.Second), Valid: Ban.IsPermanent() == false}
+	resultInfo, err := DBConnection.DBHandle.Exec("INSERT INTO Bans (UserID, IPRange, Reason, BannerID, ExpiryTime) VALUES (?, ?, ?, ?, ?);", Ban.UserID, Ban.IPRange, Ban.Reason, Ban.BannerID, ExpiryTime)
+	var id int64
+	if err == nil {
+		id, err = resultInfo.LastInsertId()
+	}
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "SQLitePlugin/NewBan", strconv.FormatUint(Ban.BannerID, 10), logging.ResultFailure, []string{"Failed to add ban", err.Error()})
+		return 0, err
+	}
+	logging.WriteLog(logging.LogLevelInfo, "SQLitePlugin/NewBan", strconv.FormatUint(Ban.BannerID, 10), logging.ResultSuccess, []string{"Ban added", strconv.FormatUint(uint64(id), 10)})
+	return uint64(id), nil
+}
+
+//GetBan returns a single ban
+func (DBConnection *SQLitePlugin) GetBan(BanID uint64) (interfaces.BanInformation, error) {
+	bans, err := DBConnection.queryBans("WHERE Bans.ID = ?;", BanID)
+	if err == nil && len(bans) == 0 {
+		err = sql.ErrNoRows
+	}
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "SQLitePlugin/GetBan", "0", logging.ResultFailure, []string{"Failed to get ban", strconv.FormatUint(BanID, 10), err.Error()})
+		return interfaces.BanInformation{}, err
+	}
+	return bans[0], nil
+}
+
+//GetActiveBans returns every ban that has neither been lifted nor expired, newest first
+func (DBConnection *SQLitePlugin) GetActiveBans() ([]interfaces.BanInformation, error) {
+	bans, err := DBConnection.queryBans("WHERE Bans.LiftTime IS NULL AND (Bans.ExpiryTime IS NULL OR Bans.ExpiryTime > ?) ORDER BY Bans.ID DESC;", time.Now().UTC().Truncate(time.Second))
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "SQLitePlugin/GetActiveBans", "0", logging.ResultFailure, []string{"Failed to get bans", err.Error()})
+		return nil, err
+	}
+	return bans, nil
+}
+
+//GetActiveBansOn returns the bans on the account UserID, or on a range containing IP, that have neither been lifted nor expired, newest first. Use 0 for UserID to only check the address
+func (DBConnection *SQLitePlugin) GetActiveBansOn(UserID uint64, IP string) ([]interfaces.BanInformation, error) {
+	//Ranges are stored in CIDR notation, so a ban on IP is on one of the ranges containing it
+	IPRanges := interfaces.ContainingIPRanges(IP)
+	Arguments := []interface{}{time.Now().UTC().Truncate(time.Second), UserID}
+	Suffix := "WHERE Bans.LiftTime IS NULL AND (Bans.ExpiryTime IS NULL OR Bans.ExpiryTime > ?) AND ((Bans.UserID <> 0 AND Bans.UserID = ?)"
+	if len(IPRanges) > 0 {
+		Suffix += " OR Bans.IPRange IN (?" + strings.Repeat(", ?", len(IPRanges)-1) + ")"
+		for _, IPRange := range IPRanges {
+			Arguments = append(Arguments, IPRange)
+		}
+	}
+	bans, err := DBConnection.queryBans(Suffix+") ORDER BY Bans.ID DESC;", Arguments...)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "SQLitePlugin/GetActiveBansOn", strconv.FormatUint(UserID, 10), logging.ResultFailure, []string{"Failed to get bans", IP, err.Error()})
+		return nil, err
+	}
+	return bans, nil
+}
+
+//LiftBan lifts a ban that has not already been lifted, before it expires
+func (DBConnection *SQLitePlugin) LiftBan(BanID uint64, LifterID uint64) error {
+	resultInfo, err := DBConnection.DBHandle.Exec("UPDATE Bans SET LifterID = ?, LiftTime = CURRENT_TIMESTAMP WHERE ID = ? AND LiftTime IS NULL;", LifterID, BanID)
+	if err == nil {
+		if affected, _ := resultInfo.RowsAffected(); affected == 0 {
+			err = errors.New("ban does not exist or is already lifted")
+		}
+	}
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "SQLitePlugin/LiftBan", strconv.FormatUint(LifterID, 10), logging.ResultFailure, []string{"Failed to lift ban", strconv.FormatUint(BanID, 10), err.Error()})
+		return err
+	}
+	logging.WriteLog(logging.LogLevelInfo, "SQLitePlugin/LiftBan", strconv.FormatUint(LifterID, 10), logging.ResultSuccess, []string{"Ban lifted", strconv.FormatUint(BanID, 10)})
+	return nil
+}
+
+//queryBans returns bans with the names of the banned account and the moderator that banned them, Suffix is added after the joins to filter and order them
+func (DBConnection *SQLitePlugin) queryBans(Suffix string, Arguments ...interface{}) ([]interfaces.BanInformation, error) {
+	rows, err := DBConnection.DBHandle.Query(`SELECT Bans.ID, Bans.UserID, IFNULL(Banned.Name, ''), Bans.IPRange, Bans.Reason, Bans.BannerID, IFNULL(Banners.Name, ''), Bans.CreationTime, Bans.ExpiryTime, Bans.LifterID, Bans.LiftTime
+	FROM Bans
+	LEFT OUTER JOIN Users AS Banned ON Bans.UserID = Banned.ID AND Bans.UserID <> 0
+	LEFT OUTER JOIN Users AS Banners ON Bans.BannerID = Banners.ID `+Suffix, Arguments...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ToReturn []interfaces.BanInformation
+	for rows.Next() {
+		var ban interfaces.BanInformation
+		var CreationTime sql.NullTime
+		var ExpiryTime sql.NullTime
+		var LiftTime sql.NullTime
+		if err := rows.Scan(&ban.ID, &ban.UserID, &ban.UserName, &ban.IPRange, &ban.Reason, &ban.BannerID, &ban.BannerName, &CreationTime, &ExpiryTime, &ban.LifterID, &LiftTime); err != nil {
+			return nil, err
+		}
+		ban.CreationTime = CreationTime.Time
+		ban.ExpiryTime = ExpiryTime.Time
+		ban.LiftTime = LiftTime.Time
+		ToReturn = append(ToReturn, ban)
+	}
+	return ToReturn, rows.Err()
+}
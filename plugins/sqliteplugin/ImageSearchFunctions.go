@@ -0,0 +1,18 @@
+package sqliteplugin
+
+import "go-image-board/interfaces"
+
+//SearchImages performs a search for images (Returns a list of ImageInformations a result count and an error/nil)
+func (DBConnection *SQLitePlugin) SearchImages(Tags []interfaces.TagInformation, PageStart uint64, PageStride uint64) ([]interfaces.ImageInformation, uint64, error) {
+	return DBConnection.searcher().SearchImages(Tags, PageStart, PageStride)
+}
+
+//GetPrevNexImages performs a search for images (Returns a list of ImageInformations (Up to 2) and an error/nil)
+func (DBConnection *SQLitePlugin) GetPrevNexImages(Tags []interfaces.TagInformation, TargetID uint64) ([]interfaces.ImageInformation, error) {
+	return DBConnection.searcher().GetPrevNexImages(Tags, TargetID)
+}
+
+//GetRandomImage returns a random image (Returns a ImageInformation and an error/nil)
+func (DBConnection *SQLitePlugin) GetRandomImage(Tags []interfaces.TagInformation) (interfaces.ImageInformation, uint64, error) {
+	return DBConnection.searcher().GetRandomImage(Tags)
+}
@@ -0,0 +1,60 @@
+package sqliteplugin
+
+import (
+	"database/sql"
+	"errors"
+	"go-image-board/interfaces"
+	"go-image-board/logging"
+	"strconv"
+)
+
+//SetImagePending holds an image in the moderation queue, hiding it from searches, or publishes it
+func (DBConnection *SQLitePlugin) SetImagePending(ImageID uint64, Pending bool) error {
+	resultInfo, err := DBConnection.DBHandle.Exec("UPDATE Images SET Pending = ? WHERE ID = ? AND Pending <> ?;", Pending, ImageID, Pending)
+	if err == nil {
+		if affected, _ := resultInfo.RowsAffected(); affected == 0 {
+			if Pending {
+				err = errors.New("image does not exist or is already waiting for approval")
+			} else {
+				err = errors.New("image does not exist or is not waiting for approval")
+			}
+		}
+	}
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "SQLitePlugin/SetImagePending", "0", logging.ResultFailure, []string{"Failed to update image pending state", strconv.FormatUint(ImageID, 10), err.Error()})
+		return err
+	}
+	logging.WriteLog(logging.LogLevelInfo, "SQLitePlugin/SetImagePending", "0", logging.ResultSuccess, []string{"Image pending state updated", strconv.FormatUint(ImageID, 10), strconv.FormatBool(Pending)})
+	return nil
+}
+
+//GetPendingImages returns images waiting in the moderation queue that are not in the trash, oldest first, and the count of all of them
+func (DBConnection *SQLitePlugin) GetPendingImages(PageStart uint64, PageStride uint64) ([]interfaces.ImageInformation, uint64, error) {
+	var MaxResults uint64
+	if err := DBConnection.DBHandle.QueryRow("SELECT COUNT(*) FROM Images WHERE Pending = TRUE AND DeletedTime IS NULL;").Scan(&MaxResults); err != nil {
+		logging.WriteLog(logging.LogLevelError, "SQLitePlugin/GetPendingImages", "0", logging.ResultFailure, []string{"Error running count query", err.Error()})
+		return nil, 0, err
+	}
+	rows, err := DBConnection.DBHandle.Query(`SELECT Images.ID, Images.Name, Images.Location, Images.UploaderID, IFNULL(Users.Name, ''), Images.UploadTime
+	FROM Images
+	LEFT OUTER JOIN Users ON Images.UploaderID = Users.ID
+	WHERE Images.Pending = TRUE AND Images.DeletedTime IS NULL
+	ORDER BY Images.ID LIMIT ? OFFSET ?;`, PageStride, PageStart)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "SQLitePlugin/GetPendingImages", "0", logging.ResultFailure, []string{"Failed to get pending images", err.Error()})
+		return nil, 0, err
+	}
+	defer rows.Close()
+	var ToReturn []interfaces.ImageInformation
+	for rows.Next() {
+		imageInfo := interfaces.ImageInformation{Pending: true}
+		var UploadTime sql.NullTime
+		if err := rows.Scan(&imageInfo.ID, &imageInfo.Name, &imageInfo.Location, &imageInfo.UploaderID, &imageInfo.UploaderName, &UploadTime); err != nil {
+			logging.WriteLog(logging.LogLevelError, "SQLitePlugin/GetPendingImages", "0", logging.ResultFailure, []string{"Failed to scan pending image", err.Error()})
+			return nil, 0, err
+		}
+		imageInfo.UploadTime = UploadTime.Time
+		ToReturn = append(ToReturn, imageInfo)
+	}
+	return ToReturn, MaxResults, rows.Err()
+}
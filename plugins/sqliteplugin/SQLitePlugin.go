@@ -0,0 +1,81 @@
+package sqliteplugin
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"go-image-board/config"
+	"go-image-board/logging"
+	"go-image-board/plugins/sqliteplugin/migrations"
+	"go-image-board/plugins/migrate"
+	"go-image-board/plugins/sqlsearch"
+	"math/bits"
+	"math/rand"
+	"sync"
+	"time"
+
+	//Pure go driver, so we can still build with CGO disabled
+	"modernc.org/sqlite"
+)
+
+//auditLogRetention how long audit logs are kept before the cleanup task removes them
+var auditLogRetention = "-30 days"
+
+//SQLitePlugin acts as plugin between gib and a SQLite database file
+type SQLitePlugin struct {
+	DBHandle *sql.DB
+	//PasswordHashCost bcrypt cost used for passwords and security answers, 0 uses the same cost as the other plugins
+	PasswordHashCost int
+	//cleanupOnce ensures we only start one maintenance routine, even if InitDatabase is retried
+	cleanupOnce sync.Once
+}
+
+//searcher runs image, collection and tag searches against this database
+func (DBConnection *SQLitePlugin) searcher() *sqlsearch.Searcher {
+	return &sqlsearch.Searcher{DB: DBConnection.DBHandle, Dialect: sqlsearch.SQLite, Lookup: DBConnection}
+}
+
+func init() {
+	//SQLite has neither BIT_COUNT nor a XOR operator, so provide the hamming distance used by the similar metatag
+	err := sqlite.RegisterDeterministicScalarFunction("HAMMING_DISTANCE", 2, func(ctx *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		valueA, okA := args[0].(int64)
+		valueB, okB := args[1].(int64)
+		if okA == false || okB == false {
+			return nil, nil
+		}
+		return int64(bits.OnesCount64(uint64(valueA ^ valueB))), nil
+	})
+	if err != nil {
+		logging.WriteLog(logging.LogLevelCritical, "SQLitePlugin/init", "0", logging.ResultFailure, []string{"Failed to register HAMMING_DISTANCE function", err.Error()})
+	}
+}
+
+//InitDatabase connects to a database, and if needed, creates and or updates tables
+func (DBConnection *SQLitePlugin) InitDatabase() error {
+	rand.Seed(time.Now().UnixNano())
+	var err error
+	//https://pkg.go.dev/modernc.org/sqlite#Driver.Open
+	DBConnection.DBHandle, err = sql.Open("sqlite", "file:"+config.Configuration.DBPath+"?_pragma=foreign_keys(1)&_pragma=busy_timeout(10000)&_pragma=journal_mode(WAL)")
+	if err == nil {
+		err = DBConnection.DBHandle.Ping() //Ping actually validates we can query database
+		if err == nil {
+			runner := migrate.Runner{DB: DBConnection.DBHandle, Dialect: migrate.SQLite, Registry: migrations.Registry}
+			if err := runner.Run(); err != nil {
+				return err
+			}
+			//SQLite has no event scheduler, so we run our own maintenance
+			DBConnection.cleanupOnce.Do(func() { go DBConnection.maintenanceLoop() })
+		}
+	}
+
+	return err
+}
+
+//maintenanceLoop performs the work MariaDB would otherwise do in scheduled events
+func (DBConnection *SQLitePlugin) maintenanceLoop() {
+	for {
+		if _, err := DBConnection.DBHandle.Exec("DELETE FROM AuditLogs WHERE LogTime < datetime('now', ?);", auditLogRetention); err != nil {
+			logging.WriteLog(logging.LogLevelError, "SQLitePlugin/maintenanceLoop", "0", logging.ResultFailure, []string{"Failed to cleanup audit logs", err.Error()})
+		}
+		time.Sleep(24 * time.Hour)
+	}
+}
@@ -0,0 +1,193 @@
+package sqliteplugin
+
+import (
+	"database/sql"
+	"errors"
+	"go-image-board/interfaces"
+	"go-image-board/logging"
+	"strconv"
+	"time"
+)
+
+//TrashImage moves an image to the trash, hiding it from searches until it is restored or purged
+func (DBConnection *SQLitePlugin) TrashImage(ImageID uint64, DeleterID uint64) error {
+	resultInfo, err := DBConnection.DBHandle.Exec("UPDATE Images SET DeletedTime = CURRENT_TIMESTAMP, DeleterID = ? WHERE ID = ? AND DeletedTime IS NULL;", DeleterID, ImageID)
+	if err == nil {
+		if affected, _ := resultInfo.RowsAffected(); affected == 0 {
+			err = errors.New("image does not exist or is already in the trash")
+		}
+	}
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "SQLitePlugin/TrashImage", strconv.FormatUint(DeleterID, 10), logging.ResultFailure, []string{"Failed to trash image", strconv.FormatUint(ImageID, 10), err.Error()})
+		return err
+	}
+	logging.WriteLog(logging.LogLevelInfo, "SQLitePlugin/TrashImage", strconv.FormatUint(DeleterID, 10), logging.ResultSuccess, []string{"Image moved to trash", strconv.FormatUint(ImageID, 10)})
+	return nil
+}
+
+//RestoreImage returns an image from the trash
+func (DBConnection *SQLitePlugin) RestoreImage(ImageID uint64) error {
+	resultInfo, err := DBConnection.DBHandle.Exec("UPDATE Images SET DeletedTime = NULL, DeleterID = 0 WHERE ID = ? AND DeletedTime IS NOT NULL;", ImageID)
+	if err == nil {
+		if affected, _ := resultInfo.RowsAffected(); affected == 0 {
+			err = errors.New("image does not exist or is not in the trash")
+		}
+	}
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "SQLitePlugin/RestoreImage", "0", logging.ResultFailure, []string{"Failed to restore image", strconv.FormatUint(ImageID, 10), err.Error()})
+		return err
+	}
+	logging.WriteLog(logging.LogLevelInfo, "SQLitePlugin/RestoreImage", "0", logging.ResultSuccess, []string{"Image restored from trash", strconv.FormatUint(ImageID, 10)})
+	return nil
+}
+
+//TrashCollection moves a collection to the trash, and if IncludeMembers is set, every member not already in the trash.
+//Members share the collection's DeletedTime, which is how RestoreCollection finds them again
+func (DBConnection *SQLitePlugin) TrashCollection(CollectionID uint64, DeleterID uint64, IncludeMembers bool) error {
+	resultInfo, err := DBConnection.DBHandle.Exec("UPDATE Collections SET DeletedTime = CURRENT_TIMESTAMP, DeleterID = ? WHERE ID = ? AND DeletedTime IS NULL;", DeleterID, CollectionID)
+	if err == nil {
+		if affected, _ := resultInfo.RowsAffected(); affected == 0 {
+			err = errors.New("collection does not exist or is already in the trash")
+		}
+	}
+	if err == nil && IncludeMembers {
+		_, err = DBConnection.DBHandle.Exec(`UPDATE Images SET DeletedTime = (SELECT DeletedTime FROM Collections WHERE ID = ?), DeleterID = ?
+		WHERE DeletedTime IS NULL AND ID IN (SELECT ImageID FROM CollectionMembers WHERE CollectionID = ?);`, CollectionID, DeleterID, CollectionID)
+	}
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "SQLitePlugin/TrashCollection", strconv.FormatUint(DeleterID, 10), logging.ResultFailure, []string{"Failed to trash collection", strconv.FormatUint(CollectionID, 10), err.Error()})
+		return err
+	}
+	logging.WriteLog(logging.LogLevelInfo, "SQLitePlugin/TrashCollection", strconv.FormatUint(DeleterID, 10), logging.ResultSuccess, []string{"Collection moved to trash", strconv.FormatUint(CollectionID, 10)})
+	return nil
+}
+
+//RestoreCollection returns a collection from the trash, along with the members that were trashed with it
+func (DBConnection *SQLitePlugin) RestoreCollection(CollectionID uint64) error {
+	//Members first, as they are matched against the collection's DeletedTime
+	_, err := DBConnection.DBHandle.Exec(`UPDATE Images SET DeletedTime = NULL, DeleterID = 0
+	WHERE ID IN (SELECT ImageID FROM CollectionMembers WHERE CollectionID = ?)
+	AND DeletedTime = (SELECT DeletedTime FROM Collections WHERE ID = ?)
+	AND DeleterID = (SELECT DeleterID FROM Collections WHERE ID = ?);`, CollectionID, CollectionID, CollectionID)
+	if err == nil {
+		var resultInfo sql.Result
+		resultInfo, err = DBConnection.DBHandle.Exec("UPDATE Collections SET DeletedTime = NULL, DeleterID = 0 WHERE ID = ? AND DeletedTime IS NOT NULL;", CollectionID)
+		if err == nil {
+			if affected, _ := resultInfo.RowsAffected(); affected == 0 {
+				err = errors.New("collection does not exist or is not in the trash")
+			}
+		}
+	}
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "SQLitePlugin/RestoreCollection", "0", logging.ResultFailure, []string{"Failed to restore collection", strconv.FormatUint(CollectionID, 10), err.Error()})
+		return err
+	}
+	logging.WriteLog(logging.LogLevelInfo, "SQLitePlugin/RestoreCollection", "0", logging.ResultSuccess, []string{"Collection restored from trash", strconv.FormatUint(CollectionID, 10)})
+	return nil
+}
+
+//GetTrashedImages returns images in the trash, most recently deleted first, and the count of all images in the trash
+func (DBConnection *SQLitePlugin) GetTrashedImages(PageStart uint64, PageStride uint64) ([]interfaces.ImageInformation, uint64, error) {
+	var MaxResults uint64
+	if err := DBConnection.DBHandle.QueryRow("SELECT COUNT(*) FROM Images WHERE DeletedTime IS NOT NULL;").Scan(&MaxResults); err != nil {
+		logging.WriteLog(logging.LogLevelError, "SQLitePlugin/GetTrashedImages", "0", logging.ResultFailure, []string{"Error running count query", err.Error()})
+		return nil, 0, err
+	}
+	ToReturn, err := DBConnection.queryTrashedImages("ORDER BY Images.DeletedTime DESC, Images.ID DESC LIMIT ? OFFSET ?;", PageStride, PageStart)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "SQLitePlugin/GetTrashedImages", "0", logging.ResultFailure, []string{"Failed to get trashed images", err.Error()})
+		return nil, 0, err
+	}
+	return ToReturn, MaxResults, nil
+}
+
+//GetTrashedCollections returns collections in the trash, most recently deleted first, and the count of all collections in the trash
+func (DBConnection *SQLitePlugin) GetTrashedCollections(PageStart uint64, PageStride uint64) ([]interfaces.CollectionInformation, uint64, error) {
+	var MaxResults uint64
+	if err := DBConnection.DBHandle.QueryRow("SELECT COUNT(*) FROM Collections WHERE DeletedTime IS NOT NULL;").Scan(&MaxResults); err != nil {
+		logging.WriteLog(logging.LogLevelError, "SQLitePlugin/GetTrashedCollections", "0", logging.ResultFailure, []string{"Error running count query", err.Error()})
+		return nil, 0, err
+	}
+	ToReturn, err := DBConnection.queryTrashedCollections("ORDER BY CL.DeletedTime DESC, CL.ID DESC LIMIT ? OFFSET ?;", PageStride, PageStart)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "SQLitePlugin/GetTrashedCollections", "0", logging.ResultFailure, []string{"Failed to get trashed collections", err.Error()})
+		return nil, 0, err
+	}
+	return ToReturn, MaxResults, nil
+}
+
+//GetExpiredTrash returns every image and collection that has been in the trash for longer than RetentionPeriod
+func (DBConnection *SQLitePlugin) GetExpiredTrash(RetentionPeriod time.Duration) ([]interfaces.ImageInformation, []interfaces.CollectionInformation, error) {
+	//DeletedTime is stored to the second, so the cutoff is inclusive, otherwise something deleted this second would outlive a retention of 0
+	cutoff := "-" + strconv.FormatInt(int64(RetentionPeriod/time.Second), 10) + " seconds"
+	Images, err := DBConnection.queryTrashedImages("AND Images.DeletedTime <= datetime('now', ?) ORDER BY Images.ID;", cutoff)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "SQLitePlugin/GetExpiredTrash", "0", logging.ResultFailure, []string{"Failed to get expired images", err.Error()})
+		return nil, nil, err
+	}
+	Collections, err := DBConnection.queryTrashedCollections("AND CL.DeletedTime <= datetime('now', ?) ORDER BY CL.ID;", cutoff)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "SQLitePlugin/GetExpiredTrash", "0", logging.ResultFailure, []string{"Failed to get expired collections", err.Error()})
+		return nil, nil, err
+	}
+	return Images, Collections, nil
+}
+
+//queryTrashedImages returns the images in the trash, Suffix is added after the WHERE clause to filter and order them
+func (DBConnection *SQLitePlugin) queryTrashedImages(Suffix string, Arguments ...interface{}) ([]interfaces.ImageInformation, error) {
+	rows, err := DBConnection.DBHandle.Query(`SELECT Images.ID, Images.Name, Images.Location, Images.UploaderID, Images.DeletedTime, Images.DeleterID, IFNULL(Users.Name, '')
+	FROM Images
+	LEFT OUTER JOIN Users ON Images.DeleterID = Users.ID
+	WHERE Images.DeletedTime IS NOT NULL `+Suffix, Arguments...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ToReturn []interfaces.ImageInformation
+	for rows.Next() {
+		imageInfo := interfaces.ImageInformation{InTrash: true}
+		var DeletedTime sql.NullTime
+		if err := rows.Scan(&imageInfo.ID, &imageInfo.Name, &imageInfo.Location, &imageInfo.UploaderID, &DeletedTime, &imageInfo.DeleterID, &imageInfo.DeleterName); err != nil {
+			return nil, err
+		}
+		imageInfo.DeletedTime = DeletedTime.Time
+		ToReturn = append(ToReturn, imageInfo)
+	}
+	return ToReturn, rows.Err()
+}
+
+//queryTrashedCollections returns the collections in the trash, Suffix is added after the WHERE clause to filter and order them
+func (DBConnection *SQLitePlugin) queryTrashedCollections(Suffix string, Arguments ...interface{}) ([]interfaces.CollectionInformation, error) {
+	rows, err := DBConnection.DBHandle.Query(`SELECT CL.ID, CL.Name, IFNULL(CL.Description, ''), CL.UploaderID, CL.DeletedTime, CL.DeleterID, IFNULL(Users.Name, ''), IFNULL(Preview.Location, ''), IFNULL(Counts.Members, 0)
+	FROM Collections CL
+	LEFT OUTER JOIN Users ON CL.DeleterID = Users.ID
+	-- This part gets the number of members in a collection
+	LEFT JOIN (
+		SELECT CollectionID, Count(*) as Members
+		FROM CollectionMembers
+		GROUP BY CollectionID
+	) Counts ON Counts.CollectionID = CL.ID
+	-- This part gets a preview image location
+	LEFT JOIN (
+		SELECT CM.CollectionID as CollectionID, Images.Location as Location
+		FROM CollectionMembers as CM
+		INNER JOIN Images on Images.ID = CM.ImageID
+		WHERE OrderWeight = (SELECT MIN(OrderWeight) From CollectionMembers WHERE CollectionMembers.CollectionID = CM.CollectionID)
+	) Preview ON Preview.CollectionID = CL.ID
+	WHERE CL.DeletedTime IS NOT NULL `+Suffix, Arguments...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ToReturn []interfaces.CollectionInformation
+	for rows.Next() {
+		collectionInfo := interfaces.CollectionInformation{InTrash: true}
+		var DeletedTime sql.NullTime
+		if err := rows.Scan(&collectionInfo.ID, &collectionInfo.Name, &collectionInfo.Description, &collectionInfo.UploaderID, &DeletedTime, &collectionInfo.DeleterID, &collectionInfo.DeleterName, &collectionInfo.Location, &collectionInfo.Members); err != nil {
+			return nil, err
+		}
+		collectionInfo.DeletedTime = DeletedTime.Time
+		ToReturn = append(ToReturn, collectionInfo)
+	}
+	return ToReturn, rows.Err()
+}
@@ -0,0 +1,94 @@
+package migrations
+
+import "go-image-board/plugins/migrate"
+
+func init() {
+	Registry.Register(migrate.Migration{
+		Version:     1,
+		Description: "Initial schema",
+		Statements: []string{
+			//Images and tags
+			"CREATE TABLE Tags (ID INTEGER PRIMARY KEY AUTOINCREMENT, Name VARCHAR(255) NOT NULL UNIQUE, Description VARCHAR(255), UploaderID INTEGER NOT NULL, UploadTime TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL, AliasedID INTEGER NOT NULL DEFAULT 0, IsAlias BOOL NOT NULL DEFAULT FALSE);",
+			"CREATE TABLE Images (ID INTEGER PRIMARY KEY AUTOINCREMENT, UploaderID INTEGER NOT NULL, Name VARCHAR(255) NOT NULL, Rating VARCHAR(255) DEFAULT 'unrated', ScoreTotal INTEGER NOT NULL DEFAULT 0, ScoreAverage INTEGER NOT NULL DEFAULT 0, ScoreVoters INTEGER NOT NULL DEFAULT 0, Location VARCHAR(255) UNIQUE NOT NULL, Source VARCHAR(2000) NOT NULL DEFAULT '', UploadTime TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL, Description TEXT NOT NULL DEFAULT '');",
+			"CREATE INDEX ImagesUploaderID ON Images(UploaderID);",
+			"CREATE INDEX ImagesRating ON Images(Rating);",
+			"CREATE INDEX ImagesUploadTime ON Images(UploadTime);",
+			"CREATE INDEX ImagesScoreAverage ON Images(ScoreAverage);",
+			"CREATE TABLE ImageTags (ID INTEGER PRIMARY KEY AUTOINCREMENT, ImageID INTEGER NOT NULL REFERENCES Images(ID), TagID INTEGER NOT NULL REFERENCES Tags(ID), LinkerID INTEGER NOT NULL, LinkTime TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL, UNIQUE (TagID, ImageID));",
+			"CREATE INDEX ImageTagsImageID ON ImageTags(ImageID);",
+			"CREATE INDEX ImageTagsLinkerID ON ImageTags(LinkerID);",
+			//Hashes are stored as signed 64bit integers, as SQLite does not support unsigned, the bits are identical
+			"CREATE TABLE ImagedHashes (ID INTEGER PRIMARY KEY AUTOINCREMENT, ImageID INTEGER NOT NULL UNIQUE REFERENCES Images(ID), vHash INTEGER NOT NULL, hHash INTEGER NOT NULL);",
+			"CREATE INDEX ImagedHashesvHash ON ImagedHashes(vHash);",
+			"CREATE INDEX ImagedHasheshHash ON ImagedHashes(hHash);",
+			"CREATE TABLE ImageUserScores (ID INTEGER PRIMARY KEY AUTOINCREMENT, UserID INTEGER NOT NULL, ImageID INTEGER NOT NULL, Score INTEGER NOT NULL, CreationTime TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL, UNIQUE (UserID, ImageID));",
+			//Users, NOCASE to match the behaviour of MariaDB's default collation
+			"CREATE TABLE Users (ID INTEGER PRIMARY KEY AUTOINCREMENT, Name VARCHAR(40) NOT NULL UNIQUE COLLATE NOCASE, EMail VARCHAR(255) NOT NULL UNIQUE COLLATE NOCASE, PasswordHash VARCHAR(255) NOT NULL, TokenID VARCHAR(255), IP VARCHAR(50), SecQuestionOne VARCHAR(50), SecQuestionTwo VARCHAR(50), SecQuestionThree VARCHAR(50), SecAnswerOne VARCHAR(255), SecAnswerTwo VARCHAR(255), SecAnswerThree VARCHAR(255), CreationTime TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL, Disabled BOOL NOT NULL DEFAULT FALSE, Permissions INTEGER NOT NULL DEFAULT 0, SearchFilter VARCHAR(255) NOT NULL DEFAULT '');",
+			//Auditing
+			"CREATE TABLE AuditLogs (ID INTEGER PRIMARY KEY AUTOINCREMENT, UserID INTEGER NOT NULL, Type VARCHAR(40), Info VARCHAR(10240) NOT NULL DEFAULT '', LogTime TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL);",
+			"CREATE INDEX AuditLogsLogTime ON AuditLogs(LogTime);",
+			//Collections
+			"CREATE TABLE Collections (ID INTEGER PRIMARY KEY AUTOINCREMENT, Name VARCHAR(255) NOT NULL UNIQUE COLLATE NOCASE, Description VARCHAR(255), UploaderID INTEGER NOT NULL, UploadTime TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL);",
+			"CREATE TABLE CollectionMembers (ID INTEGER PRIMARY KEY AUTOINCREMENT, ImageID INTEGER NOT NULL REFERENCES Images(ID), CollectionID INTEGER NOT NULL REFERENCES Collections(ID), LinkerID INTEGER NOT NULL, LinkTime TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL, OrderWeight INTEGER NOT NULL, UNIQUE (CollectionID, ImageID));",
+			"CREATE TABLE CollectionTags (ID INTEGER PRIMARY KEY AUTOINCREMENT, CollectionID INTEGER NOT NULL REFERENCES Collections(ID), TagID INTEGER NOT NULL REFERENCES Tags(ID), LinkerID INTEGER NOT NULL, LinkTime TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL, UNIQUE (TagID, CollectionID));",
+			//Triggers, SQLite does not support stored procedures so the procedure bodies are inlined
+			`CREATE TRIGGER onCollectionDelete BEFORE DELETE ON Collections
+	FOR EACH ROW BEGIN
+		DELETE FROM CollectionMembers WHERE CollectionID=OLD.ID;
+		DELETE FROM CollectionTags WHERE CollectionID=OLD.ID;
+	END`,
+			`CREATE TRIGGER onCollectionMemberAdd AFTER INSERT ON CollectionMembers
+	FOR EACH ROW BEGIN
+		INSERT OR IGNORE INTO CollectionTags (TagID, CollectionID, LinkerID)
+		SELECT DISTINCT ImageTags.TagID, CollectionMembers.CollectionID, ImageTags.LinkerID
+		FROM ImageTags
+		INNER JOIN CollectionMembers ON CollectionMembers.ImageID = ImageTags.ImageID
+		LEFT JOIN CollectionTags ON CollectionTags.CollectionID = CollectionMembers.CollectionID AND CollectionTags.TagID = ImageTags.TagID
+		WHERE CollectionTags.CollectionID IS NULL AND ImageTags.ImageID = NEW.ImageID;
+	END`,
+			`CREATE TRIGGER onCollectionMemberDelete AFTER DELETE ON CollectionMembers
+	FOR EACH ROW BEGIN
+		DELETE FROM CollectionTags
+		WHERE TagID NOT IN ( SELECT TagID
+								FROM ImageTags
+								INNER JOIN CollectionMembers ON CollectionMembers.ImageID = ImageTags.ImageID
+								WHERE CollectionMembers.CollectionID = OLD.CollectionID
+							)
+		AND CollectionID=OLD.CollectionID;
+	END`,
+			`CREATE TRIGGER onImageTagDelete AFTER DELETE ON ImageTags
+	FOR EACH ROW BEGIN
+		DELETE FROM CollectionTags
+		WHERE CollectionID IN (SELECT CollectionID FROM CollectionMembers WHERE ImageID = OLD.ImageID)
+		AND TagID NOT IN ( SELECT ImageTags.TagID
+								FROM ImageTags
+								INNER JOIN CollectionMembers ON CollectionMembers.ImageID = ImageTags.ImageID
+								WHERE CollectionMembers.CollectionID = CollectionTags.CollectionID
+							);
+	END`,
+			`CREATE TRIGGER onImageDelete BEFORE DELETE ON Images
+	FOR EACH ROW BEGIN
+		DELETE FROM ImageTags WHERE ImageID=OLD.ID;
+		DELETE FROM ImageUserScores WHERE ImageID=OLD.ID;
+		DELETE FROM CollectionMembers WHERE ImageID=OLD.ID;
+		DELETE FROM ImagedHashes WHERE ImageID=OLD.ID;
+	END`,
+			`CREATE TRIGGER onImageTagInsert AFTER INSERT ON ImageTags
+	FOR EACH ROW BEGIN
+		INSERT OR IGNORE INTO CollectionTags (TagID, CollectionID, LinkerID)
+		SELECT DISTINCT ImageTags.TagID, CollectionMembers.CollectionID, ImageTags.LinkerID
+		FROM ImageTags
+		INNER JOIN CollectionMembers ON CollectionMembers.ImageID = ImageTags.ImageID
+		LEFT JOIN CollectionTags ON CollectionTags.CollectionID = CollectionMembers.CollectionID AND CollectionTags.TagID = ImageTags.TagID
+		WHERE CollectionTags.CollectionID IS NULL AND ImageTags.ImageID = NEW.ImageID;
+	END`,
+			`CREATE TRIGGER onTagDelete BEFORE DELETE ON Tags
+	FOR EACH ROW BEGIN
+		DELETE FROM ImageTags WHERE TagID=OLD.ID;
+		DELETE FROM CollectionTags WHERE TagID=OLD.ID;
+	END`,
+			//Reserve system for auditing
+			"INSERT INTO Users (ID, Name, EMail, PasswordHash, Disabled) VALUES (0, 'SYSTEM', '', '', TRUE);",
+		},
+	})
+}
@@ -0,0 +1,18 @@
+package migrations
+
+import "go-image-board/plugins/migrate"
+
+func init() {
+	Registry.Register(migrate.Migration{
+		Version:     2,
+		Description: "Add trash to images and collections",
+		Statements: []string{
+			"ALTER TABLE Images ADD COLUMN DeletedTime TIMESTAMP NULL DEFAULT NULL;",
+			"ALTER TABLE Images ADD COLUMN DeleterID INTEGER NOT NULL DEFAULT 0;",
+			"CREATE INDEX ImagesDeletedTime ON Images(DeletedTime);",
+			"ALTER TABLE Collections ADD COLUMN DeletedTime TIMESTAMP NULL DEFAULT NULL;",
+			"ALTER TABLE Collections ADD COLUMN DeleterID INTEGER NOT NULL DEFAULT 0;",
+			"CREATE INDEX CollectionsDeletedTime ON Collections(DeletedTime);",
+		},
+	})
+}
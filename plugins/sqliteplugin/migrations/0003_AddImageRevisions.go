@@ -0,0 +1,14 @@
+package migrations
+
+import "go-image-board/plugins/migrate"
+
+func init() {
+	Registry.Register(migrate.Migration{
+		Version:     3,
+		Description: "Add image revisions",
+		Statements: []string{
+			"CREATE TABLE ImageRevisions (ID INTEGER PRIMARY KEY AUTOINCREMENT, ImageID INTEGER NOT NULL REFERENCES Images(ID), Location VARCHAR(255) NOT NULL, ReplacerID INTEGER NOT NULL, ReplacedTime TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL);",
+			"CREATE INDEX ImageRevisionsImageID ON ImageRevisions(ImageID);",
+		},
+	})
+}
@@ -0,0 +1,14 @@
+package migrations
+
+import "go-image-board/plugins/migrate"
+
+func init() {
+	Registry.Register(migrate.Migration{
+		Version:     5,
+		Description: "Add tag implications",
+		Statements: []string{
+			"CREATE TABLE TagImplications (ID INTEGER PRIMARY KEY AUTOINCREMENT, TagID INTEGER NOT NULL REFERENCES Tags(ID), ImpliedTagID INTEGER NOT NULL REFERENCES Tags(ID), CreatorID INTEGER NOT NULL, CreationTime TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL, UNIQUE (TagID, ImpliedTagID));",
+			"CREATE INDEX TagImplicationsImpliedTagID ON TagImplications(ImpliedTagID);",
+		},
+	})
+}
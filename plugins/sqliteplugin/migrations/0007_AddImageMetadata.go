@@ -0,0 +1,18 @@
+package migrations
+
+import "go-image-board/plugins/migrate"
+
+func init() {
+	Registry.Register(migrate.Migration{
+		Version:     7,
+		Description: "Add image metadata",
+		Statements: []string{
+			"ALTER TABLE Images ADD COLUMN Width INTEGER NOT NULL DEFAULT 0;",
+			"ALTER TABLE Images ADD COLUMN Height INTEGER NOT NULL DEFAULT 0;",
+			"ALTER TABLE Images ADD COLUMN FileSize INTEGER NOT NULL DEFAULT 0;",
+			"ALTER TABLE Images ADD COLUMN MIMEType VARCHAR(255) NOT NULL DEFAULT '';",
+			"ALTER TABLE Images ADD COLUMN Duration REAL NOT NULL DEFAULT 0;",
+			"CREATE INDEX ImagesMIMEType ON Images(MIMEType);",
+		},
+	})
+}
@@ -0,0 +1,14 @@
+package migrations
+
+import "go-image-board/plugins/migrate"
+
+func init() {
+	Registry.Register(migrate.Migration{
+		Version:     9,
+		Description: "Add image favorites",
+		Statements: []string{
+			"CREATE TABLE ImageUserFavorites (ID INTEGER PRIMARY KEY AUTOINCREMENT, UserID INTEGER NOT NULL, ImageID INTEGER NOT NULL REFERENCES Images(ID), CreationTime TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL, UNIQUE (UserID, ImageID));",
+			"CREATE INDEX ImageUserFavoritesImageID ON ImageUserFavorites(ImageID);",
+		},
+	})
+}
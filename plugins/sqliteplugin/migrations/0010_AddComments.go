@@ -0,0 +1,19 @@
+package migrations
+
+import "go-image-board/plugins/migrate"
+
+func init() {
+	Registry.Register(migrate.Migration{
+		Version:     10,
+		Description: "Add comments",
+		Statements: []string{
+			//ImageID and CollectionID are 0 when the comment is on the other, so neither can reference its table
+			"CREATE TABLE Comments (ID INTEGER PRIMARY KEY AUTOINCREMENT, ImageID INTEGER NOT NULL DEFAULT 0, CollectionID INTEGER NOT NULL DEFAULT 0, ParentID INTEGER NOT NULL DEFAULT 0, ThreadID INTEGER NOT NULL DEFAULT 0, UserID INTEGER NOT NULL, Body TEXT NOT NULL, CreationTime TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL, EditTime TIMESTAMP NULL DEFAULT NULL, DeletedTime TIMESTAMP NULL DEFAULT NULL, DeleterID INTEGER NOT NULL DEFAULT 0);",
+			"CREATE INDEX CommentsImageID ON Comments(ImageID);",
+			"CREATE INDEX CommentsCollectionID ON Comments(CollectionID);",
+			"CREATE INDEX CommentsThreadID ON Comments(ThreadID);",
+			"CREATE TABLE CommentRevisions (ID INTEGER PRIMARY KEY AUTOINCREMENT, CommentID INTEGER NOT NULL REFERENCES Comments(ID), Body TEXT NOT NULL, EditorID INTEGER NOT NULL, EditTime TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL);",
+			"CREATE INDEX CommentRevisionsCommentID ON CommentRevisions(CommentID);",
+		},
+	})
+}
@@ -0,0 +1,16 @@
+package migrations
+
+import "go-image-board/plugins/migrate"
+
+func init() {
+	Registry.Register(migrate.Migration{
+		Version:     11,
+		Description: "Add notes",
+		Statements: []string{
+			"CREATE TABLE Notes (ID INTEGER PRIMARY KEY AUTOINCREMENT, ImageID INTEGER NOT NULL REFERENCES Images(ID), CreatorID INTEGER NOT NULL, X INTEGER NOT NULL, Y INTEGER NOT NULL, Width INTEGER NOT NULL, Height INTEGER NOT NULL, ImageWidth INTEGER NOT NULL, ImageHeight INTEGER NOT NULL, Body TEXT NOT NULL, CreationTime TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL, EditTime TIMESTAMP NULL DEFAULT NULL, DeletedTime TIMESTAMP NULL DEFAULT NULL, DeleterID INTEGER NOT NULL DEFAULT 0);",
+			"CREATE INDEX NotesImageID ON Notes(ImageID);",
+			"CREATE TABLE NoteRevisions (ID INTEGER PRIMARY KEY AUTOINCREMENT, NoteID INTEGER NOT NULL REFERENCES Notes(ID), X INTEGER NOT NULL, Y INTEGER NOT NULL, Width INTEGER NOT NULL, Height INTEGER NOT NULL, ImageWidth INTEGER NOT NULL, ImageHeight INTEGER NOT NULL, Body TEXT NOT NULL, Deleted BOOL NOT NULL DEFAULT FALSE, EditorID INTEGER NOT NULL, EditTime TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL);",
+			"CREATE INDEX NoteRevisionsNoteID ON NoteRevisions(NoteID);",
+		},
+	})
+}
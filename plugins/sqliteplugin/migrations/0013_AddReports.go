@@ -0,0 +1,15 @@
+package migrations
+
+import "go-image-board/plugins/migrate"
+
+func init() {
+	Registry.Register(migrate.Migration{
+		Version:     13,
+		Description: "Add reports",
+		Statements: []string{
+			"CREATE TABLE Reports (ID INTEGER PRIMARY KEY AUTOINCREMENT, TargetType VARCHAR(16) NOT NULL, TargetID INTEGER NOT NULL, ReporterID INTEGER NOT NULL, Category VARCHAR(32) NOT NULL, Reason TEXT NOT NULL, Status VARCHAR(16) NOT NULL DEFAULT 'open', ModeratorID INTEGER NOT NULL DEFAULT 0, Outcome TEXT NOT NULL DEFAULT '', CreationTime TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL, UpdateTime TIMESTAMP NULL DEFAULT NULL);",
+			"CREATE INDEX ReportsStatus ON Reports(Status);",
+			"CREATE INDEX ReportsReporterID ON Reports(ReporterID);",
+		},
+	})
+}
@@ -0,0 +1,14 @@
+package migrations
+
+import "go-image-board/plugins/migrate"
+
+func init() {
+	Registry.Register(migrate.Migration{
+		Version:     14,
+		Description: "Add bans",
+		Statements: []string{
+			"CREATE TABLE Bans (ID INTEGER PRIMARY KEY AUTOINCREMENT, UserID INTEGER NOT NULL DEFAULT 0, IPRange VARCHAR(64) NOT NULL DEFAULT '', Reason TEXT NOT NULL, BannerID INTEGER NOT NULL, CreationTime TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL, ExpiryTime TIMESTAMP NULL DEFAULT NULL, LifterID INTEGER NOT NULL DEFAULT 0, LiftTime TIMESTAMP NULL DEFAULT NULL);",
+			"CREATE INDEX BansLiftTime ON Bans(LiftTime);",
+		},
+	})
+}
@@ -0,0 +1,14 @@
+package migrations
+
+import "go-image-board/plugins/migrate"
+
+func init() {
+	Registry.Register(migrate.Migration{
+		Version:     15,
+		Description: "Index active bans by expiry",
+		Statements: []string{
+			"DROP INDEX BansLiftTime;",
+			"CREATE INDEX BansActive ON Bans(LiftTime, ExpiryTime);",
+		},
+	})
+}
@@ -0,0 +1,133 @@
+package sqliteplugin
+
+import (
+	"go-image-board/config"
+	"go-image-board/interfaces"
+	"go-image-board/logging"
+	"go-image-board/plugins"
+	"go-image-board/plugins/sqliteplugin/migrations"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestMain(m *testing.M) {
+	logging.LogInterface = &plugins.STDLog{}
+	logging.LogInterface.Init(-1, "", "")
+	os.Exit(m.Run())
+}
+
+//openTestDatabase returns a plugin using a new database file, migrated to the latest version
+func openTestDatabase(t *testing.T) *SQLitePlugin {
+	t.Helper()
+	config.Configuration.DBPath = filepath.Join(t.TempDir(), "gib.db")
+	db := &SQLitePlugin{PasswordHashCost: bcrypt.MinCost}
+	if err := db.InitDatabase(); err != nil {
+		t.Fatalf("InitDatabase: %v", err)
+	}
+	t.Cleanup(func() { db.DBHandle.Close() })
+	return db
+}
+
+func TestInitDatabaseIsRepeatable(t *testing.T) {
+	db := openTestDatabase(t)
+	if err := db.CreateUser("admin", []byte("adminpass"), "admin@example.com", 0); err != nil {
+		t.Fatal(err)
+	}
+	db.DBHandle.Close()
+
+	//Starting again on the same file keeps the data and applies nothing new
+	reopened := &SQLitePlugin{PasswordHashCost: bcrypt.MinCost}
+	if err := reopened.InitDatabase(); err != nil {
+		t.Fatalf("InitDatabase on an existing database: %v", err)
+	}
+	defer reopened.DBHandle.Close()
+	if _, err := reopened.GetUserID("admin"); err != nil {
+		t.Errorf("user was lost, %v", err)
+	}
+	var applied int
+	if err := reopened.DBHandle.QueryRow("SELECT COUNT(*) FROM SchemaMigrations WHERE Status = 'applied';").Scan(&applied); err != nil || applied != len(migrations.Registry.Migrations()) {
+		t.Errorf("%d of %d migrations applied, %v", applied, len(migrations.Registry.Migrations()), err)
+	}
+}
+
+func TestGetActiveBans(t *testing.T) {
+	db := openTestDatabase(t)
+	now := time.Now()
+	bans := map[string]interfaces.BanInformation{
+		"expired":   {UserID: 2, Reason: "expired", BannerID: 1, ExpiryTime: now.Add(-time.Minute)},
+		"lifted":    {UserID: 2, Reason: "lifted", BannerID: 1, ExpiryTime: now.Add(time.Hour)},
+		"active":    {UserID: 2, Reason: "active", BannerID: 1, ExpiryTime: now.Add(time.Minute)},
+		"permanent": {IPRange: "192.0.2.0/24", Reason: "permanent", BannerID: 1},
+		//Times in other zones are compared by the instant, not the text
+		"zoned": {UserID: 3, Reason: "zoned", BannerID: 1, ExpiryTime: now.Add(-time.Minute).In(time.FixedZone("UTC+10", 10*60*60))},
+	}
+	for _, reason := range []string{"expired", "lifted", "active", "permanent", "zoned"} {
+		banID, err := db.NewBan(bans[reason])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if reason == "lifted" {
+			if err := db.LiftBan(banID, 1); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	active, err := db.GetActiveBans()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(active) != 2 || active[0].Reason != "permanent" || active[1].Reason != "active" {
+		t.Fatalf("active bans are %+v", active)
+	}
+	if active[0].IsPermanent() == false || active[1].ExpiryTime.Sub(now) < 59*time.Second {
+		t.Errorf("active bans lost their expiry, %+v", active)
+	}
+}
+
+func TestGetActiveBansOn(t *testing.T) {
+	db := openTestDatabase(t)
+	for _, ban := range []interfaces.BanInformation{
+		{UserID: 2, Reason: "account", BannerID: 1},
+		{UserID: 3, Reason: "other account", BannerID: 1},
+		{IPRange: "192.0.2.0/24", Reason: "range", BannerID: 1},
+		{IPRange: "2001:db8::/32", Reason: "ipv6", BannerID: 1},
+		{IPRange: "198.51.100.7/32", Reason: "expired", BannerID: 1, ExpiryTime: time.Now().Add(-time.Minute)},
+	} {
+		if _, err := db.NewBan(ban); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		UserID   uint64
+		IP       string
+		Expected string
+	}{
+		{2, "", "account"},
+		{0, "192.0.2.55", "range"},
+		{2, "192.0.2.55", "range account"},
+		{0, "2001:db8::1", "ipv6"},
+		{0, "198.51.100.7", ""},
+		{0, "203.0.113.1", ""},
+		{0, "not an address", ""},
+	}
+	for _, test := range tests {
+		active, err := db.GetActiveBansOn(test.UserID, test.IP)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var reasons []string
+		for _, ban := range active {
+			reasons = append(reasons, ban.Reason)
+		}
+		if strings.Join(reasons, " ") != test.Expected {
+			t.Errorf("bans on %d at %q are %v, expected %q", test.UserID, test.IP, reasons, test.Expected)
+		}
+	}
+}
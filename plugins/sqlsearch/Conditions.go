@@ -0,0 +1,102 @@
+package sqlsearch
+
+import (
+	"errors"
+	"go-image-board/interfaces"
+	"go-image-board/plugins/tagquery"
+)
+
+//getTimeRangeCondition returns a condition comparing Column against a date metatag's range, and the arguments it needs
+func (searcher *Searcher) getTimeRangeCondition(Column string, comparator string, Range interfaces.TimeRange) (string, []interface{}, error) {
+	start, startValue := searcher.Dialect.TimeValue(Range.Start)
+	end, endValue := searcher.Dialect.TimeValue(Range.End)
+	switch comparator {
+	case "=":
+		return "(" + Column + " >= " + start + " AND " + Column + " < " + end + ") ", []interface{}{startValue, endValue}, nil
+	case "!=":
+		return "(" + Column + " < " + start + " OR " + Column + " >= " + end + ") ", []interface{}{startValue, endValue}, nil
+	case ">":
+		return Column + " >= " + end + " ", []interface{}{endValue}, nil
+	case ">=":
+		return Column + " >= " + start + " ", []interface{}{startValue}, nil
+	case "<":
+		return Column + " < " + start + " ", []interface{}{startValue}, nil
+	case "<=":
+		return Column + " < " + end + " ", []interface{}{endValue}, nil
+	}
+	return "", nil, errors.New("Unsupported comparator for dates " + comparator)
+}
+
+//getRatioCondition returns a condition comparing the width over the height of images against Ratio, ratios within tagquery.RatioTolerance of it are equal
+//Images without dimensions have no ratio, so never match
+func getRatioCondition(comparator string, Ratio float64) (string, []interface{}, error) {
+	ratioColumn := "Images.Width * 1.0 / Images.Height"
+	switch comparator {
+	case "=":
+		return "(Images.Height > 0 AND ABS(" + ratioColumn + " - ?) < ?) ", []interface{}{Ratio, tagquery.RatioTolerance}, nil
+	case "!=":
+		return "(Images.Height > 0 AND ABS(" + ratioColumn + " - ?) >= ?) ", []interface{}{Ratio, tagquery.RatioTolerance}, nil
+	case ">":
+		return "(Images.Height > 0 AND " + ratioColumn + " >= ?) ", []interface{}{Ratio + tagquery.RatioTolerance}, nil
+	case ">=":
+		return "(Images.Height > 0 AND " + ratioColumn + " > ?) ", []interface{}{Ratio - tagquery.RatioTolerance}, nil
+	case "<":
+		return "(Images.Height > 0 AND " + ratioColumn + " <= ?) ", []interface{}{Ratio - tagquery.RatioTolerance}, nil
+	case "<=":
+		return "(Images.Height > 0 AND " + ratioColumn + " < ?) ", []interface{}{Ratio + tagquery.RatioTolerance}, nil
+	}
+	return "", nil, errors.New("Unsupported comparator for ratios " + comparator)
+}
+
+//inverts a tags comparator
+func getInvertedComparator(comparator string) string {
+	if comparator == "=" {
+		return "!="
+	}
+	if comparator == ">" {
+		return "<="
+	}
+	if comparator == "<" {
+		return ">="
+	}
+	if comparator == ">=" {
+		return "<"
+	}
+	if comparator == "<=" {
+		return ">"
+	}
+	if comparator == "LIKE" {
+		return "NOT LIKE"
+	}
+	return ""
+}
+
+//sliceContains is a helper function that returns whether a slice contains a specifc string
+func sliceContains(slice []string, item string) bool {
+	for _, sliceItem := range slice {
+		if sliceItem == item {
+			return true
+		}
+	}
+	return false
+}
+
+//tagsContainID is a helper function to check if a TagInformation slice contains a specified ID
+func tagsContainID(ID uint64, Tags []interfaces.TagInformation) bool {
+	for _, Tag := range Tags {
+		if Tag.ID == ID {
+			return true
+		}
+	}
+	return false
+}
+
+//tagsContainName is a helper function to check if a TagInformation slice contains a specified Name
+func tagsContainName(Name string, Tags []interfaces.TagInformation) bool {
+	for _, Tag := range Tags {
+		if Tag.Name == Name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+package sqlsearch
+
+import (
+	"strconv"
+	"time"
+)
+
+//Dialect describes the database specific parts of searching
+type Dialect struct {
+	//Name is the name of the plugin, used as the source of log entries
+	Name string
+	//Comparison returns a comparison against a bound value, to follow a column. LIKE and NOT LIKE match case insensitively, with \ escaping wildcards
+	Comparison func(Comparator string) string
+	//HammingDistance returns an expression counting the bits that differ between Column and Hash
+	HammingDistance func(Column string, Hash uint64) string
+	//TimeValue returns the placeholder a time is compared through, and the value to bind to it
+	TimeValue func(Value time.Time) (string, interface{})
+}
+
+//MariaDB dialect, LIKE is case insensitive with the default collation and escapes with \
+var MariaDB = Dialect{
+	Name: "MariaDBPlugin",
+	Comparison: func(Comparator string) string {
+		return Comparator + " ? "
+	},
+	HammingDistance: func(Column string, Hash uint64) string {
+		return "BIT_COUNT(" + Column + " ^ " + strconv.FormatUint(Hash, 10) + ")"
+	},
+	TimeValue: bindTime,
+}
+
+//Postgres dialect, LIKE is case sensitive so ILIKE is used instead
+var Postgres = Dialect{
+	Name: "PostgresPlugin",
+	Comparison: func(Comparator string) string {
+		if Comparator == "LIKE" {
+			return "ILIKE ? "
+		}
+		if Comparator == "NOT LIKE" {
+			return "NOT ILIKE ? "
+		}
+		return Comparator + " ? "
+	},
+	HammingDistance: hammingDistanceFunction,
+	TimeValue:       bindTime,
+}
+
+//SQLite dialect, LIKE has no default escape character, and CURRENT_TIMESTAMP is stored as UTC text so times are compared in the same format
+var SQLite = Dialect{
+	Name: "SQLitePlugin",
+	Comparison: func(Comparator string) string {
+		if Comparator == "LIKE" || Comparator == "NOT LIKE" {
+			return Comparator + " ? ESCAPE '\\' "
+		}
+		return Comparator + " ? "
+	},
+	HammingDistance: hammingDistanceFunction,
+	TimeValue: func(Value time.Time) (string, interface{}) {
+		return "datetime(?)", Value.UTC().Format("2006-01-02 15:04:05")
+	},
+}
+
+//bindTime binds a time as is, for drivers that convert it themselves
+func bindTime(Value time.Time) (string, interface{}) {
+	return "?", Value
+}
+
+//hammingDistanceFunction calls the HAMMING_DISTANCE function the plugin provides, for databases without BIT_COUNT, with Hash signed as it is stored
+func hammingDistanceFunction(Column string, Hash uint64) string {
+	return "HAMMING_DISTANCE(" + Column + ", " + strconv.FormatInt(int64(Hash), 10) + ")"
+}
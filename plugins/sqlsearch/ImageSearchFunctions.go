@@ -0,0 +1,588 @@
+package sqlsearch
+
+import (
+	"database/sql"
+	"errors"
+	"go-image-board/interfaces"
+	"go-image-board/logging"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+//SearchImages performs a search for images (Returns a list of ImageInformations a result count and an error/nil)
+//If you edit this function, consider SearchCollections and GetPrevNexImages for a similar change
+func (searcher *Searcher) SearchImages(Tags []interfaces.TagInformation, PageStart uint64, PageStride uint64) ([]interfaces.ImageInformation, uint64, error) {
+	//Cleanup input for use in code below
+	//Specifically we separate the include, the exclude and metatags into their own lists
+	var IncludeTags []uint64
+	var ExcludeTags []uint64
+	var MetaTags []interfaces.TagInformation
+	var Order interfaces.ImageOrder
+	for _, tag := range Tags {
+		if tag.Exists && tag.IsAlias == false && tag.IsMeta == false {
+			if tag.Exclude {
+				ExcludeTags = append(ExcludeTags, tag.ID)
+			} else {
+				IncludeTags = append(IncludeTags, tag.ID)
+			}
+		} else if orderValue, isOrder := tag.MetaValue.(interfaces.ImageOrder); tag.Exists && isOrder {
+			//Only the first order is used, so a user's query wins over their filter
+			if Order.Key == "" {
+				Order = orderValue
+			}
+		} else if tag.Exists && tag.IsMeta {
+			MetaTags = append(MetaTags, tag)
+		}
+	}
+
+	//Initialize output
+	var ToReturn []interfaces.ImageInformation
+	var MaxResults uint64
+
+	//Construct SQL Query
+
+	//This is the start of the query we want
+	sqlQuery := `SELECT ID, Name, Location, MIMEType `
+	sqlCountQuery := `SELECT COUNT(*) `
+	if len(IncludeTags) == 0 {
+		sqlQuery = sqlQuery + `FROM Images `
+		sqlCountQuery = sqlCountQuery + `FROM Images `
+	} else {
+		sqlQuery = sqlQuery + `FROM (
+			SELECT ImageID as ID, Name, Location, MIMEType, COUNT(*) as MatchingTags
+			FROM ImageTags 
+			INNER JOIN Images ON ImageTags.ImageID=Images.ID `
+		sqlCountQuery = sqlCountQuery + `FROM ( 
+			SELECT ImageID as ID, Name, Location, MIMEType, COUNT(*) as MatchingTags
+			FROM ImageTags 
+			INNER JOIN Images ON ImageTags.ImageID=Images.ID `
+	}
+
+	//Now for the variable piece
+	//Items in the trash or waiting for approval are never found
+	sqlWhereClause := "WHERE Images.DeletedTime IS NULL AND Images.Pending = FALSE "
+	if len(IncludeTags) > 0 {
+		sqlWhereClause = sqlWhereClause + "AND TagID IN (?" + strings.Repeat(",?", len(IncludeTags)-1) + ") "
+	}
+	if len(ExcludeTags) > 0 {
+		sqlWhereClause += "AND Images.ID NOT IN (SELECT DISTINCT ImageID FROM ImageTags WHERE TagID IN (?" + strings.Repeat(",?", len(ExcludeTags)-1) + ")) "
+	}
+
+	//And add any metatags
+	metaQueryArray := []interface{}{}
+	for _, tag := range MetaTags {
+		metaTagQuery, metaTagArguments, err := searcher.getMetaTagCondition(tag)
+		if err != nil {
+			return ToReturn, 0, err
+		}
+		sqlWhereClause = sqlWhereClause + "AND " + metaTagQuery
+		metaQueryArray = append(metaQueryArray, metaTagArguments...)
+	}
+
+	if len(IncludeTags) > 0 {
+		sqlQuery = sqlQuery + sqlWhereClause + `GROUP BY ImageID, Name, Location, MIMEType) InnerStatement WHERE MatchingTags = ? `
+		sqlCountQuery = sqlCountQuery + sqlWhereClause + `GROUP BY ImageID, Name, Location, MIMEType) InnerStatement WHERE MatchingTags = ? `
+	} else {
+		sqlQuery = sqlQuery + sqlWhereClause
+		sqlCountQuery = sqlCountQuery + sqlWhereClause
+	}
+
+	//Add Order
+	idColumn := "Images.ID"
+	if len(IncludeTags) > 0 {
+		idColumn = "InnerStatement.ID"
+	}
+	sqlQuery = sqlQuery + "ORDER BY " + getImageOrderClause(Order, idColumn, false) + "LIMIT ? OFFSET ?;"
+
+	//Now construct arguments list. Order must follow query order
+	/*
+		Inclusive Tags
+		Exclusive Tags
+		Inclusive Tag Count
+		<However we pause here to run count query, as that one does not have limits>
+		Max Amount of results to return (Stride)
+		Offset (Start)
+	*/
+	queryArray := []interface{}{}
+	//Add inclusive tags to our queryArray
+	for _, tag := range IncludeTags {
+		queryArray = append(queryArray, tag)
+	}
+	//Add the exclusive tags
+	for _, tag := range ExcludeTags {
+		queryArray = append(queryArray, tag)
+	}
+	//Add values for metatags
+	queryArray = append(queryArray, metaQueryArray...)
+
+	//Add inclusive tag count, but only if we have any
+	if len(IncludeTags) > 0 {
+		queryArray = append(queryArray, len(IncludeTags))
+	}
+
+	//Run the count query (Count query does not use start/stride, so run this before we add those)
+	err := searcher.DB.QueryRow(sqlCountQuery, queryArray...).Scan(&MaxResults)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, searcher.Dialect.Name+"/SearchImages", "0", logging.ResultFailure, []string{"Error running search query", sqlCountQuery, err.Error()})
+		return nil, 0, err
+	}
+
+	//Add rest of arguments now that we have max result count
+	queryArray = append(queryArray, PageStride)
+	queryArray = append(queryArray, PageStart)
+
+	//Now we have query and args, run the query
+	rows, err := searcher.DB.Query(sqlQuery, queryArray...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+	//Placeholders for data returned by each row
+	var ImageID uint64
+	var Name string
+	var Location string
+	var MIMEType string
+	//For each row
+	for rows.Next() {
+		//Parse out the data
+		err := rows.Scan(&ImageID, &Name, &Location, &MIMEType)
+		if err != nil {
+			return nil, 0, err
+		}
+		//Add this result to ToReturn
+		ToReturn = append(ToReturn, interfaces.ImageInformation{Name: Name, ID: ImageID, Location: Location, MIMEType: MIMEType})
+	}
+	return ToReturn, MaxResults, nil
+}
+
+//GetPrevNexImages performs a search for images (Returns a list of ImageInformations (Up to 2) and an error/nil)
+func (searcher *Searcher) GetPrevNexImages(Tags []interfaces.TagInformation, TargetID uint64) ([]interfaces.ImageInformation, error) {
+	if TargetID == 0 {
+		return nil, errors.New("invalid targetid")
+	}
+
+	var ToReturn []interfaces.ImageInformation
+
+	if len(Tags) > 0 {
+		if imageInfo, err := searcher.getPrevNexImage(Tags, TargetID, true); err == nil {
+			ToReturn = append(ToReturn, imageInfo)
+		} else if err != sql.ErrNoRows {
+			return ToReturn, err
+		}
+
+		if imageInfo, err := searcher.getPrevNexImage(Tags, TargetID, false); err == nil {
+			ToReturn = append(ToReturn, imageInfo)
+		} else if err != sql.ErrNoRows {
+			return ToReturn, err
+		}
+	} else {
+		if imageInfo, err := searcher.getPrevNextImageWithoutTags(TargetID, true); err == nil {
+			ToReturn = append(ToReturn, imageInfo)
+		} else if err != sql.ErrNoRows {
+			return ToReturn, err
+		}
+
+		if imageInfo, err := searcher.getPrevNextImageWithoutTags(TargetID, false); err == nil {
+			ToReturn = append(ToReturn, imageInfo)
+		} else if err != sql.ErrNoRows {
+			return ToReturn, err
+		}
+	}
+
+	return ToReturn, nil
+}
+
+//GetPrevNexImages performs a search for images (Returns a ImageInformation and an error/nil)
+func (searcher *Searcher) getPrevNexImage(Tags []interfaces.TagInformation, TargetID uint64, Next bool) (interfaces.ImageInformation, error) {
+	//Cleanup input for use in code below
+	//Specifically we separate the include, the exclude and metatags into their own lists
+	var IncludeTags []uint64
+	var ExcludeTags []uint64
+	var MetaTags []interfaces.TagInformation
+	var Order interfaces.ImageOrder
+	for _, tag := range Tags {
+		if tag.Exists && tag.IsAlias == false && tag.IsMeta == false {
+			if tag.Exclude {
+				ExcludeTags = append(ExcludeTags, tag.ID)
+			} else {
+				IncludeTags = append(IncludeTags, tag.ID)
+			}
+		} else if orderValue, isOrder := tag.MetaValue.(interfaces.ImageOrder); tag.Exists && isOrder {
+			//Only the first order is used, so a user's query wins over their filter
+			if Order.Key == "" {
+				Order = orderValue
+			}
+		} else if tag.Exists && tag.IsMeta {
+			MetaTags = append(MetaTags, tag)
+		}
+	}
+
+	//Initialize output
+	var ToReturn interfaces.ImageInformation
+	//var MaxResults uint64
+
+	//Construct SQL Query
+
+	//This is the start of the query we want
+	sqlQuery := `SELECT ID, Name, Location `
+
+	if len(IncludeTags) == 0 {
+		sqlQuery = sqlQuery + `FROM Images `
+	} else {
+		sqlQuery = sqlQuery + `FROM (
+			SELECT ImageID as ID, Name, Location, COUNT(*) as MatchingTags
+			FROM ImageTags 
+			INNER JOIN Images ON ImageTags.ImageID=Images.ID `
+	}
+
+	//Now for the variable piece
+	//Items in the trash or waiting for approval are never found
+	sqlWhereClause := "WHERE Images.DeletedTime IS NULL AND Images.Pending = FALSE "
+	if len(IncludeTags) > 0 {
+		sqlWhereClause = sqlWhereClause + "AND TagID IN (?" + strings.Repeat(",?", len(IncludeTags)-1) + ") "
+	}
+	if len(ExcludeTags) > 0 {
+		sqlWhereClause += "AND Images.ID NOT IN (SELECT DISTINCT ImageID FROM ImageTags WHERE TagID IN (?" + strings.Repeat(",?", len(ExcludeTags)-1) + ")) "
+	}
+
+	//And add any metatags
+	metaQueryArray := []interface{}{}
+	for _, tag := range MetaTags {
+		metaTagQuery, metaTagArguments, err := searcher.getMetaTagCondition(tag)
+		if err != nil {
+			return ToReturn, err
+		}
+		sqlWhereClause = sqlWhereClause + "AND " + metaTagQuery
+		metaQueryArray = append(metaQueryArray, metaTagArguments...)
+	}
+
+	//Add changes for next/prev, next is the image before the target in the search order
+	orderCondition, orderArguments := getImageOrderCondition(Order, TargetID, Next)
+	sqlWhereClause += "AND " + orderCondition
+
+	if len(IncludeTags) > 0 {
+		sqlQuery = sqlQuery + sqlWhereClause + `GROUP BY ImageID, Name, Location) InnerStatement WHERE MatchingTags = ? `
+	} else {
+		sqlQuery = sqlQuery + sqlWhereClause
+	}
+
+	//Add Order
+	idColumn := "Images.ID"
+	if len(IncludeTags) > 0 {
+		idColumn = "InnerStatement.ID"
+	}
+	sqlQuery = sqlQuery + "ORDER BY " + getImageOrderClause(Order, idColumn, Next) + "LIMIT 1;"
+
+	//Now construct arguments list. Order must follow query order
+	/*
+		Inclusive Tags
+		Exclusive Tags
+		Inclusive Tag Count
+		<However we pause here to run count query, as that one does not have limits>
+		Max Amount of results to return (Stride)
+		Offset (Start)
+	*/
+	queryArray := []interface{}{}
+	//Add inclusive tags to our queryArray
+	for _, tag := range IncludeTags {
+		queryArray = append(queryArray, tag)
+	}
+	//Add the exclusive tags
+	for _, tag := range ExcludeTags {
+		queryArray = append(queryArray, tag)
+	}
+	//Add values for metatags
+	queryArray = append(queryArray, metaQueryArray...)
+
+	//Add arguments for the target's place in the order
+	queryArray = append(queryArray, orderArguments...)
+
+	//Add inclusive tag count, but only if we have any
+	if len(IncludeTags) > 0 {
+		queryArray = append(queryArray, len(IncludeTags))
+	}
+
+	//Run the count query (Count query does not use start/stride, so run this before we add those)
+	/*err := searcher.DB.QueryRow(sqlCountQuery, queryArray...).Scan(&MaxResults) //Uneeded
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError,searcher.Dialect.Name+"/SearchImages", "0", logging.ResultFailure, []string{"Error running search query", sqlCountQuery, err.Error()})
+		return nil, 0, err
+	}*/
+
+	/*Add rest of arguments now that we have max result count
+	queryArray = append(queryArray, PageStride)
+	queryArray = append(queryArray, PageStart)*/
+
+	//Placeholders for data returned by each row
+	var ImageID uint64
+	var Name string
+	var Location string
+
+	//Now we have query and args, run the query
+	err := searcher.DB.QueryRow(sqlQuery, queryArray...).Scan(&ImageID, &Name, &Location)
+	if err != nil {
+		return ToReturn, err
+	}
+	ToReturn = interfaces.ImageInformation{Name: Name, ID: ImageID, Location: Location}
+
+	return ToReturn, nil
+}
+
+//getMetaTagCondition returns the condition for a single metatag, including groups, and the arguments it needs
+func (searcher *Searcher) getMetaTagCondition(tag interfaces.TagInformation) (string, []interface{}, error) {
+	if tag.IsGroup {
+		return searcher.getTagGroupCondition(tag)
+	}
+	if tag.Name == "Order" {
+		return "", nil, errors.New("order can not be used inside a group")
+	}
+
+	metaTagQuery := ""
+
+	//Handle Comparator transforms
+	comparator := tag.Comparator
+	if tag.Exclude {
+		comparator = getInvertedComparator(comparator)
+	}
+	if comparator == "" {
+		return "", nil, errors.New("Failed to invert query to negate on " + tag.Name)
+	}
+
+	//Handle Complex Tags Here
+	if tag.Name == "InCollection" { //Special Exception for InCollection
+		tagBoolValue, isTagValued := tag.MetaValue.(bool)
+		if isTagValued == false {
+			return "", nil, errors.New("Failed get value of " + tag.Name)
+		}
+		if (comparator == "=" && tagBoolValue == true) || (comparator == "!=" && tagBoolValue == false) {
+			comparator = " IN "
+		} else {
+			comparator = " NOT IN "
+		}
+		metaTagQuery += "Images.ID" + comparator + "(SELECT DISTINCT ImageID FROM CollectionMembers) "
+		return metaTagQuery, nil, nil
+	} else if tag.Name == "HasNotes" { //Special Exception for HasNotes
+		tagBoolValue, isTagValued := tag.MetaValue.(bool)
+		if isTagValued == false {
+			return "", nil, errors.New("Failed get value of " + tag.Name)
+		}
+		if (comparator == "=" && tagBoolValue == true) || (comparator == "!=" && tagBoolValue == false) {
+			comparator = " IN "
+		} else {
+			comparator = " NOT IN "
+		}
+		metaTagQuery += "Images.ID" + comparator + "(SELECT DISTINCT ImageID FROM Notes WHERE Notes.DeletedTime IS NULL) "
+		return metaTagQuery, nil, nil
+	} else if tag.Name == "TagCount" { //Special Exception for TagCount
+		tagStringValue, isTagValued := tag.MetaValue.(string)
+		if isTagValued == false {
+			return "", nil, errors.New("Failed get value of " + tag.Name)
+		}
+		metaTagQuery += "Images.ID IN (SELECT ImageID FROM (SELECT ImageID, COUNT(*) AS TagCount FROM ImageTags GROUP BY ImageID) TagCountTBL WHERE TagCountTBL.TagCount " + comparator + " " + tagStringValue + ") "
+		return metaTagQuery, nil, nil
+	} else if tag.Name == "CategoryTags" { //Special Exception for CategoryTags
+		tagCategoryValue, isTagValued := tag.MetaValue.(interfaces.TagCategoryCount)
+		if isTagValued == false || interfaces.IsTagCategory(tagCategoryValue.Category) == false {
+			return "", nil, errors.New("Failed get value of " + tag.Name)
+		}
+		//Category is checked against the known categories above, so is safe to place in the query
+		metaTagQuery += "(SELECT COUNT(*) FROM ImageTags AS CategoryImageTags INNER JOIN Tags ON Tags.ID = CategoryImageTags.TagID WHERE CategoryImageTags.ImageID = Images.ID AND Tags.Category = '" + tagCategoryValue.Category + "') " + comparator + " " + strconv.FormatInt(tagCategoryValue.Count, 10) + " "
+		return metaTagQuery, nil, nil
+	} else if tag.Name == "Similar" { //Special Exception for TagCount
+		tagImagedHashValue, isTagValued := tag.MetaValue.(interfaces.ImagedHash)
+		if isTagValued == false {
+			return "", nil, errors.New("Failed get value of " + tag.Name)
+		}
+		metaTagQuery += "Images.ID IN (SELECT ImageID FROM ImagedHashes WHERE (" + searcher.Dialect.HammingDistance("hHash", tagImagedHashValue.ImagehHash) + "+" + searcher.Dialect.HammingDistance("vHash", tagImagedHashValue.ImagevHash) + ") " + comparator + " " + strconv.FormatUint(tagImagedHashValue.SimilarityThreshold, 10) + ") "
+		return metaTagQuery, nil, nil
+	} else if tag.Name == "Uploaded" || tag.Name == "Tagged" || tag.Name == "Voted" { //Special Exception for dates
+		tagTimeValue, isTagValued := tag.MetaValue.(interfaces.TimeRange)
+		if isTagValued == false {
+			return "", nil, errors.New("Failed get value of " + tag.Name)
+		}
+		if tag.Name == "Uploaded" {
+			return searcher.getTimeRangeCondition("Images.UploadTime", comparator, tagTimeValue)
+		}
+		//Any tag or vote in the range matches, so excluding finds images without one, rather than inverting the comparator
+		timeQuery, timeArguments, err := searcher.getTimeRangeCondition("ImageTags.LinkTime", tag.Comparator, tagTimeValue)
+		if tag.Name == "Voted" {
+			timeQuery, timeArguments, err = searcher.getTimeRangeCondition("ImageUserScores.CreationTime", tag.Comparator, tagTimeValue)
+			timeQuery = "(SELECT ImageID FROM ImageUserScores WHERE " + timeQuery + ") "
+		} else {
+			timeQuery = "(SELECT ImageID FROM ImageTags WHERE " + timeQuery + ") "
+		}
+		if tag.Exclude {
+			return "Images.ID NOT IN " + timeQuery, timeArguments, err
+		}
+		return "Images.ID IN " + timeQuery, timeArguments, err
+	} else if tag.Name == "Favorite" { //Special Exception for Favorite
+		tagUserValue, isTagValued := tag.MetaValue.(uint64)
+		if isTagValued == false {
+			return "", nil, errors.New("Failed get value of " + tag.Name)
+		}
+		if comparator == "=" {
+			return "Images.ID IN (SELECT ImageID FROM ImageUserFavorites WHERE UserID = ?) ", []interface{}{tagUserValue}, nil
+		}
+		return "Images.ID NOT IN (SELECT ImageID FROM ImageUserFavorites WHERE UserID = ?) ", []interface{}{tagUserValue}, nil
+	} else if tag.Name == "FavoriteCount" { //Special Exception for FavoriteCount
+		return "(SELECT COUNT(*) FROM ImageUserFavorites WHERE ImageUserFavorites.ImageID = Images.ID) " + comparator + " ? ", []interface{}{tag.MetaValue}, nil
+	} else if tag.Name == "CommentCount" { //Special Exception for CommentCount
+		return "(SELECT COUNT(*) FROM Comments WHERE Comments.ImageID = Images.ID AND Comments.DeletedTime IS NULL) " + comparator + " ? ", []interface{}{tag.MetaValue}, nil
+	} else if tag.Name == "Ratio" { //Special Exception for Ratio
+		tagRatioValue, isTagValued := tag.MetaValue.(float64)
+		if isTagValued == false {
+			return "", nil, errors.New("Failed get value of " + tag.Name)
+		}
+		return getRatioCondition(comparator, tagRatioValue)
+	}
+
+	metaTagQuery = metaTagQuery + "Images." + tag.Name + " "
+	metaTagQuery = metaTagQuery + searcher.Dialect.Comparison(comparator)
+	return metaTagQuery, []interface{}{tag.MetaValue}, nil
+}
+
+//getTagGroupCondition returns a condition matching images that satisfy a group of tags, and the arguments it needs
+func (searcher *Searcher) getTagGroupCondition(Group interfaces.TagInformation) (string, []interface{}, error) {
+	var conditions []string
+	queryArray := []interface{}{}
+	for _, tag := range Group.GroupTags {
+		if tag.IsMeta {
+			condition, arguments, err := searcher.getMetaTagCondition(tag)
+			if err != nil {
+				return "", nil, err
+			}
+			conditions = append(conditions, condition)
+			queryArray = append(queryArray, arguments...)
+			continue
+		}
+		comparator := "IN"
+		if tag.Exclude {
+			comparator = "NOT IN"
+		}
+		conditions = append(conditions, "Images.ID "+comparator+" (SELECT ImageID FROM ImageTags WHERE TagID = ?) ")
+		queryArray = append(queryArray, tag.ID)
+	}
+	joiner := "AND "
+	if Group.MatchAny {
+		joiner = "OR "
+	}
+	condition := "(" + strings.Join(conditions, joiner) + ") "
+	if Group.Exclude {
+		condition = "NOT " + condition
+	}
+	return condition, queryArray, nil
+}
+
+//getImageOrderExpression returns the value images are sorted by for Order, for the image whose ID is in IDColumn
+func getImageOrderExpression(Order interfaces.ImageOrder, IDColumn string) string {
+	switch Order.Key {
+	case interfaces.ImageOrderScore:
+		return "(SELECT ScoreAverage FROM Images AS OrderImages WHERE OrderImages.ID = " + IDColumn + ")"
+	case interfaces.ImageOrderTotalScore:
+		return "(SELECT ScoreTotal FROM Images AS OrderImages WHERE OrderImages.ID = " + IDColumn + ")"
+	case interfaces.ImageOrderScoreVoters:
+		return "(SELECT ScoreVoters FROM Images AS OrderImages WHERE OrderImages.ID = " + IDColumn + ")"
+	case interfaces.ImageOrderUploaded:
+		return "(SELECT UploadTime FROM Images AS OrderImages WHERE OrderImages.ID = " + IDColumn + ")"
+	case interfaces.ImageOrderTagCount:
+		return "(SELECT COUNT(*) FROM ImageTags AS OrderImageTags WHERE OrderImageTags.ImageID = " + IDColumn + ")"
+	case interfaces.ImageOrderRandom:
+		//Must match tagquery.RandomOrderValue. Seed is a number, so is safe to place in the query
+		return "(((" + IDColumn + " + " + strconv.FormatInt(Order.Seed, 10) + ") * 2654435761) % 4294967291)"
+	}
+	return IDColumn
+}
+
+//getImageOrderClause returns the ORDER BY clause for Order, ties are broken by ID. Reverse flips the direction
+func getImageOrderClause(Order interfaces.ImageOrder, IDColumn string, Reverse bool) string {
+	direction := "DESC"
+	if Order.Ascending != Reverse {
+		direction = "ASC"
+	}
+	if Order.Key == "" || Order.Key == interfaces.ImageOrderID {
+		return IDColumn + " " + direction + " "
+	}
+	return getImageOrderExpression(Order, IDColumn) + " " + direction + ", " + IDColumn + " " + direction + " "
+}
+
+//getImageOrderCondition returns a condition matching images after TargetID in Order, or before it when Before is set, and the arguments it needs
+func getImageOrderCondition(Order interfaces.ImageOrder, TargetID uint64, Before bool) (string, []interface{}) {
+	comparator := "<"
+	if Order.Ascending != Before {
+		comparator = ">"
+	}
+	if Order.Key == "" || Order.Key == interfaces.ImageOrderID {
+		return "Images.ID " + comparator + " ? ", []interface{}{TargetID}
+	}
+	value := getImageOrderExpression(Order, "Images.ID")
+	targetValue := getImageOrderExpression(Order, "?")
+	return "(" + value + " " + comparator + " " + targetValue + " OR (" + value + " = " + targetValue + " AND Images.ID " + comparator + " ?)) ", []interface{}{TargetID, TargetID, TargetID}
+}
+
+//GetRandomImage returns a random image (Returns a ImageInformation and an error/nil)
+func (searcher *Searcher) GetRandomImage(Tags []interfaces.TagInformation) (interfaces.ImageInformation, uint64, error) {
+	imageInfo, resultCount, err := searcher.SearchImages(Tags, 0, 1)
+
+	if err == nil {
+		if resultCount <= 0 {
+			return interfaces.ImageInformation{}, 0, errors.New("no images found with provided tags")
+		}
+		if resultCount == 1 {
+			return imageInfo[0], resultCount, nil //Shortcut for one result
+		}
+
+		rando := rand.Float64()
+		randoID := uint64(rando * float64(resultCount))
+		imageInfo, _, err = searcher.SearchImages(Tags, randoID, 1)
+		if err == nil {
+			return imageInfo[0], resultCount, nil
+		}
+		return interfaces.ImageInformation{}, resultCount, err
+	}
+	return interfaces.ImageInformation{}, resultCount, err
+}
+
+//getPrevNextImageWithoutTags performs a search for images (Returns an ImageInformation and an error/nil)
+func (searcher *Searcher) getPrevNextImageWithoutTags(TargetID uint64, Next bool) (interfaces.ImageInformation, error) {
+	//Initialize output
+	var ToReturn interfaces.ImageInformation
+	//var MaxResults uint64
+
+	//Construct SQL Query
+
+	//This is the start of the query we want
+	sqlQuery := `SELECT ID, Name, Location FROM Images `
+
+	//Add changes for next/prev
+	sqlWhereClause := "WHERE Images.DeletedTime IS NULL AND Images.Pending = FALSE AND "
+
+	if Next == false {
+		sqlWhereClause += "Images.ID < ? "
+	} else {
+		sqlWhereClause += "Images.ID > ? "
+	}
+
+	sqlQuery = sqlQuery + sqlWhereClause
+
+	//Add Order
+	order := "DESC "
+	if Next {
+		order = ""
+	}
+	sqlQuery = sqlQuery + `ORDER BY ID ` + order + `LIMIT 1;`
+
+	//Placeholders for data returned by each row
+	var ImageID uint64
+	var Name string
+	var Location string
+
+	//Now we have query and args, run the query
+	err := searcher.DB.QueryRow(sqlQuery, TargetID).Scan(&ImageID, &Name, &Location)
+	if err != nil {
+		return ToReturn, err
+	}
+	ToReturn = interfaces.ImageInformation{Name: Name, ID: ImageID, Location: Location}
+
+	return ToReturn, nil
+}
@@ -0,0 +1,93 @@
+package sqlsearch
+
+import (
+	"database/sql"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+)
+
+//Queryer runs the queries used by searches, *sql.DB satisfies it, as do wrappers that rewrite queries for their database
+type Queryer interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+//Lookup is implemented by the database plugin, for the lookups searches need that are not part of searching
+type Lookup interface {
+	GetUserID(userName string) (uint64, error)
+	GetImagedHash(ID uint64) (uint64, uint64, error)
+}
+
+//Searcher builds and runs the image, collection and tag queries shared by the SQL database plugins
+type Searcher struct {
+	//DB is the database handle of the plugin
+	DB Queryer
+	//Dialect is the SQL the database needs where the plugins differ
+	Dialect Dialect
+	//Lookup is the plugin, used to look up users and hashes named in metatags
+	Lookup Lookup
+}
+
+var regexTagName = regexp.MustCompile("[^a-zA-Z0-9_-]") //Used to cleanup tag names
+var regexWhiteSpace = regexp.MustCompile("\\s{2,}")     //Matches 2 or more consecutive whitespace
+//regexTagValue is used to cleanup metatag values, / and + are kept for ratios such as 16/9 and types such as image/svg+xml
+var regexTagValue = regexp.MustCompile("[^a-zA-Z0-9_\\-\\.\\*/+]")
+
+//PrepareTagName cleans up a tag name as entered by a user, lowercasing it and replacing whitespace and special characters with _
+//The comparator and value of a metatag are cleaned up separately from its name
+func PrepareTagName(Name string) string {
+	//Lowercase Name -> Trimmed front and end of whitespace -> any inner whitespace reduced and underscored
+	Name = regexWhiteSpace.ReplaceAllString(strings.TrimSpace(strings.ToLower(Name)), "_") //Replace all whitespace with _
+	//Case of metatag
+	if strings.Count(Name, ":") == 1 {
+		//Assume a metatag
+		NameValue := strings.Split(Name, ":")
+		value, comparator := GetTagComparator(NameValue[1]) //Strip comparator, so it does not get replaced by a _
+		Name = regexTagName.ReplaceAllString(NameValue[0], "_") + ":" + comparator + regexTagValue.ReplaceAllString(value, "_")
+	} else {
+		//Then any special characters replaced with _
+		Name = regexTagName.ReplaceAllString(Name, "_")
+	}
+	return Name
+}
+
+//nullTime scans a time that may be NULL, from drivers that return times as time.Time, or as text in UTC such as SQLite's CURRENT_TIMESTAMP and MariaDB without parseTime
+type nullTime struct {
+	Time  time.Time
+	Valid bool
+}
+
+//Scan implements sql.Scanner
+func (NT *nullTime) Scan(Value interface{}) error {
+	NT.Time, NT.Valid = time.Time{}, Value != nil
+	switch value := Value.(type) {
+	case nil:
+		return nil
+	case time.Time:
+		NT.Time = value
+		return nil
+	case []byte:
+		return NT.parse(string(value))
+	case string:
+		return NT.parse(value)
+	}
+	return errors.New("unsupported time value")
+}
+
+//parse reads a time stored as text
+func (NT *nullTime) parse(Value string) error {
+	var err error
+	for _, layout := range []string{"2006-01-02 15:04:05", time.RFC3339Nano} {
+		if NT.Time, err = time.Parse(layout, Value); err == nil {
+			return nil
+		}
+	}
+	//Zero dates, such as MariaDB's 0000-00-00 00:00:00, are left as the zero time
+	if strings.HasPrefix(Value, "0000-00-00") {
+		NT.Time, NT.Valid = time.Time{}, false
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,134 @@
+package sqlsearch
+
+import (
+	"database/sql"
+	"errors"
+	"go-image-board/interfaces"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestComparison(t *testing.T) {
+	tests := []struct {
+		Dialect    Dialect
+		Comparator string
+		Expected   string
+	}{
+		{MariaDB, "LIKE", "LIKE ? "},
+		{MariaDB, ">=", ">= ? "},
+		{Postgres, "LIKE", "ILIKE ? "},
+		{Postgres, "NOT LIKE", "NOT ILIKE ? "},
+		{Postgres, "!=", "!= ? "},
+		{SQLite, "LIKE", "LIKE ? ESCAPE '\\' "},
+		{SQLite, "NOT LIKE", "NOT LIKE ? ESCAPE '\\' "},
+		{SQLite, "=", "= ? "},
+	}
+	for _, test := range tests {
+		if result := test.Dialect.Comparison(test.Comparator); result != test.Expected {
+			t.Errorf("%s Comparison(%q) = %q, expected %q", test.Dialect.Name, test.Comparator, result, test.Expected)
+		}
+	}
+}
+
+func TestHammingDistance(t *testing.T) {
+	//Hashes with the top bit set are stored signed where there is no unsigned column type
+	var hash uint64 = 1<<63 | 5
+	tests := map[string]string{
+		MariaDB.HammingDistance("hHash", hash):  "BIT_COUNT(hHash ^ 9223372036854775813)",
+		Postgres.HammingDistance("hHash", hash): "HAMMING_DISTANCE(hHash, -9223372036854775803)",
+		SQLite.HammingDistance("hHash", hash):   "HAMMING_DISTANCE(hHash, -9223372036854775803)",
+	}
+	for result, expected := range tests {
+		if result != expected {
+			t.Errorf("HammingDistance = %q, expected %q", result, expected)
+		}
+	}
+}
+
+func TestTimeRangeCondition(t *testing.T) {
+	zone := time.FixedZone("UTC+10", 10*60*60)
+	Range := interfaces.TimeRange{Start: time.Date(2025, 3, 1, 0, 0, 0, 0, zone), End: time.Date(2025, 4, 1, 0, 0, 0, 0, zone)}
+
+	mariadb := &Searcher{Dialect: MariaDB}
+	condition, arguments, err := mariadb.getTimeRangeCondition("Images.UploadTime", "=", Range)
+	if err != nil || condition != "(Images.UploadTime >= ? AND Images.UploadTime < ?) " || reflect.DeepEqual(arguments, []interface{}{Range.Start, Range.End}) == false {
+		t.Errorf("MariaDB range is %q %v, %v", condition, arguments, err)
+	}
+
+	//SQLite compares text, so the range is written in UTC like CURRENT_TIMESTAMP
+	sqlite := &Searcher{Dialect: SQLite}
+	tests := map[string]struct {
+		Condition string
+		Arguments []interface{}
+	}{
+		"=":  {"(Images.UploadTime >= datetime(?) AND Images.UploadTime < datetime(?)) ", []interface{}{"2025-02-28 14:00:00", "2025-03-31 14:00:00"}},
+		"!=": {"(Images.UploadTime < datetime(?) OR Images.UploadTime >= datetime(?)) ", []interface{}{"2025-02-28 14:00:00", "2025-03-31 14:00:00"}},
+		">":  {"Images.UploadTime >= datetime(?) ", []interface{}{"2025-03-31 14:00:00"}},
+		">=": {"Images.UploadTime >= datetime(?) ", []interface{}{"2025-02-28 14:00:00"}},
+		"<":  {"Images.UploadTime < datetime(?) ", []interface{}{"2025-02-28 14:00:00"}},
+		"<=": {"Images.UploadTime < datetime(?) ", []interface{}{"2025-03-31 14:00:00"}},
+	}
+	for comparator, expected := range tests {
+		condition, arguments, err := sqlite.getTimeRangeCondition("Images.UploadTime", comparator, Range)
+		if err != nil || condition != expected.Condition || reflect.DeepEqual(arguments, expected.Arguments) == false {
+			t.Errorf("SQLite %s range is %q %v, %v", comparator, condition, arguments, err)
+		}
+	}
+	if _, _, err := sqlite.getTimeRangeCondition("Images.UploadTime", "LIKE", Range); err == nil {
+		t.Error("LIKE is not a date comparison")
+	}
+}
+
+func TestNullTime(t *testing.T) {
+	expected := time.Date(2025, 3, 1, 12, 30, 0, 0, time.UTC)
+	for _, value := range []interface{}{expected, []byte("2025-03-01 12:30:00"), "2025-03-01 12:30:00", "2025-03-01T12:30:00Z"} {
+		var scanned nullTime
+		if err := scanned.Scan(value); err != nil || scanned.Valid == false || scanned.Time.Equal(expected) == false {
+			t.Errorf("Scan(%v) = %+v, %v", value, scanned, err)
+		}
+	}
+	for _, value := range []interface{}{nil, []byte("0000-00-00 00:00:00")} {
+		var scanned nullTime
+		if err := scanned.Scan(value); err != nil || scanned.Valid {
+			t.Errorf("Scan(%v) = %+v, %v", value, scanned, err)
+		}
+	}
+	var scanned nullTime
+	if err := scanned.Scan("yesterday"); err == nil {
+		t.Error("text that is not a time was scanned")
+	}
+}
+
+func TestPrepareTagName(t *testing.T) {
+	tests := map[string]string{
+		"  Blue   Sky ":      "blue_sky",
+		"rating:>=safe":      "rating:>=safe",
+		"ratio:16/9":         "ratio:16/9",
+		"type:image/svg+xml": "type:image/svg+xml",
+		"a&b":                "a_b",
+	}
+	for name, expected := range tests {
+		if result := PrepareTagName(name); result != expected {
+			t.Errorf("PrepareTagName(%q) = %q, expected %q", name, result, expected)
+		}
+	}
+}
+
+//failingDB is a Queryer whose queries all fail
+type failingDB struct{}
+
+func (failingDB) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return nil, errors.New("connection lost")
+}
+
+func (failingDB) QueryRow(query string, args ...interface{}) *sql.Row {
+	return &sql.Row{}
+}
+
+func TestGetTagsInfoReturnsQueryErrors(t *testing.T) {
+	searcher := &Searcher{DB: failingDB{}, Dialect: SQLite}
+	if tags, err := searcher.getTagsInfo([]string{"blue_sky"}, false, false); err == nil {
+		t.Errorf("failed query returned %+v", tags)
+	}
+}
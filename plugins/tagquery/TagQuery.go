@@ -0,0 +1,512 @@
+package tagquery
+
+import (
+	"errors"
+	"go-image-board/interfaces"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var regexRelativeTime = regexp.MustCompile("^([0-9]+)(h|d|w|mo|y)$") //Used to parse ages such as 7d in date metatags
+
+var regexPattern = regexp.MustCompile("[^a-z0-9_\\-\\*]") //Used to cleanup wildcard patterns the same way as tag names
+
+var regexFileSize = regexp.MustCompile("^([0-9]+(?:\\.[0-9]+)?)(b|kb|mb|gb)?$") //Used to parse sizes such as 1.5mb in filesize metatags
+
+var regexMIMEType = regexp.MustCompile("^[a-z0-9.+\\-]+(/[a-z0-9.+\\-]+)?$") //Used to validate MIME types and media types such as video in type metatags
+
+//RatioTolerance is how far apart two aspect ratios may be and still be equal, so ratio:16x9 finds both 1920x1080 and 1366x768
+const RatioTolerance = 0.01
+
+//Term is a single tag or metatag from a search query, or a group of terms
+type Term struct {
+	//Name of the tag or metatag as the user typed it, quotes removed and inner spaces replaced by _. Empty for groups
+	Name string
+	//Exclude is true when the term was negated with -
+	Exclude bool
+	//IsGroup is true when this term holds other terms rather than a name
+	IsGroup bool
+	//MatchAny is true when only one of Terms must match, an OR group, rather than all of them
+	MatchAny bool
+	//Terms are the members of a group
+	Terms []Term
+}
+
+//String returns the term written back as a query
+func (T Term) String() string {
+	ToReturn := ""
+	if T.Exclude {
+		ToReturn = "-"
+	}
+	if T.IsGroup == false {
+		return ToReturn + T.Name
+	}
+	separator := " "
+	if T.MatchAny {
+		separator = " OR "
+	}
+	var members []string
+	for _, member := range T.Terms {
+		members = append(members, member.String())
+	}
+	return ToReturn + "(" + strings.Join(members, separator) + ")"
+}
+
+//Tokens produced by tokenize, anything else is a term
+const (
+	tokenOpen        = "("
+	tokenExcludeOpen = "-("
+	tokenClose       = ")"
+	tokenOr          = "OR"
+)
+
+//token is either one of the token constants, or a term when Term is set
+type token struct {
+	Operator string
+	Term     *Term
+}
+
+//Parse splits a query into the terms that must all match
+//
+//Terms are separated by spaces, quotes join several words into one tag, and - excludes a term.
+//Terms joined by OR, or prefixed with ~, match when any one of them does. OR binds tighter than the spaces between terms, so "a b OR c" is a AND (b OR c).
+//Parentheses group terms, and may be negated as a whole with -(. Unbalanced parentheses are closed at the end of the query.
+func Parse(UserQuery string) []Term {
+	tokens := tokenize(UserQuery)
+	position := 0
+	var ToReturn []Term
+	//A stray ) at the top level has nothing to close, so it is skipped
+	for position < len(tokens) {
+		ToReturn = append(ToReturn, parseSequence(tokens, &position)...)
+		position++
+	}
+	return ToReturn
+}
+
+//parseSequence reads terms up to the next unmatched ), or the end of the query, returning the terms that must all match
+func parseSequence(tokens []token, position *int) []Term {
+	var ToReturn []Term
+	for *position < len(tokens) && tokens[*position].Operator != tokenClose {
+		if tokens[*position].Operator == tokenOr {
+			//An OR with nothing before it, as in "~a ~b", is the start of the group
+			*position++
+			continue
+		}
+		members := []Term{}
+		if term, valid := parseTerm(tokens, position); valid {
+			members = append(members, term)
+		}
+		for *position < len(tokens) && tokens[*position].Operator == tokenOr {
+			*position++
+			if term, valid := parseTerm(tokens, position); valid {
+				members = append(members, term)
+			}
+		}
+		if len(members) == 1 {
+			ToReturn = appendAll(ToReturn, members[0])
+		} else if len(members) > 1 {
+			ToReturn = appendAll(ToReturn, anyOf(members))
+		}
+	}
+	return ToReturn
+}
+
+//parseTerm reads a single term or parenthesised group, valid is false if there was nothing to read, such as an empty group
+func parseTerm(tokens []token, position *int) (Term, bool) {
+	if *position >= len(tokens) {
+		return Term{}, false
+	}
+	current := tokens[*position]
+	if current.Term != nil {
+		*position++
+		return *current.Term, true
+	}
+	if current.Operator != tokenOpen && current.Operator != tokenExcludeOpen {
+		//A ) or OR where a term was expected
+		return Term{}, false
+	}
+	*position++
+	members := parseSequence(tokens, position)
+	if *position < len(tokens) {
+		//Skip the closing )
+		*position++
+	}
+	if len(members) == 0 {
+		return Term{}, false
+	}
+	ToReturn := members[0]
+	if len(members) > 1 {
+		ToReturn = Term{IsGroup: true, Terms: members}
+	}
+	if current.Operator == tokenExcludeOpen {
+		ToReturn.Exclude = ToReturn.Exclude == false
+	}
+	return ToReturn, true
+}
+
+//appendAll adds a term to a list of terms that must all match, a group that must all match is merged in instead of nested
+func appendAll(Terms []Term, ToAdd Term) []Term {
+	if ToAdd.IsGroup && ToAdd.MatchAny == false && ToAdd.Exclude == false {
+		return append(Terms, ToAdd.Terms...)
+	}
+	return append(Terms, ToAdd)
+}
+
+//anyOf returns an OR group of members, members that are themselves OR groups are merged in instead of nested
+func anyOf(Members []Term) Term {
+	ToReturn := Term{IsGroup: true, MatchAny: true}
+	for _, member := range Members {
+		if member.IsGroup && member.MatchAny && member.Exclude == false {
+			ToReturn.Terms = append(ToReturn.Terms, member.Terms...)
+		} else {
+			ToReturn.Terms = append(ToReturn.Terms, member)
+		}
+	}
+	return ToReturn
+}
+
+//tokenize splits a query into terms and grouping operators
+//The goal here it to take something like
+//-("i wrote you a song" ~audio)
+//and turn it into
+//-(, i_wrote_you_a_song, OR, audio, )
+func tokenize(UserQuery string) []token {
+	var ToReturn []token
+	InQuote := false
+	TagConstruct := ""
+	var Negate = false //User is specifically negating this tag
+	addTerm := func(Name string) {
+		if len(Name) > 0 {
+			ToReturn = append(ToReturn, token{Term: &Term{Name: Name, Exclude: Negate}})
+		}
+		Negate = false
+	}
+	for _, Tag := range strings.Fields(UserQuery) {
+		if InQuote {
+			//Parentheses only close a group after the closing quote
+			Closes := 0
+			if trimmed := strings.TrimRight(Tag, ")"); len(trimmed) > 0 && (trimmed[len(trimmed)-1:] == "\"" || trimmed[len(trimmed)-1:] == "'") {
+				Closes = len(Tag) - len(trimmed)
+				Tag = trimmed
+			}
+			//TagConsturct should already have something at this point, so add a underscore between it and the new field
+			TagConstruct = TagConstruct + "_" + Tag
+			//If we now end in a quote, then we add the tag construct as one tag
+			if TagConstruct[len(TagConstruct)-1:] == "\"" || TagConstruct[len(TagConstruct)-1:] == "'" {
+				addTerm(TagConstruct[1 : len(TagConstruct)-1]) //Cleanup end and beginning quotes
+				//Reset TagConstruct tracking
+				TagConstruct = ""
+				InQuote = false
+			}
+			for ; Closes > 0; Closes-- {
+				ToReturn = append(ToReturn, token{Operator: tokenClose})
+			}
+			continue
+		}
+
+		if Tag == tokenOr {
+			ToReturn = append(ToReturn, token{Operator: tokenOr})
+			continue
+		}
+		//Peel grouping operators off the front of the field
+		for len(Tag) > 0 {
+			if Tag[0:1] == "~" {
+				ToReturn = append(ToReturn, token{Operator: tokenOr})
+				Tag = Tag[1:]
+			} else if strings.HasPrefix(Tag, tokenExcludeOpen) {
+				ToReturn = append(ToReturn, token{Operator: tokenExcludeOpen})
+				Tag = Tag[2:]
+			} else if Tag[0:1] == tokenOpen {
+				ToReturn = append(ToReturn, token{Operator: tokenOpen})
+				Tag = Tag[1:]
+			} else {
+				break
+			}
+		}
+		//And closing parentheses off the end, tag names can not contain them
+		trimmed := strings.TrimRight(Tag, ")")
+		Closes := len(Tag) - len(trimmed)
+		Tag = trimmed
+
+		if len(Tag) > 0 && Tag[0:1] == "-" {
+			Negate = true
+			Tag = Tag[1:] //Remove the minus
+		}
+		if len(Tag) == 0 {
+			//Nothing but operators
+			Negate = false
+		} else if len(Tag) > 1 && ((Tag[0:1] == "\"" && Tag[len(Tag)-1:] == "\"") || (Tag[0:1] == "'" && Tag[len(Tag)-1:] == "'")) {
+			//Case when tag is already quoted, beggining and ending quotes stripped, then this follows the same as the basic tag
+			addTerm(Tag[1 : len(Tag)-1])
+		} else if Tag[0:1] == "\"" || Tag[0:1] == "'" {
+			//If first character of new field/tag is a "
+			//We store the tag in a temporary spot until we find the ending "
+			InQuote = true
+			TagConstruct = Tag
+			//Any parentheses were part of the quoted text
+			TagConstruct += strings.Repeat(")", Closes)
+			Closes = 0
+		} else {
+			//Default, not in quotes, not starting or ending quotes, just a simple tag or metatag.
+			addTerm(Tag)
+		}
+		for ; Closes > 0; Closes-- {
+			ToReturn = append(ToReturn, token{Operator: tokenClose})
+		}
+	}
+	//Now as a fallback, if TagConstruct has anything in it, treat it as if it ended in a quote
+	//For queries formatted like
+	//audio "i wrote you a song
+	//with this fallback will return
+	//audio, i_wrote_you_a_song
+	if len(TagConstruct) != 0 {
+		addTerm(TagConstruct[1:]) //Remove starting quote
+	}
+	return ToReturn
+}
+
+//ResolveCurrentUser replaces fav:me in a query with fav:UserName, as the plugins parse queries without knowing who is searching
+//Any -, ~ or parentheses around the term are kept. Without a user the query is returned as is, and fav:me finds nothing
+func ResolveCurrentUser(UserQuery string, UserName string) string {
+	if UserName == "" {
+		return UserQuery
+	}
+	fields := strings.Fields(UserQuery)
+	for index, field := range fields {
+		trimmed := strings.TrimLeft(field, "~-(")
+		prefix := field[:len(field)-len(trimmed)]
+		name := strings.TrimRight(trimmed, ")")
+		suffix := trimmed[len(name):]
+		if strings.EqualFold(name, "fav:me") || strings.EqualFold(name, "fav:=me") {
+			fields[index] = prefix + "fav:" + UserName + suffix
+		}
+	}
+	return strings.Join(fields, " ")
+}
+
+//GroupInfo converts a group into a TagInformation that SearchImages and SearchCollections treat as a complex metatag
+//TagsInfo looks up a single tag or metatag by name, returning the tag it aliases as well when it is an alias
+//Members that do not exist are left out, as they are from the rest of a query, and the group only exists if it has members left
+func GroupInfo(Group Term, TagsInfo func(Name string, Exclude bool) ([]interfaces.TagInformation, error)) (interfaces.TagInformation, error) {
+	ToReturn := interfaces.TagInformation{Name: Group.String(), Exclude: Group.Exclude, IsMeta: true, IsComplexMeta: true, IsGroup: true, MatchAny: Group.MatchAny}
+	for _, member := range Group.Terms {
+		if member.IsGroup {
+			memberInfo, err := GroupInfo(member, TagsInfo)
+			if err != nil {
+				return ToReturn, err
+			}
+			if memberInfo.Exists {
+				ToReturn.GroupTags = append(ToReturn.GroupTags, memberInfo)
+			}
+			continue
+		}
+		tags, err := TagsInfo(member.Name, member.Exclude)
+		if err != nil {
+			return ToReturn, err
+		}
+		//An alias is searched as the tag it aliases
+		for _, tag := range tags {
+			if tag.Exists && tag.IsAlias == false {
+				ToReturn.GroupTags = append(ToReturn.GroupTags, tag)
+				break
+			}
+		}
+	}
+	ToReturn.Exists = len(ToReturn.GroupTags) > 0
+	return ToReturn, nil
+}
+
+//IsPattern returns true if a term's name is a wildcard pattern, such as artist_* or *_hair, rather than a single tag or metatag
+func IsPattern(Name string) bool {
+	return strings.Contains(Name, "*") && strings.Contains(Name, ":") == false
+}
+
+//CleanPattern lowercases a wildcard pattern and replaces any characters a tag name can not contain with _
+func CleanPattern(Pattern string) string {
+	return regexPattern.ReplaceAllString(strings.ToLower(Pattern), "_")
+}
+
+//LikePattern converts a cleaned wildcard pattern into a LIKE pattern using \ as the escape character, as _ is common in tag names
+func LikePattern(Pattern string) string {
+	Pattern = strings.Replace(Pattern, "\\", "\\\\", -1)
+	Pattern = strings.Replace(Pattern, "%", "\\%", -1)
+	Pattern = strings.Replace(Pattern, "_", "\\_", -1)
+	return strings.Replace(Pattern, "*", "%", -1)
+}
+
+//PatternError returns the error for a pattern matching more than Limit tags
+func PatternError(Pattern string, Limit uint64) error {
+	return interfaces.QueryError{Message: "The pattern " + Pattern + " matches more than " + strconv.FormatUint(Limit, 10) + " tags, please make it more specific"}
+}
+
+//PatternInfo converts the tags matching a wildcard pattern into a group that matches images with any of them, searched as a complex metatag
+//Aliases are swapped for the tags they alias, and the group only exists if something matched
+func PatternInfo(Pattern string, Exclude bool, Matches []interfaces.TagInformation) interfaces.TagInformation {
+	ToReturn := interfaces.TagInformation{Name: Pattern, Exclude: Exclude, IsMeta: true, IsComplexMeta: true, IsGroup: true, MatchAny: true}
+	var IDs []uint64
+	for _, tag := range Matches {
+		member := interfaces.TagInformation{ID: tag.ID, Name: tag.Name, Category: tag.Category, Exists: true}
+		if tag.IsAlias {
+			member = interfaces.TagInformation{ID: tag.AliasedID, Exists: true}
+		}
+		alreadyAdded := false
+		for _, ID := range IDs {
+			if ID == member.ID {
+				alreadyAdded = true
+				break
+			}
+		}
+		if alreadyAdded == false {
+			IDs = append(IDs, member.ID)
+			ToReturn.GroupTags = append(ToReturn.GroupTags, member)
+		}
+	}
+	ToReturn.Exists = len(ToReturn.GroupTags) > 0
+	return ToReturn
+}
+
+//ParseImageOrder parses the value of an order metatag, a key optionally followed by _asc or _desc, such as score_asc
+//Random orders take a seed instead, such as random_42, so the order stays the same between pages. Without one the seed changes daily
+//Every order other than random defaults to descending
+func ParseImageOrder(Value string) (interfaces.ImageOrder, error) {
+	Value = strings.ToLower(Value)
+	if Value == interfaces.ImageOrderRandom {
+		return interfaces.ImageOrder{Key: interfaces.ImageOrderRandom, Seed: time.Now().Unix() / 86400 % 1000000007}, nil
+	}
+	if strings.HasPrefix(Value, interfaces.ImageOrderRandom+"_") {
+		seed, err := strconv.ParseInt(strings.TrimPrefix(Value, interfaces.ImageOrderRandom+"_"), 10, 64)
+		if err != nil || seed < 0 {
+			return interfaces.ImageOrder{}, errors.New("could not parse random order seed, ensure it is a positive number")
+		}
+		//Kept small so the shuffle can not overflow
+		return interfaces.ImageOrder{Key: interfaces.ImageOrderRandom, Seed: seed % 1000000007}, nil
+	}
+	ToReturn := interfaces.ImageOrder{Key: Value}
+	if strings.HasSuffix(Value, "_asc") {
+		ToReturn = interfaces.ImageOrder{Key: strings.TrimSuffix(Value, "_asc"), Ascending: true}
+	} else if strings.HasSuffix(Value, "_desc") {
+		ToReturn.Key = strings.TrimSuffix(Value, "_desc")
+	}
+	switch ToReturn.Key {
+	case interfaces.ImageOrderID, interfaces.ImageOrderScore, interfaces.ImageOrderTotalScore, interfaces.ImageOrderScoreVoters, interfaces.ImageOrderUploaded, interfaces.ImageOrderTagCount:
+		return ToReturn, nil
+	}
+	return interfaces.ImageOrder{}, errors.New("could not parse order tag, " + Value + " is not a known order")
+}
+
+//RandomOrderValue returns where the image with ID sorts in a random order with Seed. SQL plugins compute the same value in their queries
+func RandomOrderValue(ID uint64, Seed int64) uint64 {
+	return ((ID + uint64(Seed)) * 2654435761) % 4294967291
+}
+
+//ParseTimeRange parses the value of a date metatag, returning the time range it covers and the comparator to search it with
+//Dates can be a day, 2025-01-31, a month, 2025-01, or a year, 2025. Ages such as 12h, 7d, 2w, 3mo or 1y count back from Now,
+//so their comparator is flipped. uploaded:<7d finds images uploaded less than 7 days ago, and uploaded:7d is the same as uploaded:<=7d
+func ParseTimeRange(Value string, Comparator string, Now time.Time) (interfaces.TimeRange, string, error) {
+	if Comparator == "" {
+		Comparator = "="
+	}
+	if match := regexRelativeTime.FindStringSubmatch(Value); match != nil {
+		count, err := strconv.Atoi(match[1])
+		if err != nil {
+			return interfaces.TimeRange{}, Comparator, errors.New("could not parse date, " + Value + " is too large")
+		}
+		var since time.Time
+		switch match[2] {
+		case "h":
+			since = Now.Add(-time.Duration(count) * time.Hour)
+		case "d":
+			since = Now.AddDate(0, 0, -count)
+		case "w":
+			since = Now.AddDate(0, 0, -7*count)
+		case "mo":
+			since = Now.AddDate(0, -count, 0)
+		case "y":
+			since = Now.AddDate(-count, 0, 0)
+		}
+		flipped := map[string]string{"=": ">=", "<": ">", "<=": ">=", ">": "<", ">=": "<="}
+		return interfaces.TimeRange{Start: since, End: since}, flipped[Comparator], nil
+	}
+	for _, layout := range []struct {
+		format string
+		years  int
+		months int
+		days   int
+	}{{"2006-01-02", 0, 0, 1}, {"2006-01", 0, 1, 0}, {"2006", 1, 0, 0}} {
+		if start, err := time.Parse(layout.format, Value); err == nil {
+			return interfaces.TimeRange{Start: start, End: start.AddDate(layout.years, layout.months, layout.days)}, Comparator, nil
+		}
+	}
+	return interfaces.TimeRange{}, Comparator, errors.New("could not parse date, use a date such as 2025-01-31, 2025-01 or 2025, or an age such as 7d")
+}
+
+//ParseFileSize parses the value of a filesize metatag into bytes. Sizes may be followed by b, kb, mb or gb, such as 1.5mb, and are bytes without one
+func ParseFileSize(Value string) (int64, error) {
+	match := regexFileSize.FindStringSubmatch(strings.ToLower(Value))
+	if match == nil {
+		return 0, errors.New("could not parse file size, use a size such as 500kb or 1.5mb")
+	}
+	size, err := strconv.ParseFloat(match[1], 64)
+	if err != nil {
+		return 0, errors.New("could not parse file size, " + Value + " is too large")
+	}
+	switch match[2] {
+	case "kb":
+		size *= 1024
+	case "mb":
+		size *= 1024 * 1024
+	case "gb":
+		size *= 1024 * 1024 * 1024
+	}
+	return int64(size), nil
+}
+
+//ParseRatio parses the value of a ratio metatag, the width over the height of an image, written as 16x9, 16/9 or 1.78
+func ParseRatio(Value string) (float64, error) {
+	//ParseFloat reads 16_9 as 169, and values are cleaned so that other characters become _
+	if strings.Contains(Value, "_") {
+		return 0, errors.New("could not parse ratio, use a ratio such as 16x9 or 1.78")
+	}
+	parts := strings.FieldsFunc(strings.ToLower(Value), func(r rune) bool { return r == 'x' || r == '/' })
+	if len(parts) == 1 && strings.ContainsAny(Value, "xX/") == false {
+		if ratio, err := strconv.ParseFloat(parts[0], 64); err == nil && ratio > 0 {
+			return ratio, nil
+		}
+	} else if len(parts) == 2 {
+		width, err := strconv.ParseFloat(parts[0], 64)
+		height, err2 := strconv.ParseFloat(parts[1], 64)
+		if err == nil && err2 == nil && width > 0 && height > 0 {
+			return width / height, nil
+		}
+	}
+	return 0, errors.New("could not parse ratio, use a ratio such as 16x9 or 1.78")
+}
+
+//ParseSeconds parses the value of a duration metatag into seconds, written as a number of seconds such as 90, or a duration such as 1m30s
+func ParseSeconds(Value string) (float64, error) {
+	if strings.Contains(Value, "_") {
+		return 0, errors.New("could not parse duration, use a number of seconds such as 90, or a duration such as 1m30s")
+	}
+	if seconds, err := strconv.ParseFloat(Value, 64); err == nil && seconds >= 0 {
+		return seconds, nil
+	}
+	if duration, err := time.ParseDuration(strings.ToLower(Value)); err == nil && duration >= 0 {
+		return duration.Seconds(), nil
+	}
+	return 0, errors.New("could not parse duration, use a number of seconds such as 90, or a duration such as 1m30s")
+}
+
+//MIMETypePattern parses the value of a type metatag into a LIKE pattern for the MIME type column
+//A full MIME type such as image/png matches only that type, while a media type such as video matches every video
+func MIMETypePattern(Value string) (string, error) {
+	Value = strings.ToLower(Value)
+	if regexMIMEType.MatchString(Value) == false {
+		return "", errors.New("could not parse type, use a type such as image, video, audio or image/png")
+	}
+	if strings.Contains(Value, "/") == false {
+		return Value + "/%", nil
+	}
+	return Value, nil
+}
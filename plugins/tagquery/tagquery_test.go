@@ -0,0 +1,244 @@
+package tagquery
+
+import (
+	"errors"
+	"go-image-board/interfaces"
+	"strings"
+	"testing"
+	"time"
+)
+
+//queryString writes parsed terms back as a query, so results can be compared as text
+func queryString(Terms []Term) string {
+	var written []string
+	for _, term := range Terms {
+		written = append(written, term.String())
+	}
+	return strings.Join(written, " ")
+}
+
+func TestParse(t *testing.T) {
+	tests := map[string]string{
+		"":                              "",
+		"cat dog":                       "cat dog",
+		"cat -dog":                      "cat -dog",
+		"cat OR dog":                    "(cat OR dog)",
+		"cat ~dog":                      "(cat OR dog)",
+		"~cat ~dog ~bird":               "(cat OR dog OR bird)",
+		"a b OR c d":                    "a (b OR c) d",
+		"(red ~blue) -sketch":           "(red OR blue) -sketch",
+		"(red blue) -sketch":            "red blue -sketch",
+		"-(red blue)":                   "-(red blue)",
+		"-(red)":                        "-red",
+		"-(-red)":                       "red",
+		"(a OR b) OR c":                 "(a OR b OR c)",
+		"((a b) OR c) d":                "((a b) OR c) d",
+		"(a OR (b -c))":                 "(a OR (b -c))",
+		"-(a OR rating:safe)":           "-(a OR rating:safe)",
+		"( a OR b )":                    "(a OR b)",
+		"(a OR b":                       "(a OR b)",
+		"a OR b)) c":                    "(a OR b) c",
+		"() a OR":                       "a",
+		"OR":                            "",
+		"\"i wrote you\" OR audio":      "(i_wrote_you OR audio)",
+		"(\"i wrote you\" ~audio)":      "(i_wrote_you OR audio)",
+		"-\"i wrote you\"":              "-i_wrote_you",
+		"audio \"i wrote you a song":    "audio i_wrote_you_a_song",
+		"(\"i wrote) you\" ~audio)":     "(i_wrote)_you OR audio)",
+		"\"\" cat":                      "cat",
+		"score:>1 OR -uploader:someone": "(score:>1 OR -uploader:someone)",
+	}
+	for query, expected := range tests {
+		if result := queryString(Parse(query)); result != expected {
+			t.Errorf("Parse(%q) = %q, expected %q", query, result, expected)
+		}
+	}
+}
+
+func TestResolveCurrentUser(t *testing.T) {
+	tests := map[string]string{
+		"":                    "",
+		"fav:me":              "fav:Alice",
+		"cat -fav:me":         "cat -fav:Alice",
+		"~fav:=me ~dog":       "~fav:Alice ~dog",
+		"-(fav:me outdoor)":   "-(fav:Alice outdoor)",
+		"(cat OR FAV:ME))":    "(cat OR fav:Alice))",
+		"fav:bob favcount:>1": "fav:bob favcount:>1",
+		"fav:meme \"fav:me\"": "fav:meme \"fav:me\"",
+	}
+	for query, expected := range tests {
+		if result := ResolveCurrentUser(query, "Alice"); result != expected {
+			t.Errorf("ResolveCurrentUser(%q) = %q, expected %q", query, result, expected)
+		}
+	}
+	if result := ResolveCurrentUser("cat  fav:me", ""); result != "cat  fav:me" {
+		t.Errorf("ResolveCurrentUser without a user changed the query to %q", result)
+	}
+}
+
+func TestGroupInfo(t *testing.T) {
+	known := map[string]interfaces.TagInformation{
+		"cat":    {Name: "cat", ID: 1, Exists: true},
+		"kitty":  {Name: "kitty", ID: 2, Exists: true, IsAlias: true, AliasedID: 1},
+		"dog":    {Name: "dog", ID: 3, Exists: true},
+		"rating": {Name: "Rating", MetaValue: "safe", Comparator: "=", Exists: true, IsMeta: true},
+	}
+	tagsInfo := func(Name string, Exclude bool) ([]interfaces.TagInformation, error) {
+		if Name == "broken" {
+			return nil, errors.New("lookup failed")
+		}
+		tag, exists := known[strings.Split(Name, ":")[0]]
+		if exists == false {
+			return []interfaces.TagInformation{{Name: Name, Exclude: Exclude}}, nil
+		}
+		tag.Exclude = Exclude
+		ToReturn := []interfaces.TagInformation{tag}
+		if tag.IsAlias {
+			ToReturn = append(ToReturn, interfaces.TagInformation{Name: "cat", ID: 1, Exists: true, Exclude: Exclude})
+		}
+		return ToReturn, nil
+	}
+
+	group, err := GroupInfo(Parse("-(kitty OR missing OR (dog -rating:safe))")[0], tagsInfo)
+	if err != nil {
+		t.Fatalf("GroupInfo: %v", err)
+	}
+	if group.Exists == false || group.IsGroup == false || group.IsMeta == false || group.MatchAny == false || group.Exclude == false || group.Name != "-(kitty OR missing OR (dog -rating:safe))" {
+		t.Fatalf("unexpected group %+v", group)
+	}
+	//missing is left out, and kitty is searched as cat
+	if len(group.GroupTags) != 2 || group.GroupTags[0].ID != 1 || group.GroupTags[1].IsGroup == false {
+		t.Fatalf("unexpected members %+v", group.GroupTags)
+	}
+	inner := group.GroupTags[1]
+	if inner.MatchAny || len(inner.GroupTags) != 2 || inner.GroupTags[0].ID != 3 || inner.GroupTags[1].IsMeta == false || inner.GroupTags[1].Exclude == false {
+		t.Errorf("unexpected inner group %+v", inner)
+	}
+
+	if group, err := GroupInfo(Parse("missing OR other")[0], tagsInfo); err != nil || group.Exists {
+		t.Errorf("group of missing tags returned %+v, %v", group, err)
+	}
+	if _, err := GroupInfo(Parse("cat OR broken")[0], tagsInfo); err == nil {
+		t.Error("lookup error was not returned")
+	}
+}
+
+func TestLikePattern(t *testing.T) {
+	tests := map[string]string{
+		"Artist_*":    "artist\\_%",
+		"*hair*":      "%hair%",
+		"a%b*":        "a\\_b%",
+		"*(red)":      "%\\_red\\_",
+		"long-hair**": "long-hair%%",
+	}
+	for pattern, expected := range tests {
+		if result := LikePattern(CleanPattern(pattern)); result != expected {
+			t.Errorf("LikePattern(CleanPattern(%q)) = %q, expected %q", pattern, result, expected)
+		}
+	}
+	if IsPattern("artist_*") == false || IsPattern("artist:*") || IsPattern("cat") {
+		t.Error("IsPattern did not separate patterns from tags and metatags")
+	}
+}
+
+func TestPatternInfo(t *testing.T) {
+	matches := []interfaces.TagInformation{
+		{Name: "cat", ID: 1},
+		{Name: "cat_ears", ID: 3},
+		{Name: "catt", ID: 2, IsAlias: true, AliasedID: 1},
+		{Name: "cattail", ID: 4, IsAlias: true, AliasedID: 5},
+	}
+	group := PatternInfo("cat*", true, matches)
+	if group.Exists == false || group.IsGroup == false || group.MatchAny == false || group.Exclude == false || group.Name != "cat*" {
+		t.Fatalf("unexpected group %+v", group)
+	}
+	var IDs []uint64
+	for _, member := range group.GroupTags {
+		IDs = append(IDs, member.ID)
+	}
+	if len(IDs) != 3 || IDs[0] != 1 || IDs[1] != 3 || IDs[2] != 5 {
+		t.Errorf("unexpected members %v, expected [1 3 5]", IDs)
+	}
+	if group := PatternInfo("dog*", false, nil); group.Exists {
+		t.Errorf("pattern without matches exists %+v", group)
+	}
+}
+
+func TestParseTimeRange(t *testing.T) {
+	now := time.Date(2025, 3, 15, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value      string
+		comparator string
+		start      time.Time
+		end        time.Time
+		expected   string
+	}{
+		{"2025-01-31", "", time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC), time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC), "="},
+		{"2025-02", ">", time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC), ">"},
+		{"2024", "<=", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), "<="},
+		{"7d", "<", now.AddDate(0, 0, -7), now.AddDate(0, 0, -7), ">"},
+		{"12h", "=", now.Add(-12 * time.Hour), now.Add(-12 * time.Hour), ">="},
+		{"2w", ">=", now.AddDate(0, 0, -14), now.AddDate(0, 0, -14), "<="},
+		{"1mo", ">", now.AddDate(0, -1, 0), now.AddDate(0, -1, 0), "<"},
+		{"1y", "<=", now.AddDate(-1, 0, 0), now.AddDate(-1, 0, 0), ">="},
+	}
+	for _, test := range tests {
+		result, comparator, err := ParseTimeRange(test.value, test.comparator, now)
+		if err != nil || result.Start.Equal(test.start) == false || result.End.Equal(test.end) == false || comparator != test.expected {
+			t.Errorf("ParseTimeRange(%q, %q) = %v, %q, %v, expected %v - %v, %q", test.value, test.comparator, result, comparator, err, test.start, test.end, test.expected)
+		}
+	}
+	for _, value := range []string{"yesterday", "2025-13", "7x", "-7d", ""} {
+		if _, _, err := ParseTimeRange(value, "", now); err == nil {
+			t.Errorf("ParseTimeRange(%q) did not fail", value)
+		}
+	}
+}
+
+func TestParseMediaValues(t *testing.T) {
+	sizes := map[string]int64{"512": 512, "512b": 512, "500KB": 512000, "1.5mb": 1572864, "2gb": 2147483648}
+	for value, expected := range sizes {
+		if result, err := ParseFileSize(value); err != nil || result != expected {
+			t.Errorf("ParseFileSize(%q) = %d, %v, expected %d", value, result, err, expected)
+		}
+	}
+	ratios := map[string]float64{"16x9": 16.0 / 9.0, "4/3": 4.0 / 3.0, "1.5": 1.5, "1X1": 1}
+	for value, expected := range ratios {
+		if result, err := ParseRatio(value); err != nil || result != expected {
+			t.Errorf("ParseRatio(%q) = %v, %v, expected %v", value, result, err, expected)
+		}
+	}
+	seconds := map[string]float64{"90": 90, "2.5": 2.5, "1m30s": 90, "1H": 3600}
+	for value, expected := range seconds {
+		if result, err := ParseSeconds(value); err != nil || result != expected {
+			t.Errorf("ParseSeconds(%q) = %v, %v, expected %v", value, result, err, expected)
+		}
+	}
+	types := map[string]string{"video": "video/%", "Image/PNG": "image/png", "image/svg+xml": "image/svg+xml"}
+	for value, expected := range types {
+		if result, err := MIMETypePattern(value); err != nil || result != expected {
+			t.Errorf("MIMETypePattern(%q) = %q, %v, expected %q", value, result, err, expected)
+		}
+	}
+
+	for _, value := range []string{"", "-5", "5tb", "mb"} {
+		if _, err := ParseFileSize(value); err == nil {
+			t.Errorf("ParseFileSize(%q) did not fail", value)
+		}
+	}
+	for _, value := range []string{"", "16x", "x9", "16x0", "0", "wide", "1x2x3", "16_9"} {
+		if _, err := ParseRatio(value); err == nil {
+			t.Errorf("ParseRatio(%q) did not fail", value)
+		}
+	}
+	for _, value := range []string{"", "-5", "long", "1_000"} {
+		if _, err := ParseSeconds(value); err == nil {
+			t.Errorf("ParseSeconds(%q) did not fail", value)
+		}
+	}
+	for _, value := range []string{"", "video/%", "image_png", "a/b/c"} {
+		if _, err := MIMETypePattern(value); err == nil {
+			t.Errorf("MIMETypePattern(%q) did not fail", value)
+		}
+	}
+}
@@ -5,71 +5,133 @@ import (
 	"go-image-board/database"
 	"go-image-board/logging"
 	"go-image-board/routers"
+	"go-image-board/storage"
 	"os"
-	"path"
-	"path/filepath"
 	"strconv"
 )
 
+//renameAllImages renames images, and their previous files, to follow the current naming convention and layout, correcting their locations in the database
 func renameAllImages() {
-	_, maxCount, err := database.DBInterface.SearchImages(nil, 0, config.Configuration.PageStride)
-	if err != nil {
-		logging.WriteLog(logging.LogLevelError, "renameUtility/renameAllImages", "0", logging.ResultFailure, []string{"Failed to query for images", err.Error()})
-		return
-	}
-	logging.WriteLog(logging.LogLevelInfo, "renameUtility/renameAllImages", "0", logging.ResultInfo, []string{"Images to process", strconv.FormatUint(maxCount, 10)})
-	//Loop through the images one page at a time
-	for count := uint64(0); count < maxCount; count += config.Configuration.PageStride {
-		logging.WriteLog(logging.LogLevelInfo, "renameUtility/renameAllImages", "0", logging.ResultInfo, []string{"Processing at", strconv.FormatUint(count, 10)})
-		images, _, err := database.DBInterface.SearchImages(nil, count, config.Configuration.PageStride)
+	//Loop through every image one page at a time, including those in the trash or waiting for approval
+	for lastID := uint64(0); ; {
+		images, err := database.DBInterface.GetAllImages(lastID, config.Configuration.PageStride)
 		if err != nil {
 			logging.WriteLog(logging.LogLevelCritical, "renameUtility/renameAllImages", "0", logging.ResultFailure, []string{"Failed to query for images", err.Error()})
 			return
 		}
+		if len(images) == 0 {
+			return
+		}
+		logging.WriteLog(logging.LogLevelInfo, "renameUtility/renameAllImages", "0", logging.ResultInfo, []string{"Processing after", strconv.FormatUint(lastID, 10)})
 		//Loop through the images in this page
 		for _, imageInfo := range images {
-			//Open file for reading
-			fileStream, err := os.Open(path.Join(config.Configuration.ImageDirectory, imageInfo.Location))
+			lastID = imageInfo.ID
+			err := renameFile(imageInfo.Location, func(Location string) error {
+				return database.DBInterface.UpdateImage(imageInfo.ID, nil, nil, nil, nil, nil, Location)
+			})
 			if err != nil {
-				logging.WriteLog(logging.LogLevelCritical, "renameUtility/renameAllImages", "0", logging.ResultFailure, []string{"Failed to open file", err.Error()})
-				return
-			}
-
-			//Get new name
-			newName, err := routers.GetNewImageName(imageInfo.Location, fileStream)
-			fileStream.Close()
-			if err != nil {
-				logging.WriteLog(logging.LogLevelCritical, "renameUtility/renameAllImages", "0", logging.ResultFailure, []string{"Error generating new name", err.Error()})
-				return //On error cancel out to keep db and image in sync
-			}
-			if newName == imageInfo.Location {
-				logging.WriteLog(logging.LogLevelCritical, "renameUtility/renameAllImages", "0", logging.ResultInfo, []string{"Skipping due to same name", newName})
-				continue //Skip if same name
-			}
-			//Rename image
-			if err := os.Rename(path.Join(config.Configuration.ImageDirectory, imageInfo.Location), path.Join(config.Configuration.ImageDirectory, newName)); err != nil {
-				logging.WriteLog(logging.LogLevelCritical, "renameUtility/renameAllImages", "0", logging.ResultFailure, []string{"Error renaming file", err.Error()})
 				return //On error cancel out to keep db and image in sync
 			}
-			//Rename thumbnail
-			if err := os.Rename(path.Join(config.Configuration.ImageDirectory, "thumbs"+string(filepath.Separator)+imageInfo.Location+".png"), path.Join(config.Configuration.ImageDirectory, "thumbs"+string(filepath.Separator)+newName+".png")); err != nil {
-				logging.WriteLog(logging.LogLevelError, "renameUtility/renameAllImages", "0", logging.ResultFailure, []string{"Error renaming file", err.Error()})
+			//Previous files of the image follow the same convention
+			revisions, err := database.DBInterface.GetImageRevisions(imageInfo.ID)
+			if err != nil {
+				logging.WriteLog(logging.LogLevelCritical, "renameUtility/renameAllImages", "0", logging.ResultFailure, []string{"Failed to query for image revisions", err.Error()})
+				return
 			}
-			//Update database
-			if err := database.DBInterface.UpdateImage(imageInfo.ID, nil, nil, nil, nil, nil, newName); err != nil {
-				//Rollback and cancel on error
-				logging.WriteLog(logging.LogLevelError, "renameUtility/renameAllImages", "0", logging.ResultFailure, []string{"Error adding renamed image to db, cancelling", err.Error()})
-				//Rename thumbnail
-				if err := os.Rename(path.Join(config.Configuration.ImageDirectory, "thumbs"+string(filepath.Separator)+newName+".png"), path.Join(config.Configuration.ImageDirectory, "thumbs"+string(filepath.Separator)+imageInfo.Location+".png")); err != nil {
-					logging.WriteLog(logging.LogLevelError, "renameUtility/renameAllImages", "0", logging.ResultFailure, []string{"Error renaming file", err.Error()})
-				}
-				//Rename image
-				if err := os.Rename(path.Join(config.Configuration.ImageDirectory, newName), path.Join(config.Configuration.ImageDirectory, imageInfo.Location)); err != nil {
-					logging.WriteLog(logging.LogLevelError, "renameUtility/renameAllImages", "0", logging.ResultFailure, []string{"Error renaming file", err.Error()})
+			for _, revision := range revisions {
+				err := renameFile(revision.Location, func(Location string) error {
+					return database.DBInterface.UpdateImageRevisionLocation(revision.ID, Location)
+				})
+				if err != nil {
+					return //On error cancel out to keep db and image in sync
 				}
-				return
 			}
-			logging.WriteLog(logging.LogLevelInfo, "renameUtility/renameAllImages", "0", logging.ResultInfo, []string{"Successfull rename", newName})
 		}
 	}
 }
+
+//renameFile copies the file stored at Location, and its thumbnail, to its new name, calling SetLocation to record it in the database before removing the original
+func renameFile(Location string, SetLocation func(string) error) error {
+	//Open file for reading
+	fileStream, _, err := storage.StorageInterface.Get(Location)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelCritical, "renameUtility/renameFile", "0", logging.ResultFailure, []string{"Failed to open file", err.Error()})
+		return err
+	}
+
+	//Get new name
+	newName, err := routers.GetNewImageName(Location, fileStream)
+	fileStream.Close()
+	if err != nil {
+		logging.WriteLog(logging.LogLevelCritical, "renameUtility/renameFile", "0", logging.ResultFailure, []string{"Error generating new name", err.Error()})
+		return err
+	}
+	newName = routers.ImageLocation(newName) //Follow the configured layout
+	if newName == Location {
+		logging.WriteLog(logging.LogLevelCritical, "renameUtility/renameFile", "0", logging.ResultInfo, []string{"Skipping due to same name", newName})
+		return nil //Skip if same name
+	}
+	//Storage cannot rename, so copy the file and only remove the original once the database is updated
+	//Files with the same content have the same name, so the new name may already belong to another image or revision
+	//Only files copied here are removed on rollback, so those are never lost
+	var created []string
+	fileCreated, err := copyObjectIfMissing(Location, newName)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelCritical, "renameUtility/renameFile", "0", logging.ResultFailure, []string{"Error copying file", err.Error()})
+		return err
+	}
+	if fileCreated {
+		created = append(created, newName)
+	}
+	//Copy thumbnail
+	thumbnailCreated, err := copyObjectIfMissing(storage.ThumbnailName(Location), storage.ThumbnailName(newName))
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "renameUtility/renameFile", "0", logging.ResultFailure, []string{"Error copying thumbnail", err.Error()})
+	}
+	if thumbnailCreated {
+		created = append(created, storage.ThumbnailName(newName))
+	}
+	//Update database
+	if err := SetLocation(newName); err != nil {
+		//Rollback and cancel on error
+		logging.WriteLog(logging.LogLevelError, "renameUtility/renameFile", "0", logging.ResultFailure, []string{"Error adding renamed file to db, cancelling", err.Error()})
+		removeObjects(created)
+		return err
+	}
+	//Remove the original file and thumbnail
+	routers.RemoveImageFiles(Location)
+	logging.WriteLog(logging.LogLevelInfo, "renameUtility/renameFile", "0", logging.ResultInfo, []string{"Successfull rename", newName})
+	return nil
+}
+
+//removeObjects deletes each of Names from storage, logging any failure
+func removeObjects(Names []string) {
+	for _, name := range Names {
+		if err := storage.StorageInterface.Delete(name); err != nil {
+			logging.WriteLog(logging.LogLevelError, "renameUtility/removeObjects", "0", logging.ResultFailure, []string{"Failed to delete file", name, err.Error()})
+		}
+	}
+}
+
+//copyObjectIfMissing copies the object Source to Destination, unless Destination already exists. Returns whether it was copied
+func copyObjectIfMissing(Source string, Destination string) (bool, error) {
+	if _, err := storage.StorageInterface.Stat(Destination); err == nil {
+		return false, nil
+	} else if os.IsNotExist(err) == false {
+		return false, err
+	}
+	if err := copyObject(Source, Destination); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+//copyObject copies the object Source to Destination
+func copyObject(Source string, Destination string) error {
+	object, objectInfo, err := storage.StorageInterface.Get(Source)
+	if err != nil {
+		return err
+	}
+	defer object.Close()
+	return storage.StorageInterface.Put(Destination, object, objectInfo.Size)
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"go-image-board/config"
+	"go-image-board/database"
+	"go-image-board/routers"
+	"go-image-board/storage"
+	"strings"
+	"testing"
+)
+
+func TestRenameKeepsFilesSharedByDuplicates(t *testing.T) {
+	adminID := useEmptySite(t)
+	config.Configuration.PageStride = 1
+	defer func() { config.Configuration.PageStride = 0 }()
+	//Both images have the same content, so both are renamed to the same name
+	var imageIDs []uint64
+	for _, name := range []string{"first.png", "second.png"} {
+		if err := storage.StorageInterface.Put(name, strings.NewReader("same"), 4); err != nil {
+			t.Fatal(err)
+		}
+		putFiles(t, "thumbs/"+name+".png")
+		ID, err := database.DBInterface.NewImage(name, name, adminID, "", false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		imageIDs = append(imageIDs, ID)
+	}
+	newName, err := routers.GetNewImageName("first.png", strings.NewReader("same"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//The second image cannot take the name, and cancelling must not remove the files of the first
+	renameAllImages()
+
+	if image, _ := database.DBInterface.GetImage(imageIDs[0]); image.Location != newName {
+		t.Errorf("first image is at %q, expected %q", image.Location, newName)
+	}
+	if image, _ := database.DBInterface.GetImage(imageIDs[1]); image.Location != "second.png" {
+		t.Errorf("second image is at %q", image.Location)
+	}
+	expected := []string{newName, "thumbs/" + newName + ".png", "second.png", "thumbs/second.png.png"}
+	if files := storedFiles(t, expected...); len(files) != len(expected) || files[newName] != "same" || files["thumbs/"+newName+".png"] != "thumbs/first.png.png" {
+		t.Errorf("files are %v", files)
+	}
+}
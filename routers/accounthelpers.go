@@ -15,13 +15,19 @@ import (
 
 //getSessionInformation returns userName, tokenID, and the session itself if the user token is valid, if it is not, it returns the userName, "", and the session. If the session is not valid it returns "","",session.
 func getSessionInformation(request *http.Request) (string, string, *sessions.Session) {
+	userName, tokenID, session, _ := validateSession(request)
+	return userName, tokenID, session
+}
+
+//validateSession is getSessionInformation, also returning why the session is not valid, which is an interfaces.BanError if the user is banned
+func validateSession(request *http.Request) (string, string, *sessions.Session, error) {
 	//Get Session
 	session, err := config.SessionStore.Get(request, config.SessionVariableName)
 	if err != nil {
 		//Note that this just gobbles the error. Functions that call this should redirect when tokenID is "" or userName is ""
 		//If the user is supposed to be logged in that is
 		logging.WriteLog(logging.LogLevelError, "accountrouter/getSessionInformation", "0", logging.ResultFailure, []string{err.Error()})
-		return "", "", session
+		return "", "", session, err
 	}
 	// Get some session values.
 	userName, _ := session.Values["UserName"].(string)
@@ -29,9 +35,9 @@ func getSessionInformation(request *http.Request) (string, string, *sessions.Ses
 	tokenID, _ := session.Values["TokenID"].(string)
 	ip, _, _ := net.SplitHostPort(request.RemoteAddr)
 	if err := database.DBInterface.ValidateToken(userName, tokenID, ip); err != nil {
-		return "", "", session
+		return "", "", session, err
 	}
-	return userName, tokenID, session
+	return userName, tokenID, session, nil
 }
 
 //validateProposedEmail is a helper function to determine wether an e-mail is valid or not.
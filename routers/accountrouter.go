@@ -3,10 +3,12 @@ package routers
 import (
 	"go-image-board/config"
 	"go-image-board/database"
+	"go-image-board/interfaces"
 	"go-image-board/logging"
 	"html/template"
 	"net"
 	"net/http"
+	"strconv"
 	"strings"
 )
 
@@ -23,6 +25,8 @@ func LogonGetRouter(responseWriter http.ResponseWriter, request *http.Request) {
 		}
 		//Get user filter
 		TemplateInput.UserFilter, _ = database.DBInterface.GetUserFilter(TemplateInput.UserInformation.ID)
+		//Get saved searches
+		TemplateInput.SavedSearches, _ = GetSavedSearchesWithNewCounts(TemplateInput.UserInformation.ID, false)
 	}
 
 	//Grab user query information
@@ -95,6 +99,26 @@ func LogonPostRouter(responseWriter http.ResponseWriter, request *http.Request)
 		if username != "" && request.FormValue("password") != "" {
 			err := database.DBInterface.ValidateUser(username, []byte(request.FormValue("password")))
 			if err == nil {
+				//Banned users are told why, instead of getting a token
+				userID, err := database.DBInterface.GetUserID(username)
+				if err != nil {
+					logging.WriteLog(logging.LogLevelError, "accountrouter/LogonRouter", username, logging.ResultFailure, []string{"Failed to get user ID", err.Error()})
+					TemplateInput.HTMLMessage += template.HTML("Failed to get your account, please try again later.<br>")
+					redirectWithFlash(responseWriter, request, "/logon", TemplateInput.HTMLMessage, "LogonFailed")
+					return
+				}
+				ban, isBanned, err := GetActiveBan(userID, ip)
+				if err != nil {
+					TemplateInput.HTMLMessage += template.HTML("Failed to check for bans, please try again later.<br>")
+					redirectWithFlash(responseWriter, request, "/logon", TemplateInput.HTMLMessage, "LogonFailed")
+					return
+				}
+				if isBanned {
+					go WriteAuditLogByName(username, "LOGON", username+" failed to log in. Banned by ban "+strconv.FormatUint(ban.ID, 10))
+					TemplateInput.HTMLMessage += template.HTML(template.HTMLEscapeString(ban.Message()) + "<br>")
+					redirectWithFlash(responseWriter, request, "/logon", TemplateInput.HTMLMessage, "LogonFailed")
+					return
+				}
 				//Get Session
 				_, _, session := getSessionInformation(request)
 
@@ -154,7 +178,21 @@ func LogonPostRouter(responseWriter http.ResponseWriter, request *http.Request)
 			redirectWithFlash(responseWriter, request, "/logon", TemplateInput.HTMLMessage, "AccountFailed")
 			return
 		}
-		err := database.DBInterface.CreateUser(username, []byte(request.FormValue("password")), strings.ToLower(request.FormValue("eMail")), config.Configuration.DefaultPermissions)
+		ip, _, _ := net.SplitHostPort(request.RemoteAddr)
+		ban, isBanned, err := GetActiveBan(0, ip)
+		if err != nil {
+			TemplateInput.HTMLMessage += template.HTML("Create failed, could not check for bans. Please try again later.<br>")
+			redirectWithFlash(responseWriter, request, "/logon", TemplateInput.HTMLMessage, "AccountFailed")
+			return
+		}
+		if isBanned {
+			//Banned addresses cannot get around the ban with a new account
+			logging.WriteLog(logging.LogLevelError, "accountrouter/LogonRouter", username, logging.ResultFailure, []string{"Account Creation", "Address is banned", strconv.FormatUint(ban.ID, 10)})
+			TemplateInput.HTMLMessage += template.HTML("Create failed. " + template.HTMLEscapeString(ban.Message()) + "<br>")
+			redirectWithFlash(responseWriter, request, "/logon", TemplateInput.HTMLMessage, "AccountFailed")
+			return
+		}
+		err = database.DBInterface.CreateUser(username, []byte(request.FormValue("password")), strings.ToLower(request.FormValue("eMail")), config.Configuration.DefaultPermissions)
 		if err == nil {
 			go WriteAuditLogByName(username, "ACCOUNT-CREATED", username+" successfully created an account.")
 			TemplateInput.HTMLMessage += template.HTML("Your account has been created. Please sign in.<br>")
@@ -376,6 +414,57 @@ func LogonPostRouter(responseWriter http.ResponseWriter, request *http.Request)
 		TemplateInput.HTMLMessage += template.HTML("Your filter was changed successfully.<br>")
 		redirectWithFlash(responseWriter, request, "/logon", TemplateInput.HTMLMessage, "FilterSucceeded")
 		return
+	case "savesearch", "editsavedsearch":
+		//Ensure signed in
+		if !TemplateInput.IsLoggedOn() {
+			TemplateInput.HTMLMessage += template.HTML("You must be logged in to perform this action.<br>")
+			logging.WriteLog(logging.LogLevelError, "accountrouter/LogonRouter", TemplateInput.UserInformation.GetCompositeID(), logging.ResultFailure, []string{"User not logged in"})
+			redirectWithFlash(responseWriter, request, "/logon", TemplateInput.HTMLMessage, "LogonRequired")
+			return
+		}
+		savedSearch := interfaces.SavedSearchInformation{UserID: TemplateInput.UserInformation.ID,
+			Name:              request.FormValue("name"),
+			Query:             request.FormValue("query"),
+			CollectionContext: request.FormValue("collectioncontext") == "true",
+			Pinned:            request.FormValue("pinned") == "true",
+			ShowNewCount:      request.FormValue("shownewcount") == "true"}
+		var err error
+		if command == "editsavedsearch" {
+			savedSearch.ID, err = strconv.ParseUint(request.FormValue("savedsearchid"), 10, 64)
+			if err != nil {
+				TemplateInput.HTMLMessage += template.HTML("Failed to parse saved search ID.<br>")
+				redirectWithFlash(responseWriter, request, "/logon", TemplateInput.HTMLMessage, "AccountFail")
+				return
+			}
+			if request.FormValue("action") == "Delete" {
+				if err := database.DBInterface.DeleteSavedSearch(savedSearch.ID, savedSearch.UserID); err != nil {
+					TemplateInput.HTMLMessage += template.HTML("Failed to delete saved search.<br>")
+					redirectWithFlash(responseWriter, request, "/logon", TemplateInput.HTMLMessage, "AccountFail")
+					return
+				}
+				TemplateInput.HTMLMessage += template.HTML("Saved search deleted.<br>")
+				redirectWithFlash(responseWriter, request, "/logon", TemplateInput.HTMLMessage, "SavedSearchSucceeded")
+				return
+			}
+		}
+		if err := ValidateSavedSearch(&savedSearch); err != nil {
+			TemplateInput.HTMLMessage += template.HTML(template.HTMLEscapeString("Failed to save search, "+err.Error()) + "<br>")
+			redirectWithFlash(responseWriter, request, "/logon", TemplateInput.HTMLMessage, "AccountFail")
+			return
+		}
+		if command == "editsavedsearch" {
+			err = database.DBInterface.UpdateSavedSearch(savedSearch)
+		} else {
+			_, err = database.DBInterface.NewSavedSearch(savedSearch)
+		}
+		if err != nil {
+			TemplateInput.HTMLMessage += template.HTML("Failed to save search.<br>")
+			redirectWithFlash(responseWriter, request, "/logon", TemplateInput.HTMLMessage, "AccountFail")
+			return
+		}
+		TemplateInput.HTMLMessage += template.HTML("Your search was saved successfully.<br>")
+		redirectWithFlash(responseWriter, request, "/logon", TemplateInput.HTMLMessage, "SavedSearchSucceeded")
+		return
 	}
 	TemplateInput.HTMLMessage += template.HTML("Command not recognized or form submitted incorrectly.<br>")
 	redirectWithFlash(responseWriter, request, "/logon", TemplateInput.HTMLMessage, "AccountFail")
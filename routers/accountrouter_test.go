@@ -0,0 +1,108 @@
+package routers
+
+import (
+	"go-image-board/config"
+	"go-image-board/database"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestLogonPostRouter(t *testing.T) {
+	seedDatabase(t)
+	server := newTestServer(t)
+	client := newTestClient(t, server)
+
+	failures := []url.Values{
+		{"command": {"validate"}, "userName": {"admin"}, "password": {"wrongpass"}},
+		{"command": {"validate"}, "userName": {"admin"}},
+		{"command": {"validate"}, "userName": {"nobody"}, "password": {"adminpass"}},
+	}
+	for _, form := range failures {
+		response, _ := client.postForm(t, "/logon", form)
+		if location := response.Header.Get("Location"); response.StatusCode != http.StatusFound || location != "/logon?flash=LogonFailed" {
+			t.Errorf("logon with %v returned %d, redirecting to %q", form, response.StatusCode, location)
+		}
+	}
+
+	client.logon(t, "Admin", "adminpass")
+	_, body := client.get(t, "/logon")
+	if strings.Contains(body, "admin") == false {
+		t.Errorf("logon page does not show the signed in user")
+	}
+}
+
+func TestAccountRequiredMiddleWare(t *testing.T) {
+	seedDatabase(t)
+	server := newTestServer(t)
+	config.Configuration.AccountRequiredToView = true
+	defer func() { config.Configuration.AccountRequiredToView = false }()
+
+	client := newTestClient(t, server)
+	for _, path := range []string{"/", "/images", "/image?ID=1", "/collections", "/collection?ID=1", "/tags", "/tag?ID=1"} {
+		response, _ := client.get(t, path)
+		if location := response.Header.Get("Location"); response.StatusCode != http.StatusFound || location != "/logon?flash=LogonRequired" {
+			t.Errorf("anonymous %s returned %d, redirecting to %q", path, response.StatusCode, location)
+		}
+	}
+	if response, _ := client.get(t, "/logon"); response.StatusCode != http.StatusOK {
+		t.Errorf("logon page returned %d", response.StatusCode)
+	}
+
+	client.logon(t, "admin", "adminpass")
+	for _, path := range []string{"/", "/images", "/collections", "/tags"} {
+		if response, _ := client.get(t, path); response.StatusCode != http.StatusOK {
+			t.Errorf("signed in %s returned %d", path, response.StatusCode)
+		}
+	}
+}
+
+func TestLogonPostRouterSavedSearches(t *testing.T) {
+	fixture := seedDatabase(t)
+	server := newTestServer(t)
+	client := newTestClient(t, server)
+
+	//Signed out users have nowhere to keep a search
+	response, _ := client.postForm(t, "/logon", url.Values{"command": {"saveSearch"}, "name": {"Cats"}, "query": {"cat"}})
+	if location := response.Header.Get("Location"); location != "/logon?flash=LogonRequired" {
+		t.Errorf("anonymous save redirected to %q", location)
+	}
+
+	client.logon(t, "admin", "adminpass")
+	response, _ = client.postForm(t, "/logon", url.Values{"command": {"saveSearch"}, "name": {""}, "query": {"cat"}})
+	if location := response.Header.Get("Location"); location != "/logon?flash=AccountFail" {
+		t.Errorf("save without a name redirected to %q", location)
+	}
+	response, _ = client.postForm(t, "/logon", url.Values{"command": {"saveSearch"}, "name": {"Outdoor cats"}, "query": {"cat outdoor"}, "pinned": {"true"}})
+	if location := response.Header.Get("Location"); location != "/logon?flash=SavedSearchSucceeded" {
+		t.Fatalf("save redirected to %q", location)
+	}
+	searches, err := database.DBInterface.GetSavedSearches(fixture.AdminID)
+	if err != nil || len(searches) != 1 || searches[0].Query != "cat outdoor" || searches[0].Pinned == false || searches[0].ShowNewCount {
+		t.Fatalf("saved searches are %+v, %v", searches, err)
+	}
+	searchID := strconv.FormatUint(searches[0].ID, 10)
+	_, body := client.get(t, "/logon")
+	if strings.Contains(body, `href="/images?SavedSearch=`+searchID+`"`) == false {
+		t.Errorf("logon page does not link the saved search")
+	}
+
+	response, _ = client.postForm(t, "/logon", url.Values{"command": {"editSavedSearch"}, "savedsearchid": {searchID}, "action": {"Update"}, "name": {"Dogs"}, "query": {"dog"}})
+	if location := response.Header.Get("Location"); location != "/logon?flash=SavedSearchSucceeded" {
+		t.Fatalf("update redirected to %q", location)
+	}
+	search, err := database.DBInterface.GetSavedSearch(searches[0].ID)
+	if err != nil || search.Name != "Dogs" || search.Query != "dog" || search.Pinned {
+		t.Errorf("updated search is %+v, %v", search, err)
+	}
+
+	response, _ = client.postForm(t, "/logon", url.Values{"command": {"editSavedSearch"}, "savedsearchid": {searchID}, "action": {"Delete"}})
+	if location := response.Header.Get("Location"); location != "/logon?flash=SavedSearchSucceeded" {
+		t.Fatalf("delete redirected to %q", location)
+	}
+	if searches, _ := database.DBInterface.GetSavedSearches(fixture.AdminID); len(searches) != 0 {
+		t.Errorf("saved searches after delete are %+v", searches)
+	}
+}
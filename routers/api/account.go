@@ -71,6 +71,23 @@ func LogonAPIRouter(responseWriter http.ResponseWriter, request *http.Request) {
 	if logonData.Username != "" && logonData.Password != "" {
 		err := database.DBInterface.ValidateUser(logonData.Username, []byte(logonData.Password))
 		if err == nil {
+			//Banned users are told why, instead of getting a token
+			userID, err := database.DBInterface.GetUserID(logonData.Username)
+			if err != nil {
+				logging.WriteLog(logging.LogLevelError, "account/LogonAPIRouter", logonData.Username, logging.ResultFailure, []string{"Failed to get user ID", err.Error()})
+				ReplyWithJSONError(responseWriter, request, "failed to get account", logonData.Username, http.StatusInternalServerError)
+				return
+			}
+			ban, isBanned, err := routers.GetActiveBan(userID, ip)
+			if err != nil {
+				ReplyWithJSONError(responseWriter, request, "failed to check for bans", logonData.Username, http.StatusInternalServerError)
+				return
+			}
+			if isBanned {
+				go routers.WriteAuditLogByName(logonData.Username, "LOGON", logonData.Username+" failed to log in with API. Banned by ban "+strconv.FormatUint(ban.ID, 10))
+				ReplyWithJSONError(responseWriter, request, ban.Message(), logonData.Username, http.StatusForbidden)
+				return
+			}
 			//Get Session
 			session, _ := config.SessionStore.Get(request, config.SessionVariableName)
 
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"errors"
+	"go-image-board/database"
+	"go-image-board/interfaces"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLogonAPIRouter(t *testing.T) {
+	seedDatabase(t)
+	server := newTestServer(t)
+	client := newTestClient(t, server, "", "")
+
+	if response, body := client.do(t, "POST", "/api/Logon", map[string]string{"Username": "viewer", "Password": "wrongpass"}); response.StatusCode != http.StatusUnauthorized {
+		t.Errorf("wrong password returned %d: %s", response.StatusCode, body)
+	}
+	if response, body := client.do(t, "POST", "/api/Logon", map[string]string{"Username": "viewer"}); response.StatusCode != http.StatusBadRequest {
+		t.Errorf("blank password returned %d: %s", response.StatusCode, body)
+	}
+	if response, body := client.do(t, "POST", "/api/Logon", "not an object"); response.StatusCode != http.StatusBadRequest {
+		t.Errorf("invalid request returned %d: %s", response.StatusCode, body)
+	}
+	client.getJSON(t, "/api/Images", http.StatusUnauthorized, nil)
+
+	//User names are not case sensitive
+	if response, body := client.do(t, "POST", "/api/Logon", map[string]string{"Username": "Viewer", "Password": "viewerpass"}); response.StatusCode != http.StatusOK {
+		t.Fatalf("logon returned %d: %s", response.StatusCode, body)
+	}
+	client.getJSON(t, "/api/Images", http.StatusOK, nil)
+}
+
+func TestBannedAPIUser(t *testing.T) {
+	fixture := seedDatabase(t)
+	server := newTestServer(t)
+	client := newTestClient(t, server, "viewer", "viewerpass")
+	client.getJSON(t, "/api/Images", http.StatusOK, nil)
+
+	//The session of a banned user stops working, and so do new logons, both telling them why
+	if _, err := database.DBInterface.NewBan(interfaces.BanInformation{UserID: fixture.ViewerID, Reason: "Scraping", BannerID: fixture.AdminID, ExpiryTime: time.Now().Add(time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+	if response, body := client.do(t, "GET", "/api/Images", nil); response.StatusCode != http.StatusForbidden || strings.Contains(string(body), "Scraping") == false {
+		t.Errorf("banned session returned %d: %s", response.StatusCode, body)
+	}
+	anonymous := newTestClient(t, server, "", "")
+	if response, body := anonymous.do(t, "POST", "/api/Logon", map[string]string{"Username": "viewer", "Password": "viewerpass"}); response.StatusCode != http.StatusForbidden || strings.Contains(string(body), "Your account is banned until") == false {
+		t.Errorf("banned logon returned %d: %s", response.StatusCode, body)
+	}
+	if response, body := anonymous.do(t, "POST", "/api/Logon", map[string]string{"Username": "viewer", "Password": "wrongpass"}); response.StatusCode != http.StatusUnauthorized {
+		t.Errorf("banned logon with the wrong password returned %d: %s", response.StatusCode, body)
+	}
+	anonymous.getJSON(t, "/api/Images", http.StatusUnauthorized, nil)
+}
+
+//failingBansDB is a database whose ban lookups fail
+type failingBansDB struct {
+	interfaces.DBInterface
+}
+
+func (failingBansDB) GetActiveBansOn(UserID uint64, IP string) ([]interfaces.BanInformation, error) {
+	return nil, errors.New("connection lost")
+}
+
+func TestLogonAPIRouterRefusesWhenBansFail(t *testing.T) {
+	seedDatabase(t)
+	database.DBInterface = failingBansDB{database.DBInterface}
+	t.Cleanup(func() { database.DBInterface = database.DBInterface.(failingBansDB).DBInterface })
+	client := newTestClient(t, newTestServer(t), "", "")
+
+	if response, body := client.do(t, "POST", "/api/Logon", map[string]string{"Username": "viewer", "Password": "viewerpass"}); response.StatusCode != http.StatusInternalServerError {
+		t.Errorf("logon returned %d: %s", response.StatusCode, body)
+	}
+	client.getJSON(t, "/api/Images", http.StatusUnauthorized, nil)
+}
+
+//failingUserIDDB is a database that cannot find the ID of any user, though it still validates their password
+type failingUserIDDB struct {
+	interfaces.DBInterface
+}
+
+func (failingUserIDDB) GetUserID(UserName string) (uint64, error) {
+	return 0, errors.New("connection lost")
+}
+
+func TestLogonAPIRouterRefusesWithoutUserID(t *testing.T) {
+	seedDatabase(t)
+	database.DBInterface = failingUserIDDB{database.DBInterface}
+	t.Cleanup(func() { database.DBInterface = database.DBInterface.(failingUserIDDB).DBInterface })
+	client := newTestClient(t, newTestServer(t), "", "")
+
+	//An account ban cannot be checked without the account's ID
+	if response, body := client.do(t, "POST", "/api/Logon", map[string]string{"Username": "viewer", "Password": "viewerpass"}); response.StatusCode != http.StatusInternalServerError {
+		t.Errorf("logon returned %d: %s", response.StatusCode, body)
+	}
+	client.getJSON(t, "/api/Images", http.StatusUnauthorized, nil)
+}
+
+func TestLogoutAPIRouter(t *testing.T) {
+	seedDatabase(t)
+	server := newTestServer(t)
+	client := newTestClient(t, server, "viewer", "viewerpass")
+	client.getJSON(t, "/api/Images", http.StatusOK, nil)
+
+	if response, body := client.do(t, "POST", "/api/Logout", nil); response.StatusCode != http.StatusOK {
+		t.Fatalf("logout returned %d: %s", response.StatusCode, body)
+	}
+	client.getJSON(t, "/api/Images", http.StatusUnauthorized, nil)
+}
@@ -59,12 +59,16 @@ func ReplyWithJSONError(responseWriter http.ResponseWriter, request *http.Reques
 //ValidateAPIUser This helper function shortens code elsewhere. This is generally called with every API request. Returns ShouldContinue, UserID, UserName.
 func ValidateAPIUser(responseWriter http.ResponseWriter, request *http.Request) (bool, uint64, string) {
 	//Validate Logon
-	UserID, UserName, TokenID := routers.ValidateUserLogon(request)
+	UserID, UserName, TokenID, sessionErr := routers.ValidateUserSession(request)
 	errMSG := ""
 	if UserID != 0 && UserName != "" && TokenID != "" {
 		//Validated with session
 		return true, UserID, UserName
 	}
+	if banErr, isBanned := sessionErr.(interfaces.BanError); isBanned {
+		ReplyWithJSONError(responseWriter, request, banErr.Ban.Message(), "", http.StatusForbidden)
+		return false, 0, ""
+	}
 	//Attempt with auth header instead
 	authHeader := request.Header.Get("Authorization")
 	if authHeader != "" {
@@ -78,13 +82,19 @@ func ValidateAPIUser(responseWriter http.ResponseWriter, request *http.Request)
 				ip, _, _ := net.SplitHostPort(request.RemoteAddr)
 				userID, err := database.DBInterface.GetUserID(userName)
 				if err == nil {
-					if err := database.DBInterface.ValidateToken(userName, tokenID, ip); err != nil {
+					err := database.DBInterface.ValidateToken(userName, tokenID, ip)
+					if err == nil {
 						logging.WriteLog(logging.LogLevelError, "apiroot/ValidateAPIUser", userName, logging.ResultSuccess, []string{"Validated by header"})
 						return true, userID, userName //Valid auth header
 					}
+					if banErr, isBanned := err.(interfaces.BanError); isBanned {
+						ReplyWithJSONError(responseWriter, request, banErr.Error(), "", http.StatusForbidden)
+						return false, 0, ""
+					}
 					errMSG = "Token is invalid"
+				} else {
+					errMSG = "User is invalid"
 				}
-				errMSG = "User is invalid"
 			}
 		} else {
 			errMSG = "Auth header incorrect format"
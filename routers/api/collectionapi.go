@@ -42,6 +42,13 @@ func CollectionGetAPIRouter(responseWriter http.ResponseWriter, request *http.Re
 			ReplyWithJSONError(responseWriter, request, "Interal Database Error", UserName, http.StatusInternalServerError)
 			return
 		}
+		//Collections in the trash are only visible to those who can restore them
+		if collection.InTrash {
+			if permissions, err := database.DBInterface.GetUserPermissionSet(UserName); err != nil || permissions.HasPermission(interfaces.RemoveCollections) != true {
+				ReplyWithJSONError(responseWriter, request, "No collection by that ID", UserName, http.StatusNotFound)
+				return
+			}
+		}
 		ReplyWithJSON(responseWriter, request, collection, UserName)
 		return
 	}
@@ -88,10 +95,13 @@ func CollectionDeleteAPIRouter(responseWriter http.ResponseWriter, request *http
 			go routers.WriteAuditLogByName(UserName, "DELETE-IMAGE", UserName+" failed to delete collection with API. Insufficient permissions. "+requestedID)
 			return
 		}
+		if collection.InTrash {
+			ReplyWithJSONError(responseWriter, request, "Collection is already in the trash", UserName, http.StatusConflict)
+			return
+		}
 		//Check if we are to delete members as well
-		deleteMembers := request.FormValue("DeletMembers")
-		additionalMessages := ""
-		if strings.ToLower(deleteMembers) == "true" {
+		deleteMembers := strings.ToLower(request.FormValue("DeletMembers")) == "true"
+		if deleteMembers {
 			//Grab list of images
 			CollectionMembers, _, err := database.DBInterface.GetCollectionMembers(parsedID, 0, 0)
 			if err != nil {
@@ -109,24 +119,49 @@ func CollectionDeleteAPIRouter(responseWriter http.ResponseWriter, request *http
 					return
 				}
 			}
-			//Permission validated for all members, delete them
-			for _, ImageInfo := range CollectionMembers {
-				err = database.DBInterface.DeleteImage(ImageInfo.ID)
-				if err != nil {
-					additionalMessages += "Failed to delete collection member " + strconv.FormatUint(ImageInfo.ID, 10) + ". "
-					go routers.WriteAuditLogByName(UserName, "DELETE-COLLECTION", UserName+" failed to delete image "+strconv.FormatUint(ImageInfo.ID, 10))
-				}
-			}
 		}
-		//Permission validated, delete collection
-		if err := database.DBInterface.DeleteCollection(parsedID); err != nil {
+		//Permission validated, move collection, and members if requested, to the trash
+		if err := database.DBInterface.TrashCollection(parsedID, UserID, deleteMembers); err != nil {
 			ReplyWithJSONError(responseWriter, request, "Interal Database Error", UserName, http.StatusInternalServerError)
 			go routers.WriteAuditLogByName(UserName, "DELETE-COLLECTION", UserName+" failed to delete collection with API. "+requestedID+", "+err.Error())
 			return //Cancel delete
 		}
-		ReplyWithJSON(responseWriter, request, GenericResponse{Result: "Successfully deleted collection " + requestedID + ". " + additionalMessages}, UserName)
+		go routers.WriteAuditLogByName(UserName, "DELETE-COLLECTION", UserName+" moved collection to trash with API. "+requestedID+", "+collection.Name)
+		ReplyWithJSON(responseWriter, request, GenericResponse{Result: "Successfully moved collection " + requestedID + " to the trash"}, UserName)
 		return
 	}
 	ReplyWithJSONError(responseWriter, request, "Please specify CollectionID", UserName, http.StatusBadRequest)
 	return
 }
+
+//CollectionRestoreAPIRouter serves post requests to /api/Collection/{CollectionID}/Restore
+func CollectionRestoreAPIRouter(responseWriter http.ResponseWriter, request *http.Request) {
+	//Validate Logon
+	UserAPIValidated, _, UserName := ValidateAndThrottleAPIUser(responseWriter, request)
+	if !UserAPIValidated {
+		return //User not logged in and was already handled
+	}
+	//Validate Permission to use api
+	UserAPIWriteValidated, permissions := ValidateAPIUserWriteAccess(responseWriter, request, UserName)
+	if !UserAPIWriteValidated {
+		return //User does not have API access and was already told
+	}
+	requestedID := mux.Vars(request)["CollectionID"]
+	if permissions.HasPermission(interfaces.RemoveCollections) != true {
+		ReplyWithJSONError(responseWriter, request, "You do not have permission to restore collections", UserName, http.StatusForbidden)
+		go routers.WriteAuditLogByName(UserName, "RESTORE-COLLECTION", UserName+" failed to restore collection with API. Insufficient permissions. "+requestedID)
+		return
+	}
+
+	parsedID, err := strconv.ParseUint(requestedID, 10, 32)
+	if err != nil {
+		ReplyWithJSONError(responseWriter, request, "CollectionID could not be parsed into a number", UserName, http.StatusBadRequest)
+		return
+	}
+	if err := database.DBInterface.RestoreCollection(parsedID); err != nil {
+		ReplyWithJSONError(responseWriter, request, "No collection by that ID in the trash", UserName, http.StatusNotFound)
+		return
+	}
+	go routers.WriteAuditLogByName(UserName, "RESTORE-COLLECTION", UserName+" restored collection from trash with API. "+requestedID)
+	ReplyWithJSON(responseWriter, request, GenericResponse{Result: "Successfully restored collection " + requestedID}, UserName)
+}
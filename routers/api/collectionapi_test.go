@@ -0,0 +1,139 @@
+package api
+
+import (
+	"go-image-board/database"
+	"go-image-board/interfaces"
+	"go-image-board/routers"
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+)
+
+func TestCollectionGetAPIRouter(t *testing.T) {
+	fixture := seedDatabase(t)
+	server := newTestServer(t)
+	client := newTestClient(t, server, "viewer", "viewerpass")
+
+	var collection interfaces.CollectionInformation
+	client.getJSON(t, "/api/Collection/"+strconv.FormatUint(fixture.CollectionID, 10), http.StatusOK, &collection)
+	if collection.Name != "Pets" || collection.Members != 2 || collection.UploaderID != fixture.AdminID {
+		t.Errorf("unexpected collection returned %+v", collection)
+	}
+	client.getJSON(t, "/api/Collection/999", http.StatusNotFound, nil)
+	client.getJSON(t, "/api/Collection/notanumber", http.StatusBadRequest, nil)
+}
+
+func TestCollectionDeleteAPIRouter(t *testing.T) {
+	fixture := seedDatabase(t)
+	server := newTestServer(t)
+	collectionPath := "/api/Collection/" + strconv.FormatUint(fixture.CollectionID, 10)
+
+	viewer := newTestClient(t, server, "viewer", "viewerpass")
+	if response, body := viewer.do(t, "DELETE", collectionPath, nil); response.StatusCode != http.StatusForbidden {
+		t.Fatalf("viewer delete returned %d: %s", response.StatusCode, body)
+	}
+
+	//five was trashed on its own first, so it stays in the trash when the collection is restored
+	if err := database.DBInterface.TrashImage(fixture.Images["five"], fixture.ViewerID); err != nil {
+		t.Fatalf("TrashImage: %v", err)
+	}
+	admin := newTestClient(t, server, "admin", "adminpass")
+	if response, body := admin.do(t, "DELETE", collectionPath+"?DeletMembers=true", nil); response.StatusCode != http.StatusOK {
+		t.Fatalf("admin delete returned %d: %s", response.StatusCode, body)
+	}
+	viewer.getJSON(t, collectionPath, http.StatusNotFound, nil)
+	var collection interfaces.CollectionInformation
+	admin.getJSON(t, collectionPath, http.StatusOK, &collection)
+	if collection.InTrash == false || collection.DeleterID != fixture.AdminID {
+		t.Errorf("expected collection in trash, got %+v", collection)
+	}
+	for _, name := range []string{"three", "five"} {
+		if image, err := database.DBInterface.GetImage(fixture.Images[name]); err != nil || image.InTrash == false {
+			t.Errorf("collection member %s was not moved to the trash: %+v, %v", name, image, err)
+		}
+	}
+	if image, err := database.DBInterface.GetImage(fixture.Images["one"]); err != nil || image.InTrash {
+		t.Errorf("image outside the collection was deleted: %+v, %v", image, err)
+	}
+
+	if response, body := admin.do(t, "POST", collectionPath+"/Restore", nil); response.StatusCode != http.StatusOK {
+		t.Fatalf("admin restore returned %d: %s", response.StatusCode, body)
+	}
+	viewer.getJSON(t, collectionPath, http.StatusOK, nil)
+	if image, _ := database.DBInterface.GetImage(fixture.Images["three"]); image.InTrash {
+		t.Error("collection member three was not restored with the collection")
+	}
+	if image, _ := database.DBInterface.GetImage(fixture.Images["five"]); image.InTrash == false {
+		t.Error("collection member five was restored, but it was trashed separately")
+	}
+
+	//Purging removes the collection and its trashed members
+	if response, body := admin.do(t, "DELETE", collectionPath+"?DeletMembers=true", nil); response.StatusCode != http.StatusOK {
+		t.Fatalf("admin delete returned %d: %s", response.StatusCode, body)
+	}
+	if err := routers.PurgeExpiredTrash(0); err != nil {
+		t.Fatalf("PurgeExpiredTrash: %v", err)
+	}
+	admin.getJSON(t, collectionPath, http.StatusNotFound, nil)
+	for _, name := range []string{"three", "five"} {
+		if _, err := database.DBInterface.GetImage(fixture.Images[name]); err == nil {
+			t.Errorf("collection member %s was not purged", name)
+		}
+	}
+}
+
+func TestCollectionsGetAPIRouter(t *testing.T) {
+	fixture := seedDatabase(t)
+	otherID, err := database.DBInterface.NewCollection("Outside", "", fixture.AdminID)
+	if err != nil {
+		t.Fatalf("NewCollection: %v", err)
+	}
+	if err := database.DBInterface.AddCollectionMember(otherID, []uint64{fixture.Images["two"], fixture.Images["one"]}, fixture.AdminID); err != nil {
+		t.Fatalf("AddCollectionMember: %v", err)
+	}
+	server := newTestServer(t)
+	client := newTestClient(t, server, "viewer", "viewerpass")
+
+	tests := []struct {
+		query    string
+		expected []uint64
+	}{
+		{"", []uint64{otherID, fixture.CollectionID}},
+		{"dog", []uint64{otherID, fixture.CollectionID}},
+		{"cat -outdoor", []uint64{fixture.CollectionID}},
+		{"kitty outdoor", []uint64{otherID}},
+		{"name:pets", []uint64{fixture.CollectionID}},
+		{"-(cat outdoor)", []uint64{fixture.CollectionID}},
+		{"outdoor OR name:pets", []uint64{otherID, fixture.CollectionID}},
+		{"-(outdoor OR name:pets)", nil},
+		{"out*", []uint64{otherID}},
+		{"-out*", []uint64{fixture.CollectionID}},
+		{"uploaded:<1d", []uint64{otherID, fixture.CollectionID}},
+		{"-uploaded:<1d", nil},
+		{"tagged:<1d -tagged:>1d", []uint64{otherID, fixture.CollectionID}},
+		//Only image one, in Outside, has a vote
+		{"voted:<1d", []uint64{otherID}},
+		{"-voted:<1d", []uint64{fixture.CollectionID}},
+		//Image only metatags are ignored for collections
+		{"rating:explicit", []uint64{otherID, fixture.CollectionID}},
+	}
+	for _, test := range tests {
+		var result CollectionSearchResult
+		client.getJSON(t, "/api/Collections?SearchQuery="+url.QueryEscape(test.query), http.StatusOK, &result)
+		var IDs []uint64
+		for _, collection := range result.Collections {
+			IDs = append(IDs, collection.ID)
+		}
+		if equalIDs(IDs, test.expected) == false || result.ResultCount != uint64(len(test.expected)) {
+			t.Errorf("collection query %q returned %v (count %d), expected %v", test.query, IDs, result.ResultCount, test.expected)
+		}
+	}
+
+	//The preview is the first member
+	var result CollectionSearchResult
+	client.getJSON(t, "/api/Collections?SearchQuery=name:outside", http.StatusOK, &result)
+	if len(result.Collections) != 1 || result.Collections[0].Location != "two.png" || result.Collections[0].Members != 2 {
+		t.Errorf("unexpected collection preview %+v", result.Collections)
+	}
+}
@@ -45,5 +45,9 @@ func CollectionsGetAPIRouter(responseWriter http.ResponseWriter, request *http.R
 		return
 	}
 	logging.WriteLog(logging.LogLevelError, "collectionqueries/CollectionsAPIRouter", UserName, logging.ResultFailure, []string{"Failed to load user's filter", err.Error()})
+	if queryErr, isQueryError := err.(interfaces.QueryError); isQueryError {
+		ReplyWithJSONError(responseWriter, request, queryErr.Message, UserName, http.StatusBadRequest)
+		return
+	}
 	ReplyWithJSONError(responseWriter, request, "failed to parse your query", UserName, http.StatusInternalServerError)
 }
@@ -0,0 +1,333 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"go-image-board/config"
+	"go-image-board/database"
+	"go-image-board/interfaces"
+	"go-image-board/routers"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+//CommentSearchResult response format for a page of comments, ResultCount is the count of threads
+type CommentSearchResult struct {
+	Comments     []interfaces.CommentInformation
+	ResultCount  uint64
+	ServerStride uint64
+}
+
+type postCommentInput struct {
+	Body     string
+	ParentID uint64
+}
+
+//CommentsGetAPIRouter serves get requests to /api/Image/{ImageID}/Comments, and /api/Collection/{CollectionID}/Comments
+func CommentsGetAPIRouter(responseWriter http.ResponseWriter, request *http.Request) {
+	//Validate Logon
+	UserAPIValidated, UserID, UserName := ValidateAndThrottleAPIUser(responseWriter, request)
+	if !UserAPIValidated {
+		return //User not logged in and was already handled
+	}
+	permissions, err := database.DBInterface.GetUserPermissionSet(UserName)
+	if err != nil {
+		ReplyWithJSONError(responseWriter, request, "Could not validate your permission, internal database error", UserName, http.StatusInternalServerError)
+		return
+	}
+	ImageID, CollectionID, _, found := getCommentTarget(responseWriter, request, UserID, UserName, permissions)
+	if !found {
+		return //Already replied
+	}
+	pageStart, _ := strconv.ParseUint(request.FormValue("PageStart"), 10, 32) //Either parses fine, or is 0, both works
+	pageStride := config.Configuration.PageStride
+	comments, MaxCount, err := routers.GetVisibleComments(ImageID, CollectionID, pageStart, pageStride, permissions)
+	if err != nil {
+		ReplyWithJSONError(responseWriter, request, "Interal Database Error", UserName, http.StatusInternalServerError)
+		return
+	}
+	if comments == nil {
+		comments = []interfaces.CommentInformation{}
+	}
+	ReplyWithJSON(responseWriter, request, CommentSearchResult{Comments: comments, ResultCount: MaxCount, ServerStride: pageStride}, UserName)
+}
+
+//CommentsPostAPIRouter serves post requests to /api/Image/{ImageID}/Comments, and /api/Collection/{CollectionID}/Comments. Set ParentID to reply to a comment
+func CommentsPostAPIRouter(responseWriter http.ResponseWriter, request *http.Request) {
+	//Validate Logon
+	UserAPIValidated, UserID, UserName := ValidateAndThrottleAPIUser(responseWriter, request)
+	if !UserAPIValidated {
+		return //User not logged in and was already handled
+	}
+	//Validate Permission to use api
+	UserAPIWriteValidated, permissions := ValidateAPIUserWriteAccess(responseWriter, request, UserName)
+	if !UserAPIWriteValidated {
+		return //User does not have API access and was already told
+	}
+	if permissions.HasPermission(interfaces.PostComments) != true {
+		ReplyWithJSONError(responseWriter, request, "You do not have permission to post comments", UserName, http.StatusForbidden)
+		go routers.WriteAuditLog(UserID, "COMMENT-ADD", UserName+" failed to comment with API. No permissions.")
+		return
+	}
+	ImageID, CollectionID, InTrash, found := getCommentTarget(responseWriter, request, UserID, UserName, permissions)
+	if !found {
+		return //Already replied
+	}
+	if InTrash {
+		ReplyWithJSONError(responseWriter, request, "Comments cannot be posted to something in the trash", UserName, http.StatusConflict)
+		return
+	}
+	decoder := json.NewDecoder(request.Body)
+	var commentData postCommentInput
+	if err := decoder.Decode(&commentData); err != nil {
+		ReplyWithJSONError(responseWriter, request, "Failed to parse request data", UserName, http.StatusBadRequest)
+		return
+	}
+	body, err := routers.ValidateCommentBody(commentData.Body)
+	if err != nil {
+		ReplyWithJSONError(responseWriter, request, "Failed to post comment, "+err.Error(), UserName, http.StatusBadRequest)
+		return
+	}
+	if commentData.ParentID != 0 {
+		parent, err := database.DBInterface.GetComment(commentData.ParentID)
+		if err != nil || parent.ImageID != ImageID || parent.CollectionID != CollectionID {
+			ReplyWithJSONError(responseWriter, request, "No comment by that ParentID here", UserName, http.StatusBadRequest)
+			return
+		}
+	}
+	CommentID, err := database.DBInterface.NewComment(interfaces.CommentInformation{ImageID: ImageID, CollectionID: CollectionID, ParentID: commentData.ParentID, UserID: UserID, Body: body})
+	if err != nil {
+		ReplyWithJSONError(responseWriter, request, "Interal Database Error", UserName, http.StatusInternalServerError)
+		return
+	}
+	go routers.WriteAuditLog(UserID, "COMMENT-ADD", UserName+" posted comment "+strconv.FormatUint(CommentID, 10)+" with API.")
+	replyWithComment(responseWriter, request, CommentID, UserName, permissions)
+}
+
+//CommentGetAPIRouter serves get requests to /api/Comment/{CommentID}
+func CommentGetAPIRouter(responseWriter http.ResponseWriter, request *http.Request) {
+	//Validate Logon
+	UserAPIValidated, UserID, UserName := ValidateAndThrottleAPIUser(responseWriter, request)
+	if !UserAPIValidated {
+		return //User not logged in and was already handled
+	}
+	permissions, err := database.DBInterface.GetUserPermissionSet(UserName)
+	if err != nil {
+		ReplyWithJSONError(responseWriter, request, "Could not validate your permission, internal database error", UserName, http.StatusInternalServerError)
+		return
+	}
+	comment, found := getRequestedComment(responseWriter, request, UserID, UserName)
+	if !found {
+		return //Already replied
+	}
+	replyWithComment(responseWriter, request, comment.ID, UserName, permissions)
+}
+
+//CommentPutAPIRouter serves put requests to /api/Comment/{CommentID}, replacing the body of the user's own comment
+func CommentPutAPIRouter(responseWriter http.ResponseWriter, request *http.Request) {
+	//Validate Logon
+	UserAPIValidated, UserID, UserName := ValidateAndThrottleAPIUser(responseWriter, request)
+	if !UserAPIValidated {
+		return //User not logged in and was already handled
+	}
+	//Validate Permission to use api
+	UserAPIWriteValidated, permissions := ValidateAPIUserWriteAccess(responseWriter, request, UserName)
+	if !UserAPIWriteValidated {
+		return //User does not have API access and was already told
+	}
+	comment, found := getRequestedComment(responseWriter, request, UserID, UserName)
+	if !found {
+		return //Already replied
+	}
+	if routers.CanEditComment(UserID, permissions, comment) != true {
+		ReplyWithJSONError(responseWriter, request, "You do not have permission to edit this comment", UserName, http.StatusForbidden)
+		go routers.WriteAuditLog(UserID, "COMMENT-EDIT", UserName+" failed to edit comment "+strconv.FormatUint(comment.ID, 10)+" with API. No permissions.")
+		return
+	}
+	decoder := json.NewDecoder(request.Body)
+	var commentData postCommentInput
+	if err := decoder.Decode(&commentData); err != nil {
+		ReplyWithJSONError(responseWriter, request, "Failed to parse request data", UserName, http.StatusBadRequest)
+		return
+	}
+	body, err := routers.ValidateCommentBody(commentData.Body)
+	if err != nil {
+		ReplyWithJSONError(responseWriter, request, "Failed to edit comment, "+err.Error(), UserName, http.StatusBadRequest)
+		return
+	}
+	if body != comment.Body {
+		if err := database.DBInterface.UpdateComment(comment.ID, body, UserID); err != nil {
+			ReplyWithJSONError(responseWriter, request, "Interal Database Error", UserName, http.StatusInternalServerError)
+			return
+		}
+		go routers.WriteAuditLog(UserID, "COMMENT-EDIT", UserName+" edited comment "+strconv.FormatUint(comment.ID, 10)+" with API.")
+	}
+	replyWithComment(responseWriter, request, comment.ID, UserName, permissions)
+}
+
+//CommentDeleteAPIRouter serves delete requests to /api/Comment/{CommentID}
+func CommentDeleteAPIRouter(responseWriter http.ResponseWriter, request *http.Request) {
+	//Validate Logon
+	UserAPIValidated, UserID, UserName := ValidateAndThrottleAPIUser(responseWriter, request)
+	if !UserAPIValidated {
+		return //User not logged in and was already handled
+	}
+	//Validate Permission to use api
+	UserAPIWriteValidated, permissions := ValidateAPIUserWriteAccess(responseWriter, request, UserName)
+	if !UserAPIWriteValidated {
+		return //User does not have API access and was already told
+	}
+	comment, found := getRequestedComment(responseWriter, request, UserID, UserName)
+	if !found {
+		return //Already replied
+	}
+	if routers.CanDeleteComment(UserID, permissions, comment) != true {
+		ReplyWithJSONError(responseWriter, request, "You do not have permission to delete this comment", UserName, http.StatusForbidden)
+		go routers.WriteAuditLog(UserID, "COMMENT-DELETE", UserName+" failed to delete comment "+strconv.FormatUint(comment.ID, 10)+" with API. No permissions.")
+		return
+	}
+	if err := database.DBInterface.DeleteComment(comment.ID, UserID); err != nil {
+		ReplyWithJSONError(responseWriter, request, "Failed to delete comment, "+err.Error(), UserName, http.StatusConflict)
+		return
+	}
+	go routers.WriteAuditLog(UserID, "COMMENT-DELETE", UserName+" deleted comment "+strconv.FormatUint(comment.ID, 10)+" with API.")
+	ReplyWithJSON(responseWriter, request, GenericResponse{Result: "Successfully deleted comment " + strconv.FormatUint(comment.ID, 10)}, UserName)
+}
+
+//CommentRestoreAPIRouter serves post requests to /api/Comment/{CommentID}/Restore
+func CommentRestoreAPIRouter(responseWriter http.ResponseWriter, request *http.Request) {
+	//Validate Logon
+	UserAPIValidated, UserID, UserName := ValidateAndThrottleAPIUser(responseWriter, request)
+	if !UserAPIValidated {
+		return //User not logged in and was already handled
+	}
+	//Validate Permission to use api
+	UserAPIWriteValidated, permissions := ValidateAPIUserWriteAccess(responseWriter, request, UserName)
+	if !UserAPIWriteValidated {
+		return //User does not have API access and was already told
+	}
+	if permissions.HasPermission(interfaces.ModerateComments) != true {
+		ReplyWithJSONError(responseWriter, request, "You do not have permission to restore comments", UserName, http.StatusForbidden)
+		go routers.WriteAuditLog(UserID, "COMMENT-RESTORE", UserName+" failed to restore comment with API. No permissions.")
+		return
+	}
+	comment, found := getRequestedComment(responseWriter, request, UserID, UserName)
+	if !found {
+		return //Already replied
+	}
+	if err := database.DBInterface.RestoreComment(comment.ID); err != nil {
+		ReplyWithJSONError(responseWriter, request, "Failed to restore comment, "+err.Error(), UserName, http.StatusConflict)
+		return
+	}
+	go routers.WriteAuditLog(UserID, "COMMENT-RESTORE", UserName+" restored comment "+strconv.FormatUint(comment.ID, 10)+" with API.")
+	replyWithComment(responseWriter, request, comment.ID, UserName, permissions)
+}
+
+//CommentRevisionsGetAPIRouter serves get requests to /api/Comment/{CommentID}/Revisions, only the author and moderators may see them
+func CommentRevisionsGetAPIRouter(responseWriter http.ResponseWriter, request *http.Request) {
+	//Validate Logon
+	UserAPIValidated, UserID, UserName := ValidateAndThrottleAPIUser(responseWriter, request)
+	if !UserAPIValidated {
+		return //User not logged in and was already handled
+	}
+	permissions, err := database.DBInterface.GetUserPermissionSet(UserName)
+	if err != nil {
+		ReplyWithJSONError(responseWriter, request, "Could not validate your permission, internal database error", UserName, http.StatusInternalServerError)
+		return
+	}
+	comment, found := getRequestedComment(responseWriter, request, UserID, UserName)
+	if !found {
+		return //Already replied
+	}
+	if comment.UserID != UserID && permissions.HasPermission(interfaces.ModerateComments) != true {
+		ReplyWithJSONError(responseWriter, request, "You do not have permission to see the revisions of this comment", UserName, http.StatusForbidden)
+		return
+	}
+	revisions, err := database.DBInterface.GetCommentRevisions(comment.ID)
+	if err != nil {
+		ReplyWithJSONError(responseWriter, request, "Interal Database Error", UserName, http.StatusInternalServerError)
+		return
+	}
+	if revisions == nil {
+		revisions = []interfaces.CommentRevisionInformation{}
+	}
+	ReplyWithJSON(responseWriter, request, revisions, UserName)
+}
+
+//getCommentTarget returns the image, or collection, requested by ImageID or CollectionID, and whether it is in the trash.
+//Something in the trash is only found by those who can restore it, and an image waiting for approval only by those who can see it, otherwise replies with the error and returns false
+func getCommentTarget(responseWriter http.ResponseWriter, request *http.Request, UserID uint64, UserName string, permissions interfaces.UserPermission) (uint64, uint64, bool, bool) {
+	urlVariables := mux.Vars(request)
+	if requestedID, isImage := urlVariables["ImageID"]; isImage {
+		parsedID, err := strconv.ParseUint(requestedID, 10, 32)
+		if err != nil {
+			ReplyWithJSONError(responseWriter, request, "ImageID could not be parsed into a number", UserName, http.StatusBadRequest)
+			return 0, 0, false, false
+		}
+		image, found := getVisibleImage(responseWriter, request, parsedID, UserID, UserName)
+		if !found {
+			return 0, 0, false, false
+		}
+		if image.InTrash && permissions.HasPermission(interfaces.RemoveImage) != true {
+			ReplyWithJSONError(responseWriter, request, "No image by that ID", UserName, http.StatusNotFound)
+			return 0, 0, false, false
+		}
+		return parsedID, 0, image.InTrash, true
+	}
+	parsedID, err := strconv.ParseUint(urlVariables["CollectionID"], 10, 32)
+	if err != nil {
+		ReplyWithJSONError(responseWriter, request, "CollectionID could not be parsed into a number", UserName, http.StatusBadRequest)
+		return 0, 0, false, false
+	}
+	collection, err := database.DBInterface.GetCollection(parsedID)
+	if err != nil || (collection.InTrash && permissions.HasPermission(interfaces.RemoveCollections) != true) {
+		if err == nil || err == sql.ErrNoRows {
+			ReplyWithJSONError(responseWriter, request, "No collection by that ID", UserName, http.StatusNotFound)
+			return 0, 0, false, false
+		}
+		ReplyWithJSONError(responseWriter, request, "Interal Database Error", UserName, http.StatusInternalServerError)
+		return 0, 0, false, false
+	}
+	return 0, parsedID, collection.InTrash, true
+}
+
+//getRequestedComment returns the comment requested by CommentID, unless it is on an image the user cannot see, otherwise replies with the error and returns false
+func getRequestedComment(responseWriter http.ResponseWriter, request *http.Request, UserID uint64, UserName string) (interfaces.CommentInformation, bool) {
+	parsedID, err := strconv.ParseUint(mux.Vars(request)["CommentID"], 10, 64)
+	if err != nil {
+		ReplyWithJSONError(responseWriter, request, "CommentID could not be parsed into a number", UserName, http.StatusBadRequest)
+		return interfaces.CommentInformation{}, false
+	}
+	comment, err := database.DBInterface.GetComment(parsedID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ReplyWithJSONError(responseWriter, request, "No comment by that ID", UserName, http.StatusNotFound)
+			return comment, false
+		}
+		ReplyWithJSONError(responseWriter, request, "Interal Database Error", UserName, http.StatusInternalServerError)
+		return comment, false
+	}
+	if comment.ImageID != 0 {
+		if _, found := getVisibleImage(responseWriter, request, comment.ImageID, UserID, UserName); !found {
+			return comment, false
+		}
+	}
+	return comment, true
+}
+
+//replyWithComment replies with a single comment, hiding its body if it was deleted and the user may not moderate comments
+func replyWithComment(responseWriter http.ResponseWriter, request *http.Request, CommentID uint64, UserName string, permissions interfaces.UserPermission) {
+	comment, err := database.DBInterface.GetComment(CommentID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ReplyWithJSONError(responseWriter, request, "No comment by that ID", UserName, http.StatusNotFound)
+			return
+		}
+		ReplyWithJSONError(responseWriter, request, "Interal Database Error", UserName, http.StatusInternalServerError)
+		return
+	}
+	routers.HideDeletedComment(&comment, permissions)
+	ReplyWithJSON(responseWriter, request, comment, UserName)
+}
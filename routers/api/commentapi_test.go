@@ -0,0 +1,185 @@
+package api
+
+import (
+	"encoding/json"
+	"go-image-board/config"
+	"go-image-board/database"
+	"go-image-board/interfaces"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestCommentsAPIRouter(t *testing.T) {
+	fixture := seedDatabase(t)
+	server := newTestServer(t)
+	admin := newTestClient(t, server, "admin", "adminpass")
+	viewer := newTestClient(t, server, "viewer", "viewerpass")
+	imagePath := "/api/Image/" + strconv.FormatUint(fixture.Images["one"], 10) + "/Comments"
+
+	post := func(client *testClient, path string, body string, parentID uint64) interfaces.CommentInformation {
+		t.Helper()
+		response, responseBody := client.do(t, "POST", path, map[string]interface{}{"Body": body, "ParentID": parentID})
+		if response.StatusCode != http.StatusOK {
+			t.Fatalf("posting %q returned %d: %s", body, response.StatusCode, responseBody)
+		}
+		var comment interfaces.CommentInformation
+		if err := json.Unmarshal(responseBody, &comment); err != nil || comment.ID == 0 || comment.Body != strings.TrimSpace(body) {
+			t.Fatalf("posting %q returned %s, %v", body, responseBody, err)
+		}
+		return comment
+	}
+	commentPath := func(comment interfaces.CommentInformation) string {
+		return "/api/Comment/" + strconv.FormatUint(comment.ID, 10)
+	}
+
+	if response, body := admin.do(t, "POST", imagePath, map[string]interface{}{"Body": "   "}); response.StatusCode != http.StatusBadRequest {
+		t.Errorf("blank comment returned %d: %s", response.StatusCode, body)
+	}
+	first := post(admin, imagePath, " First! ", 0)
+	if first.Body != "First!" || first.UserID != fixture.AdminID || first.UserName != "admin" || first.ParentID != 0 {
+		t.Errorf("posted comment is %+v", first)
+	}
+	reply := post(admin, imagePath, "A reply", first.ID)
+	second := post(admin, imagePath, "Second", 0)
+	nested := post(admin, imagePath, "A reply to the reply", reply.ID)
+	if nested.ThreadID != first.ID {
+		t.Errorf("nested reply is in thread %d, expected %d", nested.ThreadID, first.ID)
+	}
+
+	//Replies are listed after their parent
+	var result CommentSearchResult
+	admin.getJSON(t, imagePath, http.StatusOK, &result)
+	expected := []struct {
+		ID    uint64
+		Depth int
+	}{{first.ID, 0}, {reply.ID, 1}, {nested.ID, 2}, {second.ID, 0}}
+	if result.ResultCount != 2 || len(result.Comments) != len(expected) {
+		t.Fatalf("listed %+v, expected 2 threads of 4 comments", result)
+	}
+	for index, comment := range result.Comments {
+		if comment.ID != expected[index].ID || comment.Depth != expected[index].Depth {
+			t.Errorf("comment %d is %d at depth %d, expected %d at depth %d", index, comment.ID, comment.Depth, expected[index].ID, expected[index].Depth)
+		}
+	}
+
+	//Pages hold whole threads
+	config.Configuration.PageStride = 1
+	admin.getJSON(t, imagePath+"?PageStart=1", http.StatusOK, &result)
+	config.Configuration.PageStride = 10
+	if result.ResultCount != 2 || len(result.Comments) != 1 || result.Comments[0].ID != second.ID {
+		t.Errorf("second page is %+v", result)
+	}
+
+	//Replies must be on the same image
+	otherPath := "/api/Image/" + strconv.FormatUint(fixture.Images["two"], 10) + "/Comments"
+	if response, body := admin.do(t, "POST", otherPath, map[string]interface{}{"Body": "Elsewhere", "ParentID": first.ID}); response.StatusCode != http.StatusBadRequest {
+		t.Errorf("reply on another image returned %d: %s", response.StatusCode, body)
+	}
+	admin.getJSON(t, otherPath, http.StatusOK, &result)
+	if result.ResultCount != 0 || len(result.Comments) != 0 {
+		t.Errorf("other image has comments %+v", result)
+	}
+
+	//Posting requires API write access and PostComments
+	if response, body := viewer.do(t, "POST", imagePath, map[string]interface{}{"Body": "Hello"}); response.StatusCode != http.StatusForbidden {
+		t.Errorf("viewer post returned %d: %s", response.StatusCode, body)
+	}
+	if err := database.DBInterface.SetUserPermissionSet(fixture.ViewerID, uint64(interfaces.APIWriteAccess)); err != nil {
+		t.Fatalf("SetUserPermissionSet: %v", err)
+	}
+	if response, body := viewer.do(t, "POST", imagePath, map[string]interface{}{"Body": "Hello"}); response.StatusCode != http.StatusForbidden {
+		t.Errorf("viewer post without PostComments returned %d: %s", response.StatusCode, body)
+	}
+	if err := database.DBInterface.SetUserPermissionSet(fixture.ViewerID, uint64(interfaces.APIWriteAccess|interfaces.PostComments)); err != nil {
+		t.Fatalf("SetUserPermissionSet: %v", err)
+	}
+	viewerComment := post(viewer, imagePath, "Hello", second.ID)
+
+	//Only the author may edit, and the previous body is kept
+	if response, body := viewer.do(t, "PUT", commentPath(first), map[string]interface{}{"Body": "Mine now"}); response.StatusCode != http.StatusForbidden {
+		t.Errorf("editing another user's comment returned %d: %s", response.StatusCode, body)
+	}
+	response, body := viewer.do(t, "PUT", commentPath(viewerComment), map[string]interface{}{"Body": "Hello there"})
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("edit returned %d: %s", response.StatusCode, body)
+	}
+	var edited interfaces.CommentInformation
+	if err := json.Unmarshal(body, &edited); err != nil || edited.Body != "Hello there" || edited.EditTime.IsZero() {
+		t.Errorf("edit returned %s, %v", body, err)
+	}
+	var revisions []interfaces.CommentRevisionInformation
+	viewer.getJSON(t, commentPath(viewerComment)+"/Revisions", http.StatusOK, &revisions)
+	if len(revisions) != 1 || revisions[0].Body != "Hello" || revisions[0].EditorID != fixture.ViewerID || revisions[0].EditorName != "viewer" {
+		t.Errorf("revisions are %+v", revisions)
+	}
+	admin.getJSON(t, commentPath(viewerComment)+"/Revisions", http.StatusOK, &revisions)
+	viewer.getJSON(t, commentPath(first)+"/Revisions", http.StatusForbidden, nil)
+
+	//Deleted comments keep their place, but only moderators can read them
+	if response, body := viewer.do(t, "DELETE", commentPath(first), nil); response.StatusCode != http.StatusForbidden {
+		t.Errorf("viewer delete returned %d: %s", response.StatusCode, body)
+	}
+	if response, body := admin.do(t, "DELETE", commentPath(viewerComment), nil); response.StatusCode != http.StatusOK {
+		t.Fatalf("delete returned %d: %s", response.StatusCode, body)
+	}
+	if response, body := admin.do(t, "DELETE", commentPath(viewerComment), nil); response.StatusCode != http.StatusConflict {
+		t.Errorf("second delete returned %d: %s", response.StatusCode, body)
+	}
+	var deleted interfaces.CommentInformation
+	viewer.getJSON(t, commentPath(viewerComment), http.StatusOK, &deleted)
+	if deleted.Deleted == false || deleted.Body != "" || deleted.DeleterID != fixture.AdminID {
+		t.Errorf("viewer sees deleted comment %+v", deleted)
+	}
+	admin.getJSON(t, commentPath(viewerComment), http.StatusOK, &deleted)
+	if deleted.Deleted == false || deleted.Body != "Hello there" {
+		t.Errorf("moderator sees deleted comment %+v", deleted)
+	}
+	if response, body := viewer.do(t, "PUT", commentPath(viewerComment), map[string]interface{}{"Body": "Undeleted"}); response.StatusCode != http.StatusForbidden {
+		t.Errorf("editing a deleted comment returned %d: %s", response.StatusCode, body)
+	}
+	if response, body := viewer.do(t, "POST", commentPath(viewerComment)+"/Restore", nil); response.StatusCode != http.StatusForbidden {
+		t.Errorf("viewer restore returned %d: %s", response.StatusCode, body)
+	}
+	if response, body := admin.do(t, "POST", commentPath(viewerComment)+"/Restore", nil); response.StatusCode != http.StatusOK {
+		t.Errorf("restore returned %d: %s", response.StatusCode, body)
+	}
+	viewer.getJSON(t, commentPath(viewerComment), http.StatusOK, &deleted)
+	if deleted.Deleted || deleted.Body != "Hello there" {
+		t.Errorf("restored comment is %+v", deleted)
+	}
+	viewer.getJSON(t, "/api/Comment/999999", http.StatusNotFound, nil)
+
+	//Collections have their own comments
+	collectionPath := "/api/Collection/" + strconv.FormatUint(fixture.CollectionID, 10) + "/Comments"
+	if response, body := admin.do(t, "POST", collectionPath, map[string]interface{}{"Body": "Off topic", "ParentID": first.ID}); response.StatusCode != http.StatusBadRequest {
+		t.Errorf("reply to an image comment returned %d: %s", response.StatusCode, body)
+	}
+	collectionComment := post(admin, collectionPath, "Cute pets", 0)
+	admin.getJSON(t, collectionPath, http.StatusOK, &result)
+	if result.ResultCount != 1 || len(result.Comments) != 1 || result.Comments[0].ID != collectionComment.ID || result.Comments[0].CollectionID != fixture.CollectionID {
+		t.Errorf("collection comments are %+v", result)
+	}
+	admin.getJSON(t, "/api/Collection/999999/Comments", http.StatusNotFound, nil)
+
+	//Deleted comments are not counted
+	if err := database.DBInterface.DeleteComment(second.ID, fixture.AdminID); err != nil {
+		t.Fatalf("DeleteComment: %v", err)
+	}
+	var images ImageSearchResult
+	admin.getJSON(t, "/api/Images?SearchQuery="+url.QueryEscape("commentcount:4"), http.StatusOK, &images)
+	if equalIDs(imageIDs(images.Images), fixture.fixtureImageIDs("one")) == false {
+		t.Errorf("commentcount:4 returned %v", imageIDs(images.Images))
+	}
+	admin.getJSON(t, "/api/Images?SearchQuery="+url.QueryEscape("commentcount:0"), http.StatusOK, &images)
+	if equalIDs(imageIDs(images.Images), fixture.fixtureImageIDs("five", "four", "three", "two")) == false {
+		t.Errorf("commentcount:0 returned %v", imageIDs(images.Images))
+	}
+	var collections CollectionSearchResult
+	admin.getJSON(t, "/api/Collections?SearchQuery="+url.QueryEscape("commentcount:>0"), http.StatusOK, &collections)
+	if len(collections.Collections) != 1 || collections.Collections[0].ID != fixture.CollectionID {
+		t.Errorf("commentcount:>0 returned %+v", collections.Collections)
+	}
+}
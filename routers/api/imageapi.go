@@ -1,6 +1,7 @@
 package api
 
 import (
+	"bytes"
 	"database/sql"
 	"encoding/json"
 	"go-image-board/config"
@@ -8,9 +9,6 @@ import (
 	"go-image-board/interfaces"
 	"go-image-board/routers"
 	"net/http"
-	"os"
-	"path"
-	"path/filepath"
 	"strconv"
 
 	"github.com/gorilla/mux"
@@ -19,7 +17,7 @@ import (
 //ImageGetAPIRouter serves get requests to /api/Image/{ImageID}
 func ImageGetAPIRouter(responseWriter http.ResponseWriter, request *http.Request) {
 	//Validate Logon
-	UserAPIValidated, _, UserName := ValidateAndThrottleAPIUser(responseWriter, request)
+	UserAPIValidated, UserID, UserName := ValidateAndThrottleAPIUser(responseWriter, request)
 	if !UserAPIValidated {
 		return //User not logged in and was already handled
 	}
@@ -35,15 +33,18 @@ func ImageGetAPIRouter(responseWriter http.ResponseWriter, request *http.Request
 			ReplyWithJSONError(responseWriter, request, "ImageID could not be parsed into a number", UserName, http.StatusBadRequest)
 			return
 		}
-		image, err := database.DBInterface.GetImage(parsedID)
-		if err != nil {
-			if err == sql.ErrNoRows {
+		image, found := getVisibleImage(responseWriter, request, parsedID, UserID, UserName)
+		if !found {
+			return //Already replied
+		}
+		//Images in the trash are only visible to those who can restore them
+		if image.InTrash {
+			if permissions, err := database.DBInterface.GetUserPermissionSet(UserName); err != nil || permissions.HasPermission(interfaces.RemoveImage) != true {
 				ReplyWithJSONError(responseWriter, request, "No image by that ID", UserName, http.StatusNotFound)
 				return
 			}
-			ReplyWithJSONError(responseWriter, request, "Interal Database Error", UserName, http.StatusInternalServerError)
-			return
 		}
+		image.UsersFavorite, _ = database.DBInterface.GetUserFavorite(UserID, parsedID)
 		ReplyWithJSON(responseWriter, request, image, UserName)
 		return
 	}
@@ -75,14 +76,9 @@ func ImageDeleteAPIRouter(responseWriter http.ResponseWriter, request *http.Requ
 			return
 		}
 		//Get Image info
-		imageInfo, err := database.DBInterface.GetImage(parsedID)
-		if err != nil {
-			if err == sql.ErrNoRows {
-				ReplyWithJSONError(responseWriter, request, "No image by that ID", UserName, http.StatusNotFound)
-				return
-			}
-			ReplyWithJSONError(responseWriter, request, "Interal Database Error", UserName, http.StatusInternalServerError)
-			return
+		imageInfo, found := getVisibleImage(responseWriter, request, parsedID, UserID, UserName)
+		if !found {
+			return //Already replied
 		}
 
 		//Validate delete permissions
@@ -91,26 +87,252 @@ func ImageDeleteAPIRouter(responseWriter http.ResponseWriter, request *http.Requ
 			go routers.WriteAuditLogByName(UserName, "DELETE-IMAGE", UserName+" failed to delete image with API. Insufficient permissions. "+requestedID)
 			return
 		}
+		if imageInfo.InTrash {
+			ReplyWithJSONError(responseWriter, request, "Image is already in the trash", UserName, http.StatusConflict)
+			return
+		}
 
-		//Delete
-		//Permission validated, now delete (ImageTags and Images)
-		if err := database.DBInterface.DeleteImage(parsedID); err != nil {
+		//Permission validated, now move to trash. The files are removed once the trash is purged
+		if err := database.DBInterface.TrashImage(parsedID, UserID); err != nil {
 			ReplyWithJSONError(responseWriter, request, "Interal Database Error", UserName, http.StatusInternalServerError)
 			go routers.WriteAuditLogByName(UserName, "DELETE-IMAGE", UserName+" failed to delete image with API. "+requestedID+", "+err.Error())
 			return //Cancel delete
 		}
-		go routers.WriteAuditLogByName(UserName, "DELETE-IMAGE", UserName+" deleted image with API. "+requestedID+", "+imageInfo.Name+", "+imageInfo.Location)
-		//Third, delete Image from Disk
-		go os.Remove(path.Join(config.Configuration.ImageDirectory, imageInfo.Location))
-		//Last delete thumbnail from disk
-		go os.Remove(path.Join(config.Configuration.ImageDirectory, "thumbs"+string(filepath.Separator)+imageInfo.Location+".png"))
+		go routers.WriteAuditLogByName(UserName, "DELETE-IMAGE", UserName+" moved image to trash with API. "+requestedID+", "+imageInfo.Name+", "+imageInfo.Location)
 		//Reply Success
-		ReplyWithJSON(responseWriter, request, GenericResponse{Result: "Successfully deleted image " + requestedID}, UserName)
+		ReplyWithJSON(responseWriter, request, GenericResponse{Result: "Successfully moved image " + requestedID + " to the trash"}, UserName)
 		return
 	}
 	ReplyWithJSONError(responseWriter, request, "Please specify ImageID", UserName, http.StatusBadRequest)
 }
 
+//ImageRestoreAPIRouter serves post requests to /api/Image/{ImageID}/Restore
+func ImageRestoreAPIRouter(responseWriter http.ResponseWriter, request *http.Request) {
+	//Validate Logon
+	UserAPIValidated, _, UserName := ValidateAndThrottleAPIUser(responseWriter, request)
+	if !UserAPIValidated {
+		return //User not logged in and was already handled
+	}
+	//Validate Permission to use api
+	UserAPIWriteValidated, permissions := ValidateAPIUserWriteAccess(responseWriter, request, UserName)
+	if !UserAPIWriteValidated {
+		return //User does not have API access and was already told
+	}
+	requestedID := mux.Vars(request)["ImageID"]
+	if permissions.HasPermission(interfaces.RemoveImage) != true {
+		ReplyWithJSONError(responseWriter, request, "You do not have permission to restore images", UserName, http.StatusForbidden)
+		go routers.WriteAuditLogByName(UserName, "RESTORE-IMAGE", UserName+" failed to restore image with API. Insufficient permissions. "+requestedID)
+		return
+	}
+
+	parsedID, err := strconv.ParseUint(requestedID, 10, 32)
+	if err != nil {
+		ReplyWithJSONError(responseWriter, request, "ImageID could not be parsed into a number", UserName, http.StatusBadRequest)
+		return
+	}
+	if err := database.DBInterface.RestoreImage(parsedID); err != nil {
+		ReplyWithJSONError(responseWriter, request, "No image by that ID in the trash", UserName, http.StatusNotFound)
+		return
+	}
+	go routers.WriteAuditLogByName(UserName, "RESTORE-IMAGE", UserName+" restored image from trash with API. "+requestedID)
+	ReplyWithJSON(responseWriter, request, GenericResponse{Result: "Successfully restored image " + requestedID}, UserName)
+}
+
+//ImageFilePutAPIRouter serves put requests to /api/Image/{ImageID}/File, replacing the file of an image while keeping the previous one as a revision
+func ImageFilePutAPIRouter(responseWriter http.ResponseWriter, request *http.Request) {
+	//Validate Logon
+	UserAPIValidated, UserID, UserName := ValidateAndThrottleAPIUser(responseWriter, request)
+	if !UserAPIValidated {
+		return //User not logged in and was already handled
+	}
+	//Validate Permission to use api
+	UserAPIWriteValidated, permissions := ValidateAPIUserWriteAccess(responseWriter, request, UserName)
+	if !UserAPIWriteValidated {
+		return //User does not have API access and was already told
+	}
+	imageInfo, valid := getModifiableImage(responseWriter, request, UserID, UserName, permissions)
+	if !valid {
+		return //Already replied
+	}
+
+	//Parse user upload JSON request
+	decoder := json.NewDecoder(request.Body)
+	var file routers.UploadingFile
+	if err := decoder.Decode(&file); err != nil || len(file.Data) == 0 {
+		ReplyWithJSONError(responseWriter, request, "Failed to parse request data", UserName, http.StatusBadRequest)
+		return
+	}
+	newLocation, err := routers.ReplaceImageFile(imageInfo, file.Name, bytes.NewReader(file.Data), int64(len(file.Data)), UserID)
+	if err != nil {
+		ReplyWithJSONError(responseWriter, request, err.Error(), UserName, http.StatusBadRequest)
+		go routers.WriteAuditLogByName(UserName, "REPLACE-IMAGEFILE", UserName+" failed to replace file of image with API. "+strconv.FormatUint(imageInfo.ID, 10)+", "+err.Error())
+		return
+	}
+	go routers.WriteAuditLogByName(UserName, "REPLACE-IMAGEFILE", UserName+" replaced file of image with API. "+strconv.FormatUint(imageInfo.ID, 10)+", "+imageInfo.Location+" replaced by "+newLocation)
+	replyWithImage(responseWriter, request, imageInfo.ID, UserName)
+}
+
+//ImageRevisionsGetAPIRouter serves get requests to /api/Image/{ImageID}/Revisions
+func ImageRevisionsGetAPIRouter(responseWriter http.ResponseWriter, request *http.Request) {
+	//Validate Logon
+	UserAPIValidated, UserID, UserName := ValidateAndThrottleAPIUser(responseWriter, request)
+	if !UserAPIValidated {
+		return //User not logged in and was already handled
+	}
+	parsedID, err := strconv.ParseUint(mux.Vars(request)["ImageID"], 10, 32)
+	if err != nil {
+		ReplyWithJSONError(responseWriter, request, "ImageID could not be parsed into a number", UserName, http.StatusBadRequest)
+		return
+	}
+	if _, found := getVisibleImage(responseWriter, request, parsedID, UserID, UserName); !found {
+		return //Already replied
+	}
+	revisions, err := database.DBInterface.GetImageRevisions(parsedID)
+	if err != nil {
+		ReplyWithJSONError(responseWriter, request, "Interal Database Error", UserName, http.StatusInternalServerError)
+		return
+	}
+	if revisions == nil {
+		revisions = []interfaces.ImageRevisionInformation{}
+	}
+	ReplyWithJSON(responseWriter, request, revisions, UserName)
+}
+
+//ImageRevisionRestoreAPIRouter serves post requests to /api/Image/{ImageID}/Revisions/{RevisionID}/Restore
+func ImageRevisionRestoreAPIRouter(responseWriter http.ResponseWriter, request *http.Request) {
+	//Validate Logon
+	UserAPIValidated, UserID, UserName := ValidateAndThrottleAPIUser(responseWriter, request)
+	if !UserAPIValidated {
+		return //User not logged in and was already handled
+	}
+	//Validate Permission to use api
+	UserAPIWriteValidated, permissions := ValidateAPIUserWriteAccess(responseWriter, request, UserName)
+	if !UserAPIWriteValidated {
+		return //User does not have API access and was already told
+	}
+	imageInfo, valid := getModifiableImage(responseWriter, request, UserID, UserName, permissions)
+	if !valid {
+		return //Already replied
+	}
+	revisionID, err := strconv.ParseUint(mux.Vars(request)["RevisionID"], 10, 64)
+	if err != nil {
+		ReplyWithJSONError(responseWriter, request, "RevisionID could not be parsed into a number", UserName, http.StatusBadRequest)
+		return
+	}
+	revision, err := database.DBInterface.GetImageRevision(revisionID)
+	if err != nil || revision.ImageID != imageInfo.ID {
+		ReplyWithJSONError(responseWriter, request, "No revision by that ID on this image", UserName, http.StatusNotFound)
+		return
+	}
+	if err := routers.RestoreImageRevision(imageInfo, revision, UserID); err != nil {
+		ReplyWithJSONError(responseWriter, request, "Interal Database Error", UserName, http.StatusInternalServerError)
+		go routers.WriteAuditLogByName(UserName, "REPLACE-IMAGEFILE", UserName+" failed to restore previous file of image with API. "+strconv.FormatUint(imageInfo.ID, 10)+", "+err.Error())
+		return
+	}
+	go routers.WriteAuditLogByName(UserName, "REPLACE-IMAGEFILE", UserName+" restored previous file of image with API. "+strconv.FormatUint(imageInfo.ID, 10)+", "+imageInfo.Location+" replaced by "+revision.Location)
+	replyWithImage(responseWriter, request, imageInfo.ID, UserName)
+}
+
+//ImageFavoritePostAPIRouter serves post requests to /api/Image/{ImageID}/Favorite, adding the image to the user's favorites
+func ImageFavoritePostAPIRouter(responseWriter http.ResponseWriter, request *http.Request) {
+	setImageFavorite(responseWriter, request, true)
+}
+
+//ImageFavoriteDeleteAPIRouter serves delete requests to /api/Image/{ImageID}/Favorite, removing the image from the user's favorites
+func ImageFavoriteDeleteAPIRouter(responseWriter http.ResponseWriter, request *http.Request) {
+	setImageFavorite(responseWriter, request, false)
+}
+
+//setImageFavorite adds or removes the image requested by ImageID from the user's favorites, then replies with the image
+func setImageFavorite(responseWriter http.ResponseWriter, request *http.Request, Favorite bool) {
+	//Validate Logon
+	UserAPIValidated, UserID, UserName := ValidateAndThrottleAPIUser(responseWriter, request)
+	if !UserAPIValidated {
+		return //User not logged in and was already handled
+	}
+	parsedID, err := strconv.ParseUint(mux.Vars(request)["ImageID"], 10, 32)
+	if err != nil {
+		ReplyWithJSONError(responseWriter, request, "ImageID could not be parsed into a number", UserName, http.StatusBadRequest)
+		return
+	}
+	imageInfo, found := getVisibleImage(responseWriter, request, parsedID, UserID, UserName)
+	if !found {
+		return //Already replied
+	}
+	//Removing a favorite is still allowed, so a trashed image can be cleared out of the list
+	if imageInfo.InTrash && Favorite {
+		ReplyWithJSONError(responseWriter, request, "Image is in the trash", UserName, http.StatusConflict)
+		return
+	}
+	if err := database.DBInterface.SetUserFavorite(UserID, parsedID, Favorite); err != nil {
+		ReplyWithJSONError(responseWriter, request, "Interal Database Error", UserName, http.StatusInternalServerError)
+		return
+	}
+	imageInfo, err = database.DBInterface.GetImage(parsedID)
+	if err != nil {
+		ReplyWithJSONError(responseWriter, request, "Interal Database Error", UserName, http.StatusInternalServerError)
+		return
+	}
+	imageInfo.UsersFavorite = Favorite
+	ReplyWithJSON(responseWriter, request, imageInfo, UserName)
+}
+
+//getModifiableImage returns the image requested by ImageID if the user may replace its file, otherwise replies with the error and returns false
+func getModifiableImage(responseWriter http.ResponseWriter, request *http.Request, UserID uint64, UserName string, permissions interfaces.UserPermission) (interfaces.ImageInformation, bool) {
+	requestedID := mux.Vars(request)["ImageID"]
+	parsedID, err := strconv.ParseUint(requestedID, 10, 32)
+	if err != nil {
+		ReplyWithJSONError(responseWriter, request, "ImageID could not be parsed into a number", UserName, http.StatusBadRequest)
+		return interfaces.ImageInformation{}, false
+	}
+	imageInfo, found := getVisibleImage(responseWriter, request, parsedID, UserID, UserName)
+	if !found {
+		return imageInfo, false
+	}
+	//The previous file is no longer shown, so replacing is treated as deleting it
+	if permissions.HasPermission(interfaces.RemoveImage) != true && (config.Configuration.UsersControlOwnObjects != true || imageInfo.UploaderID != UserID) {
+		ReplyWithJSONError(responseWriter, request, "You do not have permission to replace the file of that image", UserName, http.StatusForbidden)
+		go routers.WriteAuditLogByName(UserName, "REPLACE-IMAGEFILE", UserName+" failed to replace file of image with API. Insufficient permissions. "+requestedID)
+		return imageInfo, false
+	}
+	if imageInfo.InTrash {
+		ReplyWithJSONError(responseWriter, request, "Image is in the trash", UserName, http.StatusConflict)
+		return imageInfo, false
+	}
+	return imageInfo, true
+}
+
+//getVisibleImage returns an image, hiding it if it is waiting for approval and the user is neither its uploader nor may approve it, otherwise replies with the error and returns false
+func getVisibleImage(responseWriter http.ResponseWriter, request *http.Request, ImageID uint64, UserID uint64, UserName string) (interfaces.ImageInformation, bool) {
+	imageInfo, err := database.DBInterface.GetImage(ImageID)
+	if err == nil && imageInfo.Pending {
+		//Failing to get permissions leaves only the uploader able to see it
+		permissions, _ := database.DBInterface.GetUserPermissionSet(UserName)
+		if routers.CanSeePendingImage(imageInfo, UserID, permissions) == false {
+			err = sql.ErrNoRows
+		}
+	}
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ReplyWithJSONError(responseWriter, request, "No image by that ID", UserName, http.StatusNotFound)
+			return imageInfo, false
+		}
+		ReplyWithJSONError(responseWriter, request, "Interal Database Error", UserName, http.StatusInternalServerError)
+		return imageInfo, false
+	}
+	return imageInfo, true
+}
+
+//replyWithImage replies with the current information of an image
+func replyWithImage(responseWriter http.ResponseWriter, request *http.Request, ImageID uint64, UserName string) {
+	imageInfo, err := database.DBInterface.GetImage(ImageID)
+	if err != nil {
+		ReplyWithJSONError(responseWriter, request, "Interal Database Error", UserName, http.StatusInternalServerError)
+		return
+	}
+	ReplyWithJSON(responseWriter, request, imageInfo, UserName)
+}
+
 type uploadFileInput struct {
 	Tags       string
 	Source     string
@@ -0,0 +1,294 @@
+package api
+
+import (
+	"encoding/json"
+	"go-image-board/config"
+	"go-image-board/database"
+	"go-image-board/interfaces"
+	"go-image-board/routers"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"testing"
+)
+
+func TestImageGetAPIRouter(t *testing.T) {
+	fixture := seedDatabase(t)
+	server := newTestServer(t)
+	client := newTestClient(t, server, "viewer", "viewerpass")
+
+	var image interfaces.ImageInformation
+	client.getJSON(t, "/api/Image/"+strconv.FormatUint(fixture.Images["one"], 10), http.StatusOK, &image)
+	if image.Name != "one" || image.Location != "one.png" || image.UploaderName != "admin" {
+		t.Errorf("unexpected image returned %+v", image)
+	}
+	if image.ScoreTotal != 5 || image.ScoreVoters != 1 || image.ScoreAverage != 5 {
+		t.Errorf("unexpected score on image %+v", image)
+	}
+
+	client.getJSON(t, "/api/Image/999", http.StatusNotFound, nil)
+	client.getJSON(t, "/api/Image/notanumber", http.StatusBadRequest, nil)
+
+	anonymous := newTestClient(t, server, "", "")
+	anonymous.getJSON(t, "/api/Image/1", http.StatusUnauthorized, nil)
+}
+
+func TestImageDeleteAPIRouter(t *testing.T) {
+	fixture := seedDatabase(t)
+	server := newTestServer(t)
+	imagePath := "/api/Image/" + strconv.FormatUint(fixture.Images["three"], 10)
+
+	//Viewer has no API write access
+	viewer := newTestClient(t, server, "viewer", "viewerpass")
+	if response, body := viewer.do(t, "DELETE", imagePath, nil); response.StatusCode != http.StatusForbidden {
+		t.Fatalf("viewer delete returned %d: %s", response.StatusCode, body)
+	}
+
+	admin := newTestClient(t, server, "admin", "adminpass")
+	if response, body := admin.do(t, "DELETE", imagePath, nil); response.StatusCode != http.StatusOK {
+		t.Fatalf("admin delete returned %d: %s", response.StatusCode, body)
+	}
+	if response, _ := admin.do(t, "DELETE", "/api/Image/999", nil); response.StatusCode != http.StatusNotFound {
+		t.Errorf("deleting a missing image returned %d", response.StatusCode)
+	}
+
+	//The image is in the trash, only those who can restore it can see it, and it is not found by searches
+	viewer.getJSON(t, imagePath, http.StatusNotFound, nil)
+	var image interfaces.ImageInformation
+	admin.getJSON(t, imagePath, http.StatusOK, &image)
+	if image.InTrash == false || image.DeleterID != fixture.AdminID || image.DeleterName != "admin" {
+		t.Errorf("expected image in trash, got %+v", image)
+	}
+	var result ImageSearchResult
+	admin.getJSON(t, "/api/Images?SearchQuery=dog", http.StatusOK, &result)
+	if expected := fixture.fixtureImageIDs("two"); equalIDs(imageIDs(result.Images), expected) == false {
+		t.Errorf("search for dog returned %v, expected %v", imageIDs(result.Images), expected)
+	}
+
+	//Restoring returns it to searches
+	if response, body := viewer.do(t, "POST", imagePath+"/Restore", nil); response.StatusCode != http.StatusForbidden {
+		t.Errorf("viewer restore returned %d: %s", response.StatusCode, body)
+	}
+	if response, body := admin.do(t, "POST", imagePath+"/Restore", nil); response.StatusCode != http.StatusOK {
+		t.Fatalf("admin restore returned %d: %s", response.StatusCode, body)
+	}
+	if response, _ := admin.do(t, "POST", imagePath+"/Restore", nil); response.StatusCode != http.StatusNotFound {
+		t.Errorf("restoring an image not in the trash returned %d", response.StatusCode)
+	}
+	viewer.getJSON(t, imagePath, http.StatusOK, nil)
+
+	//Once purged, the image is gone for good
+	if response, body := admin.do(t, "DELETE", imagePath, nil); response.StatusCode != http.StatusOK {
+		t.Fatalf("admin delete returned %d: %s", response.StatusCode, body)
+	}
+	if err := routers.PurgeExpiredTrash(0); err != nil {
+		t.Fatalf("PurgeExpiredTrash: %v", err)
+	}
+	admin.getJSON(t, imagePath, http.StatusNotFound, nil)
+
+	//The collection keeps its other member, and loses the tag only the deleted image had
+	collection, err := database.DBInterface.GetCollection(fixture.CollectionID)
+	if err != nil || collection.Members != 1 {
+		t.Fatalf("expected collection with 1 member, got %+v, %v", collection, err)
+	}
+	collectionTags, _ := database.DBInterface.GetCollectionTags(fixture.CollectionID)
+	if len(collectionTags) != 1 || collectionTags[0].ID != fixture.Tags["cat"] {
+		t.Errorf("expected only cat on collection, got %+v", collectionTags)
+	}
+}
+
+func TestImageFilePutAPIRouter(t *testing.T) {
+	fixture := seedDatabase(t)
+	server := newTestServer(t)
+	imagePath := "/api/Image/" + strconv.FormatUint(fixture.Images["three"], 10)
+	newFile := routers.UploadingFile{Name: "replacement.png", Data: testPNG(t, 3)}
+
+	viewer := newTestClient(t, server, "viewer", "viewerpass")
+	if response, body := viewer.do(t, "PUT", imagePath+"/File", newFile); response.StatusCode != http.StatusForbidden {
+		t.Errorf("viewer replace returned %d: %s", response.StatusCode, body)
+	}
+
+	admin := newTestClient(t, server, "admin", "adminpass")
+	if response, body := admin.do(t, "PUT", imagePath+"/File", newFile); response.StatusCode != http.StatusOK {
+		t.Fatalf("admin replace returned %d: %s", response.StatusCode, body)
+	}
+	if response, _ := admin.do(t, "PUT", imagePath+"/File", newFile); response.StatusCode != http.StatusBadRequest {
+		t.Errorf("replacing with the same file returned %d", response.StatusCode)
+	}
+	if response, _ := admin.do(t, "PUT", "/api/Image/999/File", newFile); response.StatusCode != http.StatusNotFound {
+		t.Errorf("replacing a missing image returned %d", response.StatusCode)
+	}
+
+	//The image keeps its ID, tags and collection, only the file changes
+	var image interfaces.ImageInformation
+	admin.getJSON(t, imagePath, http.StatusOK, &image)
+	if image.ID != fixture.Images["three"] || image.Location == "three.png" || path.Ext(image.Location) != ".png" {
+		t.Errorf("unexpected image after replace %+v", image)
+	}
+	if tags, err := database.DBInterface.GetImageTags(fixture.Images["three"]); err != nil || len(tags) != 2 {
+		t.Errorf("expected image to keep its 2 tags, got %+v", tags)
+	}
+	if collection, err := database.DBInterface.GetCollection(fixture.CollectionID); err != nil || collection.Members != 2 {
+		t.Errorf("expected collection to keep 2 members, got %+v, %v", collection, err)
+	}
+
+	var revisions []interfaces.ImageRevisionInformation
+	viewer.getJSON(t, imagePath+"/Revisions", http.StatusOK, &revisions)
+	if len(revisions) != 1 || revisions[0].Location != "three.png" || revisions[0].ReplacerName != "admin" {
+		t.Fatalf("expected the previous file as a revision, got %+v", revisions)
+	}
+
+	//Restoring swaps the files, so the replacement becomes the revision
+	revisionPath := imagePath + "/Revisions/" + strconv.FormatUint(revisions[0].ID, 10) + "/Restore"
+	if response, body := viewer.do(t, "POST", revisionPath, nil); response.StatusCode != http.StatusForbidden {
+		t.Errorf("viewer restore returned %d: %s", response.StatusCode, body)
+	}
+	if response, body := admin.do(t, "POST", revisionPath, nil); response.StatusCode != http.StatusOK {
+		t.Fatalf("admin restore returned %d: %s", response.StatusCode, body)
+	}
+	if response, _ := admin.do(t, "POST", revisionPath, nil); response.StatusCode != http.StatusNotFound {
+		t.Errorf("restoring a used revision returned %d", response.StatusCode)
+	}
+	admin.getJSON(t, imagePath, http.StatusOK, &image)
+	if image.Location != "three.png" {
+		t.Errorf("expected previous file restored, got %+v", image)
+	}
+	admin.getJSON(t, imagePath+"/Revisions", http.StatusOK, &revisions)
+	if len(revisions) != 1 || revisions[0].Location == "three.png" {
+		t.Errorf("expected the replacement as a revision, got %+v", revisions)
+	}
+}
+
+func TestImageFavoriteAPIRouter(t *testing.T) {
+	fixture := seedDatabase(t)
+	server := newTestServer(t)
+	viewer := newTestClient(t, server, "viewer", "viewerpass")
+	admin := newTestClient(t, server, "admin", "adminpass")
+	threePath := "/api/Image/" + strconv.FormatUint(fixture.Images["three"], 10)
+
+	anonymous := newTestClient(t, server, "", "")
+	if response, body := anonymous.do(t, "POST", threePath+"/Favorite", nil); response.StatusCode != http.StatusUnauthorized {
+		t.Errorf("anonymous favorite returned %d: %s", response.StatusCode, body)
+	}
+	if response, body := viewer.do(t, "POST", "/api/Image/999/Favorite", nil); response.StatusCode != http.StatusNotFound {
+		t.Errorf("favorite of missing image returned %d: %s", response.StatusCode, body)
+	}
+
+	//Favorites need no API write access, and favoriting twice is the same as once
+	for _, client := range []*testClient{viewer, viewer, admin} {
+		if response, body := client.do(t, "POST", threePath+"/Favorite", nil); response.StatusCode != http.StatusOK {
+			t.Fatalf("favorite returned %d: %s", response.StatusCode, body)
+		}
+	}
+	if response, body := viewer.do(t, "POST", "/api/Image/"+strconv.FormatUint(fixture.Images["one"], 10)+"/Favorite", nil); response.StatusCode != http.StatusOK {
+		t.Fatalf("favorite returned %d: %s", response.StatusCode, body)
+	}
+	var image interfaces.ImageInformation
+	viewer.getJSON(t, threePath, http.StatusOK, &image)
+	if image.FavoriteCount != 2 || image.UsersFavorite == false {
+		t.Errorf("favorited image is %+v", image)
+	}
+
+	tests := map[string][]string{
+		"fav:viewer":       {"three", "one"},
+		"fav:me":           {"three", "one"},
+		"fav:me -cat":      nil,
+		"-fav:me":          {"five", "four", "two"},
+		"fav:admin":        {"three"},
+		"favcount:2":       {"three"},
+		"favcount:>0":      {"three", "one"},
+		"-favcount:>=1":    {"five", "four", "two"},
+		"fav:me OR dog":    {"three", "two", "one"},
+		"fav:doesnotexist": nil,
+	}
+	for query, expected := range tests {
+		var result ImageSearchResult
+		viewer.getJSON(t, "/api/Images?SearchQuery="+url.QueryEscape(query), http.StatusOK, &result)
+		if expectedIDs := fixture.fixtureImageIDs(expected...); equalIDs(imageIDs(result.Images), expectedIDs) == false {
+			t.Errorf("query %q returned %v, expected %v", query, imageIDs(result.Images), expectedIDs)
+		}
+	}
+
+	if response, body := viewer.do(t, "DELETE", threePath+"/Favorite", nil); response.StatusCode != http.StatusOK {
+		t.Fatalf("unfavorite returned %d: %s", response.StatusCode, body)
+	}
+	viewer.getJSON(t, threePath, http.StatusOK, &image)
+	if image.FavoriteCount != 1 || image.UsersFavorite {
+		t.Errorf("unfavorited image is %+v", image)
+	}
+
+	//Deleting an image removes it from everyone's favorites
+	if err := database.DBInterface.DeleteImage(fixture.Images["three"]); err != nil {
+		t.Fatal(err)
+	}
+	if favorite, err := database.DBInterface.GetUserFavorite(fixture.AdminID, fixture.Images["three"]); err != nil || favorite {
+		t.Errorf("deleted image is still a favorite, %v", err)
+	}
+}
+
+func TestImagePostAPIRouterModerated(t *testing.T) {
+	fixture := seedDatabase(t)
+	server := newTestServer(t)
+	config.Configuration.ModerateUploads = true
+	defer func() { config.Configuration.ModerateUploads = false }()
+	if err := database.DBInterface.SetUserPermissionSet(fixture.ViewerID, uint64(interfaces.APIWriteAccess|interfaces.UploadImage|interfaces.ModifyImageTags)); err != nil {
+		t.Fatalf("SetUserPermissionSet: %v", err)
+	}
+	if err := database.DBInterface.CreateUser("other", []byte("otherpass"), "other@example.com", 0); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	viewer := newTestClient(t, server, "viewer", "viewerpass")
+	admin := newTestClient(t, server, "admin", "adminpass")
+	other := newTestClient(t, server, "other", "otherpass")
+
+	upload := func(client *testClient, Width int) uint64 {
+		t.Helper()
+		var reply uploadFileReply
+		response, body := client.do(t, "POST", "/api/Image", map[string]interface{}{"Tags": "cat", "Files": []routers.UploadingFile{{Name: "new.png", Data: testPNG(t, Width)}}})
+		if response.StatusCode != http.StatusOK || json.Unmarshal(body, &reply) != nil || reply.LastID == 0 || reply.Errors != "" {
+			t.Fatalf("upload returned %d: %s", response.StatusCode, body)
+		}
+		return reply.LastID
+	}
+
+	//Uploads from users who cannot approve posts wait for a moderator
+	pendingID := upload(viewer, 11)
+	imagePath := "/api/Image/" + strconv.FormatUint(pendingID, 10)
+	var image interfaces.ImageInformation
+	for _, client := range []*testClient{viewer, admin} {
+		client.getJSON(t, imagePath, http.StatusOK, &image)
+		if image.Pending == false {
+			t.Errorf("uploaded image is %+v", image)
+		}
+	}
+	other.getJSON(t, imagePath, http.StatusNotFound, nil)
+	for _, path := range []string{"/Tags", "/TagHistory", "/Comments", "/Notes", "/Revisions"} {
+		other.getJSON(t, imagePath+path, http.StatusNotFound, nil)
+	}
+	if response, body := other.do(t, "POST", imagePath+"/Favorite", nil); response.StatusCode != http.StatusNotFound {
+		t.Errorf("favoriting a pending image returned %d: %s", response.StatusCode, body)
+	}
+	viewer.getJSON(t, imagePath+"/Tags", http.StatusOK, nil)
+	var images ImageSearchResult
+	admin.getJSON(t, "/api/Images?SearchQuery=cat", http.StatusOK, &images)
+	if equalIDs(imageIDs(images.Images), fixture.fixtureImageIDs("five", "three", "one")) == false {
+		t.Errorf("search returned %v", imageIDs(images.Images))
+	}
+
+	//Uploads from moderators are published at once
+	publishedID := upload(admin, 12)
+	admin.getJSON(t, "/api/Image/"+strconv.FormatUint(publishedID, 10), http.StatusOK, &image)
+	if image.Pending {
+		t.Errorf("moderator upload is %+v", image)
+	}
+
+	if err := database.DBInterface.SetImagePending(pendingID, false); err != nil {
+		t.Fatalf("SetImagePending: %v", err)
+	}
+	other.getJSON(t, imagePath, http.StatusOK, nil)
+	admin.getJSON(t, "/api/Images?SearchQuery=cat", http.StatusOK, &images)
+	if len(images.Images) != 5 {
+		t.Errorf("search after approval returned %v", imageIDs(images.Images))
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"go-image-board/database"
 	"go-image-board/interfaces"
 	"go-image-board/logging"
+	"go-image-board/plugins/tagquery"
 	"net/http"
 	"strconv"
 	"strings"
@@ -30,7 +31,7 @@ func ImagesGetAPIRouter(responseWriter http.ResponseWriter, request *http.Reques
 	pageStart, _ := strconv.ParseUint(request.FormValue("PageStart"), 10, 32) //Either parses fine, or is 0, both works
 	pageStride := config.Configuration.PageStride
 
-	userQTags, err := database.DBInterface.GetQueryTags(userQuery, false)
+	userQTags, err := database.DBInterface.GetQueryTags(tagquery.ResolveCurrentUser(userQuery, UserName), false)
 	if err == nil {
 		//add user's global filters to query
 		userFilterTags, err := database.DBInterface.GetUserFilterTags(UserID, false)
@@ -58,5 +59,9 @@ func ImagesGetAPIRouter(responseWriter http.ResponseWriter, request *http.Reques
 		return
 	}
 	logging.WriteLog(logging.LogLevelError, "imagequeries/ImagesAPIRouter", UserName, logging.ResultFailure, []string{"Failed to parse user query", err.Error()})
+	if queryErr, isQueryError := err.(interfaces.QueryError); isQueryError {
+		ReplyWithJSONError(responseWriter, request, queryErr.Message, UserName, http.StatusBadRequest)
+		return
+	}
 	ReplyWithJSONError(responseWriter, request, "failed to parse your query", UserName, http.StatusInternalServerError)
 }
@@ -0,0 +1,427 @@
+package api
+
+import (
+	"go-image-board/config"
+	"go-image-board/database"
+	"go-image-board/interfaces"
+	"go-image-board/plugins/tagquery"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestImagesGetAPIRouter(t *testing.T) {
+	fixture := seedDatabase(t)
+	server := newTestServer(t)
+	client := newTestClient(t, server, "admin", "adminpass")
+
+	tests := []struct {
+		query    string
+		expected []string
+	}{
+		{"", []string{"five", "four", "three", "two", "one"}},
+		{"cat", []string{"five", "three", "one"}},
+		{"cat dog", []string{"three"}},
+		{"cat -dog", []string{"five", "one"}},
+		{"-outdoor", []string{"five", "four", "three"}},
+		{"kitty", []string{"five", "three", "one"}},
+		{"-kitty", []string{"four", "two"}},
+		{"\"cat\" dog", []string{"three"}},
+		{"doesnotexist", []string{"five", "four", "three", "two", "one"}},
+		{"rating:explicit", []string{"two"}},
+		{"-rating:explicit", []string{"five", "four", "three", "one"}},
+		{"uploader:viewer", []string{"four"}},
+		{"score:>0", []string{"one"}},
+		{"scorevoters:0", []string{"five", "four", "three", "two"}},
+		{"incollection:y", []string{"five", "three"}},
+		{"incollection:n", []string{"four", "two", "one"}},
+		{"-incollection:y", []string{"four", "two", "one"}},
+		{"tagcount:2", []string{"three", "two", "one"}},
+		{"tagcount:<2", []string{"five"}},
+		{"similar:" + url.QueryEscape("1"), []string{"two", "one"}},
+		{"name:thre", []string{"three"}},
+		{"location:five.", []string{"five"}},
+		{"cat incollection:n", []string{"one"}},
+		{"cat OR dog", []string{"five", "three", "two", "one"}},
+		{"~outdoor ~dog", []string{"three", "two", "one"}},
+		{"(cat OR dog) -outdoor", []string{"five", "three"}},
+		{"-(cat OR dog)", []string{"four"}},
+		{"-(cat dog)", []string{"five", "four", "two", "one"}},
+		{"(cat -outdoor) OR (dog outdoor)", []string{"five", "three", "two"}},
+		{"kitty OR doesnotexist", []string{"five", "three", "one"}},
+		{"doesnotexist OR alsodoesnotexist", []string{"five", "four", "three", "two", "one"}},
+		{"rating:explicit OR incollection:y", []string{"five", "three", "two"}},
+		{"-(tagcount:>1 OR uploader:viewer)", []string{"five"}},
+	}
+	for _, test := range tests {
+		var result ImageSearchResult
+		client.getJSON(t, "/api/Images?SearchQuery="+url.QueryEscape(test.query), http.StatusOK, &result)
+		expectedIDs := fixture.fixtureImageIDs(test.expected...)
+		if equalIDs(imageIDs(result.Images), expectedIDs) == false || result.ResultCount != uint64(len(expectedIDs)) {
+			t.Errorf("query %q returned %v (count %d), expected %v", test.query, imageIDs(result.Images), result.ResultCount, expectedIDs)
+		}
+	}
+}
+
+func TestImagesGetAPIRouterCategories(t *testing.T) {
+	fixture := seedDatabase(t)
+	if err := database.DBInterface.UpdateTag(fixture.Tags["cat"], "cat", "The cat tag", interfaces.TagCategoryCharacter, 0, false, fixture.AdminID); err != nil {
+		t.Fatalf("UpdateTag: %v", err)
+	}
+	server := newTestServer(t)
+	client := newTestClient(t, server, "admin", "adminpass")
+
+	tests := []struct {
+		query    string
+		expected []string
+	}{
+		{"character:*", []string{"five", "three", "one"}},
+		{"-character:*", []string{"four", "two"}},
+		{"artist:*", nil},
+		{"character:cat", []string{"five", "three", "one"}},
+		{"charactertags:1", []string{"five", "three", "one"}},
+		{"charactertags:0", []string{"four", "two"}},
+		{"generaltags:>1", []string{"two"}},
+		{"character:* dog", []string{"three"}},
+	}
+	for _, test := range tests {
+		var result ImageSearchResult
+		client.getJSON(t, "/api/Images?SearchQuery="+url.QueryEscape(test.query), http.StatusOK, &result)
+		expectedIDs := fixture.fixtureImageIDs(test.expected...)
+		if equalIDs(imageIDs(result.Images), expectedIDs) == false || result.ResultCount != uint64(len(expectedIDs)) {
+			t.Errorf("query %q returned %v (count %d), expected %v", test.query, imageIDs(result.Images), result.ResultCount, expectedIDs)
+		}
+	}
+
+	//A category prefix on a new tag carries the category through to tag creation
+	queryTags, err := database.DBInterface.GetQueryTags("artist:new_artist", false)
+	if err != nil || len(queryTags) != 1 || queryTags[0].Name != "new_artist" || queryTags[0].Category != interfaces.TagCategoryArtist || queryTags[0].Exists {
+		t.Errorf("GetQueryTags returned %+v, %v", queryTags, err)
+	}
+}
+
+func TestImagesGetAPIRouterPatterns(t *testing.T) {
+	fixture := seedDatabase(t)
+	server := newTestServer(t)
+	client := newTestClient(t, server, "admin", "adminpass")
+
+	tests := []struct {
+		query    string
+		expected []string
+	}{
+		{"ca*", []string{"five", "three", "one"}},
+		{"CA*", []string{"five", "three", "one"}},
+		{"*t", []string{"five", "three", "one"}},
+		{"k*", []string{"five", "three", "one"}},
+		{"*o*", []string{"three", "two", "one"}},
+		{"-*o*", []string{"five", "four"}},
+		{"c*t dog", []string{"three"}},
+		{"(d* OR ca*) -out*", []string{"five", "three"}},
+		{"-(do* OR *door)", []string{"five", "four"}},
+		{"nothing*", []string{"five", "four", "three", "two", "one"}},
+		{"_*", []string{"five", "four", "three", "two", "one"}},
+	}
+	for _, test := range tests {
+		var result ImageSearchResult
+		client.getJSON(t, "/api/Images?SearchQuery="+url.QueryEscape(test.query), http.StatusOK, &result)
+		expectedIDs := fixture.fixtureImageIDs(test.expected...)
+		if equalIDs(imageIDs(result.Images), expectedIDs) == false || result.ResultCount != uint64(len(expectedIDs)) {
+			t.Errorf("query %q returned %v (count %d), expected %v", test.query, imageIDs(result.Images), result.ResultCount, expectedIDs)
+		}
+	}
+
+	//Patterns matching more than MaxWildcardTags are refused with a message the user can act on
+	oldLimit := config.Configuration.MaxWildcardTags
+	config.Configuration.MaxWildcardTags = 2
+	defer func() { config.Configuration.MaxWildcardTags = oldLimit }()
+	var result ImageSearchResult
+	client.getJSON(t, "/api/Images?SearchQuery="+url.QueryEscape("*o*"), http.StatusOK, &result)
+	if len(result.Images) != 3 {
+		t.Errorf("pattern at the limit returned %v", imageIDs(result.Images))
+	}
+	var errorResult ErrorResponse
+	client.getJSON(t, "/api/Images?SearchQuery="+url.QueryEscape("cat (dog OR *)"), http.StatusBadRequest, &errorResult)
+	if strings.Contains(errorResult.Error, "more than 2 tags") == false {
+		t.Errorf("unexpected error %q", errorResult.Error)
+	}
+}
+
+func TestImagesGetAPIRouterOrder(t *testing.T) {
+	fixture := seedDatabase(t)
+	db := database.DBInterface
+	//Average scores are one 5, three 3, two 1, the rest 0
+	for name, score := range map[string]int64{"three": 3, "two": 1} {
+		if err := db.UpdateUserVoteScore(fixture.AdminID, fixture.Images[name], score); err != nil {
+			t.Fatalf("UpdateUserVoteScore: %v", err)
+		}
+		//The SQL plugins total scores in the background
+		if err := db.UpdateScoreOnImage(fixture.Images[name]); err != nil {
+			t.Fatalf("UpdateScoreOnImage: %v", err)
+		}
+	}
+	server := newTestServer(t)
+	client := newTestClient(t, server, "admin", "adminpass")
+
+	tests := []struct {
+		query    string
+		expected []string
+	}{
+		{"order:id", []string{"five", "four", "three", "two", "one"}},
+		{"order:id_asc", []string{"one", "two", "three", "four", "five"}},
+		{"order:score", []string{"one", "three", "two", "five", "four"}},
+		{"order:score_asc", []string{"four", "five", "two", "three", "one"}},
+		{"order:totalscore_desc", []string{"one", "three", "two", "five", "four"}},
+		{"order:scorevoters", []string{"three", "two", "one", "five", "four"}},
+		{"order:uploaded_asc", []string{"one", "two", "three", "four", "five"}},
+		{"order:tagcount", []string{"three", "two", "one", "five", "four"}},
+		{"order:tagcount_asc", []string{"four", "five", "one", "two", "three"}},
+		{"cat order:score", []string{"one", "three", "five"}},
+		{"order:score_asc -dog", []string{"four", "five", "one"}},
+		{"order:score_asc order:id", []string{"five", "four", "three", "two", "one"}},
+		{"(cat OR dog) order:scorevoters_asc", []string{"five", "one", "two", "three"}},
+		{"order:nonsense", []string{"five", "four", "three", "two", "one"}},
+	}
+	for _, test := range tests {
+		var result ImageSearchResult
+		client.getJSON(t, "/api/Images?SearchQuery="+url.QueryEscape(test.query), http.StatusOK, &result)
+		expectedIDs := fixture.fixtureImageIDs(test.expected...)
+		if equalIDs(imageIDs(result.Images), expectedIDs) == false || result.ResultCount != uint64(len(expectedIDs)) {
+			t.Errorf("query %q returned %v (count %d), expected %v", test.query, imageIDs(result.Images), result.ResultCount, expectedIDs)
+		}
+	}
+
+	//A seeded random order is the same every time, so it can be paged through
+	expectedIDs := fixture.fixtureImageIDs("one", "two", "three", "four", "five")
+	sort.Slice(expectedIDs, func(i, j int) bool {
+		return tagquery.RandomOrderValue(expectedIDs[i], 7) > tagquery.RandomOrderValue(expectedIDs[j], 7)
+	})
+	oldStride := config.Configuration.PageStride
+	config.Configuration.PageStride = 2
+	defer func() { config.Configuration.PageStride = oldStride }()
+	var randomIDs []uint64
+	for pageStart := 0; pageStart < 5; pageStart += 2 {
+		var result ImageSearchResult
+		client.getJSON(t, "/api/Images?SearchQuery=order:random_7&PageStart="+strconv.Itoa(pageStart), http.StatusOK, &result)
+		randomIDs = append(randomIDs, imageIDs(result.Images)...)
+	}
+	if equalIDs(randomIDs, expectedIDs) == false {
+		t.Errorf("random order returned %v, expected %v", randomIDs, expectedIDs)
+	}
+
+	//Navigating between images follows the order
+	tags, err := db.GetQueryTags("order:score", false)
+	if err != nil {
+		t.Fatalf("GetQueryTags: %v", err)
+	}
+	prevNext, err := db.GetPrevNexImages(tags, fixture.Images["three"])
+	if expected := fixture.fixtureImageIDs("one", "two"); err != nil || equalIDs(imageIDs(prevNext), expected) == false {
+		t.Errorf("GetPrevNexImages returned %v, %v, expected %v", imageIDs(prevNext), err, expected)
+	}
+	tags, err = db.GetQueryTags("outdoor order:tagcount_asc", false)
+	if err != nil {
+		t.Fatalf("GetQueryTags: %v", err)
+	}
+	prevNext, err = db.GetPrevNexImages(tags, fixture.Images["two"])
+	if expected := fixture.fixtureImageIDs("one"); err != nil || equalIDs(imageIDs(prevNext), expected) == false {
+		t.Errorf("GetPrevNexImages returned %v, %v, expected %v", imageIDs(prevNext), err, expected)
+	}
+}
+
+func TestImagesGetAPIRouterDates(t *testing.T) {
+	fixture := seedDatabase(t)
+	server := newTestServer(t)
+	client := newTestClient(t, server, "admin", "adminpass")
+	thisYear := time.Now().UTC().Format("2006")
+
+	//Everything in the fixture was uploaded and tagged just now, and only image one has a vote
+	tests := []struct {
+		query    string
+		expected []string
+	}{
+		{"uploaded:<1d", []string{"five", "four", "three", "two", "one"}},
+		{"uploaded:1d", []string{"five", "four", "three", "two", "one"}},
+		{"uploaded:>1d", nil},
+		{"-uploaded:<1d", nil},
+		{"uploaded:" + thisYear, []string{"five", "four", "three", "two", "one"}},
+		{"uploaded:>2000-06", []string{"five", "four", "three", "two", "one"}},
+		{"uploaded:<=2000", nil},
+		{"-uploaded:2000-01-01", []string{"five", "four", "three", "two", "one"}},
+		{"tagged:<1h", []string{"five", "three", "two", "one"}},
+		{"-tagged:<1h", []string{"four"}},
+		{"voted:<1w", []string{"one"}},
+		{"-voted:<1w", []string{"five", "four", "three", "two"}},
+		{"voted:>1w", nil},
+		{"cat (voted:<1w OR -tagged:" + thisYear + ")", []string{"one"}},
+		{"uploaded:yesterday", []string{"five", "four", "three", "two", "one"}},
+	}
+	for _, test := range tests {
+		var result ImageSearchResult
+		client.getJSON(t, "/api/Images?SearchQuery="+url.QueryEscape(test.query), http.StatusOK, &result)
+		expectedIDs := fixture.fixtureImageIDs(test.expected...)
+		if equalIDs(imageIDs(result.Images), expectedIDs) == false || result.ResultCount != uint64(len(expectedIDs)) {
+			t.Errorf("query %q returned %v (count %d), expected %v", test.query, imageIDs(result.Images), result.ResultCount, expectedIDs)
+		}
+	}
+}
+
+func TestImagesGetAPIRouterMetadata(t *testing.T) {
+	fixture := seedDatabase(t)
+	server := newTestServer(t)
+	client := newTestClient(t, server, "admin", "adminpass")
+
+	//Image four has no metadata, as if uploaded before it was captured
+	metadata := map[string]interfaces.ImageMetadata{
+		"one":   {Width: 1920, Height: 1080, FileSize: 2097152, MIMEType: "image/png"},
+		"two":   {Width: 1366, Height: 768, FileSize: 307200, MIMEType: "image/jpeg"},
+		"three": {Width: 1280, Height: 720, FileSize: 10485760, MIMEType: "video/mp4", Duration: 95.5},
+		"five":  {FileSize: 4194304, MIMEType: "audio/mpeg", Duration: 30},
+	}
+	for name, imageMetadata := range metadata {
+		if err := database.DBInterface.SetImageMetadata(fixture.Images[name], imageMetadata); err != nil {
+			t.Fatalf("SetImageMetadata %s: %v", name, err)
+		}
+	}
+
+	var image interfaces.ImageInformation
+	client.getJSON(t, "/api/Image/"+strconv.FormatUint(fixture.Images["three"], 10), http.StatusOK, &image)
+	if image.Width != 1280 || image.Height != 720 || image.FileSize != 10485760 || image.MIMEType != "video/mp4" || image.Duration != 95.5 {
+		t.Errorf("unexpected metadata on image %+v", image)
+	}
+
+	tests := []struct {
+		query    string
+		expected []string
+	}{
+		{"width:>=1366", []string{"two", "one"}},
+		{"height:720", []string{"three"}},
+		{"-width:>1000", []string{"five", "four"}},
+		{"ratio:16x9", []string{"three", "two", "one"}},
+		{"ratio:>1.8", nil},
+		{"ratio:<=16/9", []string{"three", "two", "one"}},
+		{"-ratio:16x9", nil},
+		{"filesize:>1mb", []string{"five", "three", "one"}},
+		{"filesize:<=300kb", []string{"four", "two"}},
+		{"duration:>1m", []string{"three"}},
+		{"duration:>0", []string{"five", "three"}},
+		{"type:video", []string{"three"}},
+		{"type:image", []string{"two", "one"}},
+		{"type:IMAGE/PNG", []string{"one"}},
+		{"-type:image", []string{"five", "four", "three"}},
+		{"type:audio OR type:video", []string{"five", "three"}},
+		{"cat type:image", []string{"one"}},
+	}
+	for _, test := range tests {
+		var result ImageSearchResult
+		client.getJSON(t, "/api/Images?SearchQuery="+url.QueryEscape(test.query), http.StatusOK, &result)
+		expectedIDs := fixture.fixtureImageIDs(test.expected...)
+		if equalIDs(imageIDs(result.Images), expectedIDs) == false || result.ResultCount != uint64(len(expectedIDs)) {
+			t.Errorf("query %q returned %v (count %d), expected %v", test.query, imageIDs(result.Images), result.ResultCount, expectedIDs)
+		}
+	}
+}
+
+func TestImagesGetAPIRouterGroupsPrevNext(t *testing.T) {
+	fixture := seedDatabase(t)
+	db := database.DBInterface
+
+	//Navigation from image three follows the grouped search, skipping images two and four
+	tags, err := db.GetQueryTags("cat OR (dog -outdoor)", false)
+	if err != nil {
+		t.Fatalf("GetQueryTags: %v", err)
+	}
+	prevNext, err := db.GetPrevNexImages(tags, fixture.Images["three"])
+	if err != nil {
+		t.Fatalf("GetPrevNexImages: %v", err)
+	}
+	if expected := fixture.fixtureImageIDs("five", "one"); equalIDs(imageIDs(prevNext), expected) == false {
+		t.Errorf("GetPrevNexImages returned %v, expected %v", imageIDs(prevNext), expected)
+	}
+
+	tags, err = db.GetQueryTags("-(cat OR dog)", false)
+	if err != nil {
+		t.Fatalf("GetQueryTags: %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		image, resultCount, err := db.GetRandomImage(tags)
+		if err != nil || image.ID != fixture.Images["four"] || resultCount != 1 {
+			t.Fatalf("GetRandomImage returned %d (count %d), %v", image.ID, resultCount, err)
+		}
+	}
+}
+
+func TestImagesGetAPIRouterPaging(t *testing.T) {
+	fixture := seedDatabase(t)
+	server := newTestServer(t)
+	client := newTestClient(t, server, "admin", "adminpass")
+
+	oldStride := config.Configuration.PageStride
+	config.Configuration.PageStride = 2
+	defer func() { config.Configuration.PageStride = oldStride }()
+
+	pages := map[string][]string{
+		"0": {"five", "four"},
+		"2": {"three", "two"},
+		"4": {"one"},
+		"6": nil,
+	}
+	for pageStart, expected := range pages {
+		var result ImageSearchResult
+		client.getJSON(t, "/api/Images?PageStart="+pageStart, http.StatusOK, &result)
+		if equalIDs(imageIDs(result.Images), fixture.fixtureImageIDs(expected...)) == false || result.ResultCount != 5 || result.ServerStride != 2 {
+			t.Errorf("page %s returned %v (count %d, stride %d), expected %v", pageStart, imageIDs(result.Images), result.ResultCount, result.ServerStride, expected)
+		}
+	}
+}
+
+func TestImagesGetAPIRouterUserFilter(t *testing.T) {
+	fixture := seedDatabase(t)
+	if err := database.DBInterface.SetUserQueryTags(fixture.ViewerID, "-dog"); err != nil {
+		t.Fatalf("SetUserQueryTags: %v", err)
+	}
+	server := newTestServer(t)
+
+	var result ImageSearchResult
+	viewer := newTestClient(t, server, "viewer", "viewerpass")
+	viewer.getJSON(t, "/api/Images", http.StatusOK, &result)
+	if expected := fixture.fixtureImageIDs("five", "four", "one"); equalIDs(imageIDs(result.Images), expected) == false {
+		t.Errorf("filtered search returned %v, expected %v", imageIDs(result.Images), expected)
+	}
+	//Filters are merged with the query, not replaced by it
+	viewer.getJSON(t, "/api/Images?SearchQuery=cat", http.StatusOK, &result)
+	if expected := fixture.fixtureImageIDs("five", "one"); equalIDs(imageIDs(result.Images), expected) == false {
+		t.Errorf("filtered search for cat returned %v, expected %v", imageIDs(result.Images), expected)
+	}
+
+	//Other users are unaffected
+	admin := newTestClient(t, server, "admin", "adminpass")
+	admin.getJSON(t, "/api/Images", http.StatusOK, &result)
+	if result.ResultCount != 5 {
+		t.Errorf("unfiltered user got %d results, expected 5", result.ResultCount)
+	}
+}
+
+func TestImagesGetAPIRouterRandom(t *testing.T) {
+	fixture := seedDatabase(t)
+	server := newTestServer(t)
+	client := newTestClient(t, server, "admin", "adminpass")
+
+	var result ImageSearchResult
+	client.getJSON(t, "/api/Images?SearchType=random&SearchQuery="+url.QueryEscape("cat dog"), http.StatusOK, &result)
+	if len(result.Images) != 1 || result.Images[0].ID != fixture.Images["three"] || result.ResultCount != 1 {
+		t.Errorf("random search returned %+v", result)
+	}
+
+	allowed := map[uint64]bool{fixture.Images["one"]: true, fixture.Images["three"]: true, fixture.Images["five"]: true}
+	for i := 0; i < 10; i++ {
+		client.getJSON(t, "/api/Images?SearchType=random&SearchQuery=cat", http.StatusOK, &result)
+		if len(result.Images) != 1 || allowed[result.Images[0].ID] == false || result.ResultCount != 3 {
+			t.Fatalf("random search for cat returned %+v", result)
+		}
+	}
+
+	client.getJSON(t, "/api/Images?SearchType=random&SearchQuery="+url.QueryEscape("cat rating:explicit"), http.StatusInternalServerError, nil)
+}
@@ -22,7 +22,7 @@ type ImageTagGetResult struct {
 //ImageTagsGetAPIRouter serves get requests to /api/Image/{ImageID}/Tags
 func ImageTagsGetAPIRouter(responseWriter http.ResponseWriter, request *http.Request) {
 	//Validate Logon
-	UserAPIValidated, _, UserName := ValidateAndThrottleAPIUser(responseWriter, request)
+	UserAPIValidated, UserID, UserName := ValidateAndThrottleAPIUser(responseWriter, request)
 	if !UserAPIValidated {
 		return //User not logged in and was already handled
 	}
@@ -38,14 +38,8 @@ func ImageTagsGetAPIRouter(responseWriter http.ResponseWriter, request *http.Req
 			ReplyWithJSONError(responseWriter, request, "ImageID could not be parsed into a number", UserName, http.StatusBadRequest)
 			return
 		}
-		_, err = database.DBInterface.GetImage(parsedID)
-		if err != nil {
-			if err == sql.ErrNoRows {
-				ReplyWithJSONError(responseWriter, request, "No image by that ID", UserName, http.StatusNotFound)
-				return
-			}
-			ReplyWithJSONError(responseWriter, request, "Interal Database Error", UserName, http.StatusInternalServerError)
-			return
+		if _, found := getVisibleImage(responseWriter, request, parsedID, UserID, UserName); !found {
+			return //Already replied
 		}
 		tags, err := database.DBInterface.GetImageTags(parsedID)
 		if err != nil {
@@ -96,14 +90,9 @@ func ImageTagsDeleteAPIRouter(responseWriter http.ResponseWriter, request *http.
 		}
 
 		//Get Image info
-		imageInfo, err := database.DBInterface.GetImage(parsedID)
-		if err != nil {
-			if err == sql.ErrNoRows {
-				ReplyWithJSONError(responseWriter, request, "No image by that ID", UserName, http.StatusNotFound)
-				return
-			}
-			ReplyWithJSONError(responseWriter, request, "Interal Database Error", UserName, http.StatusInternalServerError)
-			return
+		imageInfo, found := getVisibleImage(responseWriter, request, parsedID, UserID, UserName)
+		if !found {
+			return //Already replied
 		}
 
 		//Validate delete permissions
@@ -115,7 +104,7 @@ func ImageTagsDeleteAPIRouter(responseWriter http.ResponseWriter, request *http.
 
 		//Delete tag
 		//Permission validated, now delete (ImageTags and Images)
-		if err := database.DBInterface.RemoveTag(parsedTagID, parsedID); err != nil {
+		if err := database.DBInterface.RemoveTag(parsedTagID, parsedID, UserID); err != nil {
 			ReplyWithJSONError(responseWriter, request, "Interal Database Error", UserName, http.StatusInternalServerError)
 			go routers.WriteAuditLogByName(UserName, "DELETE-IMAGE", UserName+" failed to delete image tag with API. "+requestedID+", "+requestedTagID+", "+err.Error())
 			return //Cancel delete
@@ -157,14 +146,9 @@ func ImageTagsPostAPIRouter(responseWriter http.ResponseWriter, request *http.Re
 		}
 
 		//Get Image info
-		imageInfo, err := database.DBInterface.GetImage(parsedID)
-		if err != nil {
-			if err == sql.ErrNoRows {
-				ReplyWithJSONError(responseWriter, request, "No image by that ID", UserName, http.StatusNotFound)
-				return
-			}
-			ReplyWithJSONError(responseWriter, request, "Interal Database Error", UserName, http.StatusInternalServerError)
-			return
+		imageInfo, found := getVisibleImage(responseWriter, request, parsedID, UserID, UserName)
+		if !found {
+			return //Already replied
 		}
 
 		//Verify user can modify image tags
@@ -208,7 +192,7 @@ func ImageTagsPostAPIRouter(responseWriter http.ResponseWriter, request *http.Re
 					warnings += "Unable to use tag " + tag.Name + " due to insufficient permissions of user to create tags. "
 					// /ValidatePermission
 				} else {
-					tagID, err := database.DBInterface.NewTag(tag.Name, tag.Description, UserID)
+					tagID, err := database.DBInterface.NewTag(tag.Name, tag.Description, tag.Category, UserID)
 					if err != nil {
 						go routers.WriteAuditLog(UserID, "CREATE-TAG", UserName+" failed to create tag ("+tag.Name+"). No database error. "+err.Error())
 						warnings += "Unable to use tag (" + tag.Name + ") due to a database error. "
@@ -234,3 +218,73 @@ func ImageTagsPostAPIRouter(responseWriter http.ResponseWriter, request *http.Re
 		ReplyWithJSON(responseWriter, request, uploadReply, UserName)
 	}
 }
+
+//ImageTagHistoryGetAPIRouter serves get requests to /api/Image/{ImageID}/TagHistory
+func ImageTagHistoryGetAPIRouter(responseWriter http.ResponseWriter, request *http.Request) {
+	//Validate Logon
+	UserAPIValidated, UserID, UserName := ValidateAndThrottleAPIUser(responseWriter, request)
+	if !UserAPIValidated {
+		return //User not logged in and was already handled
+	}
+	parsedID, err := strconv.ParseUint(mux.Vars(request)["ImageID"], 10, 32)
+	if err != nil {
+		ReplyWithJSONError(responseWriter, request, "ImageID could not be parsed into a number", UserName, http.StatusBadRequest)
+		return
+	}
+	if _, found := getVisibleImage(responseWriter, request, parsedID, UserID, UserName); !found {
+		return //Already replied
+	}
+	history, err := database.DBInterface.GetImageTagHistory(parsedID)
+	if err != nil {
+		ReplyWithJSONError(responseWriter, request, "Interal Database Error", UserName, http.StatusInternalServerError)
+		return
+	}
+	if history == nil {
+		history = []interfaces.TagChangeInformation{}
+	}
+	ReplyWithJSON(responseWriter, request, history, UserName)
+}
+
+//ImageTagHistoryRevertAPIRouter serves post requests to /api/Image/{ImageID}/TagHistory/{ChangeID}/Revert, undoing every tag change made after ChangeID
+func ImageTagHistoryRevertAPIRouter(responseWriter http.ResponseWriter, request *http.Request) {
+	//Validate Logon
+	UserAPIValidated, UserID, UserName := ValidateAndThrottleAPIUser(responseWriter, request)
+	if !UserAPIValidated {
+		return //User not logged in and was already handled
+	}
+	//Validate Permission to use api
+	UserAPIWriteValidated, permissions := ValidateAPIUserWriteAccess(responseWriter, request, UserName)
+	if !UserAPIWriteValidated {
+		return //User does not have API access and was already told
+	}
+	urlVariables := mux.Vars(request)
+	parsedID, err := strconv.ParseUint(urlVariables["ImageID"], 10, 32)
+	if err != nil {
+		ReplyWithJSONError(responseWriter, request, "ImageID could not be parsed into a number", UserName, http.StatusBadRequest)
+		return
+	}
+	changeID, err := strconv.ParseUint(urlVariables["ChangeID"], 10, 64)
+	if err != nil {
+		ReplyWithJSONError(responseWriter, request, "ChangeID could not be parsed into a number", UserName, http.StatusBadRequest)
+		return
+	}
+	//Reverting undoes changes made by others
+	if interfaces.UserPermission(permissions).HasPermission(interfaces.BulkTagOperations) != true {
+		ReplyWithJSONError(responseWriter, request, "You do not have permission to revert the tags of images", UserName, http.StatusForbidden)
+		go routers.WriteAuditLog(UserID, "REVERT-IMAGETAGS", UserName+" failed to revert image tags with API. Insufficient permissions. "+urlVariables["ImageID"])
+		return
+	}
+	undone, err := routers.RevertImageTags(parsedID, changeID, UserID)
+	if err != nil {
+		ReplyWithJSONError(responseWriter, request, "No change by that ID on this image", UserName, http.StatusNotFound)
+		go routers.WriteAuditLog(UserID, "REVERT-IMAGETAGS", UserName+" failed to revert image tags with API. "+urlVariables["ImageID"]+", "+urlVariables["ChangeID"]+", "+err.Error())
+		return
+	}
+	go routers.WriteAuditLog(UserID, "REVERT-IMAGETAGS", UserName+" reverted image tags with API. "+urlVariables["ImageID"]+" to change "+urlVariables["ChangeID"]+", "+strconv.FormatUint(undone, 10)+" changes undone")
+	tags, err := database.DBInterface.GetImageTags(parsedID)
+	if err != nil {
+		ReplyWithJSONError(responseWriter, request, "Interal Database Error", UserName, http.StatusInternalServerError)
+		return
+	}
+	ReplyWithJSON(responseWriter, request, ImageTagGetResult{Tags: tags, ResultCount: len(tags)}, UserName)
+}
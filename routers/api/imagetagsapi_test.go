@@ -0,0 +1,62 @@
+package api
+
+import (
+	"encoding/json"
+	"go-image-board/database"
+	"go-image-board/interfaces"
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+func TestImageTagHistoryAPIRouter(t *testing.T) {
+	fixture := seedDatabase(t)
+	server := newTestServer(t)
+	imageID := fixture.Images["one"]
+	historyPath := "/api/Image/" + strconv.FormatUint(imageID, 10) + "/TagHistory"
+
+	if err := database.DBInterface.RemoveTag(fixture.Tags["outdoor"], imageID, fixture.ViewerID); err != nil {
+		t.Fatalf("RemoveTag: %v", err)
+	}
+	if err := database.DBInterface.AddTag([]uint64{fixture.Tags["kitty"], fixture.Tags["dog"]}, imageID, fixture.ViewerID); err != nil {
+		t.Fatalf("AddTag: %v", err)
+	}
+
+	//Newest first, the alias is recorded as the tag it aliases and cat is not recorded again
+	viewer := newTestClient(t, server, "viewer", "viewerpass")
+	var history []interfaces.TagChangeInformation
+	viewer.getJSON(t, historyPath, http.StatusOK, &history)
+	if len(history) != 4 {
+		t.Fatalf("expected 4 changes, got %+v", history)
+	}
+	if history[0].TagName != "dog" || history[0].Added == false || history[0].UserName != "viewer" {
+		t.Errorf("unexpected newest change %+v", history[0])
+	}
+	if history[1].TagName != "outdoor" || history[1].Added || history[1].Operation != interfaces.TagChangeRemove {
+		t.Errorf("unexpected removal %+v", history[1])
+	}
+
+	revertPath := historyPath + "/" + strconv.FormatUint(history[2].ID, 10) + "/Revert"
+	if response, body := viewer.do(t, "POST", revertPath, nil); response.StatusCode != http.StatusForbidden {
+		t.Errorf("viewer revert returned %d: %s", response.StatusCode, body)
+	}
+	admin := newTestClient(t, server, "admin", "adminpass")
+	if response, _ := admin.do(t, "POST", historyPath+"/999/Revert", nil); response.StatusCode != http.StatusNotFound {
+		t.Errorf("reverting to a missing change returned %d", response.StatusCode)
+	}
+	var result ImageTagGetResult
+	response, body := admin.do(t, "POST", revertPath, nil)
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("admin revert returned %d: %s", response.StatusCode, body)
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		t.Fatalf("revert returned invalid JSON: %v: %s", err, body)
+	}
+	if result.ResultCount != 2 {
+		t.Errorf("expected the 2 original tags after revert, got %+v", result.Tags)
+	}
+	viewer.getJSON(t, historyPath, http.StatusOK, &history)
+	if len(history) != 6 || history[0].Operation != interfaces.TagChangeRevert || history[0].UserName != "admin" {
+		t.Errorf("expected the revert recorded in the history, got %+v", history)
+	}
+}
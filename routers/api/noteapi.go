@@ -0,0 +1,339 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"go-image-board/database"
+	"go-image-board/interfaces"
+	"go-image-board/routers"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+//postNoteInput is the region and body of a note. ImageWidth and ImageHeight may be left 0 to use the dimensions of the image
+type postNoteInput struct {
+	interfaces.NoteRegion
+	Body string
+}
+
+type rotateNotesInput struct {
+	Degrees int64
+}
+
+//NotesGetAPIRouter serves get requests to /api/Image/{ImageID}/Notes
+func NotesGetAPIRouter(responseWriter http.ResponseWriter, request *http.Request) {
+	//Validate Logon
+	UserAPIValidated, UserID, UserName := ValidateAndThrottleAPIUser(responseWriter, request)
+	if !UserAPIValidated {
+		return //User not logged in and was already handled
+	}
+	permissions, err := database.DBInterface.GetUserPermissionSet(UserName)
+	if err != nil {
+		ReplyWithJSONError(responseWriter, request, "Could not validate your permission, internal database error", UserName, http.StatusInternalServerError)
+		return
+	}
+	image, found := getNoteImage(responseWriter, request, UserID, UserName, permissions)
+	if !found {
+		return //Already replied
+	}
+	notes, err := database.DBInterface.GetNotes(image.ID, false)
+	if err != nil {
+		ReplyWithJSONError(responseWriter, request, "Interal Database Error", UserName, http.StatusInternalServerError)
+		return
+	}
+	if notes == nil {
+		notes = []interfaces.NoteInformation{}
+	}
+	ReplyWithJSON(responseWriter, request, notes, UserName)
+}
+
+//NotesPostAPIRouter serves post requests to /api/Image/{ImageID}/Notes, adding a note to the image
+func NotesPostAPIRouter(responseWriter http.ResponseWriter, request *http.Request) {
+	UserID, UserName, permissions, validated := validateNoteEditor(responseWriter, request, "NOTE-ADD")
+	if !validated {
+		return //Already replied
+	}
+	image, found := getNoteImage(responseWriter, request, UserID, UserName, permissions)
+	if !found || !noteImageEditable(responseWriter, request, image, UserName) {
+		return //Already replied
+	}
+	region, body, valid := decodeNoteInput(responseWriter, request, image, UserName)
+	if !valid {
+		return //Already replied
+	}
+	NoteID, err := database.DBInterface.NewNote(interfaces.NoteInformation{ImageID: image.ID, CreatorID: UserID, NoteRegion: region, Body: body})
+	if err != nil {
+		ReplyWithJSONError(responseWriter, request, "Interal Database Error", UserName, http.StatusInternalServerError)
+		return
+	}
+	go routers.WriteAuditLog(UserID, "NOTE-ADD", UserName+" added note "+strconv.FormatUint(NoteID, 10)+" to image "+strconv.FormatUint(image.ID, 10)+" with API.")
+	replyWithNote(responseWriter, request, NoteID, UserName)
+}
+
+//NotesRotateAPIRouter serves post requests to /api/Image/{ImageID}/Notes/Rotate, turning every note clockwise by Degrees to follow a rotated file
+func NotesRotateAPIRouter(responseWriter http.ResponseWriter, request *http.Request) {
+	UserID, UserName, permissions, validated := validateNoteEditor(responseWriter, request, "NOTE-ROTATE")
+	if !validated {
+		return //Already replied
+	}
+	image, found := getNoteImage(responseWriter, request, UserID, UserName, permissions)
+	if !found || !noteImageEditable(responseWriter, request, image, UserName) {
+		return //Already replied
+	}
+	decoder := json.NewDecoder(request.Body)
+	var rotateData rotateNotesInput
+	if err := decoder.Decode(&rotateData); err != nil {
+		ReplyWithJSONError(responseWriter, request, "Failed to parse request data", UserName, http.StatusBadRequest)
+		return
+	}
+	if _, err := (interfaces.NoteRegion{}).Rotate(rotateData.Degrees); err != nil {
+		ReplyWithJSONError(responseWriter, request, "Failed to rotate notes, "+err.Error(), UserName, http.StatusBadRequest)
+		return
+	}
+	if err := routers.RotateImageNotes(image.ID, rotateData.Degrees, UserID); err != nil {
+		ReplyWithJSONError(responseWriter, request, "Interal Database Error", UserName, http.StatusInternalServerError)
+		return
+	}
+	go routers.WriteAuditLog(UserID, "NOTE-ROTATE", UserName+" rotated the notes on image "+strconv.FormatUint(image.ID, 10)+" by "+strconv.FormatInt(rotateData.Degrees, 10)+" degrees with API.")
+	notes, err := database.DBInterface.GetNotes(image.ID, false)
+	if err != nil {
+		ReplyWithJSONError(responseWriter, request, "Interal Database Error", UserName, http.StatusInternalServerError)
+		return
+	}
+	if notes == nil {
+		notes = []interfaces.NoteInformation{}
+	}
+	ReplyWithJSON(responseWriter, request, notes, UserName)
+}
+
+//NoteGetAPIRouter serves get requests to /api/Note/{NoteID}
+func NoteGetAPIRouter(responseWriter http.ResponseWriter, request *http.Request) {
+	//Validate Logon
+	UserAPIValidated, UserID, UserName := ValidateAndThrottleAPIUser(responseWriter, request)
+	if !UserAPIValidated {
+		return //User not logged in and was already handled
+	}
+	note, found := getRequestedNote(responseWriter, request, UserName)
+	if !found {
+		return //Already replied
+	}
+	if _, found := getVisibleImage(responseWriter, request, note.ImageID, UserID, UserName); !found {
+		return //Already replied
+	}
+	ReplyWithJSON(responseWriter, request, note, UserName)
+}
+
+//NotePutAPIRouter serves put requests to /api/Note/{NoteID}, replacing the region and body of a note. A deleted note is shown again
+func NotePutAPIRouter(responseWriter http.ResponseWriter, request *http.Request) {
+	UserID, UserName, permissions, validated := validateNoteEditor(responseWriter, request, "NOTE-EDIT")
+	if !validated {
+		return //Already replied
+	}
+	note, found := getRequestedNote(responseWriter, request, UserName)
+	if !found {
+		return //Already replied
+	}
+	image, found := getNoteImageByID(responseWriter, request, note.ImageID, UserID, UserName, permissions)
+	if !found || !noteImageEditable(responseWriter, request, image, UserName) {
+		return //Already replied
+	}
+	region, body, valid := decodeNoteInput(responseWriter, request, image, UserName)
+	if !valid {
+		return //Already replied
+	}
+	if err := database.DBInterface.UpdateNote(note.ID, region, body, UserID); err != nil {
+		ReplyWithJSONError(responseWriter, request, "Interal Database Error", UserName, http.StatusInternalServerError)
+		return
+	}
+	go routers.WriteAuditLog(UserID, "NOTE-EDIT", UserName+" edited note "+strconv.FormatUint(note.ID, 10)+" with API.")
+	replyWithNote(responseWriter, request, note.ID, UserName)
+}
+
+//NoteDeleteAPIRouter serves delete requests to /api/Note/{NoteID}
+func NoteDeleteAPIRouter(responseWriter http.ResponseWriter, request *http.Request) {
+	UserID, UserName, permissions, validated := validateNoteEditor(responseWriter, request, "NOTE-DELETE")
+	if !validated {
+		return //Already replied
+	}
+	note, found := getRequestedNote(responseWriter, request, UserName)
+	if !found {
+		return //Already replied
+	}
+	image, found := getNoteImageByID(responseWriter, request, note.ImageID, UserID, UserName, permissions)
+	if !found || !noteImageEditable(responseWriter, request, image, UserName) {
+		return //Already replied
+	}
+	if err := database.DBInterface.DeleteNote(note.ID, UserID); err != nil {
+		ReplyWithJSONError(responseWriter, request, "Failed to delete note, "+err.Error(), UserName, http.StatusConflict)
+		return
+	}
+	go routers.WriteAuditLog(UserID, "NOTE-DELETE", UserName+" deleted note "+strconv.FormatUint(note.ID, 10)+" with API.")
+	ReplyWithJSON(responseWriter, request, GenericResponse{Result: "Successfully deleted note " + strconv.FormatUint(note.ID, 10)}, UserName)
+}
+
+//NoteRevisionsGetAPIRouter serves get requests to /api/Note/{NoteID}/Revisions, newest first
+func NoteRevisionsGetAPIRouter(responseWriter http.ResponseWriter, request *http.Request) {
+	//Validate Logon
+	UserAPIValidated, UserID, UserName := ValidateAndThrottleAPIUser(responseWriter, request)
+	if !UserAPIValidated {
+		return //User not logged in and was already handled
+	}
+	note, found := getRequestedNote(responseWriter, request, UserName)
+	if !found {
+		return //Already replied
+	}
+	if _, found := getVisibleImage(responseWriter, request, note.ImageID, UserID, UserName); !found {
+		return //Already replied
+	}
+	revisions, err := database.DBInterface.GetNoteRevisions(note.ID)
+	if err != nil {
+		ReplyWithJSONError(responseWriter, request, "Interal Database Error", UserName, http.StatusInternalServerError)
+		return
+	}
+	if revisions == nil {
+		revisions = []interfaces.NoteRevisionInformation{}
+	}
+	ReplyWithJSON(responseWriter, request, revisions, UserName)
+}
+
+//NoteRevisionRestoreAPIRouter serves post requests to /api/Note/{NoteID}/Revisions/{RevisionID}/Restore, making that version of the note current again
+func NoteRevisionRestoreAPIRouter(responseWriter http.ResponseWriter, request *http.Request) {
+	UserID, UserName, permissions, validated := validateNoteEditor(responseWriter, request, "NOTE-EDIT")
+	if !validated {
+		return //Already replied
+	}
+	note, found := getRequestedNote(responseWriter, request, UserName)
+	if !found {
+		return //Already replied
+	}
+	image, found := getNoteImageByID(responseWriter, request, note.ImageID, UserID, UserName, permissions)
+	if !found || !noteImageEditable(responseWriter, request, image, UserName) {
+		return //Already replied
+	}
+	RevisionID, err := strconv.ParseUint(mux.Vars(request)["RevisionID"], 10, 64)
+	if err != nil {
+		ReplyWithJSONError(responseWriter, request, "RevisionID could not be parsed into a number", UserName, http.StatusBadRequest)
+		return
+	}
+	revisions, err := database.DBInterface.GetNoteRevisions(note.ID)
+	if err != nil {
+		ReplyWithJSONError(responseWriter, request, "Interal Database Error", UserName, http.StatusInternalServerError)
+		return
+	}
+	for _, revision := range revisions {
+		if revision.ID == RevisionID {
+			if err := routers.RestoreNoteRevision(note, revision, UserID); err != nil {
+				ReplyWithJSONError(responseWriter, request, "Interal Database Error", UserName, http.StatusInternalServerError)
+				return
+			}
+			go routers.WriteAuditLog(UserID, "NOTE-EDIT", UserName+" restored note "+strconv.FormatUint(note.ID, 10)+" to revision "+strconv.FormatUint(RevisionID, 10)+" with API.")
+			replyWithNote(responseWriter, request, note.ID, UserName)
+			return
+		}
+	}
+	ReplyWithJSONError(responseWriter, request, "No revision by that ID", UserName, http.StatusNotFound)
+}
+
+//validateNoteEditor ensures the user is logged on, and may edit notes with the API, otherwise replies with the error and returns false
+func validateNoteEditor(responseWriter http.ResponseWriter, request *http.Request, AuditType string) (uint64, string, interfaces.UserPermission, bool) {
+	//Validate Logon
+	UserAPIValidated, UserID, UserName := ValidateAndThrottleAPIUser(responseWriter, request)
+	if !UserAPIValidated {
+		return 0, "", 0, false //User not logged in and was already handled
+	}
+	//Validate Permission to use api
+	UserAPIWriteValidated, permissions := ValidateAPIUserWriteAccess(responseWriter, request, UserName)
+	if !UserAPIWriteValidated {
+		return 0, "", 0, false //User does not have API access and was already told
+	}
+	if permissions.HasPermission(interfaces.EditNotes) != true {
+		ReplyWithJSONError(responseWriter, request, "You do not have permission to edit notes", UserName, http.StatusForbidden)
+		go routers.WriteAuditLog(UserID, AuditType, UserName+" failed to edit notes with API. No permissions.")
+		return 0, "", 0, false
+	}
+	return UserID, UserName, permissions, true
+}
+
+//getNoteImage returns the image requested by ImageID. An image in the trash is only found by those who can restore it, otherwise replies with the error and returns false
+func getNoteImage(responseWriter http.ResponseWriter, request *http.Request, UserID uint64, UserName string, permissions interfaces.UserPermission) (interfaces.ImageInformation, bool) {
+	parsedID, err := strconv.ParseUint(mux.Vars(request)["ImageID"], 10, 32)
+	if err != nil {
+		ReplyWithJSONError(responseWriter, request, "ImageID could not be parsed into a number", UserName, http.StatusBadRequest)
+		return interfaces.ImageInformation{}, false
+	}
+	return getNoteImageByID(responseWriter, request, parsedID, UserID, UserName, permissions)
+}
+
+//getNoteImageByID returns an image, hiding it if it is in the trash and the user cannot restore it, or waiting for approval and the user cannot see it, otherwise replies with the error and returns false
+func getNoteImageByID(responseWriter http.ResponseWriter, request *http.Request, ImageID uint64, UserID uint64, UserName string, permissions interfaces.UserPermission) (interfaces.ImageInformation, bool) {
+	image, found := getVisibleImage(responseWriter, request, ImageID, UserID, UserName)
+	if !found {
+		return image, false
+	}
+	if image.InTrash && permissions.HasPermission(interfaces.RemoveImage) != true {
+		ReplyWithJSONError(responseWriter, request, "No image by that ID", UserName, http.StatusNotFound)
+		return image, false
+	}
+	return image, true
+}
+
+//noteImageEditable replies with an error and returns false if the notes on an image cannot be changed
+func noteImageEditable(responseWriter http.ResponseWriter, request *http.Request, Image interfaces.ImageInformation, UserName string) bool {
+	if Image.InTrash {
+		ReplyWithJSONError(responseWriter, request, "Notes on images in the trash cannot be changed", UserName, http.StatusConflict)
+		return false
+	}
+	return true
+}
+
+//decodeNoteInput returns the validated region and body sent for a note, otherwise replies with the error and returns false
+func decodeNoteInput(responseWriter http.ResponseWriter, request *http.Request, Image interfaces.ImageInformation, UserName string) (interfaces.NoteRegion, string, bool) {
+	decoder := json.NewDecoder(request.Body)
+	var noteData postNoteInput
+	if err := decoder.Decode(&noteData); err != nil {
+		ReplyWithJSONError(responseWriter, request, "Failed to parse request data", UserName, http.StatusBadRequest)
+		return noteData.NoteRegion, "", false
+	}
+	region, err := routers.ValidateNoteRegion(noteData.NoteRegion, Image)
+	if err != nil {
+		ReplyWithJSONError(responseWriter, request, "Invalid note, "+err.Error(), UserName, http.StatusBadRequest)
+		return region, "", false
+	}
+	body, err := routers.ValidateNoteBody(noteData.Body)
+	if err != nil {
+		ReplyWithJSONError(responseWriter, request, "Invalid note, "+err.Error(), UserName, http.StatusBadRequest)
+		return region, "", false
+	}
+	return region, body, true
+}
+
+//getRequestedNote returns the note requested by NoteID, otherwise replies with the error and returns false
+func getRequestedNote(responseWriter http.ResponseWriter, request *http.Request, UserName string) (interfaces.NoteInformation, bool) {
+	parsedID, err := strconv.ParseUint(mux.Vars(request)["NoteID"], 10, 64)
+	if err != nil {
+		ReplyWithJSONError(responseWriter, request, "NoteID could not be parsed into a number", UserName, http.StatusBadRequest)
+		return interfaces.NoteInformation{}, false
+	}
+	note, err := database.DBInterface.GetNote(parsedID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ReplyWithJSONError(responseWriter, request, "No note by that ID", UserName, http.StatusNotFound)
+			return note, false
+		}
+		ReplyWithJSONError(responseWriter, request, "Interal Database Error", UserName, http.StatusInternalServerError)
+		return note, false
+	}
+	return note, true
+}
+
+//replyWithNote replies with a single note
+func replyWithNote(responseWriter http.ResponseWriter, request *http.Request, NoteID uint64, UserName string) {
+	note, err := database.DBInterface.GetNote(NoteID)
+	if err != nil {
+		ReplyWithJSONError(responseWriter, request, "Interal Database Error", UserName, http.StatusInternalServerError)
+		return
+	}
+	ReplyWithJSON(responseWriter, request, note, UserName)
+}
@@ -0,0 +1,172 @@
+package api
+
+import (
+	"encoding/json"
+	"go-image-board/database"
+	"go-image-board/interfaces"
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+)
+
+func TestNotesAPIRouter(t *testing.T) {
+	fixture := seedDatabase(t)
+	server := newTestServer(t)
+	admin := newTestClient(t, server, "admin", "adminpass")
+	viewer := newTestClient(t, server, "viewer", "viewerpass")
+	imagePath := "/api/Image/" + strconv.FormatUint(fixture.Images["one"], 10) + "/Notes"
+	if err := database.DBInterface.SetImageMetadata(fixture.Images["one"], interfaces.ImageMetadata{Width: 200, Height: 100, MIMEType: "image/png"}); err != nil {
+		t.Fatalf("SetImageMetadata: %v", err)
+	}
+
+	send := func(client *testClient, method string, path string, body interface{}) interfaces.NoteInformation {
+		t.Helper()
+		response, responseBody := client.do(t, method, path, body)
+		if response.StatusCode != http.StatusOK {
+			t.Fatalf("%s %s returned %d: %s", method, path, response.StatusCode, responseBody)
+		}
+		var note interfaces.NoteInformation
+		if err := json.Unmarshal(responseBody, &note); err != nil || note.ID == 0 {
+			t.Fatalf("%s %s returned %s, %v", method, path, responseBody, err)
+		}
+		return note
+	}
+	notePath := func(note interfaces.NoteInformation) string {
+		return "/api/Note/" + strconv.FormatUint(note.ID, 10)
+	}
+
+	//The region must fit on the image, and the body must not be blank
+	for _, invalid := range []map[string]interface{}{
+		{"X": 10, "Y": 10, "Width": 20, "Height": 20, "Body": "  "},
+		{"X": 190, "Y": 10, "Width": 20, "Height": 20, "Body": "Off the edge"},
+		{"X": 10, "Y": 10, "Width": 0, "Height": 20, "Body": "Empty"},
+		{"X": -1, "Y": 10, "Width": 20, "Height": 20, "Body": "Negative"},
+	} {
+		if response, body := admin.do(t, "POST", imagePath, invalid); response.StatusCode != http.StatusBadRequest {
+			t.Errorf("posting %v returned %d: %s", invalid, response.StatusCode, body)
+		}
+	}
+
+	//Dimensions default to those of the image
+	first := send(admin, "POST", imagePath, map[string]interface{}{"X": 10, "Y": 20, "Width": 30, "Height": 40, "Body": " A cat "})
+	expectedRegion := interfaces.NoteRegion{X: 10, Y: 20, Width: 30, Height: 40, ImageWidth: 200, ImageHeight: 100}
+	if first.NoteRegion != expectedRegion || first.Body != "A cat" || first.CreatorID != fixture.AdminID || first.CreatorName != "admin" {
+		t.Errorf("posted note is %+v", first)
+	}
+	//Notes placed on a larger file keep their own dimensions
+	second := send(admin, "POST", imagePath, map[string]interface{}{"X": 0, "Y": 0, "Width": 400, "Height": 100, "ImageWidth": 400, "ImageHeight": 200, "Body": "Top half"})
+	if second.ImageWidth != 400 || second.WidthPercent() != 100 || second.HeightPercent() != 50 {
+		t.Errorf("scaled note is %+v", second)
+	}
+	var notes []interfaces.NoteInformation
+	viewer.getJSON(t, imagePath, http.StatusOK, &notes)
+	if len(notes) != 2 || notes[0].ID != first.ID || notes[1].ID != second.ID {
+		t.Errorf("listed %+v", notes)
+	}
+	viewer.getJSON(t, "/api/Image/999999/Notes", http.StatusNotFound, nil)
+
+	//Editing requires API write access and EditNotes
+	if response, body := viewer.do(t, "PUT", notePath(first), map[string]interface{}{"X": 0, "Y": 0, "Width": 5, "Height": 5, "Body": "Mine"}); response.StatusCode != http.StatusForbidden {
+		t.Errorf("viewer edit returned %d: %s", response.StatusCode, body)
+	}
+	if err := database.DBInterface.SetUserPermissionSet(fixture.ViewerID, uint64(interfaces.APIWriteAccess)); err != nil {
+		t.Fatalf("SetUserPermissionSet: %v", err)
+	}
+	if response, body := viewer.do(t, "POST", imagePath, map[string]interface{}{"X": 0, "Y": 0, "Width": 5, "Height": 5, "Body": "Mine"}); response.StatusCode != http.StatusForbidden {
+		t.Errorf("viewer post without EditNotes returned %d: %s", response.StatusCode, body)
+	}
+	if err := database.DBInterface.SetUserPermissionSet(fixture.ViewerID, uint64(interfaces.APIWriteAccess|interfaces.EditNotes)); err != nil {
+		t.Fatalf("SetUserPermissionSet: %v", err)
+	}
+
+	//Anyone who may edit notes can change any note, and every version is kept
+	edited := send(viewer, "PUT", notePath(first), map[string]interface{}{"X": 100, "Y": 50, "Width": 50, "Height": 25, "Body": "A dog"})
+	if edited.Body != "A dog" || edited.X != 100 || edited.ImageWidth != 200 || edited.CreatorID != fixture.AdminID || edited.EditTime.IsZero() {
+		t.Errorf("edited note is %+v", edited)
+	}
+	if response, body := viewer.do(t, "DELETE", notePath(first), nil); response.StatusCode != http.StatusOK {
+		t.Fatalf("delete returned %d: %s", response.StatusCode, body)
+	}
+	if response, body := viewer.do(t, "DELETE", notePath(first), nil); response.StatusCode != http.StatusConflict {
+		t.Errorf("second delete returned %d: %s", response.StatusCode, body)
+	}
+	viewer.getJSON(t, imagePath, http.StatusOK, &notes)
+	if len(notes) != 1 || notes[0].ID != second.ID {
+		t.Errorf("listed after delete %+v", notes)
+	}
+	var revisions []interfaces.NoteRevisionInformation
+	admin.getJSON(t, notePath(first)+"/Revisions", http.StatusOK, &revisions)
+	if len(revisions) != 3 || revisions[0].Deleted == false || revisions[1].Body != "A dog" || revisions[1].EditorName != "viewer" || revisions[2].Body != "A cat" || revisions[2].EditorID != fixture.AdminID {
+		t.Fatalf("revisions are %+v", revisions)
+	}
+
+	//Restoring the first revision brings the note back where it was
+	restorePath := notePath(first) + "/Revisions/" + strconv.FormatUint(revisions[2].ID, 10) + "/Restore"
+	restored := send(admin, "POST", restorePath, nil)
+	if restored.Deleted || restored.Body != "A cat" || restored.NoteRegion != expectedRegion {
+		t.Errorf("restored note is %+v", restored)
+	}
+	if response, body := admin.do(t, "POST", notePath(first)+"/Revisions/999999/Restore", nil); response.StatusCode != http.StatusNotFound {
+		t.Errorf("restoring a missing revision returned %d: %s", response.StatusCode, body)
+	}
+	if response, body := admin.do(t, "POST", notePath(second)+"/Revisions/"+strconv.FormatUint(revisions[2].ID, 10)+"/Restore", nil); response.StatusCode != http.StatusNotFound {
+		t.Errorf("restoring another note's revision returned %d: %s", response.StatusCode, body)
+	}
+	admin.getJSON(t, notePath(first)+"/Revisions", http.StatusOK, &revisions)
+	if len(revisions) != 4 {
+		t.Errorf("restoring kept %d revisions", len(revisions))
+	}
+	viewer.getJSON(t, "/api/Note/999999", http.StatusNotFound, nil)
+
+	//Rotating follows a file turned on its side
+	if response, body := admin.do(t, "POST", imagePath+"/Rotate", map[string]interface{}{"Degrees": 45}); response.StatusCode != http.StatusBadRequest {
+		t.Errorf("rotating 45 degrees returned %d: %s", response.StatusCode, body)
+	}
+	if response, body := admin.do(t, "POST", imagePath+"/Rotate", map[string]interface{}{"Degrees": 90}); response.StatusCode != http.StatusOK {
+		t.Fatalf("rotate returned %d: %s", response.StatusCode, body)
+	}
+	var rotated interfaces.NoteInformation
+	admin.getJSON(t, notePath(first), http.StatusOK, &rotated)
+	if rotated.NoteRegion != (interfaces.NoteRegion{X: 40, Y: 10, Width: 40, Height: 30, ImageWidth: 100, ImageHeight: 200}) {
+		t.Errorf("rotated note is %+v", rotated.NoteRegion)
+	}
+	for _, degrees := range []int{90, 180} {
+		if response, body := admin.do(t, "POST", imagePath+"/Rotate", map[string]interface{}{"Degrees": degrees}); response.StatusCode != http.StatusOK {
+			t.Fatalf("rotate returned %d: %s", response.StatusCode, body)
+		}
+	}
+	admin.getJSON(t, notePath(first), http.StatusOK, &rotated)
+	if rotated.NoteRegion != expectedRegion {
+		t.Errorf("note rotated a full turn is %+v", rotated.NoteRegion)
+	}
+
+	//Only images with notes that are not deleted match hasnotes
+	var images ImageSearchResult
+	admin.getJSON(t, "/api/Images?SearchQuery="+url.QueryEscape("hasnotes:y"), http.StatusOK, &images)
+	if equalIDs(imageIDs(images.Images), fixture.fixtureImageIDs("one")) == false {
+		t.Errorf("hasnotes:y returned %v", imageIDs(images.Images))
+	}
+	twoPath := "/api/Image/" + strconv.FormatUint(fixture.Images["two"], 10) + "/Notes"
+	other := send(admin, "POST", twoPath, map[string]interface{}{"X": 0, "Y": 0, "Width": 1, "Height": 1, "ImageWidth": 10, "ImageHeight": 10, "Body": "Gone"})
+	if response, body := admin.do(t, "DELETE", notePath(other), nil); response.StatusCode != http.StatusOK {
+		t.Fatalf("delete returned %d: %s", response.StatusCode, body)
+	}
+	admin.getJSON(t, "/api/Images?SearchQuery="+url.QueryEscape("hasnotes:n"), http.StatusOK, &images)
+	if equalIDs(imageIDs(images.Images), fixture.fixtureImageIDs("five", "four", "three", "two")) == false {
+		t.Errorf("hasnotes:n returned %v", imageIDs(images.Images))
+	}
+
+	//Without known dimensions, they must be given
+	if response, body := admin.do(t, "POST", twoPath, map[string]interface{}{"X": 0, "Y": 0, "Width": 1, "Height": 1, "Body": "Where?"}); response.StatusCode != http.StatusBadRequest {
+		t.Errorf("posting without dimensions returned %d: %s", response.StatusCode, body)
+	}
+
+	//Notes on images in the trash cannot be changed
+	if err := database.DBInterface.TrashImage(fixture.Images["one"], fixture.AdminID); err != nil {
+		t.Fatalf("TrashImage: %v", err)
+	}
+	if response, body := admin.do(t, "PUT", notePath(first), map[string]interface{}{"X": 0, "Y": 0, "Width": 5, "Height": 5, "Body": "Trashed"}); response.StatusCode != http.StatusConflict {
+		t.Errorf("editing a note in the trash returned %d: %s", response.StatusCode, body)
+	}
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"go-image-board/config"
+	"go-image-board/database"
+	"go-image-board/interfaces"
+	"go-image-board/routers"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+//ReportSearchResult response format for a page of reports
+type ReportSearchResult struct {
+	Reports      []interfaces.ReportInformation
+	ResultCount  uint64
+	ServerStride uint64
+}
+
+type postReportInput struct {
+	TargetType string
+	TargetID   uint64
+	Category   string
+	Reason     string
+}
+
+//ReportsGetAPIRouter serves get requests to /api/Reports, listing the reports the user filed, newest first
+func ReportsGetAPIRouter(responseWriter http.ResponseWriter, request *http.Request) {
+	//Validate Logon
+	UserAPIValidated, UserID, UserName := ValidateAndThrottleAPIUser(responseWriter, request)
+	if !UserAPIValidated {
+		return //User not logged in and was already handled
+	}
+	pageStart, _ := strconv.ParseUint(request.FormValue("PageStart"), 10, 32) //Either parses fine, or is 0, both works
+	pageStride := config.Configuration.PageStride
+	reports, MaxCount, err := database.DBInterface.GetUserReports(UserID, pageStart, pageStride)
+	if err != nil {
+		ReplyWithJSONError(responseWriter, request, "Interal Database Error", UserName, http.StatusInternalServerError)
+		return
+	}
+	if reports == nil {
+		reports = []interfaces.ReportInformation{}
+	}
+	ReplyWithJSON(responseWriter, request, ReportSearchResult{Reports: reports, ResultCount: MaxCount, ServerStride: pageStride}, UserName)
+}
+
+//ReportsPostAPIRouter serves post requests to /api/Reports, filing a report against an image, collection or tag
+func ReportsPostAPIRouter(responseWriter http.ResponseWriter, request *http.Request) {
+	//Validate Logon
+	UserAPIValidated, UserID, UserName := ValidateAndThrottleAPIUser(responseWriter, request)
+	if !UserAPIValidated {
+		return //User not logged in and was already handled
+	}
+	//Validate Permission to use api
+	UserAPIWriteValidated, permissions := ValidateAPIUserWriteAccess(responseWriter, request, UserName)
+	if !UserAPIWriteValidated {
+		return //User does not have API access and was already told
+	}
+	decoder := json.NewDecoder(request.Body)
+	var reportData postReportInput
+	if err := decoder.Decode(&reportData); err != nil {
+		ReplyWithJSONError(responseWriter, request, "Failed to parse request data", UserName, http.StatusBadRequest)
+		return
+	}
+	report := interfaces.ReportInformation{TargetType: reportData.TargetType, TargetID: reportData.TargetID, ReporterID: UserID, Category: reportData.Category, Reason: reportData.Reason}
+	if err := routers.ValidateReport(&report, permissions); err != nil {
+		ReplyWithJSONError(responseWriter, request, "Failed to file report, "+err.Error(), UserName, http.StatusBadRequest)
+		return
+	}
+	ReportID, err := database.DBInterface.NewReport(report)
+	if err != nil {
+		ReplyWithJSONError(responseWriter, request, "Interal Database Error", UserName, http.StatusInternalServerError)
+		return
+	}
+	go routers.WriteAuditLog(UserID, "REPORT-CREATE", UserName+" reported "+report.TargetType+" "+strconv.FormatUint(report.TargetID, 10)+" as "+report.Category+" with API. Report "+strconv.FormatUint(ReportID, 10))
+	replyWithReport(responseWriter, request, ReportID, UserID, permissions, UserName)
+}
+
+//ReportGetAPIRouter serves get requests to /api/Report/{ReportID}, only the reporter and moderators handling reports may see a report
+func ReportGetAPIRouter(responseWriter http.ResponseWriter, request *http.Request) {
+	//Validate Logon
+	UserAPIValidated, UserID, UserName := ValidateAndThrottleAPIUser(responseWriter, request)
+	if !UserAPIValidated {
+		return //User not logged in and was already handled
+	}
+	permissions, err := database.DBInterface.GetUserPermissionSet(UserName)
+	if err != nil {
+		ReplyWithJSONError(responseWriter, request, "Could not validate your permission, internal database error", UserName, http.StatusInternalServerError)
+		return
+	}
+	parsedID, err := strconv.ParseUint(mux.Vars(request)["ReportID"], 10, 64)
+	if err != nil {
+		ReplyWithJSONError(responseWriter, request, "ReportID could not be parsed into a number", UserName, http.StatusBadRequest)
+		return
+	}
+	replyWithReport(responseWriter, request, parsedID, UserID, permissions, UserName)
+}
+
+//replyWithReport replies with a single report, if it was filed by UserID or Permissions allow handling reports
+func replyWithReport(responseWriter http.ResponseWriter, request *http.Request, ID uint64, UserID uint64, Permissions interfaces.UserPermission, UserName string) {
+	report, err := database.DBInterface.GetReport(ID)
+	if err == nil && report.ReporterID != UserID && Permissions.HasPermission(interfaces.HandleReports) != true {
+		//Reports are private, so one filed by someone else does not exist as far as this user knows
+		err = sql.ErrNoRows
+	}
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ReplyWithJSONError(responseWriter, request, "No report by that ID", UserName, http.StatusNotFound)
+			return
+		}
+		ReplyWithJSONError(responseWriter, request, "Internal database error", UserName, http.StatusInternalServerError)
+		return
+	}
+	ReplyWithJSON(responseWriter, request, report, UserName)
+}
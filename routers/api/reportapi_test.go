@@ -0,0 +1,71 @@
+package api
+
+import (
+	"encoding/json"
+	"go-image-board/database"
+	"go-image-board/interfaces"
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+func TestReportsAPIRouter(t *testing.T) {
+	fixture := seedDatabase(t)
+	server := newTestServer(t)
+	if err := database.DBInterface.SetUserPermissionSet(fixture.ViewerID, uint64(interfaces.APIWriteAccess)); err != nil {
+		t.Fatal(err)
+	}
+	viewer := newTestClient(t, server, "viewer", "viewerpass")
+
+	anonymous := newTestClient(t, server, "", "")
+	if response, body := anonymous.do(t, "POST", "/api/Reports", map[string]interface{}{"TargetType": "image", "TargetID": fixture.Images["one"], "Category": "duplicate", "Reason": "Same as two"}); response.StatusCode != http.StatusUnauthorized {
+		t.Errorf("anonymous report returned %d: %s", response.StatusCode, body)
+	}
+	for _, invalid := range []map[string]interface{}{
+		{"TargetType": "image", "TargetID": fixture.Images["one"], "Category": "duplicate", "Reason": ""},
+		{"TargetType": "image", "TargetID": fixture.Images["one"], "Category": "boring", "Reason": "Not interesting"},
+		{"TargetType": "collection", "TargetID": 999, "Category": "other", "Reason": "Missing"},
+	} {
+		if response, body := viewer.do(t, "POST", "/api/Reports", invalid); response.StatusCode != http.StatusBadRequest {
+			t.Errorf("report %v returned %d: %s", invalid, response.StatusCode, body)
+		}
+	}
+
+	response, body := viewer.do(t, "POST", "/api/Reports", map[string]interface{}{"TargetType": "image", "TargetID": fixture.Images["one"], "Category": "duplicate", "Reason": " Same as two "})
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("report returned %d: %s", response.StatusCode, body)
+	}
+	var created interfaces.ReportInformation
+	if err := json.Unmarshal(body, &created); err != nil || created.ID == 0 || created.Reason != "Same as two" || created.Status != interfaces.ReportOpen || created.ReporterName != "viewer" {
+		t.Fatalf("report returned %s, %v", body, err)
+	}
+	reportPath := "/api/Report/" + strconv.FormatUint(created.ID, 10)
+
+	//The reporter follows the outcome
+	if err := database.DBInterface.UpdateReport(created.ID, interfaces.ReportResolved, fixture.AdminID, "Merged with two"); err != nil {
+		t.Fatal(err)
+	}
+	var report interfaces.ReportInformation
+	viewer.getJSON(t, reportPath, http.StatusOK, &report)
+	if report.Status != interfaces.ReportResolved || report.ModeratorName != "admin" || report.Outcome != "Merged with two" || report.UpdateTime.IsZero() {
+		t.Errorf("report is %+v", report)
+	}
+	var reports ReportSearchResult
+	viewer.getJSON(t, "/api/Reports", http.StatusOK, &reports)
+	if reports.ResultCount != 1 || len(reports.Reports) != 1 || reports.Reports[0].ID != created.ID {
+		t.Errorf("reports returned %+v", reports)
+	}
+
+	//Reports are private to the reporter and moderators handling reports
+	if err := database.DBInterface.CreateUser("other", []byte("otherpass"), "other@example.com", 0); err != nil {
+		t.Fatal(err)
+	}
+	other := newTestClient(t, server, "other", "otherpass")
+	other.getJSON(t, reportPath, http.StatusNotFound, nil)
+	other.getJSON(t, "/api/Reports", http.StatusOK, &reports)
+	if reports.ResultCount != 0 || len(reports.Reports) != 0 {
+		t.Errorf("other user can list %+v", reports)
+	}
+	admin := newTestClient(t, server, "admin", "adminpass")
+	admin.getJSON(t, reportPath, http.StatusOK, &report)
+}
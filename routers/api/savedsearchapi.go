@@ -0,0 +1,164 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"go-image-board/database"
+	"go-image-board/interfaces"
+	"go-image-board/routers"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+type savedSearchInput struct {
+	Name              string
+	Query             string
+	CollectionContext bool
+	Pinned            bool
+	ShowNewCount      bool
+}
+
+//SavedSearchesGetAPIRouter serves get requests to /api/SavedSearches, listing the user's saved searches
+func SavedSearchesGetAPIRouter(responseWriter http.ResponseWriter, request *http.Request) {
+	//Validate Logon
+	UserAPIValidated, UserID, UserName := ValidateAndThrottleAPIUser(responseWriter, request)
+	if !UserAPIValidated {
+		return //User not logged in and was already handled
+	}
+	searches, err := routers.GetSavedSearchesWithNewCounts(UserID, false)
+	if err != nil {
+		ReplyWithJSONError(responseWriter, request, "Internal database error", UserName, http.StatusInternalServerError)
+		return
+	}
+	if searches == nil {
+		searches = []interfaces.SavedSearchInformation{}
+	}
+	ReplyWithJSON(responseWriter, request, searches, UserName)
+}
+
+//SavedSearchesPostAPIRouter serves post requests to /api/SavedSearches, adding a saved search for the user
+func SavedSearchesPostAPIRouter(responseWriter http.ResponseWriter, request *http.Request) {
+	//Validate Logon
+	UserAPIValidated, UserID, UserName := ValidateAndThrottleAPIUser(responseWriter, request)
+	if !UserAPIValidated {
+		return //User not logged in and was already handled
+	}
+	search, parsed := parseSavedSearchInput(responseWriter, request, UserName)
+	if !parsed {
+		return //Already replied with the error
+	}
+	search.UserID = UserID
+	search.ID, _ = database.DBInterface.NewSavedSearch(search)
+	if search.ID == 0 {
+		ReplyWithJSONError(responseWriter, request, "Internal database error", UserName, http.StatusInternalServerError)
+		return
+	}
+	replyWithSavedSearch(responseWriter, request, search.ID, UserID, UserName)
+}
+
+//SavedSearchGetAPIRouter serves get requests to /api/SavedSearches/{SavedSearchID}
+func SavedSearchGetAPIRouter(responseWriter http.ResponseWriter, request *http.Request) {
+	//Validate Logon
+	UserAPIValidated, UserID, UserName := ValidateAndThrottleAPIUser(responseWriter, request)
+	if !UserAPIValidated {
+		return //User not logged in and was already handled
+	}
+	parsedID, err := strconv.ParseUint(mux.Vars(request)["SavedSearchID"], 10, 64)
+	if err != nil {
+		ReplyWithJSONError(responseWriter, request, "SavedSearchID could not be parsed into a number", UserName, http.StatusBadRequest)
+		return
+	}
+	replyWithSavedSearch(responseWriter, request, parsedID, UserID, UserName)
+}
+
+//SavedSearchPutAPIRouter serves put requests to /api/SavedSearches/{SavedSearchID}, replacing the name, query and options of a saved search
+func SavedSearchPutAPIRouter(responseWriter http.ResponseWriter, request *http.Request) {
+	//Validate Logon
+	UserAPIValidated, UserID, UserName := ValidateAndThrottleAPIUser(responseWriter, request)
+	if !UserAPIValidated {
+		return //User not logged in and was already handled
+	}
+	parsedID, err := strconv.ParseUint(mux.Vars(request)["SavedSearchID"], 10, 64)
+	if err != nil {
+		ReplyWithJSONError(responseWriter, request, "SavedSearchID could not be parsed into a number", UserName, http.StatusBadRequest)
+		return
+	}
+	search, parsed := parseSavedSearchInput(responseWriter, request, UserName)
+	if !parsed {
+		return //Already replied with the error
+	}
+	search.ID = parsedID
+	search.UserID = UserID
+	if err := database.DBInterface.UpdateSavedSearch(search); err != nil {
+		if err == sql.ErrNoRows {
+			ReplyWithJSONError(responseWriter, request, "No saved search by that ID", UserName, http.StatusNotFound)
+			return
+		}
+		ReplyWithJSONError(responseWriter, request, "Internal database error", UserName, http.StatusInternalServerError)
+		return
+	}
+	replyWithSavedSearch(responseWriter, request, parsedID, UserID, UserName)
+}
+
+//SavedSearchDeleteAPIRouter serves delete requests to /api/SavedSearches/{SavedSearchID}
+func SavedSearchDeleteAPIRouter(responseWriter http.ResponseWriter, request *http.Request) {
+	//Validate Logon
+	UserAPIValidated, UserID, UserName := ValidateAndThrottleAPIUser(responseWriter, request)
+	if !UserAPIValidated {
+		return //User not logged in and was already handled
+	}
+	requestedID := mux.Vars(request)["SavedSearchID"]
+	parsedID, err := strconv.ParseUint(requestedID, 10, 64)
+	if err != nil {
+		ReplyWithJSONError(responseWriter, request, "SavedSearchID could not be parsed into a number", UserName, http.StatusBadRequest)
+		return
+	}
+	if err := database.DBInterface.DeleteSavedSearch(parsedID, UserID); err != nil {
+		if err == sql.ErrNoRows {
+			ReplyWithJSONError(responseWriter, request, "No saved search by that ID", UserName, http.StatusNotFound)
+			return
+		}
+		ReplyWithJSONError(responseWriter, request, "Internal database error", UserName, http.StatusInternalServerError)
+		return
+	}
+	ReplyWithJSON(responseWriter, request, GenericResponse{Result: "Successfully deleted saved search " + requestedID}, UserName)
+}
+
+//parseSavedSearchInput reads and validates a saved search from the request body, replying with an error if it cannot be used
+func parseSavedSearchInput(responseWriter http.ResponseWriter, request *http.Request, UserName string) (interfaces.SavedSearchInformation, bool) {
+	decoder := json.NewDecoder(request.Body)
+	var searchData savedSearchInput
+	if err := decoder.Decode(&searchData); err != nil {
+		ReplyWithJSONError(responseWriter, request, "Failed to parse request data", UserName, http.StatusBadRequest)
+		return interfaces.SavedSearchInformation{}, false
+	}
+	search := interfaces.SavedSearchInformation{Name: searchData.Name, Query: searchData.Query, CollectionContext: searchData.CollectionContext, Pinned: searchData.Pinned, ShowNewCount: searchData.ShowNewCount}
+	if err := routers.ValidateSavedSearch(&search); err != nil {
+		ReplyWithJSONError(responseWriter, request, "Failed to save search, "+err.Error(), UserName, http.StatusBadRequest)
+		return search, false
+	}
+	return search, true
+}
+
+//replyWithSavedSearch replies with a single saved search belonging to UserID, including its count of new results
+func replyWithSavedSearch(responseWriter http.ResponseWriter, request *http.Request, ID uint64, UserID uint64, UserName string) {
+	search, err := database.DBInterface.GetSavedSearch(ID)
+	if err == nil && search.UserID != UserID {
+		//Saved searches are private, so one belonging to someone else does not exist as far as this user knows
+		err = sql.ErrNoRows
+	}
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ReplyWithJSONError(responseWriter, request, "No saved search by that ID", UserName, http.StatusNotFound)
+			return
+		}
+		ReplyWithJSONError(responseWriter, request, "Internal database error", UserName, http.StatusInternalServerError)
+		return
+	}
+	if search.ShowNewCount {
+		search.NewCount, _ = routers.CountSavedSearchNewResults(search)
+	}
+	ReplyWithJSON(responseWriter, request, search, UserName)
+}
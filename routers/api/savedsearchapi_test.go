@@ -0,0 +1,89 @@
+package api
+
+import (
+	"encoding/json"
+	"go-image-board/database"
+	"go-image-board/interfaces"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestSavedSearchesAPIRouter(t *testing.T) {
+	fixture := seedDatabase(t)
+	server := newTestServer(t)
+	viewer := newTestClient(t, server, "viewer", "viewerpass")
+
+	anonymous := newTestClient(t, server, "", "")
+	if response, body := anonymous.do(t, "GET", "/api/SavedSearches", nil); response.StatusCode != http.StatusUnauthorized {
+		t.Errorf("anonymous list returned %d: %s", response.StatusCode, body)
+	}
+
+	var searches []interfaces.SavedSearchInformation
+	viewer.getJSON(t, "/api/SavedSearches", http.StatusOK, &searches)
+	if len(searches) != 0 {
+		t.Fatalf("expected no saved searches, got %+v", searches)
+	}
+
+	if response, body := viewer.do(t, "POST", "/api/SavedSearches", map[string]interface{}{"Name": "Nothing", "Query": "  "}); response.StatusCode != http.StatusBadRequest {
+		t.Errorf("blank query returned %d: %s", response.StatusCode, body)
+	}
+	if response, body := viewer.do(t, "POST", "/api/SavedSearches", map[string]interface{}{"Name": " ", "Query": "cat"}); response.StatusCode != http.StatusBadRequest {
+		t.Errorf("blank name returned %d: %s", response.StatusCode, body)
+	}
+
+	response, body := viewer.do(t, "POST", "/api/SavedSearches", map[string]interface{}{"Name": " Cats ", "Query": "cat -outdoor", "Pinned": true, "ShowNewCount": true})
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("create returned %d: %s", response.StatusCode, body)
+	}
+	var created interfaces.SavedSearchInformation
+	if err := json.Unmarshal(body, &created); err != nil || created.ID == 0 || created.Name != "Cats" || created.UserID != fixture.ViewerID || created.Pinned == false || created.NewCount != 0 {
+		t.Fatalf("create returned %s, %v", body, err)
+	}
+	searchPath := "/api/SavedSearches/" + strconv.FormatUint(created.ID, 10)
+
+	//Uploads from after the search was last opened are counted
+	time.Sleep(time.Until(created.LastViewed.Truncate(time.Second).Add(time.Second)))
+	newImageID, err := database.DBInterface.NewImage("six", "six.png", fixture.AdminID, "", false)
+	if err != nil {
+		t.Fatalf("NewImage: %v", err)
+	}
+	if err := database.DBInterface.AddTag([]uint64{fixture.Tags["cat"]}, newImageID, fixture.AdminID); err != nil {
+		t.Fatalf("AddTag: %v", err)
+	}
+	viewer.getJSON(t, "/api/SavedSearches", http.StatusOK, &searches)
+	if len(searches) != 1 || searches[0].ID != created.ID || searches[0].NewCount != 1 {
+		t.Errorf("list returned %+v, expected one new result", searches)
+	}
+
+	//Saved searches are private
+	admin := newTestClient(t, server, "admin", "adminpass")
+	admin.getJSON(t, searchPath, http.StatusNotFound, nil)
+	if response, body := admin.do(t, "PUT", searchPath, map[string]interface{}{"Name": "Mine", "Query": "dog"}); response.StatusCode != http.StatusNotFound {
+		t.Errorf("other user update returned %d: %s", response.StatusCode, body)
+	}
+	if response, body := admin.do(t, "DELETE", searchPath, nil); response.StatusCode != http.StatusNotFound {
+		t.Errorf("other user delete returned %d: %s", response.StatusCode, body)
+	}
+	admin.getJSON(t, "/api/SavedSearches", http.StatusOK, &searches)
+	if len(searches) != 0 {
+		t.Errorf("admin can list %+v", searches)
+	}
+
+	response, body = viewer.do(t, "PUT", searchPath, map[string]interface{}{"Name": "Pets", "Query": "cat OR dog", "CollectionContext": true})
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("update returned %d: %s", response.StatusCode, body)
+	}
+	var updated interfaces.SavedSearchInformation
+	viewer.getJSON(t, searchPath, http.StatusOK, &updated)
+	if updated.Name != "Pets" || updated.Query != "cat OR dog" || updated.CollectionContext == false || updated.Pinned || updated.ShowNewCount || updated.NewCount != 0 {
+		t.Errorf("updated search is %+v", updated)
+	}
+
+	if response, body := viewer.do(t, "DELETE", searchPath, nil); response.StatusCode != http.StatusOK {
+		t.Fatalf("delete returned %d: %s", response.StatusCode, body)
+	}
+	viewer.getJSON(t, searchPath, http.StatusNotFound, nil)
+	viewer.getJSON(t, "/api/SavedSearches/notanumber", http.StatusBadRequest, nil)
+}
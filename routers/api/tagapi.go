@@ -2,6 +2,7 @@ package api
 
 import (
 	"database/sql"
+	"encoding/json"
 	"go-image-board/config"
 	"go-image-board/database"
 	"go-image-board/interfaces"
@@ -102,3 +103,123 @@ func TagDeleteAPIRouter(responseWriter http.ResponseWriter, request *http.Reques
 	}
 	ReplyWithJSONError(responseWriter, request, "Please specify TagID", UserName, http.StatusBadRequest)
 }
+
+type postTagImplicationInput struct {
+	ImpliedTag string
+}
+
+//TagImplicationsGetAPIRouter serves get requests to /api/Tag/{TagID}/Implications
+func TagImplicationsGetAPIRouter(responseWriter http.ResponseWriter, request *http.Request) {
+	//Validate Logon
+	UserAPIValidated, _, UserName := ValidateAndThrottleAPIUser(responseWriter, request)
+	if !UserAPIValidated {
+		return //User not logged in and was already handled
+	}
+	urlVariables := mux.Vars(request)
+	parsedID, err := strconv.ParseUint(urlVariables["TagID"], 10, 32)
+	if err != nil {
+		ReplyWithJSONError(responseWriter, request, "TagID could not be parsed into a number", UserName, http.StatusBadRequest)
+		return
+	}
+	replyWithTagImplications(responseWriter, request, parsedID, UserName)
+}
+
+//TagImplicationsPostAPIRouter serves post requests to /api/Tag/{TagID}/Implications, adding a rule that the tag implies ImpliedTag
+func TagImplicationsPostAPIRouter(responseWriter http.ResponseWriter, request *http.Request) {
+	//Validate Logon
+	UserAPIValidated, UserID, UserName := ValidateAndThrottleAPIUser(responseWriter, request)
+	if !UserAPIValidated {
+		return //User not logged in and was already handled
+	}
+	//Validate Permission to use api
+	UserAPIWriteValidated, permissions := ValidateAPIUserWriteAccess(responseWriter, request, UserName)
+	if !UserAPIWriteValidated {
+		return //User does not have API access and was already told
+	}
+	urlVariables := mux.Vars(request)
+	parsedID, err := strconv.ParseUint(urlVariables["TagID"], 10, 32)
+	if err != nil {
+		ReplyWithJSONError(responseWriter, request, "TagID could not be parsed into a number", UserName, http.StatusBadRequest)
+		return
+	}
+	if interfaces.UserPermission(permissions).HasPermission(interfaces.ModifyTags) != true {
+		ReplyWithJSONError(responseWriter, request, "You do not have permission to modify tags", UserName, http.StatusForbidden)
+		go routers.WriteAuditLog(UserID, "MODIFY-TAGIMPLICATION", UserName+" failed to add tag implication with API. Insufficient permissions. "+urlVariables["TagID"])
+		return
+	}
+	decoder := json.NewDecoder(request.Body)
+	var implicationData postTagImplicationInput
+	if err := decoder.Decode(&implicationData); err != nil || implicationData.ImpliedTag == "" {
+		ReplyWithJSONError(responseWriter, request, "Failed to parse request data", UserName, http.StatusBadRequest)
+		return
+	}
+	if _, err := database.DBInterface.GetTag(parsedID, false); err != nil {
+		if err == sql.ErrNoRows {
+			ReplyWithJSONError(responseWriter, request, "No tag by that ID", UserName, http.StatusNotFound)
+			return
+		}
+		ReplyWithJSONError(responseWriter, request, "Internal database error", UserName, http.StatusInternalServerError)
+		return
+	}
+	impliedTagID, err := routers.AddTagImplicationByName(parsedID, implicationData.ImpliedTag, UserID)
+	if err != nil {
+		ReplyWithJSONError(responseWriter, request, "Failed to add tag implication, "+err.Error(), UserName, http.StatusBadRequest)
+		go routers.WriteAuditLog(UserID, "MODIFY-TAGIMPLICATION", UserName+" failed to add tag implication with API. "+urlVariables["TagID"]+"->"+implicationData.ImpliedTag+", "+err.Error())
+		return
+	}
+	go routers.WriteAuditLog(UserID, "MODIFY-TAGIMPLICATION", UserName+" added tag implication with API. "+urlVariables["TagID"]+"->"+strconv.FormatUint(impliedTagID, 10))
+	replyWithTagImplications(responseWriter, request, parsedID, UserName)
+}
+
+//TagImplicationDeleteAPIRouter serves delete requests to /api/Tag/{TagID}/Implications/{ImpliedTagID}
+func TagImplicationDeleteAPIRouter(responseWriter http.ResponseWriter, request *http.Request) {
+	//Validate Logon
+	UserAPIValidated, UserID, UserName := ValidateAndThrottleAPIUser(responseWriter, request)
+	if !UserAPIValidated {
+		return //User not logged in and was already handled
+	}
+	//Validate Permission to use api
+	UserAPIWriteValidated, permissions := ValidateAPIUserWriteAccess(responseWriter, request, UserName)
+	if !UserAPIWriteValidated {
+		return //User does not have API access and was already told
+	}
+	urlVariables := mux.Vars(request)
+	parsedID, err := strconv.ParseUint(urlVariables["TagID"], 10, 32)
+	if err != nil {
+		ReplyWithJSONError(responseWriter, request, "TagID could not be parsed into a number", UserName, http.StatusBadRequest)
+		return
+	}
+	impliedTagID, err := strconv.ParseUint(urlVariables["ImpliedTagID"], 10, 32)
+	if err != nil {
+		ReplyWithJSONError(responseWriter, request, "ImpliedTagID could not be parsed into a number", UserName, http.StatusBadRequest)
+		return
+	}
+	if interfaces.UserPermission(permissions).HasPermission(interfaces.ModifyTags) != true {
+		ReplyWithJSONError(responseWriter, request, "You do not have permission to modify tags", UserName, http.StatusForbidden)
+		go routers.WriteAuditLog(UserID, "MODIFY-TAGIMPLICATION", UserName+" failed to remove tag implication with API. Insufficient permissions. "+urlVariables["TagID"])
+		return
+	}
+	if err := database.DBInterface.RemoveTagImplication(parsedID, impliedTagID); err != nil {
+		if err == sql.ErrNoRows {
+			ReplyWithJSONError(responseWriter, request, "No such tag implication", UserName, http.StatusNotFound)
+			return
+		}
+		ReplyWithJSONError(responseWriter, request, "Internal database error", UserName, http.StatusInternalServerError)
+		return
+	}
+	go routers.WriteAuditLog(UserID, "MODIFY-TAGIMPLICATION", UserName+" removed tag implication with API. "+urlVariables["TagID"]+"->"+urlVariables["ImpliedTagID"])
+	ReplyWithJSON(responseWriter, request, GenericResponse{Result: "Successfully removed tag implication " + urlVariables["TagID"] + "->" + urlVariables["ImpliedTagID"]}, UserName)
+}
+
+//replyWithTagImplications replies with the rules where a tag implies, or is implied by, another tag
+func replyWithTagImplications(responseWriter http.ResponseWriter, request *http.Request, TagID uint64, UserName string) {
+	implications, err := database.DBInterface.GetTagImplications(TagID)
+	if err != nil {
+		ReplyWithJSONError(responseWriter, request, "Internal database error", UserName, http.StatusInternalServerError)
+		return
+	}
+	if implications == nil {
+		implications = []interfaces.TagImplicationInformation{}
+	}
+	ReplyWithJSON(responseWriter, request, implications, UserName)
+}
@@ -0,0 +1,131 @@
+package api
+
+import (
+	"encoding/json"
+	"go-image-board/database"
+	"go-image-board/interfaces"
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+func TestTagGetAPIRouter(t *testing.T) {
+	fixture := seedDatabase(t)
+	server := newTestServer(t)
+	client := newTestClient(t, server, "viewer", "viewerpass")
+
+	var tag interfaces.TagInformation
+	client.getJSON(t, "/api/Tag/"+strconv.FormatUint(fixture.Tags["cat"], 10), http.StatusOK, &tag)
+	if tag.Name != "cat" || tag.UseCount != 3 || tag.IsAlias || tag.Category != interfaces.TagCategoryGeneral {
+		t.Errorf("unexpected tag returned %+v", tag)
+	}
+	client.getJSON(t, "/api/Tag/"+strconv.FormatUint(fixture.Tags["kitty"], 10), http.StatusOK, &tag)
+	if tag.IsAlias == false || tag.AliasedID != fixture.Tags["cat"] {
+		t.Errorf("expected kitty to alias cat, got %+v", tag)
+	}
+	client.getJSON(t, "/api/Tag/999", http.StatusNotFound, nil)
+	client.getJSON(t, "/api/Tag/notanumber", http.StatusBadRequest, nil)
+}
+
+func TestTagDeleteAPIRouter(t *testing.T) {
+	fixture := seedDatabase(t)
+	server := newTestServer(t)
+	unusedPath := "/api/Tag/" + strconv.FormatUint(fixture.Tags["unused"], 10)
+
+	viewer := newTestClient(t, server, "viewer", "viewerpass")
+	if response, body := viewer.do(t, "DELETE", unusedPath, nil); response.StatusCode != http.StatusForbidden {
+		t.Fatalf("viewer delete returned %d: %s", response.StatusCode, body)
+	}
+
+	admin := newTestClient(t, server, "admin", "adminpass")
+	//Tags still in use can not be deleted
+	if response, _ := admin.do(t, "DELETE", "/api/Tag/"+strconv.FormatUint(fixture.Tags["cat"], 10), nil); response.StatusCode != http.StatusInternalServerError {
+		t.Errorf("deleting a tag in use returned %d", response.StatusCode)
+	}
+	if response, body := admin.do(t, "DELETE", unusedPath, nil); response.StatusCode != http.StatusOK {
+		t.Fatalf("admin delete returned %d: %s", response.StatusCode, body)
+	}
+	admin.getJSON(t, unusedPath, http.StatusNotFound, nil)
+}
+
+func TestTagsGetAPIRouter(t *testing.T) {
+	seedDatabase(t)
+	server := newTestServer(t)
+	client := newTestClient(t, server, "viewer", "viewerpass")
+
+	tests := map[string][]string{
+		"":    {"cat", "dog", "kitty", "outdoor", "unused"},
+		"o":   {"dog", "outdoor"},
+		"CAT": {"cat"},
+		"zzz": nil,
+	}
+	for query, expected := range tests {
+		var result TagSearchResult
+		client.getJSON(t, "/api/Tags?tagNameQuery="+query, http.StatusOK, &result)
+		var names []string
+		for _, tag := range result.Tags {
+			names = append(names, tag.Name)
+		}
+		if len(names) != len(expected) || result.ResultCount != uint64(len(expected)) {
+			t.Errorf("tag query %q returned %v (count %d), expected %v", query, names, result.ResultCount, expected)
+			continue
+		}
+		for index := range names {
+			if names[index] != expected[index] {
+				t.Errorf("tag query %q returned %v, expected %v", query, names, expected)
+				break
+			}
+		}
+	}
+}
+
+func TestTagImplicationsAPIRouter(t *testing.T) {
+	fixture := seedDatabase(t)
+	server := newTestServer(t)
+	dogPath := "/api/Tag/" + strconv.FormatUint(fixture.Tags["dog"], 10) + "/Implications"
+
+	viewer := newTestClient(t, server, "viewer", "viewerpass")
+	if response, body := viewer.do(t, "POST", dogPath, map[string]string{"ImpliedTag": "unused"}); response.StatusCode != http.StatusForbidden {
+		t.Fatalf("viewer add returned %d: %s", response.StatusCode, body)
+	}
+
+	admin := newTestClient(t, server, "admin", "adminpass")
+	response, body := admin.do(t, "POST", dogPath, map[string]string{"ImpliedTag": "unused"})
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("admin add returned %d: %s", response.StatusCode, body)
+	}
+	var implications []interfaces.TagImplicationInformation
+	if err := json.Unmarshal(body, &implications); err != nil || len(implications) != 1 || implications[0].TagName != "dog" || implications[0].ImpliedTagName != "unused" {
+		t.Fatalf("add returned %s, %v", body, err)
+	}
+	//The reverse rule would be a cycle
+	if response, body := admin.do(t, "POST", "/api/Tag/"+strconv.FormatUint(fixture.Tags["unused"], 10)+"/Implications", map[string]string{"ImpliedTag": "dog"}); response.StatusCode != http.StatusBadRequest {
+		t.Errorf("cyclic add returned %d: %s", response.StatusCode, body)
+	}
+	//Aliases are swapped for the tag they alias
+	outdoorPath := "/api/Tag/" + strconv.FormatUint(fixture.Tags["outdoor"], 10) + "/Implications"
+	if response, body := admin.do(t, "POST", outdoorPath, map[string]string{"ImpliedTag": "kitty"}); response.StatusCode != http.StatusOK {
+		t.Fatalf("alias add returned %d: %s", response.StatusCode, body)
+	}
+	viewer.getJSON(t, "/api/Tag/"+strconv.FormatUint(fixture.Tags["cat"], 10)+"/Implications", http.StatusOK, &implications)
+	if len(implications) != 1 || implications[0].TagID != fixture.Tags["outdoor"] || implications[0].ImpliedTagID != fixture.Tags["cat"] {
+		t.Errorf("cat implications are %+v", implications)
+	}
+
+	//Tags added from now on bring the tags they imply
+	if err := database.DBInterface.AddTag([]uint64{fixture.Tags["outdoor"]}, fixture.Images["four"], fixture.AdminID); err != nil {
+		t.Fatalf("AddTag: %v", err)
+	}
+	tags, err := database.DBInterface.GetImageTags(fixture.Images["four"])
+	if err != nil || len(tags) != 2 {
+		t.Errorf("image four has tags %+v, %v", tags, err)
+	}
+
+	implicationPath := outdoorPath + "/" + strconv.FormatUint(fixture.Tags["cat"], 10)
+	if response, body := admin.do(t, "DELETE", implicationPath, nil); response.StatusCode != http.StatusOK {
+		t.Fatalf("delete returned %d: %s", response.StatusCode, body)
+	}
+	if response, _ := admin.do(t, "DELETE", implicationPath, nil); response.StatusCode != http.StatusNotFound {
+		t.Errorf("deleting again returned %d", response.StatusCode)
+	}
+}
@@ -0,0 +1,52 @@
+package routers
+
+import (
+	"errors"
+	"go-image-board/database"
+	"go-image-board/interfaces"
+	"go-image-board/logging"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+//MaxBanReasonLength is the longest ban reason accepted, in characters
+const MaxBanReasonLength = 512
+
+//ValidateBan trims the reason of a new ban and converts its address range to CIDR notation, then ensures it is complete and does not ban BannerIP, the address of the moderator placing it
+func ValidateBan(Ban *interfaces.BanInformation, BannerIP string) error {
+	Ban.Reason = strings.TrimSpace(Ban.Reason)
+	if Ban.Reason == "" {
+		return errors.New("a reason is required")
+	}
+	if utf8.RuneCountInString(Ban.Reason) > MaxBanReasonLength {
+		return errors.New("the reason cannot be longer than " + strconv.Itoa(MaxBanReasonLength) + " characters")
+	}
+	if strings.TrimSpace(Ban.IPRange) != "" {
+		IPRange, err := interfaces.ParseIPRange(Ban.IPRange)
+		if err != nil {
+			return err
+		}
+		Ban.IPRange = IPRange
+	}
+	if err := Ban.Validate(); err != nil {
+		return err
+	}
+	if Ban.Applies(Ban.BannerID, BannerIP) {
+		return errors.New("you cannot ban yourself")
+	}
+	return nil
+}
+
+//GetActiveBan returns the active ban on the account UserID, or on a range containing IP, if there is one. Use 0 for UserID to only check the address.
+//Failing to get the bans is logged and returned, so that callers can refuse the request instead of letting a banned user through
+func GetActiveBan(UserID uint64, IP string) (interfaces.BanInformation, bool, error) {
+	bans, err := database.DBInterface.GetActiveBansOn(UserID, IP)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "banhelpers/GetActiveBan", strconv.FormatUint(UserID, 10), logging.ResultFailure, []string{"Failed to get bans", IP, err.Error()})
+		return interfaces.BanInformation{}, false, err
+	}
+	ban, isBanned := interfaces.FindBan(bans, UserID, IP, time.Now())
+	return ban, isBanned, nil
+}
@@ -0,0 +1,114 @@
+package routers
+
+import (
+	"go-image-board/database"
+	"go-image-board/interfaces"
+	"go-image-board/logging"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//ModBansGetRouter serves get requests to /mod/bans, listing every active ban
+func ModBansGetRouter(responseWriter http.ResponseWriter, request *http.Request) {
+	TemplateInput := getTemplateInputFromRequest(responseWriter, request)
+	if TemplateInput.UserPermissions.HasPermission(interfaces.DisableUser) != true {
+		TemplateInput.HTMLMessage += template.HTML("You do not have permission to view bans.<br>")
+		redirectWithFlash(responseWriter, request, "/mod", TemplateInput.HTMLMessage, "ModFail")
+		return
+	}
+
+	var err error
+	TemplateInput.Bans, err = database.DBInterface.GetActiveBans()
+	if err != nil {
+		TemplateInput.HTMLMessage += template.HTML("Failed to get bans.<br>")
+		logging.WriteLog(logging.LogLevelError, "banrouter/ModBansGetRouter", TemplateInput.UserInformation.GetCompositeID(), logging.ResultFailure, []string{"Failed to get bans", err.Error()})
+	}
+	TemplateInput.TotalResults = uint64(len(TemplateInput.Bans))
+
+	replyWithTemplate("modbans.html", TemplateInput, responseWriter, request)
+}
+
+//ModBansPostRouter serves post requests to /mod/bans, banning an account or a range of addresses for a number of hours, or permanently, and lifting bans early
+func ModBansPostRouter(responseWriter http.ResponseWriter, request *http.Request) {
+	TemplateInput := getTemplateInputFromRequest(responseWriter, request)
+	if !TemplateInput.IsLoggedOn() {
+		redirectWithFlash(responseWriter, request, "/logon", "You must be logged in to ban users", "LogonRequired")
+		return
+	}
+	command := request.FormValue("command")
+	if TemplateInput.UserPermissions.HasPermission(interfaces.DisableUser) != true {
+		TemplateInput.HTMLMessage += template.HTML("You do not have permission to ban users.<br>")
+		go WriteAuditLog(TemplateInput.UserInformation.ID, "BAN-HANDLE", TemplateInput.UserInformation.Name+" failed to "+command+" ban. Insufficient permissions.")
+		redirectWithFlash(responseWriter, request, "/mod", TemplateInput.HTMLMessage, "ModFail")
+		return
+	}
+
+	switch command {
+	case "ban":
+		ban := interfaces.BanInformation{IPRange: request.FormValue("IPRange"), Reason: request.FormValue("Reason"), BannerID: TemplateInput.UserInformation.ID}
+		target := strings.TrimSpace(request.FormValue("IPRange"))
+		if userName := strings.ToLower(strings.TrimSpace(request.FormValue("userName"))); userName != "" {
+			userID, err := database.DBInterface.GetUserID(userName)
+			if err != nil {
+				TemplateInput.HTMLMessage += template.HTML("Failed to find user.<br>")
+				redirectWithFlash(responseWriter, request, "/mod/bans", TemplateInput.HTMLMessage, "ModFail")
+				return
+			}
+			ban.UserID = userID
+			target = userName
+		}
+		//Hours is the length of the ban, 0 for a ban that does not expire
+		hours, err := strconv.ParseUint(request.FormValue("Hours"), 10, 32)
+		if err != nil {
+			TemplateInput.HTMLMessage += template.HTML("Failed to parse the length of the ban.<br>")
+			redirectWithFlash(responseWriter, request, "/mod/bans", TemplateInput.HTMLMessage, "ModFail")
+			return
+		}
+		if hours != 0 {
+			ban.ExpiryTime = time.Now().Add(time.Duration(hours) * time.Hour)
+		}
+		if err := ValidateBan(&ban, TemplateInput.UserInformation.IP); err != nil {
+			TemplateInput.HTMLMessage += template.HTML("Failed to ban, " + template.HTMLEscapeString(err.Error()) + ".<br>")
+			redirectWithFlash(responseWriter, request, "/mod/bans", TemplateInput.HTMLMessage, "ModFail")
+			return
+		}
+		if ban.IPRange != "" {
+			target = ban.IPRange
+		}
+		banID, err := database.DBInterface.NewBan(ban)
+		if err != nil {
+			TemplateInput.HTMLMessage += template.HTML("Failed to ban due to a database error.<br>")
+			redirectWithFlash(responseWriter, request, "/mod/bans", TemplateInput.HTMLMessage, "ModFail")
+			return
+		}
+		length := "permanently"
+		if ban.IsPermanent() == false {
+			length = "until " + ban.ExpiryTime.UTC().Format(time.RFC3339)
+		}
+		go WriteAuditLog(TemplateInput.UserInformation.ID, "BAN-CREATE", TemplateInput.UserInformation.Name+" banned "+target+" "+length+". Ban "+strconv.FormatUint(banID, 10)+". Reason: "+ban.Reason)
+		TemplateInput.HTMLMessage += template.HTML("Banned " + template.HTMLEscapeString(target) + ".<br>")
+		redirectWithFlash(responseWriter, request, "/mod/bans", TemplateInput.HTMLMessage, "ModSuccess")
+		return
+	case "lift":
+		banID, err := strconv.ParseUint(request.FormValue("ID"), 10, 32)
+		if err != nil {
+			TemplateInput.HTMLMessage += template.HTML("Failed to parse ban ID.<br>")
+			redirectWithFlash(responseWriter, request, "/mod/bans", TemplateInput.HTMLMessage, "ModFail")
+			return
+		}
+		if err := database.DBInterface.LiftBan(banID, TemplateInput.UserInformation.ID); err != nil {
+			TemplateInput.HTMLMessage += template.HTML("That ban does not exist or was already lifted.<br>")
+			redirectWithFlash(responseWriter, request, "/mod/bans", TemplateInput.HTMLMessage, "ModFail")
+			return
+		}
+		go WriteAuditLog(TemplateInput.UserInformation.ID, "BAN-LIFT", TemplateInput.UserInformation.Name+" lifted ban "+strconv.FormatUint(banID, 10)+".")
+		TemplateInput.HTMLMessage += template.HTML("Ban lifted.<br>")
+		redirectWithFlash(responseWriter, request, "/mod/bans", TemplateInput.HTMLMessage, "ModSuccess")
+		return
+	}
+	TemplateInput.HTMLMessage += template.HTML("Command not recognized or form submitted incorrectly.<br>")
+	redirectWithFlash(responseWriter, request, "/mod/bans", TemplateInput.HTMLMessage, "ModFail")
+}
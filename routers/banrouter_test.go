@@ -0,0 +1,183 @@
+package routers
+
+import (
+	"errors"
+	"go-image-board/database"
+	"go-image-board/interfaces"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBanRouters(t *testing.T) {
+	fixture := seedDatabase(t)
+	server := newTestServer(t)
+	admin := newTestClient(t, server)
+	admin.logon(t, "admin", "adminpass")
+	if err := database.DBInterface.CreateUser("viewer", []byte("viewerpass"), "viewer@example.com", 0); err != nil {
+		t.Fatal(err)
+	}
+	viewer := newTestClient(t, server)
+	viewer.logon(t, "viewer", "viewerpass")
+
+	//Only moderators that may disable users can ban
+	if response, _ := viewer.get(t, "/mod/bans"); response.StatusCode != http.StatusFound {
+		t.Errorf("bans returned %d to the viewer", response.StatusCode)
+	}
+	if response, _ := viewer.postForm(t, "/mod/bans", url.Values{"command": {"ban"}, "userName": {"admin"}, "Hours": {"1"}, "Reason": {"Revenge"}}); strings.Contains(response.Header.Get("Location"), "ModFail") == false {
+		t.Errorf("viewer ban redirected to %q", response.Header.Get("Location"))
+	}
+
+	//Bans need a reason and a single target, which is not the moderator
+	for _, form := range []url.Values{
+		{"command": {"ban"}, "userName": {"viewer"}, "Hours": {"1"}, "Reason": {" "}},
+		{"command": {"ban"}, "userName": {"viewer"}, "IPRange": {"192.0.2.1"}, "Hours": {"1"}, "Reason": {"Spam"}},
+		{"command": {"ban"}, "IPRange": {"192.0.2.300"}, "Hours": {"1"}, "Reason": {"Spam"}},
+		{"command": {"ban"}, "userName": {"viewer"}, "Hours": {"soon"}, "Reason": {"Spam"}},
+		{"command": {"ban"}, "userName": {"admin"}, "Hours": {"1"}, "Reason": {"Oops"}},
+		{"command": {"ban"}, "IPRange": {"127.0.0.0/8"}, "Hours": {"0"}, "Reason": {"Oops"}},
+	} {
+		if response, _ := admin.postForm(t, "/mod/bans", form); strings.Contains(response.Header.Get("Location"), "ModFail") == false {
+			t.Errorf("ban %v redirected to %q", form, response.Header.Get("Location"))
+		}
+	}
+	if bans, _ := database.DBInterface.GetActiveBans(); len(bans) != 0 {
+		t.Fatalf("invalid bans were added, %+v", bans)
+	}
+
+	//A ban ends the session of the banned user and tells them why
+	if response, _ := admin.postForm(t, "/mod/bans", url.Values{"command": {"ban"}, "userName": {"viewer"}, "Hours": {"24"}, "Reason": {" Spamming comments "}}); strings.Contains(response.Header.Get("Location"), "ModSuccess") == false {
+		t.Fatalf("ban redirected to %q", response.Header.Get("Location"))
+	}
+	bans, err := database.DBInterface.GetActiveBans()
+	if err != nil || len(bans) != 1 {
+		t.Fatalf("active bans are %+v, %v", bans, err)
+	}
+	ban := bans[0]
+	if ban.UserName != "viewer" || ban.BannerName != "admin" || ban.Reason != "Spamming comments" || ban.ExpiryTime.Sub(time.Now()) < 23*time.Hour {
+		t.Fatalf("ban is %+v", ban)
+	}
+	if _, body := admin.get(t, "/mod/bans"); strings.Contains(body, "Spamming comments") == false {
+		t.Error("bans page does not list the ban")
+	}
+	response, body := viewer.get(t, "/reports")
+	if response.StatusCode != http.StatusFound {
+		t.Errorf("banned user can still view their reports, %d", response.StatusCode)
+	}
+	if _, body = viewer.get(t, "/images"); strings.Contains(body, "Your account is banned until") == false || strings.Contains(body, "Spamming comments") == false {
+		t.Error("banned user is not told about the ban")
+	}
+	response, _ = viewer.postForm(t, "/logon", url.Values{"command": {"validate"}, "userName": {"viewer"}, "password": {"viewerpass"}})
+	if location := response.Header.Get("Location"); location != "/logon?flash=LogonFailed" {
+		t.Fatalf("banned logon redirected to %q", location)
+	}
+
+	//Lifting the ban lets the user back in
+	if response, _ := admin.postForm(t, "/mod/bans", url.Values{"command": {"lift"}, "ID": {strconv.FormatUint(ban.ID, 10)}}); strings.Contains(response.Header.Get("Location"), "ModSuccess") == false {
+		t.Fatalf("lift redirected to %q", response.Header.Get("Location"))
+	}
+	if response, _ := admin.postForm(t, "/mod/bans", url.Values{"command": {"lift"}, "ID": {strconv.FormatUint(ban.ID, 10)}}); strings.Contains(response.Header.Get("Location"), "ModFail") == false {
+		t.Errorf("lifting twice redirected to %q", response.Header.Get("Location"))
+	}
+	if lifted, _ := database.DBInterface.GetBan(ban.ID); lifted.LifterID != fixture.AdminID || lifted.LiftTime.IsZero() {
+		t.Errorf("lifted ban is %+v", lifted)
+	}
+	viewer.logon(t, "viewer", "viewerpass")
+
+	//Expired bans lift themselves
+	viewerID, _ := database.DBInterface.GetUserID("viewer")
+	if _, err := database.DBInterface.NewBan(interfaces.BanInformation{UserID: viewerID, Reason: "Old news", BannerID: fixture.AdminID, ExpiryTime: time.Now().Add(-time.Minute)}); err != nil {
+		t.Fatal(err)
+	}
+	if response, _ := viewer.get(t, "/reports"); response.StatusCode != http.StatusOK {
+		t.Errorf("expired ban still applies, %d", response.StatusCode)
+	}
+
+	//Address bans stop logons and new accounts from the range
+	if _, err := database.DBInterface.NewBan(interfaces.BanInformation{IPRange: "127.0.0.0/8", Reason: "Open proxy", BannerID: fixture.AdminID}); err != nil {
+		t.Fatal(err)
+	}
+	if response, _ := viewer.get(t, "/reports"); response.StatusCode != http.StatusFound {
+		t.Errorf("address ban does not end the session, %d", response.StatusCode)
+	}
+	anonymous := newTestClient(t, server)
+	response, _ = anonymous.postForm(t, "/logon", url.Values{"command": {"create"}, "userName": {"newcomer"}, "password": {"newpass"}, "confirmpassword": {"newpass"}, "eMail": {"newcomer@example.com"}})
+	if location := response.Header.Get("Location"); location != "/logon?flash=AccountFailed" {
+		t.Errorf("banned account creation redirected to %q", location)
+	}
+	if _, err := database.DBInterface.GetUserID("newcomer"); err == nil {
+		t.Error("account was created from a banned address")
+	}
+
+	//Bans are audited
+	expected := map[string]string{"BAN-CREATE": "admin banned viewer until", "BAN-LIFT": "admin lifted ban " + strconv.FormatUint(ban.ID, 10)}
+	for deadline := time.Now().Add(2 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		logs := auditLogs(t)
+		missing := ""
+		for logType, info := range expected {
+			if strings.Contains(logs[logType], info) == false {
+				missing = logType
+			}
+		}
+		if missing == "" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%s was not audited, logs are %v", missing, logs)
+		}
+	}
+}
+
+//failingBansDB is a database whose ban lookups fail
+type failingBansDB struct {
+	interfaces.DBInterface
+}
+
+func (failingBansDB) GetActiveBansOn(UserID uint64, IP string) ([]interfaces.BanInformation, error) {
+	return nil, errors.New("connection lost")
+}
+
+func TestBanCheckFailureRefusesLogon(t *testing.T) {
+	seedDatabase(t)
+	database.DBInterface = failingBansDB{database.DBInterface}
+	t.Cleanup(func() { database.DBInterface = database.DBInterface.(failingBansDB).DBInterface })
+	client := newTestClient(t, newTestServer(t))
+
+	//Without knowing whether the user is banned, they are not let in
+	response, _ := client.postForm(t, "/logon", url.Values{"command": {"validate"}, "userName": {"admin"}, "password": {"adminpass"}})
+	if location := response.Header.Get("Location"); location != "/logon?flash=LogonFailed" {
+		t.Errorf("logon redirected to %q", location)
+	}
+	response, _ = client.postForm(t, "/logon", url.Values{"command": {"create"}, "userName": {"newcomer"}, "password": {"newpass"}, "confirmpassword": {"newpass"}, "eMail": {"newcomer@example.com"}})
+	if location := response.Header.Get("Location"); location != "/logon?flash=AccountFailed" {
+		t.Errorf("account creation redirected to %q", location)
+	}
+	if _, err := database.DBInterface.GetUserID("newcomer"); err == nil {
+		t.Error("account was created without checking for bans")
+	}
+}
+
+//failingUserIDDB is a database that cannot find the ID of any user, though it still validates their password
+type failingUserIDDB struct {
+	interfaces.DBInterface
+}
+
+func (failingUserIDDB) GetUserID(UserName string) (uint64, error) {
+	return 0, errors.New("connection lost")
+}
+
+func TestLogonRefusedWithoutUserID(t *testing.T) {
+	seedDatabase(t)
+	database.DBInterface = failingUserIDDB{database.DBInterface}
+	t.Cleanup(func() { database.DBInterface = database.DBInterface.(failingUserIDDB).DBInterface })
+	client := newTestClient(t, newTestServer(t))
+
+	//An account ban cannot be checked without the account's ID
+	response, _ := client.postForm(t, "/logon", url.Values{"command": {"validate"}, "userName": {"admin"}, "password": {"adminpass"}})
+	if location := response.Header.Get("Location"); location != "/logon?flash=LogonFailed" {
+		t.Errorf("logon redirected to %q", location)
+	}
+}
@@ -9,9 +9,6 @@ import (
 	"html/template"
 	"net/http"
 	"net/url"
-	"os"
-	"path"
-	"path/filepath"
 	"strconv"
 	"strings"
 )
@@ -62,6 +59,12 @@ func CollectionGetRouter(responseWriter http.ResponseWriter, request *http.Reque
 		redirectWithFlash(responseWriter, request, "/collections?SearchTerms="+url.QueryEscape(userQuery), TemplateInput.HTMLMessage, "CollectionError")
 		return
 	}
+	//Collections in the trash are only shown to those who can restore them
+	if collectionInfo.InTrash && TemplateInput.UserPermissions.HasPermission(interfaces.RemoveCollections) != true {
+		TemplateInput.HTMLMessage += template.HTML("Failed to get the requested collection.<br>")
+		redirectWithFlash(responseWriter, request, "/collections?SearchTerms="+url.QueryEscape(userQuery), TemplateInput.HTMLMessage, "CollectionError")
+		return
+	}
 
 	TemplateInput.CollectionInfo = collectionInfo
 	//Parse tag results for next query
@@ -80,6 +83,8 @@ func CollectionGetRouter(responseWriter http.ResponseWriter, request *http.Reque
 		TemplateInput.HTMLMessage += template.HTML("Failed to get collection members.<br>")
 	}
 
+	loadComments(&TemplateInput, request, 0, collectionInfo.ID, "SearchTerms="+url.QueryEscape(userQuery)+"&ID="+strconv.FormatUint(collectionInfo.ID, 10)+"&PageStart="+strconv.FormatUint(pageStart, 10), "/collection")
+
 	replyWithTemplate("collection.html", TemplateInput, responseWriter, request)
 }
 
@@ -90,6 +95,32 @@ func CollectionPostRouter(responseWriter http.ResponseWriter, request *http.Requ
 	var collectionID uint64
 
 	switch cmd := request.FormValue("command"); cmd {
+	case "AddComment", "EditComment", "DeleteComment", "RestoreComment":
+		if TemplateInput.IsLoggedOn() == false {
+			//Redirect to logon
+			redirectWithFlash(responseWriter, request, "/logon", "You must be logged in to comment on a collection", "LogonRequired")
+			return
+		}
+		collectionID, err := strconv.ParseUint(request.FormValue("ID"), 10, 32)
+		if err != nil {
+			TemplateInput.HTMLMessage += template.HTML("Failed to get collection with that ID.<br>")
+			redirectWithFlash(responseWriter, request, "/collections?SearchTerms="+url.QueryEscape(userQuery), TemplateInput.HTMLMessage, "CollectionFailed")
+			return
+		}
+		CollectionInfo, err := database.DBInterface.GetCollection(collectionID)
+		if err != nil {
+			TemplateInput.HTMLMessage += template.HTML("Failed to get collection with that ID.<br>")
+			redirectWithFlash(responseWriter, request, "/collections?SearchTerms="+url.QueryEscape(userQuery), TemplateInput.HTMLMessage, "CollectionFailed")
+			return
+		}
+		if CollectionInfo.InTrash {
+			TemplateInput.HTMLMessage += template.HTML("Comments on collections in the trash cannot be changed.<br>")
+			redirectWithFlash(responseWriter, request, "/collection?ID="+strconv.FormatUint(collectionID, 10)+"&SearchTerms="+url.QueryEscape(userQuery), TemplateInput.HTMLMessage, "CollectionFailed")
+			return
+		}
+		flashName := handleCommentCommand(&TemplateInput, request, 0, collectionID)
+		redirectWithFlash(responseWriter, request, "/collection?ID="+strconv.FormatUint(collectionID, 10)+"&SearchTerms="+url.QueryEscape(userQuery), TemplateInput.HTMLMessage, flashName)
+		return
 	case "deletemember": //Remove a single image from a collection, and if last image, the collection itself
 		if TemplateInput.UserInformation.Name == "" {
 			//Redirect to logon
@@ -322,15 +353,15 @@ func CollectionPostRouter(responseWriter http.ResponseWriter, request *http.Requ
 			return
 		}
 
-		//Permission validated, now delete (Collection)
-		if err := database.DBInterface.DeleteCollection(collectionID); err != nil {
+		//Permission validated, now move to trash (Collection)
+		if err := database.DBInterface.TrashCollection(collectionID, TemplateInput.UserInformation.ID, false); err != nil {
 			TemplateInput.HTMLMessage += template.HTML("Failed to delete collection. SQL Error.<br>")
 			go WriteAuditLogByName(TemplateInput.UserInformation.Name, "DELETE-COLLECTION", TemplateInput.UserInformation.Name+" failed to delete collection. "+request.FormValue("ID")+", "+err.Error())
 			redirectWithFlash(responseWriter, request, "/collection?ID="+strconv.FormatUint(collectionID, 10)+"&SearchTerms="+url.QueryEscape(userQuery), TemplateInput.HTMLMessage, "CollectionFailed")
 			return
 		}
-		go WriteAuditLogByName(TemplateInput.UserInformation.Name, "DELETE-COLLECTION", TemplateInput.UserInformation.Name+" deleted collection. "+request.FormValue("ID")+", "+CollectionInfo.Name)
-		TemplateInput.HTMLMessage += template.HTML("Successfully deleted collection " + template.HTMLEscapeString(CollectionInfo.Name) + ".<br>")
+		go WriteAuditLogByName(TemplateInput.UserInformation.Name, "DELETE-COLLECTION", TemplateInput.UserInformation.Name+" moved collection to trash. "+request.FormValue("ID")+", "+CollectionInfo.Name)
+		TemplateInput.HTMLMessage += template.HTML("Moved collection " + template.HTMLEscapeString(CollectionInfo.Name) + " to the trash.<br>")
 		redirectWithFlash(responseWriter, request, "/collections?"+"&SearchTerms="+url.QueryEscape(TemplateInput.OldQuery), TemplateInput.HTMLMessage, "Success")
 		return
 	case "deletecollectionandmembers":
@@ -391,30 +422,16 @@ func CollectionPostRouter(responseWriter http.ResponseWriter, request *http.Requ
 			return
 		}
 
-		//Permission validated, now delete (Collection)
-		if err := database.DBInterface.DeleteCollection(collectionID); err != nil {
+		//Permission validated, now move to trash (Collection and images)
+		if err := database.DBInterface.TrashCollection(collectionID, TemplateInput.UserInformation.ID, true); err != nil {
 			TemplateInput.HTMLMessage += template.HTML("Failed to delete collection. SQL Error.<br>")
 			go WriteAuditLogByName(TemplateInput.UserInformation.Name, "DELETE-COLLECTION", TemplateInput.UserInformation.Name+" failed to delete collection. "+request.FormValue("ID")+", "+err.Error())
 			redirectWithFlash(responseWriter, request, "/collection?ID="+strconv.FormatUint(collectionID, 10)+"&SearchTerms="+url.QueryEscape(TemplateInput.OldQuery), TemplateInput.HTMLMessage, "DeleteFailed")
 			return
 		}
 
-		//Delete images
-		for _, ImageInfo := range CollectionMembers {
-			err = database.DBInterface.DeleteImage(ImageInfo.ID)
-			if err != nil {
-				TemplateInput.HTMLMessage += template.HTML("Failed to delete image " + strconv.FormatUint(ImageInfo.ID, 10) + ".<br>")
-				go WriteAuditLogByName(TemplateInput.UserInformation.Name, "DELETE-IMAGE", TemplateInput.UserInformation.Name+" failed to delete image.")
-			} else {
-				//Delete Image from Disk
-				go os.Remove(path.Join(config.Configuration.ImageDirectory, ImageInfo.Location))
-				//Delete thumbnail from disk
-				go os.Remove(path.Join(config.Configuration.ImageDirectory, "thumbs"+string(filepath.Separator)+ImageInfo.Location+".png"))
-			}
-		}
-
-		go WriteAuditLogByName(TemplateInput.UserInformation.Name, "DELETE-COLLECTION", TemplateInput.UserInformation.Name+" deleted collection. "+request.FormValue("ID")+", "+CollectionInfo.Name)
-		TemplateInput.HTMLMessage += template.HTML("Successfully deleted collection " + template.HTMLEscapeString(CollectionInfo.Name) + ".<br>")
+		go WriteAuditLogByName(TemplateInput.UserInformation.Name, "DELETE-COLLECTION", TemplateInput.UserInformation.Name+" moved collection and members to trash. "+request.FormValue("ID")+", "+CollectionInfo.Name)
+		TemplateInput.HTMLMessage += template.HTML("Moved collection " + template.HTMLEscapeString(CollectionInfo.Name) + " and its members to the trash.<br>")
 		redirectWithFlash(responseWriter, request, "/collections?SearchTerms="+url.QueryEscape(TemplateInput.OldQuery), TemplateInput.HTMLMessage, "DeleteSuccess")
 		return
 	}
@@ -0,0 +1,56 @@
+package routers
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestCollectionGetRouter(t *testing.T) {
+	fixture := seedDatabase(t)
+	server := newTestServer(t)
+	client := newTestClient(t, server)
+
+	response, body := client.get(t, "/collection?ID="+strconv.FormatUint(fixture.CollectionID, 10))
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("collection page returned %d", response.StatusCode)
+	}
+	if strings.Contains(body, "<h3>Pets</h3>") == false {
+		t.Errorf("collection page does not contain its name")
+	}
+	if IDs := linkedImageIDs(body); equalIDs(IDs, fixture.fixtureImageIDs("three")) == false {
+		t.Errorf("collection page linked %v, expected only three", IDs)
+	}
+
+	response, _ = client.get(t, "/collection?ID=abc")
+	if location := response.Header.Get("Location"); response.StatusCode != http.StatusFound || strings.HasPrefix(location, "/collections") == false {
+		t.Errorf("invalid collection returned %d, redirecting to %q", response.StatusCode, location)
+	}
+}
+
+func TestCollectionsRouter(t *testing.T) {
+	fixture := seedDatabase(t)
+	server := newTestServer(t)
+	client := newTestClient(t, server)
+	collectionLink := `href="/collection?ID=` + strconv.FormatUint(fixture.CollectionID, 10) + `&`
+
+	tests := map[string]bool{
+		"":          true,
+		"dog":       true,
+		"-cat":      false,
+		"outdoor":   false,
+		"name:pets": true,
+		"name:dogs": false,
+	}
+	for query, expected := range tests {
+		response, body := client.get(t, "/collections?SearchTerms="+url.QueryEscape(query))
+		if response.StatusCode != http.StatusOK {
+			t.Fatalf("query %q returned %d", query, response.StatusCode)
+		}
+		if found := strings.Contains(strings.ReplaceAll(body, "&amp;", "&"), collectionLink); found != expected {
+			t.Errorf("query %q listed Pets: %v, expected %v", query, found, expected)
+		}
+	}
+}
@@ -59,6 +59,9 @@ func CollectionsRouter(responseWriter http.ResponseWriter, request *http.Request
 		}
 	} else {
 		logging.WriteLog(logging.LogLevelError, "CollectionQueryRouter/CollectionsRouter", TemplateInput.UserInformation.GetCompositeID(), logging.ResultFailure, []string{"Failed to validate tags", TemplateInput.OldQuery, err.Error()})
+		if queryErr, isQueryError := err.(interfaces.QueryError); isQueryError {
+			TemplateInput.HTMLMessage += template.HTML(template.HTMLEscapeString(queryErr.Message) + "<br>")
+		}
 	}
 
 	TemplateInput.Tags = userQTags
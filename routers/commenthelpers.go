@@ -0,0 +1,167 @@
+package routers
+
+import (
+	"errors"
+	"go-image-board/config"
+	"go-image-board/database"
+	"go-image-board/interfaces"
+	"go-image-board/logging"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+//MaxCommentLength is the longest comment body accepted, in characters
+const MaxCommentLength = 4096
+
+//ValidateCommentBody trims a comment body, then ensures it is neither empty nor too long
+func ValidateCommentBody(Body string) (string, error) {
+	Body = strings.TrimSpace(Body)
+	if Body == "" {
+		return Body, errors.New("the comment cannot be empty")
+	}
+	if utf8.RuneCountInString(Body) > MaxCommentLength {
+		return Body, errors.New("the comment cannot be longer than " + strconv.Itoa(MaxCommentLength) + " characters")
+	}
+	return Body, nil
+}
+
+//CanEditComment returns whether a user may change the body of a comment. Only its author may, while they can still post comments
+func CanEditComment(UserID uint64, Permissions interfaces.UserPermission, Comment interfaces.CommentInformation) bool {
+	return UserID != 0 && Comment.UserID == UserID && Permissions.HasPermission(interfaces.PostComments) && Comment.Deleted == false
+}
+
+//CanDeleteComment returns whether a user may delete a comment. Restoring one always requires ModerateComments
+func CanDeleteComment(UserID uint64, Permissions interfaces.UserPermission, Comment interfaces.CommentInformation) bool {
+	if Permissions.HasPermission(interfaces.ModerateComments) {
+		return true
+	}
+	return UserID != 0 && Comment.UserID == UserID && config.Configuration.UsersControlOwnObjects
+}
+
+//HideDeletedComment blanks the body of a deleted comment, unless the user may moderate comments
+func HideDeletedComment(Comment *interfaces.CommentInformation, Permissions interfaces.UserPermission) {
+	if Comment.Deleted && Permissions.HasPermission(interfaces.ModerateComments) != true {
+		Comment.Body = ""
+	}
+}
+
+//GetVisibleComments returns a page of the threads on an image, or on a collection when ImageID is 0, with deleted comments hidden as HideDeletedComment does
+func GetVisibleComments(ImageID uint64, CollectionID uint64, PageStart uint64, PageStride uint64, Permissions interfaces.UserPermission) ([]interfaces.CommentInformation, uint64, error) {
+	comments, MaxCount, err := database.DBInterface.GetComments(ImageID, CollectionID, PageStart, PageStride)
+	for index := range comments {
+		HideDeletedComment(&comments[index], Permissions)
+	}
+	return comments, MaxCount, err
+}
+
+//loadComments fills TemplateInput with the page of comments requested by CommentStart, for an image, or a collection when ImageID is 0.
+//Query and PageURL are used for the links of the comment page menu
+func loadComments(TemplateInput *templateInput, request *http.Request, ImageID uint64, CollectionID uint64, Query string, PageURL string) {
+	commentStart, _ := strconv.ParseUint(request.FormValue("CommentStart"), 10, 32) //Either parses fine, or is 0, both works
+	commentStride := config.Configuration.PageStride
+	comments, MaxCount, err := GetVisibleComments(ImageID, CollectionID, commentStart, commentStride, TemplateInput.UserPermissions)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "commenthelpers/loadComments", TemplateInput.UserInformation.GetCompositeID(), logging.ResultFailure, []string{"Failed to load comments", err.Error()})
+		TemplateInput.HTMLMessage += template.HTML("Failed to load comments.<br>")
+		return
+	}
+	TemplateInput.Comments = comments
+	if MaxCount > commentStride {
+		TemplateInput.CommentPageMenu, _ = generatePageMenuWithParameter(int64(commentStart), int64(commentStride), int64(MaxCount), Query, PageURL, "CommentStart")
+	}
+}
+
+//handleCommentCommand performs the AddComment, EditComment, DeleteComment or RestoreComment command posted for an image, or a collection when ImageID is 0.
+//The outcome is added to TemplateInput.HTMLMessage, and the name of the flash to redirect with is returned
+func handleCommentCommand(TemplateInput *templateInput, request *http.Request, ImageID uint64, CollectionID uint64) string {
+	command := request.FormValue("command")
+	if command == "AddComment" {
+		if TemplateInput.UserPermissions.HasPermission(interfaces.PostComments) != true {
+			go WriteAuditLog(TemplateInput.UserInformation.ID, "COMMENT-ADD", TemplateInput.UserInformation.Name+" failed to comment. No permissions.")
+			TemplateInput.HTMLMessage += template.HTML("You do not have permission to post comments.<br>")
+			return "UpdateFailed"
+		}
+		body, err := ValidateCommentBody(request.FormValue("Body"))
+		if err != nil {
+			TemplateInput.HTMLMessage += template.HTML("Failed to post comment, " + template.HTMLEscapeString(err.Error()) + ".<br>")
+			return "UpdateFailed"
+		}
+		ParentID, _ := strconv.ParseUint(request.FormValue("ParentID"), 10, 64) //Either parses fine, or is 0 to start a thread
+		CommentID, err := database.DBInterface.NewComment(interfaces.CommentInformation{ImageID: ImageID, CollectionID: CollectionID, ParentID: ParentID, UserID: TemplateInput.UserInformation.ID, Body: body})
+		if err != nil {
+			logging.WriteLog(logging.LogLevelError, "commenthelpers/handleCommentCommand", TemplateInput.UserInformation.GetCompositeID(), logging.ResultFailure, []string{"Failed to add comment", err.Error()})
+			TemplateInput.HTMLMessage += template.HTML("Failed to post comment.<br>")
+			return "UpdateFailed"
+		}
+		go WriteAuditLog(TemplateInput.UserInformation.ID, "COMMENT-ADD", TemplateInput.UserInformation.Name+" posted comment "+strconv.FormatUint(CommentID, 10)+".")
+		TemplateInput.HTMLMessage += template.HTML("Comment posted.<br>")
+		return "UpdateSucceeded"
+	}
+
+	//Every other command changes an existing comment, which must be on the image or collection it was posted for
+	CommentID, err := strconv.ParseUint(request.FormValue("CommentID"), 10, 64)
+	if err != nil {
+		TemplateInput.HTMLMessage += template.HTML("Failed to parse comment id.<br>")
+		return "UpdateFailed"
+	}
+	comment, err := database.DBInterface.GetComment(CommentID)
+	if err != nil || comment.ImageID != ImageID || comment.CollectionID != CollectionID {
+		TemplateInput.HTMLMessage += template.HTML("No comment by that ID.<br>")
+		return "UpdateFailed"
+	}
+	switch command {
+	case "EditComment":
+		if CanEditComment(TemplateInput.UserInformation.ID, TemplateInput.UserPermissions, comment) != true {
+			go WriteAuditLog(TemplateInput.UserInformation.ID, "COMMENT-EDIT", TemplateInput.UserInformation.Name+" failed to edit comment "+strconv.FormatUint(CommentID, 10)+". No permissions.")
+			TemplateInput.HTMLMessage += template.HTML("You do not have permission to edit this comment.<br>")
+			return "UpdateFailed"
+		}
+		body, err := ValidateCommentBody(request.FormValue("Body"))
+		if err != nil {
+			TemplateInput.HTMLMessage += template.HTML("Failed to edit comment, " + template.HTMLEscapeString(err.Error()) + ".<br>")
+			return "UpdateFailed"
+		}
+		if body == comment.Body {
+			TemplateInput.HTMLMessage += template.HTML("The comment was not changed.<br>")
+			return "UpdateSucceeded"
+		}
+		if err := database.DBInterface.UpdateComment(CommentID, body, TemplateInput.UserInformation.ID); err != nil {
+			TemplateInput.HTMLMessage += template.HTML("Failed to edit comment.<br>")
+			return "UpdateFailed"
+		}
+		go WriteAuditLog(TemplateInput.UserInformation.ID, "COMMENT-EDIT", TemplateInput.UserInformation.Name+" edited comment "+strconv.FormatUint(CommentID, 10)+".")
+		TemplateInput.HTMLMessage += template.HTML("Comment edited.<br>")
+		return "UpdateSucceeded"
+	case "DeleteComment":
+		if CanDeleteComment(TemplateInput.UserInformation.ID, TemplateInput.UserPermissions, comment) != true {
+			go WriteAuditLog(TemplateInput.UserInformation.ID, "COMMENT-DELETE", TemplateInput.UserInformation.Name+" failed to delete comment "+strconv.FormatUint(CommentID, 10)+". No permissions.")
+			TemplateInput.HTMLMessage += template.HTML("You do not have permission to delete this comment.<br>")
+			return "UpdateFailed"
+		}
+		if err := database.DBInterface.DeleteComment(CommentID, TemplateInput.UserInformation.ID); err != nil {
+			TemplateInput.HTMLMessage += template.HTML("Failed to delete comment, " + template.HTMLEscapeString(err.Error()) + ".<br>")
+			return "UpdateFailed"
+		}
+		go WriteAuditLog(TemplateInput.UserInformation.ID, "COMMENT-DELETE", TemplateInput.UserInformation.Name+" deleted comment "+strconv.FormatUint(CommentID, 10)+".")
+		TemplateInput.HTMLMessage += template.HTML("Comment deleted.<br>")
+		return "UpdateSucceeded"
+	case "RestoreComment":
+		if TemplateInput.UserPermissions.HasPermission(interfaces.ModerateComments) != true {
+			go WriteAuditLog(TemplateInput.UserInformation.ID, "COMMENT-RESTORE", TemplateInput.UserInformation.Name+" failed to restore comment "+strconv.FormatUint(CommentID, 10)+". No permissions.")
+			TemplateInput.HTMLMessage += template.HTML("You do not have permission to restore comments.<br>")
+			return "UpdateFailed"
+		}
+		if err := database.DBInterface.RestoreComment(CommentID); err != nil {
+			TemplateInput.HTMLMessage += template.HTML("Failed to restore comment, " + template.HTMLEscapeString(err.Error()) + ".<br>")
+			return "UpdateFailed"
+		}
+		go WriteAuditLog(TemplateInput.UserInformation.ID, "COMMENT-RESTORE", TemplateInput.UserInformation.Name+" restored comment "+strconv.FormatUint(CommentID, 10)+".")
+		TemplateInput.HTMLMessage += template.HTML("Comment restored.<br>")
+		return "UpdateSucceeded"
+	}
+	TemplateInput.HTMLMessage += template.HTML("Unknown comment command.<br>")
+	return "UpdateFailed"
+}
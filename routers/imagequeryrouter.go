@@ -6,11 +6,13 @@ import (
 	"go-image-board/database"
 	"go-image-board/interfaces"
 	"go-image-board/logging"
+	"go-image-board/plugins/tagquery"
 	"html/template"
 	"math"
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
 )
 
 //ImageQueryRouter serves requests to /images
@@ -18,6 +20,21 @@ func ImageQueryRouter(responseWriter http.ResponseWriter, request *http.Request)
 	TemplateInput := getTemplateInputFromRequest(responseWriter, request)
 	userQuery := TemplateInput.OldQuery
 
+	//Run a saved search in place of SearchTerms
+	if request.FormValue("SavedSearch") != "" {
+		savedSearch, err := OpenSavedSearch(request.FormValue("SavedSearch"), TemplateInput.UserInformation.ID)
+		if err != nil {
+			logging.WriteLog(logging.LogLevelError, "imagequeryrouter/ImageQueryRouter", TemplateInput.UserInformation.GetCompositeID(), logging.ResultFailure, []string{"Failed to open saved search", request.FormValue("SavedSearch"), err.Error()})
+			TemplateInput.HTMLMessage += template.HTML("Failed to open that saved search.<br>")
+		} else if savedSearch.CollectionContext {
+			http.Redirect(responseWriter, request, "/collections?SearchTerms="+url.QueryEscape(strings.ToLower(savedSearch.Query)), http.StatusFound)
+			return
+		} else {
+			userQuery = strings.ToLower(savedSearch.Query)
+			TemplateInput.OldQuery = userQuery
+		}
+	}
+
 	//Change StremView if requested
 	if request.FormValue("ViewMode") == "stream" {
 		TemplateInput.ViewMode = "stream"
@@ -53,7 +70,7 @@ func ImageQueryRouter(responseWriter http.ResponseWriter, request *http.Request)
 	}
 
 	//Cleanup and format tags for use with SearchImages
-	userQTags, err := database.DBInterface.GetQueryTags(userQuery, false)
+	userQTags, err := database.DBInterface.GetQueryTags(tagquery.ResolveCurrentUser(userQuery, TemplateInput.UserInformation.Name), false)
 	if err == nil {
 		//if signed in, add user's global filters to query
 		if TemplateInput.UserInformation.Name != "" {
@@ -96,6 +113,9 @@ func ImageQueryRouter(responseWriter http.ResponseWriter, request *http.Request)
 		}
 	} else {
 		logging.WriteLog(logging.LogLevelError, "imagequeryrouter/ImageQueryRouter", TemplateInput.UserInformation.GetCompositeID(), logging.ResultFailure, []string{"Failed to validate tags", userQuery, err.Error()})
+		if queryErr, isQueryError := err.(interfaces.QueryError); isQueryError {
+			TemplateInput.HTMLMessage += template.HTML(template.HTMLEscapeString(queryErr.Message) + "<br>")
+		}
 	}
 
 	TemplateInput.Tags = userQTags
@@ -105,8 +125,28 @@ func ImageQueryRouter(responseWriter http.ResponseWriter, request *http.Request)
 	replyWithTemplate("imageresults.html", TemplateInput, responseWriter, request)
 }
 
+//FavoritesRouter serves requests to /favorites, showing the images a user has favorited, or the signed in user's own when UserName is not given
+func FavoritesRouter(responseWriter http.ResponseWriter, request *http.Request) {
+	TemplateInput := getTemplateInputFromRequest(responseWriter, request)
+	userName := strings.TrimSpace(request.FormValue("UserName"))
+	if userName == "" {
+		if TemplateInput.IsLoggedOn() == false {
+			redirectWithFlash(responseWriter, request, "/logon", "You must be logged in to see your favorites", "LogonRequired")
+			return
+		}
+		userName = TemplateInput.UserInformation.Name
+	}
+	http.Redirect(responseWriter, request, "/images?SearchTerms="+url.QueryEscape("fav:"+strings.ToLower(userName)), http.StatusFound)
+}
+
 //generatePageMenu generates a template.HTML menu given a few numbers. Returns a menu like "<< 1, 2, 3, [4], 5, 6, 7 >>"
 func generatePageMenu(Offset int64, Stride int64, Max int64, Query string, PageURL string) (template.HTML, error) {
+	return generatePageMenuWithParameter(Offset, Stride, Max, Query, PageURL, "PageStart")
+}
+
+//generatePageMenuWithParameter generates the same menu as generatePageMenu, with the offset of each page in PageParameter instead of PageStart.
+//This allows a second list on a page, such as comments, to be paged separately
+func generatePageMenuWithParameter(Offset int64, Stride int64, Max int64, Query string, PageURL string, PageParameter string) (template.HTML, error) {
 	//Validate parameters
 	if Offset < 0 || Stride <= 0 || Max < 0 || Offset > Max {
 		return template.HTML(""), errors.New("parameters don't make sense. validate your parameters are positive numbers")
@@ -133,7 +173,7 @@ func generatePageMenu(Offset int64, Stride int64, Max int64, Query string, PageU
 
 	for processPage := minPage; processPage <= maxPage; processPage++ {
 		if processPage != currentPage {
-			ToReturn = ToReturn + ", <a href=\"" + PageURL + "?" + Query + "&PageStart=" + strconv.FormatInt((processPage-1)*Stride, 10) + "\">" + strconv.FormatInt(processPage, 10) + "</a>"
+			ToReturn = ToReturn + ", <a href=\"" + PageURL + "?" + Query + "&" + PageParameter + "=" + strconv.FormatInt((processPage-1)*Stride, 10) + "\">" + strconv.FormatInt(processPage, 10) + "</a>"
 		} else {
 			ToReturn = ToReturn + ", " + strconv.FormatInt(currentPage, 10)
 		}
@@ -141,6 +181,6 @@ func generatePageMenu(Offset int64, Stride int64, Max int64, Query string, PageU
 
 	//Add end
 	endOffset := strconv.FormatInt((lastPage-1)*Stride, 10)
-	ToReturn = ToReturn + ", <a href=\"" + PageURL + "?" + Query + "&PageStart=" + endOffset + "\">&#x3E;&#x3E;</a>"
+	ToReturn = ToReturn + ", <a href=\"" + PageURL + "?" + Query + "&" + PageParameter + "=" + endOffset + "\">&#x3E;&#x3E;</a>"
 	return template.HTML(ToReturn), nil
 }
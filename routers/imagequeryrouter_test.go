@@ -0,0 +1,139 @@
+package routers
+
+import (
+	"go-image-board/database"
+	"go-image-board/interfaces"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestImageQueryRouter(t *testing.T) {
+	fixture := seedDatabase(t)
+	server := newTestServer(t)
+	client := newTestClient(t, server)
+
+	tests := map[string][]string{
+		"":                {"three", "two", "one"},
+		"cat":             {"three", "one"},
+		"cat -dog":        {"one"},
+		"outdoor":         {"two", "one"},
+		"incollection:y":  {"three"},
+		"cat dog outdoor": nil,
+	}
+	for query, expected := range tests {
+		response, body := client.get(t, "/images?SearchTerms="+url.QueryEscape(query))
+		if response.StatusCode != http.StatusOK {
+			t.Fatalf("query %q returned %d", query, response.StatusCode)
+		}
+		if IDs := linkedImageIDs(body); equalIDs(IDs, fixture.fixtureImageIDs(expected...)) == false {
+			t.Errorf("query %q linked %v, expected %v", query, IDs, expected)
+		}
+	}
+}
+
+func TestImageQueryRouterRandom(t *testing.T) {
+	fixture := seedDatabase(t)
+	server := newTestServer(t)
+	client := newTestClient(t, server)
+
+	response, _ := client.get(t, "/images?SearchType=Random&SearchTerms="+url.QueryEscape("cat dog"))
+	if response.StatusCode != http.StatusFound {
+		t.Fatalf("random query returned %d", response.StatusCode)
+	}
+	expected := "/image?ID=" + strconv.FormatUint(fixture.Images["three"], 10)
+	if location := response.Header.Get("Location"); strings.HasPrefix(location, expected+"&") == false {
+		t.Errorf("random query redirected to %q, expected %s", location, expected)
+	}
+}
+
+func TestImageQueryRouterSavedSearch(t *testing.T) {
+	fixture := seedDatabase(t)
+	server := newTestServer(t)
+	client := newTestClient(t, server)
+	client.logon(t, "admin", "adminpass")
+
+	searchID, err := database.DBInterface.NewSavedSearch(interfaces.SavedSearchInformation{UserID: fixture.AdminID, Name: "Cats", Query: "Cat -dog", Pinned: true})
+	if err != nil {
+		t.Fatalf("NewSavedSearch: %v", err)
+	}
+	searchPath := "/images?SavedSearch=" + strconv.FormatUint(searchID, 10)
+
+	//Pinned searches are linked from the header menu
+	response, body := client.get(t, "/images")
+	if response.StatusCode != http.StatusOK || strings.Contains(body, `<a href="`+searchPath+`"`) == false {
+		t.Errorf("header menu does not link the pinned search")
+	}
+	_, body = client.get(t, searchPath)
+	if IDs := linkedImageIDs(body); equalIDs(IDs, fixture.fixtureImageIDs("one")) == false {
+		t.Errorf("saved search linked %v, expected one", IDs)
+	}
+
+	//Saved searches of collections open the collection results
+	search, _ := database.DBInterface.GetSavedSearch(searchID)
+	search.CollectionContext = true
+	if err := database.DBInterface.UpdateSavedSearch(search); err != nil {
+		t.Fatalf("UpdateSavedSearch: %v", err)
+	}
+	response, _ = client.get(t, searchPath)
+	if location := response.Header.Get("Location"); response.StatusCode != http.StatusFound || location != "/collections?SearchTerms=cat+-dog" {
+		t.Errorf("collection search returned %d, redirecting to %q", response.StatusCode, location)
+	}
+
+	//Other users can not open it
+	if err := database.DBInterface.CreateUser("viewer", []byte("viewerpass"), "viewer@example.com", 0); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	viewer := newTestClient(t, server)
+	viewer.logon(t, "viewer", "viewerpass")
+	response, body = viewer.get(t, searchPath)
+	if response.StatusCode != http.StatusOK || strings.Contains(body, "Failed to open that saved search") == false {
+		t.Errorf("other user opening the search returned %d", response.StatusCode)
+	}
+}
+
+func TestFavoritesRouter(t *testing.T) {
+	fixture := seedDatabase(t)
+	server := newTestServer(t)
+	client := newTestClient(t, server)
+
+	response, _ := client.get(t, "/favorites")
+	if location := response.Header.Get("Location"); response.StatusCode != http.StatusFound || strings.HasPrefix(location, "/logon") == false {
+		t.Errorf("signed out favorites returned %d, redirecting to %q", response.StatusCode, location)
+	}
+
+	client.logon(t, "admin", "adminpass")
+	for _, name := range []string{"one", "three"} {
+		if err := database.DBInterface.SetUserFavorite(fixture.AdminID, fixture.Images[name], true); err != nil {
+			t.Fatalf("SetUserFavorite: %v", err)
+		}
+	}
+	response, _ = client.get(t, "/favorites")
+	if location := response.Header.Get("Location"); response.StatusCode != http.StatusFound || location != "/images?SearchTerms=fav%3Aadmin" {
+		t.Errorf("favorites returned %d, redirecting to %q", response.StatusCode, location)
+	}
+	response, _ = client.get(t, "/favorites?UserName=Someone")
+	if location := response.Header.Get("Location"); location != "/images?SearchTerms=fav%3Asomeone" {
+		t.Errorf("favorites of another user redirected to %q", location)
+	}
+
+	//fav:me is the signed in user
+	searches := map[string][]uint64{
+		"fav:admin":        fixture.fixtureImageIDs("three", "one"),
+		"fav:me":           fixture.fixtureImageIDs("three", "one"),
+		"-fav:me":          fixture.fixtureImageIDs("two"),
+		"dog -(fav:me)":    fixture.fixtureImageIDs("two"),
+		"fav:nobody":       nil,
+		"favcount:1 -cat":  nil,
+		"favcount:0":       fixture.fixtureImageIDs("two"),
+		"favcount:>=1 dog": fixture.fixtureImageIDs("three"),
+	}
+	for query, expected := range searches {
+		_, body := client.get(t, "/images?SearchTerms="+url.QueryEscape(query))
+		if IDs := linkedImageIDs(body); equalIDs(IDs, expected) == false {
+			t.Errorf("%s linked %v, expected %v", query, IDs, expected)
+		}
+	}
+}
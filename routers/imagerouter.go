@@ -5,14 +5,12 @@ import (
 	"go-image-board/database"
 	"go-image-board/interfaces"
 	"go-image-board/logging"
+	"go-image-board/plugins/tagquery"
 	"go-image-board/routers/templatecache"
 	"html"
 	"html/template"
 	"net/http"
 	"net/url"
-	"os"
-	"path"
-	"path/filepath"
 	"strconv"
 	"strings"
 )
@@ -64,19 +62,36 @@ func ImageGetRouter(responseWriter http.ResponseWriter, request *http.Request) {
 		redirectWithFlash(responseWriter, request, "/images?SearchTerms="+url.QueryEscape(TemplateInput.OldQuery), TemplateInput.HTMLMessage, "ImageFail")
 		return
 	}
+	//Images in the trash are only shown to those who can restore them
+	if imageInfo.InTrash && TemplateInput.UserPermissions.HasPermission(interfaces.RemoveImage) != true {
+		TemplateInput.HTMLMessage += template.HTML("No image selected or image not found.<br>")
+		redirectWithFlash(responseWriter, request, "/images?SearchTerms="+url.QueryEscape(TemplateInput.OldQuery), TemplateInput.HTMLMessage, "ImageFail")
+		return
+	}
+	//Images waiting for approval are only shown to their uploader and those who can approve them
+	if CanSeePendingImage(imageInfo, TemplateInput.UserInformation.ID, TemplateInput.UserPermissions) == false {
+		TemplateInput.HTMLMessage += template.HTML("No image selected or image not found.<br>")
+		redirectWithFlash(responseWriter, request, "/images?SearchTerms="+url.QueryEscape(TemplateInput.OldQuery), TemplateInput.HTMLMessage, "ImageFail")
+		return
+	}
 
 	//Get Collection Info
-	imageInfo.MemberCollections, err = database.DBInterface.GetCollectionsWithImage(requestedID)
+	memberCollections, err := database.DBInterface.GetCollectionsWithImage(requestedID)
 	if err != nil {
 		//log err but no need to inform user
 		logging.WriteLog(logging.LogLevelError, "imagerouter/ImageRouter", TemplateInput.UserInformation.GetCompositeID(), logging.ResultFailure, []string{"Failed to get collection info for", strconv.FormatUint(requestedID, 10), err.Error()})
 	}
+	for _, collection := range memberCollections {
+		if collection.InTrash == false {
+			imageInfo.MemberCollections = append(imageInfo.MemberCollections, collection)
+		}
+	}
 
 	//Get next and previous image based on query
 	userQTags := []interfaces.TagInformation{}
 	err = nil
 	if TemplateInput.OldQuery != "" {
-		userQTags, err = database.DBInterface.GetQueryTags(TemplateInput.OldQuery, false)
+		userQTags, err = database.DBInterface.GetQueryTags(tagquery.ResolveCurrentUser(TemplateInput.OldQuery, TemplateInput.UserInformation.Name), false)
 	}
 	if err == nil {
 		//if signed in, add user's global filters to query
@@ -125,19 +140,37 @@ func ImageGetRouter(responseWriter http.ResponseWriter, request *http.Request) {
 		TemplateInput.ImageContentInfo.SourceIsURL = true
 	}
 
-	//Get vote information if logged in
+	//Get vote and favorite information if logged in
 	if TemplateInput.IsLoggedOn() {
 		TemplateInput.ImageContentInfo.UsersVotedScore, err = database.DBInterface.GetUserVoteScore(TemplateInput.UserInformation.ID, requestedID)
+		TemplateInput.ImageContentInfo.UsersFavorite, err = database.DBInterface.GetUserFavorite(TemplateInput.UserInformation.ID, requestedID)
 	}
 
 	//Get the image content information based on type (Img, vs video vs...)
-	TemplateInput.ImageContent = templatecache.GetEmbedForContent(imageInfo.Location)
+	TemplateInput.ImageContent = templatecache.GetEmbedForImage(imageInfo)
 
 	TemplateInput.Tags, err = database.DBInterface.GetImageTags(imageInfo.ID)
 	if err != nil {
 		TemplateInput.HTMLMessage += template.HTML("Failed to load tags.<br>")
 		logging.WriteLog(logging.LogLevelError, "imagerouter/ImageRouter", TemplateInput.UserInformation.GetCompositeID(), logging.ResultFailure, []string{"Failed to load tags", err.Error()})
 	}
+	//Group tags by category for display
+	interfaces.SortTagsByCategory(TemplateInput.Tags)
+
+	TemplateInput.ImageRevisions, err = database.DBInterface.GetImageRevisions(imageInfo.ID)
+	if err != nil {
+		//log err but no need to inform user
+		logging.WriteLog(logging.LogLevelError, "imagerouter/ImageRouter", TemplateInput.UserInformation.GetCompositeID(), logging.ResultFailure, []string{"Failed to load revisions", err.Error()})
+	}
+
+	TemplateInput.TagHistory, err = database.DBInterface.GetImageTagHistory(imageInfo.ID)
+	if err != nil {
+		//log err but no need to inform user
+		logging.WriteLog(logging.LogLevelError, "imagerouter/ImageRouter", TemplateInput.UserInformation.GetCompositeID(), logging.ResultFailure, []string{"Failed to load tag history", err.Error()})
+	}
+
+	loadComments(&TemplateInput, request, imageInfo.ID, 0, "ID="+strconv.FormatUint(imageInfo.ID, 10)+"&SearchTerms="+url.QueryEscape(TemplateInput.OldQuery), "/image")
+	loadNotes(&TemplateInput, imageInfo.ID)
 
 	if TemplateInput.ViewMode == "slideshow" {
 		replyWithTemplate("image-slideshow-js.html", TemplateInput, responseWriter, request)
@@ -199,7 +232,7 @@ func ImagePostRouter(responseWriter http.ResponseWriter, request *http.Request)
 			return
 		}
 		//Validate permission to vote
-		imageInfo, err := database.DBInterface.GetImage(requestedID)
+		imageInfo, err := getVisibleImage(requestedID, TemplateInput.UserInformation.ID, TemplateInput.UserPermissions)
 		if err != nil {
 			TemplateInput.HTMLMessage += template.HTML("Failed to get image information.<br>")
 			redirectWithFlash(responseWriter, request, "/images?SearchTerms="+url.QueryEscape(TemplateInput.OldQuery), TemplateInput.HTMLMessage, "UpdateFailed")
@@ -235,6 +268,98 @@ func ImagePostRouter(responseWriter http.ResponseWriter, request *http.Request)
 		TemplateInput.HTMLMessage += template.HTML("Successfully changed vote!<br>")
 		redirectWithFlash(responseWriter, request, "/image?ID="+strconv.FormatUint(requestedID, 10)+"&SearchTerms="+url.QueryEscape(TemplateInput.OldQuery), TemplateInput.HTMLMessage, "UpdateSucceeded")
 		return
+	case "ChangeFavorite":
+		sImageID := request.FormValue("ID")
+		if TemplateInput.UserInformation.Name == "" || TemplateInput.UserInformation.ID == 0 {
+			//Redirect to logon
+			redirectWithFlash(responseWriter, request, "/logon", "You must be logged in to favorite an image", "LogonRequired")
+			return
+		}
+		requestedID, err = strconv.ParseUint(sImageID, 10, 64)
+		if err != nil {
+			logging.WriteLog(logging.LogLevelError, "imagerouter/ImageRouter", TemplateInput.UserInformation.GetCompositeID(), logging.ResultFailure, []string{"Failed to parse imageid to favorite"})
+			TemplateInput.HTMLMessage += template.HTML("Failed to parse image id to favorite.<br>")
+			redirectWithFlash(responseWriter, request, "/images?SearchTerms="+url.QueryEscape(TemplateInput.OldQuery), TemplateInput.HTMLMessage, "UpdateFailed")
+			return
+		}
+		imageInfo, err := getVisibleImage(requestedID, TemplateInput.UserInformation.ID, TemplateInput.UserPermissions)
+		if err != nil {
+			TemplateInput.HTMLMessage += template.HTML("Failed to get image information.<br>")
+			redirectWithFlash(responseWriter, request, "/images?SearchTerms="+url.QueryEscape(TemplateInput.OldQuery), TemplateInput.HTMLMessage, "UpdateFailed")
+			return
+		}
+		//Favorites are personal, so any user may favorite an image they can see
+		Favorite := request.FormValue("Favorite") == "true"
+		if imageInfo.InTrash && Favorite {
+			TemplateInput.HTMLMessage += template.HTML("Images in the trash cannot be favorited.<br>")
+			redirectWithFlash(responseWriter, request, "/image?ID="+strconv.FormatUint(requestedID, 10)+"&SearchTerms="+url.QueryEscape(TemplateInput.OldQuery), TemplateInput.HTMLMessage, "UpdateFailed")
+			return
+		}
+		if err := database.DBInterface.SetUserFavorite(TemplateInput.UserInformation.ID, requestedID, Favorite); err != nil {
+			logging.WriteLog(logging.LogLevelError, "imagerouter/ImageRouter/ChangeFavorite", TemplateInput.UserInformation.GetCompositeID(), logging.ResultFailure, []string{"Failed to set favorite in database", err.Error()})
+			TemplateInput.HTMLMessage += template.HTML("Failed to change favorite in database, internal error.<br>")
+			redirectWithFlash(responseWriter, request, "/image?ID="+strconv.FormatUint(requestedID, 10)+"&SearchTerms="+url.QueryEscape(TemplateInput.OldQuery), TemplateInput.HTMLMessage, "UpdateFailed")
+			return
+		}
+		if Favorite {
+			TemplateInput.HTMLMessage += template.HTML("Added to your favorites!<br>")
+		} else {
+			TemplateInput.HTMLMessage += template.HTML("Removed from your favorites.<br>")
+		}
+		redirectWithFlash(responseWriter, request, "/image?ID="+strconv.FormatUint(requestedID, 10)+"&SearchTerms="+url.QueryEscape(TemplateInput.OldQuery), TemplateInput.HTMLMessage, "UpdateSucceeded")
+		return
+	case "AddComment", "EditComment", "DeleteComment", "RestoreComment":
+		if TemplateInput.IsLoggedOn() == false {
+			//Redirect to logon
+			redirectWithFlash(responseWriter, request, "/logon", "You must be logged in to comment on an image", "LogonRequired")
+			return
+		}
+		requestedID, err = strconv.ParseUint(request.FormValue("ID"), 10, 64)
+		if err != nil {
+			TemplateInput.HTMLMessage += template.HTML("Failed to parse image id to comment on.<br>")
+			redirectWithFlash(responseWriter, request, "/images?SearchTerms="+url.QueryEscape(TemplateInput.OldQuery), TemplateInput.HTMLMessage, "UpdateFailed")
+			return
+		}
+		imageInfo, err := getVisibleImage(requestedID, TemplateInput.UserInformation.ID, TemplateInput.UserPermissions)
+		if err != nil {
+			TemplateInput.HTMLMessage += template.HTML("Failed to get image information.<br>")
+			redirectWithFlash(responseWriter, request, "/images?SearchTerms="+url.QueryEscape(TemplateInput.OldQuery), TemplateInput.HTMLMessage, "UpdateFailed")
+			return
+		}
+		if imageInfo.InTrash {
+			TemplateInput.HTMLMessage += template.HTML("Comments on images in the trash cannot be changed.<br>")
+			redirectWithFlash(responseWriter, request, "/image?ID="+strconv.FormatUint(requestedID, 10)+"&SearchTerms="+url.QueryEscape(TemplateInput.OldQuery), TemplateInput.HTMLMessage, "UpdateFailed")
+			return
+		}
+		flashName := handleCommentCommand(&TemplateInput, request, requestedID, 0)
+		redirectWithFlash(responseWriter, request, "/image?ID="+strconv.FormatUint(requestedID, 10)+"&SearchTerms="+url.QueryEscape(TemplateInput.OldQuery), TemplateInput.HTMLMessage, flashName)
+		return
+	case "AddNote", "EditNote", "DeleteNote", "RestoreNoteRevision", "RotateNotes":
+		if TemplateInput.IsLoggedOn() == false {
+			//Redirect to logon
+			redirectWithFlash(responseWriter, request, "/logon", "You must be logged in to edit notes", "LogonRequired")
+			return
+		}
+		requestedID, err = strconv.ParseUint(request.FormValue("ID"), 10, 64)
+		if err != nil {
+			TemplateInput.HTMLMessage += template.HTML("Failed to parse image id to edit notes on.<br>")
+			redirectWithFlash(responseWriter, request, "/images?SearchTerms="+url.QueryEscape(TemplateInput.OldQuery), TemplateInput.HTMLMessage, "UpdateFailed")
+			return
+		}
+		imageInfo, err := getVisibleImage(requestedID, TemplateInput.UserInformation.ID, TemplateInput.UserPermissions)
+		if err != nil {
+			TemplateInput.HTMLMessage += template.HTML("Failed to get image information.<br>")
+			redirectWithFlash(responseWriter, request, "/images?SearchTerms="+url.QueryEscape(TemplateInput.OldQuery), TemplateInput.HTMLMessage, "UpdateFailed")
+			return
+		}
+		if imageInfo.InTrash {
+			TemplateInput.HTMLMessage += template.HTML("Notes on images in the trash cannot be changed.<br>")
+			redirectWithFlash(responseWriter, request, "/image?ID="+strconv.FormatUint(requestedID, 10)+"&SearchTerms="+url.QueryEscape(TemplateInput.OldQuery), TemplateInput.HTMLMessage, "UpdateFailed")
+			return
+		}
+		flashName := handleNoteCommand(&TemplateInput, request, imageInfo)
+		redirectWithFlash(responseWriter, request, "/image?ID="+strconv.FormatUint(requestedID, 10)+"&SearchTerms="+url.QueryEscape(TemplateInput.OldQuery), TemplateInput.HTMLMessage, flashName)
+		return
 	case "ChangeSource":
 		sImageID := request.FormValue("ID")
 		if TemplateInput.UserInformation.Name == "" || TemplateInput.UserInformation.ID == 0 {
@@ -252,7 +377,7 @@ func ImagePostRouter(responseWriter http.ResponseWriter, request *http.Request)
 			return
 		}
 		//Validate permission to vote
-		imageInfo, err := database.DBInterface.GetImage(requestedID)
+		imageInfo, err := getVisibleImage(requestedID, TemplateInput.UserInformation.ID, TemplateInput.UserPermissions)
 		if err != nil {
 			TemplateInput.HTMLMessage += template.HTML("Failed to get image information.<br>")
 			redirectWithFlash(responseWriter, request, "/images?SearchTerms="+url.QueryEscape(TemplateInput.OldQuery), TemplateInput.HTMLMessage, "UpdateFailed")
@@ -296,7 +421,7 @@ func ImagePostRouter(responseWriter http.ResponseWriter, request *http.Request)
 			return
 		}
 		//Validate permission to vote
-		imageInfo, err := database.DBInterface.GetImage(requestedID)
+		imageInfo, err := getVisibleImage(requestedID, TemplateInput.UserInformation.ID, TemplateInput.UserPermissions)
 		if err != nil {
 			TemplateInput.HTMLMessage += template.HTML("Failed to get image information.<br>")
 			redirectWithFlash(responseWriter, request, "/image?ID="+strconv.FormatUint(requestedID, 10)+"&SearchTerms="+url.QueryEscape(TemplateInput.OldQuery), TemplateInput.HTMLMessage, "UpdateFailed")
@@ -340,7 +465,7 @@ func ImagePostRouter(responseWriter http.ResponseWriter, request *http.Request)
 			return
 		}
 
-		imageInfo, err := database.DBInterface.GetImage(requestedID)
+		imageInfo, err := getVisibleImage(requestedID, TemplateInput.UserInformation.ID, TemplateInput.UserPermissions)
 		if err != nil {
 			TemplateInput.HTMLMessage += template.HTML("Error parsing image id.<br>")
 			logging.WriteLog(logging.LogLevelError, "imagerouter/ImageRouter/RemoveTag", TemplateInput.UserInformation.GetCompositeID(), logging.ResultFailure, []string{"Failed to parse image id ", err.Error()})
@@ -364,7 +489,7 @@ func ImagePostRouter(responseWriter http.ResponseWriter, request *http.Request)
 			return
 		}
 		//Remove tag
-		if err := database.DBInterface.RemoveTag(requestedTagID, requestedID); err != nil {
+		if err := database.DBInterface.RemoveTag(requestedTagID, requestedID, TemplateInput.UserInformation.ID); err != nil {
 			TemplateInput.HTMLMessage += template.HTML("Failed to remove tag. Was it attached in the first place?<br>")
 			redirectWithFlash(responseWriter, request, "/image?ID="+strconv.FormatUint(requestedID, 10)+"&SearchTerms="+url.QueryEscape(TemplateInput.OldQuery), TemplateInput.HTMLMessage, "UpdateFailed")
 			return
@@ -388,7 +513,7 @@ func ImagePostRouter(responseWriter http.ResponseWriter, request *http.Request)
 			redirectWithFlash(responseWriter, request, "/images?SearchTerms="+url.QueryEscape(TemplateInput.OldQuery), TemplateInput.HTMLMessage, "UpdateFailed")
 			return
 		}
-		imageInfo, err := database.DBInterface.GetImage(requestedID)
+		imageInfo, err := getVisibleImage(requestedID, TemplateInput.UserInformation.ID, TemplateInput.UserPermissions)
 		if err != nil {
 			TemplateInput.HTMLMessage += template.HTML("Error parsing image id.<br>")
 			logging.WriteLog(logging.LogLevelError, "imagerouter/ImageRouter/AddTags", TemplateInput.UserInformation.GetCompositeID(), logging.ResultFailure, []string{"Failed to parse image id ", err.Error()})
@@ -428,7 +553,7 @@ func ImagePostRouter(responseWriter http.ResponseWriter, request *http.Request)
 					TemplateInput.HTMLMessage += template.HTML("Unable to use tag " + template.HTMLEscapeString(tag.Name) + " due to insufficient permissions of user to create tags.<br>")
 					// /ValidatePermission
 				} else {
-					tagID, err := database.DBInterface.NewTag(tag.Name, tag.Description, TemplateInput.UserInformation.ID)
+					tagID, err := database.DBInterface.NewTag(tag.Name, tag.Description, tag.Category, TemplateInput.UserInformation.ID)
 					if err != nil {
 						logging.WriteLog(logging.LogLevelError, "imagerouter/ImageRouter/AddTags", TemplateInput.UserInformation.GetCompositeID(), logging.ResultFailure, []string{"error attempting to create tag", err.Error(), tag.Name})
 						TemplateInput.HTMLMessage += template.HTML("Unable to use tag " + template.HTMLEscapeString(tag.Name) + " due to a database error.<br>")
@@ -465,7 +590,7 @@ func ImagePostRouter(responseWriter http.ResponseWriter, request *http.Request)
 			redirectWithFlash(responseWriter, request, "/images?SearchTerms="+url.QueryEscape(TemplateInput.OldQuery), TemplateInput.HTMLMessage, "UpdateFailed")
 			return
 		}
-		imageInfo, err := database.DBInterface.GetImage(requestedID)
+		imageInfo, err := getVisibleImage(requestedID, TemplateInput.UserInformation.ID, TemplateInput.UserPermissions)
 		if err != nil {
 			TemplateInput.HTMLMessage += template.HTML("Error parsing image id.<br>")
 			logging.WriteLog(logging.LogLevelError, "imagerouter/ImageRouter/ChangeRating", TemplateInput.UserInformation.GetCompositeID(), logging.ResultFailure, []string{"Failed to parse image id ", err.Error()})
@@ -491,6 +616,118 @@ func ImagePostRouter(responseWriter http.ResponseWriter, request *http.Request)
 		TemplateInput.HTMLMessage += template.HTML("Updated rating.<br>")
 		redirectWithFlash(responseWriter, request, "/image?ID="+strconv.FormatUint(requestedID, 10)+"&SearchTerms="+url.QueryEscape(TemplateInput.OldQuery), TemplateInput.HTMLMessage, "UpdateSucceeded")
 		return
+	case "ReplaceFile", "RestoreRevision":
+		if !TemplateInput.IsLoggedOn() {
+			//Redirect to logon
+			redirectWithFlash(responseWriter, request, "/logon", "You must be logged in to replace the file of an image", "LogonRequired")
+			return
+		}
+		requestedID, err := strconv.ParseUint(request.FormValue("ID"), 10, 32)
+		if err != nil {
+			TemplateInput.HTMLMessage += template.HTML("Error parsing image id.<br>")
+			redirectWithFlash(responseWriter, request, "/images?SearchTerms="+url.QueryEscape(TemplateInput.OldQuery), TemplateInput.HTMLMessage, "UpdateFailed")
+			return
+		}
+		imageInfo, err := getVisibleImage(requestedID, TemplateInput.UserInformation.ID, TemplateInput.UserPermissions)
+		if err != nil {
+			TemplateInput.HTMLMessage += template.HTML("Failed to get image information.<br>")
+			redirectWithFlash(responseWriter, request, "/images?SearchTerms="+url.QueryEscape(TemplateInput.OldQuery), TemplateInput.HTMLMessage, "UpdateFailed")
+			return
+		}
+		imageLink := "/image?ID=" + strconv.FormatUint(requestedID, 10) + "&SearchTerms=" + url.QueryEscape(TemplateInput.OldQuery)
+
+		//Validate permission to replace, the previous file is no longer shown so this is treated as deleting it
+		if TemplateInput.UserPermissions.HasPermission(interfaces.RemoveImage) != true && (config.Configuration.UsersControlOwnObjects != true || imageInfo.UploaderID != TemplateInput.UserInformation.ID) {
+			TemplateInput.HTMLMessage += template.HTML("You do not have permission to replace the file of this image.<br>")
+			go WriteAuditLogByName(TemplateInput.UserInformation.Name, "REPLACE-IMAGEFILE", TemplateInput.UserInformation.Name+" failed to replace file of image. Insufficient permissions. "+request.FormValue("ID"))
+			redirectWithFlash(responseWriter, request, imageLink, TemplateInput.HTMLMessage, "UpdateFailed")
+			return
+		}
+		if imageInfo.InTrash {
+			TemplateInput.HTMLMessage += template.HTML("Images in the trash must be restored before their file is replaced.<br>")
+			redirectWithFlash(responseWriter, request, imageLink, TemplateInput.HTMLMessage, "UpdateFailed")
+			return
+		}
+		// /ValidatePermission
+
+		if request.FormValue("command") == "RestoreRevision" {
+			revisionID, err := strconv.ParseUint(request.FormValue("RevisionID"), 10, 64)
+			var revision interfaces.ImageRevisionInformation
+			if err == nil {
+				revision, err = database.DBInterface.GetImageRevision(revisionID)
+			}
+			if err == nil {
+				err = RestoreImageRevision(imageInfo, revision, TemplateInput.UserInformation.ID)
+			}
+			if err != nil {
+				TemplateInput.HTMLMessage += template.HTML("Failed to restore the previous file.<br>")
+				go WriteAuditLogByName(TemplateInput.UserInformation.Name, "REPLACE-IMAGEFILE", TemplateInput.UserInformation.Name+" failed to restore previous file of image. "+request.FormValue("ID")+", "+request.FormValue("RevisionID")+", "+err.Error())
+				redirectWithFlash(responseWriter, request, imageLink, TemplateInput.HTMLMessage, "UpdateFailed")
+				return
+			}
+			go WriteAuditLogByName(TemplateInput.UserInformation.Name, "REPLACE-IMAGEFILE", TemplateInput.UserInformation.Name+" restored previous file of image. "+request.FormValue("ID")+", "+imageInfo.Location+" replaced by "+revision.Location)
+			TemplateInput.HTMLMessage += template.HTML("Previous file restored.<br>")
+			redirectWithFlash(responseWriter, request, imageLink, TemplateInput.HTMLMessage, "UpdateSucceeded")
+			return
+		}
+
+		request.ParseMultipartForm(config.Configuration.MaxUploadBytes)
+		file, fileHeader, err := request.FormFile("fileToReplace")
+		if err != nil {
+			TemplateInput.HTMLMessage += template.HTML("Please select a file to replace the image with.<br>")
+			redirectWithFlash(responseWriter, request, imageLink, TemplateInput.HTMLMessage, "UpdateFailed")
+			return
+		}
+		defer file.Close()
+		newLocation, err := ReplaceImageFile(imageInfo, fileHeader.Filename, file, fileHeader.Size, TemplateInput.UserInformation.ID)
+		if err != nil {
+			TemplateInput.HTMLMessage += template.HTML("Failed to replace file: " + html.EscapeString(err.Error()) + ".<br>")
+			go WriteAuditLogByName(TemplateInput.UserInformation.Name, "REPLACE-IMAGEFILE", TemplateInput.UserInformation.Name+" failed to replace file of image. "+request.FormValue("ID")+", "+err.Error())
+			redirectWithFlash(responseWriter, request, imageLink, TemplateInput.HTMLMessage, "UpdateFailed")
+			return
+		}
+		go WriteAuditLogByName(TemplateInput.UserInformation.Name, "REPLACE-IMAGEFILE", TemplateInput.UserInformation.Name+" replaced file of image. "+request.FormValue("ID")+", "+imageInfo.Location+" replaced by "+newLocation)
+		TemplateInput.HTMLMessage += template.HTML("File replaced, the previous file is kept as a revision.<br>")
+		redirectWithFlash(responseWriter, request, imageLink, TemplateInput.HTMLMessage, "UpdateSucceeded")
+		return
+	case "RevertTags":
+		if !TemplateInput.IsLoggedOn() {
+			//Redirect to logon
+			redirectWithFlash(responseWriter, request, "/logon", "You must be logged in to revert tags", "LogonRequired")
+			return
+		}
+		requestedID, err := strconv.ParseUint(request.FormValue("ID"), 10, 32)
+		if err != nil {
+			TemplateInput.HTMLMessage += template.HTML("Error parsing image id.<br>")
+			redirectWithFlash(responseWriter, request, "/images?SearchTerms="+url.QueryEscape(TemplateInput.OldQuery), TemplateInput.HTMLMessage, "UpdateFailed")
+			return
+		}
+		imageLink := "/image?ID=" + strconv.FormatUint(requestedID, 10) + "&SearchTerms=" + url.QueryEscape(TemplateInput.OldQuery)
+
+		//Validate permission, reverting undoes changes made by others
+		if TemplateInput.UserPermissions.HasPermission(interfaces.BulkTagOperations) != true {
+			TemplateInput.HTMLMessage += template.HTML("You do not have permission to revert the tags of images.<br>")
+			go WriteAuditLogByName(TemplateInput.UserInformation.Name, "REVERT-IMAGETAGS", TemplateInput.UserInformation.Name+" failed to revert image tags. Insufficient permissions. "+request.FormValue("ID"))
+			redirectWithFlash(responseWriter, request, imageLink, TemplateInput.HTMLMessage, "UpdateFailed")
+			return
+		}
+		// /ValidatePermission
+
+		changeID, err := strconv.ParseUint(request.FormValue("ChangeID"), 10, 64)
+		var undone uint64
+		if err == nil {
+			undone, err = RevertImageTags(requestedID, changeID, TemplateInput.UserInformation.ID)
+		}
+		if err != nil {
+			TemplateInput.HTMLMessage += template.HTML("Failed to revert tags.<br>")
+			go WriteAuditLogByName(TemplateInput.UserInformation.Name, "REVERT-IMAGETAGS", TemplateInput.UserInformation.Name+" failed to revert image tags. "+request.FormValue("ID")+", "+request.FormValue("ChangeID")+", "+err.Error())
+			redirectWithFlash(responseWriter, request, imageLink, TemplateInput.HTMLMessage, "UpdateFailed")
+			return
+		}
+		go WriteAuditLogByName(TemplateInput.UserInformation.Name, "REVERT-IMAGETAGS", TemplateInput.UserInformation.Name+" reverted image tags. "+request.FormValue("ID")+" to change "+request.FormValue("ChangeID")+", "+strconv.FormatUint(undone, 10)+" changes undone")
+		TemplateInput.HTMLMessage += template.HTML("Tags reverted, " + strconv.FormatUint(undone, 10) + " changes undone.<br>")
+		redirectWithFlash(responseWriter, request, imageLink, TemplateInput.HTMLMessage, "UpdateSucceeded")
+		return
 	case "delete":
 		if !TemplateInput.IsLoggedOn() {
 			//Redirect to logon
@@ -505,7 +742,7 @@ func ImagePostRouter(responseWriter http.ResponseWriter, request *http.Request)
 			return
 		}
 		//Cache image data
-		ImageInfo, err := database.DBInterface.GetImage(parsedImageID)
+		ImageInfo, err := getVisibleImage(parsedImageID, TemplateInput.UserInformation.ID, TemplateInput.UserPermissions)
 		if err != nil {
 			TemplateInput.HTMLMessage += template.HTML("Failed to delete image. SQL Error.<br>")
 			go WriteAuditLogByName(TemplateInput.UserInformation.Name, "DELETE-IMAGE", TemplateInput.UserInformation.Name+" failed to delete image. "+request.FormValue("ID")+", "+err.Error())
@@ -521,19 +758,15 @@ func ImagePostRouter(responseWriter http.ResponseWriter, request *http.Request)
 			return
 		}
 
-		//Permission validated, now delete (ImageTags and Images)
-		if err := database.DBInterface.DeleteImage(parsedImageID); err != nil {
+		//Permission validated, now move to trash. The files are removed once the trash is purged
+		if err := database.DBInterface.TrashImage(parsedImageID, TemplateInput.UserInformation.ID); err != nil {
 			TemplateInput.HTMLMessage += template.HTML("Failed to delete image. SQL Error.<br>")
 			go WriteAuditLogByName(TemplateInput.UserInformation.Name, "DELETE-IMAGE", TemplateInput.UserInformation.Name+" failed to delete image. "+request.FormValue("ID")+", "+err.Error())
 			redirectWithFlash(responseWriter, request, "/image?ID="+strconv.FormatUint(parsedImageID, 10)+"&SearchTerms="+url.QueryEscape(TemplateInput.OldQuery), TemplateInput.HTMLMessage, "DeleteFailed")
 			return
 		}
-		go WriteAuditLogByName(TemplateInput.UserInformation.Name, "DELETE-IMAGE", TemplateInput.UserInformation.Name+" deleted image. "+request.FormValue("ID")+", "+ImageInfo.Name+", "+ImageInfo.Location)
-		//Third, delete Image from Disk
-		go os.Remove(path.Join(config.Configuration.ImageDirectory, ImageInfo.Location))
-		//Last delete thumbnail from disk
-		go os.Remove(path.Join(config.Configuration.ImageDirectory, "thumbs"+string(filepath.Separator)+ImageInfo.Location+".png"))
-		TemplateInput.HTMLMessage += template.HTML("Deletion success.<br>")
+		go WriteAuditLogByName(TemplateInput.UserInformation.Name, "DELETE-IMAGE", TemplateInput.UserInformation.Name+" moved image to trash. "+request.FormValue("ID")+", "+ImageInfo.Name+", "+ImageInfo.Location)
+		TemplateInput.HTMLMessage += template.HTML("Image moved to the trash.<br>")
 		redirectWithFlash(responseWriter, request, "/images?SearchTerms="+url.QueryEscape(TemplateInput.OldQuery), TemplateInput.HTMLMessage, "DeleteSuccess")
 		return
 	}
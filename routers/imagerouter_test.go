@@ -0,0 +1,227 @@
+package routers
+
+import (
+	"go-image-board/database"
+	"go-image-board/interfaces"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestImageGetRouter(t *testing.T) {
+	fixture := seedDatabase(t)
+	server := newTestServer(t)
+	client := newTestClient(t, server)
+
+	response, body := client.get(t, "/image?ID="+strconv.FormatUint(fixture.Images["three"], 10))
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("image page returned %d", response.StatusCode)
+	}
+	for _, expected := range []string{"<h4>three ", `src="/images/three.png"`, `<a href="/collection?ID=` + strconv.FormatUint(fixture.CollectionID, 10) + `">Pets</a>`} {
+		if strings.Contains(body, expected) == false {
+			t.Errorf("image page does not contain %q", expected)
+		}
+	}
+
+	//Missing or invalid images go back to the search
+	for _, path := range []string{"/image?ID=999&", "/image?ID=abc&", "/image?"} {
+		response, _ := client.get(t, path+"SearchTerms=cat")
+		if location := response.Header.Get("Location"); response.StatusCode != http.StatusFound || strings.HasPrefix(location, "/images?SearchTerms=cat") == false {
+			t.Errorf("%s returned %d, redirecting to %q", path, response.StatusCode, location)
+		}
+	}
+}
+
+func TestImageGetRouterMetadata(t *testing.T) {
+	fixture := seedDatabase(t)
+	server := newTestServer(t)
+	client := newTestClient(t, server)
+
+	//The stored type decides how the file is embedded, rather than its extension
+	metadata := interfaces.ImageMetadata{Width: 1280, Height: 720, FileSize: 2048, MIMEType: "video/mp4", Duration: 95.5}
+	if err := database.DBInterface.SetImageMetadata(fixture.Images["three"], metadata); err != nil {
+		t.Fatal(err)
+	}
+	_, body := client.get(t, "/image?ID="+strconv.FormatUint(fixture.Images["three"], 10))
+	for _, expected := range []string{`<source src="/images/three.png" type="video/mp4">`, "Dimensions: 1280x720", "Size: 2048 bytes", "Duration: 95.5 seconds"} {
+		if strings.Contains(body, expected) == false {
+			t.Errorf("image page does not contain %q", expected)
+		}
+	}
+	_, body = client.get(t, "/images?SearchTerms=dog")
+	if strings.Contains(body, "overlayvideo") == false {
+		t.Error("search results do not mark the video")
+	}
+}
+
+func TestImagePostRouterFavorite(t *testing.T) {
+	fixture := seedDatabase(t)
+	server := newTestServer(t)
+	client := newTestClient(t, server)
+	imageID := strconv.FormatUint(fixture.Images["two"], 10)
+
+	//Signed out users are sent to logon
+	response, _ := client.postForm(t, "/image", url.Values{"command": {"ChangeFavorite"}, "ID": {imageID}, "Favorite": {"true"}})
+	if location := response.Header.Get("Location"); response.StatusCode != http.StatusFound || strings.HasPrefix(location, "/logon") == false {
+		t.Errorf("signed out favorite returned %d, redirecting to %q", response.StatusCode, location)
+	}
+
+	client.logon(t, "admin", "adminpass")
+	_, body := client.get(t, "/image?ID="+imageID)
+	if strings.Contains(body, `value="Favorite"`) == false {
+		t.Error("image page does not offer to favorite the image")
+	}
+	response, _ = client.postForm(t, "/image", url.Values{"command": {"ChangeFavorite"}, "ID": {imageID}, "Favorite": {"true"}, "SearchTerms": {"outdoor"}})
+	if location := response.Header.Get("Location"); response.StatusCode != http.StatusFound || strings.HasPrefix(location, "/image?ID="+imageID+"&SearchTerms=outdoor&flash=UpdateSucceeded") == false {
+		t.Errorf("favorite returned %d, redirecting to %q", response.StatusCode, location)
+	}
+	if favorite, err := database.DBInterface.GetUserFavorite(fixture.AdminID, fixture.Images["two"]); err != nil || favorite == false {
+		t.Fatalf("image was not favorited, %v", err)
+	}
+	_, body = client.get(t, "/image?ID="+imageID)
+	if strings.Contains(body, `value="Unfavorite"`) == false {
+		t.Error("image page does not offer to unfavorite the image")
+	}
+
+	client.postForm(t, "/image", url.Values{"command": {"ChangeFavorite"}, "ID": {imageID}, "Favorite": {"false"}})
+	if favorite, err := database.DBInterface.GetUserFavorite(fixture.AdminID, fixture.Images["two"]); err != nil || favorite {
+		t.Errorf("image is still favorited, %v", err)
+	}
+}
+
+func TestImagePostRouterComments(t *testing.T) {
+	fixture := seedDatabase(t)
+	server := newTestServer(t)
+	client := newTestClient(t, server)
+	imageID := strconv.FormatUint(fixture.Images["one"], 10)
+
+	//Signed out users are sent to logon
+	response, _ := client.postForm(t, "/image", url.Values{"command": {"AddComment"}, "ID": {imageID}, "Body": {"Hello"}})
+	if location := response.Header.Get("Location"); response.StatusCode != http.StatusFound || strings.HasPrefix(location, "/logon") == false {
+		t.Errorf("signed out comment returned %d, redirecting to %q", response.StatusCode, location)
+	}
+
+	client.logon(t, "admin", "adminpass")
+	_, body := client.get(t, "/image?ID="+imageID)
+	if strings.Contains(body, "No comments yet.") == false || strings.Contains(body, `id="addCommentForm"`) == false {
+		t.Error("image page does not offer to add a comment")
+	}
+	response, _ = client.postForm(t, "/image", url.Values{"command": {"AddComment"}, "ID": {imageID}, "Body": {"  "}})
+	if location := response.Header.Get("Location"); response.StatusCode != http.StatusFound || strings.HasPrefix(location, "/image?ID="+imageID+"&SearchTerms=&flash=UpdateFailed") == false {
+		t.Errorf("blank comment returned %d, redirecting to %q", response.StatusCode, location)
+	}
+	response, _ = client.postForm(t, "/image", url.Values{"command": {"AddComment"}, "ID": {imageID}, "Body": {"Nice cat"}, "SearchTerms": {"cat"}})
+	if location := response.Header.Get("Location"); response.StatusCode != http.StatusFound || strings.HasPrefix(location, "/image?ID="+imageID+"&SearchTerms=cat&flash=UpdateSucceeded") == false {
+		t.Errorf("comment returned %d, redirecting to %q", response.StatusCode, location)
+	}
+	comments, _, err := database.DBInterface.GetComments(fixture.Images["one"], 0, 0, 10)
+	if err != nil || len(comments) != 1 || comments[0].Body != "Nice cat" || comments[0].UserID != fixture.AdminID {
+		t.Fatalf("comments are %+v, %v", comments, err)
+	}
+	commentID := strconv.FormatUint(comments[0].ID, 10)
+	client.postForm(t, "/image", url.Values{"command": {"AddComment"}, "ID": {imageID}, "Body": {"Agreed"}, "ParentID": {commentID}})
+	client.postForm(t, "/image", url.Values{"command": {"EditComment"}, "ID": {imageID}, "CommentID": {commentID}, "Body": {"Nice outdoor cat"}})
+	_, body = client.get(t, "/image?ID="+imageID)
+	for _, expected := range []string{`id="comment` + commentID + `" style="margin-left: 0em;"`, "Nice outdoor cat", `style="margin-left: 1em;"`, "Agreed", ", edited "} {
+		if strings.Contains(body, expected) == false {
+			t.Errorf("image page does not contain %q", expected)
+		}
+	}
+	if revisions, err := database.DBInterface.GetCommentRevisions(comments[0].ID); err != nil || len(revisions) != 1 || revisions[0].Body != "Nice cat" {
+		t.Errorf("revisions are %+v, %v", revisions, err)
+	}
+
+	//Comments can only be changed from the image they are on
+	otherID := strconv.FormatUint(fixture.Images["two"], 10)
+	response, _ = client.postForm(t, "/image", url.Values{"command": {"DeleteComment"}, "ID": {otherID}, "CommentID": {commentID}})
+	if location := response.Header.Get("Location"); strings.HasPrefix(location, "/image?ID="+otherID+"&SearchTerms=&flash=UpdateFailed") == false {
+		t.Errorf("deleting from another image redirected to %q", location)
+	}
+	response, _ = client.postForm(t, "/image", url.Values{"command": {"DeleteComment"}, "ID": {imageID}, "CommentID": {commentID}})
+	if location := response.Header.Get("Location"); strings.HasPrefix(location, "/image?ID="+imageID+"&SearchTerms=&flash=UpdateSucceeded") == false {
+		t.Errorf("delete redirected to %q", location)
+	}
+	if comment, err := database.DBInterface.GetComment(comments[0].ID); err != nil || comment.Deleted == false || comment.DeleterID != fixture.AdminID {
+		t.Errorf("deleted comment is %+v, %v", comment, err)
+	}
+	_, body = client.get(t, "/image?ID="+imageID)
+	if strings.Contains(body, "[deleted]") == false || strings.Contains(body, `value="RestoreComment"`) == false {
+		t.Error("image page does not show the deleted comment to a moderator")
+	}
+	client.postForm(t, "/image", url.Values{"command": {"RestoreComment"}, "ID": {imageID}, "CommentID": {commentID}})
+	if comment, err := database.DBInterface.GetComment(comments[0].ID); err != nil || comment.Deleted {
+		t.Errorf("restored comment is %+v, %v", comment, err)
+	}
+}
+
+func TestImagePostRouterNotes(t *testing.T) {
+	fixture := seedDatabase(t)
+	server := newTestServer(t)
+	client := newTestClient(t, server)
+	imageID := strconv.FormatUint(fixture.Images["one"], 10)
+	note := func(command string, values url.Values) string {
+		t.Helper()
+		values.Set("command", command)
+		values.Set("ID", imageID)
+		response, _ := client.postForm(t, "/image", values)
+		return response.Header.Get("Location")
+	}
+
+	//Signed out users are sent to logon
+	if location := note("AddNote", url.Values{"X": {"1"}, "Y": {"1"}, "Width": {"1"}, "Height": {"1"}, "ImageWidth": {"10"}, "ImageHeight": {"10"}, "Body": {"Hello"}}); strings.HasPrefix(location, "/logon") == false {
+		t.Errorf("signed out note redirected to %q", location)
+	}
+
+	client.logon(t, "admin", "adminpass")
+	_, body := client.get(t, "/image?ID="+imageID)
+	if strings.Contains(body, `id="addNoteForm"`) == false || strings.Contains(body, `id="notesOverlay"`) == false {
+		t.Error("image page does not offer to add a note")
+	}
+	if location := note("AddNote", url.Values{"X": {"5"}, "Y": {"5"}, "Width": {"10"}, "Height": {"10"}, "ImageWidth": {"10"}, "ImageHeight": {"10"}, "Body": {"Too big"}}); strings.HasPrefix(location, "/image?ID="+imageID+"&SearchTerms=&flash=UpdateFailed") == false {
+		t.Errorf("note outside the image redirected to %q", location)
+	}
+	if location := note("AddNote", url.Values{"X": {"50"}, "Y": {"25"}, "Width": {"100"}, "Height": {"50"}, "ImageWidth": {"200"}, "ImageHeight": {"100"}, "Body": {"A cat"}}); strings.HasPrefix(location, "/image?ID="+imageID+"&SearchTerms=&flash=UpdateSucceeded") == false {
+		t.Errorf("note redirected to %q", location)
+	}
+	notes, err := database.DBInterface.GetNotes(fixture.Images["one"], false)
+	if err != nil || len(notes) != 1 || notes[0].Body != "A cat" || notes[0].CreatorID != fixture.AdminID {
+		t.Fatalf("notes are %+v, %v", notes, err)
+	}
+	noteID := strconv.FormatUint(notes[0].ID, 10)
+	_, body = client.get(t, "/image?ID="+imageID)
+	for _, expected := range []string{`id="note` + noteID + `" style="left: 25.0000%; top: 25.0000%; width: 50.0000%; height: 50.0000%;"`, `<div class="noteBody">A cat</div>`} {
+		if strings.Contains(body, expected) == false {
+			t.Errorf("image page does not contain %q", expected)
+		}
+	}
+
+	note("EditNote", url.Values{"NoteID": {noteID}, "X": {"0"}, "Y": {"0"}, "Width": {"200"}, "Height": {"100"}, "ImageWidth": {"200"}, "ImageHeight": {"100"}, "Body": {"The whole cat"}})
+	note("RotateNotes", url.Values{"Degrees": {"90"}})
+	if edited, err := database.DBInterface.GetNote(notes[0].ID); err != nil || edited.Body != "The whole cat" || edited.ImageWidth != 100 || edited.Width != 100 || edited.Height != 200 {
+		t.Errorf("edited note is %+v, %v", edited, err)
+	}
+
+	//Notes can only be changed from the image they are on
+	otherID := strconv.FormatUint(fixture.Images["two"], 10)
+	response, _ := client.postForm(t, "/image", url.Values{"command": {"DeleteNote"}, "ID": {otherID}, "NoteID": {noteID}})
+	if location := response.Header.Get("Location"); strings.HasPrefix(location, "/image?ID="+otherID+"&SearchTerms=&flash=UpdateFailed") == false {
+		t.Errorf("deleting from another image redirected to %q", location)
+	}
+	if location := note("DeleteNote", url.Values{"NoteID": {noteID}}); strings.HasPrefix(location, "/image?ID="+imageID+"&SearchTerms=&flash=UpdateSucceeded") == false {
+		t.Errorf("delete redirected to %q", location)
+	}
+	_, body = client.get(t, "/image?ID="+imageID)
+	if strings.Contains(body, `id="note`+noteID+`"`) || strings.Contains(body, `value="RestoreNoteRevision"`) == false {
+		t.Error("image page still shows the deleted note, or does not offer to restore it")
+	}
+	revisions, err := database.DBInterface.GetNoteRevisions(notes[0].ID)
+	if err != nil || len(revisions) != 4 {
+		t.Fatalf("revisions are %+v, %v", revisions, err)
+	}
+	note("RestoreNoteRevision", url.Values{"NoteID": {noteID}, "RevisionID": {strconv.FormatUint(revisions[len(revisions)-1].ID, 10)}})
+	if restored, err := database.DBInterface.GetNote(notes[0].ID); err != nil || restored.Deleted || restored.Body != "A cat" || restored.X != 50 {
+		t.Errorf("restored note is %+v, %v", restored, err)
+	}
+}
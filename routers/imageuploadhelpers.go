@@ -9,9 +9,9 @@ import (
 	"go-image-board/database"
 	"go-image-board/interfaces"
 	"go-image-board/logging"
+	"go-image-board/storage"
 	"io"
 	"net/http"
-	"os"
 	"path"
 	"path/filepath"
 	"sort"
@@ -95,7 +95,7 @@ func handleImageUpload(request *http.Request, userName string) (uint64, map[stri
 				errorCompilation += "Unable to use tag " + tag.Name + " due to insufficient permissions of user to create tags. "
 				// /ValidatePermission
 			} else {
-				tagID, err := database.DBInterface.NewTag(tag.Name, tag.Description, userID)
+				tagID, err := database.DBInterface.NewTag(tag.Name, tag.Description, tag.Category, userID)
 				if err != nil {
 					logging.WriteLog(logging.LogLevelError, "imagerouter/handleImageUpload", userName, logging.ResultFailure, []string{"error attempting to create tag", err.Error(), tag.Name})
 					errorCompilation += "Unable to use tag " + tag.Name + " due to a database error. "
@@ -114,11 +114,8 @@ func handleImageUpload(request *http.Request, userName string) (uint64, map[stri
 	fileHeaders := request.MultipartForm.File["fileToUpload"]
 	source := request.FormValue("Source")
 	for _, fileHeader := range fileHeaders {
-		switch ext := strings.ToLower(filepath.Ext(fileHeader.Filename)); ext {
-		case ".jpg", ".jpeg", ".jfif", ".bmp", ".gif", ".png", ".svg", ".mpg", ".mov", ".webm", ".avi", ".mp4", ".mp3", ".ogg", ".wav", ".webp", ".tiff", ".tif":
-			//Passes filter
-		default:
-			logging.WriteLog(logging.LogLevelVerbose, "imagerouter/handleImageUpload", userName, logging.ResultFailure, []string{"Attempted to upload a file which did not pass filter", ext})
+		if IsUploadableFile(fileHeader.Filename) == false {
+			logging.WriteLog(logging.LogLevelVerbose, "imagerouter/handleImageUpload", userName, logging.ResultFailure, []string{"Attempted to upload a file which did not pass filter", filepath.Ext(fileHeader.Filename)})
 			errorCompilation += fileHeader.Filename + " is not a recognized file. "
 			continue
 		}
@@ -135,16 +132,16 @@ func handleImageUpload(request *http.Request, userName string) (uint64, map[stri
 				fileStream.Close()
 				continue
 			}
+			imageLocation := ImageLocation(hashName)
 
-			filePath := path.Join(config.Configuration.ImageDirectory, hashName)
 			//Check if file exists, if so, skip
-			if _, err := os.Stat(filePath); err == nil {
+			if existingLocation, exists := FindStoredImage(hashName); exists {
 				var duplicateID uint64
-				dupInfo, ierr := database.DBInterface.GetImageByFileName(hashName)
+				dupInfo, ierr := database.DBInterface.GetImageByFileName(existingLocation)
 				if ierr == nil {
 					duplicateID = dupInfo.ID
 				}
-				logging.WriteLog(logging.LogLevelInfo, "imagerouter/handleImageUpload", userName, logging.ResultInfo, []string{"Skipping as file is already uploaded", fileHeader.Filename, filePath, strconv.FormatUint(duplicateID, 10)})
+				logging.WriteLog(logging.LogLevelInfo, "imagerouter/handleImageUpload", userName, logging.ResultInfo, []string{"Skipping as file is already uploaded", fileHeader.Filename, existingLocation, strconv.FormatUint(duplicateID, 10)})
 				if ierr == nil {
 					//errorCompilation += fileHeader.Filename + " has already been uploaded as ID " + strconv.FormatUint(duplicateID, 10) + ". "
 					duplicateIDs[fileHeader.Filename] = duplicateID
@@ -155,37 +152,35 @@ func handleImageUpload(request *http.Request, userName string) (uint64, map[stri
 				continue
 			}
 
-			saveStream, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE, 0660)
+			//Save Image
+			_, err = fileStream.Seek(0, 0)
 			if err != nil {
-				logging.WriteLog(logging.LogLevelError, "imagerouter/handleImageUpload", userName, logging.ResultFailure, []string{"Upload image, failed to open new file", err.Error()})
+				logging.WriteLog(logging.LogLevelError, "imagerouter/handleImageUpload", userName, logging.ResultFailure, []string{"Upload image, failed to seek stream", err.Error()})
 				errorCompilation += fileHeader.Filename + " could not be saved, internal error. "
-				saveStream.Close()
 				fileStream.Close()
 				continue
 			}
-			//Save Image
-			_, err = fileStream.Seek(0, 0)
-			if err != nil {
-				logging.WriteLog(logging.LogLevelError, "imagerouter/handleImageUpload", userName, logging.ResultFailure, []string{"Upload image, failed to seek stream", err.Error()})
+			if err := storage.StorageInterface.Put(imageLocation, fileStream, fileHeader.Size); err != nil {
+				logging.WriteLog(logging.LogLevelError, "imagerouter/handleImageUpload", userName, logging.ResultFailure, []string{"Upload image, failed to save file", err.Error()})
 				errorCompilation += fileHeader.Filename + " could not be saved, internal error. "
-				saveStream.Close()
 				fileStream.Close()
 				continue
 			}
-			io.Copy(saveStream, fileStream)
-			saveStream.Close()
-			//Add image to Database
-
-			lastID, err = database.DBInterface.NewImage(hashName, hashName, userID, source)
+			//Add image to Database, held for a moderator when uploads are moderated
+			pending := UploadNeedsApproval(interfaces.UserPermission(userPermission))
+			lastID, err = database.DBInterface.NewImage(hashName, imageLocation, userID, source, pending)
 			if err != nil {
-				logging.WriteLog(logging.LogLevelError, "imagerouter/handleImageUpload", userName, logging.ResultFailure, []string{"error attempting to add file to database", err.Error(), filePath})
+				logging.WriteLog(logging.LogLevelError, "imagerouter/handleImageUpload", userName, logging.ResultFailure, []string{"error attempting to add file to database", err.Error(), imageLocation})
 				errorCompilation += fileHeader.Filename + " could not be added to database, internal error. "
 				//Attempt to cleanup file
-				if err := os.Remove(filePath); err != nil {
-					logging.WriteLog(logging.LogLevelError, "imagerouter/handleImageUpload", userName, logging.ResultFailure, []string{"error attempting to remove orphaned file", err.Error(), filePath})
+				if err := storage.StorageInterface.Delete(imageLocation); err != nil {
+					logging.WriteLog(logging.LogLevelError, "imagerouter/handleImageUpload", userName, logging.ResultFailure, []string{"error attempting to remove orphaned file", err.Error(), imageLocation})
 				}
 				continue
 			}
+			if pending {
+				go WriteAuditLog(userID, "IMAGE-UPLOAD", userName+" uploaded image "+strconv.FormatUint(lastID, 10)+", which is waiting for approval.")
+			}
 
 			uploadedIDs = append(uploadedIDs, uploadData{Name: originalName, ID: lastID})
 
@@ -201,8 +196,9 @@ func handleImageUpload(request *http.Request, userName string) (uint64, map[stri
 			//Log success
 			go WriteAuditLog(userID, "IMAGE-UPLOAD", userName+" successfully uploaded an image. "+strconv.FormatUint(lastID, 10))
 			//Start go routine to generate thumbnail
-			go GenerateThumbnail(hashName)
-			go GeneratedHash(hashName, lastID)
+			go GenerateThumbnail(imageLocation)
+			go GeneratedHash(imageLocation, lastID)
+			go GenerateMetadata(imageLocation, lastID)
 		}
 		fileStream.Close()
 	}
@@ -315,7 +311,7 @@ func HandleImageUploadRequest(request *http.Request, userInformation interfaces.
 				errorCompilation += "Unable to use tag " + tag.Name + " due to insufficient permissions of user to create tags. "
 				// /ValidatePermission
 			} else {
-				tagID, err := database.DBInterface.NewTag(tag.Name, tag.Description, userInformation.ID)
+				tagID, err := database.DBInterface.NewTag(tag.Name, tag.Description, tag.Category, userInformation.ID)
 				if err != nil {
 					logging.WriteLog(logging.LogLevelError, "imagerouter/handleImageUpload", userInformation.Name, logging.ResultFailure, []string{"error attempting to create tag", err.Error(), tag.Name})
 					errorCompilation += "Unable to use tag " + tag.Name + " due to a database error. "
@@ -331,11 +327,8 @@ func HandleImageUploadRequest(request *http.Request, userInformation interfaces.
 	var lastID uint64
 	var uploadedIDs []uploadData
 	for _, toUpload := range files {
-		switch ext := strings.ToLower(filepath.Ext(toUpload.Name)); ext {
-		case ".jpg", ".jpeg", ".jfif", ".bmp", ".gif", ".png", ".svg", ".mpg", ".mov", ".webm", ".avi", ".mp4", ".mp3", ".ogg", ".wav", ".webp", ".tiff", ".tif":
-			//Passes filter
-		default:
-			logging.WriteLog(logging.LogLevelVerbose, "imagerouter/handleImageUpload", userInformation.Name, logging.ResultFailure, []string{"Attempted to upload a file which did not pass filter", ext})
+		if IsUploadableFile(toUpload.Name) == false {
+			logging.WriteLog(logging.LogLevelVerbose, "imagerouter/handleImageUpload", userInformation.Name, logging.ResultFailure, []string{"Attempted to upload a file which did not pass filter", filepath.Ext(toUpload.Name)})
 			errorCompilation += toUpload.Name + " is not a recognized file. "
 			continue
 		}
@@ -351,16 +344,16 @@ func HandleImageUploadRequest(request *http.Request, userInformation interfaces.
 				errorCompilation += err.Error()
 				continue
 			}
+			imageLocation := ImageLocation(hashName)
 
-			filePath := path.Join(config.Configuration.ImageDirectory, hashName)
 			//Check if file exists, if so, skip
-			if _, err := os.Stat(filePath); err == nil {
+			if existingLocation, exists := FindStoredImage(hashName); exists {
 				var duplicateID uint64
-				dupInfo, ierr := database.DBInterface.GetImageByFileName(hashName)
+				dupInfo, ierr := database.DBInterface.GetImageByFileName(existingLocation)
 				if ierr == nil {
 					duplicateID = dupInfo.ID
 				}
-				logging.WriteLog(logging.LogLevelInfo, "imagerouter/handleImageUpload", userInformation.Name, logging.ResultInfo, []string{"Skipping as file is already uploaded", toUpload.Name, filePath, strconv.FormatUint(duplicateID, 10)})
+				logging.WriteLog(logging.LogLevelInfo, "imagerouter/handleImageUpload", userInformation.Name, logging.ResultInfo, []string{"Skipping as file is already uploaded", toUpload.Name, existingLocation, strconv.FormatUint(duplicateID, 10)})
 				if ierr == nil {
 					//errorCompilation += fileHeader.Filename + " has already been uploaded as ID " + strconv.FormatUint(duplicateID, 10) + ". "
 					duplicateIDs[toUpload.Name] = duplicateID
@@ -370,35 +363,33 @@ func HandleImageUploadRequest(request *http.Request, userInformation interfaces.
 				continue
 			}
 
-			saveStream, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE, 0660)
-			if err != nil {
-				logging.WriteLog(logging.LogLevelError, "imagerouter/handleImageUpload", userInformation.Name, logging.ResultFailure, []string{"Upload image, failed to open new file", err.Error()})
-				errorCompilation += toUpload.Name + " could not be saved, internal error. "
-				saveStream.Close()
-				continue
-			}
 			//Save Image
 			_, err = fileStream.Seek(0, 0)
 			if err != nil {
 				logging.WriteLog(logging.LogLevelError, "imagerouter/handleImageUpload", userInformation.Name, logging.ResultFailure, []string{"Upload image, failed to seek stream", err.Error()})
 				errorCompilation += toUpload.Name + " could not be saved, internal error. "
-				saveStream.Close()
 				continue
 			}
-			io.Copy(saveStream, fileStream)
-			saveStream.Close()
-			//Add image to Database
-
-			lastID, err = database.DBInterface.NewImage(hashName, hashName, userInformation.ID, source)
+			if err := storage.StorageInterface.Put(imageLocation, fileStream, fileStream.Size()); err != nil {
+				logging.WriteLog(logging.LogLevelError, "imagerouter/handleImageUpload", userInformation.Name, logging.ResultFailure, []string{"Upload image, failed to save file", err.Error()})
+				errorCompilation += toUpload.Name + " could not be saved, internal error. "
+				continue
+			}
+			//Add image to Database, held for a moderator when uploads are moderated
+			pending := UploadNeedsApproval(interfaces.UserPermission(userPermission))
+			lastID, err = database.DBInterface.NewImage(hashName, imageLocation, userInformation.ID, source, pending)
 			if err != nil {
-				logging.WriteLog(logging.LogLevelError, "imagerouter/handleImageUpload", userInformation.Name, logging.ResultFailure, []string{"error attempting to add file to database", err.Error(), filePath})
+				logging.WriteLog(logging.LogLevelError, "imagerouter/handleImageUpload", userInformation.Name, logging.ResultFailure, []string{"error attempting to add file to database", err.Error(), imageLocation})
 				errorCompilation += toUpload.Name + " could not be added to database, internal error. "
 				//Attempt to cleanup file
-				if err := os.Remove(filePath); err != nil {
-					logging.WriteLog(logging.LogLevelError, "imagerouter/handleImageUpload", userInformation.Name, logging.ResultFailure, []string{"error attempting to remove orphaned file", err.Error(), filePath})
+				if err := storage.StorageInterface.Delete(imageLocation); err != nil {
+					logging.WriteLog(logging.LogLevelError, "imagerouter/handleImageUpload", userInformation.Name, logging.ResultFailure, []string{"error attempting to remove orphaned file", err.Error(), imageLocation})
 				}
 				continue
 			}
+			if pending {
+				go WriteAuditLog(userInformation.ID, "IMAGE-UPLOAD", userInformation.Name+" uploaded image "+strconv.FormatUint(lastID, 10)+", which is waiting for approval.")
+			}
 
 			uploadedIDs = append(uploadedIDs, uploadData{Name: originalName, ID: lastID})
 
@@ -414,8 +405,9 @@ func HandleImageUploadRequest(request *http.Request, userInformation interfaces.
 			//Log success
 			go WriteAuditLog(userInformation.ID, "IMAGE-UPLOAD", userInformation.Name+" successfully uploaded an image. "+strconv.FormatUint(lastID, 10))
 			//Start go routine to generate thumbnail
-			go GenerateThumbnail(hashName)
-			go GeneratedHash(hashName, lastID)
+			go GenerateThumbnail(imageLocation)
+			go GeneratedHash(imageLocation, lastID)
+			go GenerateMetadata(imageLocation, lastID)
 		}
 	}
 	//Now handle collection if requested
@@ -452,6 +444,90 @@ func HandleImageUploadRequest(request *http.Request, userInformation interfaces.
 	return lastID, duplicateIDs, nil
 }
 
+//ReplaceImageFile stores File as the new file of an image, keeping the previous file as a revision that can be restored.
+//Tags, votes and collections stay with the image, while the thumbnail and dHash are regenerated for the new file. Returns the new location
+func ReplaceImageFile(ImageInfo interfaces.ImageInformation, FileName string, File io.ReadSeeker, Size int64, ReplacerID uint64) (string, error) {
+	if IsUploadableFile(FileName) == false {
+		return "", errors.New(FileName + " is not a recognized file")
+	}
+	hashName, err := GetNewImageName(FileName, File)
+	if err != nil {
+		return "", err
+	}
+	if existingLocation, exists := FindStoredImage(hashName); exists {
+		if existingLocation == ImageInfo.Location {
+			return "", errors.New(FileName + " is already the file of this image")
+		}
+		return "", errors.New(FileName + " has already been uploaded")
+	}
+	if _, err := File.Seek(0, 0); err != nil {
+		logging.WriteLog(logging.LogLevelError, "imageuploadhelpers/ReplaceImageFile", strconv.FormatUint(ReplacerID, 10), logging.ResultFailure, []string{"Failed to seek stream", err.Error()})
+		return "", errors.New(FileName + " could not be saved, internal error")
+	}
+	imageLocation := ImageLocation(hashName)
+	if err := storage.StorageInterface.Put(imageLocation, File, Size); err != nil {
+		logging.WriteLog(logging.LogLevelError, "imageuploadhelpers/ReplaceImageFile", strconv.FormatUint(ReplacerID, 10), logging.ResultFailure, []string{"Failed to save file", err.Error()})
+		return "", errors.New(FileName + " could not be saved, internal error")
+	}
+	if err := database.DBInterface.ReplaceImageFile(ImageInfo.ID, imageLocation, ReplacerID); err != nil {
+		//Attempt to cleanup file
+		if err := storage.StorageInterface.Delete(imageLocation); err != nil {
+			logging.WriteLog(logging.LogLevelError, "imageuploadhelpers/ReplaceImageFile", strconv.FormatUint(ReplacerID, 10), logging.ResultFailure, []string{"error attempting to remove orphaned file", err.Error(), imageLocation})
+		}
+		return "", errors.New(FileName + " could not be added to database, internal error")
+	}
+	renameToLocation(ImageInfo, imageLocation)
+	//Start go routines to generate the thumbnail, hash and metadata of the new file
+	go GenerateThumbnail(imageLocation)
+	go GeneratedHash(imageLocation, ImageInfo.ID)
+	go GenerateMetadata(imageLocation, ImageInfo.ID)
+	return imageLocation, nil
+}
+
+//RestoreImageRevision makes a previous file of an image current again, the file it replaces becomes a revision in its place
+func RestoreImageRevision(ImageInfo interfaces.ImageInformation, Revision interfaces.ImageRevisionInformation, RestorerID uint64) error {
+	if Revision.ImageID != ImageInfo.ID {
+		return errors.New("revision does not belong to this image")
+	}
+	if err := database.DBInterface.RestoreImageRevision(Revision.ID, RestorerID); err != nil {
+		return err
+	}
+	renameToLocation(ImageInfo, Revision.Location)
+	//The thumbnail was kept with the file, but the hash and metadata are only stored for the current one
+	go GeneratedHash(Revision.Location, ImageInfo.ID)
+	go GenerateMetadata(Revision.Location, ImageInfo.ID)
+	return nil
+}
+
+//renameToLocation keeps the name of an image in step with its file, for images that still have the name they were uploaded with
+func renameToLocation(ImageInfo interfaces.ImageInformation, Location string) {
+	if ImageInfo.Name != path.Base(ImageInfo.Location) {
+		return
+	}
+	if err := database.DBInterface.UpdateImage(ImageInfo.ID, path.Base(Location), nil, nil, nil, nil, nil); err != nil {
+		logging.WriteLog(logging.LogLevelWarning, "imageuploadhelpers/renameToLocation", "0", logging.ResultFailure, []string{"Failed to rename image after its file", strconv.FormatUint(ImageInfo.ID, 10), err.Error()})
+	}
+}
+
+//IsUploadableFile returns true if Name has the extension of a file type that may be uploaded
+func IsUploadableFile(Name string) bool {
+	switch strings.ToLower(filepath.Ext(Name)) {
+	case ".jpg", ".jpeg", ".jfif", ".bmp", ".gif", ".png", ".svg", ".mpg", ".mov", ".webm", ".avi", ".mp4", ".mp3", ".ogg", ".wav", ".webp", ".tiff", ".tif":
+		return true
+	}
+	return false
+}
+
+//FindStoredImage returns where the image Name is stored, checking both the flat and sharded layouts
+func FindStoredImage(Name string) (string, bool) {
+	for _, location := range []string{Name, storage.ShardedName(Name)} {
+		if _, err := storage.StorageInterface.Stat(location); err == nil {
+			return location, true
+		}
+	}
+	return "", false
+}
+
 //GetNewImageName uses the original filename and file contents to create a new name
 func GetNewImageName(originalName string, fileStream io.Reader) (string, error) {
 	hasher := sha256.New()
@@ -0,0 +1,122 @@
+package routers
+
+import (
+	"database/sql"
+	"go-image-board/config"
+	"go-image-board/database"
+	"go-image-board/interfaces"
+	"go-image-board/logging"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+//UploadNeedsApproval returns true when uploads from a user with Permissions are held in the moderation queue
+func UploadNeedsApproval(Permissions interfaces.UserPermission) bool {
+	return config.Configuration.ModerateUploads && Permissions.HasPermission(interfaces.ApprovePosts) != true
+}
+
+//CanSeePendingImage returns true if an image is not waiting for approval, or the user is its uploader or may approve it
+func CanSeePendingImage(ImageInfo interfaces.ImageInformation, UserID uint64, Permissions interfaces.UserPermission) bool {
+	return ImageInfo.Pending == false || (UserID != 0 && ImageInfo.UploaderID == UserID) || Permissions.HasPermission(interfaces.ApprovePosts)
+}
+
+//getVisibleImage returns an image, or sql.ErrNoRows if it is waiting for approval and the user cannot see it
+func getVisibleImage(ImageID uint64, UserID uint64, Permissions interfaces.UserPermission) (interfaces.ImageInformation, error) {
+	imageInfo, err := database.DBInterface.GetImage(ImageID)
+	if err == nil && CanSeePendingImage(imageInfo, UserID, Permissions) == false {
+		return interfaces.ImageInformation{}, sql.ErrNoRows
+	}
+	return imageInfo, err
+}
+
+//ModQueueGetRouter serves get requests to /mod/queue
+func ModQueueGetRouter(responseWriter http.ResponseWriter, request *http.Request) {
+	TemplateInput := getTemplateInputFromRequest(responseWriter, request)
+	if TemplateInput.UserPermissions.HasPermission(interfaces.ApprovePosts) != true {
+		TemplateInput.HTMLMessage += template.HTML("You do not have permission to view the moderation queue.<br>")
+		redirectWithFlash(responseWriter, request, "/mod", TemplateInput.HTMLMessage, "ModFail")
+		return
+	}
+
+	//Get the page offset
+	pageStart, _ := strconv.ParseUint(request.FormValue("PageStart"), 10, 32) //Either parses fine, or is 0, both works
+	pageStride := config.Configuration.PageStride
+
+	var err error
+	TemplateInput.ImageInfo, TemplateInput.TotalResults, err = database.DBInterface.GetPendingImages(pageStart, pageStride)
+	if err != nil {
+		TemplateInput.HTMLMessage += template.HTML("Failed to get the moderation queue.<br>")
+		logging.WriteLog(logging.LogLevelError, "modqueuerouter/ModQueueGetRouter", TemplateInput.UserInformation.GetCompositeID(), logging.ResultFailure, []string{"Failed to get pending images", err.Error()})
+	} else {
+		TemplateInput.PageMenu, _ = generatePageMenu(int64(pageStart), int64(pageStride), int64(TemplateInput.TotalResults), "", "/mod/queue")
+	}
+
+	replyWithTemplate("modqueue.html", TemplateInput, responseWriter, request)
+}
+
+//ModQueuePostRouter serves post requests to /mod/queue, approving or rejecting an image waiting in it. A reason is required to reject
+func ModQueuePostRouter(responseWriter http.ResponseWriter, request *http.Request) {
+	TemplateInput := getTemplateInputFromRequest(responseWriter, request)
+	if !TemplateInput.IsLoggedOn() {
+		redirectWithFlash(responseWriter, request, "/logon", "You must be logged in to moderate uploads", "LogonRequired")
+		return
+	}
+	command := request.FormValue("command")
+	if TemplateInput.UserPermissions.HasPermission(interfaces.ApprovePosts) != true {
+		TemplateInput.HTMLMessage += template.HTML("You do not have permission to approve or reject uploads.<br>")
+		go WriteAuditLog(TemplateInput.UserInformation.ID, "MOD-QUEUE", TemplateInput.UserInformation.Name+" failed to "+command+" image. Insufficient permissions. "+request.FormValue("ID"))
+		redirectWithFlash(responseWriter, request, "/mod", TemplateInput.HTMLMessage, "ModFail")
+		return
+	}
+
+	requestedID, err := strconv.ParseUint(request.FormValue("ID"), 10, 32)
+	if err != nil {
+		TemplateInput.HTMLMessage += template.HTML("Failed to parse image ID.<br>")
+		redirectWithFlash(responseWriter, request, "/mod/queue", TemplateInput.HTMLMessage, "ModFail")
+		return
+	}
+	imageInfo, err := database.DBInterface.GetImage(requestedID)
+	if err != nil || imageInfo.Pending == false || imageInfo.InTrash {
+		TemplateInput.HTMLMessage += template.HTML("That image is not waiting for approval.<br>")
+		redirectWithFlash(responseWriter, request, "/mod/queue", TemplateInput.HTMLMessage, "ModFail")
+		return
+	}
+	reason := strings.TrimSpace(request.FormValue("Reason"))
+
+	switch command {
+	case "approve":
+		if err := database.DBInterface.SetImagePending(requestedID, false); err != nil {
+			TemplateInput.HTMLMessage += template.HTML("Failed to approve image.<br>")
+			redirectWithFlash(responseWriter, request, "/mod/queue", TemplateInput.HTMLMessage, "ModFail")
+			return
+		}
+		auditMessage := TemplateInput.UserInformation.Name + " approved image " + strconv.FormatUint(requestedID, 10) + " uploaded by " + imageInfo.UploaderName + "."
+		if reason != "" {
+			auditMessage += " Reason: " + reason
+		}
+		go WriteAuditLog(TemplateInput.UserInformation.ID, "MOD-APPROVE", auditMessage)
+		TemplateInput.HTMLMessage += template.HTML("Image approved.<br>")
+		redirectWithFlash(responseWriter, request, "/mod/queue", TemplateInput.HTMLMessage, "ModSuccess")
+		return
+	case "reject":
+		if reason == "" {
+			TemplateInput.HTMLMessage += template.HTML("A reason is required to reject an image.<br>")
+			redirectWithFlash(responseWriter, request, "/mod/queue", TemplateInput.HTMLMessage, "ModFail")
+			return
+		}
+		//Rejected images go to the trash, where they can still be restored until purged
+		if err := database.DBInterface.TrashImage(requestedID, TemplateInput.UserInformation.ID); err != nil {
+			TemplateInput.HTMLMessage += template.HTML("Failed to reject image.<br>")
+			redirectWithFlash(responseWriter, request, "/mod/queue", TemplateInput.HTMLMessage, "ModFail")
+			return
+		}
+		go WriteAuditLog(TemplateInput.UserInformation.ID, "MOD-REJECT", TemplateInput.UserInformation.Name+" rejected image "+strconv.FormatUint(requestedID, 10)+" uploaded by "+imageInfo.UploaderName+". Reason: "+reason)
+		TemplateInput.HTMLMessage += template.HTML("Image rejected.<br>")
+		redirectWithFlash(responseWriter, request, "/mod/queue", TemplateInput.HTMLMessage, "ModSuccess")
+		return
+	}
+	TemplateInput.HTMLMessage += template.HTML("Command not recognized or form submitted incorrectly.<br>")
+	redirectWithFlash(responseWriter, request, "/mod/queue", TemplateInput.HTMLMessage, "ModFail")
+}
@@ -0,0 +1,159 @@
+package routers
+
+import (
+	"go-image-board/config"
+	"go-image-board/database"
+	"go-image-board/interfaces"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestModQueueRouter(t *testing.T) {
+	fixture := seedDatabase(t)
+	server := newTestServer(t)
+	admin := newTestClient(t, server)
+	admin.logon(t, "admin", "adminpass")
+	for userName, permissions := range map[string]uint64{"uploader": uint64(interfaces.UploadImage), "viewer": 0} {
+		if err := database.DBInterface.CreateUser(userName, []byte(userName+"pass"), userName+"@example.com", permissions); err != nil {
+			t.Fatal(err)
+		}
+	}
+	uploader := newTestClient(t, server)
+	uploader.logon(t, "uploader", "uploaderpass")
+	viewer := newTestClient(t, server)
+	viewer.logon(t, "viewer", "viewerpass")
+
+	config.Configuration.ModerateUploads = true
+	defer func() { config.Configuration.ModerateUploads = false }()
+	uploaderPermissions, _ := database.DBInterface.GetUserPermissionSet("uploader")
+	adminPermissions, _ := database.DBInterface.GetUserPermissionSet("admin")
+	if UploadNeedsApproval(uploaderPermissions) == false || UploadNeedsApproval(adminPermissions) {
+		t.Fatal("only users without ApprovePosts should need approval")
+	}
+
+	uploaderID, _ := database.DBInterface.GetUserID("uploader")
+	pendingID, err := database.DBInterface.NewImage("pending", "pending.png", uploaderID, "", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := database.DBInterface.AddTag([]uint64{fixture.Tags["cat"]}, pendingID, uploaderID); err != nil {
+		t.Fatal(err)
+	}
+	imagePath := "/image?ID=" + strconv.FormatUint(pendingID, 10)
+	queueLink := `href="` + imagePath + `"`
+
+	//Pending images are never found, and only the uploader and moderators can open them
+	for _, client := range []*testClient{admin, uploader} {
+		if _, body := client.get(t, "/images?SearchTerms=cat"); containsID(linkedImageIDs(body), pendingID) {
+			t.Error("search found a pending image")
+		}
+		if response, body := client.get(t, imagePath); response.StatusCode != http.StatusOK || strings.Contains(body, "Waiting for approval") == false {
+			t.Errorf("pending image returned %d without a notice", response.StatusCode)
+		}
+	}
+	if response, _ := viewer.get(t, imagePath); response.StatusCode != http.StatusFound {
+		t.Errorf("pending image returned %d to another user", response.StatusCode)
+	}
+	for _, command := range []url.Values{
+		{"command": {"ChangeFavorite"}, "ID": {strconv.FormatUint(pendingID, 10)}, "Favorite": {"true"}},
+		{"command": {"AddComment"}, "ID": {strconv.FormatUint(pendingID, 10)}, "Body": {"Hello"}},
+	} {
+		if response, _ := viewer.postForm(t, "/image", command); strings.Contains(response.Header.Get("Location"), "UpdateFailed") == false {
+			t.Errorf("%s on a pending image redirected to %q", command.Get("command"), response.Header.Get("Location"))
+		}
+	}
+	viewerID, _ := database.DBInterface.GetUserID("viewer")
+	if favorite, _ := database.DBInterface.GetUserFavorite(viewerID, pendingID); favorite {
+		t.Error("another user favorited a pending image")
+	}
+
+	//Only moderators see the queue
+	if response, _ := uploader.get(t, "/mod/queue"); response.StatusCode != http.StatusFound {
+		t.Errorf("queue returned %d to the uploader", response.StatusCode)
+	}
+	if response, _ := uploader.postForm(t, "/mod/queue", url.Values{"command": {"approve"}, "ID": {strconv.FormatUint(pendingID, 10)}}); response.StatusCode != http.StatusFound {
+		t.Errorf("uploader approve returned %d", response.StatusCode)
+	}
+	response, body := admin.get(t, "/mod/queue")
+	if response.StatusCode != http.StatusOK || strings.Contains(body, queueLink) == false || strings.Contains(body, "Uploaded by uploader") == false {
+		t.Fatalf("queue returned %d without the pending image", response.StatusCode)
+	}
+
+	//Rejecting requires a reason, and moves the image to the trash
+	admin.postForm(t, "/mod/queue", url.Values{"command": {"reject"}, "ID": {strconv.FormatUint(pendingID, 10)}, "Reason": {" "}})
+	if imageInfo, _ := database.DBInterface.GetImage(pendingID); imageInfo.InTrash || imageInfo.Pending == false {
+		t.Fatalf("rejected without a reason, %+v", imageInfo)
+	}
+	admin.postForm(t, "/mod/queue", url.Values{"command": {"reject"}, "ID": {strconv.FormatUint(pendingID, 10)}, "Reason": {"Off topic"}})
+	if imageInfo, _ := database.DBInterface.GetImage(pendingID); imageInfo.InTrash == false || imageInfo.DeleterID != fixture.AdminID {
+		t.Fatalf("rejected image is %+v", imageInfo)
+	}
+	if _, body := admin.get(t, "/mod/queue"); strings.Contains(body, queueLink) {
+		t.Error("queue still lists the rejected image")
+	}
+
+	//Approving publishes the image
+	if err := database.DBInterface.RestoreImage(pendingID); err != nil {
+		t.Fatal(err)
+	}
+	if response, _ := admin.postForm(t, "/mod/queue", url.Values{"command": {"approve"}, "ID": {strconv.FormatUint(pendingID, 10)}, "Reason": {"Fine after all"}}); response.StatusCode != http.StatusFound {
+		t.Errorf("approve returned %d", response.StatusCode)
+	}
+	if _, body := viewer.get(t, "/images?SearchTerms=cat"); containsID(linkedImageIDs(body), pendingID) == false {
+		t.Error("search does not find the approved image")
+	}
+	if response, _ := admin.postForm(t, "/mod/queue", url.Values{"command": {"approve"}, "ID": {strconv.FormatUint(pendingID, 10)}}); strings.Contains(response.Header.Get("Location"), "ModFail") == false {
+		t.Errorf("approving twice redirected to %q", response.Header.Get("Location"))
+	}
+
+	//Every decision is audited, with its reason
+	expected := map[string]string{"MOD-REJECT": "Reason: Off topic", "MOD-APPROVE": "Reason: Fine after all"}
+	for deadline := time.Now().Add(2 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		logs := auditLogs(t)
+		missing := ""
+		for logType, info := range expected {
+			if strings.Contains(logs[logType], info) == false {
+				missing = logType
+			}
+		}
+		if missing == "" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%s was not audited, logs are %v", missing, logs)
+		}
+	}
+}
+
+//containsID returns true if ID is in IDs
+func containsID(IDs []uint64, ID uint64) bool {
+	for _, value := range IDs {
+		if value == ID {
+			return true
+		}
+	}
+	return false
+}
+
+//auditLogs returns the info of every audit log, joined by type
+func auditLogs(t *testing.T) map[string]string {
+	t.Helper()
+	logs := make(map[string]string)
+	for _, table := range interfaces.BackupTables {
+		if table.Name != "AuditLogs" {
+			continue
+		}
+		err := database.DBInterface.ExportTables([]interfaces.BackupTable{table}, func(Table interfaces.BackupTable, Row interfaces.BackupRow) error {
+			logs[Row["Type"].(string)] += Row["Info"].(string) + "\n"
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("ExportTables: %v", err)
+		}
+	}
+	return logs
+}
@@ -1,11 +1,22 @@
 package routers
 
 import (
+	"go-image-board/database"
+	"go-image-board/interfaces"
+	"go-image-board/logging"
 	"net/http"
 )
 
 //ModRouter serves requests to /mod
 func ModRouter(responseWriter http.ResponseWriter, request *http.Request) {
 	TemplateInput := getTemplateInputFromRequest(responseWriter, request)
+	//Moderators handling reports see how many are waiting in their inbox
+	if TemplateInput.UserPermissions.HasPermission(interfaces.HandleReports) {
+		var err error
+		_, TemplateInput.TotalResults, err = database.DBInterface.GetReports(interfaces.ReportOpen, 0, 0)
+		if err != nil {
+			logging.WriteLog(logging.LogLevelError, "modrouter/ModRouter", TemplateInput.UserInformation.GetCompositeID(), logging.ResultFailure, []string{"Failed to count open reports", err.Error()})
+		}
+	}
 	replyWithTemplate("mod.html", TemplateInput, responseWriter, request)
 }
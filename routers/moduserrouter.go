@@ -4,9 +4,11 @@ import (
 	"go-image-board/database"
 	"go-image-board/interfaces"
 	"go-image-board/logging"
+	"html"
 	"html/template"
 	"net/http"
 	"strconv"
+	"time"
 )
 
 //ModUserGetRouter serves get requests to /mod/user
@@ -121,6 +123,50 @@ func ModUserPostRouter(responseWriter http.ResponseWriter, request *http.Request
 		TemplateInput.HTMLMessage += template.HTML("Successfully set the user's disable state.<br>")
 		redirectWithFlash(responseWriter, request, "/mod/user?userName="+request.FormValue("userName"), TemplateInput.HTMLMessage, "ModSucceeded")
 		return
+	case "undoTagChanges":
+		//Check if logged in
+		if TemplateInput.UserInformation.ID == 0 {
+			TemplateInput.HTMLMessage += template.HTML("You must be logged in to perform that action.<br>")
+			redirectWithFlash(responseWriter, request, "/logon", TemplateInput.HTMLMessage, "LogonRequired")
+			return
+		}
+		//Check if has permissions
+		if TemplateInput.UserPermissions.HasPermission(interfaces.BulkTagOperations) != true {
+			TemplateInput.HTMLMessage += template.HTML("User does not have permission to undo tag changes.<br>")
+			go WriteAuditLog(TemplateInput.UserInformation.ID, "UNDO-USERTAGCHANGES", TemplateInput.UserInformation.Name+" failed to undo user tag changes, insufficient permissions.")
+			redirectWithFlash(responseWriter, request, "/mod", TemplateInput.HTMLMessage, "ModFailed")
+			return
+		}
+		//Do the thing, times are whole minutes in UTC, and until includes the minute given
+		since, err := time.Parse("2006-01-02T15:04", request.FormValue("since"))
+		var until time.Time
+		if err == nil {
+			until, err = time.Parse("2006-01-02T15:04", request.FormValue("until"))
+		}
+		if err != nil {
+			TemplateInput.HTMLMessage += template.HTML("Failed to parse time window.<br>")
+			redirectWithFlash(responseWriter, request, "/mod/user?userName="+request.FormValue("userName"), TemplateInput.HTMLMessage, "ModFailed")
+			return
+		}
+		until = until.Add(time.Minute - time.Nanosecond)
+		sUserName := request.FormValue("userName")
+		iUserID, err := database.DBInterface.GetUserID(sUserName)
+		if err != nil {
+			TemplateInput.HTMLMessage += template.HTML("Failed to find user.<br>")
+			redirectWithFlash(responseWriter, request, "/mod", TemplateInput.HTMLMessage, "ModFailed")
+			return
+		}
+		undone, err := UndoUserTagChanges(iUserID, since, until, TemplateInput.UserInformation.ID)
+		if err != nil {
+			TemplateInput.HTMLMessage += template.HTML("Failed to undo tag changes: " + html.EscapeString(err.Error()) + ".<br>")
+			go WriteAuditLog(TemplateInput.UserInformation.ID, "UNDO-USERTAGCHANGES", TemplateInput.UserInformation.Name+" failed to undo tag changes of "+sUserName+". "+err.Error())
+			redirectWithFlash(responseWriter, request, "/mod/user?userName="+request.FormValue("userName"), TemplateInput.HTMLMessage, "ModFailed")
+			return
+		}
+		go WriteAuditLog(TemplateInput.UserInformation.ID, "UNDO-USERTAGCHANGES", TemplateInput.UserInformation.Name+" undid tag changes of "+sUserName+" from "+since.Format(time.RFC3339)+" until "+until.Format(time.RFC3339)+", "+strconv.FormatUint(undone, 10)+" changes undone")
+		TemplateInput.HTMLMessage += template.HTML("Successfully undid " + strconv.FormatUint(undone, 10) + " tag changes.<br>")
+		redirectWithFlash(responseWriter, request, "/mod/user?userName="+request.FormValue("userName"), TemplateInput.HTMLMessage, "ModSucceeded")
+		return
 	}
 
 	TemplateInput.HTMLMessage += template.HTML("Command not recognized or provided.<br>")
@@ -0,0 +1,221 @@
+package routers
+
+import (
+	"errors"
+	"go-image-board/database"
+	"go-image-board/interfaces"
+	"go-image-board/logging"
+	"html/template"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+//MaxNoteLength is the longest note body accepted, in characters
+const MaxNoteLength = 2048
+
+//ValidateNoteBody trims a note body, then ensures it is neither empty nor too long
+func ValidateNoteBody(Body string) (string, error) {
+	Body = strings.TrimSpace(Body)
+	if Body == "" {
+		return Body, errors.New("the note cannot be empty")
+	}
+	if utf8.RuneCountInString(Body) > MaxNoteLength {
+		return Body, errors.New("the note cannot be longer than " + strconv.Itoa(MaxNoteLength) + " characters")
+	}
+	return Body, nil
+}
+
+//ValidateNoteRegion ensures a region fits on an image. When the region does not give the dimensions it was placed on, the current dimensions of the image are used
+func ValidateNoteRegion(Region interfaces.NoteRegion, ImageInfo interfaces.ImageInformation) (interfaces.NoteRegion, error) {
+	if Region.ImageWidth == 0 && Region.ImageHeight == 0 {
+		Region.ImageWidth = ImageInfo.Width
+		Region.ImageHeight = ImageInfo.Height
+	}
+	return Region, Region.Validate()
+}
+
+//RotateImageNotes turns every note on an image clockwise by Degrees, to follow a file that was rotated by as much
+func RotateImageNotes(ImageID uint64, Degrees int64, EditorID uint64) error {
+	notes, err := database.DBInterface.GetNotes(ImageID, false)
+	if err != nil {
+		return err
+	}
+	for _, note := range notes {
+		region, err := note.NoteRegion.Rotate(Degrees)
+		if err != nil {
+			return err
+		}
+		if err := database.DBInterface.UpdateNote(note.ID, region, note.Body, EditorID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//RestoreNoteRevision makes a previous version of a note current again, including deleting it if that version was a deletion
+func RestoreNoteRevision(Note interfaces.NoteInformation, Revision interfaces.NoteRevisionInformation, EditorID uint64) error {
+	if Revision.NoteID != Note.ID {
+		return errors.New("revision does not belong to this note")
+	}
+	if Revision.Deleted {
+		if Note.Deleted {
+			return nil
+		}
+		return database.DBInterface.DeleteNote(Note.ID, EditorID)
+	}
+	return database.DBInterface.UpdateNote(Note.ID, Revision.NoteRegion, Revision.Body, EditorID)
+}
+
+//loadNotes fills TemplateInput with the notes on an image, and the history of every note on it for users that may edit notes
+func loadNotes(TemplateInput *templateInput, ImageID uint64) {
+	notes, err := database.DBInterface.GetNotes(ImageID, true)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "notehelpers/loadNotes", TemplateInput.UserInformation.GetCompositeID(), logging.ResultFailure, []string{"Failed to load notes", err.Error()})
+		TemplateInput.HTMLMessage += template.HTML("Failed to load notes.<br>")
+		return
+	}
+	canEditNotes := TemplateInput.UserPermissions.HasPermission(interfaces.EditNotes)
+	for _, note := range notes {
+		if note.Deleted == false {
+			TemplateInput.Notes = append(TemplateInput.Notes, note)
+		}
+		if canEditNotes {
+			revisions, err := database.DBInterface.GetNoteRevisions(note.ID)
+			if err != nil {
+				//log err but no need to inform user
+				logging.WriteLog(logging.LogLevelError, "notehelpers/loadNotes", TemplateInput.UserInformation.GetCompositeID(), logging.ResultFailure, []string{"Failed to load note revisions", err.Error()})
+				continue
+			}
+			TemplateInput.NoteRevisions = append(TemplateInput.NoteRevisions, revisions...)
+		}
+	}
+	sort.Slice(TemplateInput.NoteRevisions, func(i, j int) bool { return TemplateInput.NoteRevisions[i].ID > TemplateInput.NoteRevisions[j].ID })
+}
+
+//handleNoteCommand performs the AddNote, EditNote, DeleteNote, RestoreNoteRevision or RotateNotes command posted for an image.
+//The outcome is added to TemplateInput.HTMLMessage, and the name of the flash to redirect with is returned
+func handleNoteCommand(TemplateInput *templateInput, request *http.Request, ImageInfo interfaces.ImageInformation) string {
+	command := request.FormValue("command")
+	if TemplateInput.UserPermissions.HasPermission(interfaces.EditNotes) != true {
+		go WriteAuditLog(TemplateInput.UserInformation.ID, "NOTE-EDIT", TemplateInput.UserInformation.Name+" failed to "+command+" on image "+strconv.FormatUint(ImageInfo.ID, 10)+". No permissions.")
+		TemplateInput.HTMLMessage += template.HTML("You do not have permission to edit notes.<br>")
+		return "UpdateFailed"
+	}
+	if command == "RotateNotes" {
+		Degrees, err := strconv.ParseInt(request.FormValue("Degrees"), 10, 64)
+		if err == nil {
+			err = RotateImageNotes(ImageInfo.ID, Degrees, TemplateInput.UserInformation.ID)
+		}
+		if err != nil {
+			TemplateInput.HTMLMessage += template.HTML("Failed to rotate notes, " + template.HTMLEscapeString(err.Error()) + ".<br>")
+			return "UpdateFailed"
+		}
+		go WriteAuditLog(TemplateInput.UserInformation.ID, "NOTE-ROTATE", TemplateInput.UserInformation.Name+" rotated the notes on image "+strconv.FormatUint(ImageInfo.ID, 10)+" by "+strconv.FormatInt(Degrees, 10)+" degrees.")
+		TemplateInput.HTMLMessage += template.HTML("Notes rotated.<br>")
+		return "UpdateSucceeded"
+	}
+	if command == "AddNote" {
+		region, body, err := parseNoteForm(request, ImageInfo)
+		if err != nil {
+			TemplateInput.HTMLMessage += template.HTML("Failed to add note, " + template.HTMLEscapeString(err.Error()) + ".<br>")
+			return "UpdateFailed"
+		}
+		NoteID, err := database.DBInterface.NewNote(interfaces.NoteInformation{ImageID: ImageInfo.ID, CreatorID: TemplateInput.UserInformation.ID, NoteRegion: region, Body: body})
+		if err != nil {
+			TemplateInput.HTMLMessage += template.HTML("Failed to add note.<br>")
+			return "UpdateFailed"
+		}
+		go WriteAuditLog(TemplateInput.UserInformation.ID, "NOTE-ADD", TemplateInput.UserInformation.Name+" added note "+strconv.FormatUint(NoteID, 10)+" to image "+strconv.FormatUint(ImageInfo.ID, 10)+".")
+		TemplateInput.HTMLMessage += template.HTML("Note added.<br>")
+		return "UpdateSucceeded"
+	}
+
+	//Every other command changes an existing note, which must be on this image
+	NoteID, err := strconv.ParseUint(request.FormValue("NoteID"), 10, 64)
+	if err != nil {
+		TemplateInput.HTMLMessage += template.HTML("Failed to parse note id.<br>")
+		return "UpdateFailed"
+	}
+	note, err := database.DBInterface.GetNote(NoteID)
+	if err != nil || note.ImageID != ImageInfo.ID {
+		TemplateInput.HTMLMessage += template.HTML("No note by that ID.<br>")
+		return "UpdateFailed"
+	}
+	switch command {
+	case "EditNote":
+		region, body, err := parseNoteForm(request, ImageInfo)
+		if err != nil {
+			TemplateInput.HTMLMessage += template.HTML("Failed to edit note, " + template.HTMLEscapeString(err.Error()) + ".<br>")
+			return "UpdateFailed"
+		}
+		if err := database.DBInterface.UpdateNote(NoteID, region, body, TemplateInput.UserInformation.ID); err != nil {
+			TemplateInput.HTMLMessage += template.HTML("Failed to edit note.<br>")
+			return "UpdateFailed"
+		}
+		go WriteAuditLog(TemplateInput.UserInformation.ID, "NOTE-EDIT", TemplateInput.UserInformation.Name+" edited note "+strconv.FormatUint(NoteID, 10)+".")
+		TemplateInput.HTMLMessage += template.HTML("Note edited.<br>")
+		return "UpdateSucceeded"
+	case "DeleteNote":
+		if err := database.DBInterface.DeleteNote(NoteID, TemplateInput.UserInformation.ID); err != nil {
+			TemplateInput.HTMLMessage += template.HTML("Failed to delete note, " + template.HTMLEscapeString(err.Error()) + ".<br>")
+			return "UpdateFailed"
+		}
+		go WriteAuditLog(TemplateInput.UserInformation.ID, "NOTE-DELETE", TemplateInput.UserInformation.Name+" deleted note "+strconv.FormatUint(NoteID, 10)+".")
+		TemplateInput.HTMLMessage += template.HTML("Note deleted.<br>")
+		return "UpdateSucceeded"
+	case "RestoreNoteRevision":
+		RevisionID, err := strconv.ParseUint(request.FormValue("RevisionID"), 10, 64)
+		if err != nil {
+			TemplateInput.HTMLMessage += template.HTML("Failed to parse revision id.<br>")
+			return "UpdateFailed"
+		}
+		revisions, err := database.DBInterface.GetNoteRevisions(NoteID)
+		if err != nil {
+			TemplateInput.HTMLMessage += template.HTML("Failed to get note revisions.<br>")
+			return "UpdateFailed"
+		}
+		for _, revision := range revisions {
+			if revision.ID == RevisionID {
+				if err := RestoreNoteRevision(note, revision, TemplateInput.UserInformation.ID); err != nil {
+					TemplateInput.HTMLMessage += template.HTML("Failed to restore note, " + template.HTMLEscapeString(err.Error()) + ".<br>")
+					return "UpdateFailed"
+				}
+				go WriteAuditLog(TemplateInput.UserInformation.ID, "NOTE-EDIT", TemplateInput.UserInformation.Name+" restored note "+strconv.FormatUint(NoteID, 10)+" to revision "+strconv.FormatUint(RevisionID, 10)+".")
+				TemplateInput.HTMLMessage += template.HTML("Note restored.<br>")
+				return "UpdateSucceeded"
+			}
+		}
+		TemplateInput.HTMLMessage += template.HTML("No revision by that ID.<br>")
+		return "UpdateFailed"
+	}
+	TemplateInput.HTMLMessage += template.HTML("Unknown note command.<br>")
+	return "UpdateFailed"
+}
+
+//parseNoteForm returns the validated region and body posted for a note
+func parseNoteForm(request *http.Request, ImageInfo interfaces.ImageInformation) (interfaces.NoteRegion, string, error) {
+	var region interfaces.NoteRegion
+	for _, field := range []struct {
+		name  string
+		value *int64
+	}{{"X", &region.X}, {"Y", &region.Y}, {"Width", &region.Width}, {"Height", &region.Height}, {"ImageWidth", &region.ImageWidth}, {"ImageHeight", &region.ImageHeight}} {
+		formValue := request.FormValue(field.name)
+		if formValue == "" && (field.name == "ImageWidth" || field.name == "ImageHeight") {
+			continue //Optional, the current dimensions of the image are used instead
+		}
+		value, err := strconv.ParseInt(formValue, 10, 64)
+		if err != nil {
+			return region, "", errors.New(strings.ToLower(field.name) + " must be a number")
+		}
+		*field.value = value
+	}
+	region, err := ValidateNoteRegion(region, ImageInfo)
+	if err != nil {
+		return region, "", err
+	}
+	body, err := ValidateNoteBody(request.FormValue("Body"))
+	return region, body, err
+}
@@ -0,0 +1,180 @@
+package routers
+
+import (
+	"errors"
+	"go-image-board/database"
+	"go-image-board/interfaces"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+//MaxReportLength is the longest report reason or outcome accepted, in characters
+const MaxReportLength = 2048
+
+//ReportAction is what a moderator does to the reported object while resolving a report
+type ReportAction struct {
+	//Command is delete, rating or retag, or blank to leave the object as it is
+	Command string
+	//Rating is the new rating of a reported image, for the rating command
+	Rating string
+	//AddTags and RemoveTags are the existing tags to add to or remove from a reported image, for the retag command
+	AddTags    string
+	RemoveTags string
+}
+
+//ValidateReport trims the reason of a new report, then ensures it is complete and that the object reported exists and can be seen by the reporter
+func ValidateReport(Report *interfaces.ReportInformation, Permissions interfaces.UserPermission) error {
+	Report.Reason = strings.TrimSpace(Report.Reason)
+	if err := Report.Validate(); err != nil {
+		return err
+	}
+	if Report.Reason == "" {
+		return errors.New("a reason is required")
+	}
+	if utf8.RuneCountInString(Report.Reason) > MaxReportLength {
+		return errors.New("the reason cannot be longer than " + strconv.Itoa(MaxReportLength) + " characters")
+	}
+	switch Report.TargetType {
+	case "image":
+		imageInfo, err := database.DBInterface.GetImage(Report.TargetID)
+		if err != nil || imageInfo.InTrash || CanSeePendingImage(imageInfo, Report.ReporterID, Permissions) == false {
+			return errors.New("that image does not exist")
+		}
+	case "collection":
+		collectionInfo, err := database.DBInterface.GetCollection(Report.TargetID)
+		if err != nil || collectionInfo.InTrash {
+			return errors.New("that collection does not exist")
+		}
+	case "tag":
+		if _, err := database.DBInterface.GetTag(Report.TargetID, false); err != nil {
+			return errors.New("that tag does not exist")
+		}
+	}
+	return nil
+}
+
+//ValidateReportOutcome trims the outcome a moderator gives a report, then ensures it is not too long
+func ValidateReportOutcome(Outcome string) (string, error) {
+	Outcome = strings.TrimSpace(Outcome)
+	if utf8.RuneCountInString(Outcome) > MaxReportLength {
+		return Outcome, errors.New("the outcome cannot be longer than " + strconv.Itoa(MaxReportLength) + " characters")
+	}
+	return Outcome, nil
+}
+
+//ApplyReportAction performs Action on the object a report is about, as the moderator ModeratorID with Permissions.
+//Returns a description of what was done for the outcome of the report, which is blank if Action changes nothing
+func ApplyReportAction(Report interfaces.ReportInformation, Action ReportAction, ModeratorID uint64, Permissions interfaces.UserPermission) (string, error) {
+	switch Action.Command {
+	case "":
+		return "", nil
+	case "delete":
+		switch Report.TargetType {
+		case "image":
+			if Permissions.HasPermission(interfaces.RemoveImage) != true {
+				return "", errors.New("you do not have permission to delete images")
+			}
+			if err := database.DBInterface.TrashImage(Report.TargetID, ModeratorID); err != nil {
+				return "", errors.New("failed to delete the image, is it already in the trash?")
+			}
+			return "The image was deleted.", nil
+		case "collection":
+			if Permissions.HasPermission(interfaces.RemoveCollections) != true {
+				return "", errors.New("you do not have permission to delete collections")
+			}
+			if err := database.DBInterface.TrashCollection(Report.TargetID, ModeratorID, false); err != nil {
+				return "", errors.New("failed to delete the collection, is it already in the trash?")
+			}
+			return "The collection was deleted.", nil
+		case "tag":
+			if Permissions.HasPermission(interfaces.RemoveTags) != true {
+				return "", errors.New("you do not have permission to delete tags")
+			}
+			if err := database.DBInterface.DeleteTag(Report.TargetID); err != nil {
+				return "", errors.New("failed to delete the tag, is it still in use?")
+			}
+			return "The tag was deleted.", nil
+		}
+	case "rating":
+		if Report.TargetType != "image" {
+			return "", errors.New("only images have a rating")
+		}
+		if Permissions.HasPermission(interfaces.ModifyImageTags) != true {
+			return "", errors.New("you do not have permission to change the rating of images")
+		}
+		rating := strings.ToLower(strings.TrimSpace(Action.Rating))
+		if rating == "" {
+			return "", errors.New("a rating is required")
+		}
+		if err := database.DBInterface.SetImageRating(Report.TargetID, rating); err != nil {
+			return "", errors.New("failed to change the rating of the image")
+		}
+		return "The rating of the image was changed to " + rating + ".", nil
+	case "retag":
+		if Report.TargetType != "image" {
+			return "", errors.New("only images can be retagged")
+		}
+		if Permissions.HasPermission(interfaces.ModifyImageTags) != true {
+			return "", errors.New("you do not have permission to change the tags of images")
+		}
+		addTags, err := getExistingReportTags(Action.AddTags)
+		if err != nil {
+			return "", err
+		}
+		removeTags, err := getExistingReportTags(Action.RemoveTags)
+		if err != nil {
+			return "", err
+		}
+		if len(addTags) == 0 && len(removeTags) == 0 {
+			return "", errors.New("no tags to add or remove were given")
+		}
+		description := ""
+		if len(addTags) > 0 {
+			var tagIDs []uint64
+			var tagNames []string
+			for _, tag := range addTags {
+				tagIDs = append(tagIDs, tag.ID)
+				tagNames = append(tagNames, tag.Name)
+			}
+			if err := database.DBInterface.AddTag(tagIDs, Report.TargetID, ModeratorID); err != nil {
+				return "", errors.New("failed to add tags to the image")
+			}
+			description += "Tags added: " + strings.Join(tagNames, ", ") + "."
+		}
+		if len(removeTags) > 0 {
+			var tagNames []string
+			for _, tag := range removeTags {
+				if err := database.DBInterface.RemoveTag(tag.ID, Report.TargetID, ModeratorID); err != nil {
+					return "", errors.New("failed to remove tag " + tag.Name + " from the image")
+				}
+				tagNames = append(tagNames, tag.Name)
+			}
+			description = strings.TrimSpace(description + " Tags removed: " + strings.Join(tagNames, ", ") + ".")
+		}
+		return description, nil
+	}
+	return "", errors.New("action not recognized")
+}
+
+//getExistingReportTags returns the tags in Query, which must all exist already, as moderators retag reported images without creating tags
+func getExistingReportTags(Query string) ([]interfaces.TagInformation, error) {
+	if strings.TrimSpace(Query) == "" {
+		return nil, nil
+	}
+	queryTags, err := database.DBInterface.GetQueryTags(Query, false)
+	if err != nil {
+		return nil, errors.New("failed to get tags from input")
+	}
+	var ToReturn []interfaces.TagInformation
+	for _, tag := range queryTags {
+		if tag.IsMeta {
+			continue
+		}
+		if tag.Exists == false {
+			return nil, errors.New("tag " + tag.Name + " does not exist")
+		}
+		ToReturn = append(ToReturn, tag)
+	}
+	return ToReturn, nil
+}
@@ -0,0 +1,199 @@
+package routers
+
+import (
+	"go-image-board/config"
+	"go-image-board/database"
+	"go-image-board/interfaces"
+	"go-image-board/logging"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+//ReportPostRouter serves post requests to /report, filing a report against an image, collection or tag
+func ReportPostRouter(responseWriter http.ResponseWriter, request *http.Request) {
+	TemplateInput := getTemplateInputFromRequest(responseWriter, request)
+	if !TemplateInput.IsLoggedOn() {
+		redirectWithFlash(responseWriter, request, "/logon", "You must be logged in to report something", "LogonRequired")
+		return
+	}
+	targetID, _ := strconv.ParseUint(request.FormValue("TargetID"), 10, 32) //0 is rejected by ValidateReport
+	report := interfaces.ReportInformation{TargetType: request.FormValue("TargetType"), TargetID: targetID, ReporterID: TemplateInput.UserInformation.ID, Category: request.FormValue("Category"), Reason: request.FormValue("Reason")}
+	redirectURL := "/"
+	if interfaces.IsReportTargetType(report.TargetType) {
+		redirectURL = report.TargetLink()
+	}
+	if err := ValidateReport(&report, TemplateInput.UserPermissions); err != nil {
+		TemplateInput.HTMLMessage += template.HTML("Failed to file report, " + template.HTMLEscapeString(err.Error()) + ".<br>")
+		redirectWithFlash(responseWriter, request, redirectURL, TemplateInput.HTMLMessage, "ReportFail")
+		return
+	}
+	reportID, err := database.DBInterface.NewReport(report)
+	if err != nil {
+		TemplateInput.HTMLMessage += template.HTML("Failed to file report due to a database error.<br>")
+		redirectWithFlash(responseWriter, request, redirectURL, TemplateInput.HTMLMessage, "ReportFail")
+		return
+	}
+	go WriteAuditLog(TemplateInput.UserInformation.ID, "REPORT-CREATE", TemplateInput.UserInformation.Name+" reported "+report.TargetType+" "+strconv.FormatUint(report.TargetID, 10)+" as "+report.Category+". Report "+strconv.FormatUint(reportID, 10))
+	TemplateInput.HTMLMessage += template.HTML("Thank you, your report was sent to the moderators. You can follow it from <a href=\"/reports\">your reports</a>.<br>")
+	redirectWithFlash(responseWriter, request, redirectURL, TemplateInput.HTMLMessage, "ReportSuccess")
+}
+
+//ReportsGetRouter serves get requests to /reports, listing the reports the user filed along with their outcome
+func ReportsGetRouter(responseWriter http.ResponseWriter, request *http.Request) {
+	TemplateInput := getTemplateInputFromRequest(responseWriter, request)
+	if !TemplateInput.IsLoggedOn() {
+		redirectWithFlash(responseWriter, request, "/logon", "You must be logged in to view your reports", "LogonRequired")
+		return
+	}
+
+	//Get the page offset
+	pageStart, _ := strconv.ParseUint(request.FormValue("PageStart"), 10, 32) //Either parses fine, or is 0, both works
+	pageStride := config.Configuration.PageStride
+
+	var err error
+	TemplateInput.Reports, TemplateInput.TotalResults, err = database.DBInterface.GetUserReports(TemplateInput.UserInformation.ID, pageStart, pageStride)
+	if err != nil {
+		TemplateInput.HTMLMessage += template.HTML("Failed to get your reports.<br>")
+		logging.WriteLog(logging.LogLevelError, "reportrouter/ReportsGetRouter", TemplateInput.UserInformation.GetCompositeID(), logging.ResultFailure, []string{"Failed to get reports", err.Error()})
+	} else {
+		TemplateInput.PageMenu, _ = generatePageMenu(int64(pageStart), int64(pageStride), int64(TemplateInput.TotalResults), "", "/reports")
+	}
+
+	replyWithTemplate("reports.html", TemplateInput, responseWriter, request)
+}
+
+//ModReportsGetRouter serves get requests to /mod/reports, the inbox of reports for moderators
+func ModReportsGetRouter(responseWriter http.ResponseWriter, request *http.Request) {
+	TemplateInput := getTemplateInputFromRequest(responseWriter, request)
+	if TemplateInput.UserPermissions.HasPermission(interfaces.HandleReports) != true {
+		TemplateInput.HTMLMessage += template.HTML("You do not have permission to view reports.<br>")
+		redirectWithFlash(responseWriter, request, "/mod", TemplateInput.HTMLMessage, "ModFail")
+		return
+	}
+
+	//Get the page offset
+	pageStart, _ := strconv.ParseUint(request.FormValue("PageStart"), 10, 32) //Either parses fine, or is 0, both works
+	pageStride := config.Configuration.PageStride
+
+	//Show open reports unless another status, or all, is requested
+	TemplateInput.ReportView = interfaces.ReportOpen
+	switch request.FormValue("View") {
+	case interfaces.ReportClaimed, interfaces.ReportResolved, interfaces.ReportDismissed:
+		TemplateInput.ReportView = request.FormValue("View")
+	case "all":
+		TemplateInput.ReportView = ""
+	}
+
+	var err error
+	TemplateInput.Reports, TemplateInput.TotalResults, err = database.DBInterface.GetReports(TemplateInput.ReportView, pageStart, pageStride)
+	if err != nil {
+		TemplateInput.HTMLMessage += template.HTML("Failed to get reports.<br>")
+		logging.WriteLog(logging.LogLevelError, "reportrouter/ModReportsGetRouter", TemplateInput.UserInformation.GetCompositeID(), logging.ResultFailure, []string{"Failed to get reports", err.Error()})
+	} else {
+		view := TemplateInput.ReportView
+		if view == "" {
+			view = "all"
+		}
+		TemplateInput.PageMenu, _ = generatePageMenu(int64(pageStart), int64(pageStride), int64(TemplateInput.TotalResults), "View="+view, "/mod/reports")
+	}
+
+	replyWithTemplate("modreports.html", TemplateInput, responseWriter, request)
+}
+
+//ModReportsPostRouter serves post requests to /mod/reports, claiming, resolving or dismissing a report.
+//Resolving may also act on the object reported, and dismissing requires an outcome to tell the reporter why
+func ModReportsPostRouter(responseWriter http.ResponseWriter, request *http.Request) {
+	TemplateInput := getTemplateInputFromRequest(responseWriter, request)
+	if !TemplateInput.IsLoggedOn() {
+		redirectWithFlash(responseWriter, request, "/logon", "You must be logged in to handle reports", "LogonRequired")
+		return
+	}
+	command := request.FormValue("command")
+	if TemplateInput.UserPermissions.HasPermission(interfaces.HandleReports) != true {
+		TemplateInput.HTMLMessage += template.HTML("You do not have permission to handle reports.<br>")
+		go WriteAuditLog(TemplateInput.UserInformation.ID, "REPORT-HANDLE", TemplateInput.UserInformation.Name+" failed to "+command+" report. Insufficient permissions. "+request.FormValue("ID"))
+		redirectWithFlash(responseWriter, request, "/mod", TemplateInput.HTMLMessage, "ModFail")
+		return
+	}
+	redirectURL := "/mod/reports?View=" + url.QueryEscape(request.FormValue("View"))
+
+	requestedID, err := strconv.ParseUint(request.FormValue("ID"), 10, 32)
+	if err != nil {
+		TemplateInput.HTMLMessage += template.HTML("Failed to parse report ID.<br>")
+		redirectWithFlash(responseWriter, request, redirectURL, TemplateInput.HTMLMessage, "ModFail")
+		return
+	}
+	report, err := database.DBInterface.GetReport(requestedID)
+	if err != nil || report.IsClosed() {
+		TemplateInput.HTMLMessage += template.HTML("That report does not exist or is already closed.<br>")
+		redirectWithFlash(responseWriter, request, redirectURL, TemplateInput.HTMLMessage, "ModFail")
+		return
+	}
+	outcome, err := ValidateReportOutcome(request.FormValue("Outcome"))
+	if err != nil {
+		TemplateInput.HTMLMessage += template.HTML(template.HTMLEscapeString(err.Error()) + ".<br>")
+		redirectWithFlash(responseWriter, request, redirectURL, TemplateInput.HTMLMessage, "ModFail")
+		return
+	}
+	reportDescription := " report " + strconv.FormatUint(report.ID, 10) + " on " + report.TargetType + " " + strconv.FormatUint(report.TargetID, 10) + " by " + report.ReporterName + "."
+
+	switch command {
+	case "claim":
+		if err := database.DBInterface.UpdateReport(report.ID, interfaces.ReportClaimed, TemplateInput.UserInformation.ID, report.Outcome); err != nil {
+			TemplateInput.HTMLMessage += template.HTML("Failed to claim report.<br>")
+			redirectWithFlash(responseWriter, request, redirectURL, TemplateInput.HTMLMessage, "ModFail")
+			return
+		}
+		go WriteAuditLog(TemplateInput.UserInformation.ID, "REPORT-CLAIM", TemplateInput.UserInformation.Name+" claimed"+reportDescription)
+		TemplateInput.HTMLMessage += template.HTML("Report claimed.<br>")
+		redirectWithFlash(responseWriter, request, redirectURL, TemplateInput.HTMLMessage, "ModSuccess")
+		return
+	case "resolve":
+		action := ReportAction{Command: request.FormValue("Action"), Rating: request.FormValue("NewRating"), AddTags: request.FormValue("AddTags"), RemoveTags: request.FormValue("RemoveTags")}
+		if action.Command == "" && outcome == "" {
+			TemplateInput.HTMLMessage += template.HTML("An action or an outcome is required to resolve a report.<br>")
+			redirectWithFlash(responseWriter, request, redirectURL, TemplateInput.HTMLMessage, "ModFail")
+			return
+		}
+		actionDescription, err := ApplyReportAction(report, action, TemplateInput.UserInformation.ID, TemplateInput.UserPermissions)
+		if err != nil {
+			TemplateInput.HTMLMessage += template.HTML("Failed to resolve report, " + template.HTMLEscapeString(err.Error()) + ".<br>")
+			go WriteAuditLog(TemplateInput.UserInformation.ID, "REPORT-RESOLVE", TemplateInput.UserInformation.Name+" failed to "+action.Command+" "+report.TargetType+" while resolving"+reportDescription+" "+err.Error())
+			redirectWithFlash(responseWriter, request, redirectURL, TemplateInput.HTMLMessage, "ModFail")
+			return
+		}
+		if actionDescription != "" && outcome != "" {
+			outcome = actionDescription + " " + outcome
+		} else if actionDescription != "" {
+			outcome = actionDescription
+		}
+		if err := database.DBInterface.UpdateReport(report.ID, interfaces.ReportResolved, TemplateInput.UserInformation.ID, outcome); err != nil {
+			TemplateInput.HTMLMessage += template.HTML("Failed to resolve report.<br>")
+			redirectWithFlash(responseWriter, request, redirectURL, TemplateInput.HTMLMessage, "ModFail")
+			return
+		}
+		go WriteAuditLog(TemplateInput.UserInformation.ID, "REPORT-RESOLVE", TemplateInput.UserInformation.Name+" resolved"+reportDescription+" Outcome: "+outcome)
+		TemplateInput.HTMLMessage += template.HTML("Report resolved.<br>")
+		redirectWithFlash(responseWriter, request, redirectURL, TemplateInput.HTMLMessage, "ModSuccess")
+		return
+	case "dismiss":
+		if outcome == "" {
+			TemplateInput.HTMLMessage += template.HTML("An outcome is required to dismiss a report.<br>")
+			redirectWithFlash(responseWriter, request, redirectURL, TemplateInput.HTMLMessage, "ModFail")
+			return
+		}
+		if err := database.DBInterface.UpdateReport(report.ID, interfaces.ReportDismissed, TemplateInput.UserInformation.ID, outcome); err != nil {
+			TemplateInput.HTMLMessage += template.HTML("Failed to dismiss report.<br>")
+			redirectWithFlash(responseWriter, request, redirectURL, TemplateInput.HTMLMessage, "ModFail")
+			return
+		}
+		go WriteAuditLog(TemplateInput.UserInformation.ID, "REPORT-DISMISS", TemplateInput.UserInformation.Name+" dismissed"+reportDescription+" Outcome: "+outcome)
+		TemplateInput.HTMLMessage += template.HTML("Report dismissed.<br>")
+		redirectWithFlash(responseWriter, request, redirectURL, TemplateInput.HTMLMessage, "ModSuccess")
+		return
+	}
+	TemplateInput.HTMLMessage += template.HTML("Command not recognized or form submitted incorrectly.<br>")
+	redirectWithFlash(responseWriter, request, redirectURL, TemplateInput.HTMLMessage, "ModFail")
+}
@@ -0,0 +1,147 @@
+package routers
+
+import (
+	"go-image-board/database"
+	"go-image-board/interfaces"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReportRouters(t *testing.T) {
+	fixture := seedDatabase(t)
+	server := newTestServer(t)
+	admin := newTestClient(t, server)
+	admin.logon(t, "admin", "adminpass")
+	for userName, permissions := range map[string]uint64{"moderator": uint64(interfaces.HandleReports | interfaces.ModifyImageTags), "viewer": 0} {
+		if err := database.DBInterface.CreateUser(userName, []byte(userName+"pass"), userName+"@example.com", permissions); err != nil {
+			t.Fatal(err)
+		}
+	}
+	moderator := newTestClient(t, server)
+	moderator.logon(t, "moderator", "moderatorpass")
+	viewer := newTestClient(t, server)
+	viewer.logon(t, "viewer", "viewerpass")
+	viewerID, _ := database.DBInterface.GetUserID("viewer")
+	unusedTagID, err := database.DBInterface.NewTag("outside", "", "", fixture.AdminID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	imageID := strconv.FormatUint(fixture.Images["one"], 10)
+
+	//Reports need a known category, a reason, and something that exists
+	for _, form := range []url.Values{
+		{"TargetType": {"image"}, "TargetID": {imageID}, "Category": {"miscategorised"}, "Reason": {" "}},
+		{"TargetType": {"image"}, "TargetID": {imageID}, "Category": {"boring"}, "Reason": {"Not interesting"}},
+		{"TargetType": {"image"}, "TargetID": {"999"}, "Category": {"duplicate"}, "Reason": {"Seen it"}},
+		{"TargetType": {"user"}, "TargetID": {"1"}, "Category": {"other"}, "Reason": {"Rude"}},
+	} {
+		if response, _ := viewer.postForm(t, "/report", form); strings.Contains(response.Header.Get("Location"), "ReportFail") == false {
+			t.Errorf("report %v redirected to %q", form, response.Header.Get("Location"))
+		}
+	}
+	response, _ := viewer.postForm(t, "/report", url.Values{"TargetType": {"image"}, "TargetID": {imageID}, "Category": {"miscategorised"}, "Reason": {"This is a dog"}})
+	if location := response.Header.Get("Location"); strings.HasPrefix(location, "/image?ID="+imageID) == false || strings.Contains(location, "ReportSuccess") == false {
+		t.Fatalf("report redirected to %q", location)
+	}
+	viewer.postForm(t, "/report", url.Values{"TargetType": {"collection"}, "TargetID": {strconv.FormatUint(fixture.CollectionID, 10)}, "Category": {"other"}, "Reason": {"Not all pets"}})
+	viewer.postForm(t, "/report", url.Values{"TargetType": {"tag"}, "TargetID": {strconv.FormatUint(unusedTagID, 10)}, "Category": {"duplicate"}, "Reason": {"Same as outdoor"}})
+	reports, total, err := database.DBInterface.GetUserReports(viewerID, 0, 10)
+	if err != nil || total != 3 {
+		t.Fatalf("viewer has %d reports, %v", total, err)
+	}
+	tagReport, collectionReport, imageReport := reports[0], reports[1], reports[2]
+	if imageReport.TargetType != "image" || imageReport.Status != interfaces.ReportOpen || imageReport.ReporterName != "viewer" {
+		t.Fatalf("image report is %+v", imageReport)
+	}
+
+	//Only moderators handling reports see the inbox
+	if response, _ := viewer.get(t, "/mod/reports"); response.StatusCode != http.StatusFound {
+		t.Errorf("inbox returned %d to the viewer", response.StatusCode)
+	}
+	if response, _ := viewer.postForm(t, "/mod/reports", url.Values{"command": {"claim"}, "ID": {strconv.FormatUint(imageReport.ID, 10)}}); strings.Contains(response.Header.Get("Location"), "ModFail") == false {
+		t.Errorf("viewer claim redirected to %q", response.Header.Get("Location"))
+	}
+	if _, body := moderator.get(t, "/mod"); strings.Contains(body, "currently 3 open") == false {
+		t.Error("moderation page does not count the open reports")
+	}
+	if response, body := moderator.get(t, "/mod/reports"); response.StatusCode != http.StatusOK || strings.Contains(body, "This is a dog") == false || strings.Contains(body, "Same as outdoor") == false {
+		t.Fatalf("inbox returned %d without the reports", response.StatusCode)
+	}
+
+	//Claiming moves a report out of the open view
+	moderator.postForm(t, "/mod/reports", url.Values{"command": {"claim"}, "ID": {strconv.FormatUint(imageReport.ID, 10)}})
+	if report, _ := database.DBInterface.GetReport(imageReport.ID); report.Status != interfaces.ReportClaimed || report.ModeratorName != "moderator" {
+		t.Fatalf("claimed report is %+v", report)
+	}
+	if _, body := moderator.get(t, "/mod/reports?View=open"); strings.Contains(body, "This is a dog") {
+		t.Error("open view lists the claimed report")
+	}
+
+	//Acting on a report still needs the permission for that action
+	moderator.postForm(t, "/mod/reports", url.Values{"command": {"resolve"}, "ID": {strconv.FormatUint(imageReport.ID, 10)}, "Action": {"delete"}})
+	if imageInfo, _ := database.DBInterface.GetImage(fixture.Images["one"]); imageInfo.InTrash {
+		t.Fatal("moderator without RemoveImage deleted the image")
+	}
+	if report, _ := database.DBInterface.GetReport(imageReport.ID); report.Status != interfaces.ReportClaimed {
+		t.Fatalf("failed action changed the report to %+v", report)
+	}
+	response, _ = moderator.postForm(t, "/mod/reports", url.Values{"command": {"resolve"}, "ID": {strconv.FormatUint(imageReport.ID, 10)}, "Action": {"retag"}, "AddTags": {"dog"}, "RemoveTags": {"outdoor"}, "Outcome": {"Thanks for spotting it"}})
+	if strings.Contains(response.Header.Get("Location"), "ModSuccess") == false {
+		t.Fatalf("retag redirected to %q", response.Header.Get("Location"))
+	}
+	tags, _ := database.DBInterface.GetImageTags(fixture.Images["one"])
+	var tagNames []string
+	for _, tag := range tags {
+		tagNames = append(tagNames, tag.Name)
+	}
+	if strings.Join(tagNames, " ") != "cat dog" {
+		t.Errorf("retagged image has tags %v", tagNames)
+	}
+	if response, _ := moderator.postForm(t, "/mod/reports", url.Values{"command": {"dismiss"}, "ID": {strconv.FormatUint(imageReport.ID, 10)}, "Outcome": {"Changed my mind"}}); strings.Contains(response.Header.Get("Location"), "ModFail") == false {
+		t.Errorf("dismissing a resolved report redirected to %q", response.Header.Get("Location"))
+	}
+
+	//Dismissing requires an outcome
+	moderator.postForm(t, "/mod/reports", url.Values{"command": {"dismiss"}, "ID": {strconv.FormatUint(collectionReport.ID, 10)}})
+	if report, _ := database.DBInterface.GetReport(collectionReport.ID); report.Status != interfaces.ReportOpen {
+		t.Fatalf("dismissed without an outcome, %+v", report)
+	}
+	moderator.postForm(t, "/mod/reports", url.Values{"command": {"dismiss"}, "ID": {strconv.FormatUint(collectionReport.ID, 10)}, "Outcome": {"They are all pets"}})
+	admin.postForm(t, "/mod/reports", url.Values{"command": {"resolve"}, "ID": {strconv.FormatUint(tagReport.ID, 10)}, "Action": {"delete"}})
+	if _, err := database.DBInterface.GetTag(unusedTagID, false); err == nil {
+		t.Error("resolving the tag report did not delete the tag")
+	}
+
+	//The reporter sees every outcome
+	response, body := viewer.get(t, "/reports")
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("reports returned %d", response.StatusCode)
+	}
+	for _, expected := range []string{"Tags added: dog. Tags removed: outdoor. Thanks for spotting it", "dismissed by moderator", "They are all pets", "The tag was deleted."} {
+		if strings.Contains(body, expected) == false {
+			t.Errorf("reports page does not show %q", expected)
+		}
+	}
+
+	//Every report and decision is audited
+	expected := map[string]string{"REPORT-CREATE": "viewer reported image " + imageID, "REPORT-CLAIM": "moderator claimed report", "REPORT-RESOLVE": "admin resolved report", "REPORT-DISMISS": "Outcome: They are all pets"}
+	for deadline := time.Now().Add(2 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		logs := auditLogs(t)
+		missing := ""
+		for logType, info := range expected {
+			if strings.Contains(logs[logType], info) == false {
+				missing = logType
+			}
+		}
+		if missing == "" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%s was not audited, logs are %v", missing, logs)
+		}
+	}
+}
@@ -1,10 +1,18 @@
 package routers
 
 import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
 	"errors"
 	"go-image-board/config"
 	"go-image-board/database"
+	"go-image-board/interfaces"
 	"go-image-board/logging"
+	"go-image-board/routers/templatecache"
+	"go-image-board/storage"
+	"io"
+	"io/ioutil"
 	"net/http"
 	"os"
 	"os/exec"
@@ -46,30 +54,107 @@ func RedirectRouter(responseWriter http.ResponseWriter, request *http.Request) {
 //ResourceImageRouter handles requests to /images/{file}
 func ResourceImageRouter(responseWriter http.ResponseWriter, request *http.Request) {
 	urlVariables := mux.Vars(request)
-	http.ServeFile(responseWriter, request, path.Join(config.Configuration.ImageDirectory, urlVariables["file"]))
+	if canServeImageFile(urlVariables["file"], getTemplateInputFromRequest(responseWriter, request)) == false {
+		http.NotFound(responseWriter, request)
+		return
+	}
+	//While files are being moved between layouts, the image may still be in the other one
+	if serveStorageObject(responseWriter, request, urlVariables["file"], storage.OtherLayoutName(urlVariables["file"])) != nil {
+		http.NotFound(responseWriter, request)
+	}
 }
 
 //ThumbnailRouter handls requests to /thumbs
 func ThumbnailRouter(responseWriter http.ResponseWriter, request *http.Request) {
 	urlVariables := mux.Vars(request)
-	thumbnailPath := path.Join(config.Configuration.ImageDirectory, "thumbs"+string(filepath.Separator)+urlVariables["file"]+".png")
-	//Check if file does not exist
-	if _, err := os.Stat(thumbnailPath); err != nil {
-		switch ext := filepath.Ext(strings.ToLower(urlVariables["file"])); ext {
-		//If it does not, and it is an image, return the original image, more bandwidth but better looking site
-		case ".jpg", ".jpeg", ".bmp", ".gif", ".png", ".svg", ".webp", ".tiff", ".tif", ".jfif":
-			thumbnailPath = path.Join(config.Configuration.ImageDirectory, string(filepath.Separator)+urlVariables["file"])
-		//If a video or music file, pull up a play icon
-		case ".mpg", ".mov", ".webm", ".avi", ".mp4", ".mp3", ".ogg", ".wav":
-			thumbnailPath = path.Join(config.Configuration.HTTPRoot, "resources"+string(filepath.Separator)+"playicon.svg")
+	if canServeImageFile(urlVariables["file"], getTemplateInputFromRequest(responseWriter, request)) == false {
+		http.NotFound(responseWriter, request)
+		return
+	}
+	otherLayoutName := storage.OtherLayoutName(urlVariables["file"])
+	//Check if thumbnail does not exist
+	if serveStorageObject(responseWriter, request, storage.ThumbnailName(urlVariables["file"]), storage.ThumbnailName(otherLayoutName)) == nil {
+		return
+	}
+	switch ext := filepath.Ext(strings.ToLower(urlVariables["file"])); ext {
+	//If it does not, and it is an image, return the original image, more bandwidth but better looking site
+	case ".jpg", ".jpeg", ".bmp", ".gif", ".png", ".svg", ".webp", ".tiff", ".tif", ".jfif":
+		if serveStorageObject(responseWriter, request, urlVariables["file"], otherLayoutName) == nil {
+			return
 		}
+	//If a video or music file, pull up a play icon
+	case ".mpg", ".mov", ".webm", ".avi", ".mp4", ".mp3", ".ogg", ".wav":
+		http.ServeFile(responseWriter, request, path.Join(config.Configuration.HTTPRoot, "resources"+string(filepath.Separator)+"playicon.svg"))
+		return
 	}
 	//Final fallback, just return a no image type icon
-	if _, err := os.Stat(thumbnailPath); err != nil {
-		thumbnailPath = path.Join(config.Configuration.HTTPRoot, "resources"+string(filepath.Separator)+"noicon.svg")
+	http.ServeFile(responseWriter, request, path.Join(config.Configuration.HTTPRoot, "resources"+string(filepath.Separator)+"noicon.svg"))
+}
+
+//canServeImageFile returns true if the file at Location belongs to an image the user can see, or is a previous file of an image
+//Files of images in the trash or waiting for approval are hidden from the same users as the image page
+func canServeImageFile(Location string, TemplateInput templateInput) bool {
+	//While files are being moved between layouts, the image may still be recorded at the other one
+	for _, name := range []string{Location, storage.OtherLayoutName(Location)} {
+		imageInfo, err := database.DBInterface.GetImageByFileName(name)
+		if err == nil {
+			if imageInfo.InTrash && TemplateInput.UserPermissions.HasPermission(interfaces.RemoveImage) != true {
+				return false
+			}
+			return CanSeePendingImage(imageInfo, TemplateInput.UserInformation.ID, TemplateInput.UserPermissions)
+		}
+		if err != sql.ErrNoRows {
+			logging.WriteLog(logging.LogLevelError, "resourcesrouters/canServeImageFile", TemplateInput.UserInformation.GetCompositeID(), logging.ResultFailure, []string{"Failed to get image", name, err.Error()})
+			return false
+		}
+		if _, err := database.DBInterface.GetImageRevisionByLocation(name); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+//serveStorageObject streams the first of Names that exists in storage, supporting range requests. Nothing is written if an error is returned
+func serveStorageObject(responseWriter http.ResponseWriter, request *http.Request, Names ...string) error {
+	var err error
+	for index, name := range Names {
+		if index > 0 && name == Names[index-1] {
+			continue
+		}
+		var object interfaces.StorageObject
+		var objectInfo interfaces.StorageObjectInfo
+		object, objectInfo, err = storage.StorageInterface.Get(name)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			logging.WriteLog(logging.LogLevelError, "resourcesrouters/serveStorageObject", "0", logging.ResultFailure, []string{"Failed to open object", name, err.Error()})
+			return err
+		}
+		defer object.Close()
+		http.ServeContent(responseWriter, request, name, objectInfo.ModTime, object)
+		return nil
+	}
+	return err
+}
+
+//ImageLocation returns where a new image named Name is stored, following the UseShardedLayout setting
+func ImageLocation(Name string) string {
+	if config.Configuration.UseShardedLayout {
+		return storage.ShardedName(Name)
 	}
+	return Name
+}
 
-	http.ServeFile(responseWriter, request, thumbnailPath)
+//RemoveImageFiles removes an image and its thumbnail from storage, logging any failure
+func RemoveImageFiles(Location string) {
+	if err := storage.StorageInterface.Delete(Location); err != nil {
+		logging.WriteLog(logging.LogLevelError, "resourcesrouters/RemoveImageFiles", "0", logging.ResultFailure, []string{"Failed to delete image", Location, err.Error()})
+	}
+	//Not every image has a thumbnail
+	if err := storage.StorageInterface.Delete(storage.ThumbnailName(Location)); err != nil && os.IsNotExist(err) == false {
+		logging.WriteLog(logging.LogLevelError, "resourcesrouters/RemoveImageFiles", "0", logging.ResultFailure, []string{"Failed to delete thumbnail", Location, err.Error()})
+	}
 }
 
 //GenerateThumbnail will attempt to generate a thumbnail for the specified resource
@@ -78,11 +163,11 @@ func GenerateThumbnail(Name string) error {
 	//Each case will contain generators for that file type
 	switch ext := filepath.Ext(strings.ToLower(Name)); ext {
 	case ".jpg", ".jpeg", ".bmp", ".gif", ".png", ".webp", ".tiff", ".tif", ".jfif":
-		File, err := os.Open(path.Join(config.Configuration.ImageDirectory, Name))
-		defer File.Close()
+		File, _, err := storage.StorageInterface.Get(Name)
 		if err != nil {
 			return err
 		}
+		defer File.Close()
 		originalImage, _, err := imageorient.Decode(File)
 		if err != nil {
 			return err
@@ -100,14 +185,12 @@ func GenerateThumbnail(Name string) error {
 			newWidth = uint(float64(newWidth) * scale)
 			newHeight = uint(float64(newHeight) * scale)
 		}
-		//Open the specified file at Path
-		NewFile, err := os.OpenFile(path.Join(config.Configuration.ImageDirectory, "thumbs"+string(filepath.Separator)+Name+".png"), os.O_CREATE|os.O_RDWR, 0660)
-		defer NewFile.Close()
-		if err != nil {
+		thumbnailImage := resize.Resize(uint(newWidth), uint(newHeight), originalImage, resize.Lanczos3)
+		var thumbnailData bytes.Buffer
+		if err := png.Encode(&thumbnailData, thumbnailImage); err != nil {
 			return err
 		}
-		thumbnailImage := resize.Resize(uint(newWidth), uint(newHeight), originalImage, resize.Lanczos3)
-		return png.Encode(NewFile, thumbnailImage)
+		return storage.StorageInterface.Put(storage.ThumbnailName(Name), &thumbnailData, int64(thumbnailData.Len()))
 	case ".mpg", ".mov", ".webm", ".avi", ".mp4":
 		logging.WriteLog(logging.LogLevelDebug, "resourcesrouters/GenerateThumbnail", "0", logging.ResultInfo, []string{"Video detected", Name})
 
@@ -115,16 +198,40 @@ func GenerateThumbnail(Name string) error {
 		if !config.Configuration.UseFFMPEG {
 			return errors.New("No thumbnail method for file type")
 		}
+		//FFMPEG needs to seek around the video, so work on a local copy in case storage is remote
+		workDirectory, err := ioutil.TempDir("", "gib-thumbnail")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(workDirectory)
+		inputPath := filepath.Join(workDirectory, "input"+ext)
+		outputPath := filepath.Join(workDirectory, "thumbnail.png")
+		if err := copyObjectToFile(Name, inputPath); err != nil {
+			logging.WriteLog(logging.LogLevelError, "resourcesrouters/GenerateThumbnail", "0", logging.ResultFailure, []string{"Failed to copy video for FFMPEG", Name, err.Error()})
+			return err
+		}
 		//Spawn FFMPEG Process and save image file
 		//ffmpeg -i input.mp4 -vf  "thumbnail,scale=640:360" -frames:v 1 thumb.png
-		//Fire forget
 		sizeParam := "thumbnail,scale=" + strconv.FormatUint(uint64(config.Configuration.MaxThumbnailWidth), 10) + ":" + strconv.FormatUint(uint64(config.Configuration.MaxThumbnailHeight), 10)
-		ffmpegCMD := exec.Command(config.Configuration.FFMPEGPath, "-i", path.Join(config.Configuration.ImageDirectory, Name), "-vf", sizeParam, "-frames:v", "1", path.Join(config.Configuration.ImageDirectory, "thumbs"+string(filepath.Separator)+Name+".png"))
-		_, err := ffmpegCMD.Output()
+		ffmpegCMD := exec.Command(config.Configuration.FFMPEGPath, "-i", inputPath, "-vf", sizeParam, "-frames:v", "1", outputPath)
+		_, err = ffmpegCMD.Output()
 		if err != nil {
 			logging.WriteLog(logging.LogLevelError, "resourcesrouters/GenerateThumbnail", "0", logging.ResultFailure, []string{"Failed to use FFMPEG", Name, err.Error()})
 			return err
 		}
+		thumbnailFile, err := os.Open(outputPath)
+		if err != nil {
+			return err
+		}
+		defer thumbnailFile.Close()
+		thumbnailInfo, err := thumbnailFile.Stat()
+		if err != nil {
+			return err
+		}
+		if err := storage.StorageInterface.Put(storage.ThumbnailName(Name), thumbnailFile, thumbnailInfo.Size()); err != nil {
+			logging.WriteLog(logging.LogLevelError, "resourcesrouters/GenerateThumbnail", "0", logging.ResultFailure, []string{"Failed to store FFMPEG output", Name, err.Error()})
+			return err
+		}
 		logging.WriteLog(logging.LogLevelInfo, "resourcesrouters/GenerateThumbnail", "0", logging.ResultInfo, []string{"FFMPEG output success", Name})
 		return nil
 	default:
@@ -132,17 +239,35 @@ func GenerateThumbnail(Name string) error {
 	}
 }
 
+//copyObjectToFile copies Name from storage to a new file at FilePath
+func copyObjectToFile(Name string, FilePath string) error {
+	object, _, err := storage.StorageInterface.Get(Name)
+	if err != nil {
+		return err
+	}
+	defer object.Close()
+	file, err := os.OpenFile(FilePath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(file, object); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
 //GeneratedHash will attempt to generate a dHash for the given image
 func GeneratedHash(Name string, ImageID uint64) error {
 	//Switch on extension
 	switch ext := filepath.Ext(strings.ToLower(Name)); ext {
 	case ".jpg", ".jpeg", ".bmp", ".gif", ".png", ".webp", ".tiff", ".tif", ".jfif":
 		//Load image
-		File, err := os.Open(path.Join(config.Configuration.ImageDirectory, Name))
-		defer File.Close()
+		File, _, err := storage.StorageInterface.Get(Name)
 		if err != nil {
 			return err
 		}
+		defer File.Close()
 		originalImage, _, err := imageorient.Decode(File)
 		if err != nil {
 			return err
@@ -189,3 +314,107 @@ func GeneratedHash(Name string, ImageID uint64) error {
 		return errors.New("Cannot process image of this type")
 	}
 }
+
+//GenerateMetadata will attempt to read the dimensions, file size, MIME type and duration of the given image, and store them
+func GenerateMetadata(Name string, ImageID uint64) error {
+	metadata, err := ReadMetadata(Name)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelWarning, "resourcesrouters/GenerateMetadata", "0", logging.ResultFailure, []string{"Failed to read metadata", Name, err.Error()})
+		//Whatever was read before the failure is still worth keeping
+		if metadata.MIMEType == "" {
+			return err
+		}
+	}
+	return database.DBInterface.SetImageMetadata(ImageID, metadata)
+}
+
+//ReadMetadata reads the dimensions, file size, MIME type and duration of the given file
+//Images are decoded, videos and audio are read with FFProbe when UseFFMPEG is set. On error, anything read so far is still returned
+func ReadMetadata(Name string) (interfaces.ImageMetadata, error) {
+	File, FileInfo, err := storage.StorageInterface.Get(Name)
+	if err != nil {
+		return interfaces.ImageMetadata{}, err
+	}
+	defer File.Close()
+	ToReturn := interfaces.ImageMetadata{FileSize: FileInfo.Size}
+	//The content decides the type where it can, as extensions are not always right
+	header := make([]byte, 512)
+	headerLength, err := io.ReadFull(File, header)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return ToReturn, err
+	}
+	header = header[:headerLength]
+	ext := filepath.Ext(strings.ToLower(Name))
+	ToReturn.MIMEType = strings.Split(http.DetectContentType(header), ";")[0]
+	switch strings.Split(ToReturn.MIMEType, "/")[0] {
+	case "image", "video", "audio":
+	default:
+		ToReturn.MIMEType = templatecache.GetMIME(ext, "application/octet-stream")
+	}
+
+	switch ext {
+	case ".jpg", ".jpeg", ".bmp", ".gif", ".png", ".webp", ".tiff", ".tif", ".jfif":
+		//Orientation is applied, so the dimensions match the image as displayed
+		imageConfig, _, err := imageorient.DecodeConfig(io.MultiReader(bytes.NewReader(header), File))
+		if err != nil {
+			return ToReturn, err
+		}
+		ToReturn.Width = int64(imageConfig.Width)
+		ToReturn.Height = int64(imageConfig.Height)
+	case ".mpg", ".mov", ".webm", ".avi", ".mp4", ".mp3", ".ogg", ".wav":
+		if !config.Configuration.UseFFMPEG {
+			return ToReturn, nil
+		}
+		return ToReturn, probeMedia(Name, ext, &ToReturn)
+	}
+	return ToReturn, nil
+}
+
+//ffprobeOutput is the part of FFProbe's json output read by probeMedia
+type ffprobeOutput struct {
+	Streams []struct {
+		CodecType string `json:"codec_type"`
+		Width     int64  `json:"width"`
+		Height    int64  `json:"height"`
+	} `json:"streams"`
+	Format struct {
+		Duration string `json:"duration"`
+	} `json:"format"`
+}
+
+//probeMedia fills in the dimensions and duration of a video or audio file using FFProbe
+func probeMedia(Name string, Extension string, Metadata *interfaces.ImageMetadata) error {
+	//FFProbe may need to seek around the file, so work on a local copy in case storage is remote
+	workDirectory, err := ioutil.TempDir("", "gib-metadata")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(workDirectory)
+	inputPath := filepath.Join(workDirectory, "input"+Extension)
+	if err := copyObjectToFile(Name, inputPath); err != nil {
+		return err
+	}
+	//ffprobe -v error -show_entries stream=codec_type,width,height:format=duration -of json input.mp4
+	ffprobeCMD := exec.Command(config.Configuration.FFProbePath, "-v", "error", "-show_entries", "stream=codec_type,width,height:format=duration", "-of", "json", inputPath)
+	output, err := ffprobeCMD.Output()
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "resourcesrouters/probeMedia", "0", logging.ResultFailure, []string{"Failed to use FFProbe", Name, err.Error()})
+		return err
+	}
+	var probed ffprobeOutput
+	if err := json.Unmarshal(output, &probed); err != nil {
+		return err
+	}
+	//Cover art in audio files shows up as a video stream, so only videos take their dimensions from one
+	for _, stream := range probed.Streams {
+		if stream.CodecType == "video" && strings.HasPrefix(Metadata.MIMEType, "audio/") == false {
+			Metadata.Width = stream.Width
+			Metadata.Height = stream.Height
+			break
+		}
+	}
+	if duration, err := strconv.ParseFloat(probed.Format.Duration, 64); err == nil {
+		Metadata.Duration = duration
+	}
+	return nil
+}
@@ -0,0 +1,240 @@
+package routers
+
+import (
+	"bytes"
+	"go-image-board/config"
+	"go-image-board/database"
+	"go-image-board/storage"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+)
+
+// putTestObject stores Data as Name, removing it when the test ends
+func putTestObject(t *testing.T, Name string, Data []byte) {
+	t.Helper()
+	if err := storage.StorageInterface.Put(Name, bytes.NewReader(Data), int64(len(Data))); err != nil {
+		t.Fatalf("Put %s: %v", Name, err)
+	}
+	t.Cleanup(func() { storage.StorageInterface.Delete(Name) })
+}
+
+// addTestImage records an image stored at Location, uploaded by UploaderID
+func addTestImage(t *testing.T, UploaderID uint64, Location string, Pending bool) uint64 {
+	t.Helper()
+	imageID, err := database.DBInterface.NewImage(Location, Location, UploaderID, "", Pending)
+	if err != nil {
+		t.Fatalf("NewImage %s: %v", Location, err)
+	}
+	return imageID
+}
+
+// testPNG returns an encoded png of the given size
+func testPNG(t *testing.T, Width int, Height int) []byte {
+	t.Helper()
+	picture := image.NewRGBA(image.Rect(0, 0, Width, Height))
+	for x := 0; x < Width; x++ {
+		for y := 0; y < Height; y++ {
+			picture.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	var data bytes.Buffer
+	if err := png.Encode(&data, picture); err != nil {
+		t.Fatal(err)
+	}
+	return data.Bytes()
+}
+
+func TestResourceImageRouterServesRanges(t *testing.T) {
+	fixture := seedDatabase(t)
+	client := newTestClient(t, newTestServer(t))
+	data := []byte(strings.Repeat("0123456789", 100))
+	putTestObject(t, "stream.mp4", data)
+	addTestImage(t, fixture.AdminID, "stream.mp4", false)
+
+	request, _ := http.NewRequest(http.MethodGet, client.server.URL+"/images/stream.mp4", nil)
+	request.Header.Set("Range", "bytes=10-19")
+	response, err := client.client.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := readBody(t, response)
+	if response.StatusCode != http.StatusPartialContent || body != "0123456789" {
+		t.Errorf("range returned %d %q", response.StatusCode, body)
+	}
+	if contentType := response.Header.Get("Content-Type"); contentType != "video/mp4" {
+		t.Errorf("Content-Type = %q, want video/mp4", contentType)
+	}
+
+	if response, _ := client.get(t, "/images/missing.png"); response.StatusCode != http.StatusNotFound {
+		t.Errorf("missing image returned %d, want 404", response.StatusCode)
+	}
+	//Files without an image are not served, even when they are in storage
+	putTestObject(t, "unknown.png", []byte("unknown"))
+	if response, _ := client.get(t, "/images/unknown.png"); response.StatusCode != http.StatusNotFound {
+		t.Errorf("file without an image returned %d, want 404", response.StatusCode)
+	}
+}
+
+func TestThumbnailRouterFallsBack(t *testing.T) {
+	fixture := seedDatabase(t)
+	client := newTestClient(t, newTestServer(t))
+	putTestObject(t, "withthumb.png", []byte("original"))
+	putTestObject(t, storage.ThumbnailName("withthumb.png"), []byte("thumbnail"))
+	putTestObject(t, "nothumb.png", []byte("original"))
+	for _, location := range []string{"withthumb.png", "nothumb.png", "missing.mp4", "missing.txt"} {
+		addTestImage(t, fixture.AdminID, location, false)
+	}
+
+	for path, expected := range map[string]string{
+		"/thumbs/withthumb.png": "thumbnail",
+		"/thumbs/nothumb.png":   "original",
+		"/thumbs/missing.mp4":   "<svg",
+		"/thumbs/missing.txt":   "<svg",
+	} {
+		response, body := client.get(t, path)
+		if response.StatusCode != http.StatusOK || strings.Contains(body, expected) == false {
+			t.Errorf("%s returned %d without %q", path, response.StatusCode, expected)
+		}
+	}
+}
+
+func TestImageFilesFollowImageVisibility(t *testing.T) {
+	fixture := seedDatabase(t)
+	server := newTestServer(t)
+	for _, name := range []string{"pending.png", "trashed.png", "replaced.png", "replacement.png"} {
+		putTestObject(t, name, []byte(name))
+		putTestObject(t, storage.ThumbnailName(name), []byte("thumbnail"))
+	}
+	addTestImage(t, fixture.AdminID, "pending.png", true)
+	trashedID := addTestImage(t, fixture.AdminID, "trashed.png", false)
+	if err := database.DBInterface.TrashImage(trashedID, fixture.AdminID); err != nil {
+		t.Fatal(err)
+	}
+	replacedID := addTestImage(t, fixture.AdminID, "replaced.png", false)
+	if err := database.DBInterface.ReplaceImageFile(replacedID, "replacement.png", fixture.AdminID); err != nil {
+		t.Fatal(err)
+	}
+
+	//Earlier files of an image are linked from its history, so they are served like the image
+	anonymous := newTestClient(t, server)
+	for path, expected := range map[string]int{
+		"/images/pending.png":     http.StatusNotFound,
+		"/thumbs/pending.png":     http.StatusNotFound,
+		"/images/trashed.png":     http.StatusNotFound,
+		"/thumbs/trashed.png":     http.StatusNotFound,
+		"/images/replaced.png":    http.StatusOK,
+		"/thumbs/replaced.png":    http.StatusOK,
+		"/images/replacement.png": http.StatusOK,
+	} {
+		if response, _ := anonymous.get(t, path); response.StatusCode != expected {
+			t.Errorf("%s returned %d to a guest, want %d", path, response.StatusCode, expected)
+		}
+	}
+
+	//The uploader, moderators and those who can restore images still see them
+	admin := newTestClient(t, server)
+	admin.logon(t, "admin", "adminpass")
+	for _, path := range []string{"/images/pending.png", "/thumbs/pending.png", "/images/trashed.png", "/thumbs/trashed.png"} {
+		if response, _ := admin.get(t, path); response.StatusCode != http.StatusOK {
+			t.Errorf("%s returned %d to the admin", path, response.StatusCode)
+		}
+	}
+}
+
+func TestGenerateThumbnailStoresThumbnail(t *testing.T) {
+	config.Configuration.MaxThumbnailWidth = 402
+	config.Configuration.MaxThumbnailHeight = 258
+	putTestObject(t, "large.png", testPNG(t, 804, 200))
+	t.Cleanup(func() { storage.StorageInterface.Delete(storage.ThumbnailName("large.png")) })
+	if err := GenerateThumbnail("large.png"); err != nil {
+		t.Fatal(err)
+	}
+	thumbnail, _, err := storage.StorageInterface.Get(storage.ThumbnailName("large.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer thumbnail.Close()
+	thumbnailConfig, err := png.DecodeConfig(thumbnail)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if thumbnailConfig.Width != 402 || thumbnailConfig.Height != 100 {
+		t.Errorf("thumbnail is %dx%d, want 402x100", thumbnailConfig.Width, thumbnailConfig.Height)
+	}
+
+	RemoveImageFiles("large.png")
+	for _, name := range []string{"large.png", storage.ThumbnailName("large.png")} {
+		if _, err := storage.StorageInterface.Stat(name); os.IsNotExist(err) == false {
+			t.Errorf("%s still exists after RemoveImageFiles", name)
+		}
+	}
+}
+
+func TestGenerateMetadataStoresMetadata(t *testing.T) {
+	fixture := seedDatabase(t)
+	data := testPNG(t, 64, 36)
+	//Named as a jpg, the content decides the type
+	putTestObject(t, "metadata.jpg", data)
+	if err := GenerateMetadata("metadata.jpg", fixture.Images["one"]); err != nil {
+		t.Fatal(err)
+	}
+	imageInfo, err := database.DBInterface.GetImage(fixture.Images["one"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imageInfo.Width != 64 || imageInfo.Height != 36 || imageInfo.FileSize != int64(len(data)) || imageInfo.MIMEType != "image/png" || imageInfo.Duration != 0 {
+		t.Errorf("unexpected metadata %dx%d, %d bytes, %q, %v seconds", imageInfo.Width, imageInfo.Height, imageInfo.FileSize, imageInfo.MIMEType, imageInfo.Duration)
+	}
+
+	//Without FFMPEG, which tests do not enable, videos only get a size and type, falling back to the extension when the content is not recognised
+	putTestObject(t, "metadata.mov", []byte("not really a video"))
+	metadata, err := ReadMetadata("metadata.mov")
+	if err != nil || metadata.MIMEType != "video/quicktime" || metadata.FileSize != 18 || metadata.Width != 0 {
+		t.Errorf("ReadMetadata returned %+v, %v", metadata, err)
+	}
+	if err := GenerateMetadata("missing.png", fixture.Images["two"]); err == nil {
+		t.Error("metadata was generated for a missing file")
+	}
+}
+
+func TestRoutersResolveBothLayouts(t *testing.T) {
+	fixture := seedDatabase(t)
+	client := newTestClient(t, newTestServer(t))
+	putTestObject(t, "abcdflat.png", []byte("flat"))
+	putTestObject(t, storage.ShardedName("abcdsharded.png"), []byte("sharded"))
+	putTestObject(t, storage.ThumbnailName(storage.ShardedName("abcdsharded.png")), []byte("thumbnail"))
+	addTestImage(t, fixture.AdminID, "abcdflat.png", false)
+	addTestImage(t, fixture.AdminID, storage.ShardedName("abcdsharded.png"), false)
+
+	//Links are either to the stored location or the old flat name, both must work while files are being moved
+	for path, expected := range map[string]string{
+		"/images/abcdflat.png":          "flat",
+		"/images/ab/cd/abcdflat.png":    "flat",
+		"/images/abcdsharded.png":       "sharded",
+		"/images/ab/cd/abcdsharded.png": "sharded",
+		"/thumbs/abcdsharded.png":       "thumbnail",
+		"/thumbs/ab/cd/abcdsharded.png": "thumbnail",
+		"/thumbs/ab/cd/abcdflat.png":    "flat",
+	} {
+		response, body := client.get(t, path)
+		if response.StatusCode != http.StatusOK || body != expected {
+			t.Errorf("%s returned %d %q, want %q", path, response.StatusCode, body, expected)
+		}
+	}
+}
+
+func TestImageLocationFollowsLayout(t *testing.T) {
+	defer func() { config.Configuration.UseShardedLayout = false }()
+	if location := ImageLocation("abcdef.png"); location != "abcdef.png" {
+		t.Errorf("flat ImageLocation = %q", location)
+	}
+	config.Configuration.UseShardedLayout = true
+	if location := ImageLocation("abcdef.png"); location != "ab/cd/abcdef.png" {
+		t.Errorf("sharded ImageLocation = %q", location)
+	}
+}
@@ -59,6 +59,34 @@ type templateInput struct {
 	RequestTime int64
 	//ModUserData contains information for the modUser page
 	ModUserData interfaces.UserInformation
+	//TrashView is either images or collections, for the trash page
+	TrashView string
+	//ImageRevisions lists the previous files of the image in a single image view
+	ImageRevisions []interfaces.ImageRevisionInformation
+	//TagHistory lists the tag changes made to the image in a single image view, newest first
+	TagHistory []interfaces.TagChangeInformation
+	//TagImplications lists the rules where the tag in a single tag view implies, or is implied by, another tag
+	TagImplications []interfaces.TagImplicationInformation
+	//PinnedSearches lists the saved searches the user pinned to the header menu, filled for every page
+	PinnedSearches []interfaces.SavedSearchInformation
+	//SavedSearches lists every saved search of the user on the account page
+	SavedSearches []interfaces.SavedSearchInformation
+	//Comments lists a page of the threads on the image or collection in a single view, in the order they are shown
+	Comments []interfaces.CommentInformation
+	//CommentPageMenu links to the other pages of Comments, empty when they fit on one page
+	CommentPageMenu template.HTML
+	//Notes lists the notes placed on the image, oldest first
+	Notes []interfaces.NoteInformation
+	//NoteRevisions lists every version of the notes on the image, newest first, for users that may edit notes
+	NoteRevisions []interfaces.NoteRevisionInformation
+	//Reports lists a page of reports, either the moderator inbox or the reports filed by the user
+	Reports []interfaces.ReportInformation
+	//ReportView is the status the moderator inbox is filtered by, blank for every report
+	ReportView string
+	//Ban is the ban that stopped the user's session, its message is shown on every page while it lasts
+	Ban interfaces.BanInformation
+	//Bans lists every active ban on the moderator bans page
+	Bans []interfaces.BanInformation
 }
 
 func (ti templateInput) IsLoggedOn() bool {
@@ -74,6 +102,13 @@ func replyWithTemplate(templateName string, templateInputInterface interface{},
 	if ti, ok := templateInputInterface.(templateInput); ok {
 		ti.RequestTime = time.Now().Sub(ti.RequestStart).Nanoseconds() / 1000000 //Nanosecond to Millisecond
 		applyFlash(responseWriter, request, &ti)                                 //Apply any pending flash cookies
+		if ti.IsLoggedOn() {
+			pinnedSearches, err := GetSavedSearchesWithNewCounts(ti.UserInformation.ID, true)
+			if err != nil {
+				logging.WriteLog(logging.LogLevelWarning, "routertemplate/replyWithTemplate", ti.UserInformation.GetCompositeID(), logging.ResultFailure, []string{"Failed to get pinned searches", err.Error()})
+			}
+			ti.PinnedSearches = pinnedSearches
+		}
 		templateInputInterface = ti
 	}
 	err := templateToUse.ExecuteTemplate(responseWriter, templateName, templateInputInterface)
@@ -86,17 +121,27 @@ func replyWithTemplate(templateName string, templateInputInterface interface{},
 
 //ValidateUserLogon Returns either the UserID,Name,Token or 0,"",""
 func ValidateUserLogon(request *http.Request) (uint64, string, string) {
+	userID, userName, tokenID, _ := ValidateUserSession(request)
+	return userID, userName, tokenID
+}
+
+//ValidateUserSession is ValidateUserLogon, also returning why the session is not valid, which is an interfaces.BanError if the user is banned
+func ValidateUserSession(request *http.Request) (uint64, string, string, error) {
 	//Verify user is logged in by validating token
-	userNameT, tokenIDT, _ := getSessionInformation(request) //This bit actually validates, returns "","" otherwise
+	userNameT, tokenIDT, _, err := validateSession(request) //This bit actually validates, returns "","" otherwise
+	if err != nil {
+		return 0, "", "", err
+	}
 	if tokenIDT != "" && userNameT != "" {
 		//Translate UserID
 		userID, err := database.DBInterface.GetUserID(userNameT)
 		if err == nil {
-			return userID, userNameT, tokenIDT
+			return userID, userNameT, tokenIDT, nil
 		}
-		logging.WriteLog(logging.LogLevelWarning, "routertemplate/ValidateUserLogon", userNameT, logging.ResultFailure, []string{"Failed to get UserID: ", err.Error()})
+		logging.WriteLog(logging.LogLevelWarning, "routertemplate/ValidateUserSession", userNameT, logging.ResultFailure, []string{"Failed to get UserID: ", err.Error()})
+		return 0, "", "", err
 	}
-	return 0, "", ""
+	return 0, "", "", nil
 }
 
 //getNewTemplateInput helper function initiliazes a new templateInput with common information
@@ -111,7 +156,10 @@ func getNewTemplateInput(responseWriter http.ResponseWriter, request *http.Reque
 		UserInformation:       interfaces.UserInformation{}}
 
 	//Verify user is logged in by validating token
-	userNameT, tokenIDT, session := getSessionInformation(request)
+	userNameT, tokenIDT, session, sessionErr := validateSession(request)
+	if banErr, isBanned := sessionErr.(interfaces.BanError); isBanned {
+		TemplateInput.Ban = banErr.Ban
+	}
 	if tokenIDT != "" && userNameT != "" {
 		permissions, _ := database.DBInterface.GetUserPermissionSet(userNameT)
 		TemplateInput.UserPermissions = interfaces.UserPermission(permissions)
@@ -0,0 +1,94 @@
+package routers
+
+import (
+	"database/sql"
+	"errors"
+	"go-image-board/database"
+	"go-image-board/interfaces"
+	"go-image-board/plugins/tagquery"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//ValidateSavedSearch trims a saved search's name and query, then ensures the name is usable and the query can be searched
+func ValidateSavedSearch(Search *interfaces.SavedSearchInformation) error {
+	Search.Name = strings.TrimSpace(Search.Name)
+	Search.Query = strings.TrimSpace(Search.Query)
+	if Search.Name == "" || len(Search.Name) > 255 {
+		return errors.New("the name must be between 1 and 255 characters")
+	}
+	if Search.Query == "" {
+		return errors.New("the query cannot be empty")
+	}
+	if _, err := database.DBInterface.GetQueryTags(strings.ToLower(Search.Query), Search.CollectionContext); err != nil {
+		if queryErr, isQueryError := err.(interfaces.QueryError); isQueryError {
+			return queryErr
+		}
+		return errors.New("the query could not be parsed")
+	}
+	return nil
+}
+
+//OpenSavedSearch returns a saved search belonging to UserID, and records that it was opened so new results are counted from now
+func OpenSavedSearch(RequestedID string, UserID uint64) (interfaces.SavedSearchInformation, error) {
+	parsedID, err := strconv.ParseUint(RequestedID, 10, 64)
+	if err != nil {
+		return interfaces.SavedSearchInformation{}, errors.New("SavedSearch could not be parsed into a number")
+	}
+	search, err := database.DBInterface.GetSavedSearch(parsedID)
+	if err == nil && search.UserID != UserID {
+		//Saved searches are private, so one belonging to someone else does not exist as far as this user knows
+		err = sql.ErrNoRows
+	}
+	if err != nil {
+		return search, err
+	}
+	return search, database.DBInterface.SetSavedSearchViewed(search.ID)
+}
+
+//GetSavedSearchesWithNewCounts returns a user's saved searches, or only those pinned to the header menu, with NewCount filled where ShowNewCount is set
+func GetSavedSearchesWithNewCounts(UserID uint64, PinnedOnly bool) ([]interfaces.SavedSearchInformation, error) {
+	searches, err := database.DBInterface.GetSavedSearches(UserID)
+	if err != nil {
+		return nil, err
+	}
+	var ToReturn []interfaces.SavedSearchInformation
+	for _, search := range searches {
+		if PinnedOnly && search.Pinned == false {
+			continue
+		}
+		if search.ShowNewCount {
+			//A query that stopped working, such as a wildcard that now matches too many tags, just has no count
+			search.NewCount, _ = CountSavedSearchNewResults(search)
+		}
+		ToReturn = append(ToReturn, search)
+	}
+	return ToReturn, nil
+}
+
+//CountSavedSearchNewResults counts the results of a saved search uploaded after it was last opened, with the user's global filter applied as it would be when opened
+func CountSavedSearchNewResults(Search interfaces.SavedSearchInformation) (uint64, error) {
+	user, err := database.DBInterface.GetUser(Search.UserID)
+	if err != nil {
+		return 0, err
+	}
+	queryTags, err := database.DBInterface.GetQueryTags(tagquery.ResolveCurrentUser(strings.ToLower(Search.Query), user.Name), Search.CollectionContext)
+	if err != nil {
+		return 0, err
+	}
+	userFilterTags, err := database.DBInterface.GetUserFilterTags(Search.UserID, Search.CollectionContext)
+	if err != nil {
+		return 0, err
+	}
+	queryTags = interfaces.RemoveDuplicateTags(append(queryTags, userFilterTags...))
+	//Databases keep upload times to the second, so only count from the next second, or results uploaded just before opening it would count as new
+	since := Search.LastViewed.Truncate(time.Second).Add(time.Second)
+	queryTags = append(queryTags, interfaces.TagInformation{Name: "Uploaded", Exists: true, IsMeta: true, IsComplexMeta: true, Comparator: ">=", MetaValue: interfaces.TimeRange{Start: since, End: since}})
+	if Search.CollectionContext {
+		_, count, err := database.DBInterface.SearchCollections(queryTags, 0, 1)
+		return count, err
+	}
+	_, count, err := database.DBInterface.SearchImages(queryTags, 0, 1)
+	return count, err
+}
@@ -0,0 +1,34 @@
+package routers
+
+import (
+	"errors"
+	"go-image-board/database"
+	"time"
+)
+
+//RevertImageTags returns an image to the tags it had right after the change ChangeID, by undoing every later change. Returns how many were undone
+func RevertImageTags(ImageID uint64, ChangeID uint64, UserID uint64) (uint64, error) {
+	history, err := database.DBInterface.GetImageTagHistory(ImageID)
+	if err != nil {
+		return 0, err
+	}
+	//History is newest first, which is also the order to undo it in
+	for index, change := range history {
+		if change.ID == ChangeID {
+			return database.DBInterface.UndoTagChanges(history[:index], UserID)
+		}
+	}
+	return 0, errors.New("no such change to the tags of this image")
+}
+
+//UndoUserTagChanges undoes every tag change TargetUserID made between Since and Until. Returns how many were undone
+func UndoUserTagChanges(TargetUserID uint64, Since time.Time, Until time.Time, UserID uint64) (uint64, error) {
+	if Until.Before(Since) {
+		return 0, errors.New("the end of the time window is before its start")
+	}
+	changes, err := database.DBInterface.GetUserTagHistory(TargetUserID, Since, Until)
+	if err != nil {
+		return 0, err
+	}
+	return database.DBInterface.UndoTagChanges(changes, UserID)
+}
@@ -0,0 +1,98 @@
+package routers
+
+import (
+	"go-image-board/database"
+	"go-image-board/interfaces"
+	"sort"
+	"testing"
+	"time"
+)
+
+//imageTagNames returns the sorted names of an image's tags
+func imageTagNames(t *testing.T, ImageID uint64) []string {
+	t.Helper()
+	tags, err := database.DBInterface.GetImageTags(ImageID)
+	if err != nil {
+		t.Fatalf("GetImageTags: %v", err)
+	}
+	var names []string
+	for _, tag := range tags {
+		names = append(names, tag.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestUndoUserTagChanges(t *testing.T) {
+	fixture := seedDatabase(t)
+	db := database.DBInterface
+	if err := db.CreateUser("vandal", []byte("vandalpass"), "vandal@example.com", 0); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	vandalID, _ := db.GetUserID("vandal")
+	since := time.Now()
+
+	//Every kind of change, then a later change by someone else that the undo should leave alone
+	if err := db.BulkAddTag(fixture.Tags["outdoor"], fixture.Tags["cat"], vandalID); err != nil {
+		t.Fatalf("BulkAddTag: %v", err)
+	}
+	if err := db.RemoveTag(fixture.Tags["cat"], fixture.Images["one"], vandalID); err != nil {
+		t.Fatalf("RemoveTag: %v", err)
+	}
+	if err := db.ReplaceImageTags(fixture.Tags["dog"], fixture.Tags["cat"], vandalID); err != nil {
+		t.Fatalf("ReplaceImageTags: %v", err)
+	}
+	if err := db.AddTag([]uint64{fixture.Tags["dog"]}, fixture.Images["two"], fixture.AdminID); err != nil {
+		t.Fatalf("AddTag: %v", err)
+	}
+
+	vandalChanges, err := db.GetUserTagHistory(vandalID, since, time.Now())
+	if err != nil || len(vandalChanges) != 5 {
+		t.Fatalf("expected 5 changes by vandal, got %+v, %v", vandalChanges, err)
+	}
+	if _, err := UndoUserTagChanges(vandalID, time.Now(), since, fixture.AdminID); err == nil {
+		t.Errorf("expected an error for a window that ends before it starts")
+	}
+	undone, err := UndoUserTagChanges(vandalID, since, time.Now(), fixture.AdminID)
+	if err != nil {
+		t.Fatalf("UndoUserTagChanges: %v", err)
+	}
+	//dog was already added back to two by admin
+	if undone != 4 {
+		t.Errorf("expected 4 changes undone, got %d", undone)
+	}
+	expected := map[string][]string{"one": {"cat", "outdoor"}, "two": {"dog", "outdoor"}, "three": {"cat", "dog"}}
+	for name, tags := range expected {
+		if actual := imageTagNames(t, fixture.Images[name]); sliceContains(actual, tags[0]) == false || sliceContains(actual, tags[1]) == false || len(actual) != 2 {
+			t.Errorf("expected %s to have %v, got %v", name, tags, actual)
+		}
+	}
+
+	history, _ := db.GetImageTagHistory(fixture.Images["three"])
+	if len(history) == 0 || history[0].Operation != interfaces.TagChangeRevert || history[0].UserName != "admin" {
+		t.Errorf("expected the undo to be recorded in the image's history, got %+v", history)
+	}
+}
+
+func TestRevertImageTags(t *testing.T) {
+	fixture := seedDatabase(t)
+	db := database.DBInterface
+	imageID := fixture.Images["one"]
+	original, _ := db.GetImageTagHistory(imageID)
+	if len(original) != 2 || original[0].Added == false || original[0].Operation != interfaces.TagChangeAdd || original[0].UserName != "admin" {
+		t.Fatalf("expected the two tags added at upload in the history, got %+v", original)
+	}
+
+	db.RemoveTag(fixture.Tags["outdoor"], imageID, fixture.AdminID)
+	db.AddTag([]uint64{fixture.Tags["dog"]}, imageID, fixture.AdminID)
+	undone, err := RevertImageTags(imageID, original[0].ID, fixture.AdminID)
+	if err != nil || undone != 2 {
+		t.Fatalf("expected 2 changes undone, got %d, %v", undone, err)
+	}
+	if actual := imageTagNames(t, imageID); len(actual) != 2 || actual[0] != "cat" || actual[1] != "outdoor" {
+		t.Errorf("expected cat and outdoor after revert, got %v", actual)
+	}
+	if _, err := RevertImageTags(fixture.Images["two"], original[0].ID, fixture.AdminID); err == nil {
+		t.Errorf("expected an error reverting to a change of another image")
+	}
+}
@@ -0,0 +1,26 @@
+package routers
+
+import (
+	"errors"
+	"go-image-board/database"
+	"go-image-board/interfaces"
+)
+
+//AddTagImplicationByName adds a rule that TagID implies the tag named ImpliedTagName, returns the implied tag's ID
+func AddTagImplicationByName(TagID uint64, ImpliedTagName string, CreatorID uint64) (uint64, error) {
+	impliedTags, err := database.DBInterface.GetQueryTags(ImpliedTagName, false)
+	if err != nil {
+		return 0, err
+	}
+	//An alias is returned along with the tag it aliases, which is the one to imply
+	var nonAliases []interfaces.TagInformation
+	for _, tag := range impliedTags {
+		if tag.IsAlias == false {
+			nonAliases = append(nonAliases, tag)
+		}
+	}
+	if len(nonAliases) != 1 || nonAliases[0].Exists == false || nonAliases[0].IsMeta || nonAliases[0].Exclude {
+		return 0, errors.New("the implied tag must be a single tag that already exists")
+	}
+	return nonAliases[0].ID, database.DBInterface.AddTagImplication(TagID, nonAliases[0].ID, CreatorID)
+}
@@ -0,0 +1,94 @@
+package routers
+
+import (
+	"go-image-board/database"
+	"go-image-board/interfaces"
+	"strings"
+	"testing"
+)
+
+func TestAddTagImplicationByName(t *testing.T) {
+	fixture := seedDatabase(t)
+	db := database.DBInterface
+	animalID, err := db.NewTag("animal", "", "", fixture.AdminID)
+	if err != nil {
+		t.Fatalf("NewTag: %v", err)
+	}
+	if _, err := db.NewTag("maine_coon", "", "", fixture.AdminID); err != nil {
+		t.Fatalf("NewTag: %v", err)
+	}
+
+	//maine_coon -> cat -> animal, and dog -> animal
+	for _, rule := range [][2]string{{"cat", "animal"}, {"dog", "animal"}} {
+		if _, err := AddTagImplicationByName(fixture.Tags[rule[0]], rule[1], fixture.AdminID); err != nil {
+			t.Fatalf("AddTagImplicationByName %s -> %s: %v", rule[0], rule[1], err)
+		}
+	}
+	maineCoon, _ := db.GetTagByName("maine_coon")
+	if impliedID, err := AddTagImplicationByName(maineCoon.ID, "cat", fixture.AdminID); err != nil || impliedID != fixture.Tags["cat"] {
+		t.Fatalf("AddTagImplicationByName maine_coon -> cat returned %d, %v", impliedID, err)
+	}
+
+	//Rules that are refused
+	refused := map[string][2]uint64{
+		"self":      {animalID, animalID},
+		"cycle":     {animalID, maineCoon.ID},
+		"duplicate": {fixture.Tags["cat"], animalID},
+	}
+	for name, rule := range refused {
+		if err := db.AddTagImplication(rule[0], rule[1], fixture.AdminID); err == nil {
+			t.Errorf("%s implication was allowed", name)
+		}
+	}
+	if _, err := AddTagImplicationByName(animalID, "does_not_exist", fixture.AdminID); err == nil {
+		t.Error("implication of a tag that does not exist was allowed")
+	}
+
+	//Adding maine_coon adds cat and animal too, recorded as implied
+	if err := db.AddTag([]uint64{maineCoon.ID}, fixture.Images["two"], fixture.AdminID); err != nil {
+		t.Fatalf("AddTag: %v", err)
+	}
+	if names := strings.Join(imageTagNames(t, fixture.Images["two"]), ","); names != "animal,cat,dog,maine_coon,outdoor" {
+		t.Errorf("image two has tags %s after adding maine_coon", names)
+	}
+	history, err := db.GetImageTagHistory(fixture.Images["two"])
+	if err != nil {
+		t.Fatalf("GetImageTagHistory: %v", err)
+	}
+	operations := make(map[string]string)
+	for _, change := range history {
+		operations[change.TagName] = change.Operation
+	}
+	if operations["maine_coon"] != interfaces.TagChangeAdd || operations["cat"] != interfaces.TagChangeImply || operations["animal"] != interfaces.TagChangeImply {
+		t.Errorf("unexpected operations recorded %v", operations)
+	}
+
+	//Images tagged before the rules existed only gain the implied tags once applied
+	if names := strings.Join(imageTagNames(t, fixture.Images["one"]), ","); names != "cat,outdoor" {
+		t.Errorf("image one has tags %s before applying implications", names)
+	}
+	added, err := db.ApplyTagImplications(fixture.AdminID)
+	if err != nil || added != 2 {
+		t.Errorf("ApplyTagImplications added %d, %v, expected 2", added, err)
+	}
+	for _, image := range []string{"one", "three"} {
+		if names := imageTagNames(t, fixture.Images[image]); sliceContains(names, "animal") == false {
+			t.Errorf("image %s has tags %v after applying implications", image, names)
+		}
+	}
+	if added, err := db.ApplyTagImplications(fixture.AdminID); err != nil || added != 0 {
+		t.Errorf("applying implications again added %d, %v", added, err)
+	}
+
+	//Removed rules no longer apply
+	if err := db.RemoveTagImplication(maineCoon.ID, fixture.Tags["cat"]); err != nil {
+		t.Fatalf("RemoveTagImplication: %v", err)
+	}
+	if err := db.RemoveTagImplication(maineCoon.ID, fixture.Tags["cat"]); err == nil {
+		t.Error("removing a missing implication succeeded")
+	}
+	implications, err := db.GetTagImplications(fixture.Tags["cat"])
+	if err != nil || len(implications) != 1 || implications[0].ImpliedTagName != "animal" {
+		t.Errorf("GetTagImplications returned %+v, %v", implications, err)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"go-image-board/database"
 	"go-image-board/interfaces"
 	"go-image-board/logging"
+	"html"
 	"html/template"
 	"net/http"
 	"net/url"
@@ -43,6 +44,11 @@ func TagGetRouter(responseWriter http.ResponseWriter, request *http.Request) {
 	}
 	TemplateInput.TagContentInfo = tag
 
+	TemplateInput.TagImplications, err = database.DBInterface.GetTagImplications(tag.ID)
+	if err != nil {
+		TemplateInput.HTMLMessage += template.HTML("Error pulling tag implications.<br>")
+	}
+
 	replyWithTemplate("tag.html", TemplateInput, responseWriter, request)
 }
 
@@ -94,14 +100,19 @@ func TagPostRouter(responseWriter http.ResponseWriter, request *http.Request) {
 			}
 			aliasID = aliasedTags[0].ID
 		}
+		//Keep the current category unless a new one was chosen
+		tagCategory := request.FormValue("tagCategory")
+		if tagCategory == "" {
+			tagCategory = tagInfo.Category
+		}
 		//Update tag
-		if err := database.DBInterface.UpdateTag(requestedID, request.FormValue("tagName"), request.FormValue("tagDescription"), aliasID, len(aliasedTags) == 1, TemplateInput.UserInformation.ID); err != nil {
+		if err := database.DBInterface.UpdateTag(requestedID, request.FormValue("tagName"), request.FormValue("tagDescription"), tagCategory, aliasID, len(aliasedTags) == 1, TemplateInput.UserInformation.ID); err != nil {
 			TemplateInput.HTMLMessage += template.HTML("Failed to update tag. Is your name too short? Did it exist in the first place?<br>")
 			redirectWithFlash(responseWriter, request, "/tag?ID="+strconv.FormatUint(requestedID, 10)+"&SearchTerms="+url.QueryEscape(TemplateInput.OldQuery), TemplateInput.HTMLMessage, "TagFail")
 			return
 		}
 		TemplateInput.HTMLMessage += template.HTML("Tag updated successfully.<br>")
-		go WriteAuditLogByName(TemplateInput.UserInformation.Name, "MODIFY-TAG", TemplateInput.UserInformation.Name+" successfully updated tag. "+strconv.FormatUint(requestedID, 10)+" to alias "+request.FormValue("aliasedTagName")+" with name "+request.FormValue("tagName")+" and description "+request.FormValue("tagDescription"))
+		go WriteAuditLogByName(TemplateInput.UserInformation.Name, "MODIFY-TAG", TemplateInput.UserInformation.Name+" successfully updated tag. "+strconv.FormatUint(requestedID, 10)+" to alias "+request.FormValue("aliasedTagName")+" with name "+request.FormValue("tagName")+", category "+tagCategory+" and description "+request.FormValue("tagDescription"))
 		redirectWithFlash(responseWriter, request, "/tag?ID="+strconv.FormatUint(requestedID, 10)+"&SearchTerms="+url.QueryEscape(TemplateInput.OldQuery), TemplateInput.HTMLMessage, "TagSucceeded")
 		return
 	case "bulkAddTag":
@@ -188,6 +199,63 @@ func TagPostRouter(responseWriter http.ResponseWriter, request *http.Request) {
 		go WriteAuditLog(TemplateInput.UserInformation.ID, "REPLACE-BULKIMAGETAG", TemplateInput.UserInformation.Name+" bulk added tags to images. "+oldTagQuery+"->"+newTagQuery)
 		redirectWithFlash(responseWriter, request, "/tag?ID="+strconv.FormatUint(userNewQTags[0].ID, 10)+"&SearchTerms="+url.QueryEscape(TemplateInput.OldQuery), TemplateInput.HTMLMessage, "TagSucceeded")
 		return
+	case "addImplication", "removeImplication":
+		if !TemplateInput.IsLoggedOn() {
+			TemplateInput.HTMLMessage += template.HTML("You must be logged in to perform that action.<br>")
+			redirectWithFlash(responseWriter, request, "/logon", TemplateInput.HTMLMessage, "LogonRequired")
+			return
+		}
+
+		requestedID, err := strconv.ParseUint(request.FormValue("ID"), 10, 32)
+		if err != nil {
+			TemplateInput.HTMLMessage += template.HTML("Error parsing tag id.<br>")
+			logging.WriteLog(logging.LogLevelError, "tagrouter/TagRouter/"+cmd, TemplateInput.UserInformation.GetCompositeID(), logging.ResultFailure, []string{"Failed to parse tag id ", err.Error()})
+			redirectWithFlash(responseWriter, request, "/tags?SearchTerms="+url.QueryEscape(TemplateInput.OldQuery), TemplateInput.HTMLMessage, "TagFail")
+			return
+		}
+		tagURL := "/tag?ID=" + strconv.FormatUint(requestedID, 10) + "&SearchTerms=" + url.QueryEscape(TemplateInput.OldQuery)
+
+		//Validate permission to modify tags, implications change what every image is tagged with so owning the tag is not enough
+		if TemplateInput.UserPermissions.HasPermission(interfaces.ModifyTags) != true {
+			TemplateInput.HTMLMessage += template.HTML("User does not have modify permission for tags.<br>")
+			go WriteAuditLogByName(TemplateInput.UserInformation.Name, "MODIFY-TAGIMPLICATION", TemplateInput.UserInformation.Name+" failed to change tag implications. Insufficient permissions. "+strconv.FormatUint(requestedID, 10))
+			redirectWithFlash(responseWriter, request, tagURL, TemplateInput.HTMLMessage, "TagFail")
+			return
+		}
+		// /ValidatePermission
+
+		if cmd == "addImplication" {
+			impliedTagName := request.FormValue("impliedTagName")
+			impliedTagID, err := AddTagImplicationByName(requestedID, impliedTagName, TemplateInput.UserInformation.ID)
+			if err != nil {
+				TemplateInput.HTMLMessage += template.HTML("Failed to add tag implication, " + html.EscapeString(err.Error()) + ".<br>")
+				go WriteAuditLogByName(TemplateInput.UserInformation.Name, "MODIFY-TAGIMPLICATION", TemplateInput.UserInformation.Name+" failed to add tag implication. "+strconv.FormatUint(requestedID, 10)+"->"+impliedTagName+", "+err.Error())
+				redirectWithFlash(responseWriter, request, tagURL, TemplateInput.HTMLMessage, "TagFail")
+				return
+			}
+			TemplateInput.HTMLMessage += template.HTML("Tag implication added. Images tagged before now gain the implied tag once the server is run with -applyimplications.<br>")
+			go WriteAuditLogByName(TemplateInput.UserInformation.Name, "MODIFY-TAGIMPLICATION", TemplateInput.UserInformation.Name+" added tag implication. "+strconv.FormatUint(requestedID, 10)+"->"+strconv.FormatUint(impliedTagID, 10))
+		} else {
+			//The rule may be one implying this tag, so both of its tags are given
+			implyingTagID, err := strconv.ParseUint(request.FormValue("TagID"), 10, 32)
+			var impliedTagID uint64
+			if err == nil {
+				impliedTagID, err = strconv.ParseUint(request.FormValue("ImpliedTagID"), 10, 32)
+			}
+			if err == nil {
+				err = database.DBInterface.RemoveTagImplication(implyingTagID, impliedTagID)
+			}
+			if err != nil {
+				TemplateInput.HTMLMessage += template.HTML("Failed to remove tag implication. Did it exist in the first place?<br>")
+				go WriteAuditLogByName(TemplateInput.UserInformation.Name, "MODIFY-TAGIMPLICATION", TemplateInput.UserInformation.Name+" failed to remove tag implication. "+request.FormValue("TagID")+"->"+request.FormValue("ImpliedTagID")+", "+err.Error())
+				redirectWithFlash(responseWriter, request, tagURL, TemplateInput.HTMLMessage, "TagFail")
+				return
+			}
+			TemplateInput.HTMLMessage += template.HTML("Tag implication removed.<br>")
+			go WriteAuditLogByName(TemplateInput.UserInformation.Name, "MODIFY-TAGIMPLICATION", TemplateInput.UserInformation.Name+" removed tag implication. "+request.FormValue("TagID")+"->"+request.FormValue("ImpliedTagID"))
+		}
+		redirectWithFlash(responseWriter, request, tagURL, TemplateInput.HTMLMessage, "TagSucceeded")
+		return
 	case "delete":
 		if !TemplateInput.IsLoggedOn() {
 			TemplateInput.HTMLMessage += template.HTML("You must be logged in to perform that action.<br>")
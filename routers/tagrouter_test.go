@@ -0,0 +1,53 @@
+package routers
+
+import (
+	"go-image-board/database"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestTagGetRouter(t *testing.T) {
+	fixture := seedDatabase(t)
+	server := newTestServer(t)
+	client := newTestClient(t, server)
+
+	if err := database.DBInterface.AddTagImplication(fixture.Tags["outdoor"], fixture.Tags["dog"], fixture.AdminID); err != nil {
+		t.Fatalf("AddTagImplication: %v", err)
+	}
+	response, body := client.get(t, "/tag?ID="+strconv.FormatUint(fixture.Tags["outdoor"], 10))
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("tag page returned %d", response.StatusCode)
+	}
+	for _, expected := range []string{"outdoor", "The outdoor tag", "</a> implies <a href=\"/tag?ID=" + strconv.FormatUint(fixture.Tags["dog"], 10)} {
+		if strings.Contains(body, expected) == false {
+			t.Errorf("tag page does not contain %q", expected)
+		}
+	}
+}
+
+func TestTagsRouter(t *testing.T) {
+	fixture := seedDatabase(t)
+	server := newTestServer(t)
+	client := newTestClient(t, server)
+
+	tests := map[string][]string{
+		"":    {"cat", "dog", "outdoor"},
+		"o":   {"dog", "outdoor"},
+		"cat": {"cat"},
+	}
+	for query, expected := range tests {
+		response, body := client.get(t, "/tags?SearchTags="+url.QueryEscape(query))
+		if response.StatusCode != http.StatusOK {
+			t.Fatalf("query %q returned %d", query, response.StatusCode)
+		}
+		for name, ID := range fixture.Tags {
+			listed := strings.Contains(body, `<a href="/tag?ID=`+strconv.FormatUint(ID, 10)+`">`)
+			if listed != sliceContains(expected, name) {
+				t.Errorf("query %q listed %s: %v, expected %v", query, name, listed, expected)
+			}
+		}
+	}
+}
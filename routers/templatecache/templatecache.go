@@ -3,6 +3,7 @@ package templatecache
 import (
 	"fmt"
 	"go-image-board/config"
+	"go-image-board/interfaces"
 	"go-image-board/logging"
 	"html/template"
 	"io/ioutil"
@@ -31,9 +32,16 @@ func CacheTemplates() error {
 	}
 
 	//Add functions here
-	getImageType := func(path string) string {
+	getImageType := func(value interface{}) string {
+		//Prefer the MIME type read from the file, older images may not have one yet
+		if imageInfo, isImage := value.(interfaces.ImageInformation); isImage {
+			if mediaType := getMediaType(imageInfo.MIMEType); mediaType != "" {
+				return mediaType
+			}
+			value = imageInfo.Location
+		}
 		text := ""
-		switch ext := filepath.Ext(strings.ToLower(path)); ext {
+		switch ext := filepath.Ext(strings.ToLower(fmt.Sprintf("%v", value))); ext {
 		case ".wav", ".mp3", ".ogg":
 			text = "audio"
 		case ".mpg", ".mov", ".webm", ".avi", ".mp4", ".gif":
@@ -74,6 +82,9 @@ func CacheTemplates() error {
 		return value
 	}
 	getEmbed := func(value interface{}) template.HTML {
+		if imageInfo, isImage := value.(interfaces.ImageInformation); isImage {
+			return GetEmbedForImage(imageInfo)
+		}
 		return GetEmbedForContent(fmt.Sprintf("%v", value))
 	}
 	templates := template.New("")
@@ -81,6 +92,8 @@ func CacheTemplates() error {
 	templates = templates.Funcs(template.FuncMap{"inc": increment})
 	templates = templates.Funcs(template.FuncMap{"dec": decrement})
 	templates = templates.Funcs(template.FuncMap{"getEmbed": getEmbed})
+	templates = templates.Funcs(template.FuncMap{"tagCategories": func() []string { return interfaces.TagCategories }})
+	templates = templates.Funcs(template.FuncMap{"reportCategories": func() []string { return interfaces.ReportCategories }})
 
 	templates, err = templates.ParseFiles(allFiles...)
 	if err != nil {
@@ -92,6 +105,32 @@ func CacheTemplates() error {
 	return nil
 }
 
+//GetEmbedForImage Returns the html necessary to embed the file of an image, using its MIME type when known, otherwise its extension
+func GetEmbedForImage(ImageInfo interfaces.ImageInformation) template.HTML {
+	imageLocation := ImageInfo.Location
+	switch strings.Split(ImageInfo.MIMEType, "/")[0] {
+	case "image":
+		return template.HTML("<img src=\"/images/" + imageLocation + "\" alt=\"" + imageLocation + "\" id=\"IMGContent\" />")
+	case "video":
+		return template.HTML("<video controls loop> <source src=\"/images/" + imageLocation + "\" type=\"" + ImageInfo.MIMEType + "\">Your browser does not support the video tag.</video>")
+	case "audio":
+		return template.HTML("<audio controls loop> <source src=\"/images/" + imageLocation + "\" type=\"" + ImageInfo.MIMEType + "\">Your browser does not support the audio tag.</audio>")
+	}
+	return GetEmbedForContent(imageLocation)
+}
+
+//getMediaType returns image, video or audio for a MIME type, or "" when it is none of them. Gifs count as video, as they are often animated
+func getMediaType(MIMEType string) string {
+	if MIMEType == "image/gif" {
+		return "video"
+	}
+	switch mediaType := strings.Split(MIMEType, "/")[0]; mediaType {
+	case "image", "video", "audio":
+		return mediaType
+	}
+	return ""
+}
+
 //GetEmbedForContent Returns the html necessary to embed the specified file
 func GetEmbedForContent(imageLocation string) template.HTML {
 	ToReturn := ""
@@ -100,9 +139,9 @@ func GetEmbedForContent(imageLocation string) template.HTML {
 	case ".jpg", ".jpeg", ".bmp", ".gif", ".png", ".svg", ".webp", ".tiff", ".tif", ".jfif":
 		ToReturn = "<img src=\"/images/" + imageLocation + "\" alt=\"" + imageLocation + "\" id=\"IMGContent\" />"
 	case ".mpg", ".mov", ".webm", ".avi", ".mp4", ".mp3", ".ogg":
-		ToReturn = "<video controls loop> <source src=\"/images/" + imageLocation + "\" type=\"" + getMIME(ext, "video/mp4") + "\">Your browser does not support the video tag.</video>"
+		ToReturn = "<video controls loop> <source src=\"/images/" + imageLocation + "\" type=\"" + GetMIME(ext, "video/mp4") + "\">Your browser does not support the video tag.</video>"
 	case ".wav":
-		ToReturn = "<audio controls loop> <source src=\"/images/" + imageLocation + "\" type=\"" + getMIME(ext, "audio/wav") + "\">Your browser does not support the audio tag.</audio>"
+		ToReturn = "<audio controls loop> <source src=\"/images/" + imageLocation + "\" type=\"" + GetMIME(ext, "audio/wav") + "\">Your browser does not support the audio tag.</audio>"
 	default:
 		logging.WriteLog(logging.LogLevelError, "templatecache/GetEmbedForContent", "0", logging.ResultFailure, []string{"File uploaded, but did not match a filter during download", imageLocation})
 		ToReturn = "<p>File format not supported. Click download.</p>"
@@ -111,9 +150,23 @@ func GetEmbedForContent(imageLocation string) template.HTML {
 	return template.HTML(ToReturn)
 }
 
-//returns a mime given a file extension. This is only required for video and audio files so we can embed mime in video/audio element
-func getMIME(extension string, fallback string) string {
+//GetMIME returns a mime given a file extension, or fallback if the extension is not known. Used to embed the mime in video/audio elements, and for files whose content does not identify them
+func GetMIME(extension string, fallback string) string {
 	switch extension {
+	case ".jpg", ".jpeg", ".jfif":
+		return "image/jpeg"
+	case ".png":
+		return "image/png"
+	case ".gif":
+		return "image/gif"
+	case ".bmp":
+		return "image/bmp"
+	case ".webp":
+		return "image/webp"
+	case ".tif", ".tiff":
+		return "image/tiff"
+	case ".svg":
+		return "image/svg+xml"
 	case ".mp4":
 		return "video/mp4"
 	case ".webm":
@@ -0,0 +1,150 @@
+package routers
+
+import (
+	"go-image-board/config"
+	"go-image-board/database"
+	"go-image-board/interfaces"
+	"go-image-board/logging"
+	"html/template"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+//trashPurgeInterval how often the trash is checked for items older than config.Configuration.TrashRetention
+const trashPurgeInterval = time.Hour
+
+//TrashGetRouter serves get requests to /trash
+func TrashGetRouter(responseWriter http.ResponseWriter, request *http.Request) {
+	TemplateInput := getTemplateInputFromRequest(responseWriter, request)
+	canRestoreImages := TemplateInput.UserPermissions.HasPermission(interfaces.RemoveImage)
+	canRestoreCollections := TemplateInput.UserPermissions.HasPermission(interfaces.RemoveCollections)
+	if canRestoreImages == false && canRestoreCollections == false {
+		TemplateInput.HTMLMessage += template.HTML("You do not have permission to view the trash.<br>")
+		redirectWithFlash(responseWriter, request, "/mod", TemplateInput.HTMLMessage, "ModFail")
+		return
+	}
+
+	//Get the page offset
+	pageStart, _ := strconv.ParseUint(request.FormValue("PageStart"), 10, 32) //Either parses fine, or is 0, both works
+	pageStride := config.Configuration.PageStride
+
+	//Show images unless collections are requested, or images cannot be restored by this user
+	TemplateInput.TrashView = "images"
+	if (request.FormValue("View") == "collections" && canRestoreCollections) || canRestoreImages == false {
+		TemplateInput.TrashView = "collections"
+	}
+
+	var err error
+	if TemplateInput.TrashView == "collections" {
+		TemplateInput.CollectionInfoList, TemplateInput.TotalResults, err = database.DBInterface.GetTrashedCollections(pageStart, pageStride)
+	} else {
+		TemplateInput.ImageInfo, TemplateInput.TotalResults, err = database.DBInterface.GetTrashedImages(pageStart, pageStride)
+	}
+	if err != nil {
+		TemplateInput.HTMLMessage += template.HTML("Failed to get the contents of the trash.<br>")
+		logging.WriteLog(logging.LogLevelError, "trashrouter/TrashGetRouter", TemplateInput.UserInformation.GetCompositeID(), logging.ResultFailure, []string{"Failed to get trash", err.Error()})
+	} else {
+		TemplateInput.PageMenu, _ = generatePageMenu(int64(pageStart), int64(pageStride), int64(TemplateInput.TotalResults), "View="+TemplateInput.TrashView, "/trash")
+	}
+
+	replyWithTemplate("trash.html", TemplateInput, responseWriter, request)
+}
+
+//TrashPostRouter serves post requests to /trash
+func TrashPostRouter(responseWriter http.ResponseWriter, request *http.Request) {
+	TemplateInput := getTemplateInputFromRequest(responseWriter, request)
+	if !TemplateInput.IsLoggedOn() {
+		redirectWithFlash(responseWriter, request, "/logon", "You must be logged in to restore from the trash", "LogonRequired")
+		return
+	}
+
+	requestedID, err := strconv.ParseUint(request.FormValue("ID"), 10, 32)
+	if err != nil {
+		TemplateInput.HTMLMessage += template.HTML("Failed to parse ID to restore.<br>")
+		redirectWithFlash(responseWriter, request, "/trash", TemplateInput.HTMLMessage, "RestoreFailed")
+		return
+	}
+
+	switch request.FormValue("command") {
+	case "restoreimage":
+		if TemplateInput.UserPermissions.HasPermission(interfaces.RemoveImage) != true {
+			TemplateInput.HTMLMessage += template.HTML("You do not have permission to restore images.<br>")
+			go WriteAuditLog(TemplateInput.UserInformation.ID, "RESTORE-IMAGE", TemplateInput.UserInformation.Name+" failed to restore image. Insufficient permissions. "+request.FormValue("ID"))
+			redirectWithFlash(responseWriter, request, "/trash", TemplateInput.HTMLMessage, "RestoreFailed")
+			return
+		}
+		if err := database.DBInterface.RestoreImage(requestedID); err != nil {
+			TemplateInput.HTMLMessage += template.HTML("Failed to restore image, is it still in the trash?<br>")
+			redirectWithFlash(responseWriter, request, "/trash", TemplateInput.HTMLMessage, "RestoreFailed")
+			return
+		}
+		go WriteAuditLog(TemplateInput.UserInformation.ID, "RESTORE-IMAGE", TemplateInput.UserInformation.Name+" restored image from trash. "+request.FormValue("ID"))
+		TemplateInput.HTMLMessage += template.HTML("Image restored.<br>")
+		redirectWithFlash(responseWriter, request, "/image?ID="+strconv.FormatUint(requestedID, 10), TemplateInput.HTMLMessage, "RestoreSuccess")
+		return
+	case "restorecollection":
+		if TemplateInput.UserPermissions.HasPermission(interfaces.RemoveCollections) != true {
+			TemplateInput.HTMLMessage += template.HTML("You do not have permission to restore collections.<br>")
+			go WriteAuditLog(TemplateInput.UserInformation.ID, "RESTORE-COLLECTION", TemplateInput.UserInformation.Name+" failed to restore collection. Insufficient permissions. "+request.FormValue("ID"))
+			redirectWithFlash(responseWriter, request, "/trash?View=collections", TemplateInput.HTMLMessage, "RestoreFailed")
+			return
+		}
+		if err := database.DBInterface.RestoreCollection(requestedID); err != nil {
+			TemplateInput.HTMLMessage += template.HTML("Failed to restore collection, is it still in the trash?<br>")
+			redirectWithFlash(responseWriter, request, "/trash?View=collections", TemplateInput.HTMLMessage, "RestoreFailed")
+			return
+		}
+		go WriteAuditLog(TemplateInput.UserInformation.ID, "RESTORE-COLLECTION", TemplateInput.UserInformation.Name+" restored collection from trash. "+request.FormValue("ID"))
+		TemplateInput.HTMLMessage += template.HTML("Collection restored.<br>")
+		redirectWithFlash(responseWriter, request, "/collection?ID="+strconv.FormatUint(requestedID, 10), TemplateInput.HTMLMessage, "RestoreSuccess")
+		return
+	}
+	TemplateInput.HTMLMessage += template.HTML("Command not recognized or form submitted incorrectly.<br>")
+	redirectWithFlash(responseWriter, request, "/trash", TemplateInput.HTMLMessage, "RestoreFailed")
+}
+
+//PurgeExpiredTrash permanently deletes images and collections that have been in the trash for longer than RetentionPeriod, along with the image files
+func PurgeExpiredTrash(RetentionPeriod time.Duration) error {
+	images, collections, err := database.DBInterface.GetExpiredTrash(RetentionPeriod)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "trashrouter/PurgeExpiredTrash", "0", logging.ResultFailure, []string{"Failed to get expired trash", err.Error()})
+		return err
+	}
+	//Collections first, so images leaving them do not need their order fixed
+	for _, collection := range collections {
+		if err := database.DBInterface.DeleteCollection(collection.ID); err != nil {
+			logging.WriteLog(logging.LogLevelError, "trashrouter/PurgeExpiredTrash", "0", logging.ResultFailure, []string{"Failed to purge collection", strconv.FormatUint(collection.ID, 10), err.Error()})
+			continue
+		}
+		WriteAuditLog(0, "PURGE-COLLECTION", "Purged collection from trash. "+strconv.FormatUint(collection.ID, 10)+", "+collection.Name)
+	}
+	for _, image := range images {
+		//Revisions are removed along with the image, so get their files first
+		revisions, err := database.DBInterface.GetImageRevisions(image.ID)
+		if err != nil {
+			continue
+		}
+		if err := database.DBInterface.DeleteImage(image.ID); err != nil {
+			logging.WriteLog(logging.LogLevelError, "trashrouter/PurgeExpiredTrash", "0", logging.ResultFailure, []string{"Failed to purge image", strconv.FormatUint(image.ID, 10), err.Error()})
+			continue
+		}
+		WriteAuditLog(0, "PURGE-IMAGE", "Purged image from trash. "+strconv.FormatUint(image.ID, 10)+", "+image.Name+", "+image.Location)
+		RemoveImageFiles(image.Location)
+		for _, revision := range revisions {
+			RemoveImageFiles(revision.Location)
+		}
+	}
+	if len(images) > 0 || len(collections) > 0 {
+		logging.WriteLog(logging.LogLevelInfo, "trashrouter/PurgeExpiredTrash", "0", logging.ResultSuccess, []string{"Purged trash", strconv.Itoa(len(images)) + " images", strconv.Itoa(len(collections)) + " collections"})
+	}
+	return nil
+}
+
+//TrashPurgeLoop purges expired trash every trashPurgeInterval, it does not return
+func TrashPurgeLoop() {
+	for {
+		PurgeExpiredTrash(config.Configuration.TrashRetention)
+		time.Sleep(trashPurgeInterval)
+	}
+}
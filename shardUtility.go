@@ -0,0 +1,114 @@
+package main
+
+import (
+	"go-image-board/config"
+	"go-image-board/database"
+	"go-image-board/logging"
+	"go-image-board/storage"
+	"os"
+	"path"
+	"strconv"
+)
+
+//shardAllImages moves images, their previous files and their thumbnails from the flat layout in to the sharded layout, updating their locations in the database
+//
+//Files are copied before the database is updated, and only removed afterwards, so the board can keep serving while this runs. Running again resumes an interrupted run
+func shardAllImages() {
+	if config.Configuration.UseShardedLayout == false {
+		logging.WriteLog(logging.LogLevelCritical, "shardUtility/shardAllImages", "0", logging.ResultFailure, []string{"UseShardedLayout must be enabled first, so new uploads are sharded as well"})
+		return
+	}
+	movedFiles := uint64(0)
+	//Loop through every image one page at a time, including those in the trash or waiting for approval
+	for lastID := uint64(0); ; {
+		images, err := database.DBInterface.GetAllImages(lastID, config.Configuration.PageStride)
+		if err != nil {
+			logging.WriteLog(logging.LogLevelCritical, "shardUtility/shardAllImages", "0", logging.ResultFailure, []string{"Failed to query for images", err.Error()})
+			return
+		}
+		if len(images) == 0 {
+			break
+		}
+		logging.WriteLog(logging.LogLevelInfo, "shardUtility/shardAllImages", "0", logging.ResultInfo, []string{"Processing after", strconv.FormatUint(lastID, 10)})
+		for _, imageInfo := range images {
+			lastID = imageInfo.ID
+			moved, err := shardFile(imageInfo.Location, func(Location string) error {
+				return database.DBInterface.UpdateImage(imageInfo.ID, nil, nil, nil, nil, nil, Location)
+			})
+			if err != nil {
+				logging.WriteLog(logging.LogLevelCritical, "shardUtility/shardAllImages", "0", logging.ResultFailure, []string{"Failed to move image, run again once resolved to resume", strconv.FormatUint(imageInfo.ID, 10), imageInfo.Location, err.Error()})
+				return
+			}
+			if moved {
+				movedFiles++
+			}
+			//Previous files of the image are moved as well, so they can still be restored
+			revisions, err := database.DBInterface.GetImageRevisions(imageInfo.ID)
+			if err != nil {
+				logging.WriteLog(logging.LogLevelCritical, "shardUtility/shardAllImages", "0", logging.ResultFailure, []string{"Failed to query for image revisions", strconv.FormatUint(imageInfo.ID, 10), err.Error()})
+				return
+			}
+			for _, revision := range revisions {
+				moved, err := shardFile(revision.Location, func(Location string) error {
+					return database.DBInterface.UpdateImageRevisionLocation(revision.ID, Location)
+				})
+				if err != nil {
+					logging.WriteLog(logging.LogLevelCritical, "shardUtility/shardAllImages", "0", logging.ResultFailure, []string{"Failed to move image revision, run again once resolved to resume", strconv.FormatUint(revision.ID, 10), revision.Location, err.Error()})
+					return
+				}
+				if moved {
+					movedFiles++
+				}
+			}
+		}
+	}
+	logging.WriteLog(logging.LogLevelInfo, "shardUtility/shardAllImages", "0", logging.ResultSuccess, []string{"Moved " + strconv.FormatUint(movedFiles, 10) + " files to the sharded layout"})
+}
+
+//shardFile moves a single file stored at Location, and its thumbnail, calling SetLocation to record the new location in the database. Returns whether it was moved.
+//Each step checks what an earlier, interrupted, run completed
+func shardFile(Location string, SetLocation func(string) error) (bool, error) {
+	flatName := path.Base(Location)
+	shardedName := storage.ShardedName(flatName)
+	if flatName == shardedName {
+		return false, nil //Name is too short to shard
+	}
+	moved := false
+	if Location != shardedName {
+		if Location != flatName {
+			logging.WriteLog(logging.LogLevelWarning, "shardUtility/shardFile", "0", logging.ResultInfo, []string{"Skipping file in an unrecognized location", Location})
+			return false, nil
+		}
+		//Copy the file, unless an earlier run already has
+		if _, err := storage.StorageInterface.Stat(shardedName); os.IsNotExist(err) {
+			if err := copyObject(flatName, shardedName); err != nil {
+				if os.IsNotExist(err) {
+					logging.WriteLog(logging.LogLevelWarning, "shardUtility/shardFile", "0", logging.ResultInfo, []string{"Skipping missing file", Location})
+					return false, nil
+				}
+				return false, err
+			}
+		} else if err != nil {
+			return false, err
+		}
+		//Copy the thumbnail, not every file has one
+		if _, err := storage.StorageInterface.Stat(storage.ThumbnailName(shardedName)); os.IsNotExist(err) {
+			if err := copyObject(storage.ThumbnailName(flatName), storage.ThumbnailName(shardedName)); err != nil && os.IsNotExist(err) == false {
+				return false, err
+			}
+		} else if err != nil {
+			return false, err
+		}
+		if err := SetLocation(shardedName); err != nil {
+			return false, err
+		}
+		moved = true
+	}
+	//Remove the flat copies, including any left behind by an interrupted run
+	for _, name := range []string{flatName, storage.ThumbnailName(flatName)} {
+		if err := storage.StorageInterface.Delete(name); err != nil && os.IsNotExist(err) == false {
+			return moved, err
+		}
+	}
+	return moved, nil
+}
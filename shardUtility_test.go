@@ -0,0 +1,62 @@
+package main
+
+import (
+	"go-image-board/config"
+	"go-image-board/database"
+	"testing"
+)
+
+func TestShardAllImages(t *testing.T) {
+	adminID := useEmptySite(t)
+	config.Configuration.UseShardedLayout = true
+	config.Configuration.PageStride = 1
+	defer func() {
+		config.Configuration.UseShardedLayout = false
+		config.Configuration.PageStride = 0
+	}()
+	newImage := func(Location string, Pending bool) uint64 {
+		t.Helper()
+		ID, err := database.DBInterface.NewImage(Location, Location, adminID, "", Pending)
+		if err != nil {
+			t.Fatal(err)
+		}
+		putFiles(t, Location, "thumbs/"+Location+".png")
+		return ID
+	}
+	//Images in the trash, waiting for approval, and replaced files are all moved
+	publishedID := newImage("abcdone.png", false)
+	trashedID := newImage("efghtwo.png", false)
+	pendingID := newImage("ijklthree.png", true)
+	if err := database.DBInterface.TrashImage(trashedID, adminID); err != nil {
+		t.Fatal(err)
+	}
+	putFiles(t, "mnopfour.png", "thumbs/mnopfour.png.png")
+	if err := database.DBInterface.ReplaceImageFile(publishedID, "mnopfour.png", adminID); err != nil {
+		t.Fatal(err)
+	}
+
+	shardAllImages()
+
+	for ID, expected := range map[uint64]string{publishedID: "mn/op/mnopfour.png", trashedID: "ef/gh/efghtwo.png", pendingID: "ij/kl/ijklthree.png"} {
+		if image, err := database.DBInterface.GetImage(ID); err != nil || image.Location != expected {
+			t.Errorf("image %d is at %q, expected %q", ID, image.Location, expected)
+		}
+	}
+	revisions, err := database.DBInterface.GetImageRevisions(publishedID)
+	if err != nil || len(revisions) != 1 || revisions[0].Location != "ab/cd/abcdone.png" {
+		t.Errorf("revisions are %+v, %v", revisions, err)
+	}
+	sharded := []string{"ab/cd/abcdone.png", "thumbs/ab/cd/abcdone.png.png", "ef/gh/efghtwo.png", "thumbs/ef/gh/efghtwo.png.png", "ij/kl/ijklthree.png", "mn/op/mnopfour.png", "thumbs/mn/op/mnopfour.png.png"}
+	if files := storedFiles(t, sharded...); len(files) != len(sharded) || files["ab/cd/abcdone.png"] != "abcdone.png" {
+		t.Errorf("sharded files are %v", files)
+	}
+	if files := storedFiles(t, "abcdone.png", "thumbs/abcdone.png.png", "efghtwo.png", "ijklthree.png", "mnopfour.png"); len(files) != 0 {
+		t.Errorf("flat files were left behind, %v", files)
+	}
+
+	//Running again changes nothing
+	shardAllImages()
+	if image, _ := database.DBInterface.GetImage(publishedID); image.Location != "mn/op/mnopfour.png" {
+		t.Errorf("image moved again to %q", image.Location)
+	}
+}
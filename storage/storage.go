@@ -0,0 +1,45 @@
+package storage
+
+import (
+	"go-image-board/interfaces"
+	"path"
+	"strings"
+)
+
+//StorageInterface is a global variable for image and thumbnail storage access
+var StorageInterface interfaces.StorageInterface
+
+//ThumbnailDirectory is the directory thumbnails are stored in
+const ThumbnailDirectory = "thumbs"
+
+//ThumbnailName returns the name of the thumbnail for the image Name
+func ThumbnailName(Name string) string {
+	return ThumbnailDirectory + "/" + Name + ".png"
+}
+
+//IsThumbnail returns whether Name is in the thumbnail directory
+func IsThumbnail(Name string) bool {
+	return strings.HasPrefix(Name, ThumbnailDirectory+"/")
+}
+
+//ShardedName returns where Name is stored in the sharded layout, beneath directories named after its first four characters, such as ab/cd/abcd...jpg
+func ShardedName(Name string) string {
+	if len(Name) < 4 {
+		return Name
+	}
+	return Name[0:2] + "/" + Name[2:4] + "/" + Name
+}
+
+//OtherLayoutName returns Name as it would be in the other layout, flat names become sharded and sharded names flat
+//
+//Names that fit neither layout are returned unchanged
+func OtherLayoutName(Name string) string {
+	baseName := path.Base(Name)
+	if Name == baseName {
+		return ShardedName(Name)
+	}
+	if Name == ShardedName(baseName) {
+		return baseName
+	}
+	return Name
+}
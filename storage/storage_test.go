@@ -0,0 +1,24 @@
+package storage
+
+import "testing"
+
+func TestLayoutNames(t *testing.T) {
+	if sharded := ShardedName("abcdef.png"); sharded != "ab/cd/abcdef.png" {
+		t.Errorf("ShardedName = %q", sharded)
+	}
+	if sharded := ShardedName("abc"); sharded != "abc" {
+		t.Errorf("ShardedName of a short name = %q, want it unchanged", sharded)
+	}
+	for name, expected := range map[string]string{
+		"abcdef.png":          "ab/cd/abcdef.png",
+		"ab/cd/abcdef.png":    "abcdef.png",
+		"other/dir/abcde.png": "other/dir/abcde.png",
+	} {
+		if other := OtherLayoutName(name); other != expected {
+			t.Errorf("OtherLayoutName(%q) = %q, want %q", name, other, expected)
+		}
+	}
+	if IsThumbnail(ThumbnailName("ab/cd/abcdef.png")) == false || IsThumbnail("ab/cd/abcdef.png") {
+		t.Error("IsThumbnail did not match the thumbnail directory")
+	}
+}